@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
@@ -14,8 +15,11 @@ import (
 	"github.com/eikuma/stockle/backend/internal/controllers"
 	"github.com/eikuma/stockle/backend/internal/database"
 	"github.com/eikuma/stockle/backend/internal/middleware"
+	"github.com/eikuma/stockle/backend/internal/models"
 	"github.com/eikuma/stockle/backend/internal/repositories"
+	"github.com/eikuma/stockle/backend/internal/search"
 	"github.com/eikuma/stockle/backend/internal/services"
+	"github.com/eikuma/stockle/backend/internal/urlnorm"
 )
 
 func main() {
@@ -36,11 +40,23 @@ func main() {
 	}
 	defer database.Close()
 
-	// Run database migrations
-	if err := database.AutoMigrate(); err != nil {
+	// Apply pending migrations and refuse to start on schema drift
+	if err := database.Migrate(cfg.Database.AutoMigrate); err != nil {
 		log.Fatalf("Failed to run database migrations: %v", err)
 	}
 
+	// URL rules decide which saved articles are duplicates
+	urls, err := urlnorm.Load(cfg.Scraper.URLRules)
+	if err != nil {
+		log.Fatalf("Failed to load URL normalization rules: %v", err)
+	}
+	if err := database.BackfillArticleURLHashes(database.GetDB(), urls); err != nil {
+		log.Fatalf("Failed to set article URL hashes: %v", err)
+	}
+	if err := database.BackfillTagNameKeys(database.GetDB()); err != nil {
+		log.Fatalf("Failed to set tag name keys: %v", err)
+	}
+
 	// Set gin mode
 	if cfg.Server.Environment == "production" {
 		gin.SetMode(gin.ReleaseMode)
@@ -48,8 +64,37 @@ func main() {
 		gin.SetMode(gin.DebugMode)
 	}
 
+	// Index articles saved before the search index existed, without delaying startup
+	db := database.GetDB()
+	searchService := services.NewSearchService(search.NewIndex(db), repositories.NewArticleRepository(db))
+	go func() {
+		if err := searchService.IndexStale(); err != nil {
+			log.Printf("Failed to update the search index: %v", err)
+		}
+	}()
+
+	// Initialize background job processing
+	scraper := setupScraper(cfg)
+	jobService, events := setupJobs(cfg, scraper, urls, searchService)
+	importService := setupImports(jobService, urls, searchService)
+	exportService := setupExports(cfg, jobService)
+	feedService := setupFeeds(cfg, jobService, scraper, urls, searchService)
+
 	// Initialize Gin router
-	router := setupRouter(cfg)
+	router := setupRouter(cfg, jobService, importService, exportService, feedService, searchService, events, urls)
+
+	// Start background workers
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	for i := 1; i <= cfg.Jobs.Workers; i++ {
+		workers.Add(1)
+		go func(workerID int) {
+			defer workers.Done()
+			jobService.StartWorker(workerCtx, workerID)
+		}(i)
+	}
+	go exportService.RunCleanup(workerCtx, time.Hour)
+	go feedService.RunScheduler(workerCtx, cfg.Feeds.SchedulerInterval)
 
 	// Setup HTTP server
 	server := &http.Server{
@@ -82,14 +127,139 @@ func main() {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
 
+	// Let running jobs finish their current step
+	stopWorkers()
+	workers.Wait()
+
 	log.Println("Server exited")
 }
 
-func setupRouter(cfg *config.Config) *gin.Engine {
+// setupScraper creates the scraper shared by content extraction and feed
+// polling, so that both stay within the same per-host limits
+func setupScraper(cfg *config.Config) *services.ScraperService {
+	extractors, err := services.LoadExtractorRegistry(cfg.Scraper.SiteRules)
+	if err != nil {
+		log.Fatalf("Failed to load site extraction rules: %v", err)
+	}
+	return services.NewScraperService(&cfg.Scraper, extractors)
+}
+
+func setupJobs(
+	cfg *config.Config,
+	scraper *services.ScraperService,
+	urls *urlnorm.Normalizer,
+	searchService *services.SearchService,
+) (*services.JobService, *services.ArticleEventBroker) {
+	aiService, err := services.NewAIService(&cfg.AI)
+	if err != nil {
+		log.Fatalf("Failed to configure AI providers: %v", err)
+	}
+
+	db := database.GetDB()
+	events := services.NewArticleEventBroker()
+	jobService := services.NewJobService(
+		repositories.NewJobRepository(db),
+		repositories.NewArticleRepository(db),
+		repositories.NewArticleSummaryRepository(db),
+		repositories.NewPreferenceRepository(db),
+		aiService,
+		scraper,
+		urls,
+		searchService,
+		events,
+	)
+	jobService.SetStaleAfter(cfg.Jobs.StaleAfter)
+	return jobService, events
+}
+
+// setupImports creates the import service and lets the job workers run imports
+func setupImports(
+	jobService *services.JobService,
+	urls *urlnorm.Normalizer,
+	searchService *services.SearchService,
+) *services.ImportService {
+	db := database.GetDB()
+	importService := services.NewImportService(
+		repositories.NewImportRepository(db),
+		repositories.NewArticleRepository(db),
+		repositories.NewCategoryRepository(db),
+		repositories.NewTagRepository(db),
+		jobService,
+		urls,
+		searchService,
+	)
+	jobService.Handle(models.JobTypeImportArticles, importService)
+	return importService
+}
+
+// setupExports creates the export service and lets the job workers write
+// export files
+func setupExports(cfg *config.Config, jobService *services.JobService) *services.ExportService {
+	exportsCfg := cfg.Exports
+	if exportsCfg.SigningKey == "" {
+		exportsCfg.SigningKey = cfg.JWT.AccessSecret
+	}
+
+	db := database.GetDB()
+	exportService := services.NewExportService(
+		repositories.NewExportRepository(db),
+		repositories.NewArticleRepository(db),
+		repositories.NewCategoryRepository(db),
+		jobService,
+		&exportsCfg,
+	)
+	jobService.Handle(models.JobTypeExportArticles, exportService)
+	return exportService
+}
+
+// setupFeeds creates the feed service and lets the job workers poll feeds
+func setupFeeds(
+	cfg *config.Config,
+	jobService *services.JobService,
+	scraper *services.ScraperService,
+	urls *urlnorm.Normalizer,
+	searchService *services.SearchService,
+) *services.FeedService {
+	db := database.GetDB()
+	feedService := services.NewFeedService(
+		repositories.NewFeedRepository(db),
+		repositories.NewArticleRepository(db),
+		repositories.NewCategoryRepository(db),
+		repositories.NewTagRepository(db),
+		jobService,
+		scraper,
+		urls,
+		searchService,
+		&cfg.Feeds,
+	)
+	jobService.Handle(models.JobTypePollFeed, feedService)
+	return feedService
+}
+
+func setupRouter(
+	cfg *config.Config,
+	jobService *services.JobService,
+	importService *services.ImportService,
+	exportService *services.ExportService,
+	feedService *services.FeedService,
+	searchService *services.SearchService,
+	events *services.ArticleEventBroker,
+	urls *urlnorm.Normalizer,
+) *gin.Engine {
 	router := gin.New()
 
 	// Initialize repositories
-	userRepo := repositories.NewUserRepository(database.GetDB())
+	db := database.GetDB()
+	userRepo := repositories.NewUserRepository(db)
+	articleRepo := repositories.NewArticleRepository(db)
+	categoryRepo := repositories.NewCategoryRepository(db)
+	tagRepo := repositories.NewTagRepository(db)
+	collectionRepo := repositories.NewSmartCollectionRepository(db)
+	importRepo := repositories.NewImportRepository(db)
+	exportRepo := repositories.NewExportRepository(db)
+	feedRepo := repositories.NewFeedRepository(db)
+	summaryRepo := repositories.NewArticleSummaryRepository(db)
+	prefRepo := repositories.NewPreferenceRepository(db)
 
 	// Initialize services
 	authService := services.NewAuthService(userRepo, &cfg.JWT)
@@ -97,6 +267,15 @@ func setupRouter(cfg *config.Config) *gin.Engine {
 	// Initialize controllers
 	healthController := controllers.NewHealthController(cfg)
 	authController := controllers.NewAuthController(authService)
+	articleController := controllers.NewArticleController(articleRepo, categoryRepo, tagRepo, jobService, searchService, events, urls)
+	categoryController := controllers.NewCategoryController(categoryRepo)
+	collectionController := controllers.NewSmartCollectionController(collectionRepo, articleRepo)
+	tagController := controllers.NewTagController(tagRepo, articleRepo, searchService)
+	importController := controllers.NewImportController(importRepo, categoryRepo, importService)
+	exportController := controllers.NewExportController(exportRepo, exportService)
+	feedController := controllers.NewFeedController(feedRepo, categoryRepo, feedService)
+	summaryController := controllers.NewSummaryController(articleRepo, summaryRepo, prefRepo, jobService)
+	preferenceController := controllers.NewPreferenceController(prefRepo)
 
 	// Initialize rate limiter
 	rateLimiter := middleware.NewRateLimiter()
@@ -127,6 +306,91 @@ func setupRouter(cfg *config.Config) *gin.Engine {
 				auth.POST("/logout", authController.Logout)
 				auth.GET("/me", middleware.AuthRequired(authService), authController.Me)
 			}
+
+			// Export downloads are authorized by the signature of the link
+			v1.GET("/exports/:id/download", exportController.DownloadExport)
+
+			// Authenticated endpoints
+			protected := v1.Group("")
+			protected.Use(middleware.AuthRequired(authService))
+			{
+				articles := protected.Group("/articles")
+				{
+					articles.POST("", articleController.SaveArticle)
+					articles.POST("/bulk", articleController.BulkUpdateArticles)
+					articles.GET("", articleController.GetArticles)
+					articles.GET("/search", articleController.SearchArticles)
+					articles.GET("/favorites", articleController.GetFavorites)
+					articles.GET("/recent", articleController.GetRecentlyRead)
+					articles.GET("/:id", articleController.GetArticle)
+					articles.GET("/:id/events", articleController.StreamArticleEvents)
+					articles.PATCH("/:id", articleController.UpdateArticle)
+					articles.DELETE("/:id", articleController.DeleteArticle)
+					articles.POST("/:id/tags", articleController.AddArticleTags)
+					articles.PUT("/:id/tags", articleController.ReplaceArticleTags)
+					articles.DELETE("/:id/tags/:tagId", articleController.RemoveArticleTag)
+					articles.GET("/:id/summaries", summaryController.GetSummaries)
+					articles.POST("/:id/summaries", summaryController.GenerateSummaries)
+				}
+
+				protected.GET("/preferences", preferenceController.GetPreferences)
+				protected.PATCH("/preferences", preferenceController.UpdatePreferences)
+
+				categories := protected.Group("/categories")
+				{
+					categories.GET("", categoryController.GetCategories)
+					categories.POST("", categoryController.CreateCategory)
+					categories.PUT("/reorder", categoryController.ReorderCategories)
+					categories.PUT("/:id", categoryController.UpdateCategory)
+					categories.POST("/:id/move", categoryController.MoveCategory)
+					categories.DELETE("/:id", categoryController.DeleteCategory)
+				}
+
+				collections := protected.Group("/collections")
+				{
+					collections.GET("", collectionController.GetCollections)
+					collections.POST("", collectionController.CreateCollection)
+					collections.PUT("/reorder", collectionController.ReorderCollections)
+					collections.PUT("/:id", collectionController.UpdateCollection)
+					collections.DELETE("/:id", collectionController.DeleteCollection)
+					collections.GET("/:id/articles", collectionController.GetCollectionArticles)
+				}
+
+				tags := protected.Group("/tags")
+				{
+					tags.GET("", tagController.GetTags)
+					tags.DELETE("/unused", tagController.DeleteUnusedTags)
+					tags.PATCH("/:id", tagController.RenameTag)
+					tags.POST("/:id/merge", tagController.MergeTag)
+					tags.DELETE("/:id", tagController.DeleteTag)
+				}
+
+				imports := protected.Group("/imports")
+				{
+					imports.POST("", importController.CreateImport)
+					imports.GET("", importController.GetImports)
+					imports.GET("/:id", importController.GetImport)
+				}
+
+				exports := protected.Group("/exports")
+				{
+					exports.POST("", exportController.CreateExport)
+					exports.GET("", exportController.GetExports)
+					exports.GET("/:id", exportController.GetExport)
+				}
+
+				feeds := protected.Group("/feeds")
+				{
+					feeds.POST("", feedController.CreateFeed)
+					feeds.GET("", feedController.GetFeeds)
+					feeds.POST("/import", feedController.ImportFeeds)
+					feeds.GET("/export", feedController.ExportFeeds)
+					feeds.GET("/:id", feedController.GetFeed)
+					feeds.PATCH("/:id", feedController.UpdateFeed)
+					feeds.DELETE("/:id", feedController.DeleteFeed)
+					feeds.POST("/:id/refresh", feedController.RefreshFeed)
+				}
+			}
 		}
 	}
 
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/eikuma/stockle/backend/internal/config"
+	"github.com/eikuma/stockle/backend/internal/extractor"
+	"github.com/eikuma/stockle/backend/internal/services"
+)
+
+// previewRunes is the length of the content shown without -full
+const previewRunes = 2000
+
+func runExtractor(args []string) error {
+	if len(args) < 1 || args[0] != "test" {
+		usage()
+		os.Exit(2)
+	}
+
+	flags := flag.NewFlagSet("extractor test", flag.ExitOnError)
+	rules := flags.String("rules", "", "YAML file or directory with site rules to try, in addition to the configured ones")
+	pageURL := flags.String("url", "", "URL the file was downloaded from, used to select the site rule")
+	showHTML := flags.Bool("html", false, "print the sanitized HTML instead of the text")
+	full := flags.Bool("full", false, "print the whole content instead of the beginning")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("extractor test takes one URL or file, got %d arguments", flags.NArg())
+	}
+	target := flags.Arg(0)
+
+	scraperCfg, err := config.LoadScraper()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	registry, err := services.LoadExtractorRegistry(scraperCfg.SiteRules)
+	if err != nil {
+		return err
+	}
+	if *rules != "" {
+		if err := registry.LoadRules(*rules); err != nil {
+			return err
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	scraper := services.NewScraperService(scraperCfg, registry)
+	var metadata *services.ArticleMetadata
+	var extractedFrom *url.URL
+	if isRemote(target) {
+		if extractedFrom, err = url.Parse(target); err != nil {
+			return fmt.Errorf("invalid URL: %w", err)
+		}
+		metadata, err = scraper.ExtractMetadata(ctx, target)
+	} else {
+		var doc *goquery.Document
+		if doc, err = readDocument(target, *pageURL); err != nil {
+			return err
+		}
+		extractedFrom = doc.Url
+		// Further pages of a saved file are not downloaded
+		metadata, err = scraper.ExtractDocument(ctx, doc, nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	source := ""
+	if site, from := registry.Lookup(extractedFrom); site != nil && site.Name() == metadata.Extractor {
+		source = from
+	}
+	return printExtraction(extractedFrom, source, metadata, *showHTML, *full)
+}
+
+func isRemote(target string) bool {
+	return strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://")
+}
+
+// readDocument parses a saved page. Without pageURL the page gets a file URL,
+// which no site rule matches.
+func readDocument(path, pageURL string) (*goquery.Document, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	doc, err := goquery.NewDocumentFromReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	if pageURL != "" {
+		doc.Url, err = url.Parse(pageURL)
+		if err != nil || doc.Url.Host == "" {
+			return nil, fmt.Errorf("invalid -url %q", pageURL)
+		}
+		return doc, nil
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	doc.Url = &url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}
+	return doc, nil
+}
+
+func printExtraction(pageURL *url.URL, source string, metadata *services.ArticleMetadata, showHTML, full bool) error {
+	extractorName := metadata.Extractor
+	if source != "" {
+		extractorName += " (" + source + ")"
+	}
+	published := "-"
+	if metadata.PublishedAt != nil {
+		published = metadata.PublishedAt.Format(time.RFC3339)
+	}
+	readingTime := time.Duration(metadata.ReadingTimeSeconds) * time.Second
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "URL\t%s\n", pageURL)
+	fmt.Fprintf(w, "EXTRACTOR\t%s\n", extractorName)
+	fmt.Fprintf(w, "TITLE\t%s\n", orDash(metadata.Title))
+	fmt.Fprintf(w, "AUTHOR\t%s\n", orDash(metadata.Author))
+	fmt.Fprintf(w, "SITE NAME\t%s\n", orDash(metadata.SiteName))
+	fmt.Fprintf(w, "PUBLISHED\t%s\n", published)
+	fmt.Fprintf(w, "LANGUAGE\t%s\n", orDash(metadata.Language))
+	fmt.Fprintf(w, "CANONICAL URL\t%s\n", orDash(metadata.CanonicalURL))
+	fmt.Fprintf(w, "KEYWORDS\t%s\n", orDash(strings.Join(metadata.Keywords, ", ")))
+	fmt.Fprintf(w, "THUMBNAIL\t%s\n", orDash(metadata.ThumbnailURL))
+	fmt.Fprintf(w, "PAGES\t%d\n", metadata.Pages)
+	fmt.Fprintf(w, "WORDS\t%d (%s to read)\n", metadata.WordCount, readingTime)
+	for _, warning := range metadata.Warnings {
+		fmt.Fprintf(w, "WARNING\t%s\n", warning)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	content := metadata.Content
+	if showHTML {
+		content = metadata.ContentHTML
+	}
+	if !full {
+		content = extractor.TruncateRunes(content, previewRunes)
+	}
+	fmt.Printf("\n%s\n", content)
+	return nil
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
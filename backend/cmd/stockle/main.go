@@ -0,0 +1,171 @@
+// Command stockle provides maintenance tasks for the Stockle backend.
+//
+// Usage:
+//
+//	stockle migrate up [-steps N]
+//	stockle migrate down [-steps N]
+//	stockle migrate redo
+//	stockle migrate status
+//	stockle extractor test [-rules path] [-url URL] [-html] [-full] <url-or-file>
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/eikuma/stockle/backend/internal/config"
+	"github.com/eikuma/stockle/backend/internal/database"
+	"github.com/eikuma/stockle/backend/internal/urlnorm"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "migrate":
+		err = runMigrate(os.Args[2:])
+	case "extractor":
+		err = runExtractor(os.Args[2:])
+	case "help", "-h", "--help":
+		usage()
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `Usage: stockle <command> [arguments]
+
+Commands:
+  migrate up [-steps N]     apply pending migrations (all by default)
+  migrate down [-steps N]   revert applied migrations (one by default)
+  migrate redo              revert and re-apply the latest migration
+  migrate status            list migrations and whether they are applied
+  extractor test [flags] <url-or-file>
+                            show which site rule matches a page and what it extracts
+                            -rules path  also load site rules from a YAML file or directory
+                            -url URL     URL a saved file was downloaded from
+                            -html        print the sanitized HTML instead of the text
+                            -full        print the whole content`)
+}
+
+func runMigrate(args []string) error {
+	if len(args) < 1 {
+		usage()
+		os.Exit(2)
+	}
+
+	flags := flag.NewFlagSet("migrate "+args[0], flag.ExitOnError)
+	steps := flags.Int("steps", 0, "number of migrations to apply or revert")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if err := database.Connect(cfg); err != nil {
+		return err
+	}
+	defer database.Close()
+
+	db := database.GetDB()
+	migrator, err := database.NewSchemaMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		if err := database.MigrateUserIDsToUUID(db); err != nil {
+			return fmt.Errorf("failed to migrate user IDs: %w", err)
+		}
+		applied, err := migrator.Up(*steps)
+		if err != nil {
+			return err
+		}
+		for _, migration := range applied {
+			fmt.Printf("applied %06d_%s\n", migration.Version, migration.Name)
+		}
+		if len(applied) == 0 {
+			fmt.Println("no pending migrations")
+		}
+		if err := database.CheckSchema(db, database.Models()...); err != nil {
+			return err
+		}
+		urls, err := urlnorm.Load(cfg.Scraper.URLRules)
+		if err != nil {
+			return err
+		}
+		if err := database.BackfillArticleURLHashes(db, urls); err != nil {
+			return err
+		}
+		return database.BackfillTagNameKeys(db)
+
+	case "down":
+		reverted, err := migrator.Down(*steps)
+		if err != nil {
+			return err
+		}
+		for _, migration := range reverted {
+			fmt.Printf("reverted %06d_%s\n", migration.Version, migration.Name)
+		}
+		if len(reverted) == 0 {
+			fmt.Println("no applied migrations")
+		}
+		return nil
+
+	case "redo":
+		migration, err := migrator.Redo()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("redid %06d_%s\n", migration.Version, migration.Name)
+		return nil
+
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		return printStatus(statuses)
+	}
+
+	return fmt.Errorf("unknown migrate command %q", args[0])
+}
+
+func printStatus(statuses []database.MigrationStatus) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, status := range statuses {
+		state := "pending"
+		appliedAt := "-"
+		if status.Applied {
+			state = "applied"
+			appliedAt = status.AppliedAt.Local().Format("2006-01-02 15:04:05")
+		}
+		if status.Modified {
+			state += " (modified)"
+		}
+		if status.Missing {
+			state += " (missing file)"
+		}
+		fmt.Fprintf(w, "%06d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+	}
+	return w.Flush()
+}
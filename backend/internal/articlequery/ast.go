@@ -0,0 +1,218 @@
+// Package articlequery parses the query language used to filter articles,
+// e.g.
+//
+//	tag:go -tag:draft site:zenn.dev saved:>2026-01-01 is:favorite "exact phrase" OR rust
+//
+// Terms next to each other must all match, OR binds looser than that, a
+// leading "-" negates a term or a parenthesized group. The result is an AST
+// that the repositories compile to SQL, so the same syntax filters the
+// article list, saved searches and bulk operations.
+package articlequery
+
+import (
+	"strings"
+	"time"
+)
+
+// Fields that a term can filter on
+const (
+	FieldTag       = "tag"
+	FieldSite      = "site"
+	FieldStatus    = "status"
+	FieldIs        = "is"
+	FieldLang      = "lang"
+	FieldCategory  = "category"
+	FieldSaved     = "saved"
+	FieldPublished = "published"
+)
+
+// Values of is:
+const (
+	IsFavorite = "favorite"
+	IsUnread   = "unread"
+	IsRead     = "read"
+	IsArchived = "archived"
+)
+
+// Node is an expression of the query: *And, *Or, *Not or *Term
+type Node interface {
+	// Offset is the character offset of the expression in the query
+	Offset() int
+	write(b *strings.Builder, parentPrecedence int)
+}
+
+// And matches when all of its nodes match
+type And struct {
+	Nodes  []Node
+	offset int
+}
+
+// Or matches when any of its nodes matches
+type Or struct {
+	Nodes  []Node
+	offset int
+}
+
+// Not matches when its node does not
+type Not struct {
+	Node   Node
+	offset int
+}
+
+// Term is a single condition. Without a field it is free text: a word, or
+// a phrase when quoted, that must occur in the article.
+type Term struct {
+	Field  string
+	Value  string
+	Phrase bool
+	// From and To bound the dates of the saved and published fields: the
+	// date must be at or after From and before To. Either may be nil.
+	From *time.Time
+	To   *time.Time
+	// Comparison is the operator of a date term as written, e.g. ">="
+	Comparison string
+
+	offset int
+}
+
+func (n *And) Offset() int  { return n.offset }
+func (n *Or) Offset() int   { return n.offset }
+func (n *Not) Offset() int  { return n.offset }
+func (n *Term) Offset() int { return n.offset }
+
+// IsText reports whether the term is free text rather than a field filter
+func (t *Term) IsText() bool {
+	return t.Field == ""
+}
+
+// Query is a parsed query
+type Query struct {
+	// Root is nil for a query without terms, which matches every article
+	Root Node
+}
+
+// String returns the query in a normalized form that parses to the same AST
+func (q *Query) String() string {
+	if q == nil || q.Root == nil {
+		return ""
+	}
+	var b strings.Builder
+	q.Root.write(&b, precedenceOr)
+	return b.String()
+}
+
+// Match evaluates the query with match deciding each term. It lets code
+// that does not use SQL apply the same boolean logic.
+func (q *Query) Match(match func(t *Term) bool) bool {
+	if q == nil || q.Root == nil {
+		return true
+	}
+	return evaluate(q.Root, match)
+}
+
+func evaluate(node Node, match func(t *Term) bool) bool {
+	switch n := node.(type) {
+	case *And:
+		for _, child := range n.Nodes {
+			if !evaluate(child, match) {
+				return false
+			}
+		}
+		return true
+	case *Or:
+		for _, child := range n.Nodes {
+			if evaluate(child, match) {
+				return true
+			}
+		}
+		return false
+	case *Not:
+		return !evaluate(n.Node, match)
+	case *Term:
+		return match(n)
+	}
+	return false
+}
+
+// Terms returns every term of the query, including negated ones
+func (q *Query) Terms() []*Term {
+	var terms []*Term
+	if q == nil || q.Root == nil {
+		return terms
+	}
+	var walk func(node Node)
+	walk = func(node Node) {
+		switch n := node.(type) {
+		case *And:
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *Or:
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *Not:
+			walk(n.Node)
+		case *Term:
+			terms = append(terms, n)
+		}
+	}
+	walk(q.Root)
+	return terms
+}
+
+// Precedence of the operators, for writing parentheses only where needed
+const (
+	precedenceOr = iota
+	precedenceAnd
+	precedenceNot
+)
+
+func (n *And) write(b *strings.Builder, parent int) {
+	writeGroup(b, n.Nodes, " ", precedenceAnd, parent)
+}
+
+func (n *Or) write(b *strings.Builder, parent int) {
+	writeGroup(b, n.Nodes, " OR ", precedenceOr, parent)
+}
+
+func writeGroup(b *strings.Builder, nodes []Node, separator string, precedence, parent int) {
+	if precedence < parent {
+		b.WriteByte('(')
+	}
+	for i, node := range nodes {
+		if i > 0 {
+			b.WriteString(separator)
+		}
+		node.write(b, precedence+1)
+	}
+	if precedence < parent {
+		b.WriteByte(')')
+	}
+}
+
+func (n *Not) write(b *strings.Builder, _ int) {
+	b.WriteByte('-')
+	n.Node.write(b, precedenceNot)
+}
+
+func (t *Term) write(b *strings.Builder, _ int) {
+	if t.Field != "" {
+		b.WriteString(t.Field)
+		b.WriteByte(':')
+		b.WriteString(t.Comparison)
+	}
+	if t.Phrase || needsQuotes(t.Value) {
+		b.WriteByte('"')
+		b.WriteString(strings.ReplaceAll(t.Value, `"`, ""))
+		b.WriteByte('"')
+		return
+	}
+	b.WriteString(t.Value)
+}
+
+// needsQuotes reports whether a value would not be read back as one word
+func needsQuotes(value string) bool {
+	return value == "OR" || value == "AND" || strings.HasPrefix(value, "-") ||
+		strings.ContainsAny(value, " \t\n\"()") || (value != "" && strings.Contains(value, ":"))
+}
@@ -0,0 +1,404 @@
+package articlequery
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/eikuma/stockle/backend/internal/search"
+)
+
+// SyntaxError points at the part of a query that could not be understood
+type SyntaxError struct {
+	// Offset is the character offset of Token in the query
+	Offset  int
+	Token   string
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	if e.Token == "" {
+		return fmt.Sprintf("%s at position %d", e.Message, e.Offset)
+	}
+	return fmt.Sprintf("%s at position %d: %s", e.Message, e.Offset, e.Token)
+}
+
+type tokenKind int
+
+const (
+	tokenEnd tokenKind = iota
+	tokenTerm
+	tokenOr
+	tokenNot
+	tokenOpen
+	tokenClose
+)
+
+type token struct {
+	kind tokenKind
+	// text is the token as written, for error messages
+	text   string
+	offset int
+
+	// Parts of a term
+	field  string
+	value  string
+	phrase bool
+}
+
+// Parse parses a query. An empty query is valid and matches every article.
+func Parse(input string) (*Query, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokenEnd {
+		return &Query{}, nil
+	}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if next := p.peek(); next.kind != tokenEnd {
+		return nil, &SyntaxError{Offset: next.offset, Token: next.text, Message: "unexpected closing parenthesis"}
+	}
+	return &Query{Root: root}, nil
+}
+
+// MustParse is like Parse but panics on an error. It is meant for queries
+// written in code.
+func MustParse(input string) *Query {
+	q, err := Parse(input)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
+
+func lex(input string) ([]token, error) {
+	runes := []rune(input)
+	var tokens []token
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenOpen, text: "(", offset: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenClose, text: ")", offset: i})
+			i++
+		case r == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]):
+			tokens = append(tokens, token{kind: tokenNot, text: "-", offset: i})
+			i++
+		case r == '"':
+			value, end, err := lexQuoted(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokenTerm, text: string(runes[i:end]), offset: i, value: value, phrase: true})
+			i = end
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '(' && runes[i] != ')' && runes[i] != '"' {
+				i++
+			}
+			word := string(runes[start:i])
+
+			if word == "OR" {
+				tokens = append(tokens, token{kind: tokenOr, text: word, offset: start})
+				continue
+			}
+			if word == "AND" {
+				// Terms are combined with AND anyway
+				continue
+			}
+
+			t := token{kind: tokenTerm, text: word, offset: start, value: word}
+			if field, value, ok := splitField(word); ok {
+				t.field, t.value = field, value
+				// A quoted value directly after the colon belongs to the field
+				if value == "" && i < len(runes) && runes[i] == '"' {
+					quoted, end, err := lexQuoted(runes, i)
+					if err != nil {
+						return nil, err
+					}
+					t.value = quoted
+					t.text = string(runes[start:end])
+					i = end
+				}
+			}
+			tokens = append(tokens, t)
+		}
+	}
+	return append(tokens, token{kind: tokenEnd, offset: len(runes)}), nil
+}
+
+// lexQuoted reads the quoted string starting at runes[start]
+func lexQuoted(runes []rune, start int) (string, int, error) {
+	for end := start + 1; end < len(runes); end++ {
+		if runes[end] == '"' {
+			return string(runes[start+1 : end]), end + 1, nil
+		}
+	}
+	return "", 0, &SyntaxError{Offset: start, Token: string(runes[start:]), Message: "missing closing quote"}
+}
+
+// splitField splits field:value. The field is made of ASCII letters; words
+// such as URLs whose value starts with // are not fields.
+func splitField(word string) (string, string, bool) {
+	colon := strings.IndexByte(word, ':')
+	if colon <= 0 {
+		return "", "", false
+	}
+	for _, r := range word[:colon] {
+		if r > unicode.MaxASCII || !unicode.IsLetter(r) {
+			return "", "", false
+		}
+	}
+	value := word[colon+1:]
+	if strings.HasPrefix(value, "//") {
+		return "", "", false
+	}
+	return strings.ToLower(word[:colon]), value, true
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEnd {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) parseOr() (Node, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	nodes := []Node{first}
+	for p.peek().kind == tokenOr {
+		or := p.next()
+		if kind := p.peek().kind; kind == tokenEnd || kind == tokenClose || kind == tokenOr {
+			return nil, &SyntaxError{Offset: or.offset, Token: or.text, Message: "OR must be followed by a term"}
+		}
+		node, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	if len(nodes) == 1 {
+		return first, nil
+	}
+	return &Or{Nodes: nodes, offset: first.Offset()}, nil
+}
+
+func (p *parser) parseAnd() (Node, error) {
+	var nodes []Node
+	for {
+		next := p.peek()
+		if next.kind == tokenEnd || next.kind == tokenOr || next.kind == tokenClose {
+			break
+		}
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+
+	switch len(nodes) {
+	case 0:
+		next := p.peek()
+		if next.kind == tokenOr {
+			return nil, &SyntaxError{Offset: next.offset, Token: next.text, Message: "OR must follow a term"}
+		}
+		if next.kind == tokenClose {
+			return nil, &SyntaxError{Offset: next.offset, Token: next.text, Message: "empty parentheses"}
+		}
+		return nil, &SyntaxError{Offset: next.offset, Message: "a term is missing"}
+	case 1:
+		return nodes[0], nil
+	}
+	return &And{Nodes: nodes, offset: nodes[0].Offset()}, nil
+}
+
+func (p *parser) parseUnary() (Node, error) {
+	if p.peek().kind != tokenNot {
+		return p.parsePrimary()
+	}
+	not := p.next()
+	if kind := p.peek().kind; kind == tokenEnd || kind == tokenOr || kind == tokenClose {
+		return nil, &SyntaxError{Offset: not.offset, Token: not.text, Message: "- must be followed by a term"}
+	}
+	node, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return &Not{Node: node, offset: not.offset}, nil
+}
+
+func (p *parser) parsePrimary() (Node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenOpen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind != tokenClose {
+			return nil, &SyntaxError{Offset: t.offset, Token: t.text, Message: "missing closing parenthesis"}
+		}
+		p.next()
+		return node, nil
+	case tokenTerm:
+		return newTerm(t)
+	}
+	return nil, &SyntaxError{Offset: t.offset, Token: t.text, Message: "unexpected token"}
+}
+
+// newTerm validates a term token and normalizes its value
+func newTerm(t token) (*Term, error) {
+	term := &Term{Field: t.field, Value: strings.TrimSpace(t.value), Phrase: t.phrase, offset: t.offset}
+	fail := func(format string, args ...interface{}) (*Term, error) {
+		return nil, &SyntaxError{Offset: t.offset, Token: t.text, Message: fmt.Sprintf(format, args...)}
+	}
+
+	if term.Value == "" {
+		if term.Field == "" {
+			return fail("empty phrase")
+		}
+		return fail("%s: needs a value", term.Field)
+	}
+
+	switch term.Field {
+	case "":
+		if len(search.QueryTerms(term.Value)) == 0 {
+			return fail("nothing to search for")
+		}
+	case FieldTag, FieldCategory:
+	case FieldSite:
+		site := strings.ToLower(term.Value)
+		site = strings.TrimPrefix(strings.TrimPrefix(site, "https://"), "http://")
+		site = strings.TrimPrefix(strings.TrimSuffix(site, "/"), "www.")
+		if site == "" || strings.ContainsAny(site, "/?#% ") {
+			return fail("site: must be a host name such as zenn.dev")
+		}
+		term.Value = site
+	case FieldStatus:
+		term.Value = strings.ToLower(term.Value)
+		if term.Value != IsUnread && term.Value != IsRead && term.Value != IsArchived {
+			return fail("status: must be unread, read or archived")
+		}
+	case FieldIs:
+		term.Value = strings.ToLower(term.Value)
+		switch term.Value {
+		case IsFavorite, IsUnread, IsRead, IsArchived:
+		default:
+			return fail("is: must be favorite, unread, read or archived")
+		}
+	case FieldLang:
+		term.Value = strings.ToLower(term.Value)
+		for _, r := range term.Value {
+			if (r < 'a' || r > 'z') && r != '-' {
+				return fail("lang: must be a language code such as ja or en")
+			}
+		}
+	case FieldSaved, FieldPublished:
+		if err := parseDateTerm(term); err != nil {
+			return fail("%s: %v", term.Field, err)
+		}
+	default:
+		return fail("unknown field %s:, use one of tag, site, status, is, lang, category, saved or published", term.Field)
+	}
+	return term, nil
+}
+
+// dateLayouts are the accepted date precisions; a date covers its whole day,
+// month or year
+var dateLayouts = []struct {
+	layout string
+	next   func(time.Time) time.Time
+}{
+	{"2006-01-02", func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }},
+	{"2006-01", func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }},
+	{"2006", func(t time.Time) time.Time { return t.AddDate(1, 0, 0) }},
+}
+
+// parseDateTerm sets the bounds of a date term such as >=2026-01,
+// 2026-01-01 or 2026-01-01..2026-03-31. Dates are in UTC.
+func parseDateTerm(term *Term) error {
+	value := term.Value
+	for _, op := range []string{">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(value, op) {
+			term.Comparison = op
+			value = value[len(op):]
+			break
+		}
+	}
+
+	if from, to, ok := strings.Cut(value, ".."); ok {
+		if term.Comparison != "" {
+			return fmt.Errorf("a range cannot be combined with %s", term.Comparison)
+		}
+		start, _, err := parseDate(from)
+		if err != nil {
+			return err
+		}
+		_, end, err := parseDate(to)
+		if err != nil {
+			return err
+		}
+		if !start.Before(end) {
+			return fmt.Errorf("the range ends before it starts")
+		}
+		term.From, term.To = &start, &end
+		term.Value = value
+		return nil
+	}
+
+	start, end, err := parseDate(value)
+	if err != nil {
+		return err
+	}
+	switch term.Comparison {
+	case ">":
+		term.From = &end
+	case ">=":
+		term.From = &start
+	case "<":
+		term.To = &start
+	case "<=":
+		term.To = &end
+	default:
+		term.Comparison = ""
+		term.From, term.To = &start, &end
+	}
+	term.Value = value
+	return nil
+}
+
+// parseDate returns the period a date covers
+func parseDate(value string) (time.Time, time.Time, error) {
+	for _, d := range dateLayouts {
+		if start, err := time.ParseInLocation(d.layout, value, time.UTC); err == nil {
+			return start, d.next(start), nil
+		}
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("%q is not a date like 2026-01-31, 2026-01 or 2026", value)
+}
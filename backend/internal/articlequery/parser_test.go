@@ -0,0 +1,135 @@
+package articlequery
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{name: "terms are combined with AND", query: "tag:go  -tag:draft is:favorite", want: "tag:go -tag:draft is:favorite"},
+		{name: "OR binds looser than AND", query: "tag:go site:zenn.dev OR tag:rust", want: "tag:go site:zenn.dev OR tag:rust"},
+		{name: "parentheses group", query: "(tag:go OR tag:rust) is:unread", want: "(tag:go OR tag:rust) is:unread"},
+		{name: "negated group", query: "-(tag:draft OR status:archived)", want: "-(tag:draft OR status:archived)"},
+		{name: "redundant parentheses are dropped", query: "((tag:go))", want: "tag:go"},
+		{name: "AND keyword is optional", query: "go AND rust", want: "go rust"},
+		{name: "phrases keep their quotes", query: `"exact phrase" OR 並行処理`, want: `"exact phrase" OR 並行処理`},
+		{name: "quoted field values", query: `tag:"machine learning" category:"Tech Notes"`, want: `tag:"machine learning" category:"Tech Notes"`},
+		{name: "fields and keywords are case-insensitive", query: "TAG:Go IS:Favorite Status:READ", want: "tag:Go is:favorite status:read"},
+		{name: "sites are reduced to the host", query: "site:https://www.Zenn.dev/", want: "site:zenn.dev"},
+		{name: "dates keep their comparison", query: "saved:>2026-01-01 published:<=2025", want: "saved:>2026-01-01 published:<=2025"},
+		{name: "date ranges", query: "saved:2026-01..2026-03", want: "saved:2026-01..2026-03"},
+		{name: "URLs are text", query: "https://example.com", want: `"https://example.com"`},
+		{name: "hyphen inside a word", query: "e-mail", want: "e-mail"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := Parse(tt.query)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, q.String())
+
+			// The normalized form reads back the same
+			again, err := Parse(q.String())
+			require.NoError(t, err)
+			assert.Equal(t, q.String(), again.String())
+		})
+	}
+}
+
+func TestParse_Empty(t *testing.T) {
+	q, err := Parse("  ")
+	require.NoError(t, err)
+	assert.Nil(t, q.Root)
+	assert.True(t, q.Match(func(*Term) bool { return false }))
+}
+
+func TestParse_Dates(t *testing.T) {
+	day := func(s string) *time.Time {
+		d, err := time.Parse("2006-01-02", s)
+		require.NoError(t, err)
+		return &d
+	}
+	tests := []struct {
+		query    string
+		from, to *time.Time
+	}{
+		{query: "saved:2026-01-15", from: day("2026-01-15"), to: day("2026-01-16")},
+		{query: "saved:2026-01", from: day("2026-01-01"), to: day("2026-02-01")},
+		{query: "saved:>2026-01-15", from: day("2026-01-16")},
+		{query: "saved:>=2026-01-15", from: day("2026-01-15")},
+		{query: "saved:<2026", to: day("2026-01-01")},
+		{query: "saved:<=2026", to: day("2027-01-01")},
+		{query: "published:2025-12..2026-01-10", from: day("2025-12-01"), to: day("2026-01-11")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := Parse(tt.query)
+			require.NoError(t, err)
+			term := q.Terms()[0]
+			assert.Equal(t, tt.from, term.From)
+			assert.Equal(t, tt.to, term.To)
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		offset int
+		token  string
+	}{
+		{name: "unknown field", query: "tag:go colour:red", offset: 7, token: "colour:red"},
+		{name: "missing value", query: "tag:", offset: 0, token: "tag:"},
+		{name: "invalid status", query: "status:done", offset: 0, token: "status:done"},
+		{name: "invalid is", query: "is:starred", offset: 0, token: "is:starred"},
+		{name: "invalid date", query: "saved:>yesterday", offset: 0, token: "saved:>yesterday"},
+		{name: "invalid site", query: "site:zenn.dev/articles", offset: 0, token: "site:zenn.dev/articles"},
+		{name: "missing closing quote", query: `go "exact`, offset: 3, token: `"exact`},
+		{name: "missing closing parenthesis", query: "(tag:go OR tag:rust", offset: 0, token: "("},
+		{name: "unexpected closing parenthesis", query: "tag:go)", offset: 6, token: ")"},
+		{name: "empty parentheses", query: "go ()", offset: 4, token: ")"},
+		{name: "dangling OR", query: "tag:go OR", offset: 7, token: "OR"},
+		{name: "leading OR", query: "OR tag:go", offset: 0, token: "OR"},
+		{name: "dangling negation", query: "go -(", offset: 5},
+		{name: "offsets count characters", query: "日本語 lang:日本", offset: 4, token: "lang:日本"},
+		{name: "nothing to search", query: "go ???", offset: 3, token: "???"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.query)
+			var syntaxErr *SyntaxError
+			require.True(t, errors.As(err, &syntaxErr), "%v", err)
+			assert.Equal(t, tt.offset, syntaxErr.Offset, syntaxErr.Message)
+			assert.Equal(t, tt.token, syntaxErr.Token, syntaxErr.Message)
+			assert.NotEmpty(t, syntaxErr.Message)
+		})
+	}
+}
+
+func TestQuery_Match(t *testing.T) {
+	q := MustParse("(tag:go OR tag:rust) -tag:draft")
+	tags := func(names ...string) func(*Term) bool {
+		return func(term *Term) bool {
+			for _, name := range names {
+				if name == term.Value {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	assert.True(t, q.Match(tags("go")))
+	assert.True(t, q.Match(tags("rust", "web")))
+	assert.False(t, q.Match(tags("go", "draft")))
+	assert.False(t, q.Match(tags("python")))
+	assert.Len(t, q.Terms(), 3)
+}
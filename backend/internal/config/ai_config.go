@@ -6,13 +6,158 @@ import (
 	"time"
 )
 
+// Types of AI providers
+const (
+	AIProviderGroq      = "groq"
+	AIProviderAnthropic = "anthropic"
+	// AIProviderOpenAI is any server of the OpenAI chat completions API,
+	// such as a local llama.cpp or Ollama server
+	AIProviderOpenAI = "openai"
+)
+
 type AIConfig struct {
-	GroqAPIKey       string        `mapstructure:"groq_api_key"`
-	AnthropicAPIKey  string        `mapstructure:"anthropic_api_key"`
-	RequestTimeout   time.Duration `mapstructure:"request_timeout"`
-	MaxRetries       int           `mapstructure:"max_retries"`
-	RetryDelay       time.Duration `mapstructure:"retry_delay"`
-	RateLimitPerMin  int           `mapstructure:"rate_limit_per_min"`
+	GroqAPIKey      string `mapstructure:"groq_api_key"`
+	AnthropicAPIKey string `mapstructure:"anthropic_api_key"`
+	OpenAIBaseURL   string `mapstructure:"openai_base_url"`
+	OpenAIAPIKey    string `mapstructure:"openai_api_key"`
+	OpenAIModel     string `mapstructure:"openai_model"`
+	// RequestTimeout limits each request to a provider
+	RequestTimeout time.Duration `mapstructure:"request_timeout"`
+	// MaxRetries is the number of retries of a request failing on rate
+	// limits, overload, timeouts or server errors, waiting RetryDelay
+	// doubled at each retry, with jitter, up to MaxRetryDelay
+	MaxRetries    int           `mapstructure:"max_retries"`
+	RetryDelay    time.Duration `mapstructure:"retry_delay"`
+	MaxRetryDelay time.Duration `mapstructure:"max_retry_delay"`
+	// RateLimitPerMin is the number of requests a minute sent to each
+	// provider, unless the provider sets its own
+	RateLimitPerMin int `mapstructure:"rate_limit_per_min"`
+	// CircuitBreakerThreshold failed requests in a row to a provider skip
+	// it for CircuitBreakerCooldown; 0 disables the circuit breaker
+	CircuitBreakerThreshold int           `mapstructure:"circuit_breaker_threshold"`
+	CircuitBreakerCooldown  time.Duration `mapstructure:"circuit_breaker_cooldown"`
+	// ChunkTokens caps the size of the chunks a long article is split into,
+	// which otherwise fill the context window of the smallest provider
+	ChunkTokens int `mapstructure:"chunk_tokens"`
+	// ChunkConcurrency is the number of chunks of an article summarized at
+	// once
+	ChunkConcurrency int `mapstructure:"chunk_concurrency"`
+	// MinConfidence is the quality score below which a summary is generated
+	// again by the next provider of the chain
+	MinConfidence float64 `mapstructure:"min_confidence"`
+	// JudgeProvider names the provider asked to score every summary against
+	// its article; no provider is asked when empty
+	JudgeProvider string `mapstructure:"judge_provider"`
+	// Providers is the chain of providers tried in order for a summary.
+	// When empty, see ProviderChain, the chain is built from the API keys
+	Providers []AIProviderConfig `mapstructure:"providers"`
+	// Routes pick another chain for some summaries; the first matching
+	// route wins and the summaries matching none use the whole chain
+	Routes []AIRouteConfig `mapstructure:"routes"`
+}
+
+type AIProviderConfig struct {
+	// Name identifies the provider in routes and on the generated summaries
+	Name string `mapstructure:"name"`
+	// Type is one of groq, anthropic and openai
+	Type string `mapstructure:"type"`
+	// BaseURL overrides the endpoint of the API; openai providers need one
+	BaseURL string `mapstructure:"base_url"`
+	// APIKey defaults to the key of the type, such as GROQ_API_KEY
+	APIKey    string `mapstructure:"api_key"`
+	Model     string `mapstructure:"model"`
+	MaxTokens int    `mapstructure:"max_tokens"`
+	// Temperature is left to the provider when unset
+	Temperature *float64 `mapstructure:"temperature"`
+	// RateLimitPerMin overrides the rate limit of AIConfig for the provider
+	RateLimitPerMin int `mapstructure:"rate_limit_per_min"`
+	// ContextWindow is the number of tokens the model reads and writes at
+	// most, 8192 when unset; longer articles are summarized in chunks
+	ContextWindow int `mapstructure:"context_window"`
+}
+
+// AIRouteConfig sends the summaries matching all of its conditions to its
+// own chain of providers; an empty condition matches every summary
+type AIRouteConfig struct {
+	Name string `mapstructure:"name"`
+	// Languages match the language of the article, "en" matching "en-US" too
+	Languages    []string `mapstructure:"languages"`
+	SummaryTypes []string `mapstructure:"summary_types"`
+	// MinContentLength and MaxContentLength bound the length of the article
+	// in characters; 0 leaves the bound open
+	MinContentLength int `mapstructure:"min_content_length"`
+	MaxContentLength int `mapstructure:"max_content_length"`
+	// Providers are the names of the providers tried in order
+	Providers []string `mapstructure:"providers"`
+}
+
+// ProviderChain returns the configured providers with their API keys
+// filled in, or when none is configured the default chain: Groq, then
+// Claude, then the OpenAI compatible server, each when its key or URL is set
+func (c *AIConfig) ProviderChain() []AIProviderConfig {
+	if len(c.Providers) == 0 {
+		var chain []AIProviderConfig
+		if c.GroqAPIKey != "" {
+			chain = append(chain, AIProviderConfig{
+				Name:          "groq",
+				Type:          AIProviderGroq,
+				Model:         "llama3-8b-8192",
+				MaxTokens:     500,
+				Temperature:   floatPtr(0.3),
+				ContextWindow: 8192,
+			})
+		}
+		if c.AnthropicAPIKey != "" {
+			chain = append(chain, AIProviderConfig{
+				Name:          "claude",
+				Type:          AIProviderAnthropic,
+				Model:         "claude-3-haiku-20240307",
+				MaxTokens:     500,
+				ContextWindow: 200000,
+			})
+		}
+		if c.OpenAIBaseURL != "" {
+			chain = append(chain, AIProviderConfig{
+				Name:        "local",
+				Type:        AIProviderOpenAI,
+				Model:       c.OpenAIModel,
+				MaxTokens:   500,
+				Temperature: floatPtr(0.3),
+			})
+		}
+		return c.withDefaultKeys(chain)
+	}
+	return c.withDefaultKeys(append([]AIProviderConfig(nil), c.Providers...))
+}
+
+func (c *AIConfig) withDefaultKeys(chain []AIProviderConfig) []AIProviderConfig {
+	for i := range chain {
+		provider := &chain[i]
+		switch provider.Type {
+		case AIProviderGroq:
+			provider.APIKey = firstNonEmpty(provider.APIKey, c.GroqAPIKey)
+		case AIProviderAnthropic:
+			provider.APIKey = firstNonEmpty(provider.APIKey, c.AnthropicAPIKey)
+		case AIProviderOpenAI:
+			provider.APIKey = firstNonEmpty(provider.APIKey, c.OpenAIAPIKey)
+			provider.BaseURL = firstNonEmpty(provider.BaseURL, c.OpenAIBaseURL)
+			provider.Model = firstNonEmpty(provider.Model, c.OpenAIModel)
+		}
+	}
+	return chain
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
 }
 
 func NewAIConfig() (*AIConfig, error) {
@@ -30,9 +175,18 @@ func NewAIConfig() (*AIConfig, error) {
 	return &AIConfig{
 		GroqAPIKey:      groqKey,
 		AnthropicAPIKey: anthropicKey,
+		OpenAIBaseURL:   os.Getenv("OPENAI_BASE_URL"),
+		OpenAIAPIKey:    os.Getenv("OPENAI_API_KEY"),
+		OpenAIModel:     os.Getenv("OPENAI_MODEL"),
 		RequestTimeout:  30 * time.Second,
 		MaxRetries:      3,
 		RetryDelay:      1 * time.Second,
+		MaxRetryDelay:   30 * time.Second,
 		RateLimitPerMin: 100,
+
+		CircuitBreakerThreshold: 5,
+		CircuitBreakerCooldown:  time.Minute,
+		ChunkTokens:             3000,
+		ChunkConcurrency:        3,
 	}, nil
-}
\ No newline at end of file
+}
@@ -13,6 +13,10 @@ type Config struct {
 	JWT      JWTConfig      `mapstructure:"jwt"`
 	Log      LogConfig      `mapstructure:"log"`
 	AI       AIConfig       `mapstructure:"ai"`
+	Jobs     JobsConfig     `mapstructure:"jobs"`
+	Scraper  ScraperConfig  `mapstructure:"scraper"`
+	Exports  ExportsConfig  `mapstructure:"exports"`
+	Feeds    FeedsConfig    `mapstructure:"feeds"`
 }
 
 type ServerConfig struct {
@@ -30,19 +34,39 @@ type CORSConfig struct {
 }
 
 type DatabaseConfig struct {
+	// Driver is one of mysql, sqlite or postgres
+	Driver          string        `mapstructure:"driver"`
+	// URL is a complete driver-specific DSN that takes precedence over the fields below
+	URL             string        `mapstructure:"url"`
 	Host            string        `mapstructure:"host"`
 	Port            int           `mapstructure:"port"`
 	User            string        `mapstructure:"user"`
 	Password        string        `mapstructure:"password"`
+	// Database is the database name, or the file path (or :memory:) for sqlite
 	Database        string        `mapstructure:"database"`
+	SSLMode         string        `mapstructure:"ssl_mode"`
 	MaxOpenConns    int           `mapstructure:"max_open_conns"`
 	MaxIdleConns    int           `mapstructure:"max_idle_conns"`
 	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime"`
+	AutoMigrate     bool          `mapstructure:"auto_migrate"`
 }
 
 func (d DatabaseConfig) DSN() string {
-	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		d.User, d.Password, d.Host, d.Port, d.Database)
+	if d.URL != "" {
+		return d.URL
+	}
+	
+	switch d.Driver {
+	case "sqlite":
+		// Enforce foreign keys and wait on locks instead of failing with SQLITE_BUSY
+		return fmt.Sprintf("%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)", d.Database)
+	case "postgres":
+		return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s TimeZone=UTC",
+			d.Host, d.Port, d.User, d.Password, d.Database, d.SSLMode)
+	default:
+		return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+			d.User, d.Password, d.Host, d.Port, d.Database)
+	}
 }
 
 type JWTConfig struct {
@@ -61,11 +85,78 @@ type LogConfig struct {
 	Format string `mapstructure:"format"` // json or console
 }
 
+type JobsConfig struct {
+	// Workers is the number of background job workers started with the API; 0 disables them
+	Workers int `mapstructure:"workers"`
+	// StaleAfter is how long a job may run without a heartbeat before it is
+	// taken to be abandoned by a stopped worker and run again; 0 never does
+	StaleAfter time.Duration `mapstructure:"stale_after"`
+}
+
+type ScraperConfig struct {
+	// MaxConcurrency bounds the number of pages fetched at once across all hosts
+	MaxConcurrency int `mapstructure:"max_concurrency"`
+	// PerHostParallelism bounds the number of pages fetched at once from a single host
+	PerHostParallelism int `mapstructure:"per_host_parallelism"`
+	// PerHostDelay is the minimum time between two requests to the same host
+	PerHostDelay time.Duration `mapstructure:"per_host_delay"`
+	// RequestTimeout is the deadline of one extraction, including the wait for a free slot
+	RequestTimeout time.Duration `mapstructure:"request_timeout"`
+	// SiteRules is a YAML file or a directory of YAML files with site extraction rules
+	SiteRules string `mapstructure:"site_rules"`
+	// URLRules is a YAML file with per-site URL normalization rules used to detect duplicate articles
+	URLRules string `mapstructure:"url_rules"`
+}
+
+type ExportsConfig struct {
+	// Dir is where export files are written; empty uses a directory under the system temp dir
+	Dir string `mapstructure:"dir"`
+	// Retention is how long a finished export file is kept before it is deleted
+	Retention time.Duration `mapstructure:"retention"`
+	// LinkExpiry is how long a signed download link stays valid
+	LinkExpiry time.Duration `mapstructure:"link_expiry"`
+	// SigningKey signs download links; empty falls back to the JWT access secret
+	SigningKey string `mapstructure:"signing_key"`
+}
+
+type FeedsConfig struct {
+	// PollInterval is the time between two fetches of a feed
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+	// MaxBackoff caps the time to the next fetch of a feed that keeps failing
+	MaxBackoff time.Duration `mapstructure:"max_backoff"`
+	// SchedulerInterval is how often the scheduler looks for feeds that are due
+	SchedulerInterval time.Duration `mapstructure:"scheduler_interval"`
+}
+
 // AIConfig is defined in ai_config.go to avoid duplication
 
 var cfg *Config
 
 func Load() (*Config, error) {
+	config, err := read()
+	if err != nil {
+		return nil, err
+	}
+	
+	if err := validateConfig(config); err != nil {
+		return nil, fmt.Errorf("config validation failed: %w", err)
+	}
+	
+	cfg = config
+	return cfg, nil
+}
+
+// LoadScraper reads only the scraper settings, for tools that run without
+// the database and the JWT secrets
+func LoadScraper() (*ScraperConfig, error) {
+	config, err := read()
+	if err != nil {
+		return nil, err
+	}
+	return &config.Scraper, nil
+}
+
+func read() (*Config, error) {
 	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
 	viper.AddConfigPath(".")
@@ -89,13 +180,7 @@ func Load() (*Config, error) {
 	if err := viper.Unmarshal(&config); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
-	
-	if err := validateConfig(&config); err != nil {
-		return nil, fmt.Errorf("config validation failed: %w", err)
-	}
-	
-	cfg = &config
-	return cfg, nil
+	return &config, nil
 }
 
 func Get() *Config {
@@ -113,6 +198,7 @@ func setDefaults() {
 	viper.SetDefault("server.cors.allowed_headers", []string{"Origin", "Content-Type", "Authorization"})
 	
 	// Database defaults
+	viper.SetDefault("database.driver", "mysql")
 	viper.SetDefault("database.host", "localhost")
 	viper.SetDefault("database.port", 3306)
 	viper.SetDefault("database.user", "root")
@@ -121,10 +207,12 @@ func setDefaults() {
 	viper.SetDefault("database.max_open_conns", 25)
 	viper.SetDefault("database.max_idle_conns", 25)
 	viper.SetDefault("database.conn_max_lifetime", "5m")
+	viper.SetDefault("database.ssl_mode", "disable")
+	viper.SetDefault("database.auto_migrate", true)
 	
 	// JWT defaults
 	viper.SetDefault("jwt.access_expiry", "15m")
-	viper.SetDefault("jwt.refresh_expiry", "7d")
+	viper.SetDefault("jwt.refresh_expiry", "168h")
 	viper.SetDefault("jwt.issuer", "stockle-api")
 	viper.SetDefault("jwt.cookie_domain", "localhost")
 	viper.SetDefault("jwt.cookie_secure", false)
@@ -138,7 +226,32 @@ func setDefaults() {
 	viper.SetDefault("ai.request_timeout", "30s")
 	viper.SetDefault("ai.max_retries", 3)
 	viper.SetDefault("ai.retry_delay", "1s")
+	viper.SetDefault("ai.max_retry_delay", "30s")
 	viper.SetDefault("ai.rate_limit_per_min", 60)
+	viper.SetDefault("ai.circuit_breaker_threshold", 5)
+	viper.SetDefault("ai.circuit_breaker_cooldown", "1m")
+	viper.SetDefault("ai.chunk_tokens", 3000)
+	viper.SetDefault("ai.chunk_concurrency", 3)
+	viper.SetDefault("ai.min_confidence", 0.5)
+	
+	// Job defaults
+	viper.SetDefault("jobs.workers", 2)
+	viper.SetDefault("jobs.stale_after", "10m")
+	
+	// Scraper defaults
+	viper.SetDefault("scraper.max_concurrency", 8)
+	viper.SetDefault("scraper.per_host_parallelism", 2)
+	viper.SetDefault("scraper.per_host_delay", "1s")
+	viper.SetDefault("scraper.request_timeout", "30s")
+	
+	// Export defaults
+	viper.SetDefault("exports.retention", "24h")
+	viper.SetDefault("exports.link_expiry", "1h")
+	
+	// Feed defaults
+	viper.SetDefault("feeds.poll_interval", "30m")
+	viper.SetDefault("feeds.max_backoff", "24h")
+	viper.SetDefault("feeds.scheduler_interval", "1m")
 }
 
 func bindEnvVars() {
@@ -147,11 +260,15 @@ func bindEnvVars() {
 	viper.BindEnv("server.environment", "ENV")
 	
 	// Database
+	viper.BindEnv("database.driver", "DB_DRIVER")
+	viper.BindEnv("database.url", "DATABASE_URL")
 	viper.BindEnv("database.host", "DB_HOST")
 	viper.BindEnv("database.port", "DB_PORT")
 	viper.BindEnv("database.user", "DB_USER")
 	viper.BindEnv("database.password", "DB_PASSWORD")
 	viper.BindEnv("database.database", "DB_NAME")
+	viper.BindEnv("database.ssl_mode", "DB_SSL_MODE")
+	viper.BindEnv("database.auto_migrate", "DB_AUTO_MIGRATE")
 	
 	// JWT
 	viper.BindEnv("jwt.access_secret", "JWT_ACCESS_SECRET")
@@ -160,6 +277,31 @@ func bindEnvVars() {
 	// AI
 	viper.BindEnv("ai.groq_api_key", "GROQ_API_KEY")
 	viper.BindEnv("ai.anthropic_api_key", "ANTHROPIC_API_KEY")
+	viper.BindEnv("ai.openai_base_url", "OPENAI_BASE_URL")
+	viper.BindEnv("ai.openai_api_key", "OPENAI_API_KEY")
+	viper.BindEnv("ai.openai_model", "OPENAI_MODEL")
+	
+	// Jobs
+	viper.BindEnv("jobs.workers", "JOB_WORKERS")
+	viper.BindEnv("jobs.stale_after", "JOB_STALE_AFTER")
+	
+	// Scraper
+	viper.BindEnv("scraper.max_concurrency", "SCRAPER_MAX_CONCURRENCY")
+	viper.BindEnv("scraper.per_host_parallelism", "SCRAPER_PER_HOST_PARALLELISM")
+	viper.BindEnv("scraper.per_host_delay", "SCRAPER_PER_HOST_DELAY")
+	viper.BindEnv("scraper.request_timeout", "SCRAPER_REQUEST_TIMEOUT")
+	viper.BindEnv("scraper.site_rules", "SCRAPER_SITE_RULES")
+	viper.BindEnv("scraper.url_rules", "SCRAPER_URL_RULES")
+	
+	// Exports
+	viper.BindEnv("exports.dir", "EXPORT_DIR")
+	viper.BindEnv("exports.retention", "EXPORT_RETENTION")
+	viper.BindEnv("exports.link_expiry", "EXPORT_LINK_EXPIRY")
+	viper.BindEnv("exports.signing_key", "EXPORT_SIGNING_KEY")
+
+	// Feeds
+	viper.BindEnv("feeds.poll_interval", "FEED_POLL_INTERVAL")
+	viper.BindEnv("feeds.max_backoff", "FEED_MAX_BACKOFF")
 }
 
 func validateConfig(config *Config) error {
@@ -167,12 +309,25 @@ func validateConfig(config *Config) error {
 		return fmt.Errorf("server port is required")
 	}
 	
-	if config.Database.Host == "" {
-		return fmt.Errorf("database host is required")
-	}
-	
-	if config.Database.User == "" {
-		return fmt.Errorf("database user is required")
+	switch config.Database.Driver {
+	case "mysql", "postgres":
+		if config.Database.URL != "" {
+			break
+		}
+		
+		if config.Database.Host == "" {
+			return fmt.Errorf("database host is required")
+		}
+		
+		if config.Database.User == "" {
+			return fmt.Errorf("database user is required")
+		}
+	case "sqlite":
+		if config.Database.Database == "" && config.Database.URL == "" {
+			return fmt.Errorf("database file is required for sqlite")
+		}
+	default:
+		return fmt.Errorf("unsupported database driver %q", config.Database.Driver)
 	}
 	
 	if config.JWT.AccessSecret == "" {
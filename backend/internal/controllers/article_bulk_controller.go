@@ -0,0 +1,294 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/eikuma/stockle/backend/internal/models"
+	"github.com/eikuma/stockle/backend/internal/repositories"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Statuses of the articles in a bulk response
+const (
+	BulkStatusUpdated  = "updated"
+	BulkStatusDeleted  = "deleted"
+	BulkStatusMatched  = "matched"
+	BulkStatusNotFound = "not_found"
+	BulkStatusFailed   = "failed"
+	BulkStatusSkipped  = "skipped"
+)
+
+// BulkArticleRequest selects articles by ID or with a query, see
+// articlequery, and applies the actions to all of them
+type BulkArticleRequest struct {
+	IDs     []string           `json:"ids,omitempty" binding:"omitempty,max=1000,unique"`
+	Query   string             `json:"query,omitempty"`
+	Actions BulkArticleActions `json:"actions"`
+	DryRun  bool               `json:"dryRun,omitempty"`
+}
+
+// BulkArticleActions are applied together. An empty CategoryID moves the
+// articles to the default category, as when updating one article; Delete
+// cannot be combined with other actions. Resummarize skips the articles
+// whose content has not been extracted yet.
+type BulkArticleActions struct {
+	Status      *string  `json:"status,omitempty" binding:"omitempty,oneof=unread read archived"`
+	IsFavorite  *bool    `json:"isFavorite,omitempty"`
+	CategoryID  *string  `json:"categoryId,omitempty"`
+	AddTags     []string `json:"addTags,omitempty" binding:"omitempty,dive,max=50"`
+	RemoveTags  []string `json:"removeTags,omitempty" binding:"omitempty,dive,max=50"`
+	Delete      bool     `json:"delete,omitempty"`
+	Resummarize bool     `json:"resummarize,omitempty"`
+}
+
+// BulkArticleResponse reports the outcome for every selected article. With
+// DryRun nothing was changed and Matched is the number that would be; a dry
+// run matching more articles than a bulk operation may change lists none.
+type BulkArticleResponse struct {
+	DryRun  bool                `json:"dryRun"`
+	Matched int                 `json:"matched"`
+	Results []BulkArticleResult `json:"results"`
+}
+
+// BulkArticleResult is the outcome for one article. Error explains a failed
+// or skipped article, or a follow-up such as queueing a summary that failed
+// or was skipped after the article was updated.
+type BulkArticleResult struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BulkUpdateArticles applies actions to many articles at once
+// POST /api/v1/articles/bulk
+func (c *ArticleController) BulkUpdateArticles(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	var req BulkArticleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid JSON format: " + err.Error(),
+		})
+		return
+	}
+
+	selection, ok := parseBulkSelection(ctx, req)
+	if !ok {
+		return
+	}
+	if !validateBulkActions(ctx, req.Actions) {
+		return
+	}
+
+	update, ok := c.resolveBulkUpdate(ctx, userID, req.Actions, req.DryRun)
+	if !ok {
+		return
+	}
+
+	result, err := c.articleRepo.BulkUpdate(userID, selection, update, req.DryRun)
+	if err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrBulkLimitExceeded):
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "too_many_articles",
+				Message: "A bulk operation can change at most " + strconv.Itoa(repositories.MaxBulkArticles) + " articles",
+			})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "invalid_category",
+				Message: "Category not found",
+			})
+		default:
+			ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+				Error:   "update_failed",
+				Message: "Failed to update articles: " + err.Error(),
+			})
+		}
+		return
+	}
+
+	response := BulkArticleResponse{
+		DryRun:  req.DryRun,
+		Matched: result.Count,
+		Results: make([]BulkArticleResult, 0, len(result.Matched)+len(result.Missing)),
+	}
+	status := BulkStatusUpdated
+	switch {
+	case req.DryRun:
+		status = BulkStatusMatched
+	case update.Delete:
+		status = BulkStatusDeleted
+	}
+	for _, id := range result.Matched {
+		response.Results = append(response.Results, BulkArticleResult{ID: id, Status: status})
+	}
+	for _, id := range result.Missing {
+		response.Results = append(response.Results, BulkArticleResult{ID: id, Status: BulkStatusNotFound})
+	}
+
+	if !req.DryRun {
+		c.afterBulkUpdate(result, update, req.Actions.Resummarize, response.Results)
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// parseBulkSelection reads which articles the request applies to: either
+// the listed IDs or a non-empty query
+func parseBulkSelection(ctx *gin.Context, req BulkArticleRequest) (repositories.ArticleSelection, bool) {
+	hasQuery := strings.TrimSpace(req.Query) != ""
+	if len(req.IDs) > 0 == hasQuery {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Either ids or query is required, but not both",
+		})
+		return repositories.ArticleSelection{}, false
+	}
+
+	if !hasQuery {
+		return repositories.ArticleSelection{IDs: req.IDs}, true
+	}
+	query, ok := parseArticleQuery(ctx, req.Query)
+	if !ok {
+		return repositories.ArticleSelection{}, false
+	}
+	return repositories.ArticleSelection{Query: query}, true
+}
+
+func validateBulkActions(ctx *gin.Context, actions BulkArticleActions) bool {
+	changes := actions.Status != nil || actions.IsFavorite != nil || actions.CategoryID != nil ||
+		len(actions.AddTags) > 0 || len(actions.RemoveTags) > 0 || actions.Resummarize
+
+	message := ""
+	switch {
+	case actions.Delete && changes:
+		message = "delete cannot be combined with other actions"
+	case !actions.Delete && !changes:
+		message = "At least one action is required"
+	}
+	if message != "" {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: message,
+		})
+		return false
+	}
+	return true
+}
+
+// resolveBulkUpdate turns the actions into repository changes, checking
+// the category and looking up the tags by name. Tags to add are created
+// unless this is a dry run.
+func (c *ArticleController) resolveBulkUpdate(
+	ctx *gin.Context,
+	userID string,
+	actions BulkArticleActions,
+	dryRun bool,
+) (repositories.ArticleBulkUpdate, bool) {
+	update := repositories.ArticleBulkUpdate{
+		Status:     actions.Status,
+		IsFavorite: actions.IsFavorite,
+		Delete:     actions.Delete,
+	}
+
+	if actions.CategoryID != nil {
+		categoryID, ok := c.resolveCategoryID(ctx, userID, actions.CategoryID)
+		if !ok {
+			return update, false
+		}
+		if categoryID == nil {
+			// Without a default category the articles are left uncategorized
+			categoryID = new(string)
+		}
+		update.CategoryID = categoryID
+	}
+
+	if len(actions.AddTags) > 0 && !dryRun {
+		tags, err := c.tagRepo.GetOrCreateMultiple(userID, actions.AddTags)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+				Error:   "update_failed",
+				Message: "Failed to create tags: " + err.Error(),
+			})
+			return update, false
+		}
+		for _, tag := range tags {
+			update.AddTagIDs = append(update.AddTagIDs, tag.ID)
+		}
+	}
+
+	if len(actions.RemoveTags) > 0 {
+		// Tags the user does not have are not on any article to begin with
+		tags, err := c.tagRepo.GetByNames(userID, actions.RemoveTags)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+				Error:   "fetch_failed",
+				Message: "Failed to fetch tags: " + err.Error(),
+			})
+			return update, false
+		}
+		for _, tag := range tags {
+			update.RemoveTagIDs = append(update.RemoveTagIDs, tag.ID)
+		}
+	}
+
+	return update, true
+}
+
+// afterBulkUpdate brings the search index up to date and queues the
+// summaries. Articles not summarized are reported on the article.
+func (c *ArticleController) afterBulkUpdate(
+	result *repositories.ArticleBulkResult,
+	update repositories.ArticleBulkUpdate,
+	resummarize bool,
+	results []BulkArticleResult,
+) {
+	ids := result.Matched
+	switch {
+	case update.Delete:
+		for _, id := range ids {
+			c.search.RemoveArticle(id)
+		}
+	case len(update.AddTagIDs) > 0 || len(update.RemoveTagIDs) > 0:
+		c.search.Refresh(ids...)
+	}
+
+	if !resummarize {
+		return
+	}
+	// Articles that were not otherwise changed failed altogether
+	changed := update.Status != nil || update.IsFavorite != nil || update.CategoryID != nil ||
+		len(update.AddTagIDs) > 0 || len(update.RemoveTagIDs) > 0
+	unextracted := make(map[string]bool, len(result.Unextracted))
+	for _, id := range result.Unextracted {
+		unextracted[id] = true
+	}
+	for i := range results[:len(ids)] {
+		if unextracted[results[i].ID] {
+			results[i].Error = "Content has not been extracted yet"
+			if !changed {
+				results[i].Status = BulkStatusSkipped
+			}
+			continue
+		}
+		err := c.jobService.EnqueueRegenerateSummaryJob(results[i].ID, models.JobPriorityLow)
+		if err != nil {
+			results[i].Error = "Failed to queue summary: " + err.Error()
+			if !changed {
+				results[i].Status = BulkStatusFailed
+			}
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/eikuma/stockle/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArticleController_BulkUpdateArticles(t *testing.T) {
+	api := newTestAPI(t)
+	golang := seedArticle(t, api, "user-1", "golang")
+	rust := seedArticle(t, api, "user-1", "rust")
+	python := seedArticle(t, api, "user-1", "python")
+	foreign := seedArticle(t, api, "user-2", "golang")
+	category := &models.Category{ID: "cat-1", UserID: "user-1", Name: "Later"}
+	require.NoError(t, api.categoryRepo.Create(category))
+
+	status := models.ArticleStatusRead
+	favorite := true
+	var resp BulkArticleResponse
+	rec := api.do(http.MethodPost, "/api/v1/articles/bulk", "user-1", BulkArticleRequest{
+		IDs: []string{golang.ID, foreign.ID, rust.ID},
+		Actions: BulkArticleActions{
+			Status:     &status,
+			IsFavorite: &favorite,
+			CategoryID: &category.ID,
+			AddTags:    []string{"systems"},
+		},
+	}, &resp)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, 2, resp.Matched)
+	assert.Equal(t, []BulkArticleResult{
+		{ID: golang.ID, Status: BulkStatusUpdated},
+		{ID: rust.ID, Status: BulkStatusUpdated},
+		{ID: foreign.ID, Status: BulkStatusNotFound},
+	}, resp.Results)
+
+	for _, id := range []string{golang.ID, rust.ID} {
+		article, err := api.articleRepo.GetByIDWithAssociations(id)
+		require.NoError(t, err)
+		assert.Equal(t, models.ArticleStatusRead, article.Status)
+		assert.True(t, article.IsFavorite)
+		assert.Equal(t, category.ID, *article.CategoryID)
+		require.Len(t, article.Tags, 1)
+		assert.Equal(t, "systems", article.Tags[0].Name)
+	}
+	untouched, err := api.articleRepo.GetByID(foreign.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ArticleStatusUnread, untouched.Status)
+
+	// The search index follows the new tags
+	var search SearchResponse
+	rec = api.do(http.MethodGet, "/api/v1/articles/search?q=systems", "user-1", nil, &search)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 2, search.Total)
+
+	t.Run("dry runs report the matching articles", func(t *testing.T) {
+		var resp BulkArticleResponse
+		rec := api.do(http.MethodPost, "/api/v1/articles/bulk", "user-1", BulkArticleRequest{
+			Query:   "tag:systems",
+			Actions: BulkArticleActions{Delete: true},
+			DryRun:  true,
+		}, &resp)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.True(t, resp.DryRun)
+		assert.Equal(t, 2, resp.Matched)
+		for _, result := range resp.Results {
+			assert.Equal(t, BulkStatusMatched, result.Status)
+		}
+
+		_, err := api.articleRepo.GetByID(golang.ID)
+		assert.NoError(t, err)
+	})
+
+	t.Run("summaries are generated again", func(t *testing.T) {
+		old := "Old summary"
+		require.NoError(t, api.articleRepo.UpdateSummary(&models.Article{ID: python.ID, Summary: &old}, models.SummaryTypeMedium))
+
+		var resp BulkArticleResponse
+		rec := api.do(http.MethodPost, "/api/v1/articles/bulk", "user-1", BulkArticleRequest{
+			Query:   "-tag:systems",
+			Actions: BulkArticleActions{Resummarize: true},
+		}, &resp)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		require.Equal(t, []BulkArticleResult{{ID: python.ID, Status: BulkStatusUpdated}}, resp.Results)
+
+		api.runJobs()
+		article, err := api.articleRepo.GetByID(python.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Summary of python", *article.Summary)
+	})
+
+	t.Run("articles not yet extracted are not summarized", func(t *testing.T) {
+		pending := &models.Article{
+			ID:               "pending-user-1",
+			UserID:           "user-1",
+			URL:              "https://example.com/pending",
+			Title:            "pending",
+			Status:           models.ArticleStatusUnread,
+			ExtractionStatus: models.ExtractionStatusPending,
+		}
+		require.NoError(t, api.articleRepo.Create(pending))
+		defer func() { require.NoError(t, api.articleRepo.Delete(pending.ID, "user-1")) }()
+
+		var resp BulkArticleResponse
+		rec := api.do(http.MethodPost, "/api/v1/articles/bulk", "user-1", BulkArticleRequest{
+			IDs:     []string{python.ID, pending.ID},
+			Actions: BulkArticleActions{Resummarize: true},
+		}, &resp)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.Equal(t, []BulkArticleResult{
+			{ID: python.ID, Status: BulkStatusUpdated},
+			{ID: pending.ID, Status: BulkStatusSkipped, Error: "Content has not been extracted yet"},
+		}, resp.Results)
+
+		// Other changes are still applied to them
+		rec = api.do(http.MethodPost, "/api/v1/articles/bulk", "user-1", BulkArticleRequest{
+			IDs:     []string{pending.ID},
+			Actions: BulkArticleActions{IsFavorite: &favorite, Resummarize: true},
+		}, &resp)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.Equal(t, []BulkArticleResult{
+			{ID: pending.ID, Status: BulkStatusUpdated, Error: "Content has not been extracted yet"},
+		}, resp.Results)
+
+		api.runJobs()
+		article, err := api.articleRepo.GetByID(pending.ID)
+		require.NoError(t, err)
+		assert.True(t, article.IsFavorite)
+		assert.Nil(t, article.Summary)
+	})
+
+	t.Run("an empty category moves articles to the default one", func(t *testing.T) {
+		defaultCategory, err := api.categoryRepo.CreateDefault("user-1")
+		require.NoError(t, err)
+
+		empty := ""
+		rec := api.do(http.MethodPost, "/api/v1/articles/bulk", "user-1", BulkArticleRequest{
+			IDs:     []string{golang.ID},
+			Actions: BulkArticleActions{CategoryID: &empty},
+		}, nil)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		article, err := api.articleRepo.GetByID(golang.ID)
+		require.NoError(t, err)
+		require.NotNil(t, article.CategoryID)
+		assert.Equal(t, defaultCategory.ID, *article.CategoryID)
+
+		// The same rule as for a single article
+		rec = api.do(http.MethodPatch, "/api/v1/articles/"+rust.ID, "user-1", UpdateArticleRequest{CategoryID: &empty}, nil)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		article, err = api.articleRepo.GetByID(rust.ID)
+		require.NoError(t, err)
+		require.NotNil(t, article.CategoryID)
+		assert.Equal(t, defaultCategory.ID, *article.CategoryID)
+	})
+
+	t.Run("tags are removed and articles deleted", func(t *testing.T) {
+		rec := api.do(http.MethodPost, "/api/v1/articles/bulk", "user-1", BulkArticleRequest{
+			IDs:     []string{rust.ID},
+			Actions: BulkArticleActions{RemoveTags: []string{"systems", "unknown"}},
+		}, nil)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		article, err := api.articleRepo.GetByIDWithAssociations(rust.ID)
+		require.NoError(t, err)
+		assert.Empty(t, article.Tags)
+
+		var resp BulkArticleResponse
+		rec = api.do(http.MethodPost, "/api/v1/articles/bulk", "user-1", BulkArticleRequest{
+			Query:   "tag:systems",
+			Actions: BulkArticleActions{Delete: true},
+		}, &resp)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.Equal(t, []BulkArticleResult{{ID: golang.ID, Status: BulkStatusDeleted}}, resp.Results)
+
+		_, err = api.articleRepo.GetByID(golang.ID)
+		assert.Error(t, err)
+		rec = api.do(http.MethodGet, "/api/v1/articles/search?q=systems", "user-1", nil, &search)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, search.Hits)
+	})
+}
+
+func TestArticleController_BulkUpdateArticlesValidation(t *testing.T) {
+	api := newTestAPI(t)
+	article := seedArticle(t, api, "user-1", "golang")
+	foreignCategory := &models.Category{ID: "cat-2", UserID: "user-2", Name: "Theirs"}
+	require.NoError(t, api.categoryRepo.Create(foreignCategory))
+
+	status := models.ArticleStatusRead
+	invalidStatus := "done"
+	tooMany := make([]string, 1001)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("article-%d", i)
+	}
+
+	tests := []struct {
+		name string
+		req  BulkArticleRequest
+	}{
+		{name: "no selection", req: BulkArticleRequest{Actions: BulkArticleActions{Status: &status}}},
+		{name: "ids and query", req: BulkArticleRequest{
+			IDs:     []string{article.ID},
+			Query:   "tag:go",
+			Actions: BulkArticleActions{Status: &status},
+		}},
+		{name: "no action", req: BulkArticleRequest{IDs: []string{article.ID}}},
+		{name: "delete with other actions", req: BulkArticleRequest{
+			IDs:     []string{article.ID},
+			Actions: BulkArticleActions{Delete: true, Status: &status},
+		}},
+		{name: "unknown status", req: BulkArticleRequest{
+			IDs:     []string{article.ID},
+			Actions: BulkArticleActions{Status: &invalidStatus},
+		}},
+		{name: "foreign category", req: BulkArticleRequest{
+			IDs:     []string{article.ID},
+			Actions: BulkArticleActions{CategoryID: &foreignCategory.ID},
+		}},
+		{name: "invalid query", req: BulkArticleRequest{
+			Query:   "tag:go OR",
+			Actions: BulkArticleActions{Status: &status},
+		}},
+		{name: "too many articles", req: BulkArticleRequest{
+			IDs:     tooMany,
+			Actions: BulkArticleActions{Status: &status},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := api.do(http.MethodPost, "/api/v1/articles/bulk", "user-1", tt.req, nil)
+			assert.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+		})
+	}
+
+	stored, err := api.articleRepo.GetByID(article.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ArticleStatusUnread, stored.Status)
+
+	rec := api.do(http.MethodPost, "/api/v1/articles/bulk", "", BulkArticleRequest{}, nil)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
@@ -1,13 +1,17 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/eikuma/stockle/backend/internal/articlequery"
 	"github.com/eikuma/stockle/backend/internal/models"
 	"github.com/eikuma/stockle/backend/internal/repositories"
 	"github.com/eikuma/stockle/backend/internal/services"
+	"github.com/eikuma/stockle/backend/internal/urlnorm"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -16,7 +20,10 @@ type ArticleController struct {
 	articleRepo  repositories.ArticleRepository
 	categoryRepo repositories.CategoryRepository
 	tagRepo      repositories.TagRepository
-	scraperSvc   *services.ScraperService
+	jobService   *services.JobService
+	search       *services.SearchService
+	events       *services.ArticleEventBroker
+	urls         *urlnorm.Normalizer
 }
 
 type SaveArticleRequest struct {
@@ -30,6 +37,8 @@ type UpdateArticleRequest struct {
 	IsFavorite      *bool    `json:"isFavorite,omitempty"`
 	CategoryID      *string  `json:"categoryId,omitempty"`
 	ReadingProgress *float64 `json:"readingProgress,omitempty"`
+	// Tags replaces the tags of the article when set
+	Tags *[]string `json:"tags,omitempty" binding:"omitempty,max=50,dive,max=50"`
 }
 
 type ArticleResponse struct {
@@ -37,34 +46,73 @@ type ArticleResponse struct {
 	Article *models.Article `json:"article,omitempty"`
 }
 
+// DuplicateArticleResponse is returned with 409 when the URL was already saved
+type DuplicateArticleResponse struct {
+	Error   string          `json:"error"`
+	Message string          `json:"message"`
+	Article *models.Article `json:"article"`
+}
+
+// ArticleListResponse is a page of articles. Total is left out when the
+// request sets include_total=false; NextCursor when this is the last page.
 type ArticleListResponse struct {
-	Articles []*models.Article `json:"articles"`
-	Total    int64             `json:"total"`
-	Page     int               `json:"page"`
-	Limit    int               `json:"limit"`
+	Articles   []*models.Article `json:"articles"`
+	Total      *int64            `json:"total,omitempty"`
+	Page       int               `json:"page"`
+	Limit      int               `json:"limit"`
+	NextCursor string            `json:"nextCursor,omitempty"`
+}
+
+type SearchResponse struct {
+	Query      string               `json:"query"`
+	Hits       []services.SearchHit `json:"hits"`
+	Total      int                  `json:"total"`
+	Page       int                  `json:"page"`
+	Limit      int                  `json:"limit"`
+	NextCursor string               `json:"nextCursor,omitempty"`
 }
 
+const (
+	articleEventPollInterval = 5 * time.Second
+	articleEventMaxDuration  = 5 * time.Minute
+)
+
 type ErrorResponse struct {
 	Error   string `json:"error"`
 	Message string `json:"message"`
 }
 
+// QueryErrorResponse is returned with 400 for a query that does not parse.
+// Position is the character offset of Token in the query.
+type QueryErrorResponse struct {
+	Error    string `json:"error"`
+	Message  string `json:"message"`
+	Position int    `json:"position"`
+	Token    string `json:"token,omitempty"`
+}
+
 func NewArticleController(
 	articleRepo repositories.ArticleRepository,
 	categoryRepo repositories.CategoryRepository,
 	tagRepo repositories.TagRepository,
-	scraperSvc *services.ScraperService,
+	jobService *services.JobService,
+	search *services.SearchService,
+	events *services.ArticleEventBroker,
+	urls *urlnorm.Normalizer,
 ) *ArticleController {
 	return &ArticleController{
 		articleRepo:  articleRepo,
 		categoryRepo: categoryRepo,
 		tagRepo:      tagRepo,
-		scraperSvc:   scraperSvc,
+		jobService:   jobService,
+		search:       search,
+		events:       events,
+		urls:         urls,
 	}
 }
 
 // SaveArticle saves a new article from URL
-// POST /api/articles
+// POST /api/v1/articles
 func (c *ArticleController) SaveArticle(ctx *gin.Context) {
 	userID := ctx.GetString("user_id")
 	if userID == "" {
@@ -84,41 +132,37 @@ func (c *ArticleController) SaveArticle(ctx *gin.Context) {
 		return
 	}
 
-	// Check if article already exists
-	existingArticle, _ := c.articleRepo.GetByURL(userID, req.URL)
-	if existingArticle != nil {
-		ctx.JSON(http.StatusConflict, ErrorResponse{
-			Error:   "duplicate_article",
-			Message: "This article has already been saved",
+	// Check if article already exists, under this or another form of its URL
+	urlHash, err := c.urls.Key(req.URL)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_url",
+			Message: err.Error(),
 		})
 		return
 	}
+	if c.respondIfDuplicate(ctx, userID, urlHash) {
+		return
+	}
 
-	// Scrape article metadata
-	metadata, err := c.scraperSvc.ExtractMetadata(req.URL)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, ErrorResponse{
-			Error:   "scraping_failed",
-			Message: "Failed to extract article content: " + err.Error(),
-		})
+	// Resolve category; articles without one go to the default category
+	categoryID, ok := c.resolveCategoryID(ctx, userID, req.CategoryID)
+	if !ok {
 		return
 	}
 
-	// Create article
+	// Save a placeholder immediately; content is extracted by a background job
 	article := &models.Article{
-		ID:           uuid.New().String(),
-		UserID:       userID,
-		CategoryID:   req.CategoryID,
-		URL:          req.URL,
-		Title:        metadata.Title,
-		Content:      &metadata.Content,
-		ThumbnailURL: &metadata.ThumbnailURL,
-		Author:       &metadata.Author,
-		SiteName:     &metadata.SiteName,
-		PublishedAt:  metadata.PublishedAt,
-		Status:       models.ArticleStatusUnread,
-		IsFavorite:   false,
-		Language:     metadata.Language,
+		ID:                      uuid.New().String(),
+		UserID:                  userID,
+		CategoryID:              categoryID,
+		URL:                     req.URL,
+		URLHash:                 &urlHash,
+		Title:                   req.URL,
+		Status:                  models.ArticleStatusUnread,
+		IsFavorite:              false,
+		ExtractionStatus:        models.ExtractionStatusPending,
+		SummaryGenerationStatus: models.SummaryStatusPending,
 	}
 
 	// Create tags if provided
@@ -140,12 +184,28 @@ func (c *ArticleController) SaveArticle(ctx *gin.Context) {
 
 	// Save article with tags
 	if err := c.articleRepo.CreateWithTags(article, tagIDs); err != nil {
+		// The same URL may have been saved concurrently
+		if c.respondIfDuplicate(ctx, userID, urlHash) {
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
 			Error:   "save_failed",
 			Message: "Failed to save article: " + err.Error(),
 		})
 		return
 	}
+	c.search.Refresh(article.ID)
+
+	if err := c.jobService.EnqueueExtractionJob(article.ID, models.JobPriorityHigh); err != nil {
+		// The article is kept so the user can see why it has no content
+		message := "Failed to schedule content extraction: " + err.Error()
+		article.ExtractionStatus = models.ExtractionStatusFailed
+		article.ExtractionError = &message
+		article.SummaryGenerationStatus = models.SummaryStatusFailed
+		if err := c.articleRepo.UpdateExtraction(article); err == nil {
+			c.articleRepo.UpdateSummaryStatus(article)
+		}
+	}
 
 	// Get article with associations for response
 	savedArticle, err := c.articleRepo.GetByIDWithAssociations(article.ID)
@@ -157,14 +217,14 @@ func (c *ArticleController) SaveArticle(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, ArticleResponse{
-		Message: "Article saved successfully",
+	ctx.JSON(http.StatusAccepted, ArticleResponse{
+		Message: "Article saved; content extraction is in progress",
 		Article: savedArticle,
 	})
 }
 
 // GetArticles retrieves articles with filtering and pagination
-// GET /api/articles
+// GET /api/v1/articles
 func (c *ArticleController) GetArticles(ctx *gin.Context) {
 	userID := ctx.GetString("user_id")
 	if userID == "" {
@@ -175,13 +235,6 @@ func (c *ArticleController) GetArticles(ctx *gin.Context) {
 		return
 	}
 
-	// Parse query parameters
-	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
-	status := ctx.Query("status")
-	categoryID := ctx.Query("category_id")
-	search := ctx.Query("search")
-	
 	var favorite *bool
 	if favoriteStr := ctx.Query("favorite"); favoriteStr != "" {
 		if fav, err := strconv.ParseBool(favoriteStr); err == nil {
@@ -189,34 +242,80 @@ func (c *ArticleController) GetArticles(ctx *gin.Context) {
 		}
 	}
 
+	query, ok := parseArticleQuery(ctx, ctx.Query("q"))
+	if !ok {
+		return
+	}
+
 	filters := repositories.ArticleFilters{
-		Status:     status,
-		CategoryID: categoryID,
-		Search:     search,
-		Favorite:   favorite,
-		Page:       page,
-		Limit:      limit,
+		Status:     ctx.Query("status"),
+		CategoryID: ctx.Query("category_id"),
+		// Articles in subcategories are listed with their ancestors
+		IncludeDescendants: ctx.Query("include_descendants") != "false",
+		Search:             ctx.Query("search"),
+		Favorite:           favorite,
+		Query:              query,
+	}
+	c.listArticles(ctx, userID, filters, repositories.SortSavedAt)
+}
+
+// GetFavorites retrieves the user's favorite articles
+// GET /api/v1/articles/favorites
+func (c *ArticleController) GetFavorites(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
 	}
 
-	result, err := c.articleRepo.GetByUserIDWithFilters(userID, filters)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error:   "fetch_failed",
-			Message: "Failed to fetch articles: " + err.Error(),
+	favorite := true
+	c.listArticles(ctx, userID, repositories.ArticleFilters{Favorite: &favorite}, repositories.SortSavedAt)
+}
+
+// GetRecentlyRead retrieves the read articles, most recently opened first
+// GET /api/v1/articles/recent
+func (c *ArticleController) GetRecentlyRead(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
 		})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, ArticleListResponse{
-		Articles: result.Articles,
-		Total:    result.Total,
-		Page:     result.Page,
-		Limit:    result.Limit,
-	})
+	filters := repositories.ArticleFilters{
+		Status:   models.ArticleStatusRead,
+		Accessed: true,
+	}
+	c.listArticles(ctx, userID, filters, repositories.SortLastAccessedAt)
+}
+
+// listArticles responds with a page of the articles matching filters,
+// applying the pagination and sort parameters of the request
+func (c *ArticleController) listArticles(
+	ctx *gin.Context,
+	userID string,
+	filters repositories.ArticleFilters,
+	defaultSort string,
+) {
+	if !parseListOptions(ctx, &filters, defaultSort) {
+		return
+	}
+
+	result, err := c.articleRepo.GetByUserIDWithFilters(userID, filters)
+	if err != nil {
+		respondListError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, newArticleListResponse(result, filters))
 }
 
 // GetArticle retrieves a single article by ID
-// GET /api/articles/:id
+// GET /api/v1/articles/:id
 func (c *ArticleController) GetArticle(ctx *gin.Context) {
 	userID := ctx.GetString("user_id")
 	if userID == "" {
@@ -262,8 +361,90 @@ func (c *ArticleController) GetArticle(ctx *gin.Context) {
 	})
 }
 
+// StreamArticleEvents streams the extraction and summary state of an article
+// as server-sent events until both background jobs have finished
+// GET /api/v1/articles/:id/events
+func (c *ArticleController) StreamArticleEvents(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	articleID := ctx.Param("id")
+	article, err := c.articleRepo.GetByID(articleID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, ErrorResponse{
+			Error:   "not_found",
+			Message: "Article not found",
+		})
+		return
+	}
+
+	// Check ownership
+	if article.UserID != userID {
+		ctx.JSON(http.StatusForbidden, ErrorResponse{
+			Error:   "forbidden",
+			Message: "Access denied",
+		})
+		return
+	}
+
+	// Subscribe before sending the current state so no change is missed
+	events, unsubscribe := c.events.Subscribe(articleID)
+	defer unsubscribe()
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+
+	// The stream outlives the server's WriteTimeout
+	http.NewResponseController(ctx.Writer).SetWriteDeadline(time.Now().Add(articleEventMaxDuration + time.Minute))
+
+	send := func(event services.ArticleEvent) bool {
+		ctx.SSEvent("status", event)
+		ctx.Writer.Flush()
+		return event.Done()
+	}
+
+	if send(services.NewArticleEvent(article)) {
+		return
+	}
+
+	// Jobs may run in another process, so the database is polled as well
+	poll := time.NewTicker(articleEventPollInterval)
+	defer poll.Stop()
+	timeout := time.NewTimer(articleEventMaxDuration)
+	defer timeout.Stop()
+
+	for {
+		select {
+		case <-ctx.Request.Context().Done():
+			return
+		case <-timeout.C:
+			return
+		case event := <-events:
+			if send(event) {
+				return
+			}
+		case <-poll.C:
+			article, err := c.articleRepo.GetByID(articleID)
+			if err != nil {
+				return
+			}
+			if send(services.NewArticleEvent(article)) {
+				return
+			}
+		}
+	}
+}
+
 // UpdateArticle updates an existing article
-// PATCH /api/articles/:id
+// PATCH /api/v1/articles/:id
 func (c *ArticleController) UpdateArticle(ctx *gin.Context) {
 	userID := ctx.GetString("user_id")
 	if userID == "" {
@@ -311,6 +492,20 @@ func (c *ArticleController) UpdateArticle(ctx *gin.Context) {
 	}
 
 	// Update fields
+	if req.CategoryID != nil {
+		categoryID, ok := c.resolveCategoryID(ctx, userID, req.CategoryID)
+		if !ok {
+			return
+		}
+		if err := c.articleRepo.UpdateCategory(articleID, userID, categoryID); err != nil {
+			ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+				Error:   "update_failed",
+				Message: "Failed to update category: " + err.Error(),
+			})
+			return
+		}
+	}
+
 	if req.Status != nil {
 		if err := c.articleRepo.UpdateStatus(articleID, userID, *req.Status); err != nil {
 			ctx.JSON(http.StatusInternalServerError, ErrorResponse{
@@ -341,15 +536,19 @@ func (c *ArticleController) UpdateArticle(ctx *gin.Context) {
 		}
 	}
 
-	if req.CategoryID != nil {
-		article.CategoryID = req.CategoryID
-		if err := c.articleRepo.Update(article); err != nil {
+	if req.Tags != nil {
+		tagIDs, ok := c.resolveTagIDs(ctx, userID, *req.Tags)
+		if !ok {
+			return
+		}
+		if err := c.articleRepo.ReplaceTags(articleID, userID, tagIDs); err != nil {
 			ctx.JSON(http.StatusInternalServerError, ErrorResponse{
 				Error:   "update_failed",
-				Message: "Failed to update category: " + err.Error(),
+				Message: "Failed to update tags: " + err.Error(),
 			})
 			return
 		}
+		c.search.Refresh(articleID)
 	}
 
 	ctx.JSON(http.StatusOK, ArticleResponse{
@@ -358,7 +557,7 @@ func (c *ArticleController) UpdateArticle(ctx *gin.Context) {
 }
 
 // DeleteArticle deletes an article
-// DELETE /api/articles/:id
+// DELETE /api/v1/articles/:id
 func (c *ArticleController) DeleteArticle(ctx *gin.Context) {
 	userID := ctx.GetString("user_id")
 	if userID == "" {
@@ -403,14 +602,16 @@ func (c *ArticleController) DeleteArticle(ctx *gin.Context) {
 		})
 		return
 	}
+	c.search.RemoveArticle(articleID)
 
 	ctx.JSON(http.StatusOK, ArticleResponse{
 		Message: "Article deleted successfully",
 	})
 }
 
-// SearchArticles searches articles by title/content
-// GET /api/articles/search
+// SearchArticles searches the title, summary, content and tags of the
+// user's articles and returns them ranked by relevance, with highlights
+// GET /api/v1/articles/search
 func (c *ArticleController) SearchArticles(ctx *gin.Context) {
 	userID := ctx.GetString("user_id")
 	if userID == "" {
@@ -430,8 +631,17 @@ func (c *ArticleController) SearchArticles(ctx *gin.Context) {
 		return
 	}
 
-	articles, err := c.articleRepo.SearchByTitle(userID, query)
+	var page listOptions
+	if !page.parse(ctx) {
+		return
+	}
+
+	result, err := c.search.Search(userID, query, page.cursor, page.page, page.limit)
 	if err != nil {
+		if errors.Is(err, repositories.ErrInvalidCursor) {
+			respondListError(ctx, err)
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
 			Error:   "search_failed",
 			Message: "Failed to search articles: " + err.Error(),
@@ -439,7 +649,176 @@ func (c *ArticleController) SearchArticles(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, map[string][]*models.Article{
-		"articles": articles,
+	ctx.JSON(http.StatusOK, SearchResponse{
+		Query:      query,
+		Hits:       result.Hits,
+		Total:      result.Total,
+		Page:       page.page,
+		Limit:      page.limit,
+		NextCursor: result.NextCursor,
+	})
+}
+
+// parseArticleQuery parses a query written in the articlequery syntax and
+// responds with 400 pointing at the offending token when it is invalid
+func parseArticleQuery(ctx *gin.Context, input string) (*articlequery.Query, bool) {
+	query, err := articlequery.Parse(input)
+	if err == nil {
+		return query, true
+	}
+
+	response := QueryErrorResponse{Error: "invalid_query", Message: err.Error()}
+	var syntaxErr *articlequery.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		response.Message = syntaxErr.Message
+		response.Position = syntaxErr.Offset
+		response.Token = syntaxErr.Token
+	}
+	ctx.JSON(http.StatusBadRequest, response)
+	return nil, false
+}
+
+// listOptions are the pagination parameters shared by the article listings
+type listOptions struct {
+	page   int
+	limit  int
+	cursor string
+}
+
+// parse reads page, limit and cursor and responds with 400 when they are
+// invalid. A cursor replaces page; both cannot be given.
+func (o *listOptions) parse(ctx *gin.Context) bool {
+	var err error
+	o.page, err = strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || o.page < 1 {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "page must be a positive integer",
+		})
+		return false
+	}
+
+	o.limit, err = strconv.Atoi(ctx.DefaultQuery("limit", strconv.Itoa(repositories.DefaultPageSize)))
+	if err != nil || o.limit < 1 || o.limit > repositories.MaxPageSize {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "limit must be between 1 and " + strconv.Itoa(repositories.MaxPageSize),
+		})
+		return false
+	}
+
+	o.cursor = ctx.Query("cursor")
+	if _, hasPage := ctx.GetQuery("page"); hasPage && o.cursor != "" {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "page and cursor cannot be used together",
+		})
+		return false
+	}
+	return true
+}
+
+// parseListOptions applies the pagination, sort, order and include_total
+// parameters to filters and responds with 400 when one is invalid
+func parseListOptions(ctx *gin.Context, filters *repositories.ArticleFilters, defaultSort string) bool {
+	var page listOptions
+	if !page.parse(ctx) {
+		return false
+	}
+	filters.Page, filters.Limit, filters.Cursor = page.page, page.limit, page.cursor
+
+	sort, err := repositories.ParseArticleSort(ctx.DefaultQuery("sort", defaultSort), ctx.Query("order"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+		return false
+	}
+	filters.Sort = sort
+
+	includeTotal, err := strconv.ParseBool(ctx.DefaultQuery("include_total", "true"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "include_total must be true or false",
+		})
+		return false
+	}
+	filters.SkipTotal = !includeTotal
+	return true
+}
+
+// respondListError responds to a failed listing, with 400 for a cursor
+// that does not belong to the listing
+func respondListError(ctx *gin.Context, err error) {
+	if errors.Is(err, repositories.ErrInvalidCursor) {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_cursor",
+			Message: "The cursor is invalid or was issued for another sort order",
+		})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+		Error:   "fetch_failed",
+		Message: "Failed to fetch articles: " + err.Error(),
+	})
+}
+
+func newArticleListResponse(result *repositories.ArticleListResult, filters repositories.ArticleFilters) ArticleListResponse {
+	response := ArticleListResponse{
+		Articles:   result.Articles,
+		Page:       result.Page,
+		Limit:      result.Limit,
+		NextCursor: result.NextCursor,
+	}
+	if response.Articles == nil {
+		response.Articles = []*models.Article{}
+	}
+	if !filters.SkipTotal {
+		total := result.Total
+		response.Total = &total
+	}
+	return response
+}
+
+// respondIfDuplicate responds with 409 and the saved article when the user
+// already has an article with the URL hash
+func (c *ArticleController) respondIfDuplicate(ctx *gin.Context, userID, urlHash string) bool {
+	existing, err := c.articleRepo.GetByURLHash(userID, urlHash)
+	if err != nil {
+		return false
+	}
+	if withAssociations, err := c.articleRepo.GetByIDWithAssociations(existing.ID); err == nil {
+		existing = withAssociations
+	}
+	ctx.JSON(http.StatusConflict, DuplicateArticleResponse{
+		Error:   "duplicate_article",
+		Message: "This article has already been saved",
+		Article: existing,
 	})
-}
\ No newline at end of file
+	return true
+}
+
+// resolveCategoryID validates that the requested category belongs to the user.
+// When no category is requested the user's default category is used if present.
+func (c *ArticleController) resolveCategoryID(ctx *gin.Context, userID string, categoryID *string) (*string, bool) {
+	if categoryID == nil || *categoryID == "" {
+		defaultCategory, err := c.categoryRepo.GetDefault(userID)
+		if err != nil {
+			return nil, true
+		}
+		return &defaultCategory.ID, true
+	}
+
+	category, err := c.categoryRepo.GetByID(*categoryID)
+	if err != nil || category.UserID != userID {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_category",
+			Message: "Category not found",
+		})
+		return nil, false
+	}
+
+	return &category.ID, true
+}
@@ -0,0 +1,641 @@
+package controllers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eikuma/stockle/backend/internal/models"
+	"github.com/eikuma/stockle/backend/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArticleController_SaveArticle(t *testing.T) {
+	api := newTestAPI(t)
+	server := newArticleServer(t)
+
+	defaultCategory, err := api.categoryRepo.CreateDefault("user-1")
+	require.NoError(t, err)
+
+	var resp ArticleResponse
+	rec := api.do(http.MethodPost, "/api/v1/articles", "user-1", SaveArticleRequest{
+		URL:  server.URL + "/posts/1",
+		Tags: []string{"go", "concurrency", " "},
+	}, &resp)
+
+	// The placeholder is returned before the page is scraped
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+	require.NotNil(t, resp.Article)
+	assert.Equal(t, server.URL+"/posts/1", resp.Article.Title)
+	assert.Equal(t, "user-1", resp.Article.UserID)
+	assert.Equal(t, models.ExtractionStatusPending, resp.Article.ExtractionStatus)
+	assert.Equal(t, models.SummaryStatusPending, resp.Article.SummaryGenerationStatus)
+	assert.Equal(t, models.ArticleStatusUnread, resp.Article.Status)
+	require.NotNil(t, resp.Article.CategoryID)
+	assert.Equal(t, defaultCategory.ID, *resp.Article.CategoryID)
+	assert.Len(t, resp.Article.Tags, 2)
+
+	t.Run("background jobs extract and summarize the article", func(t *testing.T) {
+		api.runJobs()
+
+		var got map[string]*models.Article
+		rec := api.do(http.MethodGet, "/api/v1/articles/"+resp.Article.ID, "user-1", nil, &got)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		article := got["article"]
+		assert.Equal(t, "Go Concurrency Patterns", article.Title)
+		assert.Equal(t, "en", article.Language)
+		require.NotNil(t, article.SiteName)
+		assert.Equal(t, "Test Blog", *article.SiteName)
+		assert.Equal(t, models.ExtractionStatusCompleted, article.ExtractionStatus)
+		assert.Equal(t, models.SummaryStatusCompleted, article.SummaryGenerationStatus)
+		require.NotNil(t, article.Summary)
+		assert.Equal(t, "Summary of Go Concurrency Patterns", *article.Summary)
+		assert.Len(t, article.Tags, 2)
+	})
+
+	t.Run("duplicate URL is rejected", func(t *testing.T) {
+		for _, url := range []string{
+			server.URL + "/posts/1",
+			server.URL + "/posts/1/?utm_source=twitter&fbclid=abc",
+			server.URL + "/posts/1#comments",
+		} {
+			var dup DuplicateArticleResponse
+			rec := api.do(http.MethodPost, "/api/v1/articles", "user-1", SaveArticleRequest{URL: url}, &dup)
+			assert.Equal(t, http.StatusConflict, rec.Code, url)
+			assert.Equal(t, "duplicate_article", dup.Error)
+			require.NotNil(t, dup.Article, url)
+			assert.Equal(t, resp.Article.ID, dup.Article.ID)
+			assert.Len(t, dup.Article.Tags, 2)
+		}
+
+		// Other users can save the same URL
+		rec := api.do(http.MethodPost, "/api/v1/articles", "user-2", SaveArticleRequest{
+			URL: server.URL + "/posts/1",
+		}, nil)
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+	})
+
+	t.Run("category of another user is rejected", func(t *testing.T) {
+		other, err := api.categoryRepo.CreateDefault("user-2")
+		require.NoError(t, err)
+
+		rec := api.do(http.MethodPost, "/api/v1/articles", "user-1", SaveArticleRequest{
+			URL:        server.URL + "/posts/2",
+			CategoryID: &other.ID,
+		}, nil)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("invalid URL", func(t *testing.T) {
+		rec := api.do(http.MethodPost, "/api/v1/articles", "user-1", map[string]string{"url": "not a url"}, nil)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		rec := api.do(http.MethodPost, "/api/v1/articles", "", SaveArticleRequest{URL: server.URL}, nil)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("unreachable site fails extraction after retries", func(t *testing.T) {
+		var resp ArticleResponse
+		rec := api.do(http.MethodPost, "/api/v1/articles", "user-1", SaveArticleRequest{
+			URL: "http://127.0.0.1:1/unreachable",
+		}, &resp)
+		require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+
+		// Skip the retry backoff
+		for i := 0; i < 3; i++ {
+			api.jobRepo.mu.Lock()
+			for _, job := range api.jobRepo.jobs {
+				job.ScheduledAt = nil
+			}
+			api.jobRepo.mu.Unlock()
+			api.runJobs()
+		}
+
+		article, err := api.articleRepo.GetByID(resp.Article.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.ExtractionStatusFailed, article.ExtractionStatus)
+		assert.NotNil(t, article.ExtractionError)
+		assert.Equal(t, models.SummaryStatusFailed, article.SummaryGenerationStatus)
+	})
+
+	t.Run("job abandoned by stopped workers fails", func(t *testing.T) {
+		var resp ArticleResponse
+		rec := api.do(http.MethodPost, "/api/v1/articles", "user-1", SaveArticleRequest{
+			URL: server.URL + "/posts/3",
+		}, &resp)
+		require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+
+		// Each worker that claimed the job stopped before finishing it
+		for i := 0; i < 3; i++ {
+			job, err := api.jobRepo.ClaimNextJob(time.Minute)
+			require.NoError(t, err)
+			require.NotNil(t, job)
+			api.jobRepo.abandon(job.ID)
+		}
+
+		processed, err := api.jobService.ProcessNext(context.Background())
+		require.NoError(t, err)
+		assert.True(t, processed)
+
+		article, err := api.articleRepo.GetByID(resp.Article.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.ExtractionStatusFailed, article.ExtractionStatus)
+		assert.Equal(t, models.SummaryStatusFailed, article.SummaryGenerationStatus)
+	})
+}
+
+func TestArticleController_SaveArticle_DuplicateFoundWhileScraping(t *testing.T) {
+	api := newTestAPI(t)
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	mux.HandleFunc("/posts/1", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(`<html><head><title>Canonical post</title>
+<link rel="canonical" href="/articles/canonical-post"></head>
+<body><article>The same post is reachable under several addresses.</article></body></html>`))
+	})
+	mux.HandleFunc("/short/1", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/posts/1?utm_source=short", http.StatusFound)
+	})
+
+	var first ArticleResponse
+	rec := api.do(http.MethodPost, "/api/v1/articles", "user-1", SaveArticleRequest{URL: server.URL + "/posts/1"}, &first)
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+	api.runJobs()
+
+	article, err := api.articleRepo.GetByID(first.Article.ID)
+	require.NoError(t, err)
+	require.NotNil(t, article.CanonicalURL)
+	assert.Equal(t, server.URL+"/articles/canonical-post", *article.CanonicalURL)
+
+	// The canonical URL is known once the first article has been scraped
+	rec = api.do(http.MethodPost, "/api/v1/articles", "user-1", SaveArticleRequest{URL: server.URL + "/articles/canonical-post"}, nil)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	// A redirect is only followed while scraping; the duplicate is removed then
+	var second ArticleResponse
+	rec = api.do(http.MethodPost, "/api/v1/articles", "user-1", SaveArticleRequest{URL: server.URL + "/short/1"}, &second)
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+
+	events, unsubscribe := api.events.Subscribe(second.Article.ID)
+	defer unsubscribe()
+	api.runJobs()
+
+	_, err = api.articleRepo.GetByID(second.Article.ID)
+	assert.Error(t, err)
+	select {
+	case event := <-events:
+		assert.Equal(t, first.Article.ID, event.DuplicateOfID)
+		assert.True(t, event.Done())
+	default:
+		t.Fatal("no event for the removed duplicate")
+	}
+
+	rec = api.do(http.MethodPost, "/api/v1/articles", "user-1", SaveArticleRequest{URL: server.URL + "/posts/1"}, nil)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+}
+
+func TestArticleController_StreamArticleEvents(t *testing.T) {
+	api := newTestAPI(t)
+	articleServer := newArticleServer(t)
+	server := httptest.NewServer(api.router)
+	t.Cleanup(server.Close)
+
+	var saved ArticleResponse
+	rec := api.do(http.MethodPost, "/api/v1/articles", "user-1", SaveArticleRequest{
+		URL: articleServer.URL + "/posts/1",
+	}, &saved)
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/api/v1/articles/"+saved.Article.ID+"/events", nil)
+	require.NoError(t, err)
+	req.Header.Set(testUserHeader, "user-1")
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Contains(t, res.Header.Get("Content-Type"), "text/event-stream")
+
+	events := make(chan services.ArticleEvent)
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(res.Body)
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data:")
+			if !ok {
+				continue
+			}
+			var event services.ArticleEvent
+			if json.Unmarshal([]byte(data), &event) == nil {
+				events <- event
+			}
+		}
+	}()
+
+	first := <-events
+	assert.Equal(t, models.ExtractionStatusPending, first.ExtractionStatus)
+
+	go api.runJobs()
+
+	var last services.ArticleEvent
+	for event := range events {
+		last = event
+	}
+	// The stream ends once both jobs have finished
+	assert.Equal(t, models.ExtractionStatusCompleted, last.ExtractionStatus)
+	assert.Equal(t, models.SummaryStatusCompleted, last.SummaryGenerationStatus)
+
+	t.Run("other users cannot subscribe", func(t *testing.T) {
+		rec := api.do(http.MethodGet, "/api/v1/articles/"+saved.Article.ID+"/events", "user-2", nil, nil)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
+
+func seedArticle(t *testing.T, api *testAPI, userID, title string) *models.Article {
+	t.Helper()
+
+	content := title + " content"
+	article := &models.Article{
+		ID:      title + "-" + userID,
+		UserID:  userID,
+		URL:     "https://example.com/" + title,
+		Title:   title,
+		Content: &content,
+		Status:  models.ArticleStatusUnread,
+		// Seeded articles are already extracted
+		ExtractionStatus: models.ExtractionStatusCompleted,
+	}
+	require.NoError(t, api.articleRepo.Create(article))
+	api.search.Refresh(article.ID)
+	return article
+}
+
+func TestArticleController_GetArticles(t *testing.T) {
+	api := newTestAPI(t)
+	seedArticle(t, api, "user-1", "golang")
+	seedArticle(t, api, "user-1", "rust")
+	favorite := seedArticle(t, api, "user-1", "python")
+	seedArticle(t, api, "user-2", "golang")
+	require.NoError(t, api.articleRepo.UpdateFavorite(favorite.ID, "user-1", true))
+
+	var list ArticleListResponse
+	rec := api.do(http.MethodGet, "/api/v1/articles?limit=2", "user-1", nil, &list)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NotNil(t, list.Total)
+	assert.Equal(t, int64(3), *list.Total)
+	assert.Len(t, list.Articles, 2)
+	assert.Equal(t, 2, list.Limit)
+
+	rec = api.do(http.MethodGet, "/api/v1/articles?favorite=true", "user-1", nil, &list)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Len(t, list.Articles, 1)
+	assert.Equal(t, favorite.ID, list.Articles[0].ID)
+
+	rec = api.do(http.MethodGet, "/api/v1/articles?search=RUST", "user-1", nil, &list)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Len(t, list.Articles, 1)
+	assert.Equal(t, "rust", list.Articles[0].Title)
+}
+
+func TestArticleController_GetArticles_Query(t *testing.T) {
+	api := newTestAPI(t)
+	tags, err := api.tagRepo.GetOrCreateMultiple("user-1", []string{"go", "draft"})
+	require.NoError(t, err)
+
+	published := &models.Article{
+		ID:       "published",
+		UserID:   "user-1",
+		URL:      "https://zenn.dev/gopher/articles/generics",
+		Title:    "Go generics",
+		Status:   models.ArticleStatusUnread,
+		Language: "en",
+	}
+	require.NoError(t, api.articleRepo.CreateWithTags(published, []string{tags[0].ID}))
+	draft := &models.Article{
+		ID:       "draft",
+		UserID:   "user-1",
+		URL:      "https://example.com/draft",
+		Title:    "Go draft",
+		Status:   models.ArticleStatusUnread,
+		Language: "ja",
+	}
+	require.NoError(t, api.articleRepo.CreateWithTags(draft, []string{tags[0].ID, tags[1].ID}))
+	require.NoError(t, api.articleRepo.UpdateFavorite(draft.ID, "user-1", true))
+	seedArticle(t, api, "user-1", "rust")
+
+	list := func(query string) []string {
+		t.Helper()
+		var list ArticleListResponse
+		rec := api.do(http.MethodGet, "/api/v1/articles?q="+url.QueryEscape(query), "user-1", nil, &list)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		ids := []string{}
+		for _, article := range list.Articles {
+			ids = append(ids, article.ID)
+		}
+		return ids
+	}
+	assert.ElementsMatch(t, []string{"published", "draft"}, list("tag:go"))
+	assert.Equal(t, []string{"published"}, list("tag:go -tag:draft site:zenn.dev lang:en"))
+	assert.ElementsMatch(t, []string{"draft", "rust-user-1"}, list("is:favorite OR rust"))
+	assert.Len(t, list(""), 3)
+
+	t.Run("invalid queries point at the token", func(t *testing.T) {
+		var resp QueryErrorResponse
+		rec := api.do(http.MethodGet, "/api/v1/articles?q="+url.QueryEscape("tag:go saved:>soon"), "user-1", nil, &resp)
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, "invalid_query", resp.Error)
+		assert.Equal(t, 7, resp.Position)
+		assert.Equal(t, "saved:>soon", resp.Token)
+		assert.Contains(t, resp.Message, "soon")
+	})
+}
+
+func TestArticleController_ListPagination(t *testing.T) {
+	api := newTestAPI(t)
+	for _, title := range []string{"alpha", "bravo", "charlie", "delta", "echo"} {
+		seedArticle(t, api, "user-1", title)
+	}
+
+	titles := func(list ArticleListResponse) []string {
+		var out []string
+		for _, article := range list.Articles {
+			out = append(out, article.Title)
+		}
+		return out
+	}
+
+	var first, second, last ArticleListResponse
+	rec := api.do(http.MethodGet, "/api/v1/articles?sort=title&order=asc&limit=2&include_total=false", "user-1", nil, &first)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, []string{"alpha", "bravo"}, titles(first))
+	assert.Nil(t, first.Total)
+	require.NotEmpty(t, first.NextCursor)
+
+	rec = api.do(http.MethodGet, "/api/v1/articles?sort=title&order=asc&limit=2&cursor="+first.NextCursor, "user-1", nil, &second)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, []string{"charlie", "delta"}, titles(second))
+	require.NotNil(t, second.Total)
+	assert.Equal(t, int64(5), *second.Total)
+
+	rec = api.do(http.MethodGet, "/api/v1/articles?sort=title&order=asc&limit=2&cursor="+second.NextCursor, "user-1", nil, &last)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []string{"echo"}, titles(last))
+	assert.Empty(t, last.NextCursor)
+
+	t.Run("invalid parameters are rejected", func(t *testing.T) {
+		for _, query := range []string{
+			"limit=0",
+			"limit=101",
+			"page=0",
+			"sort=popularity",
+			"order=up",
+			"include_total=maybe",
+			"page=2&cursor=" + first.NextCursor,
+		} {
+			rec := api.do(http.MethodGet, "/api/v1/articles?"+query, "user-1", nil, nil)
+			assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+		}
+
+		var errResp ErrorResponse
+		rec := api.do(http.MethodGet, "/api/v1/articles?sort=title&cursor="+first.NextCursor, "user-1", nil, &errResp)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, "invalid_cursor", errResp.Error)
+	})
+
+	t.Run("favorites and recently read", func(t *testing.T) {
+		require.NoError(t, api.articleRepo.UpdateFavorite("bravo-user-1", "user-1", true))
+		require.NoError(t, api.articleRepo.UpdateFavorite("delta-user-1", "user-1", true))
+
+		var list ArticleListResponse
+		rec := api.do(http.MethodGet, "/api/v1/articles/favorites?sort=title&order=desc", "user-1", nil, &list)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.Equal(t, []string{"delta", "bravo"}, titles(list))
+
+		for _, id := range []string{"charlie-user-1", "alpha-user-1", "echo-user-1"} {
+			require.NoError(t, api.articleRepo.UpdateStatus(id, "user-1", models.ArticleStatusRead))
+		}
+		require.NoError(t, api.articleRepo.MarkAsAccessed("charlie-user-1"))
+		require.NoError(t, api.articleRepo.MarkAsAccessed("alpha-user-1"))
+		opened := api.store.articles["charlie-user-1"].LastAccessedAt.Add(-time.Minute)
+		api.store.articles["charlie-user-1"].LastAccessedAt = &opened
+
+		rec = api.do(http.MethodGet, "/api/v1/articles/recent", "user-1", nil, &list)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.Equal(t, []string{"alpha", "charlie"}, titles(list), "unopened articles are left out")
+	})
+}
+
+func TestArticleController_GetArticle(t *testing.T) {
+	api := newTestAPI(t)
+	article := seedArticle(t, api, "user-1", "golang")
+
+	var resp map[string]*models.Article
+	rec := api.do(http.MethodGet, "/api/v1/articles/"+article.ID, "user-1", nil, &resp)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, article.ID, resp["article"].ID)
+
+	stored, err := api.articleRepo.GetByID(article.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, stored.LastAccessedAt)
+
+	rec = api.do(http.MethodGet, "/api/v1/articles/"+article.ID, "user-2", nil, nil)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = api.do(http.MethodGet, "/api/v1/articles/missing", "user-1", nil, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestArticleController_UpdateArticle(t *testing.T) {
+	api := newTestAPI(t)
+	article := seedArticle(t, api, "user-1", "golang")
+	category := &models.Category{ID: "cat-1", UserID: "user-1", Name: "Tech"}
+	require.NoError(t, api.categoryRepo.Create(category))
+
+	status := models.ArticleStatusRead
+	favorite := true
+	progress := 0.5
+	rec := api.do(http.MethodPatch, "/api/v1/articles/"+article.ID, "user-1", UpdateArticleRequest{
+		Status:          &status,
+		IsFavorite:      &favorite,
+		ReadingProgress: &progress,
+		CategoryID:      &category.ID,
+	}, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	updated, err := api.articleRepo.GetByID(article.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ArticleStatusRead, updated.Status)
+	assert.True(t, updated.IsFavorite)
+	assert.Equal(t, 0.5, updated.ReadingProgress)
+	require.NotNil(t, updated.CategoryID)
+	assert.Equal(t, category.ID, *updated.CategoryID)
+
+	// Tags are replaced when given and kept otherwise
+	tags := []string{"go", "backend"}
+	rec = api.do(http.MethodPatch, "/api/v1/articles/"+article.ID, "user-1", UpdateArticleRequest{Tags: &tags}, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = api.do(http.MethodPatch, "/api/v1/articles/"+article.ID, "user-1", UpdateArticleRequest{Status: &status}, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	withTags, err := api.articleRepo.GetByIDWithAssociations(article.ID)
+	require.NoError(t, err)
+	assert.Len(t, withTags.Tags, 2)
+
+	rec = api.do(http.MethodPatch, "/api/v1/articles/"+article.ID, "user-2", UpdateArticleRequest{Status: &status}, nil)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	foreign := &models.Category{ID: "cat-2", UserID: "user-2", Name: "Other"}
+	require.NoError(t, api.categoryRepo.Create(foreign))
+	rec = api.do(http.MethodPatch, "/api/v1/articles/"+article.ID, "user-1", UpdateArticleRequest{CategoryID: &foreign.ID}, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestArticleController_UpdateArticle_ExtractedMeanwhile(t *testing.T) {
+	api := newTestAPI(t)
+	server := newArticleServer(t)
+	_, err := api.categoryRepo.CreateDefault("user-1")
+	require.NoError(t, err)
+	category := &models.Category{ID: "cat-1", UserID: "user-1", Name: "Tech"}
+	require.NoError(t, api.categoryRepo.Create(category))
+
+	var resp ArticleResponse
+	rec := api.do(http.MethodPost, "/api/v1/articles", "user-1", SaveArticleRequest{URL: server.URL + "/posts/1"}, &resp)
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+
+	// The article is extracted and summarized after the PATCH has loaded it
+	api.articleRepo.afterGet = api.runJobs
+	rec = api.do(http.MethodPatch, "/api/v1/articles/"+resp.Article.ID, "user-1", UpdateArticleRequest{CategoryID: &category.ID}, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	updated, err := api.articleRepo.GetByID(resp.Article.ID)
+	require.NoError(t, err)
+	require.NotNil(t, updated.CategoryID)
+	assert.Equal(t, category.ID, *updated.CategoryID)
+	assert.Equal(t, "Go Concurrency Patterns", updated.Title)
+	assert.Equal(t, models.ExtractionStatusCompleted, updated.ExtractionStatus)
+	assert.Equal(t, models.SummaryStatusCompleted, updated.SummaryGenerationStatus)
+	require.NotNil(t, updated.Summary)
+	assert.Equal(t, "Summary of Go Concurrency Patterns", *updated.Summary)
+}
+
+func TestArticleController_DeleteArticle(t *testing.T) {
+	api := newTestAPI(t)
+	article := seedArticle(t, api, "user-1", "golang")
+
+	rec := api.do(http.MethodDelete, "/api/v1/articles/"+article.ID, "user-2", nil, nil)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = api.do(http.MethodDelete, "/api/v1/articles/"+article.ID, "user-1", nil, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	_, err := api.articleRepo.GetByID(article.ID)
+	assert.Error(t, err)
+}
+
+func TestArticleController_SearchArticles(t *testing.T) {
+	api := newTestAPI(t)
+	seedArticle(t, api, "user-1", "golang")
+	seedArticle(t, api, "user-1", "rust")
+	seedArticle(t, api, "user-2", "golang-internals")
+
+	var resp SearchResponse
+	rec := api.do(http.MethodGet, "/api/v1/articles/search?q=GOLANG", "user-1", nil, &resp)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Len(t, resp.Hits, 1)
+	assert.Equal(t, 1, resp.Total)
+	assert.Equal(t, "golang", resp.Hits[0].Article.Title)
+	assert.Equal(t, "<mark>golang</mark>", resp.Hits[0].Highlights.Title)
+	assert.Equal(t, "<mark>golang</mark> content", resp.Hits[0].Highlights.Snippet)
+
+	rec = api.do(http.MethodGet, "/api/v1/articles/search", "user-1", nil, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = api.do(http.MethodGet, "/api/v1/articles/search?q=go&limit=1000", "user-1", nil, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	t.Run("Japanese text is found by any part of a phrase", func(t *testing.T) {
+		article := seedArticle(t, api, "user-1", "Goの並行処理入門")
+		summary := "ゴルーチンとチャネルで並行処理を書く方法"
+		require.NoError(t, api.articleRepo.UpdateSummary(&models.Article{ID: article.ID, Summary: &summary}, models.SummaryTypeMedium))
+		api.search.Refresh(article.ID)
+
+		var resp SearchResponse
+		rec := api.do(http.MethodGet, "/api/v1/articles/search?q=チャネル", "user-1", nil, &resp)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		require.Len(t, resp.Hits, 1)
+		assert.Equal(t, article.ID, resp.Hits[0].Article.ID)
+		assert.Equal(t, "ゴルーチンと<mark>チャネル</mark>で並行処理を書く方法", resp.Hits[0].Highlights.Snippet)
+
+		rec = api.do(http.MethodGet, "/api/v1/articles/search?q=並列処理", "user-1", nil, &resp)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, resp.Hits)
+	})
+
+	t.Run("hits are ranked and paginated", func(t *testing.T) {
+		inTitle := seedArticle(t, api, "user-1", "kubernetes")
+		inBody := seedArticle(t, api, "user-1", "operators")
+		content := "Writing operators for kubernetes"
+		require.NoError(t, api.articleRepo.UpdateExtraction(&models.Article{ID: inBody.ID, Title: inBody.Title, Content: &content}))
+		api.search.Refresh(inBody.ID)
+
+		var resp SearchResponse
+		rec := api.do(http.MethodGet, "/api/v1/articles/search?q=kubernetes&limit=1", "user-1", nil, &resp)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, 2, resp.Total)
+		require.Len(t, resp.Hits, 1)
+		assert.Equal(t, inTitle.ID, resp.Hits[0].Article.ID)
+
+		rec = api.do(http.MethodGet, "/api/v1/articles/search?q=kubernetes&limit=1&page=2", "user-1", nil, &resp)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Len(t, resp.Hits, 1)
+		assert.Equal(t, inBody.ID, resp.Hits[0].Article.ID)
+
+		var first, next SearchResponse
+		rec = api.do(http.MethodGet, "/api/v1/articles/search?q=kubernetes&limit=1", "user-1", nil, &first)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.NotEmpty(t, first.NextCursor)
+		rec = api.do(http.MethodGet, "/api/v1/articles/search?q=kubernetes&limit=1&cursor="+first.NextCursor, "user-1", nil, &next)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		require.Len(t, next.Hits, 1)
+		assert.Equal(t, inBody.ID, next.Hits[0].Article.ID)
+		assert.Empty(t, next.NextCursor)
+
+		rec = api.do(http.MethodGet, "/api/v1/articles/search?q=kubernetes&cursor=bogus", "user-1", nil, nil)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("deleted articles and renamed tags are reindexed", func(t *testing.T) {
+		var saved ArticleResponse
+		rec := api.do(http.MethodPost, "/api/v1/articles", "user-1", SaveArticleRequest{
+			URL:  "https://example.com/tagged",
+			Tags: []string{"terraform"},
+		}, &saved)
+		require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+
+		var resp SearchResponse
+		api.do(http.MethodGet, "/api/v1/articles/search?q=terraform", "user-1", nil, &resp)
+		require.Len(t, resp.Hits, 1)
+
+		tagID := saved.Article.Tags[0].ID
+		rec = api.do(http.MethodPatch, "/api/v1/tags/"+tagID, "user-1", RenameTagRequest{Name: "opentofu"}, nil)
+		require.Equal(t, http.StatusOK, rec.Code)
+		api.do(http.MethodGet, "/api/v1/articles/search?q=terraform", "user-1", nil, &resp)
+		assert.Empty(t, resp.Hits)
+		api.do(http.MethodGet, "/api/v1/articles/search?q=opentofu", "user-1", nil, &resp)
+		require.Len(t, resp.Hits, 1)
+
+		rec = api.do(http.MethodDelete, "/api/v1/articles/"+saved.Article.ID, "user-1", nil, nil)
+		require.Equal(t, http.StatusOK, rec.Code)
+		api.do(http.MethodGet, "/api/v1/articles/search?q=opentofu", "user-1", nil, &resp)
+		assert.Empty(t, resp.Hits)
+	})
+}
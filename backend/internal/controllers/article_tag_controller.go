@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/eikuma/stockle/backend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// ArticleTagsRequest names tags; missing tags are created
+type ArticleTagsRequest struct {
+	Tags []string `json:"tags" binding:"required,max=50,dive,max=50"`
+}
+
+// AddArticleTags attaches tags to an article, keeping the ones it has
+// POST /api/v1/articles/:id/tags
+func (c *ArticleController) AddArticleTags(ctx *gin.Context) {
+	c.changeArticleTags(ctx, false)
+}
+
+// ReplaceArticleTags makes the given tags the only tags of an article; an
+// empty list removes all of them
+// PUT /api/v1/articles/:id/tags
+func (c *ArticleController) ReplaceArticleTags(ctx *gin.Context) {
+	c.changeArticleTags(ctx, true)
+}
+
+func (c *ArticleController) changeArticleTags(ctx *gin.Context, replace bool) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	var req ArticleTagsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid JSON format: " + err.Error(),
+		})
+		return
+	}
+	if !replace && len(req.Tags) == 0 {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "At least one tag is required",
+		})
+		return
+	}
+
+	article, ok := c.getOwnedArticle(ctx, userID)
+	if !ok {
+		return
+	}
+
+	tagIDs, ok := c.resolveTagIDs(ctx, userID, req.Tags)
+	if !ok {
+		return
+	}
+
+	change := c.articleRepo.AddTags
+	if replace {
+		change = c.articleRepo.ReplaceTags
+	}
+	if err := change(article.ID, userID, tagIDs); err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "update_failed",
+			Message: "Failed to update tags: " + err.Error(),
+		})
+		return
+	}
+
+	c.respondWithTags(ctx, article.ID)
+}
+
+// RemoveArticleTag detaches a tag from an article; the tag itself is kept
+// DELETE /api/v1/articles/:id/tags/:tagId
+func (c *ArticleController) RemoveArticleTag(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	article, ok := c.getOwnedArticle(ctx, userID)
+	if !ok {
+		return
+	}
+
+	tag, err := c.tagRepo.GetByID(ctx.Param("tagId"))
+	if err != nil || tag.UserID != userID {
+		ctx.JSON(http.StatusNotFound, ErrorResponse{
+			Error:   "not_found",
+			Message: "Tag not found",
+		})
+		return
+	}
+
+	if err := c.articleRepo.RemoveTags(article.ID, userID, []string{tag.ID}); err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "update_failed",
+			Message: "Failed to remove tag: " + err.Error(),
+		})
+		return
+	}
+
+	c.respondWithTags(ctx, article.ID)
+}
+
+// resolveTagIDs returns the IDs of the named tags, creating the missing ones
+func (c *ArticleController) resolveTagIDs(ctx *gin.Context, userID string, names []string) ([]string, bool) {
+	tags, err := c.tagRepo.GetOrCreateMultiple(userID, names)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "update_failed",
+			Message: "Failed to create tags: " + err.Error(),
+		})
+		return nil, false
+	}
+
+	tagIDs := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tagIDs = append(tagIDs, tag.ID)
+	}
+	return tagIDs, true
+}
+
+// respondWithTags indexes the article again, since its tags are searchable,
+// and responds with it
+func (c *ArticleController) respondWithTags(ctx *gin.Context, articleID string) {
+	c.search.Refresh(articleID)
+
+	article, err := c.articleRepo.GetByIDWithAssociations(articleID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "fetch_failed",
+			Message: "Tags updated but failed to fetch the article",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, ArticleResponse{
+		Message: "Tags updated successfully",
+		Article: article,
+	})
+}
+
+// getOwnedArticle loads the article in the :id path parameter and writes
+// the error response itself when it is missing or owned by someone else
+func (c *ArticleController) getOwnedArticle(ctx *gin.Context, userID string) (*models.Article, bool) {
+	articleID := ctx.Param("id")
+	if articleID == "" {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Article ID is required",
+		})
+		return nil, false
+	}
+
+	article, err := c.articleRepo.GetByID(articleID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, ErrorResponse{
+			Error:   "not_found",
+			Message: "Article not found",
+		})
+		return nil, false
+	}
+
+	if article.UserID != userID {
+		ctx.JSON(http.StatusForbidden, ErrorResponse{
+			Error:   "forbidden",
+			Message: "Access denied",
+		})
+		return nil, false
+	}
+
+	return article, true
+}
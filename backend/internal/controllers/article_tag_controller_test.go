@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArticleController_ArticleTags(t *testing.T) {
+	api := newTestAPI(t)
+	article := seedArticle(t, api, "user-1", "golang")
+	path := "/api/v1/articles/" + article.ID + "/tags"
+
+	tagNames := func(resp ArticleResponse) []string {
+		var names []string
+		for _, tag := range resp.Article.Tags {
+			names = append(names, tag.Name)
+		}
+		return names
+	}
+
+	// Names differing only in case or width are the same tag
+	var resp ArticleResponse
+	rec := api.do(http.MethodPost, path, "user-1", ArticleTagsRequest{Tags: []string{"Go", "ｇｏ", "backend"}}, &resp)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.ElementsMatch(t, []string{"Go", "backend"}, tagNames(resp))
+
+	rec = api.do(http.MethodPost, path, "user-1", ArticleTagsRequest{Tags: []string{"GO", "web"}}, &resp)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.ElementsMatch(t, []string{"Go", "backend", "web"}, tagNames(resp))
+
+	goTag, err := api.tagRepo.GetOrCreate("user-1", "go")
+	require.NoError(t, err)
+	assert.Equal(t, 1, goTag.UsageCount)
+
+	// Tags are searchable right away
+	var search SearchResponse
+	rec = api.do(http.MethodGet, "/api/v1/articles/search?q=backend", "user-1", nil, &search)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 1, search.Total)
+
+	rec = api.do(http.MethodDelete, path+"/"+goTag.ID, "user-1", nil, &resp)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.ElementsMatch(t, []string{"backend", "web"}, tagNames(resp))
+	goTag, err = api.tagRepo.GetByID(goTag.ID)
+	require.NoError(t, err)
+	assert.Zero(t, goTag.UsageCount)
+
+	rec = api.do(http.MethodPut, path, "user-1", ArticleTagsRequest{Tags: []string{"rust"}}, &resp)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, []string{"rust"}, tagNames(resp))
+
+	rec = api.do(http.MethodPut, path, "user-1", ArticleTagsRequest{Tags: []string{}}, &resp)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Empty(t, resp.Article.Tags)
+
+	t.Run("errors", func(t *testing.T) {
+		foreignTag, err := api.tagRepo.GetOrCreate("user-2", "theirs")
+		require.NoError(t, err)
+
+		rec := api.do(http.MethodPost, path, "user-1", ArticleTagsRequest{Tags: []string{}}, nil)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		rec = api.do(http.MethodPost, path, "user-2", ArticleTagsRequest{Tags: []string{"mine"}}, nil)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		rec = api.do(http.MethodPut, "/api/v1/articles/missing/tags", "user-1", ArticleTagsRequest{Tags: []string{"go"}}, nil)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		rec = api.do(http.MethodDelete, path+"/"+foreignTag.ID, "user-1", nil, nil)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		rec = api.do(http.MethodPost, path, "", ArticleTagsRequest{Tags: []string{"go"}}, nil)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
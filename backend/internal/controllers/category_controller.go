@@ -0,0 +1,428 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/eikuma/stockle/backend/internal/models"
+	"github.com/eikuma/stockle/backend/internal/repositories"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type CategoryController struct {
+	categoryRepo repositories.CategoryRepository
+}
+
+type CreateCategoryRequest struct {
+	Name     string  `json:"name" binding:"required,max=100"`
+	Color    string  `json:"color,omitempty" binding:"omitempty,hexcolor,len=7"`
+	ParentID *string `json:"parentId,omitempty"`
+}
+
+type UpdateCategoryRequest struct {
+	Name         *string `json:"name,omitempty" binding:"omitempty,max=100"`
+	Color        *string `json:"color,omitempty" binding:"omitempty,hexcolor,len=7"`
+	DisplayOrder *int    `json:"displayOrder,omitempty" binding:"omitempty,min=0"`
+}
+
+// MoveCategoryRequest names the new parent; null or empty moves the
+// category to the top level
+type MoveCategoryRequest struct {
+	ParentID *string `json:"parentId"`
+}
+
+type ReorderCategoriesRequest struct {
+	CategoryIDs []string `json:"categoryIds" binding:"required,min=1,unique"`
+}
+
+type CategoryResponse struct {
+	Message  string           `json:"message"`
+	Category *models.Category `json:"category,omitempty"`
+}
+
+type CategoryListResponse struct {
+	Categories []*models.Category `json:"categories"`
+}
+
+func NewCategoryController(categoryRepo repositories.CategoryRepository) *CategoryController {
+	return &CategoryController{
+		categoryRepo: categoryRepo,
+	}
+}
+
+// GetCategories retrieves the user's categories with article counts
+// GET /api/v1/categories
+func (c *CategoryController) GetCategories(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	categories, err := c.categoryRepo.GetByUserIDWithCounts(userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "fetch_failed",
+			Message: "Failed to fetch categories: " + err.Error(),
+		})
+		return
+	}
+
+	if categories == nil {
+		categories = []*models.Category{}
+	}
+
+	ctx.JSON(http.StatusOK, CategoryListResponse{
+		Categories: categories,
+	})
+}
+
+// CreateCategory creates a new category
+// POST /api/v1/categories
+func (c *CategoryController) CreateCategory(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	var req CreateCategoryRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid JSON format: " + err.Error(),
+		})
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Category name is required",
+		})
+		return
+	}
+
+	color := req.Color
+	if color == "" {
+		color = "#6B7280"
+	}
+
+	parentID, ok := c.resolveParentID(ctx, userID, req.ParentID)
+	if !ok {
+		return
+	}
+
+	// New categories are appended after the existing ones
+	existing, err := c.categoryRepo.GetByUserID(userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "fetch_failed",
+			Message: "Failed to fetch categories: " + err.Error(),
+		})
+		return
+	}
+
+	displayOrder := 0
+	for _, category := range existing {
+		if category.DisplayOrder >= displayOrder {
+			displayOrder = category.DisplayOrder + 1
+		}
+	}
+
+	category := &models.Category{
+		ID:           uuid.New().String(),
+		UserID:       userID,
+		ParentID:     parentID,
+		Name:         name,
+		Color:        color,
+		DisplayOrder: displayOrder,
+		IsDefault:    false,
+	}
+
+	if err := c.categoryRepo.Create(category); err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "create_failed",
+			Message: "Failed to create category: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, CategoryResponse{
+		Message:  "Category created successfully",
+		Category: category,
+	})
+}
+
+// UpdateCategory updates name, color or display order of a category
+// PUT /api/v1/categories/:id
+func (c *CategoryController) UpdateCategory(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	var req UpdateCategoryRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid JSON format: " + err.Error(),
+		})
+		return
+	}
+
+	category, ok := c.getOwnedCategory(ctx, userID)
+	if !ok {
+		return
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "invalid_request",
+				Message: "Category name must not be empty",
+			})
+			return
+		}
+		category.Name = name
+	}
+
+	if req.Color != nil {
+		category.Color = *req.Color
+	}
+
+	if req.DisplayOrder != nil {
+		category.DisplayOrder = *req.DisplayOrder
+	}
+
+	if err := c.categoryRepo.Update(category); err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "update_failed",
+			Message: "Failed to update category: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, CategoryResponse{
+		Message:  "Category updated successfully",
+		Category: category,
+	})
+}
+
+// MoveCategory moves a category below another one or to the top level,
+// together with its descendants
+// POST /api/v1/categories/:id/move
+func (c *CategoryController) MoveCategory(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	var req MoveCategoryRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid JSON format: " + err.Error(),
+		})
+		return
+	}
+
+	category, ok := c.getOwnedCategory(ctx, userID)
+	if !ok {
+		return
+	}
+
+	parentID, ok := c.resolveParentID(ctx, userID, req.ParentID)
+	if !ok {
+		return
+	}
+
+	if err := c.categoryRepo.Move(category.ID, userID, parentID); err != nil {
+		if errors.Is(err, repositories.ErrCategoryCycle) {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "invalid_parent",
+				Message: "A category cannot be moved below itself or its descendants",
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "update_failed",
+			Message: "Failed to move category: " + err.Error(),
+		})
+		return
+	}
+
+	category.ParentID = parentID
+	ctx.JSON(http.StatusOK, CategoryResponse{
+		Message:  "Category moved successfully",
+		Category: category,
+	})
+}
+
+// DeleteCategory deletes a category. Its subcategories move up one level and
+// its articles go to the default category, or to the parent category with
+// ?reassign=parent.
+// DELETE /api/v1/categories/:id
+func (c *CategoryController) DeleteCategory(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	reassign := repositories.ReassignTo(ctx.DefaultQuery("reassign", string(repositories.ReassignToDefault)))
+	if reassign != repositories.ReassignToDefault && reassign != repositories.ReassignToParent {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "reassign must be default or parent",
+		})
+		return
+	}
+
+	category, ok := c.getOwnedCategory(ctx, userID)
+	if !ok {
+		return
+	}
+
+	if category.IsDefault {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "The default category cannot be deleted",
+		})
+		return
+	}
+
+	if err := c.categoryRepo.Delete(category.ID, userID, reassign); err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "delete_failed",
+			Message: "Failed to delete category: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, CategoryResponse{
+		Message: "Category deleted successfully",
+	})
+}
+
+// ReorderCategories sets DisplayOrder according to the given ID order
+// PUT /api/v1/categories/reorder
+func (c *CategoryController) ReorderCategories(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	var req ReorderCategoriesRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid JSON format: " + err.Error(),
+		})
+		return
+	}
+
+	if err := c.categoryRepo.Reorder(userID, req.CategoryIDs); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "invalid_request",
+				Message: "One or more categories were not found",
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "update_failed",
+			Message: "Failed to reorder categories: " + err.Error(),
+		})
+		return
+	}
+
+	categories, err := c.categoryRepo.GetByUserID(userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "fetch_failed",
+			Message: "Failed to fetch categories: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, CategoryListResponse{
+		Categories: categories,
+	})
+}
+
+// getOwnedCategory loads the category in the :id path parameter and writes
+// the error response itself when it is missing or owned by someone else
+func (c *CategoryController) getOwnedCategory(ctx *gin.Context, userID string) (*models.Category, bool) {
+	categoryID := ctx.Param("id")
+	if categoryID == "" {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Category ID is required",
+		})
+		return nil, false
+	}
+
+	category, err := c.categoryRepo.GetByID(categoryID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, ErrorResponse{
+			Error:   "not_found",
+			Message: "Category not found",
+		})
+		return nil, false
+	}
+
+	if category.UserID != userID {
+		ctx.JSON(http.StatusForbidden, ErrorResponse{
+			Error:   "forbidden",
+			Message: "Access denied",
+		})
+		return nil, false
+	}
+
+	return category, true
+}
+
+// resolveParentID validates that the requested parent category belongs to
+// the user. Nil and empty IDs mean the top level.
+func (c *CategoryController) resolveParentID(ctx *gin.Context, userID string, parentID *string) (*string, bool) {
+	if parentID == nil || *parentID == "" {
+		return nil, true
+	}
+
+	parent, err := c.categoryRepo.GetByID(*parentID)
+	if err != nil || parent.UserID != userID {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_parent",
+			Message: "Parent category not found",
+		})
+		return nil, false
+	}
+	return &parent.ID, true
+}
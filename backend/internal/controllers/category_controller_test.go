@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/eikuma/stockle/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCategoryController_CRUD(t *testing.T) {
+	api := newTestAPI(t)
+	defaultCategory, err := api.categoryRepo.CreateDefault("user-1")
+	require.NoError(t, err)
+
+	var created CategoryResponse
+	rec := api.do(http.MethodPost, "/api/v1/categories", "user-1", CreateCategoryRequest{
+		Name:  "プログラミング",
+		Color: "#10B981",
+	}, &created)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	require.NotNil(t, created.Category)
+	assert.NotEmpty(t, created.Category.ID)
+	assert.Equal(t, 1, created.Category.DisplayOrder)
+	assert.False(t, created.Category.IsDefault)
+
+	article := seedArticle(t, api, "user-1", "golang")
+	article.CategoryID = &created.Category.ID
+	require.NoError(t, api.articleRepo.Update(article))
+
+	var list CategoryListResponse
+	rec = api.do(http.MethodGet, "/api/v1/categories", "user-1", nil, &list)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Len(t, list.Categories, 2)
+	assert.Equal(t, defaultCategory.ID, list.Categories[0].ID)
+	assert.Equal(t, 1, list.Categories[1].ArticleCount)
+
+	name := "Go"
+	color := "#3B82F6"
+	var updated CategoryResponse
+	rec = api.do(http.MethodPut, "/api/v1/categories/"+created.Category.ID, "user-1", UpdateCategoryRequest{
+		Name:  &name,
+		Color: &color,
+	}, &updated)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "Go", updated.Category.Name)
+	assert.Equal(t, "#3B82F6", updated.Category.Color)
+
+	rec = api.do(http.MethodDelete, "/api/v1/categories/"+created.Category.ID, "user-1", nil, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	moved, err := api.articleRepo.GetByID(article.ID)
+	require.NoError(t, err)
+	require.NotNil(t, moved.CategoryID)
+	assert.Equal(t, defaultCategory.ID, *moved.CategoryID)
+}
+
+func TestCategoryController_Validation(t *testing.T) {
+	api := newTestAPI(t)
+	defaultCategory, err := api.categoryRepo.CreateDefault("user-1")
+	require.NoError(t, err)
+	foreign := &models.Category{ID: "foreign", UserID: "user-2", Name: "Other"}
+	require.NoError(t, api.categoryRepo.Create(foreign))
+
+	rec := api.do(http.MethodPost, "/api/v1/categories", "user-1", CreateCategoryRequest{Name: "x", Color: "blue"}, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = api.do(http.MethodPost, "/api/v1/categories", "user-1", CreateCategoryRequest{Name: "   "}, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = api.do(http.MethodDelete, "/api/v1/categories/"+defaultCategory.ID, "user-1", nil, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	name := "mine"
+	rec = api.do(http.MethodPut, "/api/v1/categories/"+foreign.ID, "user-1", UpdateCategoryRequest{Name: &name}, nil)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = api.do(http.MethodDelete, "/api/v1/categories/missing", "user-1", nil, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestCategoryController_ReorderCategories(t *testing.T) {
+	api := newTestAPI(t)
+	for _, id := range []string{"a", "b", "c"} {
+		require.NoError(t, api.categoryRepo.Create(&models.Category{ID: id, UserID: "user-1", Name: id}))
+	}
+	require.NoError(t, api.categoryRepo.Create(&models.Category{ID: "z", UserID: "user-2", Name: "z"}))
+
+	var list CategoryListResponse
+	rec := api.do(http.MethodPut, "/api/v1/categories/reorder", "user-1", ReorderCategoriesRequest{
+		CategoryIDs: []string{"c", "a", "b"},
+	}, &list)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Len(t, list.Categories, 3)
+	assert.Equal(t, "c", list.Categories[0].ID)
+	assert.Equal(t, "a", list.Categories[1].ID)
+	assert.Equal(t, "b", list.Categories[2].ID)
+
+	rec = api.do(http.MethodPut, "/api/v1/categories/reorder", "user-1", ReorderCategoriesRequest{
+		CategoryIDs: []string{"a", "z"},
+	}, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = api.do(http.MethodPut, "/api/v1/categories/reorder", "user-1", ReorderCategoriesRequest{
+		CategoryIDs: []string{"a", "a"},
+	}, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestCategoryController_Hierarchy(t *testing.T) {
+	api := newTestAPI(t)
+	defaultCategory, err := api.categoryRepo.CreateDefault("user-1")
+	require.NoError(t, err)
+
+	create := func(name string, parentID *string) *models.Category {
+		var resp CategoryResponse
+		rec := api.do(http.MethodPost, "/api/v1/categories", "user-1", CreateCategoryRequest{Name: name, ParentID: parentID}, &resp)
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		return resp.Category
+	}
+	tech := create("Tech", nil)
+	golang := create("Go", &tech.ID)
+	web := create("Web", nil)
+
+	inCategory := func(title string, category *models.Category) *models.Article {
+		article := seedArticle(t, api, "user-1", title)
+		article.CategoryID = &category.ID
+		require.NoError(t, api.articleRepo.Update(article))
+		return article
+	}
+	inCategory("rust", tech)
+	generics := inCategory("generics", golang)
+
+	// Web moves below Go, three levels deep
+	var resp CategoryResponse
+	rec := api.do(http.MethodPost, "/api/v1/categories/"+web.ID+"/move", "user-1", MoveCategoryRequest{ParentID: &golang.ID}, &resp)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, golang.ID, *resp.Category.ParentID)
+	inCategory("http", web)
+
+	var list CategoryListResponse
+	rec = api.do(http.MethodGet, "/api/v1/categories", "user-1", nil, &list)
+	require.Equal(t, http.StatusOK, rec.Code)
+	totals := map[string]int{}
+	for _, category := range list.Categories {
+		totals[category.Name] = category.TotalArticleCount
+	}
+	assert.Equal(t, map[string]int{"未分類": 0, "Tech": 3, "Go": 2, "Web": 1}, totals)
+
+	var articles ArticleListResponse
+	rec = api.do(http.MethodGet, "/api/v1/articles?category_id="+tech.ID, "user-1", nil, &articles)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Len(t, articles.Articles, 3)
+	rec = api.do(http.MethodGet, "/api/v1/articles?include_descendants=false&category_id="+tech.ID, "user-1", nil, &articles)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Len(t, articles.Articles, 1)
+
+	t.Run("invalid moves", func(t *testing.T) {
+		foreign := &models.Category{ID: "foreign", UserID: "user-2", Name: "Theirs"}
+		require.NoError(t, api.categoryRepo.Create(foreign))
+
+		rec := api.do(http.MethodPost, "/api/v1/categories/"+tech.ID+"/move", "user-1", MoveCategoryRequest{ParentID: &web.ID}, nil)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		rec = api.do(http.MethodPost, "/api/v1/categories/"+tech.ID+"/move", "user-1", MoveCategoryRequest{ParentID: &foreign.ID}, nil)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		rec = api.do(http.MethodPost, "/api/v1/categories", "user-1", CreateCategoryRequest{Name: "Child", ParentID: &foreign.ID}, nil)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		rec = api.do(http.MethodPost, "/api/v1/categories/"+foreign.ID+"/move", "user-1", MoveCategoryRequest{}, nil)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		rec = api.do(http.MethodDelete, "/api/v1/categories/"+tech.ID+"?reassign=elsewhere", "user-1", nil, nil)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	// Deleting Go hands its article and Web over to Tech
+	rec = api.do(http.MethodDelete, "/api/v1/categories/"+golang.ID+"?reassign=parent", "user-1", nil, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	article, err := api.articleRepo.GetByID(generics.ID)
+	require.NoError(t, err)
+	assert.Equal(t, tech.ID, *article.CategoryID)
+	moved, err := api.categoryRepo.GetByID(web.ID)
+	require.NoError(t, err)
+	assert.Equal(t, tech.ID, *moved.ParentID)
+
+	// Moving to the top level clears the parent
+	var topLevel CategoryResponse
+	rec = api.do(http.MethodPost, "/api/v1/categories/"+web.ID+"/move", "user-1", MoveCategoryRequest{}, &topLevel)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Nil(t, topLevel.Category.ParentID)
+
+	rec = api.do(http.MethodDelete, "/api/v1/categories/"+tech.ID, "user-1", nil, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	article, err = api.articleRepo.GetByID(generics.ID)
+	require.NoError(t, err)
+	assert.Equal(t, defaultCategory.ID, *article.CategoryID)
+}
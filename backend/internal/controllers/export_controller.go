@@ -0,0 +1,228 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/eikuma/stockle/backend/internal/exporter"
+	"github.com/eikuma/stockle/backend/internal/models"
+	"github.com/eikuma/stockle/backend/internal/repositories"
+	"github.com/eikuma/stockle/backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type ExportController struct {
+	exportRepo    repositories.ExportRepository
+	exportService *services.ExportService
+}
+
+// CreateExportRequest selects the exported articles by ID or with a query,
+// see articlequery. Every article is exported when both are empty.
+type CreateExportRequest struct {
+	Format     string   `json:"format" binding:"required"`
+	ArticleIDs []string `json:"articleIds,omitempty" binding:"omitempty,max=1000,unique"`
+	Query      string   `json:"query,omitempty"`
+}
+
+type ExportResponse struct {
+	Message string         `json:"message,omitempty"`
+	Export  *models.Export `json:"export"`
+	// DownloadURL is a signed link to the file, set once the export completed
+	DownloadURL string `json:"downloadUrl,omitempty"`
+}
+
+type ExportListResponse struct {
+	Exports []*models.Export `json:"exports"`
+}
+
+func NewExportController(
+	exportRepo repositories.ExportRepository,
+	exportService *services.ExportService,
+) *ExportController {
+	return &ExportController{
+		exportRepo:    exportRepo,
+		exportService: exportService,
+	}
+}
+
+// CreateExport starts writing the selected articles to a file in the
+// background
+// POST /api/v1/exports
+func (c *ExportController) CreateExport(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	var req CreateExportRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid JSON format: " + err.Error(),
+		})
+		return
+	}
+
+	format, err := exporter.ParseFormat(req.Format)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "unknown_format",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	query := strings.TrimSpace(req.Query)
+	if len(req.ArticleIDs) > 0 && query != "" {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "articleIds and query cannot be combined",
+		})
+		return
+	}
+	if query != "" {
+		if _, ok := parseArticleQuery(ctx, query); !ok {
+			return
+		}
+	}
+
+	export, err := c.exportService.Start(userID, format, req.ArticleIDs, query)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "export_failed",
+			Message: "Failed to start the export: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, ExportResponse{
+		Message: "Export started; the file is written in the background",
+		Export:  export,
+	})
+}
+
+// GetExports lists the user's exports, newest first
+// GET /api/v1/exports
+func (c *ExportController) GetExports(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	exports, err := c.exportRepo.GetByUserID(userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "fetch_failed",
+			Message: "Failed to fetch exports: " + err.Error(),
+		})
+		return
+	}
+
+	if exports == nil {
+		exports = []*models.Export{}
+	}
+	ctx.JSON(http.StatusOK, ExportListResponse{
+		Exports: exports,
+	})
+}
+
+// GetExport returns the state of an export and, once its file is written,
+// a signed link to download it
+// GET /api/v1/exports/:id
+func (c *ExportController) GetExport(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	export, ok := c.getOwnedExport(ctx, userID)
+	if !ok {
+		return
+	}
+
+	response := ExportResponse{Export: export}
+	if export.Status == models.ExportStatusCompleted {
+		response.DownloadURL = c.exportService.DownloadURL(export)
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+// DownloadExport sends the file of an export. The signature of the link
+// stands in for authentication, so that browsers and e-readers can fetch
+// the file without a token.
+// GET /api/v1/exports/:id/download
+func (c *ExportController) DownloadExport(ctx *gin.Context) {
+	export, err := c.exportService.Download(ctx.Param("id"), ctx.Query("expires"), ctx.Query("signature"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidDownloadLink):
+			ctx.JSON(http.StatusForbidden, ErrorResponse{
+				Error:   "invalid_signature",
+				Message: "The download link is invalid",
+			})
+		case errors.Is(err, services.ErrDownloadLinkExpired):
+			ctx.JSON(http.StatusGone, ErrorResponse{
+				Error:   "link_expired",
+				Message: "The download link or the export expired; request a new one",
+			})
+		case errors.Is(err, services.ErrExportNotReady):
+			ctx.JSON(http.StatusConflict, ErrorResponse{
+				Error:   "not_ready",
+				Message: "The export is not complete",
+			})
+		default:
+			ctx.JSON(http.StatusNotFound, ErrorResponse{
+				Error:   "not_found",
+				Message: "Export not found",
+			})
+		}
+		return
+	}
+
+	format, _ := exporter.ParseFormat(export.Format)
+	ctx.Header("Content-Type", format.ContentType())
+	ctx.FileAttachment(export.FilePath, "stockle-export-"+export.CreatedAt.Format("20060102")+"."+format.Extension())
+}
+
+func (c *ExportController) getOwnedExport(ctx *gin.Context, userID string) (*models.Export, bool) {
+	exportID := ctx.Param("id")
+	if exportID == "" {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Export ID is required",
+		})
+		return nil, false
+	}
+
+	export, err := c.exportRepo.GetByID(exportID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, ErrorResponse{
+			Error:   "not_found",
+			Message: "Export not found",
+		})
+		return nil, false
+	}
+
+	if export.UserID != userID {
+		ctx.JSON(http.StatusForbidden, ErrorResponse{
+			Error:   "forbidden",
+			Message: "Access denied",
+		})
+		return nil, false
+	}
+
+	return export, true
+}
@@ -0,0 +1,155 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eikuma/stockle/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportController_Download(t *testing.T) {
+	api := newTestAPI(t)
+	golang := seedArticle(t, api, "user-1", "golang")
+	seedArticle(t, api, "user-1", "rust")
+	seedArticle(t, api, "user-2", "python")
+	require.NoError(t, api.articleRepo.UpdateFavorite(golang.ID, "user-1", true))
+
+	var created ExportResponse
+	rec := api.do(http.MethodPost, "/api/v1/exports", "user-1", map[string]interface{}{"format": "csv"}, &created)
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+	assert.Equal(t, models.ExportStatusPending, created.Export.Status)
+
+	api.runJobs()
+
+	var got ExportResponse
+	rec = api.do(http.MethodGet, "/api/v1/exports/"+created.Export.ID, "user-1", nil, &got)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, models.ExportStatusCompleted, got.Export.Status)
+	assert.Equal(t, 2, got.Export.ArticleCount)
+	assert.NotZero(t, got.Export.FileSize)
+	require.NotNil(t, got.Export.ExpiresAt)
+	require.NotEmpty(t, got.DownloadURL)
+
+	// The signed link works without authentication
+	rec = api.do(http.MethodGet, got.DownloadURL, "", nil, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "text/csv; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Header().Get("Content-Disposition"), "stockle-export-"+time.Now().Format("20060102")+".csv")
+	records, err := csv.NewReader(strings.NewReader(rec.Body.String())).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.ElementsMatch(t, []string{"golang", "rust"}, []string{records[1][2], records[2][2]})
+
+	// Changing the link breaks the signature
+	link, err := url.Parse(got.DownloadURL)
+	require.NoError(t, err)
+	query := link.Query()
+	query.Set("expires", "99999999999")
+	rec = api.do(http.MethodGet, link.Path+"?"+query.Encode(), "", nil, nil)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	rec = api.do(http.MethodGet, link.Path, "", nil, nil)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// Others cannot see the export
+	rec = api.do(http.MethodGet, "/api/v1/exports/"+created.Export.ID, "user-2", nil, nil)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	var list ExportListResponse
+	rec = api.do(http.MethodGet, "/api/v1/exports", "user-1", nil, &list)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Len(t, list.Exports, 1)
+}
+
+func TestExportController_Selection(t *testing.T) {
+	api := newTestAPI(t)
+	golang := seedArticle(t, api, "user-1", "golang")
+	rust := seedArticle(t, api, "user-1", "rust")
+	other := seedArticle(t, api, "user-2", "python")
+
+	exported := func(body map[string]interface{}) []string {
+		t.Helper()
+		body["format"] = "json"
+		var created ExportResponse
+		rec := api.do(http.MethodPost, "/api/v1/exports", "user-1", body, &created)
+		require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+		api.runJobs()
+
+		var got ExportResponse
+		api.do(http.MethodGet, "/api/v1/exports/"+created.Export.ID, "user-1", nil, &got)
+		require.Equal(t, models.ExportStatusCompleted, got.Export.Status)
+		rec = api.do(http.MethodGet, got.DownloadURL, "", nil, nil)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var file struct {
+			Articles []struct {
+				ID string `json:"id"`
+			} `json:"articles"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &file))
+		var ids []string
+		for _, article := range file.Articles {
+			ids = append(ids, article.ID)
+		}
+		return ids
+	}
+
+	// Articles of other users are never exported
+	assert.Equal(t, []string{rust.ID}, exported(map[string]interface{}{"articleIds": []string{rust.ID, other.ID}}))
+	assert.Equal(t, []string{golang.ID}, exported(map[string]interface{}{"query": "golang"}))
+
+	rec := api.do(http.MethodPost, "/api/v1/exports", "user-1", map[string]interface{}{
+		"format": "json", "articleIds": []string{rust.ID}, "query": "golang",
+	}, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = api.do(http.MethodPost, "/api/v1/exports", "user-1", map[string]interface{}{"format": "pdf"}, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "unknown_format")
+	rec = api.do(http.MethodPost, "/api/v1/exports", "user-1", map[string]interface{}{"format": "json", "query": "tag:"}, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = api.do(http.MethodPost, "/api/v1/exports", "", map[string]interface{}{"format": "json"}, nil)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestExportController_Expiry(t *testing.T) {
+	api := newTestAPI(t)
+	seedArticle(t, api, "user-1", "golang")
+
+	var created ExportResponse
+	api.do(http.MethodPost, "/api/v1/exports", "user-1", map[string]interface{}{"format": "epub"}, &created)
+
+	// A link cannot be used before the file is written
+	pending, err := api.exportRepo.GetByID(created.Export.ID)
+	require.NoError(t, err)
+	rec := api.do(http.MethodGet, api.exports.DownloadURL(pending), "", nil, nil)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	api.runJobs()
+	var got ExportResponse
+	api.do(http.MethodGet, "/api/v1/exports/"+created.Export.ID, "user-1", nil, &got)
+	require.Equal(t, models.ExportStatusCompleted, got.Export.Status)
+	stored, err := api.exportRepo.GetByID(created.Export.ID)
+	require.NoError(t, err)
+	_, err = os.Stat(stored.FilePath)
+	require.NoError(t, err)
+
+	// Once the export expires its file is deleted and links stop working
+	api.exports.PurgeExpired(time.Now().Add(25 * time.Hour))
+	stored, err = api.exportRepo.GetByID(created.Export.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ExportStatusExpired, stored.Status)
+	assert.Empty(t, stored.FilePath)
+	rec = api.do(http.MethodGet, got.DownloadURL, "", nil, nil)
+	assert.Equal(t, http.StatusGone, rec.Code)
+
+	var after ExportResponse
+	api.do(http.MethodGet, "/api/v1/exports/"+created.Export.ID, "user-1", nil, &after)
+	assert.Empty(t, after.DownloadURL)
+}
@@ -0,0 +1,2117 @@
+package controllers
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eikuma/stockle/backend/internal/articlequery"
+	"github.com/eikuma/stockle/backend/internal/models"
+	"github.com/eikuma/stockle/backend/internal/repositories"
+	"github.com/eikuma/stockle/backend/internal/search"
+	"github.com/eikuma/stockle/backend/internal/services"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// memoryStore is an in-memory stand-in for the MySQL tables used by the
+// article, category, smart collection, tag, feed, summary and preference
+// repositories
+type memoryStore struct {
+	mu          sync.Mutex
+	articles    map[string]*models.Article
+	categories  map[string]*models.Category
+	collections map[string]*models.SmartCollection
+	tags        map[string]*models.Tag
+	articleTags map[string]map[string]time.Time
+	feeds       map[string]*models.Feed
+	// feedEntries maps a feed to the keys of its seen entries
+	feedEntries map[string]map[string]*string
+	// summaries holds the summary history in the order generated
+	summaries   []*models.ArticleSummary
+	preferences map[string]*models.UserPreference
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		articles:    make(map[string]*models.Article),
+		categories:  make(map[string]*models.Category),
+		collections: make(map[string]*models.SmartCollection),
+		tags:        make(map[string]*models.Tag),
+		articleTags: make(map[string]map[string]time.Time),
+		feeds:       make(map[string]*models.Feed),
+		feedEntries: make(map[string]map[string]*string),
+		preferences: make(map[string]*models.UserPreference),
+	}
+}
+
+// withAssociations returns a copy of the article with Category and Tags loaded.
+// The caller must hold the lock.
+func (s *memoryStore) withAssociations(a *models.Article) *models.Article {
+	article := *a
+	article.Category = nil
+	if article.CategoryID != nil {
+		if category, ok := s.categories[*article.CategoryID]; ok {
+			c := *category
+			article.Category = &c
+		}
+	}
+
+	article.Tags = []models.Tag{}
+	for tagID := range s.articleTags[article.ID] {
+		if tag, ok := s.tags[tagID]; ok {
+			article.Tags = append(article.Tags, *tag)
+		}
+	}
+	sort.Slice(article.Tags, func(i, j int) bool { return article.Tags[i].Name < article.Tags[j].Name })
+
+	return &article
+}
+
+// matchesTerm evaluates a query term the way the SQL compiled by the article
+// repository does. The caller must hold the lock.
+func (s *memoryStore) matchesTerm(a *models.Article, term *articlequery.Term) bool {
+	article := s.withAssociations(a)
+	switch term.Field {
+	case "":
+		text := article.Title
+		for _, field := range []*string{article.Summary, article.Content} {
+			if field != nil {
+				text += " " + *field
+			}
+		}
+		for _, tag := range article.Tags {
+			text += " " + tag.Name
+		}
+		if term.Phrase && !strings.Contains(strings.ToLower(text), strings.ToLower(term.Value)) {
+			return false
+		}
+		tokens := map[string]bool{}
+		for _, token := range search.Tokenize(text) {
+			tokens[token.Term] = true
+		}
+		for _, queryTerm := range search.QueryTerms(term.Value) {
+			if !tokens[queryTerm] {
+				return false
+			}
+		}
+		return true
+	case articlequery.FieldTag:
+		for _, tag := range article.Tags {
+			if strings.EqualFold(tag.Name, term.Value) {
+				return true
+			}
+		}
+		return false
+	case articlequery.FieldCategory:
+		return article.Category != nil && strings.EqualFold(article.Category.Name, term.Value)
+	case articlequery.FieldSite:
+		u, err := url.Parse(article.URL)
+		if err != nil {
+			return false
+		}
+		host := strings.ToLower(u.Hostname())
+		return host == term.Value || strings.HasSuffix(host, "."+term.Value)
+	case articlequery.FieldStatus:
+		return article.Status == term.Value
+	case articlequery.FieldIs:
+		if term.Value == articlequery.IsFavorite {
+			return article.IsFavorite
+		}
+		return article.Status == term.Value
+	case articlequery.FieldLang:
+		lang := strings.ToLower(article.Language)
+		return lang == term.Value || strings.HasPrefix(lang, term.Value+"-")
+	case articlequery.FieldSaved:
+		return inRange(&article.SavedAt, term)
+	case articlequery.FieldPublished:
+		return inRange(article.PublishedAt, term)
+	}
+	return false
+}
+
+func inRange(t *time.Time, term *articlequery.Term) bool {
+	if t == nil {
+		return false
+	}
+	return (term.From == nil || !t.Before(*term.From)) && (term.To == nil || t.Before(*term.To))
+}
+
+// categoryDescendants returns id followed by every category below it. The
+// caller must hold the lock.
+func (s *memoryStore) categoryDescendants(id string) []string {
+	ids := []string{id}
+	for i := 0; i < len(ids); i++ {
+		for _, category := range s.categories {
+			if category.ParentID != nil && *category.ParentID == ids[i] {
+				ids = append(ids, category.ID)
+			}
+		}
+	}
+	return ids
+}
+
+// recountTags updates the usage count of every tag, as the repositories do
+// for the tags they change. The caller must hold the lock.
+func (s *memoryStore) recountTags() {
+	for _, tag := range s.tags {
+		tag.UsageCount = s.tagUsage(tag.ID)
+	}
+}
+
+// tagUsage counts the articles a tag is attached to. The caller must hold the lock.
+func (s *memoryStore) tagUsage(tagID string) int {
+	count := 0
+	for _, tags := range s.articleTags {
+		if _, ok := tags[tagID]; ok {
+			count++
+		}
+	}
+	return count
+}
+
+type fakeArticleRepository struct {
+	store *memoryStore
+	// afterGet runs once after the next GetByID, as a job finishing while
+	// the caller holds the article it loaded
+	afterGet func()
+}
+
+var _ repositories.ArticleRepository = (*fakeArticleRepository)(nil)
+
+func (r *fakeArticleRepository) Create(article *models.Article) error {
+	return r.CreateWithTags(article, nil)
+}
+
+func (r *fakeArticleRepository) CreateWithTags(article *models.Article, tagIDs []string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	// Mirrors the unique (user_id, url_hash) index
+	if article.URLHash != nil {
+		for _, stored := range r.store.articles {
+			if stored.UserID == article.UserID && stored.URLHash != nil && *stored.URLHash == *article.URLHash {
+				return errors.New("duplicate entry for idx_articles_user_url_hash")
+			}
+		}
+	}
+
+	now := time.Now()
+	// Imported articles keep the date they were first saved
+	if article.SavedAt.IsZero() {
+		article.SavedAt = now
+	}
+	article.CreatedAt = now
+	article.UpdatedAt = now
+
+	stored := *article
+	stored.Tags = nil
+	stored.Category = nil
+	r.store.articles[article.ID] = &stored
+
+	r.store.articleTags[article.ID] = make(map[string]time.Time)
+	for _, tagID := range tagIDs {
+		if tag, ok := r.store.tags[tagID]; ok && tag.UserID == article.UserID {
+			r.store.articleTags[article.ID][tagID] = now
+		}
+	}
+	r.store.recountTags()
+	return nil
+}
+
+func (r *fakeArticleRepository) Update(article *models.Article) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored := *article
+	stored.Tags = nil
+	stored.Category = nil
+	stored.UpdatedAt = time.Now()
+	r.store.articles[article.ID] = &stored
+	return nil
+}
+
+func (r *fakeArticleRepository) update(id, userID string, fn func(a *models.Article)) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if article, ok := r.store.articles[id]; ok && article.UserID == userID {
+		fn(article)
+		article.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+func (r *fakeArticleRepository) UpdateStatus(id, userID, status string) error {
+	return r.update(id, userID, func(a *models.Article) { a.Status = status })
+}
+
+func (r *fakeArticleRepository) UpdateFavorite(id, userID string, isFavorite bool) error {
+	return r.update(id, userID, func(a *models.Article) { a.IsFavorite = isFavorite })
+}
+
+func (r *fakeArticleRepository) UpdateCategory(id, userID string, categoryID *string) error {
+	return r.update(id, userID, func(a *models.Article) { a.CategoryID = categoryID })
+}
+
+func (r *fakeArticleRepository) UpdateReadingProgress(id, userID string, progress float64) error {
+	return r.update(id, userID, func(a *models.Article) {
+		now := time.Now()
+		a.ReadingProgress = progress
+		a.LastAccessedAt = &now
+	})
+}
+
+func (r *fakeArticleRepository) GetByID(id string) (*models.Article, error) {
+	if afterGet := r.afterGet; afterGet != nil {
+		r.afterGet = nil
+		defer afterGet()
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	article, ok := r.store.articles[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	a := *article
+	return &a, nil
+}
+
+func (r *fakeArticleRepository) GetByIDWithAssociations(id string) (*models.Article, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	article, ok := r.store.articles[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.store.withAssociations(article), nil
+}
+
+func (r *fakeArticleRepository) GetByIDsWithAssociations(userID string, ids []string) ([]*models.Article, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var articles []*models.Article
+	for _, id := range ids {
+		if article, ok := r.store.articles[id]; ok && article.UserID == userID {
+			articles = append(articles, r.store.withAssociations(article))
+		}
+	}
+	return articles, nil
+}
+
+func (r *fakeArticleRepository) GetIDsByTag(tagID string) ([]string, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var ids []string
+	for articleID, tags := range r.store.articleTags {
+		if _, ok := tags[tagID]; ok {
+			ids = append(ids, articleID)
+		}
+	}
+	return ids, nil
+}
+
+func (r *fakeArticleRepository) list(userID string, match func(a *models.Article) bool) []*models.Article {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var articles []*models.Article
+	for _, article := range r.store.articles {
+		if article.UserID == userID && (match == nil || match(article)) {
+			articles = append(articles, r.store.withAssociations(article))
+		}
+	}
+	sort.Slice(articles, func(i, j int) bool {
+		if articles[i].SavedAt.Equal(articles[j].SavedAt) {
+			return articles[i].ID > articles[j].ID
+		}
+		return articles[i].SavedAt.After(articles[j].SavedAt)
+	})
+	return articles
+}
+
+func (r *fakeArticleRepository) GetByUserID(userID string) ([]*models.Article, error) {
+	return r.list(userID, nil), nil
+}
+
+func (r *fakeArticleRepository) GetByUserIDWithFilters(
+	userID string,
+	filters repositories.ArticleFilters,
+) (*repositories.ArticleListResult, error) {
+	search := strings.ToLower(filters.Search)
+	categories := map[string]bool{filters.CategoryID: true}
+	if filters.IncludeDescendants {
+		r.store.mu.Lock()
+		for _, id := range r.store.categoryDescendants(filters.CategoryID) {
+			categories[id] = true
+		}
+		r.store.mu.Unlock()
+	}
+	articles := r.list(userID, func(a *models.Article) bool {
+		if filters.Status != "" && a.Status != filters.Status {
+			return false
+		}
+		if filters.CategoryID != "" && (a.CategoryID == nil || !categories[*a.CategoryID]) {
+			return false
+		}
+		if filters.Favorite != nil && a.IsFavorite != *filters.Favorite {
+			return false
+		}
+		if search != "" {
+			text := strings.ToLower(a.Title)
+			if a.Content != nil {
+				text += " " + strings.ToLower(*a.Content)
+			}
+			if a.Summary != nil {
+				text += " " + strings.ToLower(*a.Summary)
+			}
+			if !strings.Contains(text, search) {
+				return false
+			}
+		}
+		return filters.Query.Match(func(term *articlequery.Term) bool { return r.store.matchesTerm(a, term) })
+	})
+
+	if filters.Accessed {
+		var accessed []*models.Article
+		for _, article := range articles {
+			if article.LastAccessedAt != nil {
+				accessed = append(accessed, article)
+			}
+		}
+		articles = accessed
+	}
+	sortArticles(articles, filters.Sort)
+
+	if filters.Page < 1 {
+		filters.Page = 1
+	}
+	if filters.Limit < 1 {
+		filters.Limit = repositories.DefaultPageSize
+	}
+	filters.Limit = min(filters.Limit, repositories.MaxPageSize)
+
+	result := &repositories.ArticleListResult{Page: filters.Page, Limit: filters.Limit}
+	if !filters.SkipTotal {
+		result.Total = int64(len(articles))
+	}
+
+	start := min((filters.Page-1)*filters.Limit, len(articles))
+	if filters.Cursor != "" {
+		// The fake cursor is the sort and the ID of the last article
+		data, err := base64.RawURLEncoding.DecodeString(filters.Cursor)
+		prefix := fakeCursorPrefix(filters.Sort)
+		if err != nil || !strings.HasPrefix(string(data), prefix) {
+			return nil, repositories.ErrInvalidCursor
+		}
+		lastID := strings.TrimPrefix(string(data), prefix)
+		start = len(articles)
+		for i, article := range articles {
+			if article.ID == lastID {
+				start = i + 1
+			}
+		}
+	}
+	end := min(start+filters.Limit, len(articles))
+	result.Articles = articles[start:end]
+	if end < len(articles) {
+		last := result.Articles[len(result.Articles)-1]
+		result.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(fakeCursorPrefix(filters.Sort) + last.ID))
+	}
+	return result, nil
+}
+
+func fakeCursorPrefix(s repositories.ArticleSort) string {
+	return fmt.Sprintf("%s/%t/", s.Field, s.Ascending)
+}
+
+// sortArticles orders articles like the SQL of the article repository:
+// NULLs last, ties by ID in the direction of the sort
+func sortArticles(articles []*models.Article, s repositories.ArticleSort) {
+	// NULLs are placed by isNull before values are compared
+	compareTimes := func(a, b *time.Time) int {
+		if a == nil || b == nil {
+			return 0
+		}
+		return a.Compare(*b)
+	}
+	isNull := func(a *models.Article) bool {
+		switch s.Field {
+		case repositories.SortPublishedAt:
+			return a.PublishedAt == nil
+		case repositories.SortLastAccessedAt:
+			return a.LastAccessedAt == nil
+		case repositories.SortWordCount:
+			return a.WordCount == nil
+		}
+		return false
+	}
+	compare := func(a, b *models.Article) int {
+		switch s.Field {
+		case repositories.SortPublishedAt:
+			return compareTimes(a.PublishedAt, b.PublishedAt)
+		case repositories.SortLastAccessedAt:
+			return compareTimes(a.LastAccessedAt, b.LastAccessedAt)
+		case repositories.SortTitle:
+			return strings.Compare(a.Title, b.Title)
+		case repositories.SortReadingProgress:
+			return cmp.Compare(a.ReadingProgress, b.ReadingProgress)
+		case repositories.SortWordCount:
+			if a.WordCount == nil || b.WordCount == nil {
+				return 0
+			}
+			return cmp.Compare(*a.WordCount, *b.WordCount)
+		}
+		return a.SavedAt.Compare(b.SavedAt)
+	}
+
+	sort.SliceStable(articles, func(i, j int) bool {
+		a, b := articles[i], articles[j]
+		if isNull(a) != isNull(b) {
+			return isNull(b)
+		}
+		c := compare(a, b)
+		if c == 0 {
+			c = strings.Compare(a.ID, b.ID)
+		}
+		if s.Ascending {
+			return c < 0
+		}
+		return c > 0
+	})
+}
+
+func (r *fakeArticleRepository) GetByURLHash(userID, urlHash string) (*models.Article, error) {
+	articles := r.list(userID, func(a *models.Article) bool {
+		return (a.URLHash != nil && *a.URLHash == urlHash) || (a.CanonicalURLHash != nil && *a.CanonicalURLHash == urlHash)
+	})
+	if len(articles) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	// list sorts the newest first
+	return articles[len(articles)-1], nil
+}
+
+func (r *fakeArticleRepository) Delete(id, userID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if article, ok := r.store.articles[id]; ok && article.UserID == userID {
+		delete(r.store.articles, id)
+		delete(r.store.articleTags, id)
+		r.store.deleteSummaries(id)
+		r.store.recountTags()
+	}
+	return nil
+}
+
+func (r *fakeArticleRepository) AddTags(id, userID string, tagIDs []string) error {
+	return r.changeTags(id, userID, func(tags map[string]time.Time) {
+		for _, tagID := range tagIDs {
+			if _, ok := tags[tagID]; !ok {
+				tags[tagID] = time.Now()
+			}
+		}
+	}, tagIDs)
+}
+
+func (r *fakeArticleRepository) RemoveTags(id, userID string, tagIDs []string) error {
+	return r.changeTags(id, userID, func(tags map[string]time.Time) {
+		for _, tagID := range tagIDs {
+			delete(tags, tagID)
+		}
+	}, tagIDs)
+}
+
+func (r *fakeArticleRepository) ReplaceTags(id, userID string, tagIDs []string) error {
+	return r.changeTags(id, userID, func(tags map[string]time.Time) {
+		keep := make(map[string]bool)
+		for _, tagID := range tagIDs {
+			keep[tagID] = true
+			if _, ok := tags[tagID]; !ok {
+				tags[tagID] = time.Now()
+			}
+		}
+		for tagID := range tags {
+			if !keep[tagID] {
+				delete(tags, tagID)
+			}
+		}
+	}, tagIDs)
+}
+
+// changeTags mirrors the ownership checks of the repository before letting
+// fn change the tags of the article
+func (r *fakeArticleRepository) changeTags(id, userID string, fn func(tags map[string]time.Time), tagIDs []string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if article, ok := r.store.articles[id]; !ok || article.UserID != userID {
+		return gorm.ErrRecordNotFound
+	}
+	for _, tagID := range tagIDs {
+		if tag, ok := r.store.tags[tagID]; !ok || tag.UserID != userID {
+			return gorm.ErrRecordNotFound
+		}
+	}
+	fn(r.store.articleTags[id])
+	r.store.recountTags()
+	return nil
+}
+
+func (r *fakeArticleRepository) Each(
+	userID string,
+	selection repositories.ArticleSelection,
+	batchSize int,
+	fn func([]*models.Article) error,
+) error {
+	ids := make(map[string]bool)
+	for _, id := range selection.IDs {
+		ids[id] = true
+	}
+	articles := r.list(userID, func(a *models.Article) bool {
+		switch {
+		case len(selection.IDs) > 0:
+			return ids[a.ID]
+		case selection.Query != nil:
+			return selection.Query.Match(func(term *articlequery.Term) bool { return r.store.matchesTerm(a, term) })
+		default:
+			return true
+		}
+	})
+	sort.SliceStable(articles, func(i, j int) bool {
+		if articles[i].SavedAt.Equal(articles[j].SavedAt) {
+			return articles[i].ID < articles[j].ID
+		}
+		return articles[i].SavedAt.Before(articles[j].SavedAt)
+	})
+
+	for start := 0; start < len(articles); start += batchSize {
+		if err := fn(articles[start:min(start+batchSize, len(articles))]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *fakeArticleRepository) BulkUpdate(
+	userID string,
+	selection repositories.ArticleSelection,
+	update repositories.ArticleBulkUpdate,
+	dryRun bool,
+) (*repositories.ArticleBulkResult, error) {
+	result := &repositories.ArticleBulkResult{}
+	if len(selection.IDs) > 0 {
+		r.store.mu.Lock()
+		seen := make(map[string]bool)
+		for _, id := range selection.IDs {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			if article, ok := r.store.articles[id]; ok && article.UserID == userID {
+				result.Matched = append(result.Matched, id)
+			} else {
+				result.Missing = append(result.Missing, id)
+			}
+		}
+		r.store.mu.Unlock()
+	} else {
+		articles := r.list(userID, func(a *models.Article) bool {
+			return selection.Query.Match(func(term *articlequery.Term) bool { return r.store.matchesTerm(a, term) })
+		})
+		for _, article := range articles {
+			result.Matched = append(result.Matched, article.ID)
+		}
+		sort.Strings(result.Matched)
+	}
+	result.Count = len(result.Matched)
+	if result.Count > repositories.MaxBulkArticles {
+		if dryRun {
+			return &repositories.ArticleBulkResult{Count: result.Count}, nil
+		}
+		return nil, repositories.ErrBulkLimitExceeded
+	}
+	r.store.mu.Lock()
+	for _, id := range result.Matched {
+		if r.store.articles[id].ExtractionStatus != models.ExtractionStatusCompleted {
+			result.Unextracted = append(result.Unextracted, id)
+		}
+	}
+	r.store.mu.Unlock()
+	if dryRun {
+		return result, nil
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if update.CategoryID != nil && *update.CategoryID != "" {
+		if category, ok := r.store.categories[*update.CategoryID]; !ok || category.UserID != userID {
+			return nil, gorm.ErrRecordNotFound
+		}
+	}
+	for _, tagID := range append(append([]string{}, update.AddTagIDs...), update.RemoveTagIDs...) {
+		if tag, ok := r.store.tags[tagID]; !ok || tag.UserID != userID {
+			return nil, gorm.ErrRecordNotFound
+		}
+	}
+
+	now := time.Now()
+	for _, id := range result.Matched {
+		if update.Delete {
+			delete(r.store.articles, id)
+			delete(r.store.articleTags, id)
+			r.store.deleteSummaries(id)
+			continue
+		}
+		article := r.store.articles[id]
+		if update.Status != nil {
+			article.Status = *update.Status
+		}
+		if update.IsFavorite != nil {
+			article.IsFavorite = *update.IsFavorite
+		}
+		if update.CategoryID != nil {
+			article.CategoryID = nil
+			if *update.CategoryID != "" {
+				categoryID := *update.CategoryID
+				article.CategoryID = &categoryID
+			}
+		}
+		for _, tagID := range update.RemoveTagIDs {
+			delete(r.store.articleTags[id], tagID)
+		}
+		for _, tagID := range update.AddTagIDs {
+			if _, ok := r.store.articleTags[id][tagID]; !ok {
+				r.store.articleTags[id][tagID] = now
+			}
+		}
+		article.UpdatedAt = now
+	}
+	r.store.recountTags()
+	return result, nil
+}
+
+func (r *fakeArticleRepository) GetFavorites(userID string, page, limit int) (*repositories.ArticleListResult, error) {
+	favorite := true
+	return r.GetByUserIDWithFilters(userID, repositories.ArticleFilters{Favorite: &favorite, Page: page, Limit: limit})
+}
+
+func (r *fakeArticleRepository) GetRecentlyRead(userID string, limit int) ([]*models.Article, error) {
+	result, err := r.GetByUserIDWithFilters(userID, repositories.ArticleFilters{
+		Status:    models.ArticleStatusRead,
+		Accessed:  true,
+		Sort:      repositories.ArticleSort{Field: repositories.SortLastAccessedAt},
+		Limit:     limit,
+		SkipTotal: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.Articles, nil
+}
+
+func (r *fakeArticleRepository) MarkAsAccessed(id string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if article, ok := r.store.articles[id]; ok {
+		now := time.Now()
+		article.LastAccessedAt = &now
+	}
+	return nil
+}
+
+// setFields copies the job-owned fields onto the stored article
+func (r *fakeArticleRepository) setFields(article *models.Article, fn func(stored *models.Article)) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if stored, ok := r.store.articles[article.ID]; ok {
+		fn(stored)
+		stored.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+func (r *fakeArticleRepository) UpdateExtraction(article *models.Article) error {
+	return r.setFields(article, func(stored *models.Article) {
+		stored.Title = article.Title
+		stored.Content = article.Content
+		stored.ContentHTML = article.ContentHTML
+		stored.WordCount = article.WordCount
+		stored.ReadingTimeSeconds = article.ReadingTimeSeconds
+		stored.ThumbnailURL = article.ThumbnailURL
+		stored.Author = article.Author
+		stored.SiteName = article.SiteName
+		stored.PublishedAt = article.PublishedAt
+		stored.Language = article.Language
+		stored.CanonicalURL = article.CanonicalURL
+		stored.CanonicalURLHash = article.CanonicalURLHash
+		stored.Keywords = article.Keywords
+		stored.ExtractionStatus = article.ExtractionStatus
+		stored.ExtractionError = article.ExtractionError
+	})
+}
+
+func (r *fakeArticleRepository) UpdateSummary(article *models.Article, summaryType string) error {
+	return r.setFields(article, func(stored *models.Article) {
+		switch summaryType {
+		case models.SummaryTypeShort:
+			stored.SummaryShort = article.SummaryShort
+		case models.SummaryTypeLong:
+			stored.SummaryLong = article.SummaryLong
+		default:
+			stored.Summary = article.Summary
+		}
+		stored.SummaryGenerationStatus = article.SummaryGenerationStatus
+		stored.SummaryGeneratedAt = article.SummaryGeneratedAt
+		stored.SummaryModelVersion = article.SummaryModelVersion
+		stored.SummaryStrategy = article.SummaryStrategy
+		stored.SummaryConfidence = article.SummaryConfidence
+	})
+}
+
+func (r *fakeArticleRepository) UpdateSummaryStatus(article *models.Article) error {
+	return r.setFields(article, func(stored *models.Article) {
+		stored.SummaryGenerationStatus = article.SummaryGenerationStatus
+	})
+}
+
+// fakeSearchIndex matches documents containing every query term, using the
+// real tokenizer, and scores them by the number of matching tokens with the
+// title counting double
+type fakeSearchIndex struct {
+	mu   sync.Mutex
+	docs map[string]search.Document
+}
+
+func newFakeSearchIndex() *fakeSearchIndex {
+	return &fakeSearchIndex{docs: make(map[string]search.Document)}
+}
+
+func (x *fakeSearchIndex) Index(doc search.Document) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.docs[doc.ArticleID] = doc
+	return nil
+}
+
+func (x *fakeSearchIndex) Remove(articleID string) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	delete(x.docs, articleID)
+	return nil
+}
+
+func (x *fakeSearchIndex) Search(userID string, query search.Query) (*search.Results, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	terms := search.QueryTerms(query.Text)
+	results := &search.Results{Terms: terms}
+	if len(terms) == 0 {
+		return results, nil
+	}
+
+	for _, doc := range x.docs {
+		if doc.UserID != userID {
+			continue
+		}
+		counts := map[string]float64{}
+		for _, token := range search.Tokenize(doc.Title) {
+			counts[token.Term] += 2
+		}
+		body := doc.Summary + " " + doc.Content + " " + strings.Join(doc.Tags, " ")
+		for _, token := range search.Tokenize(body) {
+			counts[token.Term]++
+		}
+
+		score := 0.0
+		for _, term := range terms {
+			if counts[term] == 0 {
+				score = 0
+				break
+			}
+			score += counts[term]
+		}
+		if score > 0 {
+			results.Hits = append(results.Hits, search.Hit{ArticleID: doc.ArticleID, Score: score})
+		}
+	}
+	sort.Slice(results.Hits, func(i, j int) bool { return results.Hits[i].Before(results.Hits[j]) })
+
+	results.Total = len(results.Hits)
+	start := min(query.Offset, len(results.Hits))
+	if query.After != nil {
+		start = sort.Search(len(results.Hits), func(i int) bool { return query.After.Before(results.Hits[i]) })
+	}
+	end := min(start+query.Limit, len(results.Hits))
+	results.Hits = results.Hits[start:end]
+	return results, nil
+}
+
+func (x *fakeSearchIndex) Stale(after string, limit int) ([]string, error) {
+	return nil, nil
+}
+
+type fakeCategoryRepository struct {
+	store *memoryStore
+}
+
+var _ repositories.CategoryRepository = (*fakeCategoryRepository)(nil)
+
+func (r *fakeCategoryRepository) Create(category *models.Category) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	category.CreatedAt = now
+	category.UpdatedAt = now
+	c := *category
+	r.store.categories[category.ID] = &c
+	return nil
+}
+
+func (r *fakeCategoryRepository) Update(category *models.Category) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	category.UpdatedAt = time.Now()
+	c := *category
+	r.store.categories[category.ID] = &c
+	return nil
+}
+
+func (r *fakeCategoryRepository) GetByID(id string) (*models.Category, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	category, ok := r.store.categories[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	c := *category
+	return &c, nil
+}
+
+func (r *fakeCategoryRepository) GetByUserID(userID string) ([]*models.Category, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var categories []*models.Category
+	for _, category := range r.store.categories {
+		if category.UserID == userID {
+			c := *category
+			categories = append(categories, &c)
+		}
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		if categories[i].DisplayOrder == categories[j].DisplayOrder {
+			return categories[i].CreatedAt.Before(categories[j].CreatedAt)
+		}
+		return categories[i].DisplayOrder < categories[j].DisplayOrder
+	})
+	return categories, nil
+}
+
+func (r *fakeCategoryRepository) GetByUserIDWithCounts(userID string) ([]*models.Category, error) {
+	categories, _ := r.GetByUserID(userID)
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	counts := make(map[string]int)
+	for _, article := range r.store.articles {
+		if article.UserID == userID && article.CategoryID != nil {
+			counts[*article.CategoryID]++
+		}
+	}
+	for _, category := range categories {
+		category.ArticleCount = counts[category.ID]
+		for _, id := range r.store.categoryDescendants(category.ID) {
+			category.TotalArticleCount += counts[id]
+		}
+	}
+	return categories, nil
+}
+
+func (r *fakeCategoryRepository) Move(id, userID string, parentID *string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	category, ok := r.store.categories[id]
+	if !ok || category.UserID != userID {
+		return gorm.ErrRecordNotFound
+	}
+	if parentID != nil {
+		if parent, ok := r.store.categories[*parentID]; !ok || parent.UserID != userID {
+			return gorm.ErrRecordNotFound
+		}
+		for _, descendant := range r.store.categoryDescendants(id) {
+			if descendant == *parentID {
+				return repositories.ErrCategoryCycle
+			}
+		}
+		p := *parentID
+		parentID = &p
+	}
+	category.ParentID = parentID
+	return nil
+}
+
+func (r *fakeCategoryRepository) Delete(id, userID string, reassign repositories.ReassignTo) error {
+	defaultCategory, err := r.GetDefault(userID)
+	if err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	category, ok := r.store.categories[id]
+	if !ok || category.UserID != userID || category.IsDefault {
+		return nil
+	}
+	target := defaultCategory.ID
+	if reassign == repositories.ReassignToParent && category.ParentID != nil {
+		target = *category.ParentID
+	}
+
+	for _, article := range r.store.articles {
+		if article.UserID == userID && article.CategoryID != nil && *article.CategoryID == id {
+			targetID := target
+			article.CategoryID = &targetID
+		}
+	}
+	for _, feed := range r.store.feeds {
+		if feed.UserID == userID && feed.CategoryID != nil && *feed.CategoryID == id {
+			targetID := target
+			feed.CategoryID = &targetID
+		}
+	}
+	for _, child := range r.store.categories {
+		if child.ParentID != nil && *child.ParentID == id {
+			child.ParentID = category.ParentID
+		}
+	}
+	delete(r.store.categories, id)
+	return nil
+}
+
+func (r *fakeCategoryRepository) GetDefault(userID string) (*models.Category, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, category := range r.store.categories {
+		if category.UserID == userID && category.IsDefault {
+			c := *category
+			return &c, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeCategoryRepository) CreateDefault(userID string) (*models.Category, error) {
+	category := &models.Category{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Name:      "未分類",
+		Color:     "#6B7280",
+		IsDefault: true,
+	}
+	return category, r.Create(category)
+}
+
+func (r *fakeCategoryRepository) Reorder(userID string, categoryIDs []string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, id := range categoryIDs {
+		if category, ok := r.store.categories[id]; !ok || category.UserID != userID {
+			return gorm.ErrRecordNotFound
+		}
+	}
+	for i, id := range categoryIDs {
+		r.store.categories[id].DisplayOrder = i
+	}
+	return nil
+}
+
+type fakeSmartCollectionRepository struct {
+	store *memoryStore
+}
+
+var _ repositories.SmartCollectionRepository = (*fakeSmartCollectionRepository)(nil)
+
+func (r *fakeSmartCollectionRepository) Create(collection *models.SmartCollection) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	collection.CreatedAt = now
+	collection.UpdatedAt = now
+	c := *collection
+	r.store.collections[collection.ID] = &c
+	return nil
+}
+
+func (r *fakeSmartCollectionRepository) Update(collection *models.SmartCollection) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	collection.UpdatedAt = time.Now()
+	c := *collection
+	r.store.collections[collection.ID] = &c
+	return nil
+}
+
+func (r *fakeSmartCollectionRepository) GetByID(id string) (*models.SmartCollection, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	collection, ok := r.store.collections[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	c := *collection
+	return &c, nil
+}
+
+func (r *fakeSmartCollectionRepository) GetByUserID(userID string) ([]*models.SmartCollection, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var collections []*models.SmartCollection
+	for _, collection := range r.store.collections {
+		if collection.UserID == userID {
+			c := *collection
+			collections = append(collections, &c)
+		}
+	}
+	sort.Slice(collections, func(i, j int) bool {
+		if collections[i].DisplayOrder == collections[j].DisplayOrder {
+			return collections[i].CreatedAt.Before(collections[j].CreatedAt)
+		}
+		return collections[i].DisplayOrder < collections[j].DisplayOrder
+	})
+	return collections, nil
+}
+
+func (r *fakeSmartCollectionRepository) GetByUserIDWithCounts(userID string) ([]*models.SmartCollection, error) {
+	collections, _ := r.GetByUserID(userID)
+
+	articles := &fakeArticleRepository{store: r.store}
+	for _, collection := range collections {
+		query, err := articlequery.Parse(collection.Query)
+		if err != nil {
+			collection.QueryError = err.Error()
+			continue
+		}
+		result, err := articles.GetByUserIDWithFilters(userID, repositories.ArticleFilters{Query: query})
+		if err != nil {
+			return nil, err
+		}
+		collection.ArticleCount = int(result.Total)
+	}
+	return collections, nil
+}
+
+func (r *fakeSmartCollectionRepository) Delete(id, userID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if collection, ok := r.store.collections[id]; ok && collection.UserID == userID {
+		delete(r.store.collections, id)
+	}
+	return nil
+}
+
+func (r *fakeSmartCollectionRepository) Reorder(userID string, collectionIDs []string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, id := range collectionIDs {
+		if collection, ok := r.store.collections[id]; !ok || collection.UserID != userID {
+			return gorm.ErrRecordNotFound
+		}
+	}
+	for i, id := range collectionIDs {
+		r.store.collections[id].DisplayOrder = i
+	}
+	return nil
+}
+
+type fakeTagRepository struct {
+	store *memoryStore
+}
+
+var _ repositories.TagRepository = (*fakeTagRepository)(nil)
+
+func (r *fakeTagRepository) Create(tag *models.Tag) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	tag.CreatedAt = now
+	tag.UpdatedAt = now
+	t := *tag
+	r.store.tags[tag.ID] = &t
+	return nil
+}
+
+func (r *fakeTagRepository) GetOrCreate(userID, name string) (*models.Tag, error) {
+	name = repositories.NormalizeTagName(name)
+	if name == "" {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	r.store.mu.Lock()
+	for _, tag := range r.store.tags {
+		if tag.UserID == userID && strings.EqualFold(tag.Name, name) {
+			t := *tag
+			r.store.mu.Unlock()
+			return &t, nil
+		}
+	}
+	r.store.mu.Unlock()
+
+	tag := &models.Tag{ID: uuid.New().String(), UserID: userID, Name: name}
+	return tag, r.Create(tag)
+}
+
+func (r *fakeTagRepository) GetOrCreateMultiple(userID string, names []string) ([]*models.Tag, error) {
+	var tags []*models.Tag
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if repositories.NormalizeTagName(name) == "" {
+			continue
+		}
+		tag, err := r.GetOrCreate(userID, name)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[tag.ID] {
+			seen[tag.ID] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+func (r *fakeTagRepository) GetByNames(userID string, names []string) ([]*models.Tag, error) {
+	keys := make(map[string]bool)
+	for _, name := range names {
+		keys[strings.ToLower(repositories.NormalizeTagName(name))] = true
+	}
+	return r.list(userID, func(t *models.Tag) bool { return keys[strings.ToLower(t.Name)] }), nil
+}
+
+func (r *fakeTagRepository) GetByID(id string) (*models.Tag, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	tag, ok := r.store.tags[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	t := *tag
+	return &t, nil
+}
+
+func (r *fakeTagRepository) list(userID string, match func(t *models.Tag) bool) []*models.Tag {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var tags []*models.Tag
+	for _, tag := range r.store.tags {
+		if tag.UserID == userID && (match == nil || match(tag)) {
+			t := *tag
+			tags = append(tags, &t)
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].UsageCount == tags[j].UsageCount {
+			return tags[i].Name < tags[j].Name
+		}
+		return tags[i].UsageCount > tags[j].UsageCount
+	})
+	return tags
+}
+
+func (r *fakeTagRepository) GetByUserID(userID string) ([]*models.Tag, error) {
+	return r.list(userID, nil), nil
+}
+
+func (r *fakeTagRepository) GetPopularTags(userID string, limit int) ([]*models.Tag, error) {
+	tags := r.list(userID, func(t *models.Tag) bool { return t.UsageCount > 0 })
+	if len(tags) > limit {
+		tags = tags[:limit]
+	}
+	return tags, nil
+}
+
+func (r *fakeTagRepository) SearchByName(userID, query string, limit int) ([]*models.Tag, error) {
+	query = strings.ToLower(repositories.NormalizeTagName(query))
+	tags := r.list(userID, func(t *models.Tag) bool {
+		return strings.Contains(strings.ToLower(t.Name), query)
+	})
+	// Stable sort keeps the usage order among prefix matches and the rest
+	sort.SliceStable(tags, func(i, j int) bool {
+		return strings.HasPrefix(strings.ToLower(tags[i].Name), query) &&
+			!strings.HasPrefix(strings.ToLower(tags[j].Name), query)
+	})
+	if len(tags) > limit {
+		tags = tags[:limit]
+	}
+	return tags, nil
+}
+
+func (r *fakeTagRepository) UpdateUsageCount(tagID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if tag, ok := r.store.tags[tagID]; ok {
+		tag.UsageCount = r.store.tagUsage(tagID)
+	}
+	return nil
+}
+
+func (r *fakeTagRepository) Rename(id, userID, name string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	tag, ok := r.store.tags[id]
+	if !ok || tag.UserID != userID {
+		return gorm.ErrRecordNotFound
+	}
+	name = repositories.NormalizeTagName(name)
+	for _, other := range r.store.tags {
+		if other.ID != id && other.UserID == userID && strings.EqualFold(other.Name, name) {
+			return repositories.ErrTagNameConflict
+		}
+	}
+	tag.Name = name
+	return nil
+}
+
+func (r *fakeTagRepository) Merge(sourceID, targetID, userID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	source, ok := r.store.tags[sourceID]
+	if !ok || source.UserID != userID {
+		return gorm.ErrRecordNotFound
+	}
+	target, ok := r.store.tags[targetID]
+	if !ok || target.UserID != userID {
+		return gorm.ErrRecordNotFound
+	}
+
+	for _, tags := range r.store.articleTags {
+		if createdAt, ok := tags[sourceID]; ok {
+			if _, exists := tags[targetID]; !exists {
+				tags[targetID] = createdAt
+			}
+			delete(tags, sourceID)
+		}
+	}
+	delete(r.store.tags, sourceID)
+	target.UsageCount = r.store.tagUsage(targetID)
+	return nil
+}
+
+func (r *fakeTagRepository) Delete(id, userID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	tag, ok := r.store.tags[id]
+	if !ok || tag.UserID != userID {
+		return gorm.ErrRecordNotFound
+	}
+	for _, tags := range r.store.articleTags {
+		delete(tags, id)
+	}
+	delete(r.store.tags, id)
+	return nil
+}
+
+func (r *fakeTagRepository) GetUnusedTags(userID string) ([]*models.Tag, error) {
+	return r.list(userID, func(t *models.Tag) bool { return t.UsageCount == 0 }), nil
+}
+
+func (r *fakeTagRepository) DeleteUnused(userID string) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var deleted int64
+	for id, tag := range r.store.tags {
+		if tag.UserID == userID && r.store.tagUsage(id) == 0 {
+			delete(r.store.tags, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+type fakeUserRepository struct {
+	mu       sync.Mutex
+	store    *memoryStore
+	users    map[string]*models.User
+	sessions map[string]*models.UserSession
+}
+
+var _ repositories.UserRepository = (*fakeUserRepository)(nil)
+
+func newFakeUserRepository(store *memoryStore) *fakeUserRepository {
+	return &fakeUserRepository{
+		store:    store,
+		users:    make(map[string]*models.User),
+		sessions: make(map[string]*models.UserSession),
+	}
+}
+
+func (r *fakeUserRepository) Create(user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := user.BeforeCreate(nil); err != nil {
+		return err
+	}
+	u := *user
+	r.users[user.ID] = &u
+	return nil
+}
+
+func (r *fakeUserRepository) CreateWithDefaultCategory(user *models.User) error {
+	if err := r.Create(user); err != nil {
+		return err
+	}
+	_, err := (&fakeCategoryRepository{store: r.store}).CreateDefault(user.ID)
+	return err
+}
+
+func (r *fakeUserRepository) Update(user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u := *user
+	r.users[user.ID] = &u
+	return nil
+}
+
+func (r *fakeUserRepository) find(match func(u *models.User) bool) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, user := range r.users {
+		if match(user) {
+			u := *user
+			return &u, nil
+		}
+	}
+	return nil, errors.New("user not found")
+}
+
+func (r *fakeUserRepository) GetByID(id string) (*models.User, error) {
+	return r.find(func(u *models.User) bool { return u.ID == id })
+}
+
+func (r *fakeUserRepository) GetByEmail(email string) (*models.User, error) {
+	return r.find(func(u *models.User) bool { return u.Email == email })
+}
+
+func (r *fakeUserRepository) GetByGoogleID(googleID string) (*models.User, error) {
+	return r.find(func(u *models.User) bool { return u.GoogleID != nil && *u.GoogleID == googleID })
+}
+
+func (r *fakeUserRepository) Delete(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.users, id)
+	return nil
+}
+
+func (r *fakeUserRepository) CreateSession(session *models.UserSession) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := session.BeforeCreate(nil); err != nil {
+		return err
+	}
+	session.IsActive = true
+	s := *session
+	r.sessions[session.ID] = &s
+	return nil
+}
+
+func (r *fakeUserRepository) GetSessionByToken(token string) (*models.UserSession, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, session := range r.sessions {
+		if session.IsActive && bcrypt.CompareHashAndPassword([]byte(session.TokenHash), []byte(token)) == nil {
+			s := *session
+			return &s, nil
+		}
+	}
+	return nil, errors.New("session not found")
+}
+
+func (r *fakeUserRepository) GetSessionsByUserID(userID string) ([]models.UserSession, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var sessions []models.UserSession
+	for _, session := range r.sessions {
+		if session.UserID == userID && session.IsActive {
+			sessions = append(sessions, *session)
+		}
+	}
+	return sessions, nil
+}
+
+func (r *fakeUserRepository) UpdateSession(session *models.UserSession) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := *session
+	r.sessions[session.ID] = &s
+	return nil
+}
+
+func (r *fakeUserRepository) DeleteSession(sessionID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.sessions, sessionID)
+	return nil
+}
+
+func (r *fakeUserRepository) DeleteExpiredSessions() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, session := range r.sessions {
+		if time.Now().After(session.ExpiresAt) {
+			delete(r.sessions, id)
+		}
+	}
+	return nil
+}
+
+func (r *fakeUserRepository) DeleteUserSessions(userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, session := range r.sessions {
+		if session.UserID == userID {
+			delete(r.sessions, id)
+		}
+	}
+	return nil
+}
+
+type fakeJobRepository struct {
+	mu   sync.Mutex
+	jobs []*models.JobQueue
+}
+
+var _ repositories.JobRepository = (*fakeJobRepository)(nil)
+
+func (r *fakeJobRepository) Create(job *models.JobQueue) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job.CreatedAt = time.Now()
+	stored := *job
+	r.jobs = append(r.jobs, &stored)
+	return nil
+}
+
+func (r *fakeJobRepository) Update(job *models.JobQueue) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, stored := range r.jobs {
+		if stored.ID == job.ID {
+			updated := *job
+			r.jobs[i] = &updated
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (r *fakeJobRepository) GetByID(id string) (*models.JobQueue, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, stored := range r.jobs {
+		if stored.ID == id {
+			job := *stored
+			return &job, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// next returns the due pending job in queue order. The caller must hold the lock.
+func (r *fakeJobRepository) next(staleAfter time.Duration) *models.JobQueue {
+	var next *models.JobQueue
+	now := time.Now()
+	for _, job := range r.jobs {
+		due := job.Status == models.JobStatusPending && (job.ScheduledAt == nil || !job.ScheduledAt.After(now))
+		stale := staleAfter > 0 && job.Status == models.JobStatusProcessing &&
+			job.StartedAt != nil && job.StartedAt.Before(now.Add(-staleAfter))
+		if !due && !stale {
+			continue
+		}
+		if next == nil || job.Priority < next.Priority {
+			next = job
+		}
+	}
+	return next
+}
+
+func (r *fakeJobRepository) GetNextJob() (*models.JobQueue, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if next := r.next(0); next != nil {
+		job := *next
+		return &job, nil
+	}
+	return nil, nil
+}
+
+func (r *fakeJobRepository) ClaimNextJob(staleAfter time.Duration) (*models.JobQueue, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next := r.next(staleAfter)
+	if next == nil {
+		return nil, nil
+	}
+	if next.Status == models.JobStatusProcessing {
+		next.RetryCount++
+	}
+	now := time.Now()
+	next.Status = models.JobStatusProcessing
+	next.StartedAt = &now
+	job := *next
+	return &job, nil
+}
+
+func (r *fakeJobRepository) Heartbeat(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, job := range r.jobs {
+		if job.ID == id && job.Status == models.JobStatusProcessing {
+			now := time.Now()
+			job.StartedAt = &now
+		}
+	}
+	return nil
+}
+
+// abandon makes a processing job look like its worker stopped an hour ago
+func (r *fakeJobRepository) abandon(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stoppedAt := time.Now().Add(-time.Hour)
+	for _, job := range r.jobs {
+		if job.ID == id {
+			job.StartedAt = &stoppedAt
+		}
+	}
+}
+
+func (r *fakeJobRepository) GetPendingJobs() ([]*models.JobQueue, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var jobs []*models.JobQueue
+	for _, stored := range r.jobs {
+		if stored.Status == models.JobStatusPending {
+			job := *stored
+			jobs = append(jobs, &job)
+		}
+	}
+	return jobs, nil
+}
+
+type fakeImportRepository struct {
+	mu      sync.Mutex
+	imports map[string]*models.Import
+	items   map[string][]*models.ImportItem
+}
+
+var _ repositories.ImportRepository = (*fakeImportRepository)(nil)
+
+func newFakeImportRepository() *fakeImportRepository {
+	return &fakeImportRepository{
+		imports: make(map[string]*models.Import),
+		items:   make(map[string][]*models.ImportItem),
+	}
+}
+
+func (r *fakeImportRepository) Create(imp *models.Import, items []*models.ImportItem) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	imp.CreatedAt = now
+	imp.UpdatedAt = now
+	stored := *imp
+	r.imports[imp.ID] = &stored
+
+	r.items[imp.ID] = nil
+	for _, item := range items {
+		item.ImportID = imp.ID
+		i := *item
+		r.items[imp.ID] = append(r.items[imp.ID], &i)
+	}
+	sort.SliceStable(r.items[imp.ID], func(i, j int) bool {
+		return r.items[imp.ID][i].Position < r.items[imp.ID][j].Position
+	})
+	return nil
+}
+
+func (r *fakeImportRepository) Update(imp *models.Import) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	imp.UpdatedAt = time.Now()
+	stored := *imp
+	r.imports[imp.ID] = &stored
+	return nil
+}
+
+func (r *fakeImportRepository) GetByID(id string) (*models.Import, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	imp, ok := r.imports[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	i := *imp
+	return &i, nil
+}
+
+func (r *fakeImportRepository) GetByUserID(userID string) ([]*models.Import, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var imports []*models.Import
+	for _, imp := range r.imports {
+		if imp.UserID == userID {
+			i := *imp
+			imports = append(imports, &i)
+		}
+	}
+	sort.Slice(imports, func(i, j int) bool {
+		return imports[i].CreatedAt.After(imports[j].CreatedAt)
+	})
+	return imports, nil
+}
+
+func (r *fakeImportRepository) GetItems(importID, status string, limit int) ([]*models.ImportItem, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var items []*models.ImportItem
+	for _, item := range r.items[importID] {
+		if item.Status == status && len(items) < limit {
+			i := *item
+			items = append(items, &i)
+		}
+	}
+	return items, nil
+}
+
+func (r *fakeImportRepository) UpdateItem(item *models.ImportItem) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, stored := range r.items[item.ImportID] {
+		if stored.Position == item.Position {
+			updated := *item
+			r.items[item.ImportID][i] = &updated
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (r *fakeImportRepository) CountItems(importID string) (map[string]int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	counts := make(map[string]int)
+	for _, item := range r.items[importID] {
+		counts[item.Status]++
+	}
+	return counts, nil
+}
+
+// fakeSummarizer returns a fixed summary instead of calling an LLM API
+type fakeSummarizer struct{}
+
+func (fakeSummarizer) GenerateSummary(ctx context.Context, req *services.SummaryRequest) (*services.SummaryResponse, error) {
+	provider := req.Provider
+	if provider == "" {
+		provider = "fake"
+	}
+	summary := "Summary of " + req.Title
+	if req.SummaryType != models.SummaryTypeMedium {
+		summary = req.SummaryType + " summary of " + req.Title
+	}
+	return &services.SummaryResponse{
+		Summary:      summary,
+		Confidence:   0.9,
+		ModelVersion: provider + "-1",
+		Provider:     provider,
+		GeneratedAt:  time.Now(),
+		Strategy:     models.SummaryStrategySingle,
+		Chunks:       1,
+	}, nil
+}
+
+func (fakeSummarizer) Providers() []string {
+	return []string{"fake", "other"}
+}
+
+type fakeExportRepository struct {
+	mu      sync.Mutex
+	exports map[string]*models.Export
+}
+
+var _ repositories.ExportRepository = (*fakeExportRepository)(nil)
+
+func newFakeExportRepository() *fakeExportRepository {
+	return &fakeExportRepository{exports: make(map[string]*models.Export)}
+}
+
+func (r *fakeExportRepository) Create(export *models.Export) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	export.CreatedAt = now
+	export.UpdatedAt = now
+	stored := *export
+	r.exports[export.ID] = &stored
+	return nil
+}
+
+func (r *fakeExportRepository) Update(export *models.Export) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	export.UpdatedAt = time.Now()
+	stored := *export
+	r.exports[export.ID] = &stored
+	return nil
+}
+
+func (r *fakeExportRepository) GetByID(id string) (*models.Export, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	export, ok := r.exports[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	e := *export
+	return &e, nil
+}
+
+func (r *fakeExportRepository) GetByUserID(userID string) ([]*models.Export, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var exports []*models.Export
+	for _, export := range r.exports {
+		if export.UserID == userID {
+			e := *export
+			exports = append(exports, &e)
+		}
+	}
+	sort.Slice(exports, func(i, j int) bool {
+		return exports[i].CreatedAt.After(exports[j].CreatedAt)
+	})
+	return exports, nil
+}
+
+func (r *fakeExportRepository) GetExpired(now time.Time) ([]*models.Export, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var exports []*models.Export
+	for _, export := range r.exports {
+		if export.Status == models.ExportStatusCompleted && export.ExpiresAt != nil && export.ExpiresAt.Before(now) {
+			e := *export
+			exports = append(exports, &e)
+		}
+	}
+	return exports, nil
+}
+
+type fakeFeedRepository struct {
+	store *memoryStore
+}
+
+var _ repositories.FeedRepository = (*fakeFeedRepository)(nil)
+
+func (r *fakeFeedRepository) Create(feed *models.Feed) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	feed.CreatedAt = now
+	feed.UpdatedAt = now
+	stored := *feed
+	r.store.feeds[feed.ID] = &stored
+	return nil
+}
+
+func (r *fakeFeedRepository) Update(feed *models.Feed) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	feed.UpdatedAt = time.Now()
+	stored := *feed
+	r.store.feeds[feed.ID] = &stored
+	return nil
+}
+
+func (r *fakeFeedRepository) UpdatePoll(feed *models.Feed) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.store.feeds[feed.ID]
+	if !ok {
+		return nil
+	}
+	stored.ETag = feed.ETag
+	stored.LastModified = feed.LastModified
+	stored.Title = feed.Title
+	stored.SiteURL = feed.SiteURL
+	stored.LastFetchedAt = feed.LastFetchedAt
+	stored.NextFetchAt = feed.NextFetchAt
+	stored.ErrorCount = feed.ErrorCount
+	stored.LastError = feed.LastError
+	stored.UpdatedAt = time.Now()
+	return nil
+}
+
+func (r *fakeFeedRepository) Delete(id, userID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	feed, ok := r.store.feeds[id]
+	if !ok || feed.UserID != userID {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.store.feeds, id)
+	delete(r.store.feedEntries, id)
+	return nil
+}
+
+func (r *fakeFeedRepository) GetByID(id string) (*models.Feed, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	feed, ok := r.store.feeds[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	f := *feed
+	return &f, nil
+}
+
+func (r *fakeFeedRepository) GetByUserID(userID string) ([]*models.Feed, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var feeds []*models.Feed
+	for _, feed := range r.store.feeds {
+		if feed.UserID == userID {
+			f := *feed
+			feeds = append(feeds, &f)
+		}
+	}
+	sort.Slice(feeds, func(i, j int) bool {
+		if feeds[i].Title != feeds[j].Title {
+			return feeds[i].Title < feeds[j].Title
+		}
+		return feeds[i].CreatedAt.Before(feeds[j].CreatedAt)
+	})
+	return feeds, nil
+}
+
+func (r *fakeFeedRepository) GetByURL(userID, url string) (*models.Feed, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, feed := range r.store.feeds {
+		if feed.UserID == userID && feed.URL == url {
+			f := *feed
+			return &f, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeFeedRepository) GetDue(now time.Time, limit int) ([]*models.Feed, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var feeds []*models.Feed
+	for _, feed := range r.store.feeds {
+		if feed.IsActive && !feed.NextFetchAt.After(now) {
+			f := *feed
+			feeds = append(feeds, &f)
+		}
+	}
+	sort.Slice(feeds, func(i, j int) bool { return feeds[i].NextFetchAt.Before(feeds[j].NextFetchAt) })
+	if len(feeds) > limit {
+		feeds = feeds[:limit]
+	}
+	return feeds, nil
+}
+
+func (r *fakeFeedRepository) SeenEntries(feedID string, keys []string) (map[string]bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		if _, ok := r.store.feedEntries[feedID][key]; ok {
+			seen[key] = true
+		}
+	}
+	return seen, nil
+}
+
+func (r *fakeFeedRepository) AddEntries(entries []*models.FeedEntry) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, entry := range entries {
+		if r.store.feedEntries[entry.FeedID] == nil {
+			r.store.feedEntries[entry.FeedID] = make(map[string]*string)
+		}
+		if _, ok := r.store.feedEntries[entry.FeedID][entry.EntryKey]; !ok {
+			r.store.feedEntries[entry.FeedID][entry.EntryKey] = entry.ArticleID
+		}
+	}
+	return nil
+}
+
+type fakeArticleSummaryRepository struct {
+	store *memoryStore
+}
+
+var _ repositories.ArticleSummaryRepository = (*fakeArticleSummaryRepository)(nil)
+
+// deleteSummaries removes the summaries of an article. The caller must hold
+// the lock.
+func (s *memoryStore) deleteSummaries(articleID string) {
+	kept := s.summaries[:0]
+	for _, summary := range s.summaries {
+		if summary.ArticleID != articleID {
+			kept = append(kept, summary)
+		}
+	}
+	s.summaries = kept
+}
+
+func (r *fakeArticleSummaryRepository) Create(summary *models.ArticleSummary) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, stored := range r.store.summaries {
+		if stored.ArticleID == summary.ArticleID && stored.SummaryType == summary.SummaryType && stored.Language == summary.Language {
+			stored.IsCurrent = false
+		}
+	}
+	summary.IsCurrent = true
+	summary.CreatedAt = time.Now()
+	stored := *summary
+	r.store.summaries = append(r.store.summaries, &stored)
+	return nil
+}
+
+func (r *fakeArticleSummaryRepository) GetByArticleID(articleID string) ([]*models.ArticleSummary, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var summaries []*models.ArticleSummary
+	for i := len(r.store.summaries) - 1; i >= 0; i-- {
+		if summary := r.store.summaries[i]; summary.ArticleID == articleID {
+			s := *summary
+			summaries = append(summaries, &s)
+		}
+	}
+	return summaries, nil
+}
+
+func (r *fakeArticleSummaryRepository) GetCurrent(articleID, summaryType, language string) (*models.ArticleSummary, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, summary := range r.store.summaries {
+		if summary.ArticleID == articleID && summary.SummaryType == summaryType && summary.Language == language && summary.IsCurrent {
+			s := *summary
+			return &s, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+type fakePreferenceRepository struct {
+	store *memoryStore
+}
+
+var _ repositories.PreferenceRepository = (*fakePreferenceRepository)(nil)
+
+func (r *fakePreferenceRepository) Get(userID string) (*models.UserPreference, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if preference, ok := r.store.preferences[userID]; ok {
+		p := *preference
+		return &p, nil
+	}
+	return models.DefaultUserPreference(userID), nil
+}
+
+func (r *fakePreferenceRepository) Save(preference *models.UserPreference) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored := *preference
+	r.store.preferences[preference.UserID] = &stored
+	return nil
+}
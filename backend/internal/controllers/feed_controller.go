@@ -0,0 +1,471 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/eikuma/stockle/backend/internal/feeds"
+	"github.com/eikuma/stockle/backend/internal/models"
+	"github.com/eikuma/stockle/backend/internal/repositories"
+	"github.com/eikuma/stockle/backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// maxOPMLFileSize bounds an uploaded OPML file
+const maxOPMLFileSize = 4 << 20
+
+type FeedController struct {
+	feedRepo     repositories.FeedRepository
+	categoryRepo repositories.CategoryRepository
+	feedService  *services.FeedService
+}
+
+// CreateFeedRequest subscribes to the feed at URL, or to the first feed
+// announced by the page at URL
+type CreateFeedRequest struct {
+	URL        string   `json:"url" binding:"required,url"`
+	CategoryID *string  `json:"categoryId,omitempty"`
+	Tags       []string `json:"tags,omitempty" binding:"omitempty,max=50,dive,max=50"`
+	// IncludeKeywords keep only the entries mentioning one of them;
+	// entries mentioning one of ExcludeKeywords are skipped
+	IncludeKeywords []string `json:"includeKeywords,omitempty" binding:"omitempty,max=50,dive,max=100"`
+	ExcludeKeywords []string `json:"excludeKeywords,omitempty" binding:"omitempty,max=50,dive,max=100"`
+}
+
+// UpdateFeedRequest changes the given fields only. An empty CategoryID
+// files new articles in the default category.
+type UpdateFeedRequest struct {
+	Title           *string   `json:"title,omitempty" binding:"omitempty,max=500"`
+	CategoryID      *string   `json:"categoryId,omitempty"`
+	Tags            *[]string `json:"tags,omitempty" binding:"omitempty,max=50,dive,max=50"`
+	IncludeKeywords *[]string `json:"includeKeywords,omitempty" binding:"omitempty,max=50,dive,max=100"`
+	ExcludeKeywords *[]string `json:"excludeKeywords,omitempty" binding:"omitempty,max=50,dive,max=100"`
+	IsActive        *bool     `json:"isActive,omitempty"`
+}
+
+type FeedResponse struct {
+	Message string       `json:"message,omitempty"`
+	Feed    *models.Feed `json:"feed,omitempty"`
+}
+
+type FeedListResponse struct {
+	Feeds []*models.Feed `json:"feeds"`
+}
+
+// ImportFeedsResponse lists the feeds an OPML import subscribed to; the
+// feeds the user already followed are counted as skipped
+type ImportFeedsResponse struct {
+	Message string         `json:"message"`
+	Feeds   []*models.Feed `json:"feeds"`
+	Skipped int            `json:"skipped"`
+}
+
+func NewFeedController(
+	feedRepo repositories.FeedRepository,
+	categoryRepo repositories.CategoryRepository,
+	feedService *services.FeedService,
+) *FeedController {
+	return &FeedController{
+		feedRepo:     feedRepo,
+		categoryRepo: categoryRepo,
+		feedService:  feedService,
+	}
+}
+
+// CreateFeed subscribes to a feed and schedules its first poll
+// POST /api/v1/feeds
+func (c *FeedController) CreateFeed(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	var req CreateFeedRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid JSON format: " + err.Error(),
+		})
+		return
+	}
+
+	categoryID, ok := c.feedCategory(ctx, userID, req.CategoryID)
+	if !ok {
+		return
+	}
+
+	feed, err := c.feedService.Subscribe(ctx.Request.Context(), userID, req.URL, services.FeedOptions{
+		CategoryID:      categoryID,
+		Tags:            req.Tags,
+		IncludeKeywords: req.IncludeKeywords,
+		ExcludeKeywords: req.ExcludeKeywords,
+	})
+	switch {
+	case errors.Is(err, services.ErrFeedNotFound):
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "feed_not_found",
+			Message: "The URL is not a feed and the page does not link to one",
+		})
+		return
+	case errors.Is(err, services.ErrAlreadySubscribed):
+		ctx.JSON(http.StatusConflict, ErrorResponse{
+			Error:   "already_subscribed",
+			Message: "You already follow this feed",
+		})
+		return
+	case errors.Is(err, services.ErrFeedUnreachable):
+		ctx.JSON(http.StatusBadGateway, ErrorResponse{
+			Error:   "fetch_failed",
+			Message: err.Error(),
+		})
+		return
+	case err != nil:
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "create_failed",
+			Message: "Failed to subscribe to feed: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, FeedResponse{
+		Message: "Subscribed to feed; new entries are saved in the background",
+		Feed:    feed,
+	})
+}
+
+// GetFeeds lists the user's feeds by title
+// GET /api/v1/feeds
+func (c *FeedController) GetFeeds(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	list, err := c.feedRepo.GetByUserID(userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "fetch_failed",
+			Message: "Failed to fetch feeds: " + err.Error(),
+		})
+		return
+	}
+
+	if list == nil {
+		list = []*models.Feed{}
+	}
+	ctx.JSON(http.StatusOK, FeedListResponse{
+		Feeds: list,
+	})
+}
+
+// GetFeed returns a feed with the outcome of its last fetch
+// GET /api/v1/feeds/:id
+func (c *FeedController) GetFeed(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	feed, ok := c.getOwnedFeed(ctx, userID)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, FeedResponse{
+		Feed: feed,
+	})
+}
+
+// UpdateFeed changes the title, category, tags, filters or state of a feed.
+// Articles saved before are not touched.
+// PATCH /api/v1/feeds/:id
+func (c *FeedController) UpdateFeed(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	var req UpdateFeedRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid JSON format: " + err.Error(),
+		})
+		return
+	}
+
+	feed, ok := c.getOwnedFeed(ctx, userID)
+	if !ok {
+		return
+	}
+
+	if req.Title != nil {
+		title := strings.TrimSpace(*req.Title)
+		if title == "" {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "invalid_request",
+				Message: "Feed title must not be empty",
+			})
+			return
+		}
+		feed.Title = title
+	}
+
+	options := services.FeedOptions{
+		CategoryID:      feed.CategoryID,
+		Tags:            feed.Tags,
+		IncludeKeywords: feed.IncludeKeywords,
+		ExcludeKeywords: feed.ExcludeKeywords,
+	}
+	if req.CategoryID != nil {
+		categoryID, ok := c.feedCategory(ctx, userID, req.CategoryID)
+		if !ok {
+			return
+		}
+		options.CategoryID = categoryID
+	}
+	if req.Tags != nil {
+		options.Tags = *req.Tags
+	}
+	if req.IncludeKeywords != nil {
+		options.IncludeKeywords = *req.IncludeKeywords
+	}
+	if req.ExcludeKeywords != nil {
+		options.ExcludeKeywords = *req.ExcludeKeywords
+	}
+	c.feedService.ApplyOptions(feed, options)
+
+	if req.IsActive != nil {
+		feed.IsActive = *req.IsActive
+	}
+
+	if err := c.feedRepo.Update(feed); err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "update_failed",
+			Message: "Failed to update feed: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, FeedResponse{
+		Message: "Feed updated successfully",
+		Feed:    feed,
+	})
+}
+
+// DeleteFeed unsubscribes from a feed; the articles it saved are kept
+// DELETE /api/v1/feeds/:id
+func (c *FeedController) DeleteFeed(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	feed, ok := c.getOwnedFeed(ctx, userID)
+	if !ok {
+		return
+	}
+
+	if err := c.feedRepo.Delete(feed.ID, userID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "delete_failed",
+			Message: "Failed to delete feed: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, FeedResponse{
+		Message: "Feed deleted successfully",
+	})
+}
+
+// RefreshFeed schedules a fetch of a feed without waiting for its turn
+// POST /api/v1/feeds/:id/refresh
+func (c *FeedController) RefreshFeed(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	feed, ok := c.getOwnedFeed(ctx, userID)
+	if !ok {
+		return
+	}
+
+	if err := c.feedService.Refresh(feed); err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "refresh_failed",
+			Message: "Failed to schedule the feed: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, FeedResponse{
+		Message: "Feed refresh scheduled",
+		Feed:    feed,
+	})
+}
+
+// ImportFeeds subscribes to the feeds of an uploaded OPML file, filing each
+// in the category of its folder
+// POST /api/v1/feeds/import
+func (c *FeedController) ImportFeeds(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxOPMLFileSize+1<<20)
+	fileHeader, err := ctx.FormFile("file")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) || (err == nil && fileHeader.Size > maxOPMLFileSize) {
+		ctx.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{
+			Error:   "file_too_large",
+			Message: "OPML files are limited to 4 MB",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "An OPML file is required in the \"file\" field: " + err.Error(),
+		})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Failed to read the OPML file: " + err.Error(),
+		})
+		return
+	}
+	defer file.Close()
+
+	subscriptions, err := feeds.ParseOPML(file)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_file",
+			Message: "Failed to parse the OPML file: " + err.Error(),
+		})
+		return
+	}
+
+	imported, err := c.feedService.ImportOPML(userID, subscriptions)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "import_failed",
+			Message: "Failed to import feeds: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, ImportFeedsResponse{
+		Message: "Feeds imported; new entries are saved in the background",
+		Feeds:   imported,
+		Skipped: len(subscriptions) - len(imported),
+	})
+}
+
+// ExportFeeds downloads the user's feeds as an OPML file
+// GET /api/v1/feeds/export
+func (c *FeedController) ExportFeeds(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := c.feedService.ExportOPML(&buf, userID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "export_failed",
+			Message: "Failed to export feeds: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.Header("Content-Disposition", `attachment; filename="stockle-feeds.opml"`)
+	ctx.Data(http.StatusOK, "text/x-opml; charset=utf-8", buf.Bytes())
+}
+
+// feedCategory checks that a requested category belongs to the user. An
+// empty or missing ID means the default category and is returned as nil.
+func (c *FeedController) feedCategory(ctx *gin.Context, userID string, id *string) (*string, bool) {
+	if id == nil || *id == "" {
+		return nil, true
+	}
+	category, err := c.categoryRepo.GetByID(*id)
+	if err != nil || category.UserID != userID {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_category",
+			Message: "Category not found",
+		})
+		return nil, false
+	}
+	return &category.ID, true
+}
+
+func (c *FeedController) getOwnedFeed(ctx *gin.Context, userID string) (*models.Feed, bool) {
+	feedID := ctx.Param("id")
+	if feedID == "" {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Feed ID is required",
+		})
+		return nil, false
+	}
+
+	feed, err := c.feedRepo.GetByID(feedID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, ErrorResponse{
+			Error:   "not_found",
+			Message: "Feed not found",
+		})
+		return nil, false
+	}
+
+	if feed.UserID != userID {
+		ctx.JSON(http.StatusForbidden, ErrorResponse{
+			Error:   "forbidden",
+			Message: "Access denied",
+		})
+		return nil, false
+	}
+
+	return feed, true
+}
@@ -0,0 +1,442 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eikuma/stockle/backend/internal/models"
+	"github.com/eikuma/stockle/backend/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// feedServer serves a blog whose home page announces an RSS feed. The feed
+// answers conditional requests with 304 until its items change.
+type feedServer struct {
+	*httptest.Server
+	mu          sync.Mutex
+	items       []string
+	version     int
+	notModified int
+	broken      bool
+	// onFetch runs while the feed is being fetched
+	onFetch func()
+}
+
+func newFeedServer(t *testing.T, items ...string) *feedServer {
+	server := &feedServer{items: items, version: 1}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.mu.Lock()
+		defer server.mu.Unlock()
+
+		switch {
+		case r.URL.Path == "/":
+			fmt.Fprint(w, `<html><head><title>Blog</title>
+<link rel="alternate" type="application/rss+xml" href="/feed.xml"></head><body></body></html>`)
+		case r.URL.Path == "/plain":
+			fmt.Fprint(w, `<html><head><title>No feed here</title></head><body></body></html>`)
+		case r.URL.Path == "/feed.xml":
+			if server.onFetch != nil {
+				server.onFetch()
+			}
+			if server.broken {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			etag := fmt.Sprintf(`"v%d"`, server.version)
+			if r.Header.Get("If-None-Match") == etag {
+				server.notModified++
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("Content-Type", "application/rss+xml")
+			w.Header().Set("ETag", etag)
+			fmt.Fprint(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>Example Blog</title><link>/</link>`)
+			for i, title := range server.items {
+				fmt.Fprintf(w, `<item><title>%s</title><link>/posts/%d</link><guid>post-%d</guid></item>`, title, i, i)
+			}
+			fmt.Fprint(w, `</channel></rss>`)
+		default:
+			// Posts keep the title of their item once extracted
+			var index int
+			if _, err := fmt.Sscanf(r.URL.Path, "/posts/%d", &index); err != nil || index >= len(server.items) {
+				http.NotFound(w, r)
+				return
+			}
+			fmt.Fprintf(w, `<html><head><title>%s</title></head><body><p>Post body</p></body></html>`, server.items[index])
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// publish adds an item to the feed and changes its ETag
+func (s *feedServer) publish(title string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items = append(s.items, title)
+	s.version++
+}
+
+// feedArticles returns the articles of a user by title
+func feedArticles(api *testAPI, userID string) []*models.Article {
+	api.store.mu.Lock()
+	defer api.store.mu.Unlock()
+
+	var articles []*models.Article
+	for _, article := range api.store.articles {
+		if article.UserID == userID {
+			articles = append(articles, api.store.withAssociations(article))
+		}
+	}
+	sort.Slice(articles, func(i, j int) bool { return articles[i].Title < articles[j].Title })
+	return articles
+}
+
+func TestFeedController_SubscribeAndPoll(t *testing.T) {
+	api := newTestAPI(t)
+	_, err := api.categoryRepo.CreateDefault("user-1")
+	require.NoError(t, err)
+	news := &models.Category{ID: "news", UserID: "user-1", Name: "News"}
+	require.NoError(t, api.categoryRepo.Create(news))
+	server := newFeedServer(t, "Go 1.23 released", "Sponsored: buy now", "Rust news")
+
+	// The site URL leads to the feed it announces
+	var created FeedResponse
+	rec := api.do(http.MethodPost, "/api/v1/feeds", "user-1", map[string]interface{}{
+		"url":             server.URL + "/",
+		"categoryId":      "news",
+		"tags":            []string{"Feed", " feed ", "blog"},
+		"excludeKeywords": []string{"sponsored", " "},
+	}, &created)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	feed := created.Feed
+	assert.Equal(t, server.URL+"/feed.xml", feed.URL)
+	assert.Equal(t, "Example Blog", feed.Title)
+	assert.Equal(t, models.StringList{"Feed", "blog"}, feed.Tags)
+	assert.Equal(t, models.StringList{"sponsored"}, feed.ExcludeKeywords)
+	assert.True(t, feed.IsActive)
+
+	api.runJobs()
+
+	// Entries matching an exclude keyword are skipped
+	articles := feedArticles(api, "user-1")
+	require.Len(t, articles, 2)
+	assert.Equal(t, "Go 1.23 released", articles[0].Title)
+	assert.Equal(t, server.URL+"/posts/0", articles[0].URL)
+	assert.Equal(t, models.ArticleStatusUnread, articles[0].Status)
+	assert.Equal(t, "news", *articles[0].CategoryID)
+	require.Len(t, articles[0].Tags, 2)
+	assert.Equal(t, "Rust news", articles[1].Title)
+
+	var got FeedResponse
+	rec = api.do(http.MethodGet, "/api/v1/feeds/"+feed.ID, "user-1", nil, &got)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NotNil(t, got.Feed.LastFetchedAt)
+	assert.Zero(t, got.Feed.ErrorCount)
+	assert.WithinDuration(t, time.Now().Add(30*time.Minute), got.Feed.NextFetchAt, time.Minute)
+
+	// An unchanged feed answers the conditional request with 304
+	rec = api.do(http.MethodPost, "/api/v1/feeds/"+feed.ID+"/refresh", "user-1", nil, nil)
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+	api.runJobs()
+	assert.Equal(t, 1, server.notModified)
+	assert.Len(t, feedArticles(api, "user-1"), 2)
+
+	// Only the new entry is saved, even when the user deleted an older one
+	require.NoError(t, api.articleRepo.Delete(articles[1].ID, "user-1"))
+	server.publish("Go 1.24 released")
+	rec = api.do(http.MethodPost, "/api/v1/feeds/"+feed.ID+"/refresh", "user-1", nil, nil)
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+	api.runJobs()
+	articles = feedArticles(api, "user-1")
+	require.Len(t, articles, 2)
+	assert.Equal(t, "Go 1.24 released", articles[1].Title)
+
+	// The same feed cannot be followed twice
+	rec = api.do(http.MethodPost, "/api/v1/feeds", "user-1", map[string]interface{}{"url": server.URL + "/feed.xml"}, nil)
+	assert.Equal(t, http.StatusConflict, rec.Code, rec.Body.String())
+	// Other users can
+	rec = api.do(http.MethodPost, "/api/v1/feeds", "user-2", map[string]interface{}{"url": server.URL + "/feed.xml"}, nil)
+	assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+}
+
+func TestFeedController_Include(t *testing.T) {
+	api := newTestAPI(t)
+	_, err := api.categoryRepo.CreateDefault("user-1")
+	require.NoError(t, err)
+	server := newFeedServer(t, "Go 1.23 released", "Rust news", "GOPHERCON recap")
+
+	var created FeedResponse
+	rec := api.do(http.MethodPost, "/api/v1/feeds", "user-1", map[string]interface{}{
+		"url":             server.URL + "/feed.xml",
+		"includeKeywords": []string{"go", "Gopher"},
+		"excludeKeywords": []string{"recap"},
+	}, &created)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	api.runJobs()
+
+	articles := feedArticles(api, "user-1")
+	require.Len(t, articles, 1)
+	assert.Equal(t, "Go 1.23 released", articles[0].Title)
+	// Without a category the default one is used
+	defaultCategory, err := api.categoryRepo.GetDefault("user-1")
+	require.NoError(t, err)
+	assert.Equal(t, defaultCategory.ID, *articles[0].CategoryID)
+}
+
+func TestFeedController_SubscribeErrors(t *testing.T) {
+	api := newTestAPI(t)
+	server := newFeedServer(t)
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	var errResp ErrorResponse
+	rec := api.do(http.MethodPost, "/api/v1/feeds", "user-1", map[string]interface{}{"url": server.URL + "/plain"}, &errResp)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "feed_not_found", errResp.Error)
+
+	rec = api.do(http.MethodPost, "/api/v1/feeds", "user-1", map[string]interface{}{"url": closed.URL + "/feed.xml"}, &errResp)
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+	assert.Equal(t, "fetch_failed", errResp.Error)
+
+	rec = api.do(http.MethodPost, "/api/v1/feeds", "user-1", map[string]interface{}{"url": "not a url"}, &errResp)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "invalid_request", errResp.Error)
+
+	other := &models.Category{ID: "other", UserID: "user-2", Name: "Other"}
+	require.NoError(t, api.categoryRepo.Create(other))
+	rec = api.do(http.MethodPost, "/api/v1/feeds", "user-1", map[string]interface{}{"url": server.URL + "/feed.xml", "categoryId": "other"}, &errResp)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "invalid_category", errResp.Error)
+
+	rec = api.do(http.MethodPost, "/api/v1/feeds", "", map[string]interface{}{"url": server.URL + "/feed.xml"}, nil)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestFeedController_UpdateAndDelete(t *testing.T) {
+	api := newTestAPI(t)
+	_, err := api.categoryRepo.CreateDefault("user-1")
+	require.NoError(t, err)
+	news := &models.Category{ID: "news", UserID: "user-1", Name: "News"}
+	require.NoError(t, api.categoryRepo.Create(news))
+	server := newFeedServer(t, "First")
+
+	var created FeedResponse
+	rec := api.do(http.MethodPost, "/api/v1/feeds", "user-1", map[string]interface{}{"url": server.URL + "/feed.xml", "categoryId": "news"}, &created)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	path := "/api/v1/feeds/" + created.Feed.ID
+
+	var updated FeedResponse
+	rec = api.do(http.MethodPatch, path, "user-1", map[string]interface{}{
+		"title":           "  My blog ",
+		"categoryId":      "",
+		"includeKeywords": []string{"go"},
+		"isActive":        false,
+	}, &updated)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "My blog", updated.Feed.Title)
+	assert.Nil(t, updated.Feed.CategoryID)
+	assert.Equal(t, models.StringList{"go"}, updated.Feed.IncludeKeywords)
+	assert.Empty(t, updated.Feed.Tags)
+	assert.False(t, updated.Feed.IsActive)
+
+	// Paused feeds are not fetched
+	api.runJobs()
+	assert.Empty(t, feedArticles(api, "user-1"))
+	scheduled, err := api.feeds.EnqueueDue(time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, scheduled)
+
+	var errResp ErrorResponse
+	rec = api.do(http.MethodPatch, path, "user-1", map[string]interface{}{"title": " "}, &errResp)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = api.do(http.MethodPatch, path, "user-1", map[string]interface{}{"categoryId": "missing"}, &errResp)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "invalid_category", errResp.Error)
+
+	rec = api.do(http.MethodGet, path, "user-2", nil, nil)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	rec = api.do(http.MethodDelete, path, "user-2", nil, nil)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	rec = api.do(http.MethodGet, "/api/v1/feeds/missing", "user-1", nil, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = api.do(http.MethodDelete, path, "user-1", nil, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var list FeedListResponse
+	rec = api.do(http.MethodGet, "/api/v1/feeds", "user-1", nil, &list)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Empty(t, list.Feeds)
+}
+
+func TestFeedService_KeepsChangesMadeDuringFetch(t *testing.T) {
+	api := newTestAPI(t)
+	_, err := api.categoryRepo.CreateDefault("user-1")
+	require.NoError(t, err)
+	server := newFeedServer(t, "First")
+
+	var created FeedResponse
+	rec := api.do(http.MethodPost, "/api/v1/feeds", "user-1", map[string]interface{}{"url": server.URL + "/feed.xml"}, &created)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	path := "/api/v1/feeds/" + created.Feed.ID
+	api.runJobs()
+
+	// The user pauses the feed and changes its filters while it is polled
+	server.publish("Second")
+	server.onFetch = func() {
+		rec := api.do(http.MethodPatch, path, "user-1", map[string]interface{}{
+			"includeKeywords": []string{"go"},
+			"isActive":        false,
+		}, nil)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	}
+	require.NoError(t, api.feeds.Refresh(created.Feed))
+	api.runJobs()
+
+	feed, err := api.feedRepo.GetByID(created.Feed.ID)
+	require.NoError(t, err)
+	assert.False(t, feed.IsActive)
+	assert.Equal(t, models.StringList{"go"}, feed.IncludeKeywords)
+	require.NotNil(t, feed.ETag)
+	assert.Equal(t, `"v2"`, *feed.ETag)
+}
+
+func TestFeedService_Backoff(t *testing.T) {
+	api := newTestAPI(t)
+	_, err := api.categoryRepo.CreateDefault("user-1")
+	require.NoError(t, err)
+	server := newFeedServer(t, "First")
+
+	var created FeedResponse
+	rec := api.do(http.MethodPost, "/api/v1/feeds", "user-1", map[string]interface{}{"url": server.URL + "/feed.xml"}, &created)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	api.runJobs()
+
+	// A failed fetch is recorded and its job retried
+	server.mu.Lock()
+	server.broken = true
+	server.mu.Unlock()
+	require.NoError(t, api.feeds.Refresh(created.Feed))
+	api.runJobs()
+	feed, err := api.feedRepo.GetByID(created.Feed.ID)
+	require.NoError(t, err)
+	require.NotNil(t, feed.LastError)
+	assert.Contains(t, *feed.LastError, "Internal Server Error")
+
+	// Once the job gives up, every failure in a row doubles the wait
+	payload := &services.JobPayload{FeedID: feed.ID}
+	for _, wait := range []time.Duration{time.Hour, 2 * time.Hour, 4 * time.Hour} {
+		api.feeds.JobFailed(nil, payload, fmt.Errorf("fetch failed"))
+		feed, err = api.feedRepo.GetByID(feed.ID)
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(wait), feed.NextFetchAt, time.Minute)
+	}
+	assert.Equal(t, 3, feed.ErrorCount)
+	for i := 0; i < 10; i++ {
+		api.feeds.JobFailed(nil, payload, fmt.Errorf("fetch failed"))
+	}
+	feed, err = api.feedRepo.GetByID(feed.ID)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), feed.NextFetchAt, time.Minute)
+
+	// The scheduler picks up due feeds once and moves them to their next turn
+	now := time.Now().Add(25 * time.Hour)
+	scheduled, err := api.feeds.EnqueueDue(now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, scheduled)
+	scheduled, err = api.feeds.EnqueueDue(now)
+	require.NoError(t, err)
+	assert.Zero(t, scheduled)
+
+	// A successful fetch resets the errors
+	server.mu.Lock()
+	server.broken = false
+	server.mu.Unlock()
+	api.runJobs()
+	feed, err = api.feedRepo.GetByID(feed.ID)
+	require.NoError(t, err)
+	assert.Zero(t, feed.ErrorCount)
+	assert.Nil(t, feed.LastError)
+}
+
+func TestFeedController_OPML(t *testing.T) {
+	api := newTestAPI(t)
+	_, err := api.categoryRepo.CreateDefault("user-1")
+	require.NoError(t, err)
+	tech := &models.Category{ID: "tech", UserID: "user-1", Name: "Tech"}
+	require.NoError(t, api.categoryRepo.Create(tech))
+	existing := &models.Feed{ID: "existing", UserID: "user-1", URL: "https://blog.rust-lang.org/feed.xml", Title: "Rust Blog", IsActive: true}
+	require.NoError(t, api.feedRepo.Create(existing))
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "subscriptions.opml")
+	require.NoError(t, err)
+	_, err = part.Write([]byte(`<?xml version="1.0"?>
+<opml version="1.0"><body>
+  <outline text="tech">
+    <outline text="Go">
+      <outline text="Go Blog" xmlUrl="https://go.dev/blog/feed.atom" htmlUrl="https://go.dev/blog/"/>
+    </outline>
+    <outline text="Rust Blog" xmlUrl="https://blog.rust-lang.org/feed.xml"/>
+  </outline>
+  <outline text="News" xmlUrl="https://news.example.com/rss"/>
+</body></opml>`))
+	require.NoError(t, err)
+	require.NoError(t, form.Close())
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/feeds/import", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set(testUserHeader, "user-1")
+	rec := httptest.NewRecorder()
+	api.router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var imported ImportFeedsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &imported))
+	require.Len(t, imported.Feeds, 2)
+	assert.Equal(t, 1, imported.Skipped)
+
+	// Folders are matched against the categories by path, without regard
+	// to case, and the missing ones are created
+	goBlog := imported.Feeds[0]
+	assert.Equal(t, "Go Blog", goBlog.Title)
+	assert.Equal(t, "https://go.dev/blog/", *goBlog.SiteURL)
+	require.NotNil(t, goBlog.CategoryID)
+	goCategory, err := api.categoryRepo.GetByID(*goBlog.CategoryID)
+	require.NoError(t, err)
+	assert.Equal(t, "Go", goCategory.Name)
+	assert.Equal(t, "tech", *goCategory.ParentID)
+	assert.Nil(t, imported.Feeds[1].CategoryID)
+
+	existing.CategoryID = &tech.ID
+	require.NoError(t, api.feedRepo.Update(existing))
+	rec = api.do(http.MethodGet, "/api/v1/feeds/export", "user-1", nil, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Header().Get("Content-Disposition"), "stockle-feeds.opml")
+	opml := rec.Body.String()
+	assert.Contains(t, opml, `<outline text="Tech" title="Tech">`)
+	assert.Contains(t, opml, `<outline text="Go" title="Go">`)
+	assert.Contains(t, opml, `xmlUrl="https://go.dev/blog/feed.atom" htmlUrl="https://go.dev/blog/"`)
+	assert.Contains(t, opml, `xmlUrl="https://blog.rust-lang.org/feed.xml"`)
+	assert.Contains(t, opml, `xmlUrl="https://news.example.com/rss"`)
+	assert.True(t, strings.Index(opml, "Go Blog") < strings.Index(opml, "Rust Blog"))
+
+	// Deleting a category moves its feeds along with its articles
+	rec = api.do(http.MethodDelete, "/api/v1/categories/"+*goBlog.CategoryID, "user-1", nil, nil)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	moved, err := api.feedRepo.GetByID(goBlog.ID)
+	require.NoError(t, err)
+	defaultCategory, err := api.categoryRepo.GetDefault("user-1")
+	require.NoError(t, err)
+	assert.Equal(t, defaultCategory.ID, *moved.CategoryID)
+}
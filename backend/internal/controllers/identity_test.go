@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/eikuma/stockle/backend/internal/models"
+	"github.com/eikuma/stockle/backend/internal/services"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type loginResponse struct {
+	Tokens services.TokenPair  `json:"tokens"`
+	User   models.UserResponse `json:"user"`
+}
+
+// registerAndLogin creates an account through the API and returns the login response
+func registerAndLogin(t *testing.T, api *testAPI, email string) loginResponse {
+	t.Helper()
+
+	rec := api.do(http.MethodPost, "/api/v1/auth/register", "", services.RegisterRequest{
+		Email:       email,
+		Password:    "password123",
+		DisplayName: email,
+	}, nil)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var resp loginResponse
+	rec = api.do(http.MethodPost, "/api/v1/auth/login", "", services.LoginRequest{
+		Email:    email,
+		Password: "password123",
+	}, &resp)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	return resp
+}
+
+func TestIdentity_TokenUserOwnsArticles(t *testing.T) {
+	api := newTestAPI(t)
+	server := newArticleServer(t)
+
+	alice := registerAndLogin(t, api, "alice@example.com")
+	bob := registerAndLogin(t, api, "bob@example.com")
+
+	// User IDs are UUIDs and are carried unchanged into the JWT claims
+	_, err := uuid.Parse(alice.User.ID)
+	require.NoError(t, err)
+	claims, err := api.authService.ValidateToken(alice.Tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, alice.User.ID, claims.UserID)
+
+	// Registration created the default category under the same ID
+	defaultCategory, err := api.categoryRepo.GetDefault(alice.User.ID)
+	require.NoError(t, err)
+
+	var saved ArticleResponse
+	rec := api.doWithToken(http.MethodPost, "/api/v1/articles", alice.Tokens.AccessToken, SaveArticleRequest{
+		URL: server.URL + "/alice",
+	}, &saved)
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+	assert.Equal(t, alice.User.ID, saved.Article.UserID)
+	require.NotNil(t, saved.Article.CategoryID)
+	assert.Equal(t, defaultCategory.ID, *saved.Article.CategoryID)
+
+	t.Run("owner can read the article", func(t *testing.T) {
+		var resp map[string]*models.Article
+		rec := api.doWithToken(http.MethodGet, "/api/v1/articles/"+saved.Article.ID, alice.Tokens.AccessToken, nil, &resp)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, saved.Article.ID, resp["article"].ID)
+
+		var list ArticleListResponse
+		rec = api.doWithToken(http.MethodGet, "/api/v1/articles", alice.Tokens.AccessToken, nil, &list)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.NotNil(t, list.Total)
+		assert.Equal(t, int64(1), *list.Total)
+	})
+
+	t.Run("another user cannot read or modify it", func(t *testing.T) {
+		rec := api.doWithToken(http.MethodGet, "/api/v1/articles/"+saved.Article.ID, bob.Tokens.AccessToken, nil, nil)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+
+		status := models.ArticleStatusRead
+		rec = api.doWithToken(http.MethodPatch, "/api/v1/articles/"+saved.Article.ID, bob.Tokens.AccessToken,
+			UpdateArticleRequest{Status: &status}, nil)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+
+		rec = api.doWithToken(http.MethodDelete, "/api/v1/articles/"+saved.Article.ID, bob.Tokens.AccessToken, nil, nil)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+
+		var list ArticleListResponse
+		rec = api.doWithToken(http.MethodGet, "/api/v1/articles", bob.Tokens.AccessToken, nil, &list)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.NotNil(t, list.Total)
+		assert.Equal(t, int64(0), *list.Total)
+	})
+
+	t.Run("invalid token is rejected", func(t *testing.T) {
+		rec := api.doWithToken(http.MethodGet, "/api/v1/articles", "not-a-token", nil, nil)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
@@ -0,0 +1,256 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+
+	"github.com/eikuma/stockle/backend/internal/importer"
+	"github.com/eikuma/stockle/backend/internal/models"
+	"github.com/eikuma/stockle/backend/internal/repositories"
+	"github.com/eikuma/stockle/backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportFileSize bounds an uploaded import file
+const maxImportFileSize = 32 << 20
+
+// maxImportErrors is the number of failed items returned with an import
+const maxImportErrors = 100
+
+type ImportController struct {
+	importRepo    repositories.ImportRepository
+	categoryRepo  repositories.CategoryRepository
+	importService *services.ImportService
+}
+
+type ImportResponse struct {
+	Message string         `json:"message,omitempty"`
+	Import  *models.Import `json:"import"`
+	// Errors lists the first failed items, in the order of the file
+	Errors []*models.ImportItem `json:"errors,omitempty"`
+}
+
+type ImportListResponse struct {
+	Imports []*models.Import `json:"imports"`
+}
+
+func NewImportController(
+	importRepo repositories.ImportRepository,
+	categoryRepo repositories.CategoryRepository,
+	importService *services.ImportService,
+) *ImportController {
+	return &ImportController{
+		importRepo:    importRepo,
+		categoryRepo:  categoryRepo,
+		importService: importService,
+	}
+}
+
+// CreateImport reads an uploaded export file and starts importing its links
+// in the background
+// POST /api/v1/imports
+func (c *ImportController) CreateImport(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxImportFileSize+1<<20) // room for the other form fields
+	fileHeader, err := ctx.FormFile("file")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) || (err == nil && fileHeader.Size > maxImportFileSize) {
+		ctx.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{
+			Error:   "file_too_large",
+			Message: "Import files are limited to 32 MB",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "An import file is required in the \"file\" field: " + err.Error(),
+		})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Failed to read the import file: " + err.Error(),
+		})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Failed to read the import file: " + err.Error(),
+		})
+		return
+	}
+
+	fileName := filepath.Base(fileHeader.Filename)
+	var format importer.Format
+	if name := ctx.PostForm("format"); name != "" {
+		format, err = importer.ParseFormat(name)
+	} else {
+		format, err = importer.Detect(fileName, data)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "unknown_format",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	items, err := importer.Parse(format, bytes.NewReader(data))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_file",
+			Message: "Failed to parse the import file: " + err.Error(),
+		})
+		return
+	}
+	if len(items) == 0 {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "empty_import",
+			Message: "The import file has no links",
+		})
+		return
+	}
+	if len(items) > services.MaxImportItems {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "too_many_items",
+			Message: "An import may have at most 20000 links; split the file",
+		})
+		return
+	}
+
+	var categoryID *string
+	if id := ctx.PostForm("categoryId"); id != "" {
+		category, err := c.categoryRepo.GetByID(id)
+		if err != nil || category.UserID != userID {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "invalid_category",
+				Message: "Category not found",
+			})
+			return
+		}
+		categoryID = &category.ID
+	}
+
+	imp, err := c.importService.Start(userID, format, fileName, categoryID, items)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "import_failed",
+			Message: "Failed to start the import: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, ImportResponse{
+		Message: "Import started; the links are saved in the background",
+		Import:  imp,
+	})
+}
+
+// GetImports lists the user's imports, newest first
+// GET /api/v1/imports
+func (c *ImportController) GetImports(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	imports, err := c.importRepo.GetByUserID(userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "fetch_failed",
+			Message: "Failed to fetch imports: " + err.Error(),
+		})
+		return
+	}
+
+	if imports == nil {
+		imports = []*models.Import{}
+	}
+	ctx.JSON(http.StatusOK, ImportListResponse{
+		Imports: imports,
+	})
+}
+
+// GetImport returns the progress of an import and the links that failed
+// GET /api/v1/imports/:id
+func (c *ImportController) GetImport(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	imp, ok := c.getOwnedImport(ctx, userID)
+	if !ok {
+		return
+	}
+
+	failed, err := c.importRepo.GetItems(imp.ID, models.ImportItemStatusFailed, maxImportErrors)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "fetch_failed",
+			Message: "Failed to fetch import errors: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, ImportResponse{
+		Import: imp,
+		Errors: failed,
+	})
+}
+
+func (c *ImportController) getOwnedImport(ctx *gin.Context, userID string) (*models.Import, bool) {
+	importID := ctx.Param("id")
+	if importID == "" {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Import ID is required",
+		})
+		return nil, false
+	}
+
+	imp, err := c.importRepo.GetByID(importID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, ErrorResponse{
+			Error:   "not_found",
+			Message: "Import not found",
+		})
+		return nil, false
+	}
+
+	if imp.UserID != userID {
+		ctx.JSON(http.StatusForbidden, ErrorResponse{
+			Error:   "forbidden",
+			Message: "Access denied",
+		})
+		return nil, false
+	}
+
+	return imp, true
+}
@@ -0,0 +1,311 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/eikuma/stockle/backend/internal/models"
+	"github.com/eikuma/stockle/backend/internal/urlnorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// upload posts an import file with the given form fields
+func (a *testAPI) upload(userID, fileName, content string, fields map[string]string, out interface{}) *httptest.ResponseRecorder {
+	a.t.Helper()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for name, value := range fields {
+		require.NoError(a.t, form.WriteField(name, value))
+	}
+	if fileName != "" {
+		part, err := form.CreateFormFile("file", fileName)
+		require.NoError(a.t, err)
+		_, err = part.Write([]byte(content))
+		require.NoError(a.t, err)
+	}
+	require.NoError(a.t, form.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/imports", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set(testUserHeader, userID)
+	rec := httptest.NewRecorder()
+	a.router.ServeHTTP(rec, req)
+
+	if out != nil {
+		require.NoError(a.t, json.Unmarshal(rec.Body.Bytes(), out), rec.Body.String())
+	}
+	return rec
+}
+
+// runImport processes the import job alone, leaving the extraction jobs of
+// the imported articles queued
+func (a *testAPI) runImport() {
+	a.t.Helper()
+
+	processed, err := a.jobService.ProcessNext(context.Background())
+	require.NoError(a.t, err)
+	require.True(a.t, processed)
+}
+
+const bookmarksFile = `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<TITLE>Bookmarks</TITLE>
+<DL><p>
+    <DT><H3>Tech</H3>
+    <DL><p>
+        <DT><A HREF="https://go.dev/blog/" ADD_DATE="1600000000" TAGS="go,Blog">The Go Blog</A>
+        <DT><H3>Web</H3>
+        <DL><p>
+            <DT><A HREF="https://developer.mozilla.org/">MDN</A>
+        </DL><p>
+    </DL><p>
+    <DT><A HREF="https://example.com/saved">Already saved</A>
+    <DT><A HREF="ftp://example.com/file">Not a web page</A>
+</DL><p>
+`
+
+func TestImportController_Bookmarks(t *testing.T) {
+	api := newTestAPI(t)
+	_, err := api.categoryRepo.CreateDefault("user-1")
+	require.NoError(t, err)
+
+	urlHash, err := urlnorm.New().Key("https://example.com/saved")
+	require.NoError(t, err)
+	existing := &models.Article{
+		ID:      "existing",
+		UserID:  "user-1",
+		URL:     "https://example.com/saved",
+		URLHash: &urlHash,
+		Title:   "Already saved",
+		Status:  models.ArticleStatusUnread,
+	}
+	require.NoError(t, api.articleRepo.Create(existing))
+
+	var started ImportResponse
+	rec := api.upload("user-1", "bookmarks.html", bookmarksFile, nil, &started)
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+	assert.Equal(t, "bookmarks_html", started.Import.Format)
+	assert.Equal(t, models.ImportStatusPending, started.Import.Status)
+	assert.Equal(t, 4, started.Import.TotalItems)
+
+	api.runImport()
+
+	var resp ImportResponse
+	rec = api.do(http.MethodGet, "/api/v1/imports/"+started.Import.ID, "user-1", nil, &resp)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, models.ImportStatusCompleted, resp.Import.Status)
+	assert.NotNil(t, resp.Import.CompletedAt)
+	assert.Equal(t, 4, resp.Import.ProcessedItems)
+	assert.Equal(t, 2, resp.Import.ImportedItems)
+	assert.Equal(t, 1, resp.Import.DuplicateItems)
+	assert.Equal(t, 1, resp.Import.FailedItems)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "ftp://example.com/file", resp.Errors[0].URL)
+	assert.Contains(t, *resp.Errors[0].Error, "only http and https")
+
+	// Folders become nested categories
+	categories, err := api.categoryRepo.GetByUserID("user-1")
+	require.NoError(t, err)
+	byName := make(map[string]*models.Category)
+	for _, category := range categories {
+		byName[category.Name] = category
+	}
+	require.Contains(t, byName, "Tech")
+	require.Contains(t, byName, "Web")
+	assert.Nil(t, byName["Tech"].ParentID)
+	require.NotNil(t, byName["Web"].ParentID)
+	assert.Equal(t, byName["Tech"].ID, *byName["Web"].ParentID)
+
+	articles, err := api.articleRepo.GetByUserID("user-1")
+	require.NoError(t, err)
+	require.Len(t, articles, 3)
+	byURL := make(map[string]*models.Article)
+	for _, article := range articles {
+		byURL[article.URL] = article
+	}
+
+	blog := byURL["https://go.dev/blog/"]
+	require.NotNil(t, blog)
+	assert.Equal(t, "The Go Blog", blog.Title)
+	assert.Equal(t, byName["Tech"].ID, *blog.CategoryID)
+	assert.True(t, blog.SavedAt.Equal(time.Unix(1600000000, 0)))
+	assert.Equal(t, models.ExtractionStatusPending, blog.ExtractionStatus)
+	withTags, err := api.articleRepo.GetByIDWithAssociations(blog.ID)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"go", "Blog"}, tagNames(withTags.Tags))
+
+	assert.Equal(t, byName["Web"].ID, *byURL["https://developer.mozilla.org/"].CategoryID)
+
+	// The articles are queued for extraction behind the ones saved by hand
+	pending, err := api.jobRepo.GetPendingJobs()
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	for _, job := range pending {
+		assert.Equal(t, models.JobTypeExtractContent, job.JobType)
+		assert.Equal(t, models.JobPriorityLow, job.Priority)
+	}
+
+	// Importing the file again finds the same categories and only duplicates
+	rec = api.upload("user-1", "bookmarks.html", bookmarksFile, nil, &started)
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+	api.runImport()
+	var again ImportResponse
+	api.do(http.MethodGet, "/api/v1/imports/"+started.Import.ID, "user-1", nil, &again)
+	assert.Equal(t, 3, again.Import.DuplicateItems)
+	assert.Equal(t, 0, again.Import.ImportedItems)
+	categories, err = api.categoryRepo.GetByUserID("user-1")
+	require.NoError(t, err)
+	assert.Len(t, categories, 3)
+
+	var list ImportListResponse
+	rec = api.do(http.MethodGet, "/api/v1/imports", "user-1", nil, &list)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Len(t, list.Imports, 2)
+}
+
+func TestImportController_StateAndCategory(t *testing.T) {
+	api := newTestAPI(t)
+	_, err := api.categoryRepo.CreateDefault("user-1")
+	require.NoError(t, err)
+	inbox := &models.Category{ID: "inbox", UserID: "user-1", Name: "Inbox"}
+	require.NoError(t, api.categoryRepo.Create(inbox))
+
+	csv := "title,url,time_added,tags,status\n" +
+		"Unread,https://example.com/unread,1600000000,go|web,unread\n" +
+		"Read,https://example.com/read,1600000100,,archive\n"
+
+	var started ImportResponse
+	rec := api.upload("user-1", "pocket.csv", csv, map[string]string{"categoryId": "inbox"}, &started)
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+	assert.Equal(t, "pocket_csv", started.Import.Format)
+	api.runImport()
+
+	articles, err := api.articleRepo.GetByUserID("user-1")
+	require.NoError(t, err)
+	require.Len(t, articles, 2)
+	for _, article := range articles {
+		assert.Equal(t, "inbox", *article.CategoryID)
+		if article.URL == "https://example.com/read" {
+			assert.Equal(t, models.ArticleStatusArchived, article.Status)
+			assert.Equal(t, 1.0, article.ReadingProgress)
+		} else {
+			assert.Equal(t, models.ArticleStatusUnread, article.Status)
+			assert.Zero(t, article.ReadingProgress)
+		}
+	}
+
+	// An explicit format overrides detection
+	var favorite ImportResponse
+	rec = api.upload("user-1", "export.txt", "URL,Title,Selection,Folder,Timestamp\nhttps://example.com/star,Star,,Starred,1300000000\n",
+		map[string]string{"format": "instapaper_csv"}, &favorite)
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+	api.runImport()
+	starred, err := api.articleRepo.GetFavorites("user-1", 1, 10)
+	require.NoError(t, err)
+	require.Len(t, starred.Articles, 1)
+	assert.Equal(t, "https://example.com/star", starred.Articles[0].URL)
+}
+
+func TestImportController_ResumesAfterWorkerStopped(t *testing.T) {
+	api := newTestAPI(t)
+	_, err := api.categoryRepo.CreateDefault("user-1")
+	require.NoError(t, err)
+
+	var started ImportResponse
+	rec := api.upload("user-1", "links.txt", "https://example.com/first\nhttps://example.com/second\n", nil, &started)
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+
+	// A worker imported the first link and stopped before the second
+	job, err := api.jobRepo.ClaimNextJob(time.Minute)
+	require.NoError(t, err)
+	require.NotNil(t, job)
+	imp, err := api.importRepo.GetByID(started.Import.ID)
+	require.NoError(t, err)
+	imp.Status = models.ImportStatusProcessing
+	require.NoError(t, api.importRepo.Update(imp))
+
+	items, err := api.importRepo.GetItems(imp.ID, models.ImportItemStatusPending, 1)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	urlHash, err := urlnorm.New().Key(items[0].URL)
+	require.NoError(t, err)
+	first := &models.Article{ID: "first", UserID: "user-1", URL: items[0].URL, URLHash: &urlHash, Title: "First", Status: models.ArticleStatusUnread}
+	require.NoError(t, api.articleRepo.Create(first))
+	items[0].Status = models.ImportItemStatusImported
+	items[0].ArticleID = &first.ID
+	require.NoError(t, api.importRepo.UpdateItem(items[0]))
+	api.jobRepo.abandon(job.ID)
+
+	// Another worker takes the job over and carries on with the second link
+	api.runImport()
+
+	var resp ImportResponse
+	rec = api.do(http.MethodGet, "/api/v1/imports/"+imp.ID, "user-1", nil, &resp)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, models.ImportStatusCompleted, resp.Import.Status)
+	assert.Equal(t, 2, resp.Import.ProcessedItems)
+	assert.Equal(t, 2, resp.Import.ImportedItems)
+	assert.Zero(t, resp.Import.DuplicateItems)
+
+	articles, err := api.articleRepo.GetByUserID("user-1")
+	require.NoError(t, err)
+	urls := []string{}
+	for _, article := range articles {
+		urls = append(urls, article.URL)
+	}
+	assert.ElementsMatch(t, []string{"https://example.com/first", "https://example.com/second"}, urls)
+}
+
+func TestImportController_Errors(t *testing.T) {
+	api := newTestAPI(t)
+	require.NoError(t, api.categoryRepo.Create(&models.Category{ID: "other", UserID: "user-2", Name: "Other"}))
+
+	tests := []struct {
+		name     string
+		fileName string
+		content  string
+		fields   map[string]string
+		status   int
+		code     string
+	}{
+		{name: "no file", status: http.StatusBadRequest, code: "invalid_request"},
+		{name: "unknown format name", fileName: "links.txt", content: "https://example.com\n", fields: map[string]string{"format": "evernote"}, status: http.StatusBadRequest, code: "unknown_format"},
+		{name: "undetectable file", fileName: "export.csv", content: "a,b\n1,2\n", status: http.StatusBadRequest, code: "unknown_format"},
+		{name: "no links", fileName: "links.txt", content: "# nothing here\n", status: http.StatusBadRequest, code: "empty_import"},
+		{name: "category of another user", fileName: "links.txt", content: "https://example.com\n", fields: map[string]string{"categoryId": "other"}, status: http.StatusBadRequest, code: "invalid_category"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp ErrorResponse
+			rec := api.upload("user-1", tt.fileName, tt.content, tt.fields, &resp)
+			assert.Equal(t, tt.status, rec.Code, rec.Body.String())
+			assert.Equal(t, tt.code, resp.Error)
+		})
+	}
+
+	var started ImportResponse
+	rec := api.upload("user-1", "links.txt", "https://example.com\n", nil, &started)
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+
+	rec = api.do(http.MethodGet, "/api/v1/imports/"+started.Import.ID, "user-2", nil, nil)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	rec = api.do(http.MethodGet, "/api/v1/imports/missing", "user-1", nil, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = api.do(http.MethodGet, "/api/v1/imports", "", nil, nil)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func tagNames(tags []models.Tag) []string {
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
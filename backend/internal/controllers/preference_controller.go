@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/eikuma/stockle/backend/internal/models"
+	"github.com/eikuma/stockle/backend/internal/repositories"
+	"github.com/gin-gonic/gin"
+)
+
+type PreferenceController struct {
+	prefRepo repositories.PreferenceRepository
+}
+
+// UpdatePreferencesRequest changes the preferences that are set
+type UpdatePreferencesRequest struct {
+	Language           *string `json:"language" binding:"omitempty,min=2,max=10"`
+	Theme              *string `json:"theme" binding:"omitempty,max=10"`
+	NotificationsEmail *bool   `json:"notifications_email"`
+	NotificationsPush  *bool   `json:"notifications_push"`
+	AutoSummarize      *bool   `json:"auto_summarize"`
+	// SummaryLanguage is a language code, or "original" for the language of
+	// each article
+	SummaryLanguage *string `json:"summary_language" binding:"omitempty,min=2,max=10"`
+	SummaryLength   *string `json:"summary_length" binding:"omitempty,oneof=short medium long"`
+}
+
+// Preferences are the preferences of a user as the API returns them
+type Preferences struct {
+	Language           string `json:"language"`
+	Theme              string `json:"theme"`
+	NotificationsEmail bool   `json:"notifications_email"`
+	NotificationsPush  bool   `json:"notifications_push"`
+	AutoSummarize      bool   `json:"auto_summarize"`
+	SummaryLanguage    string `json:"summary_language"`
+	SummaryLength      string `json:"summary_length"`
+}
+
+type PreferencesResponse struct {
+	Message     string      `json:"message,omitempty"`
+	Preferences Preferences `json:"preferences"`
+}
+
+func NewPreferenceController(prefRepo repositories.PreferenceRepository) *PreferenceController {
+	return &PreferenceController{
+		prefRepo: prefRepo,
+	}
+}
+
+// GetPreferences retrieves the user's preferences, the defaults until the
+// user changes them
+// GET /api/v1/preferences
+func (c *PreferenceController) GetPreferences(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	preference, err := c.prefRepo.Get(userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "fetch_failed",
+			Message: "Failed to fetch preferences: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, PreferencesResponse{
+		Preferences: newPreferences(preference),
+	})
+}
+
+// UpdatePreferences changes the user's preferences
+// PATCH /api/v1/preferences
+func (c *PreferenceController) UpdatePreferences(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	var req UpdatePreferencesRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid JSON format: " + err.Error(),
+		})
+		return
+	}
+
+	preference, err := c.prefRepo.Get(userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "fetch_failed",
+			Message: "Failed to fetch preferences: " + err.Error(),
+		})
+		return
+	}
+
+	if req.Language != nil {
+		preference.Language = *req.Language
+	}
+	if req.Theme != nil {
+		preference.Theme = *req.Theme
+	}
+	if req.NotificationsEmail != nil {
+		preference.NotificationsEmail = *req.NotificationsEmail
+	}
+	if req.NotificationsPush != nil {
+		preference.NotificationsPush = *req.NotificationsPush
+	}
+	if req.AutoSummarize != nil {
+		preference.AutoSummarize = *req.AutoSummarize
+	}
+	if req.SummaryLanguage != nil {
+		preference.SummaryLanguage = *req.SummaryLanguage
+	}
+	if req.SummaryLength != nil {
+		preference.SummaryLength = *req.SummaryLength
+	}
+
+	if err := c.prefRepo.Save(preference); err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "update_failed",
+			Message: "Failed to update preferences: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, PreferencesResponse{
+		Message:     "Preferences updated successfully",
+		Preferences: newPreferences(preference),
+	})
+}
+
+func newPreferences(preference *models.UserPreference) Preferences {
+	return Preferences{
+		Language:           preference.Language,
+		Theme:              preference.Theme,
+		NotificationsEmail: preference.NotificationsEmail,
+		NotificationsPush:  preference.NotificationsPush,
+		AutoSummarize:      preference.AutoSummarize,
+		SummaryLanguage:    preference.SummaryLanguage,
+		SummaryLength:      preference.SummaryLength,
+	}
+}
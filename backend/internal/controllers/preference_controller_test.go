@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/eikuma/stockle/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreferenceController(t *testing.T) {
+	api := newTestAPI(t)
+
+	var resp PreferencesResponse
+	rec := api.do(http.MethodGet, "/api/v1/preferences", "user-1", nil, &resp)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.True(t, resp.Preferences.AutoSummarize)
+	assert.Equal(t, "ja", resp.Preferences.SummaryLanguage)
+	assert.Equal(t, models.SummaryTypeMedium, resp.Preferences.SummaryLength)
+
+	autoSummarize := false
+	length := models.SummaryTypeShort
+	language := models.SummaryLanguageOriginal
+	rec = api.do(http.MethodPatch, "/api/v1/preferences", "user-1", UpdatePreferencesRequest{
+		AutoSummarize:   &autoSummarize,
+		SummaryLength:   &length,
+		SummaryLanguage: &language,
+	}, &resp)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.False(t, resp.Preferences.AutoSummarize)
+	assert.Equal(t, models.SummaryTypeShort, resp.Preferences.SummaryLength)
+	assert.Equal(t, models.SummaryLanguageOriginal, resp.Preferences.SummaryLanguage)
+	assert.Equal(t, "light", resp.Preferences.Theme, "preferences not sent are kept")
+
+	rec = api.do(http.MethodGet, "/api/v1/preferences", "user-2", nil, &resp)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, resp.Preferences.AutoSummarize, "preferences are per user")
+
+	t.Run("articles are not summarized automatically", func(t *testing.T) {
+		server := newArticleServer(t)
+		var saved ArticleResponse
+		rec := api.do(http.MethodPost, "/api/v1/articles", "user-1", SaveArticleRequest{URL: server.URL + "/posts/1"}, &saved)
+		require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+		events, unsubscribe := api.events.Subscribe(saved.Article.ID)
+		defer unsubscribe()
+		api.runJobs()
+
+		article, err := api.articleRepo.GetByID(saved.Article.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.ExtractionStatusCompleted, article.ExtractionStatus)
+		assert.Equal(t, models.SummaryStatusSkipped, article.SummaryGenerationStatus)
+		assert.Nil(t, article.Summary)
+
+		// Clients waiting for the article stop once it is extracted
+		select {
+		case event := <-events:
+			assert.Equal(t, models.SummaryStatusSkipped, event.SummaryGenerationStatus)
+			assert.True(t, event.Done())
+		default:
+			t.Fatal("no event for the extracted article")
+		}
+
+		// The preferred length in the language of the article is generated
+		// on request
+		var resp GenerateSummariesResponse
+		rec = api.do(http.MethodPost, "/api/v1/articles/"+article.ID+"/summaries", "user-1", nil, &resp)
+		require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+		api.runJobs()
+		summary, err := api.summaryRepo.GetCurrent(article.ID, models.SummaryTypeShort, "en")
+		require.NoError(t, err)
+		assert.Equal(t, "short summary of Go Concurrency Patterns", summary.Content)
+		article, err = api.articleRepo.GetByID(article.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.SummaryStatusCompleted, article.SummaryGenerationStatus)
+	})
+
+	t.Run("invalid preferences are rejected", func(t *testing.T) {
+		for _, body := range []map[string]interface{}{
+			{"summary_length": "huge"},
+			{"summary_language": "x"},
+			{"auto_summarize": "yes"},
+		} {
+			rec := api.do(http.MethodPatch, "/api/v1/preferences", "user-1", body, nil)
+			assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+		}
+
+		rec := api.do(http.MethodGet, "/api/v1/preferences", "", nil, nil)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
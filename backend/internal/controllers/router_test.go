@@ -0,0 +1,281 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/eikuma/stockle/backend/internal/config"
+	"github.com/eikuma/stockle/backend/internal/middleware"
+	"github.com/eikuma/stockle/backend/internal/models"
+	"github.com/eikuma/stockle/backend/internal/services"
+	"github.com/eikuma/stockle/backend/internal/urlnorm"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+const testUserHeader = "X-Test-User"
+
+type testAPI struct {
+	t            *testing.T
+	router       *gin.Engine
+	store        *memoryStore
+	articleRepo  *fakeArticleRepository
+	categoryRepo *fakeCategoryRepository
+	collections  *fakeSmartCollectionRepository
+	tagRepo      *fakeTagRepository
+	userRepo     *fakeUserRepository
+	jobRepo      *fakeJobRepository
+	importRepo   *fakeImportRepository
+	exportRepo   *fakeExportRepository
+	feedRepo     *fakeFeedRepository
+	summaryRepo  *fakeArticleSummaryRepository
+	prefRepo     *fakePreferenceRepository
+	authService  *services.AuthService
+	jobService   *services.JobService
+	exports      *services.ExportService
+	feeds        *services.FeedService
+	search       *services.SearchService
+	events       *services.ArticleEventBroker
+}
+
+// newTestAPI wires the auth, article, category, collection, tag, import,
+// export, feed, summary and preference controllers onto the same routes as cmd/api, backed by in-memory
+// repositories. Requests with an Authorization header go through middleware.AuthRequired; otherwise the
+// user ID is taken from a test header.
+func newTestAPI(t *testing.T) *testAPI {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	store := newMemoryStore()
+	api := &testAPI{
+		t:            t,
+		store:        store,
+		articleRepo:  &fakeArticleRepository{store: store},
+		categoryRepo: &fakeCategoryRepository{store: store},
+		collections:  &fakeSmartCollectionRepository{store: store},
+		tagRepo:      &fakeTagRepository{store: store},
+		userRepo:     newFakeUserRepository(store),
+		jobRepo:      &fakeJobRepository{},
+		importRepo:   newFakeImportRepository(),
+		exportRepo:   newFakeExportRepository(),
+		feedRepo:     &fakeFeedRepository{store: store},
+		summaryRepo:  &fakeArticleSummaryRepository{store: store},
+		prefRepo:     &fakePreferenceRepository{store: store},
+	}
+	api.authService = services.NewAuthService(api.userRepo, &config.JWTConfig{
+		AccessSecret:  "test-access-secret",
+		RefreshSecret: "test-refresh-secret",
+		AccessExpiry:  15 * time.Minute,
+		RefreshExpiry: time.Hour,
+		Issuer:        "stockle-test",
+	})
+
+	events := services.NewArticleEventBroker()
+	api.events = events
+	urls := urlnorm.New()
+	api.search = services.NewSearchService(newFakeSearchIndex(), api.articleRepo)
+	scraper := services.NewScraperService(&config.ScraperConfig{}, nil)
+	api.jobService = services.NewJobService(api.jobRepo, api.articleRepo, api.summaryRepo, api.prefRepo, fakeSummarizer{}, scraper, urls, api.search, events)
+	importService := services.NewImportService(api.importRepo, api.articleRepo, api.categoryRepo, api.tagRepo, api.jobService, urls, api.search)
+	api.jobService.Handle(models.JobTypeImportArticles, importService)
+	api.exports = services.NewExportService(api.exportRepo, api.articleRepo, api.categoryRepo, api.jobService, &config.ExportsConfig{
+		Dir:        t.TempDir(),
+		Retention:  24 * time.Hour,
+		LinkExpiry: time.Hour,
+		SigningKey: "test-export-key",
+	})
+	api.jobService.Handle(models.JobTypeExportArticles, api.exports)
+	api.feeds = services.NewFeedService(api.feedRepo, api.articleRepo, api.categoryRepo, api.tagRepo, api.jobService, scraper, urls, api.search, &config.FeedsConfig{
+		PollInterval: 30 * time.Minute,
+		MaxBackoff:   24 * time.Hour,
+	})
+	api.jobService.Handle(models.JobTypePollFeed, api.feeds)
+
+	articleController := NewArticleController(api.articleRepo, api.categoryRepo, api.tagRepo, api.jobService, api.search, events, urls)
+	categoryController := NewCategoryController(api.categoryRepo)
+	collectionController := NewSmartCollectionController(api.collections, api.articleRepo)
+	tagController := NewTagController(api.tagRepo, api.articleRepo, api.search)
+	importController := NewImportController(api.importRepo, api.categoryRepo, importService)
+	exportController := NewExportController(api.exportRepo, api.exports)
+	feedController := NewFeedController(api.feedRepo, api.categoryRepo, api.feeds)
+	summaryController := NewSummaryController(api.articleRepo, api.summaryRepo, api.prefRepo, api.jobService)
+	preferenceController := NewPreferenceController(api.prefRepo)
+	authController := NewAuthController(api.authService)
+
+	router := gin.New()
+	auth := router.Group("/api/v1/auth")
+	auth.POST("/register", authController.Register)
+	auth.POST("/login", authController.Login)
+	router.GET("/api/v1/exports/:id/download", exportController.DownloadExport)
+
+	authRequired := middleware.AuthRequired(api.authService)
+	protected := router.Group("/api/v1")
+	protected.Use(func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "" {
+			authRequired(c)
+			return
+		}
+		if userID := c.GetHeader(testUserHeader); userID != "" {
+			c.Set("user_id", userID)
+		}
+		c.Next()
+	})
+	{
+		articles := protected.Group("/articles")
+		articles.POST("", articleController.SaveArticle)
+		articles.POST("/bulk", articleController.BulkUpdateArticles)
+		articles.GET("", articleController.GetArticles)
+		articles.GET("/search", articleController.SearchArticles)
+		articles.GET("/favorites", articleController.GetFavorites)
+		articles.GET("/recent", articleController.GetRecentlyRead)
+		articles.GET("/:id", articleController.GetArticle)
+		articles.GET("/:id/events", articleController.StreamArticleEvents)
+		articles.PATCH("/:id", articleController.UpdateArticle)
+		articles.DELETE("/:id", articleController.DeleteArticle)
+		articles.POST("/:id/tags", articleController.AddArticleTags)
+		articles.PUT("/:id/tags", articleController.ReplaceArticleTags)
+		articles.DELETE("/:id/tags/:tagId", articleController.RemoveArticleTag)
+		articles.GET("/:id/summaries", summaryController.GetSummaries)
+		articles.POST("/:id/summaries", summaryController.GenerateSummaries)
+
+		protected.GET("/preferences", preferenceController.GetPreferences)
+		protected.PATCH("/preferences", preferenceController.UpdatePreferences)
+
+		categories := protected.Group("/categories")
+		categories.GET("", categoryController.GetCategories)
+		categories.POST("", categoryController.CreateCategory)
+		categories.PUT("/reorder", categoryController.ReorderCategories)
+		categories.PUT("/:id", categoryController.UpdateCategory)
+		categories.POST("/:id/move", categoryController.MoveCategory)
+		categories.DELETE("/:id", categoryController.DeleteCategory)
+
+		collections := protected.Group("/collections")
+		collections.GET("", collectionController.GetCollections)
+		collections.POST("", collectionController.CreateCollection)
+		collections.PUT("/reorder", collectionController.ReorderCollections)
+		collections.PUT("/:id", collectionController.UpdateCollection)
+		collections.DELETE("/:id", collectionController.DeleteCollection)
+		collections.GET("/:id/articles", collectionController.GetCollectionArticles)
+
+		tags := protected.Group("/tags")
+		tags.GET("", tagController.GetTags)
+		tags.DELETE("/unused", tagController.DeleteUnusedTags)
+		tags.PATCH("/:id", tagController.RenameTag)
+		tags.POST("/:id/merge", tagController.MergeTag)
+		tags.DELETE("/:id", tagController.DeleteTag)
+
+		imports := protected.Group("/imports")
+		imports.POST("", importController.CreateImport)
+		imports.GET("", importController.GetImports)
+		imports.GET("/:id", importController.GetImport)
+
+		exports := protected.Group("/exports")
+		exports.POST("", exportController.CreateExport)
+		exports.GET("", exportController.GetExports)
+		exports.GET("/:id", exportController.GetExport)
+
+		feeds := protected.Group("/feeds")
+		feeds.POST("", feedController.CreateFeed)
+		feeds.GET("", feedController.GetFeeds)
+		feeds.POST("/import", feedController.ImportFeeds)
+		feeds.GET("/export", feedController.ExportFeeds)
+		feeds.GET("/:id", feedController.GetFeed)
+		feeds.PATCH("/:id", feedController.UpdateFeed)
+		feeds.DELETE("/:id", feedController.DeleteFeed)
+		feeds.POST("/:id/refresh", feedController.RefreshFeed)
+	}
+
+	api.router = router
+	return api
+}
+
+// runJobs processes queued background jobs until the queue is empty
+func (a *testAPI) runJobs() {
+	a.t.Helper()
+
+	for {
+		processed, err := a.jobService.ProcessNext(context.Background())
+		require.NoError(a.t, err)
+		if !processed {
+			return
+		}
+	}
+}
+
+// do performs a request as userID and decodes the JSON response into out when non-nil
+func (a *testAPI) do(method, path, userID string, body interface{}, out interface{}) *httptest.ResponseRecorder {
+	a.t.Helper()
+
+	var header http.Header
+	if userID != "" {
+		header = http.Header{testUserHeader: []string{userID}}
+	}
+	return a.doWithHeader(method, path, header, body, out)
+}
+
+// doWithToken performs a request authenticated with a bearer access token
+func (a *testAPI) doWithToken(method, path, token string, body interface{}, out interface{}) *httptest.ResponseRecorder {
+	a.t.Helper()
+
+	return a.doWithHeader(method, path, http.Header{"Authorization": []string{"Bearer " + token}}, body, out)
+}
+
+func (a *testAPI) doWithHeader(
+	method, path string,
+	header http.Header,
+	body interface{},
+	out interface{},
+) *httptest.ResponseRecorder {
+	a.t.Helper()
+
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		require.NoError(a.t, err)
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	rec := httptest.NewRecorder()
+	a.router.ServeHTTP(rec, req)
+
+	if out != nil {
+		require.NoError(a.t, json.Unmarshal(rec.Body.Bytes(), out), rec.Body.String())
+	}
+	return rec
+}
+
+// newArticleServer serves a small article page for the scraper to extract
+func newArticleServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(`<!DOCTYPE html>
+<html lang="en">
+<head>
+	<title>Go Concurrency Patterns</title>
+	<meta property="og:site_name" content="Test Blog">
+	<meta name="author" content="Gopher">
+	<meta property="og:locale" content="en_US">
+</head>
+<body>
+	<article>Goroutines and channels make concurrent programs easy to write.</article>
+</body>
+</html>`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
@@ -0,0 +1,402 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/eikuma/stockle/backend/internal/articlequery"
+	"github.com/eikuma/stockle/backend/internal/models"
+	"github.com/eikuma/stockle/backend/internal/repositories"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type SmartCollectionController struct {
+	collectionRepo repositories.SmartCollectionRepository
+	articleRepo    repositories.ArticleRepository
+}
+
+type CreateSmartCollectionRequest struct {
+	Name  string `json:"name" binding:"required,max=100"`
+	Query string `json:"query" binding:"required"`
+	Color string `json:"color,omitempty" binding:"omitempty,hexcolor,len=7"`
+}
+
+type UpdateSmartCollectionRequest struct {
+	Name         *string `json:"name,omitempty" binding:"omitempty,max=100"`
+	Query        *string `json:"query,omitempty"`
+	Color        *string `json:"color,omitempty" binding:"omitempty,hexcolor,len=7"`
+	DisplayOrder *int    `json:"displayOrder,omitempty" binding:"omitempty,min=0"`
+}
+
+type ReorderSmartCollectionsRequest struct {
+	CollectionIDs []string `json:"collectionIds" binding:"required,min=1,unique"`
+}
+
+type SmartCollectionResponse struct {
+	Message    string                  `json:"message"`
+	Collection *models.SmartCollection `json:"collection,omitempty"`
+}
+
+type SmartCollectionListResponse struct {
+	Collections []*models.SmartCollection `json:"collections"`
+}
+
+func NewSmartCollectionController(
+	collectionRepo repositories.SmartCollectionRepository,
+	articleRepo repositories.ArticleRepository,
+) *SmartCollectionController {
+	return &SmartCollectionController{
+		collectionRepo: collectionRepo,
+		articleRepo:    articleRepo,
+	}
+}
+
+// GetCollections retrieves the user's smart collections with article counts
+// GET /api/v1/collections
+func (c *SmartCollectionController) GetCollections(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	collections, err := c.collectionRepo.GetByUserIDWithCounts(userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "fetch_failed",
+			Message: "Failed to fetch collections: " + err.Error(),
+		})
+		return
+	}
+
+	if collections == nil {
+		collections = []*models.SmartCollection{}
+	}
+
+	ctx.JSON(http.StatusOK, SmartCollectionListResponse{
+		Collections: collections,
+	})
+}
+
+// CreateCollection saves a search as a new smart collection
+// POST /api/v1/collections
+func (c *SmartCollectionController) CreateCollection(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	var req CreateSmartCollectionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid JSON format: " + err.Error(),
+		})
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Collection name is required",
+		})
+		return
+	}
+
+	query, ok := parseCollectionQuery(ctx, req.Query)
+	if !ok {
+		return
+	}
+
+	color := req.Color
+	if color == "" {
+		color = "#6B7280"
+	}
+
+	// New collections are appended after the existing ones
+	existing, err := c.collectionRepo.GetByUserID(userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "fetch_failed",
+			Message: "Failed to fetch collections: " + err.Error(),
+		})
+		return
+	}
+
+	displayOrder := 0
+	for _, collection := range existing {
+		if collection.DisplayOrder >= displayOrder {
+			displayOrder = collection.DisplayOrder + 1
+		}
+	}
+
+	collection := &models.SmartCollection{
+		ID:           uuid.New().String(),
+		UserID:       userID,
+		Name:         name,
+		Query:        query.String(),
+		Color:        color,
+		DisplayOrder: displayOrder,
+	}
+
+	if err := c.collectionRepo.Create(collection); err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "create_failed",
+			Message: "Failed to create collection: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, SmartCollectionResponse{
+		Message:    "Collection created successfully",
+		Collection: collection,
+	})
+}
+
+// UpdateCollection updates name, query, color or display order of a collection
+// PUT /api/v1/collections/:id
+func (c *SmartCollectionController) UpdateCollection(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	var req UpdateSmartCollectionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid JSON format: " + err.Error(),
+		})
+		return
+	}
+
+	collection, ok := c.getOwnedCollection(ctx, userID)
+	if !ok {
+		return
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "invalid_request",
+				Message: "Collection name must not be empty",
+			})
+			return
+		}
+		collection.Name = name
+	}
+
+	if req.Query != nil {
+		query, ok := parseCollectionQuery(ctx, *req.Query)
+		if !ok {
+			return
+		}
+		collection.Query = query.String()
+	}
+
+	if req.Color != nil {
+		collection.Color = *req.Color
+	}
+
+	if req.DisplayOrder != nil {
+		collection.DisplayOrder = *req.DisplayOrder
+	}
+
+	if err := c.collectionRepo.Update(collection); err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "update_failed",
+			Message: "Failed to update collection: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, SmartCollectionResponse{
+		Message:    "Collection updated successfully",
+		Collection: collection,
+	})
+}
+
+// DeleteCollection deletes a collection; its articles are not touched
+// DELETE /api/v1/collections/:id
+func (c *SmartCollectionController) DeleteCollection(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	collection, ok := c.getOwnedCollection(ctx, userID)
+	if !ok {
+		return
+	}
+
+	if err := c.collectionRepo.Delete(collection.ID, userID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "delete_failed",
+			Message: "Failed to delete collection: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, SmartCollectionResponse{
+		Message: "Collection deleted successfully",
+	})
+}
+
+// ReorderCollections sets DisplayOrder according to the given ID order
+// PUT /api/v1/collections/reorder
+func (c *SmartCollectionController) ReorderCollections(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	var req ReorderSmartCollectionsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid JSON format: " + err.Error(),
+		})
+		return
+	}
+
+	if err := c.collectionRepo.Reorder(userID, req.CollectionIDs); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "invalid_request",
+				Message: "One or more collections were not found",
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "update_failed",
+			Message: "Failed to reorder collections: " + err.Error(),
+		})
+		return
+	}
+
+	collections, err := c.collectionRepo.GetByUserID(userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "fetch_failed",
+			Message: "Failed to fetch collections: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, SmartCollectionListResponse{
+		Collections: collections,
+	})
+}
+
+// GetCollectionArticles lists the articles matching a collection, paginated
+// like GET /api/v1/articles
+// GET /api/v1/collections/:id/articles
+func (c *SmartCollectionController) GetCollectionArticles(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	collection, ok := c.getOwnedCollection(ctx, userID)
+	if !ok {
+		return
+	}
+
+	query, err := articlequery.Parse(collection.Query)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "fetch_failed",
+			Message: "Stored collection query is invalid: " + err.Error(),
+		})
+		return
+	}
+
+	filters := repositories.ArticleFilters{Query: query}
+	if !parseListOptions(ctx, &filters, repositories.SortSavedAt) {
+		return
+	}
+
+	result, err := c.articleRepo.GetByUserIDWithFilters(userID, filters)
+	if err != nil {
+		respondListError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, newArticleListResponse(result, filters))
+}
+
+// parseCollectionQuery parses the query of a collection, which unlike the
+// q parameter of the article list must not be empty
+func parseCollectionQuery(ctx *gin.Context, input string) (*articlequery.Query, bool) {
+	query, ok := parseArticleQuery(ctx, input)
+	if !ok {
+		return nil, false
+	}
+	if query.Root == nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Collection query must not be empty",
+		})
+		return nil, false
+	}
+	return query, true
+}
+
+// getOwnedCollection loads the collection in the :id path parameter and
+// writes the error response itself when it is missing or owned by someone else
+func (c *SmartCollectionController) getOwnedCollection(ctx *gin.Context, userID string) (*models.SmartCollection, bool) {
+	collectionID := ctx.Param("id")
+	if collectionID == "" {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Collection ID is required",
+		})
+		return nil, false
+	}
+
+	collection, err := c.collectionRepo.GetByID(collectionID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, ErrorResponse{
+			Error:   "not_found",
+			Message: "Collection not found",
+		})
+		return nil, false
+	}
+
+	if collection.UserID != userID {
+		ctx.JSON(http.StatusForbidden, ErrorResponse{
+			Error:   "forbidden",
+			Message: "Access denied",
+		})
+		return nil, false
+	}
+
+	return collection, true
+}
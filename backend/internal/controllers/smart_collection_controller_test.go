@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/eikuma/stockle/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSmartCollectionController_CRUD(t *testing.T) {
+	api := newTestAPI(t)
+	seedArticle(t, api, "user-1", "golang")
+	favorite := seedArticle(t, api, "user-1", "rust")
+	require.NoError(t, api.articleRepo.UpdateFavorite(favorite.ID, "user-1", true))
+	seedArticle(t, api, "user-2", "golang")
+
+	var created SmartCollectionResponse
+	rec := api.do(http.MethodPost, "/api/v1/collections", "user-1", CreateSmartCollectionRequest{
+		Name:  "Go to read",
+		Query: "golang   is:UNREAD",
+	}, &created)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	require.NotNil(t, created.Collection)
+	assert.Equal(t, "golang is:unread", created.Collection.Query, "queries are stored normalized")
+	assert.Equal(t, "#6B7280", created.Collection.Color)
+
+	rec = api.do(http.MethodPost, "/api/v1/collections", "user-1", CreateSmartCollectionRequest{
+		Name:  "Favorites",
+		Query: "is:favorite",
+	}, nil)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var list SmartCollectionListResponse
+	rec = api.do(http.MethodGet, "/api/v1/collections", "user-1", nil, &list)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Len(t, list.Collections, 2)
+	assert.Equal(t, created.Collection.ID, list.Collections[0].ID)
+	assert.Equal(t, 1, list.Collections[0].ArticleCount)
+	assert.Equal(t, 1, list.Collections[1].DisplayOrder)
+
+	var articles ArticleListResponse
+	rec = api.do(http.MethodGet, "/api/v1/collections/"+created.Collection.ID+"/articles?limit=10", "user-1", nil, &articles)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Len(t, articles.Articles, 1)
+	assert.Equal(t, "golang", articles.Articles[0].Title)
+	assert.Equal(t, 10, articles.Limit)
+
+	// Counts and listings follow the articles as they change
+	seedArticle(t, api, "user-1", "golang-generics")
+	rec = api.do(http.MethodGet, "/api/v1/collections", "user-1", nil, &list)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 2, list.Collections[0].ArticleCount)
+
+	query := "rust OR golang"
+	name := "Systems"
+	var updated SmartCollectionResponse
+	rec = api.do(http.MethodPut, "/api/v1/collections/"+created.Collection.ID, "user-1", UpdateSmartCollectionRequest{
+		Name:  &name,
+		Query: &query,
+	}, &updated)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "Systems", updated.Collection.Name)
+	assert.Equal(t, "rust OR golang", updated.Collection.Query)
+
+	rec = api.do(http.MethodGet, "/api/v1/collections/"+created.Collection.ID+"/articles", "user-1", nil, &articles)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NotNil(t, articles.Total)
+	assert.Equal(t, int64(3), *articles.Total)
+
+	rec = api.do(http.MethodDelete, "/api/v1/collections/"+created.Collection.ID, "user-1", nil, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	rec = api.do(http.MethodGet, "/api/v1/collections/"+created.Collection.ID+"/articles", "user-1", nil, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// Deleting a collection keeps its articles
+	rec = api.do(http.MethodGet, "/api/v1/articles", "user-1", nil, &articles)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NotNil(t, articles.Total)
+	assert.Equal(t, int64(3), *articles.Total)
+}
+
+func TestSmartCollectionController_Validation(t *testing.T) {
+	api := newTestAPI(t)
+	foreign := &models.SmartCollection{ID: "foreign", UserID: "user-2", Name: "Other", Query: "tag:go"}
+	require.NoError(t, api.collections.Create(foreign))
+
+	rec := api.do(http.MethodPost, "/api/v1/collections", "user-1", CreateSmartCollectionRequest{Name: "x"}, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = api.do(http.MethodPost, "/api/v1/collections", "user-1", CreateSmartCollectionRequest{Name: "x", Query: "  "}, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	var queryErr QueryErrorResponse
+	rec = api.do(http.MethodPost, "/api/v1/collections", "user-1", CreateSmartCollectionRequest{
+		Name:  "x",
+		Query: "tag:go OR",
+	}, &queryErr)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "invalid_query", queryErr.Error)
+	assert.Equal(t, 7, queryErr.Position)
+
+	name := "mine"
+	rec = api.do(http.MethodPut, "/api/v1/collections/"+foreign.ID, "user-1", UpdateSmartCollectionRequest{Name: &name}, nil)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = api.do(http.MethodGet, "/api/v1/collections/"+foreign.ID+"/articles", "user-1", nil, nil)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = api.do(http.MethodDelete, "/api/v1/collections/missing", "user-1", nil, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestSmartCollectionController_ReorderCollections(t *testing.T) {
+	api := newTestAPI(t)
+	for _, id := range []string{"a", "b", "c"} {
+		require.NoError(t, api.collections.Create(&models.SmartCollection{ID: id, UserID: "user-1", Name: id, Query: "tag:" + id}))
+	}
+	require.NoError(t, api.collections.Create(&models.SmartCollection{ID: "z", UserID: "user-2", Name: "z", Query: "tag:z"}))
+
+	var list SmartCollectionListResponse
+	rec := api.do(http.MethodPut, "/api/v1/collections/reorder", "user-1", ReorderSmartCollectionsRequest{
+		CollectionIDs: []string{"c", "a", "b"},
+	}, &list)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Len(t, list.Collections, 3)
+	assert.Equal(t, "c", list.Collections[0].ID)
+	assert.Equal(t, "a", list.Collections[1].ID)
+	assert.Equal(t, "b", list.Collections[2].ID)
+
+	rec = api.do(http.MethodPut, "/api/v1/collections/reorder", "user-1", ReorderSmartCollectionsRequest{
+		CollectionIDs: []string{"a", "z"},
+	}, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,248 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/eikuma/stockle/backend/internal/models"
+	"github.com/eikuma/stockle/backend/internal/repositories"
+	"github.com/eikuma/stockle/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// allSummaryTypes requests every summary type at once
+const allSummaryTypes = "all"
+
+type SummaryController struct {
+	articleRepo repositories.ArticleRepository
+	summaryRepo repositories.ArticleSummaryRepository
+	prefRepo    repositories.PreferenceRepository
+	jobService  *services.JobService
+}
+
+type SummaryListResponse struct {
+	Summaries []*models.ArticleSummary `json:"summaries"`
+}
+
+type GenerateSummariesResponse struct {
+	Message string `json:"message"`
+	// Summaries are the requested summaries that already exist
+	Summaries []*models.ArticleSummary `json:"summaries"`
+	// Queued are the summary types being generated
+	Queued []string `json:"queued"`
+}
+
+func NewSummaryController(
+	articleRepo repositories.ArticleRepository,
+	summaryRepo repositories.ArticleSummaryRepository,
+	prefRepo repositories.PreferenceRepository,
+	jobService *services.JobService,
+) *SummaryController {
+	return &SummaryController{
+		articleRepo: articleRepo,
+		summaryRepo: summaryRepo,
+		prefRepo:    prefRepo,
+		jobService:  jobService,
+	}
+}
+
+// GetSummaries retrieves every summary generated for an article, the newest
+// first, optionally of one type or language
+// GET /api/v1/articles/:id/summaries
+func (c *SummaryController) GetSummaries(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	article, ok := c.getOwnedArticle(ctx, userID)
+	if !ok {
+		return
+	}
+
+	summaries, err := c.summaryRepo.GetByArticleID(article.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "fetch_failed",
+			Message: "Failed to fetch summaries: " + err.Error(),
+		})
+		return
+	}
+
+	filtered := []*models.ArticleSummary{}
+	for _, summary := range summaries {
+		if t := ctx.Query("type"); t != "" && summary.SummaryType != t {
+			continue
+		}
+		if language := ctx.Query("language"); language != "" && summary.Language != language {
+			continue
+		}
+		filtered = append(filtered, summary)
+	}
+
+	ctx.JSON(http.StatusOK, SummaryListResponse{
+		Summaries: filtered,
+	})
+}
+
+// GenerateSummaries generates the summaries of an article missing in the
+// requested type and language, by default those the user prefers. With
+// regenerate, existing summaries are generated again and kept as history;
+// provider picks the AI provider that writes them.
+// POST /api/v1/articles/:id/summaries
+func (c *SummaryController) GenerateSummaries(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	regenerate := false
+	if value := ctx.Query("regenerate"); value != "" {
+		var err error
+		if regenerate, err = strconv.ParseBool(value); err != nil {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "invalid_request",
+				Message: "regenerate must be a boolean",
+			})
+			return
+		}
+	}
+
+	provider := ctx.Query("provider")
+	if provider != "" && !c.jobService.HasSummaryProvider(provider) {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Unknown summary provider: " + provider,
+		})
+		return
+	}
+
+	language := ctx.Query("language")
+	if len(language) > 10 {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "language must be at most 10 characters",
+		})
+		return
+	}
+
+	article, ok := c.getOwnedArticle(ctx, userID)
+	if !ok {
+		return
+	}
+	if article.Content == nil || *article.Content == "" {
+		ctx.JSON(http.StatusConflict, ErrorResponse{
+			Error:   "no_content",
+			Message: "The article has no extracted content to summarize",
+		})
+		return
+	}
+
+	preference, err := c.prefRepo.Get(userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "fetch_failed",
+			Message: "Failed to fetch preferences: " + err.Error(),
+		})
+		return
+	}
+
+	var summaryTypes []string
+	switch t := ctx.Query("type"); {
+	case t == "":
+		summaryTypes = []string{preference.SummaryLength}
+	case t == allSummaryTypes:
+		summaryTypes = models.SummaryTypes
+	case models.IsSummaryType(t):
+		summaryTypes = []string{t}
+	default:
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "type must be short, medium, long or all",
+		})
+		return
+	}
+
+	if language == "" {
+		language = preference.SummaryLanguage
+	}
+	if language == "" || language == models.SummaryLanguageOriginal {
+		language = article.Language
+	}
+
+	response := GenerateSummariesResponse{
+		Summaries: []*models.ArticleSummary{},
+		Queued:    []string{},
+	}
+	for _, summaryType := range summaryTypes {
+		if !regenerate {
+			summary, err := c.summaryRepo.GetCurrent(article.ID, summaryType, language)
+			if err == nil {
+				response.Summaries = append(response.Summaries, summary)
+				continue
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+					Error:   "fetch_failed",
+					Message: "Failed to fetch summaries: " + err.Error(),
+				})
+				return
+			}
+		}
+
+		err := c.jobService.EnqueueSummary(article.ID, models.JobPriorityHigh, services.SummaryOptions{
+			Type:       summaryType,
+			Language:   language,
+			Provider:   provider,
+			Regenerate: regenerate,
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+				Error:   "queue_failed",
+				Message: "Failed to queue summary generation: " + err.Error(),
+			})
+			return
+		}
+		response.Queued = append(response.Queued, summaryType)
+	}
+
+	if len(response.Queued) == 0 {
+		response.Message = "Summaries already generated"
+		ctx.JSON(http.StatusOK, response)
+		return
+	}
+	response.Message = "Summary generation queued"
+	ctx.JSON(http.StatusAccepted, response)
+}
+
+// getOwnedArticle fetches the article of the request, responding with an
+// error unless it belongs to the user
+func (c *SummaryController) getOwnedArticle(ctx *gin.Context, userID string) (*models.Article, bool) {
+	article, err := c.articleRepo.GetByID(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, ErrorResponse{
+			Error:   "not_found",
+			Message: "Article not found",
+		})
+		return nil, false
+	}
+
+	if article.UserID != userID {
+		ctx.JSON(http.StatusForbidden, ErrorResponse{
+			Error:   "forbidden",
+			Message: "Access denied",
+		})
+		return nil, false
+	}
+	return article, true
+}
@@ -0,0 +1,175 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/eikuma/stockle/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummaryController_GenerateSummaries(t *testing.T) {
+	api := newTestAPI(t)
+	article := seedArticle(t, api, "user-1", "golang")
+	article.Language = "en"
+	require.NoError(t, api.articleRepo.UpdateExtraction(article))
+
+	// An English article is summarized in Japanese, the default summary
+	// language
+	var resp GenerateSummariesResponse
+	rec := api.do(http.MethodPost, "/api/v1/articles/"+article.ID+"/summaries?type=long", "user-1", nil, &resp)
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+	assert.Equal(t, []string{models.SummaryTypeLong}, resp.Queued)
+	assert.Empty(t, resp.Summaries)
+
+	api.runJobs()
+	stored, err := api.articleRepo.GetByID(article.ID)
+	require.NoError(t, err)
+	require.NotNil(t, stored.SummaryLong)
+	assert.Equal(t, "long summary of golang", *stored.SummaryLong)
+	assert.Nil(t, stored.Summary)
+	assert.Equal(t, models.SummaryStatusCompleted, stored.SummaryGenerationStatus)
+	require.NotNil(t, stored.SummaryConfidence)
+	assert.InDelta(t, 0.9, *stored.SummaryConfidence, 0.001)
+
+	t.Run("existing summaries are returned", func(t *testing.T) {
+		var resp GenerateSummariesResponse
+		rec := api.do(http.MethodPost, "/api/v1/articles/"+article.ID+"/summaries?type=long", "user-1", nil, &resp)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.Empty(t, resp.Queued)
+		require.Len(t, resp.Summaries, 1)
+		assert.Equal(t, "long summary of golang", resp.Summaries[0].Content)
+		assert.Equal(t, "ja", resp.Summaries[0].Language)
+		assert.InDelta(t, 0.9, resp.Summaries[0].Confidence, 0.001)
+		assert.True(t, resp.Summaries[0].IsCurrent)
+	})
+
+	t.Run("missing lengths are generated", func(t *testing.T) {
+		var resp GenerateSummariesResponse
+		rec := api.do(http.MethodPost, "/api/v1/articles/"+article.ID+"/summaries?type=all", "user-1", nil, &resp)
+		require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+		assert.Equal(t, []string{models.SummaryTypeShort, models.SummaryTypeMedium}, resp.Queued)
+		require.Len(t, resp.Summaries, 1)
+
+		api.runJobs()
+		stored, err := api.articleRepo.GetByID(article.ID)
+		require.NoError(t, err)
+		require.NotNil(t, stored.SummaryShort)
+		assert.Equal(t, "short summary of golang", *stored.SummaryShort)
+		require.NotNil(t, stored.Summary)
+		assert.Equal(t, "Summary of golang", *stored.Summary)
+	})
+
+	t.Run("summaries in the language of the article", func(t *testing.T) {
+		var resp GenerateSummariesResponse
+		rec := api.do(http.MethodPost, "/api/v1/articles/"+article.ID+"/summaries?type=short&language=original", "user-1", nil, &resp)
+		require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+		api.runJobs()
+
+		summary, err := api.summaryRepo.GetCurrent(article.ID, models.SummaryTypeShort, "en")
+		require.NoError(t, err)
+		assert.Equal(t, "short summary of golang", summary.Content)
+	})
+
+	t.Run("summaries are regenerated with another provider", func(t *testing.T) {
+		var resp GenerateSummariesResponse
+		rec := api.do(http.MethodPost, "/api/v1/articles/"+article.ID+"/summaries?type=long&regenerate=true&provider=other", "user-1", nil, &resp)
+		require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+		assert.Equal(t, []string{models.SummaryTypeLong}, resp.Queued)
+		api.runJobs()
+
+		var list SummaryListResponse
+		rec = api.do(http.MethodGet, "/api/v1/articles/"+article.ID+"/summaries?type=long", "user-1", nil, &list)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		require.Len(t, list.Summaries, 2)
+		assert.Equal(t, "other", list.Summaries[0].Provider)
+		assert.Equal(t, "other-1", list.Summaries[0].ModelVersion)
+		assert.True(t, list.Summaries[0].IsCurrent)
+		assert.Equal(t, "fake", list.Summaries[1].Provider)
+		assert.False(t, list.Summaries[1].IsCurrent)
+
+		stored, err := api.articleRepo.GetByID(article.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "other-1", *stored.SummaryModelVersion)
+
+		rec = api.do(http.MethodGet, "/api/v1/articles/"+article.ID+"/summaries", "user-1", nil, &list)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Len(t, list.Summaries, 5)
+	})
+
+	t.Run("preferred length by default", func(t *testing.T) {
+		other := seedArticle(t, api, "user-1", "rust")
+		var resp GenerateSummariesResponse
+		rec := api.do(http.MethodPost, "/api/v1/articles/"+other.ID+"/summaries", "user-1", nil, &resp)
+		require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+		assert.Equal(t, []string{models.SummaryTypeMedium}, resp.Queued)
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		for _, query := range []string{"type=huge", "provider=unknown", "regenerate=maybe"} {
+			rec := api.do(http.MethodPost, "/api/v1/articles/"+article.ID+"/summaries?"+query, "user-1", nil, nil)
+			assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+		}
+
+		rec := api.do(http.MethodPost, "/api/v1/articles/"+article.ID+"/summaries", "user-2", nil, nil)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		rec = api.do(http.MethodGet, "/api/v1/articles/"+article.ID+"/summaries", "user-2", nil, nil)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		rec = api.do(http.MethodPost, "/api/v1/articles/unknown/summaries", "user-1", nil, nil)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		rec = api.do(http.MethodPost, "/api/v1/articles/"+article.ID+"/summaries", "", nil, nil)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("summaries are deleted with the article", func(t *testing.T) {
+		rec := api.do(http.MethodDelete, "/api/v1/articles/"+article.ID, "user-1", nil, nil)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		summaries, err := api.summaryRepo.GetByArticleID(article.ID)
+		require.NoError(t, err)
+		assert.Empty(t, summaries)
+	})
+}
+
+func TestSummaryController_GenerateSummariesWithoutContent(t *testing.T) {
+	api := newTestAPI(t)
+	summarized := seedArticle(t, api, "user-1", "golang")
+	empty := seedArticle(t, api, "user-1", "rust")
+
+	rec := api.do(http.MethodPost, "/api/v1/articles/"+summarized.ID+"/summaries?type=short", "user-1", nil, nil)
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+	api.runJobs()
+
+	// The content of both articles is gone by the time their summaries run
+	for _, article := range []*models.Article{summarized, empty} {
+		rec := api.do(http.MethodPost, "/api/v1/articles/"+article.ID+"/summaries?type=medium", "user-1", nil, nil)
+		require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+
+		stored, err := api.articleRepo.GetByID(article.ID)
+		require.NoError(t, err)
+		stored.Content = nil
+		require.NoError(t, api.articleRepo.UpdateExtraction(stored))
+	}
+	api.runJobs()
+
+	// The jobs fail at once instead of being retried
+	api.jobRepo.mu.Lock()
+	for _, job := range api.jobRepo.jobs {
+		if job.JobType == models.JobTypeSummarize && job.Status != models.JobStatusCompleted {
+			assert.Equal(t, models.JobStatusFailed, job.Status)
+			assert.Equal(t, 1, job.RetryCount)
+		}
+	}
+	api.jobRepo.mu.Unlock()
+
+	// The short summary of the first article still stands
+	stored, err := api.articleRepo.GetByID(summarized.ID)
+	require.NoError(t, err)
+	assert.Nil(t, stored.Summary)
+	require.NotNil(t, stored.SummaryShort)
+	assert.Equal(t, models.SummaryStatusCompleted, stored.SummaryGenerationStatus)
+
+	stored, err = api.articleRepo.GetByID(empty.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.SummaryStatusFailed, stored.SummaryGenerationStatus)
+}
@@ -0,0 +1,346 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/eikuma/stockle/backend/internal/models"
+	"github.com/eikuma/stockle/backend/internal/repositories"
+	"github.com/eikuma/stockle/backend/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type TagController struct {
+	tagRepo     repositories.TagRepository
+	articleRepo repositories.ArticleRepository
+	search      *services.SearchService
+}
+
+type RenameTagRequest struct {
+	Name string `json:"name" binding:"required,max=50"`
+}
+
+type MergeTagRequest struct {
+	TargetID string `json:"targetId" binding:"required"`
+}
+
+type TagResponse struct {
+	Message string      `json:"message"`
+	Tag     *models.Tag `json:"tag,omitempty"`
+}
+
+type TagListResponse struct {
+	Tags []*models.Tag `json:"tags"`
+}
+
+type DeleteUnusedTagsResponse struct {
+	Message string `json:"message"`
+	Deleted int64  `json:"deleted"`
+}
+
+const (
+	defaultTagSuggestions = 20
+	maxTagSuggestions     = 100
+)
+
+func NewTagController(
+	tagRepo repositories.TagRepository,
+	articleRepo repositories.ArticleRepository,
+	search *services.SearchService,
+) *TagController {
+	return &TagController{
+		tagRepo:     tagRepo,
+		articleRepo: articleRepo,
+		search:      search,
+	}
+}
+
+// GetTags retrieves the user's tags, optionally filtered by name or limited
+// to popular ones. Filtering by name suggests tags for autocompletion, those
+// starting with q first.
+// GET /api/v1/tags
+func (c *TagController) GetTags(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	var tags []*models.Tag
+	var err error
+
+	switch {
+	case strings.TrimSpace(ctx.Query("q")) != "":
+		limit, convErr := strconv.Atoi(ctx.DefaultQuery("limit", strconv.Itoa(defaultTagSuggestions)))
+		if convErr != nil || limit < 1 || limit > maxTagSuggestions {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "invalid_request",
+				Message: "limit must be between 1 and " + strconv.Itoa(maxTagSuggestions),
+			})
+			return
+		}
+		tags, err = c.tagRepo.SearchByName(userID, ctx.Query("q"), limit)
+	case ctx.Query("popular") != "":
+		limit, convErr := strconv.Atoi(ctx.Query("popular"))
+		if convErr != nil || limit < 1 {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "invalid_request",
+				Message: "popular must be a positive integer",
+			})
+			return
+		}
+		tags, err = c.tagRepo.GetPopularTags(userID, limit)
+	default:
+		tags, err = c.tagRepo.GetByUserID(userID)
+	}
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "fetch_failed",
+			Message: "Failed to fetch tags: " + err.Error(),
+		})
+		return
+	}
+
+	if tags == nil {
+		tags = []*models.Tag{}
+	}
+
+	ctx.JSON(http.StatusOK, TagListResponse{
+		Tags: tags,
+	})
+}
+
+// RenameTag renames a tag
+// PATCH /api/v1/tags/:id
+func (c *TagController) RenameTag(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	var req RenameTagRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid JSON format: " + err.Error(),
+		})
+		return
+	}
+
+	tag, ok := c.getOwnedTag(ctx, userID)
+	if !ok {
+		return
+	}
+
+	name := repositories.NormalizeTagName(req.Name)
+	if name == "" {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Tag name must not be empty",
+		})
+		return
+	}
+
+	if err := c.tagRepo.Rename(tag.ID, userID, name); err != nil {
+		if errors.Is(err, repositories.ErrTagNameConflict) {
+			ctx.JSON(http.StatusConflict, ErrorResponse{
+				Error:   "duplicate_tag",
+				Message: "A tag with this name already exists; merge the tags instead",
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "update_failed",
+			Message: "Failed to rename tag: " + err.Error(),
+		})
+		return
+	}
+
+	// Tag names are indexed with the articles
+	c.search.Refresh(c.taggedArticles(tag.ID)...)
+
+	tag.Name = name
+	ctx.JSON(http.StatusOK, TagResponse{
+		Message: "Tag renamed successfully",
+		Tag:     tag,
+	})
+}
+
+// MergeTag moves all articles of a tag to the target tag and deletes it
+// POST /api/v1/tags/:id/merge
+func (c *TagController) MergeTag(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	var req MergeTagRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid JSON format: " + err.Error(),
+		})
+		return
+	}
+
+	source, ok := c.getOwnedTag(ctx, userID)
+	if !ok {
+		return
+	}
+
+	if source.ID == req.TargetID {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "A tag cannot be merged into itself",
+		})
+		return
+	}
+
+	articleIDs := c.taggedArticles(source.ID)
+	if err := c.tagRepo.Merge(source.ID, req.TargetID, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, ErrorResponse{
+				Error:   "not_found",
+				Message: "Target tag not found",
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "merge_failed",
+			Message: "Failed to merge tags: " + err.Error(),
+		})
+		return
+	}
+	c.search.Refresh(articleIDs...)
+
+	target, err := c.tagRepo.GetByID(req.TargetID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "fetch_failed",
+			Message: "Tags merged but failed to fetch details",
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, TagResponse{
+		Message: "Tags merged successfully",
+		Tag:     target,
+	})
+}
+
+// DeleteTag deletes a tag and detaches it from all articles
+// DELETE /api/v1/tags/:id
+func (c *TagController) DeleteTag(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	tag, ok := c.getOwnedTag(ctx, userID)
+	if !ok {
+		return
+	}
+
+	articleIDs := c.taggedArticles(tag.ID)
+	if err := c.tagRepo.Delete(tag.ID, userID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "delete_failed",
+			Message: "Failed to delete tag: " + err.Error(),
+		})
+		return
+	}
+	c.search.Refresh(articleIDs...)
+
+	ctx.JSON(http.StatusOK, TagResponse{
+		Message: "Tag deleted successfully",
+	})
+}
+
+// DeleteUnusedTags deletes the user's tags that are not on any article
+// DELETE /api/v1/tags/unused
+func (c *TagController) DeleteUnusedTags(ctx *gin.Context) {
+	userID := ctx.GetString("user_id")
+	if userID == "" {
+		ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	deleted, err := c.tagRepo.DeleteUnused(userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "delete_failed",
+			Message: "Failed to delete unused tags: " + err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, DeleteUnusedTagsResponse{
+		Message: "Unused tags deleted successfully",
+		Deleted: deleted,
+	})
+}
+
+// taggedArticles returns the IDs of the articles of a tag, which need to be
+// indexed again when the tag changes. Without them search results only go
+// stale, so a failure is not reported.
+func (c *TagController) taggedArticles(tagID string) []string {
+	articleIDs, err := c.articleRepo.GetIDsByTag(tagID)
+	if err != nil {
+		return nil
+	}
+	return articleIDs
+}
+
+// getOwnedTag loads the tag in the :id path parameter and writes the error
+// response itself when it is missing or owned by someone else
+func (c *TagController) getOwnedTag(ctx *gin.Context, userID string) (*models.Tag, bool) {
+	tagID := ctx.Param("id")
+	if tagID == "" {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Tag ID is required",
+		})
+		return nil, false
+	}
+
+	tag, err := c.tagRepo.GetByID(tagID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, ErrorResponse{
+			Error:   "not_found",
+			Message: "Tag not found",
+		})
+		return nil, false
+	}
+
+	if tag.UserID != userID {
+		ctx.JSON(http.StatusForbidden, ErrorResponse{
+			Error:   "forbidden",
+			Message: "Access denied",
+		})
+		return nil, false
+	}
+
+	return tag, true
+}
@@ -0,0 +1,152 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTagController_GetTags(t *testing.T) {
+	api := newTestAPI(t)
+	tags, err := api.tagRepo.GetOrCreateMultiple("user-1", []string{"golang", "rust", "go-modules"})
+	require.NoError(t, err)
+	_, err = api.tagRepo.GetOrCreate("user-2", "golang")
+	require.NoError(t, err)
+
+	article := seedArticle(t, api, "user-1", "article")
+	require.NoError(t, api.articleRepo.Delete(article.ID, "user-1"))
+	require.NoError(t, api.articleRepo.CreateWithTags(article, []string{tags[0].ID}))
+	require.NoError(t, api.tagRepo.UpdateUsageCount(tags[0].ID))
+
+	var list TagListResponse
+	rec := api.do(http.MethodGet, "/api/v1/tags", "user-1", nil, &list)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Len(t, list.Tags, 3)
+	assert.Equal(t, "golang", list.Tags[0].Name)
+
+	rec = api.do(http.MethodGet, "/api/v1/tags?q=GO", "user-1", nil, &list)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Len(t, list.Tags, 2)
+
+	rec = api.do(http.MethodGet, "/api/v1/tags?q=%EF%BD%87%EF%BD%8F&limit=1", "user-1", nil, &list)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Len(t, list.Tags, 1)
+	assert.Equal(t, "golang", list.Tags[0].Name)
+
+	for _, limit := range []string{"0", "101", "abc"} {
+		rec = api.do(http.MethodGet, "/api/v1/tags?q=go&limit="+limit, "user-1", nil, nil)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, limit)
+	}
+
+	rec = api.do(http.MethodGet, "/api/v1/tags?popular=5", "user-1", nil, &list)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Len(t, list.Tags, 1)
+	assert.Equal(t, 1, list.Tags[0].UsageCount)
+
+	rec = api.do(http.MethodGet, "/api/v1/tags?popular=abc", "user-1", nil, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestTagController_RenameTag(t *testing.T) {
+	api := newTestAPI(t)
+	tags, err := api.tagRepo.GetOrCreateMultiple("user-1", []string{"golang", "rust"})
+	require.NoError(t, err)
+
+	var resp TagResponse
+	rec := api.do(http.MethodPatch, "/api/v1/tags/"+tags[0].ID, "user-1", RenameTagRequest{Name: " Go "}, &resp)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "Go", resp.Tag.Name)
+
+	rec = api.do(http.MethodPatch, "/api/v1/tags/"+tags[0].ID, "user-1", RenameTagRequest{Name: "rust"}, nil)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	rec = api.do(http.MethodPatch, "/api/v1/tags/"+tags[0].ID, "user-2", RenameTagRequest{Name: "mine"}, nil)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestTagController_MergeTag(t *testing.T) {
+	api := newTestAPI(t)
+	tags, err := api.tagRepo.GetOrCreateMultiple("user-1", []string{"golang", "go"})
+	require.NoError(t, err)
+	source, target := tags[0], tags[1]
+
+	first := seedArticle(t, api, "user-1", "first")
+	second := seedArticle(t, api, "user-1", "second")
+	for _, article := range []struct {
+		id     string
+		tagIDs []string
+	}{
+		{first.ID, []string{source.ID}},
+		{second.ID, []string{source.ID, target.ID}},
+	} {
+		stored, err := api.articleRepo.GetByID(article.id)
+		require.NoError(t, err)
+		require.NoError(t, api.articleRepo.Delete(article.id, "user-1"))
+		require.NoError(t, api.articleRepo.CreateWithTags(stored, article.tagIDs))
+	}
+
+	var resp TagResponse
+	rec := api.do(http.MethodPost, "/api/v1/tags/"+source.ID+"/merge", "user-1", MergeTagRequest{TargetID: target.ID}, &resp)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, target.ID, resp.Tag.ID)
+	assert.Equal(t, 2, resp.Tag.UsageCount)
+
+	_, err = api.tagRepo.GetByID(source.ID)
+	assert.Error(t, err)
+
+	for _, id := range []string{first.ID, second.ID} {
+		article, err := api.articleRepo.GetByIDWithAssociations(id)
+		require.NoError(t, err)
+		require.Len(t, article.Tags, 1)
+		assert.Equal(t, "go", article.Tags[0].Name)
+	}
+
+	rec = api.do(http.MethodPost, "/api/v1/tags/"+target.ID+"/merge", "user-1", MergeTagRequest{TargetID: target.ID}, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = api.do(http.MethodPost, "/api/v1/tags/"+target.ID+"/merge", "user-1", MergeTagRequest{TargetID: "missing"}, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestTagController_DeleteTag(t *testing.T) {
+	api := newTestAPI(t)
+	tag, err := api.tagRepo.GetOrCreate("user-1", "golang")
+	require.NoError(t, err)
+
+	rec := api.do(http.MethodDelete, "/api/v1/tags/"+tag.ID, "user-2", nil, nil)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = api.do(http.MethodDelete, "/api/v1/tags/"+tag.ID, "user-1", nil, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = api.do(http.MethodDelete, "/api/v1/tags/"+tag.ID, "user-1", nil, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestTagController_DeleteUnusedTags(t *testing.T) {
+	api := newTestAPI(t)
+	tags, err := api.tagRepo.GetOrCreateMultiple("user-1", []string{"golang", "rust", "zig"})
+	require.NoError(t, err)
+	_, err = api.tagRepo.GetOrCreate("user-2", "unused")
+	require.NoError(t, err)
+
+	article := seedArticle(t, api, "user-1", "article")
+	require.NoError(t, api.articleRepo.AddTags(article.ID, "user-1", []string{tags[0].ID}))
+
+	var resp DeleteUnusedTagsResponse
+	rec := api.do(http.MethodDelete, "/api/v1/tags/unused", "user-1", nil, &resp)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, int64(2), resp.Deleted)
+
+	var list TagListResponse
+	rec = api.do(http.MethodGet, "/api/v1/tags", "user-1", nil, &list)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Len(t, list.Tags, 1)
+	assert.Equal(t, "golang", list.Tags[0].Name)
+
+	rec = api.do(http.MethodGet, "/api/v1/tags", "user-2", nil, &list)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Len(t, list.Tags, 1)
+}
@@ -3,11 +3,14 @@ package database
 import (
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/eikuma/stockle/backend/internal/config"
 	"github.com/eikuma/stockle/backend/internal/models"
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
@@ -17,8 +20,6 @@ var DB *gorm.DB
 func Connect(cfg *config.Config) error {
 	var err error
 	
-	dsn := cfg.Database.DSN()
-	
 	// GORM logger configuration
 	var gormLogger logger.Interface
 	if cfg.Server.Environment == "development" {
@@ -27,7 +28,31 @@ func Connect(cfg *config.Config) error {
 		gormLogger = logger.Default.LogMode(logger.Error)
 	}
 	
-	DB, err = gorm.Open(mysql.Open(dsn), &gorm.Config{
+	DB, err = Open(cfg.Database, gormLogger)
+	if err != nil {
+		return err
+	}
+	
+	log.Printf("Database connected successfully (%s)", cfg.Database.Driver)
+	return nil
+}
+
+// Open connects to the database selected by cfg.Driver and configures the
+// connection pool. It does not touch the package-level DB.
+func Open(cfg config.DatabaseConfig, gormLogger logger.Interface) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch cfg.Driver {
+	case DriverMySQL, "":
+		dialector = mysql.Open(cfg.DSN())
+	case DriverSQLite:
+		dialector = sqlite.Open(cfg.DSN())
+	case DriverPostgres:
+		dialector = postgres.Open(cfg.DSN())
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", cfg.Driver)
+	}
+	
+	db, err := gorm.Open(dialector, &gorm.Config{
 		Logger: gormLogger,
 		NowFunc: func() time.Time {
 			return time.Now().UTC()
@@ -35,45 +60,50 @@ func Connect(cfg *config.Config) error {
 	})
 	
 	if err != nil {
-		return fmt.Errorf("failed to connect to database: %w", err)
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	
+	// Use the ArticleTag model for the article_tags join table
+	if err := setupJoinTables(db); err != nil {
+		return nil, err
 	}
 	
 	// Get underlying sql.DB instance
-	sqlDB, err := DB.DB()
+	sqlDB, err := db.DB()
 	if err != nil {
-		return fmt.Errorf("failed to get underlying sql.DB: %w", err)
+		return nil, fmt.Errorf("failed to get underlying sql.DB: %w", err)
 	}
 	
 	// Set connection pool settings
-	sqlDB.SetMaxOpenConns(cfg.Database.MaxOpenConns)
-	sqlDB.SetMaxIdleConns(cfg.Database.MaxIdleConns)
-	sqlDB.SetConnMaxLifetime(cfg.Database.ConnMaxLifetime)
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	if cfg.Driver == DriverSQLite && isSQLiteMemory(cfg.DSN()) {
+		// Every connection to :memory: opens its own empty database
+		sqlDB.SetMaxOpenConns(1)
+		sqlDB.SetMaxIdleConns(1)
+		sqlDB.SetConnMaxLifetime(0)
+	}
 	
 	// Test connection
 	if err := sqlDB.Ping(); err != nil {
-		return fmt.Errorf("failed to ping database: %w", err)
+		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 	
-	log.Println("Database connected successfully")
-	return nil
+	return db, nil
 }
 
-func AutoMigrate() error {
-	if DB == nil {
-		return fmt.Errorf("database connection is not initialized")
+func isSQLiteMemory(dsn string) bool {
+	return strings.HasPrefix(dsn, ":memory:") || strings.Contains(dsn, "mode=memory")
+}
+
+func setupJoinTables(db *gorm.DB) error {
+	if err := db.SetupJoinTable(&models.Article{}, "Tags", &models.ArticleTag{}); err != nil {
+		return fmt.Errorf("failed to setup article_tags join table: %w", err)
 	}
-	
-	err := DB.AutoMigrate(
-		&models.User{},
-		&models.UserSession{},
-		&models.UserPreference{},
-	)
-	
-	if err != nil {
-		return fmt.Errorf("failed to auto migrate: %w", err)
+	if err := db.SetupJoinTable(&models.Tag{}, "Articles", &models.ArticleTag{}); err != nil {
+		return fmt.Errorf("failed to setup article_tags join table: %w", err)
 	}
-	
-	log.Println("Database migration completed successfully")
 	return nil
 }
 
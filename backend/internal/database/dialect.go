@@ -0,0 +1,154 @@
+package database
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Supported values of database.driver
+const (
+	DriverMySQL    = "mysql"
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// likeEscape escapes wildcards in LIKE patterns. A backslash would need
+// different quoting on MySQL and PostgreSQL, so use a character that means
+// the same thing everywhere.
+const likeEscape = "!"
+
+// Dialect hides the SQL differences between the supported databases from
+// the repositories
+type Dialect interface {
+	Name() string
+	// Now is the current timestamp as stored by the driver
+	Now() clause.Expr
+	// ContainsFold matches rows whose column contains term, ignoring case
+	ContainsFold(column, term string) clause.Expr
+	// MatchFold matches rows whose column is made of parts in order with
+	// anything between them, ignoring case. An empty first or last part
+	// leaves the start or end open.
+	MatchFold(column string, parts ...string) clause.Expr
+	// Upsert makes an INSERT update updateColumns (or do nothing when none
+	// are given) if it conflicts with the unique key made of columns
+	Upsert(columns []string, updateColumns ...string) clause.OnConflict
+}
+
+// DialectOf returns the dialect matching the driver db was opened with
+func DialectOf(db *gorm.DB) Dialect {
+	switch db.Dialector.Name() {
+	case DriverSQLite:
+		return sqliteDialect{}
+	case DriverPostgres:
+		return postgresDialect{}
+	default:
+		return mysqlDialect{}
+	}
+}
+
+// containsPattern builds a LIKE pattern matching term anywhere, with the
+// wildcards in term escaped
+func containsPattern(term string) string {
+	return matchPattern("", term, "")
+}
+
+// matchPattern joins parts with %, escaping the wildcards in them
+func matchPattern(parts ...string) string {
+	replacer := strings.NewReplacer(likeEscape, likeEscape+likeEscape, "%", likeEscape+"%", "_", likeEscape+"_")
+	escaped := make([]string, len(parts))
+	for i, part := range parts {
+		escaped[i] = replacer.Replace(strings.ToLower(part))
+	}
+	return strings.Join(escaped, "%")
+}
+
+// lowerLike matches a lower-cased pattern against the lower-cased column
+func lowerLike(column, pattern string) clause.Expr {
+	return clause.Expr{
+		SQL:  "LOWER(" + column + ") LIKE ? ESCAPE '" + likeEscape + "'",
+		Vars: []interface{}{pattern},
+	}
+}
+
+func upsertColumns(names []string) []clause.Column {
+	columns := make([]clause.Column, 0, len(names))
+	for _, name := range names {
+		columns = append(columns, clause.Column{Name: name})
+	}
+	return columns
+}
+
+func upsertClause(columns []clause.Column, updateColumns []string) clause.OnConflict {
+	if len(updateColumns) == 0 {
+		return clause.OnConflict{Columns: columns, DoNothing: true}
+	}
+	return clause.OnConflict{Columns: columns, DoUpdates: clause.AssignmentColumns(updateColumns)}
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string { return DriverMySQL }
+
+func (mysqlDialect) Now() clause.Expr { return clause.Expr{SQL: "NOW()"} }
+
+func (mysqlDialect) ContainsFold(column, term string) clause.Expr {
+	return lowerLike(column, containsPattern(term))
+}
+
+func (mysqlDialect) MatchFold(column string, parts ...string) clause.Expr {
+	return lowerLike(column, matchPattern(parts...))
+}
+
+// Upsert leaves out the conflict target, which MySQL does not support: ON
+// DUPLICATE KEY UPDATE fires for any unique key
+func (mysqlDialect) Upsert(_ []string, updateColumns ...string) clause.OnConflict {
+	return upsertClause(nil, updateColumns)
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string { return DriverSQLite }
+
+// Now binds the current time in UTC, as the NowFunc set by Open does for the
+// timestamps gorm fills in. SQLite compares timestamps as text, so the driver
+// has to write both in the same layout and offset.
+func (sqliteDialect) Now() clause.Expr {
+	return clause.Expr{SQL: "?", Vars: []interface{}{time.Now().UTC()}}
+}
+
+func (sqliteDialect) ContainsFold(column, term string) clause.Expr {
+	return lowerLike(column, containsPattern(term))
+}
+
+func (sqliteDialect) MatchFold(column string, parts ...string) clause.Expr {
+	return lowerLike(column, matchPattern(parts...))
+}
+
+func (sqliteDialect) Upsert(columns []string, updateColumns ...string) clause.OnConflict {
+	return upsertClause(upsertColumns(columns), updateColumns)
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string { return DriverPostgres }
+
+func (postgresDialect) Now() clause.Expr { return clause.Expr{SQL: "NOW()"} }
+
+// ContainsFold uses ILIKE, which unlike LOWER() ... LIKE folds non-ASCII text too
+func (d postgresDialect) ContainsFold(column, term string) clause.Expr {
+	return d.MatchFold(column, "", term, "")
+}
+
+func (postgresDialect) MatchFold(column string, parts ...string) clause.Expr {
+	return clause.Expr{
+		SQL:  column + " ILIKE ? ESCAPE '" + likeEscape + "'",
+		Vars: []interface{}{matchPattern(parts...)},
+	}
+}
+
+func (postgresDialect) Upsert(columns []string, updateColumns ...string) clause.OnConflict {
+	return upsertClause(upsertColumns(columns), updateColumns)
+}
@@ -0,0 +1,84 @@
+package database
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/eikuma/stockle/backend/internal/models"
+	"github.com/eikuma/stockle/backend/migrations"
+	"gorm.io/gorm"
+)
+
+// Models returns every model whose table is managed by the SQL migrations
+func Models() []interface{} {
+	return []interface{}{
+		&models.User{},
+		&models.UserSession{},
+		&models.UserPreference{},
+		&models.Category{},
+		&models.SmartCollection{},
+		&models.Tag{},
+		&models.Article{},
+		&models.ArticleTag{},
+		&models.ArticleSummary{},
+		&models.JobQueue{},
+		&models.Import{},
+		&models.ImportItem{},
+		&models.Export{},
+		&models.Feed{},
+		&models.FeedEntry{},
+		&models.SearchDocument{},
+		&models.SearchPosting{},
+	}
+}
+
+// NewSchemaMigrator returns a Migrator for the embedded SQL migrations of
+// the driver db was opened with
+func NewSchemaMigrator(db *gorm.DB) (*Migrator, error) {
+	fsys, err := migrations.ForDriver(db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	return NewMigrator(db, fsys), nil
+}
+
+// Migrate brings the schema up to date and verifies it against the models.
+// When autoApply is false, pending migrations are reported as an error so
+// that they can be applied explicitly with `stockle migrate up`.
+func Migrate(autoApply bool) error {
+	if DB == nil {
+		return fmt.Errorf("database connection is not initialized")
+	}
+
+	// Convert databases created with integer user IDs before running migrations
+	if err := MigrateUserIDsToUUID(DB); err != nil {
+		return fmt.Errorf("failed to migrate user IDs: %w", err)
+	}
+
+	migrator, err := NewSchemaMigrator(DB)
+	if err != nil {
+		return err
+	}
+	if autoApply {
+		applied, err := migrator.Up(0)
+		if err != nil {
+			return err
+		}
+		log.Printf("Applied %d database migrations", len(applied))
+	} else {
+		pending, err := migrator.Pending()
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("%d database migrations are pending, run `stockle migrate up`", len(pending))
+		}
+	}
+
+	if err := CheckSchema(DB, Models()...); err != nil {
+		return err
+	}
+
+	log.Println("Database schema is up to date")
+	return nil
+}
@@ -0,0 +1,457 @@
+package database
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrMigrationLocked is returned when another process holds the migration lock
+var ErrMigrationLocked = errors.New("migrations are locked by another process")
+
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_([A-Za-z0-9_\-]+)\.(up|down)\.sql$`)
+
+// Migration is a numbered pair of up/down SQL scripts
+type Migration struct {
+	Version  uint64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// MigrationStatus describes a migration file and whether it has been applied
+type MigrationStatus struct {
+	Version   uint64
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+	// Modified is set when the up script changed after it was applied
+	Modified bool
+	// Missing is set when an applied version has no migration file anymore
+	Missing bool
+}
+
+// schemaMigration is a row of the schema_migrations bookkeeping table
+type schemaMigration struct {
+	Version   uint64    `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"type:varchar(255);not null"`
+	Checksum  string    `gorm:"type:varchar(64);not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// schemaMigrationLock holds at most one row while a migration run is in progress
+type schemaMigrationLock struct {
+	ID       int       `gorm:"primaryKey;autoIncrement:false"`
+	Owner    string    `gorm:"type:varchar(255);not null"`
+	LockedAt time.Time `gorm:"not null"`
+}
+
+func (schemaMigrationLock) TableName() string {
+	return "schema_migrations_lock"
+}
+
+// Migrator applies versioned SQL migrations read from an fs.FS
+type Migrator struct {
+	db   *gorm.DB
+	fsys fs.FS
+
+	// LockWait is how long to wait for another process to release the lock
+	LockWait time.Duration
+	// LockExpiry is the age after which a lock is considered abandoned
+	LockExpiry time.Duration
+
+	owner string
+}
+
+func NewMigrator(db *gorm.DB, fsys fs.FS) *Migrator {
+	hostname, _ := os.Hostname()
+	return &Migrator{
+		db:         db,
+		fsys:       fsys,
+		LockWait:   time.Minute,
+		LockExpiry: 15 * time.Minute,
+		owner:      fmt.Sprintf("%s:%d:%d", hostname, os.Getpid(), time.Now().UnixNano()),
+	}
+}
+
+// Load reads and validates all migration files, sorted by version
+func (m *Migrator) Load() ([]Migration, error) {
+	entries, err := fs.ReadDir(m.fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[uint64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", entry.Name(), err)
+		}
+
+		data, err := fs.ReadFile(m.fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", entry.Name(), err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d is used by both %q and %q", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(data)
+			sum := sha256.Sum256(data)
+			migration.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			migration.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// Up applies pending migrations in order. steps <= 0 applies all of them.
+func (m *Migrator) Up(steps int) ([]Migration, error) {
+	var applied []Migration
+	err := m.withLock(func() error {
+		pending, err := m.pending()
+		if err != nil {
+			return err
+		}
+		if steps > 0 && len(pending) > steps {
+			pending = pending[:steps]
+		}
+
+		for _, migration := range pending {
+			if err := m.apply(migration); err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the most recently applied migrations. steps <= 0 reverts one.
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	if steps <= 0 {
+		steps = 1
+	}
+
+	var reverted []Migration
+	err := m.withLock(func() error {
+		migrations, err := m.Load()
+		if err != nil {
+			return err
+		}
+		files := make(map[uint64]Migration, len(migrations))
+		for _, migration := range migrations {
+			files[migration.Version] = migration
+		}
+
+		applied, err := m.appliedVersions()
+		if err != nil {
+			return err
+		}
+
+		for i := len(applied) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration, ok := files[applied[i].Version]
+			if !ok {
+				return fmt.Errorf("migration %d is applied but its files are missing", applied[i].Version)
+			}
+			if strings.TrimSpace(migration.Down) == "" {
+				return fmt.Errorf("migration %d_%s has no down script", migration.Version, migration.Name)
+			}
+			if err := m.revert(migration); err != nil {
+				return err
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Redo reverts the latest migration and applies it again
+func (m *Migrator) Redo() (*Migration, error) {
+	reverted, err := m.Down(1)
+	if err != nil {
+		return nil, err
+	}
+	if len(reverted) == 0 {
+		return nil, errors.New("no applied migration to redo")
+	}
+
+	applied, err := m.Up(1)
+	if err != nil {
+		return nil, err
+	}
+	if len(applied) == 0 || applied[0].Version != reverted[0].Version {
+		return nil, fmt.Errorf("redo of migration %d applied an unexpected migration", reverted[0].Version)
+	}
+	return &applied[0], nil
+}
+
+// Status lists every known migration together with its applied state
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	migrations, err := m.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.ensureTables(); err != nil {
+		return nil, err
+	}
+	applied, err := m.appliedVersions()
+	if err != nil {
+		return nil, err
+	}
+	appliedByVersion := make(map[uint64]schemaMigration, len(applied))
+	for _, row := range applied {
+		appliedByVersion[row.Version] = row
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, migration := range migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if row, ok := appliedByVersion[migration.Version]; ok {
+			appliedAt := row.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+			status.Modified = row.Checksum != migration.Checksum
+			delete(appliedByVersion, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+
+	for _, row := range appliedByVersion {
+		appliedAt := row.AppliedAt
+		statuses = append(statuses, MigrationStatus{
+			Version:   row.Version,
+			Name:      row.Name,
+			Applied:   true,
+			AppliedAt: &appliedAt,
+			Missing:   true,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+
+	return statuses, nil
+}
+
+// Pending returns the migrations that have not been applied yet
+func (m *Migrator) Pending() ([]Migration, error) {
+	if err := m.ensureTables(); err != nil {
+		return nil, err
+	}
+	return m.pending()
+}
+
+func (m *Migrator) pending() ([]Migration, error) {
+	migrations, err := m.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := m.appliedVersions()
+	if err != nil {
+		return nil, err
+	}
+	done := make(map[uint64]bool, len(applied))
+	for _, row := range applied {
+		done[row.Version] = true
+	}
+
+	var pending []Migration
+	for _, migration := range migrations {
+		if !done[migration.Version] {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+func (m *Migrator) appliedVersions() ([]schemaMigration, error) {
+	var rows []schemaMigration
+	if err := m.db.Order("version ASC").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	return rows, nil
+}
+
+// apply runs the up script and records the version in one transaction.
+// MySQL commits DDL implicitly, so a failing statement can leave earlier
+// statements of the same migration applied.
+func (m *Migrator) apply(migration Migration) error {
+	log.Printf("Applying migration %06d_%s", migration.Version, migration.Name)
+
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range SplitStatements(migration.Up) {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Create(&schemaMigration{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Checksum:  migration.Checksum,
+			AppliedAt: time.Now().UTC(),
+		}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("migration %d_%s failed: %w", migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+func (m *Migrator) revert(migration Migration) error {
+	log.Printf("Reverting migration %06d_%s", migration.Version, migration.Name)
+
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range SplitStatements(migration.Down) {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Where("version = ?", migration.Version).Delete(&schemaMigration{}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("revert of migration %d_%s failed: %w", migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+func (m *Migrator) ensureTables() error {
+	if err := m.db.AutoMigrate(&schemaMigration{}, &schemaMigrationLock{}); err != nil {
+		return fmt.Errorf("failed to create migration tables: %w", err)
+	}
+	return nil
+}
+
+// withLock runs fn while holding the row lock in schema_migrations_lock.
+// The lock is a plain row rather than an advisory lock so that it works the
+// same on every database driver.
+func (m *Migrator) withLock(fn func() error) error {
+	if err := m.ensureTables(); err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(m.LockWait)
+	for {
+		lock := &schemaMigrationLock{ID: 1, Owner: m.owner, LockedAt: time.Now().UTC()}
+		if err := m.db.Create(lock).Error; err == nil {
+			break
+		}
+
+		// Take over locks left behind by a crashed process
+		var current schemaMigrationLock
+		if err := m.db.Where("id = ?", 1).First(&current).Error; err == nil &&
+			time.Since(current.LockedAt) > m.LockExpiry {
+			log.Printf("Removing stale migration lock held by %s since %s", current.Owner, current.LockedAt)
+			m.db.Where("id = ? AND owner = ?", 1, current.Owner).Delete(&schemaMigrationLock{})
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return ErrMigrationLocked
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+
+	defer func() {
+		if err := m.db.Where("id = ? AND owner = ?", 1, m.owner).Delete(&schemaMigrationLock{}).Error; err != nil {
+			log.Printf("Failed to release migration lock: %v", err)
+		}
+	}()
+
+	return fn()
+}
+
+// SplitStatements splits a SQL script into individual statements on
+// semicolons, ignoring semicolons inside quotes and comments
+func SplitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+
+	flush := func() {
+		statement := strings.TrimSpace(current.String())
+		if statement != "" {
+			statements = append(statements, statement)
+		}
+		current.Reset()
+	}
+
+	runes := []rune(script)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		switch {
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			// Line comment
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			current.WriteRune('\n')
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			// Block comment
+			i += 2
+			for i+1 < len(runes) && !(runes[i] == '*' && runes[i+1] == '/') {
+				i++
+			}
+			i++
+		case r == '\'' || r == '"' || r == '`':
+			current.WriteRune(r)
+			for i++; i < len(runes); i++ {
+				current.WriteRune(runes[i])
+				if runes[i] == r {
+					break
+				}
+			}
+		case r == ';':
+			flush()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	flush()
+
+	return statements
+}
@@ -0,0 +1,215 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/eikuma/stockle/backend/migrations"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+func testMigrations() fstest.MapFS {
+	return fstest.MapFS{
+		"000001_create_notes.up.sql": {Data: []byte(`
+-- Notes belong to a user
+CREATE TABLE notes (
+    id VARCHAR(36) PRIMARY KEY,
+    body TEXT NOT NULL DEFAULT ';'
+);
+INSERT INTO notes (id, body) VALUES ('seed', 'first; note');
+`)},
+		"000001_create_notes.down.sql": {Data: []byte(`DROP TABLE notes;`)},
+		"000002_add_title.up.sql":      {Data: []byte(`ALTER TABLE notes ADD COLUMN title VARCHAR(100);`)},
+		"000002_add_title.down.sql":    {Data: []byte(`ALTER TABLE notes DROP COLUMN title;`)},
+		"README.md":                    {Data: []byte(`ignored`)},
+	}
+}
+
+func TestMigrator_UpDownStatus(t *testing.T) {
+	db := openTestDB(t)
+	migrator := NewMigrator(db, testMigrations())
+
+	applied, err := migrator.Up(0)
+	require.NoError(t, err)
+	require.Len(t, applied, 2)
+	assert.True(t, db.Migrator().HasColumn("notes", "title"))
+
+	var body string
+	require.NoError(t, db.Raw("SELECT body FROM notes WHERE id = 'seed'").Scan(&body).Error)
+	assert.Equal(t, "first; note", body)
+
+	// Running again is a no-op
+	applied, err = migrator.Up(0)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	statuses, err := migrator.Status()
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	for _, status := range statuses {
+		assert.True(t, status.Applied)
+		assert.False(t, status.Modified)
+		assert.NotNil(t, status.AppliedAt)
+	}
+
+	reverted, err := migrator.Down(1)
+	require.NoError(t, err)
+	require.Len(t, reverted, 1)
+	assert.Equal(t, uint64(2), reverted[0].Version)
+	assert.False(t, db.Migrator().HasColumn("notes", "title"))
+
+	pending, err := migrator.Pending()
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, "add_title", pending[0].Name)
+
+	applied, err = migrator.Up(1)
+	require.NoError(t, err)
+	require.Len(t, applied, 1)
+
+	redone, err := migrator.Redo()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), redone.Version)
+	assert.True(t, db.Migrator().HasColumn("notes", "title"))
+
+	reverted, err = migrator.Down(5)
+	require.NoError(t, err)
+	assert.Len(t, reverted, 2)
+	assert.False(t, db.Migrator().HasTable("notes"))
+}
+
+func TestMigrator_FailedMigrationIsNotRecorded(t *testing.T) {
+	db := openTestDB(t)
+	fsys := testMigrations()
+	fsys["000003_broken.up.sql"] = &fstest.MapFile{Data: []byte(`ALTER TABLE missing ADD COLUMN x INT;`)}
+
+	applied, err := NewMigrator(db, fsys).Up(0)
+	require.Error(t, err)
+	assert.Len(t, applied, 2)
+
+	statuses, err := NewMigrator(db, fsys).Status()
+	require.NoError(t, err)
+	require.Len(t, statuses, 3)
+	assert.False(t, statuses[2].Applied)
+}
+
+func TestMigrator_StatusDetectsModifiedAndMissingFiles(t *testing.T) {
+	db := openTestDB(t)
+	fsys := testMigrations()
+
+	_, err := NewMigrator(db, fsys).Up(0)
+	require.NoError(t, err)
+
+	fsys["000002_add_title.up.sql"] = &fstest.MapFile{Data: []byte(`ALTER TABLE notes ADD COLUMN title VARCHAR(200);`)}
+	delete(fsys, "000001_create_notes.up.sql")
+	delete(fsys, "000001_create_notes.down.sql")
+
+	statuses, err := NewMigrator(db, fsys).Status()
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.True(t, statuses[0].Missing)
+	assert.True(t, statuses[1].Modified)
+}
+
+func TestMigrator_Lock(t *testing.T) {
+	db := openTestDB(t)
+	migrator := NewMigrator(db, testMigrations())
+	migrator.LockWait = 0
+	require.NoError(t, migrator.ensureTables())
+
+	require.NoError(t, db.Create(&schemaMigrationLock{ID: 1, Owner: "other", LockedAt: time.Now().UTC()}).Error)
+
+	_, err := migrator.Up(0)
+	assert.ErrorIs(t, err, ErrMigrationLocked)
+
+	// A lock older than LockExpiry is taken over
+	require.NoError(t, db.Model(&schemaMigrationLock{}).Where("id = 1").
+		Update("locked_at", time.Now().UTC().Add(-time.Hour)).Error)
+	applied, err := migrator.Up(0)
+	require.NoError(t, err)
+	assert.Len(t, applied, 2)
+
+	var count int64
+	require.NoError(t, db.Model(&schemaMigrationLock{}).Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+func TestMigrator_LoadRejectsMissingUpScript(t *testing.T) {
+	_, err := NewMigrator(nil, fstest.MapFS{
+		"000001_only_down.down.sql": {Data: []byte(`DROP TABLE x;`)},
+	}).Load()
+	assert.Error(t, err)
+}
+
+func TestEmbeddedMigrationsMatchAcrossDrivers(t *testing.T) {
+	var versions map[uint64]string
+	for _, driver := range []string{DriverMySQL, DriverSQLite, DriverPostgres} {
+		fsys, err := migrations.ForDriver(driver)
+		require.NoError(t, err)
+
+		loaded, err := NewMigrator(nil, fsys).Load()
+		require.NoError(t, err, driver)
+		require.NotEmpty(t, loaded, driver)
+
+		found := make(map[uint64]string, len(loaded))
+		for _, migration := range loaded {
+			assert.NotEmpty(t, migration.Down, "%s %d_%s", driver, migration.Version, migration.Name)
+			found[migration.Version] = migration.Name
+		}
+		if versions == nil {
+			versions = found
+		}
+		assert.Equal(t, versions, found, "%s migrations differ from mysql", driver)
+	}
+
+	_, err := migrations.ForDriver("oracle")
+	assert.Error(t, err)
+}
+
+func TestEmbeddedMigrations_SQLite(t *testing.T) {
+	db := openTestDB(t)
+	migrator, err := NewSchemaMigrator(db)
+	require.NoError(t, err)
+
+	applied, err := migrator.Up(0)
+	require.NoError(t, err)
+	assert.NoError(t, CheckSchema(db, Models()...))
+
+	reverted, err := migrator.Down(len(applied))
+	require.NoError(t, err)
+	assert.Len(t, reverted, len(applied))
+	assert.False(t, db.Migrator().HasTable("users"))
+}
+
+func TestSplitStatements(t *testing.T) {
+	statements := SplitStatements(`
+/* header; comment */
+CREATE TABLE a (x TEXT DEFAULT 'a;b'); -- trailing; comment
+INSERT INTO a VALUES ("c;d");
+
+`)
+	assert.Equal(t, []string{
+		"CREATE TABLE a (x TEXT DEFAULT 'a;b')",
+		`INSERT INTO a VALUES ("c;d")`,
+	}, statements)
+}
@@ -0,0 +1,201 @@
+package database
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// SchemaDrift is a single difference between a GORM model and the live schema
+type SchemaDrift struct {
+	Table   string
+	Column  string
+	Problem string
+}
+
+func (d SchemaDrift) String() string {
+	if d.Column == "" {
+		return fmt.Sprintf("%s: %s", d.Table, d.Problem)
+	}
+	return fmt.Sprintf("%s.%s: %s", d.Table, d.Column, d.Problem)
+}
+
+// SchemaDriftError lists every drift found by CheckSchema
+type SchemaDriftError struct {
+	Drifts []SchemaDrift
+}
+
+func (e *SchemaDriftError) Error() string {
+	lines := make([]string, 0, len(e.Drifts))
+	for _, drift := range e.Drifts {
+		lines = append(lines, "  "+drift.String())
+	}
+	return fmt.Sprintf("database schema does not match the models (%d problems):\n%s",
+		len(e.Drifts), strings.Join(lines, "\n"))
+}
+
+var typeLengthPattern = regexp.MustCompile(`\((\d+)\)`)
+
+type typeFamily string
+
+const (
+	familyString  typeFamily = "string"
+	familyInt     typeFamily = "int"
+	familyFloat   typeFamily = "float"
+	familyBool    typeFamily = "bool"
+	familyTime    typeFamily = "time"
+	familyBytes   typeFamily = "bytes"
+	familyUnknown typeFamily = ""
+)
+
+// CheckSchema compares the columns of each model against the live database
+// and returns a *SchemaDriftError when a table or column is missing, has an
+// incompatible type or is too short, or when the table has a NOT NULL column
+// without a default that the model would never write.
+func CheckSchema(db *gorm.DB, models ...interface{}) error {
+	var drifts []SchemaDrift
+
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return fmt.Errorf("failed to parse model %T: %w", model, err)
+		}
+		modelSchema := stmt.Schema
+		table := modelSchema.Table
+
+		if !db.Migrator().HasTable(table) {
+			drifts = append(drifts, SchemaDrift{Table: table, Problem: "table is missing"})
+			continue
+		}
+
+		columnTypes, err := db.Migrator().ColumnTypes(table)
+		if err != nil {
+			return fmt.Errorf("failed to inspect table %s: %w", table, err)
+		}
+		columns := make(map[string]gorm.ColumnType, len(columnTypes))
+		for _, column := range columnTypes {
+			columns[strings.ToLower(column.Name())] = column
+		}
+
+		for _, field := range modelSchema.Fields {
+			if field.DBName == "" || field.IgnoreMigration {
+				continue
+			}
+
+			column, ok := columns[strings.ToLower(field.DBName)]
+			if !ok {
+				drifts = append(drifts, SchemaDrift{Table: table, Column: field.DBName, Problem: "column is missing"})
+				continue
+			}
+			delete(columns, strings.ToLower(field.DBName))
+
+			if problem := compareColumn(field, column); problem != "" {
+				drifts = append(drifts, SchemaDrift{Table: table, Column: field.DBName, Problem: problem})
+			}
+		}
+
+		// Columns the model does not know about are fine unless inserts would fail
+		for _, column := range columnTypes {
+			if _, unknown := columns[strings.ToLower(column.Name())]; !unknown {
+				continue
+			}
+			nullable, ok := column.Nullable()
+			_, hasDefault := column.DefaultValue()
+			if ok && !nullable && !hasDefault {
+				drifts = append(drifts, SchemaDrift{
+					Table:   table,
+					Column:  column.Name(),
+					Problem: "NOT NULL column without default is not mapped by the model",
+				})
+			}
+		}
+	}
+
+	if len(drifts) > 0 {
+		return &SchemaDriftError{Drifts: drifts}
+	}
+	return nil
+}
+
+func compareColumn(field *schema.Field, column gorm.ColumnType) string {
+	modelFamily := modelTypeFamily(field)
+	columnFamily := sqlTypeFamily(column.DatabaseTypeName())
+
+	if !compatibleFamilies(modelFamily, columnFamily) {
+		return fmt.Sprintf("type %s is not compatible with %s field %s",
+			column.DatabaseTypeName(), field.FieldType, field.Name)
+	}
+
+	if modelFamily == familyString {
+		modelLength := field.Size
+		if match := typeLengthPattern.FindStringSubmatch(string(field.DataType)); match != nil {
+			modelLength, _ = strconv.Atoi(match[1])
+		}
+		columnLength, ok := column.Length()
+		if ok && modelLength > 0 && columnLength > 0 && columnLength < int64(modelLength) {
+			return fmt.Sprintf("length %d is shorter than the model's %d", columnLength, modelLength)
+		}
+	}
+
+	return ""
+}
+
+func modelTypeFamily(field *schema.Field) typeFamily {
+	switch field.DataType {
+	case schema.String:
+		return familyString
+	case schema.Int, schema.Uint:
+		return familyInt
+	case schema.Float:
+		return familyFloat
+	case schema.Bool:
+		return familyBool
+	case schema.Time:
+		return familyTime
+	case schema.Bytes:
+		return familyBytes
+	}
+	// Explicit type tags such as type:varchar(36)
+	return sqlTypeFamily(string(field.DataType))
+}
+
+func sqlTypeFamily(typeName string) typeFamily {
+	name := strings.ToLower(typeName)
+	switch {
+	case strings.Contains(name, "char"), strings.Contains(name, "text"), strings.Contains(name, "enum"),
+		strings.Contains(name, "uuid"), strings.Contains(name, "json"):
+		return familyString
+	case strings.Contains(name, "bool"), name == "bit":
+		return familyBool
+	case strings.Contains(name, "int"), name == "serial", name == "bigserial":
+		return familyInt
+	case strings.Contains(name, "double"), strings.Contains(name, "float"), strings.Contains(name, "real"),
+		strings.Contains(name, "decimal"), strings.Contains(name, "numeric"):
+		return familyFloat
+	case strings.Contains(name, "time"), strings.Contains(name, "date"):
+		return familyTime
+	case strings.Contains(name, "blob"), strings.Contains(name, "binary"), strings.Contains(name, "bytea"):
+		return familyBytes
+	}
+	return familyUnknown
+}
+
+func compatibleFamilies(model, column typeFamily) bool {
+	if model == familyUnknown || column == familyUnknown || model == column {
+		return true
+	}
+
+	switch model {
+	case familyBool:
+		// MySQL stores booleans as TINYINT(1) and SQLite as NUMERIC
+		return column == familyInt || column == familyFloat
+	case familyInt:
+		// SQLite reports NUMERIC affinity for some integer columns
+		return column == familyFloat || column == familyBool
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/eikuma/stockle/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckSchema_MatchesModels(t *testing.T) {
+	db := openTestDB(t)
+	require.NoError(t, db.AutoMigrate(Models()...))
+
+	assert.NoError(t, CheckSchema(db, Models()...))
+}
+
+func TestCheckSchema_ReportsDrift(t *testing.T) {
+	db := openTestDB(t)
+
+	// The job_queues layout shipped with the original SQL file
+	require.NoError(t, db.Exec(`CREATE TABLE job_queues (
+		id VARCHAR(36) PRIMARY KEY,
+		type VARCHAR(50) NOT NULL,
+		status VARCHAR(20) DEFAULT 'pending',
+		payload TEXT,
+		attempt_count INT DEFAULT 0,
+		created_at DATETIME,
+		updated_at DATETIME
+	)`).Error)
+	// users created with an auto-increment integer ID and a short email column
+	require.NoError(t, db.Exec(`CREATE TABLE users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		email VARCHAR(100) NOT NULL
+	)`).Error)
+
+	err := CheckSchema(db, &models.JobQueue{}, &models.User{}, &models.Tag{})
+	require.Error(t, err)
+
+	var driftErr *SchemaDriftError
+	require.ErrorAs(t, err, &driftErr)
+
+	problems := map[string]string{}
+	for _, drift := range driftErr.Drifts {
+		problems[drift.Table+"."+drift.Column] = drift.Problem
+	}
+
+	assert.Equal(t, "column is missing", problems["job_queues.job_type"])
+	assert.Equal(t, "column is missing", problems["job_queues.retry_count"])
+	assert.Contains(t, problems["job_queues.type"], "NOT NULL")
+	assert.NotContains(t, problems, "job_queues.attempt_count")
+	assert.Contains(t, problems["users.id"], "not compatible")
+	assert.Contains(t, problems["users.email"], "shorter")
+	assert.Equal(t, "table is missing", problems["tags."])
+	assert.Contains(t, err.Error(), "job_queues.job_type: column is missing")
+}
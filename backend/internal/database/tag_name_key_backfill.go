@@ -0,0 +1,70 @@
+package database
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/eikuma/stockle/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+const tagNameKeyBatchSize = 500
+
+// BackfillTagNameKeys sets the name key of tags saved before names were
+// keyed, i.e. rows that have none after migration 000015. When several
+// existing tags of a user differ only in case the oldest gets the key; the
+// others are kept without one, can still be merged into it, and are checked
+// again on every run.
+func BackfillTagNameKeys(db *gorm.DB) error {
+	type row struct {
+		ID     string
+		UserID string
+		Name   string
+	}
+
+	updated, skipped := 0, 0
+	lastID := ""
+	for {
+		var rows []row
+		err := db.Table("tags").
+			Select("id", "user_id", "name").
+			Where("name_key IS NULL AND id > ?", lastID).
+			Order("id").
+			Limit(tagNameKeyBatchSize).
+			Find(&rows).Error
+		if err != nil {
+			return fmt.Errorf("failed to read tags: %w", err)
+		}
+		if len(rows) == 0 {
+			break
+		}
+		lastID = rows[len(rows)-1].ID
+
+		for _, r := range rows {
+			key := models.TagNameKey(r.Name)
+
+			// An older tag of the same name keeps it
+			var taken int64
+			if err := db.Table("tags").
+				Where("user_id = ? AND id <> ?", r.UserID, r.ID).
+				Where("name_key = ? OR (name_key IS NULL AND LOWER(name) = LOWER(?) AND created_at < (SELECT created_at FROM tags WHERE id = ?))", key, r.Name, r.ID).
+				Count(&taken).Error; err != nil {
+				return fmt.Errorf("failed to check name key of tag %s: %w", r.ID, err)
+			}
+			if taken > 0 {
+				skipped++
+				continue
+			}
+
+			if err := db.Table("tags").Where("id = ?", r.ID).Update("name_key", key).Error; err != nil {
+				return fmt.Errorf("failed to set name key of tag %s: %w", r.ID, err)
+			}
+			updated++
+		}
+	}
+
+	if updated > 0 {
+		log.Printf("Set the name key of %d tags, %d differing only in case left without one", updated, skipped)
+	}
+	return nil
+}
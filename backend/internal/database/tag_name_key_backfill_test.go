@@ -0,0 +1,52 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackfillTagNameKeys(t *testing.T) {
+	db := openTestDB(t)
+	migrator, err := NewSchemaMigrator(db)
+	require.NoError(t, err)
+	_, err = migrator.Up(14)
+	require.NoError(t, err)
+
+	// Before migration 000015 names differing in case could be saved twice
+	require.NoError(t, db.Exec(`INSERT INTO users (id, email, name, display_name) VALUES
+		('user-1', 'a@example.com', 'A', 'A'), ('user-2', 'b@example.com', 'B', 'B')`).Error)
+	require.NoError(t, db.Exec(`INSERT INTO tags (id, user_id, name, created_at) VALUES
+		('t3', 'user-1', 'GO', '2024-01-01 00:00:00'),
+		('t1', 'user-1', 'go', '2024-01-02 00:00:00'),
+		('t2', 'user-1', 'Rust', '2024-01-03 00:00:00'),
+		('t4', 'user-2', 'Go', '2024-01-04 00:00:00')`).Error)
+	_, err = migrator.Up(0)
+	require.NoError(t, err)
+
+	require.NoError(t, BackfillTagNameKeys(db))
+
+	keys := map[string]*string{}
+	rows, err := db.Table("tags").Select("id", "name_key").Rows()
+	require.NoError(t, err)
+	for rows.Next() {
+		var id string
+		var key *string
+		require.NoError(t, rows.Scan(&id, &key))
+		keys[id] = key
+	}
+	require.NoError(t, rows.Close())
+
+	// The oldest of the duplicates of user-1 gets the key
+	require.NotNil(t, keys["t3"])
+	assert.Equal(t, "go", *keys["t3"])
+	assert.Nil(t, keys["t1"])
+	require.NotNil(t, keys["t2"])
+	assert.Equal(t, "rust", *keys["t2"])
+	require.NotNil(t, keys["t4"])
+	assert.Equal(t, "go", *keys["t4"])
+
+	// Running again changes nothing
+	require.NoError(t, BackfillTagNameKeys(db))
+}
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/eikuma/stockle/backend/internal/urlnorm"
+	"gorm.io/gorm"
+)
+
+const urlHashBatchSize = 500
+
+// BackfillArticleURLHashes sets the URL hash of articles saved before
+// duplicates were detected by it, i.e. rows that have none after migration
+// 000005. When several existing articles of a user normalize to the same URL
+// only one of them gets the hash; the others are kept without one and are
+// checked again, cheaply, on every run.
+func BackfillArticleURLHashes(db *gorm.DB, normalizer *urlnorm.Normalizer) error {
+	type row struct {
+		ID     string
+		UserID string
+		URL    string
+	}
+
+	updated, skipped := 0, 0
+	lastID := ""
+	for {
+		var rows []row
+		err := db.Table("articles").
+			Select("id", "user_id", "url").
+			Where("url_hash IS NULL AND id > ?", lastID).
+			Order("id").
+			Limit(urlHashBatchSize).
+			Find(&rows).Error
+		if err != nil {
+			return fmt.Errorf("failed to read articles: %w", err)
+		}
+		if len(rows) == 0 {
+			break
+		}
+		lastID = rows[len(rows)-1].ID
+
+		for _, r := range rows {
+			hash, err := normalizer.Key(r.URL)
+			if err != nil {
+				skipped++
+				continue
+			}
+
+			var taken int64
+			if err := db.Table("articles").
+				Where("user_id = ? AND url_hash = ?", r.UserID, hash).
+				Count(&taken).Error; err != nil {
+				return fmt.Errorf("failed to check URL hash of article %s: %w", r.ID, err)
+			}
+			if taken > 0 {
+				skipped++
+				continue
+			}
+
+			if err := db.Table("articles").Where("id = ?", r.ID).Update("url_hash", hash).Error; err != nil {
+				return fmt.Errorf("failed to set URL hash of article %s: %w", r.ID, err)
+			}
+			updated++
+		}
+	}
+
+	if updated > 0 {
+		log.Printf("Set the URL hash of %d articles, %d duplicate or invalid URLs left without one", updated, skipped)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/eikuma/stockle/backend/internal/urlnorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackfillArticleURLHashes(t *testing.T) {
+	db := openTestDB(t)
+	migrator, err := NewSchemaMigrator(db)
+	require.NoError(t, err)
+	_, err = migrator.Up(0)
+	require.NoError(t, err)
+
+	require.NoError(t, db.Exec(`INSERT INTO users (id, email, name, display_name) VALUES
+		('user-1', 'a@example.com', 'A', 'A'), ('user-2', 'b@example.com', 'B', 'B')`).Error)
+	require.NoError(t, db.Exec(`INSERT INTO articles (id, user_id, url, title) VALUES
+		('a1', 'user-1', 'https://example.com/post', 'Post'),
+		('a2', 'user-1', 'http://www.example.com/post/?utm_source=feed', 'Post again'),
+		('a3', 'user-2', 'https://example.com/post', 'Post of another user'),
+		('a4', 'user-1', 'not a url', 'Broken')`).Error)
+
+	normalizer := urlnorm.New()
+	require.NoError(t, BackfillArticleURLHashes(db, normalizer))
+
+	hashes := map[string]*string{}
+	rows, err := db.Table("articles").Select("id", "url_hash").Rows()
+	require.NoError(t, err)
+	for rows.Next() {
+		var id string
+		var hash *string
+		require.NoError(t, rows.Scan(&id, &hash))
+		hashes[id] = hash
+	}
+	require.NoError(t, rows.Close())
+
+	want, err := normalizer.Key("https://example.com/post")
+	require.NoError(t, err)
+
+	// One of the duplicates of user-1 gets the hash, the other keeps none
+	require.NotNil(t, hashes["a3"])
+	assert.Equal(t, want, *hashes["a3"])
+	assert.True(t, (hashes["a1"] == nil) != (hashes["a2"] == nil))
+	assert.Nil(t, hashes["a4"])
+
+	// Running again changes nothing
+	require.NoError(t, BackfillArticleURLHashes(db, normalizer))
+}
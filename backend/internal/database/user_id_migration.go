@@ -0,0 +1,424 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// userIDTables hold a user_id referencing users.id. Tables created before
+// users moved to UUID primary keys have an integer user_id or a varchar(36)
+// one containing the stringified integer ID.
+var userIDTables = []string{"user_sessions", "user_preferences", "articles", "categories", "tags"}
+
+// uuidColumn holds the new ID of each user while the IDs are converted. Its
+// presence tells that a conversion was interrupted.
+const uuidColumn = "uuid"
+
+// foreignKeysTable records the MySQL foreign keys referencing users that are
+// dropped while the IDs are converted, until they are created again
+const foreignKeysTable = "user_id_migration_foreign_keys"
+
+// MigrateUserIDsToUUID converts a users table created with the former
+// auto-increment integer primary key into UUID primary keys, rewriting every
+// user_id that references it. It is a no-op when users.id is already a string.
+//
+// Each step checks whether it already ran, so a conversion that failed half
+// way resumes where it stopped when it is run again. The new IDs are stored
+// in users.uuid until the end, and sessions keep working with the new IDs.
+// On MySQL the foreign keys referencing users are dropped while the columns
+// change type and created again, with the same rules, once the users have
+// their new IDs. MySQL commits DDL implicitly, so take a backup before
+// running this against production data.
+func MigrateUserIDsToUUID(db *gorm.DB) error {
+	switch db.Dialector.Name() {
+	case DriverMySQL:
+		return migrateUserIDsToUUID(db)
+	case DriverSQLite:
+		// Rebuilding users would fail with foreign keys enforced. They can
+		// only be switched off outside a transaction, on one connection.
+		return db.Connection(func(conn *gorm.DB) error {
+			conn = conn.Session(&gorm.Session{})
+			if err := conn.Exec("PRAGMA foreign_keys = OFF").Error; err != nil {
+				return fmt.Errorf("failed to disable foreign keys: %w", err)
+			}
+			defer conn.Exec("PRAGMA foreign_keys = ON")
+			return migrateUserIDsToUUID(conn)
+		})
+	default:
+		// PostgreSQL databases were never created with integer user IDs
+		return nil
+	}
+}
+
+func migrateUserIDsToUUID(db *gorm.DB) error {
+	if !db.Migrator().HasTable("users") {
+		return nil
+	}
+	integerIDs, err := isIntegerColumn(db, "users", "id")
+	if err != nil {
+		return err
+	}
+	resuming := db.Migrator().HasColumn("users", uuidColumn)
+	if !integerIDs && !resuming {
+		return nil
+	}
+
+	if resuming {
+		log.Println("Resuming the migration of integer user IDs to UUIDs")
+	} else {
+		log.Println("Migrating integer user IDs to UUIDs")
+	}
+
+	tables := userIDTables
+	if db.Dialector.Name() == DriverMySQL {
+		if err := dropForeignKeysTo(db, "users"); err != nil {
+			return err
+		}
+		if tables, err = referencingTables(db); err != nil {
+			return err
+		}
+	}
+
+	if !resuming {
+		query := fmt.Sprintf("ALTER TABLE users ADD COLUMN %s VARCHAR(36) NULL", uuidColumn)
+		if err := db.Exec(query).Error; err != nil {
+			return fmt.Errorf("failed to add users.%s: %w", uuidColumn, err)
+		}
+	}
+	if err := assignUUIDs(db); err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		if !db.Migrator().HasTable(table) {
+			continue
+		}
+		integer, err := isIntegerColumn(db, table, "user_id")
+		if err != nil {
+			return err
+		}
+		if integer {
+			if err := retypeColumn(db, table, "user_id", "VARCHAR(36) NOT NULL"); err != nil {
+				return err
+			}
+		}
+	}
+
+	count, err := rewriteUserIDs(db, tables)
+	if err != nil {
+		return err
+	}
+
+	// Move the users over to their new IDs
+	if integerIDs {
+		if err := retypeColumn(db, "users", "id", "VARCHAR(36) NOT NULL"); err != nil {
+			return err
+		}
+	}
+	query := fmt.Sprintf("UPDATE users SET id = %[1]s WHERE %[1]s IS NOT NULL AND id <> %[1]s", uuidColumn)
+	if err := db.Exec(query).Error; err != nil {
+		return fmt.Errorf("failed to set the new user IDs: %w", err)
+	}
+	if db.Dialector.Name() == DriverMySQL {
+		if err := restoreForeignKeys(db); err != nil {
+			return err
+		}
+	}
+	if err := db.Exec(fmt.Sprintf("ALTER TABLE users DROP COLUMN %s", uuidColumn)).Error; err != nil {
+		return fmt.Errorf("failed to drop users.%s: %w", uuidColumn, err)
+	}
+
+	log.Printf("Migrated %d users to UUID IDs", count)
+	return nil
+}
+
+// assignUUIDs gives a new ID to every user that has none yet
+func assignUUIDs(db *gorm.DB) error {
+	var ids []string
+	err := db.Table("users").Where(uuidColumn+" IS NULL").Pluck("CAST(id AS CHAR(36))", &ids).Error
+	if err != nil {
+		return fmt.Errorf("failed to read user IDs: %w", err)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, id := range ids {
+			query := fmt.Sprintf("UPDATE users SET %s = ? WHERE id = ?", uuidColumn)
+			if err := tx.Exec(query, uuid.New().String(), id).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to assign new user IDs: %w", err)
+	}
+	return nil
+}
+
+// rewriteUserIDs points the user_id of every table to the new ID of its user
+// and returns the number of users. Rows already pointing to a new ID match no
+// old ID.
+func rewriteUserIDs(db *gorm.DB, tables []string) (int, error) {
+	var users []struct {
+		ID   string
+		UUID string
+	}
+	err := db.Table("users").Select("CAST(id AS CHAR(36)) AS id", uuidColumn+" AS uuid").Scan(&users).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to read user IDs: %w", err)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, user := range users {
+			if user.ID == user.UUID {
+				continue
+			}
+			for _, table := range tables {
+				if !tx.Migrator().HasTable(table) {
+					continue
+				}
+				query := fmt.Sprintf("UPDATE %s SET user_id = ? WHERE user_id = ?", table)
+				if err := tx.Exec(query, user.UUID, user.ID).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to rewrite user IDs: %w", err)
+	}
+	return len(users), nil
+}
+
+// isIntegerColumn reports whether table.column has an integer type
+func isIntegerColumn(db *gorm.DB, table, column string) (bool, error) {
+	columnTypes, err := db.Migrator().ColumnTypes(table)
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect %s table: %w", table, err)
+	}
+
+	for _, columnType := range columnTypes {
+		if columnType.Name() == column {
+			return strings.Contains(strings.ToLower(columnType.DatabaseTypeName()), "int"), nil
+		}
+	}
+	return false, nil
+}
+
+// retypeColumn changes the type of table.column to definition, keeping its
+// primary key
+func retypeColumn(db *gorm.DB, table, column, definition string) error {
+	if db.Dialector.Name() == DriverSQLite {
+		return rebuildSQLiteTable(db, table, column, definition)
+	}
+	query := fmt.Sprintf("ALTER TABLE %s MODIFY %s %s", table, column, definition)
+	if err := db.Exec(query).Error; err != nil {
+		return fmt.Errorf("failed to execute %q: %w", query, err)
+	}
+	return nil
+}
+
+var sqliteCreateTable = regexp.MustCompile("(?i)^\\s*CREATE\\s+TABLE\\s+(?:IF\\s+NOT\\s+EXISTS\\s+)?[`\"]?\\w+[`\"]?")
+
+// rebuildSQLiteTable copies table into a new one where column has the given
+// definition, since SQLite cannot change the type of a column in place, and
+// recreates the indexes of the table
+func rebuildSQLiteTable(db *gorm.DB, table, column, definition string) error {
+	var ddl string
+	err := db.Raw("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&ddl).Error
+	if err != nil {
+		return fmt.Errorf("failed to read the definition of %s: %w", table, err)
+	}
+	var indexes []string
+	err = db.Raw("SELECT sql FROM sqlite_master WHERE type = 'index' AND tbl_name = ? AND sql IS NOT NULL", table).
+		Scan(&indexes).Error
+	if err != nil {
+		return fmt.Errorf("failed to read the indexes of %s: %w", table, err)
+	}
+
+	start, end, ok := findColumnDefinition(ddl, column)
+	if !ok {
+		return fmt.Errorf("column %s not found in the definition of %s", column, table)
+	}
+	// An integer primary key is an alias of the rowid and declared inline
+	if strings.Contains(strings.ToUpper(ddl[start:end]), "PRIMARY KEY") {
+		definition += " PRIMARY KEY"
+	}
+	temp := table + "__rebuild"
+	ddl = ddl[:start] + "`" + column + "` " + definition + ddl[end:]
+	ddl = sqliteCreateTable.ReplaceAllString(ddl, "CREATE TABLE `"+temp+"`")
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		statements := []string{
+			ddl,
+			fmt.Sprintf("INSERT INTO `%s` SELECT * FROM `%s`", temp, table),
+			fmt.Sprintf("DROP TABLE `%s`", table),
+			fmt.Sprintf("ALTER TABLE `%s` RENAME TO `%s`", temp, table),
+		}
+		if err := execAll(tx, append(statements, indexes...)); err != nil {
+			return fmt.Errorf("failed to rebuild %s: %w", table, err)
+		}
+		return nil
+	})
+}
+
+// findColumnDefinition returns the bounds of the definition of column in a
+// CREATE TABLE statement, from its name up to the comma or parenthesis
+// ending it
+func findColumnDefinition(ddl, column string) (int, int, bool) {
+	name := regexp.MustCompile("(?i)[(,]\\s*([`\"]?" + regexp.QuoteMeta(column) + "[`\"]?)\\s")
+	match := name.FindStringSubmatchIndex(ddl)
+	if match == nil {
+		return 0, 0, false
+	}
+
+	depth := 0
+	for i := match[3]; i < len(ddl); i++ {
+		switch ddl[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return match[2], i, true
+			}
+			depth--
+		case ',':
+			if depth == 0 {
+				return match[2], i, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// foreignKey is a single column foreign key constraint
+type foreignKey struct {
+	TableName        string
+	ConstraintName   string
+	ColumnName       string
+	ReferencedColumn string
+	DeleteRule       string
+	UpdateRule       string
+}
+
+// dropForeignKeysTo records in foreignKeysTable, then drops, every foreign
+// key constraint referencing table. The ones recorded by an interrupted run
+// are kept as they were.
+func dropForeignKeysTo(db *gorm.DB, table string) error {
+	err := db.Exec(`CREATE TABLE IF NOT EXISTS ` + foreignKeysTable + ` (
+		constraint_name VARCHAR(64) NOT NULL PRIMARY KEY,
+		table_name VARCHAR(64) NOT NULL,
+		column_name VARCHAR(64) NOT NULL,
+		referenced_column VARCHAR(64) NOT NULL,
+		delete_rule VARCHAR(20) NOT NULL,
+		update_rule VARCHAR(20) NOT NULL
+	)`).Error
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", foreignKeysTable, err)
+	}
+
+	var constraints []foreignKey
+	err = db.Raw(`
+		SELECT rc.TABLE_NAME AS table_name, rc.CONSTRAINT_NAME AS constraint_name,
+			kcu.COLUMN_NAME AS column_name, kcu.REFERENCED_COLUMN_NAME AS referenced_column,
+			rc.DELETE_RULE AS delete_rule, rc.UPDATE_RULE AS update_rule
+		FROM information_schema.REFERENTIAL_CONSTRAINTS rc
+		JOIN information_schema.KEY_COLUMN_USAGE kcu
+			ON kcu.CONSTRAINT_SCHEMA = rc.CONSTRAINT_SCHEMA
+			AND kcu.TABLE_NAME = rc.TABLE_NAME
+			AND kcu.CONSTRAINT_NAME = rc.CONSTRAINT_NAME
+		WHERE rc.CONSTRAINT_SCHEMA = DATABASE() AND rc.REFERENCED_TABLE_NAME = ?
+	`, table).Scan(&constraints).Error
+	if err != nil {
+		return fmt.Errorf("failed to list foreign keys referencing %s: %w", table, err)
+	}
+
+	for _, c := range constraints {
+		query := "INSERT IGNORE INTO " + foreignKeysTable +
+			" (constraint_name, table_name, column_name, referenced_column, delete_rule, update_rule) VALUES (?, ?, ?, ?, ?, ?)"
+		err := db.Exec(query, c.ConstraintName, c.TableName, c.ColumnName, c.ReferencedColumn, c.DeleteRule, c.UpdateRule).Error
+		if err != nil {
+			return fmt.Errorf("failed to record foreign key %s: %w", c.ConstraintName, err)
+		}
+		query = fmt.Sprintf("ALTER TABLE `%s` DROP FOREIGN KEY `%s`", c.TableName, c.ConstraintName)
+		if err := db.Exec(query).Error; err != nil {
+			return fmt.Errorf("failed to drop foreign key %s: %w", c.ConstraintName, err)
+		}
+	}
+	return nil
+}
+
+// referencingTables returns userIDTables and the other tables whose user_id
+// had a foreign key to users
+func referencingTables(db *gorm.DB) ([]string, error) {
+	var recorded []string
+	err := db.Table(foreignKeysTable).Where("column_name = ?", "user_id").Distinct().Pluck("table_name", &recorded).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", foreignKeysTable, err)
+	}
+
+	tables := append([]string{}, userIDTables...)
+	for _, table := range recorded {
+		known := false
+		for _, t := range tables {
+			known = known || t == table
+		}
+		if !known {
+			tables = append(tables, table)
+		}
+	}
+	return tables, nil
+}
+
+// restoreForeignKeys creates the foreign keys recorded in foreignKeysTable
+// again, skipping the ones that exist, and then drops the record
+func restoreForeignKeys(db *gorm.DB) error {
+	if !db.Migrator().HasTable(foreignKeysTable) {
+		return nil
+	}
+	var constraints []foreignKey
+	if err := db.Table(foreignKeysTable).Scan(&constraints).Error; err != nil {
+		return fmt.Errorf("failed to read %s: %w", foreignKeysTable, err)
+	}
+
+	for _, c := range constraints {
+		var existing int64
+		err := db.Raw(`
+			SELECT COUNT(*) FROM information_schema.TABLE_CONSTRAINTS
+			WHERE CONSTRAINT_SCHEMA = DATABASE() AND TABLE_NAME = ? AND CONSTRAINT_NAME = ?
+				AND CONSTRAINT_TYPE = 'FOREIGN KEY'
+		`, c.TableName, c.ConstraintName).Scan(&existing).Error
+		if err != nil {
+			return fmt.Errorf("failed to look up foreign key %s: %w", c.ConstraintName, err)
+		}
+		if existing > 0 {
+			continue
+		}
+		query := fmt.Sprintf("ALTER TABLE `%s` ADD CONSTRAINT `%s` FOREIGN KEY (`%s`) REFERENCES `users` (`%s`) ON DELETE %s ON UPDATE %s",
+			c.TableName, c.ConstraintName, c.ColumnName, c.ReferencedColumn, c.DeleteRule, c.UpdateRule)
+		if err := db.Exec(query).Error; err != nil {
+			return fmt.Errorf("failed to recreate foreign key %s: %w", c.ConstraintName, err)
+		}
+	}
+
+	if err := db.Migrator().DropTable(foreignKeysTable); err != nil {
+		return fmt.Errorf("failed to drop %s: %w", foreignKeysTable, err)
+	}
+	log.Printf("Recreated %d foreign keys referencing users", len(constraints))
+	return nil
+}
+
+func execAll(db *gorm.DB, statements []string) error {
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return fmt.Errorf("failed to execute %q: %w", statement, err)
+		}
+	}
+	return nil
+}
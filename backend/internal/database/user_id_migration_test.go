@@ -0,0 +1,258 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/eikuma/stockle/backend/internal/config"
+	"github.com/glebarez/sqlite"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openLegacyDB returns a database with the integer user IDs of the schema
+// AutoMigrate created before users moved to UUIDs, with foreign keys enforced
+func openLegacyDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "legacy.db") + "?_pragma=foreign_keys(1)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	require.NoError(t, execAll(db, []string{
+		"CREATE TABLE `users` (`id` integer PRIMARY KEY AUTOINCREMENT,`email` varchar(255) NOT NULL,`name` varchar(255))",
+		"CREATE UNIQUE INDEX `idx_users_email` ON `users`(`email`)",
+		"CREATE TABLE `user_sessions` (`id` varchar(36),`user_id` integer NOT NULL,`token_hash` varchar(500),PRIMARY KEY (`id`)," +
+			"CONSTRAINT `fk_users_sessions` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`))",
+		"CREATE INDEX `idx_user_sessions_user_id` ON `user_sessions`(`user_id`)",
+		"CREATE TABLE `user_preferences` (`user_id` integer PRIMARY KEY,`theme` varchar(10) DEFAULT 'light')",
+		"CREATE TABLE `categories` (`id` varchar(36) PRIMARY KEY,`user_id` varchar(36) NOT NULL,`name` varchar(100))",
+		"CREATE TABLE `articles` (`id` varchar(36) PRIMARY KEY,`user_id` varchar(36) NOT NULL,`title` varchar(500))",
+		"INSERT INTO users (id, email, name) VALUES (1, 'alice@example.com', 'Alice'), (2, 'bob@example.com', 'Bob')",
+		"INSERT INTO user_sessions (id, user_id, token_hash) VALUES ('s1', 1, 'hash-1'), ('s2', 2, 'hash-2')",
+		"INSERT INTO user_preferences (user_id, theme) VALUES (1, 'dark')",
+		"INSERT INTO categories (id, user_id, name) VALUES ('c1', '1', 'Alice'), ('c2', '2', 'Bob')",
+		"INSERT INTO articles (id, user_id, title) VALUES ('a1', '1', 'Post of Alice')",
+	}))
+	return db
+}
+
+// ownersOf returns the email of the user owning each row of table
+func ownersOf(t *testing.T, db *gorm.DB, table, key string) map[string]string {
+	t.Helper()
+
+	var rows []struct {
+		Key   string
+		Email string
+	}
+	err := db.Table(table).
+		Select(table + "." + key + " AS `key`, users.email").
+		Joins("JOIN users ON users.id = " + table + ".user_id").
+		Scan(&rows).Error
+	require.NoError(t, err)
+
+	owners := make(map[string]string, len(rows))
+	for _, row := range rows {
+		owners[row.Key] = row.Email
+	}
+	return owners
+}
+
+func userIDs(t *testing.T, db *gorm.DB) map[string]string {
+	t.Helper()
+
+	var users []struct {
+		ID    string
+		Email string
+	}
+	require.NoError(t, db.Table("users").Select("id", "email").Scan(&users).Error)
+	ids := make(map[string]string, len(users))
+	for _, user := range users {
+		ids[user.Email] = user.ID
+	}
+	return ids
+}
+
+func assertMigratedUserIDs(t *testing.T, db *gorm.DB) map[string]string {
+	t.Helper()
+
+	ids := userIDs(t, db)
+	require.Len(t, ids, 2)
+	for _, id := range ids {
+		_, err := uuid.Parse(id)
+		assert.NoError(t, err, id)
+	}
+	integer, err := isIntegerColumn(db, "users", "id")
+	require.NoError(t, err)
+	assert.False(t, integer)
+	assert.False(t, db.Migrator().HasColumn("users", uuidColumn))
+
+	// Sessions and everything else the users own follow them
+	assert.Equal(t, map[string]string{"s1": "alice@example.com", "s2": "bob@example.com"}, ownersOf(t, db, "user_sessions", "id"))
+	assert.Equal(t, map[string]string{"dark": "alice@example.com"}, ownersOf(t, db, "user_preferences", "theme"))
+	assert.Equal(t, map[string]string{"c1": "alice@example.com", "c2": "bob@example.com"}, ownersOf(t, db, "categories", "id"))
+	assert.Equal(t, map[string]string{"a1": "alice@example.com"}, ownersOf(t, db, "articles", "id"))
+
+	// Indexes and foreign keys survive the rebuilt tables
+	err = db.Exec("INSERT INTO users (id, email) VALUES (?, 'alice@example.com')", uuid.New().String()).Error
+	assert.Error(t, err, "emails stay unique")
+	assert.True(t, db.Migrator().HasIndex("user_sessions", "idx_user_sessions_user_id"))
+	var violations []map[string]interface{}
+	require.NoError(t, db.Raw("PRAGMA foreign_key_check").Scan(&violations).Error)
+	assert.Empty(t, violations)
+	return ids
+}
+
+func TestMigrateUserIDsToUUID(t *testing.T) {
+	db := openLegacyDB(t)
+
+	require.NoError(t, MigrateUserIDsToUUID(db))
+	ids := assertMigratedUserIDs(t, db)
+
+	// Running again changes nothing
+	require.NoError(t, MigrateUserIDsToUUID(db))
+	assert.Equal(t, ids, userIDs(t, db))
+}
+
+func TestMigrateUserIDsToUUID_Resumes(t *testing.T) {
+	db := openLegacyDB(t)
+
+	// A previous run gave Alice her new ID and moved her categories, then
+	// stopped
+	aliceID := uuid.New().String()
+	require.NoError(t, execAll(db, []string{
+		"ALTER TABLE users ADD COLUMN uuid VARCHAR(36) NULL",
+		"UPDATE users SET uuid = '" + aliceID + "' WHERE id = 1",
+		"UPDATE categories SET user_id = '" + aliceID + "' WHERE user_id = '1'",
+	}))
+
+	require.NoError(t, MigrateUserIDsToUUID(db))
+	ids := assertMigratedUserIDs(t, db)
+	assert.Equal(t, aliceID, ids["alice@example.com"])
+}
+
+func TestMigrateUserIDsToUUID_UUIDSchema(t *testing.T) {
+	db := openTestDB(t)
+	migrator, err := NewSchemaMigrator(db)
+	require.NoError(t, err)
+	_, err = migrator.Up(0)
+	require.NoError(t, err)
+	require.NoError(t, db.Exec(`INSERT INTO users (id, email, name, display_name) VALUES ('user-1', 'a@example.com', 'A', 'A')`).Error)
+
+	require.NoError(t, MigrateUserIDsToUUID(db))
+	assert.Equal(t, map[string]string{"a@example.com": "user-1"}, userIDs(t, db))
+}
+
+// openLegacyMySQL returns the legacy schema on the disposable MySQL database
+// of STOCKLE_TEST_MYSQL_URL, or skips the test when it is not set
+func openLegacyMySQL(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	url := os.Getenv("STOCKLE_TEST_MYSQL_URL")
+	if url == "" {
+		t.Skip("STOCKLE_TEST_MYSQL_URL is not set")
+	}
+	db, err := Open(config.DatabaseConfig{Driver: DriverMySQL, URL: url}, logger.Default.LogMode(logger.Silent))
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+
+	dropTables := []string{
+		"DROP TABLE IF EXISTS " + foreignKeysTable,
+		"DROP TABLE IF EXISTS articles",
+		"DROP TABLE IF EXISTS categories",
+		"DROP TABLE IF EXISTS user_preferences",
+		"DROP TABLE IF EXISTS user_sessions",
+		"DROP TABLE IF EXISTS users",
+	}
+	require.NoError(t, execAll(db, dropTables))
+	t.Cleanup(func() {
+		execAll(db, dropTables)
+		sqlDB.Close()
+	})
+
+	require.NoError(t, execAll(db, []string{
+		"CREATE TABLE users (id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY, email VARCHAR(255) NOT NULL UNIQUE, name VARCHAR(255))",
+		"CREATE TABLE user_sessions (id VARCHAR(36) PRIMARY KEY, user_id BIGINT UNSIGNED NOT NULL, token_hash VARCHAR(500)," +
+			" CONSTRAINT fk_users_sessions FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE)",
+		"CREATE TABLE user_preferences (user_id BIGINT UNSIGNED PRIMARY KEY, theme VARCHAR(10) DEFAULT 'light'," +
+			" CONSTRAINT fk_users_preferences FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE ON UPDATE RESTRICT)",
+		"CREATE TABLE categories (id VARCHAR(36) PRIMARY KEY, user_id VARCHAR(36) NOT NULL, name VARCHAR(100))",
+		"CREATE TABLE articles (id VARCHAR(36) PRIMARY KEY, user_id VARCHAR(36) NOT NULL, title VARCHAR(500))",
+		"INSERT INTO users (id, email, name) VALUES (1, 'alice@example.com', 'Alice'), (2, 'bob@example.com', 'Bob')",
+		"INSERT INTO user_sessions (id, user_id, token_hash) VALUES ('s1', 1, 'hash-1'), ('s2', 2, 'hash-2')",
+		"INSERT INTO user_preferences (user_id, theme) VALUES (1, 'dark')",
+		"INSERT INTO categories (id, user_id, name) VALUES ('c1', '1', 'Alice'), ('c2', '2', 'Bob')",
+		"INSERT INTO articles (id, user_id, title) VALUES ('a1', '1', 'Post of Alice')",
+	}))
+	return db
+}
+
+// foreignKeysToUsers lists the foreign keys referencing users with their rules
+func foreignKeysToUsers(t *testing.T, db *gorm.DB) []foreignKey {
+	t.Helper()
+
+	var constraints []foreignKey
+	err := db.Raw(`
+		SELECT TABLE_NAME AS table_name, CONSTRAINT_NAME AS constraint_name,
+			DELETE_RULE AS delete_rule, UPDATE_RULE AS update_rule
+		FROM information_schema.REFERENTIAL_CONSTRAINTS
+		WHERE CONSTRAINT_SCHEMA = DATABASE() AND REFERENCED_TABLE_NAME = 'users'
+		ORDER BY CONSTRAINT_NAME
+	`).Scan(&constraints).Error
+	require.NoError(t, err)
+	return constraints
+}
+
+// assertMySQLForeignKeys checks that the foreign keys referencing users are
+// back with their rules, enforced on the new user IDs
+func assertMySQLForeignKeys(t *testing.T, db *gorm.DB, want []foreignKey) {
+	t.Helper()
+
+	require.Len(t, want, 2)
+	assert.Equal(t, want, foreignKeysToUsers(t, db))
+	assert.False(t, db.Migrator().HasTable(foreignKeysTable))
+
+	err := db.Exec("INSERT INTO user_sessions (id, user_id) VALUES ('orphan', ?)", uuid.New().String()).Error
+	assert.Error(t, err)
+	require.NoError(t, db.Exec("DELETE FROM users WHERE email = 'bob@example.com'").Error)
+	var sessions int64
+	require.NoError(t, db.Table("user_sessions").Where("id = 's2'").Count(&sessions).Error)
+	assert.Zero(t, sessions, "sessions are deleted with their user")
+}
+
+func TestMigrateUserIDsToUUID_MySQL(t *testing.T) {
+	db := openLegacyMySQL(t)
+	constraints := foreignKeysToUsers(t, db)
+
+	require.NoError(t, MigrateUserIDsToUUID(db))
+	ids := userIDs(t, db)
+	require.Len(t, ids, 2)
+	assert.Equal(t, map[string]string{"s1": "alice@example.com", "s2": "bob@example.com"}, ownersOf(t, db, "user_sessions", "id"))
+	assert.Equal(t, map[string]string{"c1": "alice@example.com", "c2": "bob@example.com"}, ownersOf(t, db, "categories", "id"))
+	assertMySQLForeignKeys(t, db, constraints)
+}
+
+func TestMigrateUserIDsToUUID_MySQLResumes(t *testing.T) {
+	db := openLegacyMySQL(t)
+	constraints := foreignKeysToUsers(t, db)
+
+	// A previous run dropped the foreign keys and gave the users their new
+	// IDs, then stopped
+	require.NoError(t, dropForeignKeysTo(db, "users"))
+	require.NoError(t, execAll(db, []string{
+		"ALTER TABLE users ADD COLUMN uuid VARCHAR(36) NULL",
+		"UPDATE users SET uuid = UUID()",
+	}))
+
+	require.NoError(t, MigrateUserIDsToUUID(db))
+	assert.Equal(t, map[string]string{"dark": "alice@example.com"}, ownersOf(t, db, "user_preferences", "theme"))
+	assertMySQLForeignKeys(t, db, constraints)
+}
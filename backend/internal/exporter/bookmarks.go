@@ -0,0 +1,121 @@
+package exporter
+
+import (
+	"bufio"
+	"html"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/eikuma/stockle/backend/internal/models"
+)
+
+// bookmark is what a bookmark file keeps of an article
+type bookmark struct {
+	url     string
+	title   string
+	savedAt int64
+	tags    []string
+}
+
+// bookmarksWriter writes a Netscape bookmark file with a folder for each
+// category. Folders are only complete at the end, so the writer keeps the
+// links, without their content, until Close.
+type bookmarksWriter struct {
+	w          io.Writer
+	opts       Options
+	children   map[string][]*models.Category
+	bookmarks  map[string][]bookmark
+	categories map[string]bool
+}
+
+func newBookmarksWriter(w io.Writer, opts Options) *bookmarksWriter {
+	writer := &bookmarksWriter{
+		w:          w,
+		opts:       opts,
+		children:   make(map[string][]*models.Category),
+		bookmarks:  make(map[string][]bookmark),
+		categories: make(map[string]bool),
+	}
+	for _, category := range opts.Categories {
+		writer.categories[category.ID] = true
+	}
+	for _, category := range opts.Categories {
+		parent := ""
+		if category.ParentID != nil && writer.categories[*category.ParentID] {
+			parent = *category.ParentID
+		}
+		writer.children[parent] = append(writer.children[parent], category)
+	}
+	for _, children := range writer.children {
+		sort.SliceStable(children, func(i, j int) bool {
+			return children[i].DisplayOrder < children[j].DisplayOrder
+		})
+	}
+	return writer
+}
+
+func (b *bookmarksWriter) Write(article *models.Article) error {
+	// Links of unknown categories are written at the top level
+	folder := ""
+	if article.CategoryID != nil && b.categories[*article.CategoryID] {
+		folder = *article.CategoryID
+	}
+	b.bookmarks[folder] = append(b.bookmarks[folder], bookmark{
+		url:     article.URL,
+		title:   article.Title,
+		savedAt: article.SavedAt.Unix(),
+		tags:    tagNames(article),
+	})
+	return nil
+}
+
+func (b *bookmarksWriter) Close() error {
+	w := bufio.NewWriter(b.w)
+	w.WriteString("<!DOCTYPE NETSCAPE-Bookmark-file-1>\n")
+	w.WriteString("<!-- This is an automatically generated file. -->\n")
+	w.WriteString(`<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">` + "\n")
+	w.WriteString("<TITLE>" + html.EscapeString(b.opts.Title) + "</TITLE>\n")
+	w.WriteString("<H1>" + html.EscapeString(b.opts.Title) + "</H1>\n")
+	w.WriteString("<DL><p>\n")
+	b.writeFolder(w, "", 1)
+	w.WriteString("</DL><p>\n")
+	return w.Flush()
+}
+
+// writeFolder writes the subfolders and links of a category, or of the top
+// level for ""
+func (b *bookmarksWriter) writeFolder(w *bufio.Writer, id string, depth int) {
+	indent := strings.Repeat("    ", depth)
+	for _, category := range b.children[id] {
+		if !b.hasBookmarks(category.ID) {
+			continue
+		}
+		w.WriteString(indent + "<DT><H3>" + html.EscapeString(category.Name) + "</H3>\n")
+		w.WriteString(indent + "<DL><p>\n")
+		b.writeFolder(w, category.ID, depth+1)
+		w.WriteString(indent + "</DL><p>\n")
+	}
+	for _, link := range b.bookmarks[id] {
+		w.WriteString(indent + `<DT><A HREF="` + html.EscapeString(link.url) + `" ADD_DATE="` + strconv.FormatInt(link.savedAt, 10) + `"`)
+		if len(link.tags) > 0 {
+			w.WriteString(` TAGS="` + html.EscapeString(strings.Join(link.tags, ",")) + `"`)
+		}
+		w.WriteString(">" + html.EscapeString(link.title) + "</A>\n")
+	}
+}
+
+// hasBookmarks reports whether a category or one of its descendants has
+// links, so that empty folders are left out
+func (b *bookmarksWriter) hasBookmarks(id string) bool {
+	if len(b.bookmarks[id]) > 0 {
+		return true
+	}
+	for _, child := range b.children[id] {
+		if b.hasBookmarks(child.ID) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package exporter
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eikuma/stockle/backend/internal/models"
+)
+
+// csvHeader are the columns of CSV exports. Tags are separated by | as in
+// Pocket exports, and the category is its path separated by /.
+var csvHeader = []string{
+	"id", "url", "title", "category", "tags", "status", "favorite", "reading_progress",
+	"saved_at", "published_at", "author", "site_name", "summary", "content",
+}
+
+type csvWriter struct {
+	w     *csv.Writer
+	paths map[string][]string
+}
+
+func newCSVWriter(w io.Writer, paths map[string][]string) (*csvWriter, error) {
+	writer := &csvWriter{w: csv.NewWriter(w), paths: paths}
+	if err := writer.w.Write(csvHeader); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (c *csvWriter) Write(article *models.Article) error {
+	publishedAt := ""
+	if article.PublishedAt != nil {
+		publishedAt = article.PublishedAt.UTC().Format(time.RFC3339)
+	}
+	return c.w.Write([]string{
+		article.ID,
+		article.URL,
+		article.Title,
+		strings.Join(categoryPath(article, c.paths), "/"),
+		strings.Join(tagNames(article), "|"),
+		article.Status,
+		strconv.FormatBool(article.IsFavorite),
+		strconv.FormatFloat(article.ReadingProgress, 'f', -1, 64),
+		article.SavedAt.UTC().Format(time.RFC3339),
+		publishedAt,
+		deref(article.Author),
+		deref(article.SiteName),
+		summary(article),
+		deref(article.Content),
+	})
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
@@ -0,0 +1,273 @@
+package exporter
+
+import (
+	"archive/zip"
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/eikuma/stockle/backend/internal/models"
+	"github.com/google/uuid"
+	xhtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const epubStyle = `body { font-family: serif; line-height: 1.6; }
+h1 { font-size: 1.4em; margin-bottom: 0.2em; }
+.meta { color: #666; font-size: 0.85em; }
+.summary { border-left: 3px solid #ccc; margin-left: 0; padding-left: 1em; color: #444; }
+pre { white-space: pre-wrap; font-size: 0.85em; }
+`
+
+const epubContainer = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+// epubAttrs are the attributes kept on the elements of article content;
+// the rest are dropped to keep the chapters valid XHTML
+var epubAttrs = map[string]bool{
+	"href": true, "title": true, "alt": true, "colspan": true, "rowspan": true, "start": true,
+}
+
+// epubChapter is an article of the book, kept for the table of contents
+type epubChapter struct {
+	id    string
+	title string
+}
+
+// epubWriter writes an EPUB 3 book with a chapter for each article. The
+// chapters are written as they come; the package document and the tables
+// of contents that list them are written on Close.
+type epubWriter struct {
+	zip      *zip.Writer
+	opts     Options
+	language string
+	chapters []epubChapter
+}
+
+func newEPUBWriter(w io.Writer, opts Options) (*epubWriter, error) {
+	writer := &epubWriter{zip: zip.NewWriter(w), opts: opts}
+
+	// The media type comes first and uncompressed so that readers can
+	// recognize the file
+	mimetype, err := writer.zip.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(mimetype, "application/epub+zip"); err != nil {
+		return nil, err
+	}
+	if err := writer.writeFile("META-INF/container.xml", epubContainer); err != nil {
+		return nil, err
+	}
+	if err := writer.writeFile("OEBPS/style.css", epubStyle); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (e *epubWriter) writeFile(name, content string) error {
+	file, err := e.zip.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(file, content)
+	return err
+}
+
+func (e *epubWriter) Write(article *models.Article) error {
+	if e.language == "" && article.Language != "" {
+		e.language = article.Language
+	}
+	chapter := epubChapter{
+		id:    fmt.Sprintf("article-%04d", len(e.chapters)+1),
+		title: article.Title,
+	}
+	e.chapters = append(e.chapters, chapter)
+
+	file, err := e.zip.Create("OEBPS/text/" + chapter.id + ".xhtml")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	language := html.EscapeString(orDefault(article.Language, "en"))
+	title := html.EscapeString(article.Title)
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" lang="%s" xml:lang="%s">
+<head>
+<meta charset="UTF-8"/>
+<title>%s</title>
+<link rel="stylesheet" type="text/css" href="../style.css"/>
+</head>
+<body>
+<section epub:type="chapter">
+<h1>%s</h1>
+`, language, language, title, title)
+
+	var meta []string
+	for _, part := range []string{deref(article.SiteName), deref(article.Author)} {
+		if part != "" {
+			meta = append(meta, html.EscapeString(part))
+		}
+	}
+	meta = append(meta, `<a href="`+html.EscapeString(article.URL)+`">`+html.EscapeString(article.URL)+`</a>`)
+	fmt.Fprintf(w, "<p class=\"meta\">%s</p>\n", strings.Join(meta, " · "))
+	if s := summary(article); s != "" {
+		fmt.Fprintf(w, "<blockquote class=\"summary\">%s</blockquote>\n", textParagraphs(s))
+	}
+
+	if article.ContentHTML != nil && strings.TrimSpace(*article.ContentHTML) != "" {
+		if err := writeXHTML(w, *article.ContentHTML); err != nil {
+			return err
+		}
+	} else {
+		w.WriteString(textParagraphs(deref(article.Content)))
+	}
+	w.WriteString("\n</section>\n</body>\n</html>\n")
+	return w.Flush()
+}
+
+func (e *epubWriter) Close() error {
+	language := html.EscapeString(orDefault(e.language, "en"))
+	title := html.EscapeString(e.opts.Title)
+
+	var nav, ncx, manifest, spine strings.Builder
+	for i, chapter := range e.chapters {
+		href := "text/" + chapter.id + ".xhtml"
+		chapterTitle := html.EscapeString(orDefault(chapter.title, chapter.id))
+		fmt.Fprintf(&nav, "      <li><a href=\"%s\">%s</a></li>\n", href, chapterTitle)
+		fmt.Fprintf(&ncx, "    <navPoint id=\"nav-%d\" playOrder=\"%d\"><navLabel><text>%s</text></navLabel><content src=\"%s\"/></navPoint>\n",
+			i+1, i+1, chapterTitle, href)
+		fmt.Fprintf(&manifest, "    <item id=\"%s\" href=\"%s\" media-type=\"application/xhtml+xml\"/>\n", chapter.id, href)
+		fmt.Fprintf(&spine, "    <itemref idref=\"%s\"/>\n", chapter.id)
+	}
+
+	err := e.writeFile("OEBPS/nav.xhtml", fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" lang="%s" xml:lang="%s">
+<head>
+<meta charset="UTF-8"/>
+<title>%s</title>
+</head>
+<body>
+  <nav epub:type="toc" id="toc">
+    <h1>%s</h1>
+    <ol>
+%s    </ol>
+  </nav>
+</body>
+</html>
+`, language, language, title, title, nav.String()))
+	if err != nil {
+		return err
+	}
+
+	identifier := "urn:uuid:" + uuid.New().String()
+	err = e.writeFile("OEBPS/toc.ncx", fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/" version="2005-1">
+  <head>
+    <meta name="dtb:uid" content="%s"/>
+  </head>
+  <docTitle><text>%s</text></docTitle>
+  <navMap>
+%s  </navMap>
+</ncx>
+`, identifier, title, ncx.String()))
+	if err != nil {
+		return err
+	}
+
+	err = e.writeFile("OEBPS/content.opf", fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id" xml:lang="%s">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:identifier id="book-id">%s</dc:identifier>
+    <dc:title>%s</dc:title>
+    <dc:language>%s</dc:language>
+    <dc:creator>Stockle</dc:creator>
+    <meta property="dcterms:modified">%s</meta>
+  </metadata>
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+    <item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>
+    <item id="style" href="style.css" media-type="text/css"/>
+%s  </manifest>
+  <spine toc="ncx">
+    <itemref idref="nav"/>
+%s  </spine>
+</package>
+`, language, identifier, title, language, e.opts.ExportedAt.UTC().Format("2006-01-02T15:04:05Z"), manifest.String(), spine.String()))
+	if err != nil {
+		return err
+	}
+	return e.zip.Close()
+}
+
+// writeXHTML writes article content as XHTML. Images are left out since the
+// book does not carry them and readers do not load them from the web.
+func writeXHTML(w io.Writer, source string) error {
+	body := &xhtml.Node{Type: xhtml.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := xhtml.ParseFragment(strings.NewReader(source), body)
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		cleanXHTML(node)
+		if node.Type == xhtml.ElementNode && node.Data == "img" {
+			continue
+		}
+		// Render closes void elements with "/>", as XHTML requires
+		if err := xhtml.Render(w, node); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cleanXHTML drops images and the attributes not in epubAttrs below n
+func cleanXHTML(n *xhtml.Node) {
+	attrs := n.Attr[:0]
+	for _, a := range n.Attr {
+		if epubAttrs[a.Key] && a.Namespace == "" {
+			attrs = append(attrs, a)
+		}
+	}
+	n.Attr = attrs
+
+	for child := n.FirstChild; child != nil; {
+		next := child.NextSibling
+		if child.Type == xhtml.ElementNode && child.Data == "img" {
+			n.RemoveChild(child)
+		} else {
+			cleanXHTML(child)
+		}
+		child = next
+	}
+}
+
+// textParagraphs writes plain text as paragraphs, one per block of lines
+func textParagraphs(text string) string {
+	var b strings.Builder
+	for _, paragraph := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" {
+			continue
+		}
+		lines := strings.Split(html.EscapeString(paragraph), "\n")
+		b.WriteString("<p>" + strings.Join(lines, "<br/>") + "</p>\n")
+	}
+	return b.String()
+}
+
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,191 @@
+// Package exporter writes a user's articles out in formats other tools read:
+//
+//   - JSON with every field of the articles
+//   - CSV with one row per article
+//   - a zip of Markdown files with YAML front matter
+//   - a Netscape bookmark file, which every browser imports
+//   - an EPUB book for e-readers
+//
+// Articles are written one at a time as the caller reads them, so an export
+// never needs the whole library in memory.
+package exporter
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/eikuma/stockle/backend/internal/models"
+)
+
+// Format is the kind of file an export writes
+type Format string
+
+const (
+	FormatJSON      Format = "json"
+	FormatCSV       Format = "csv"
+	FormatMarkdown  Format = "markdown"
+	FormatBookmarks Format = "bookmarks_html"
+	FormatEPUB      Format = "epub"
+)
+
+// Formats lists every supported format
+var Formats = []Format{
+	FormatJSON,
+	FormatCSV,
+	FormatMarkdown,
+	FormatBookmarks,
+	FormatEPUB,
+}
+
+// ErrUnknownFormat is returned for unsupported format names
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// ParseFormat returns the format with the given name
+func ParseFormat(name string) (Format, error) {
+	for _, format := range Formats {
+		if string(format) == name {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnknownFormat, name)
+}
+
+// Extension is the file name extension of the format, without the dot
+func (f Format) Extension() string {
+	switch f {
+	case FormatMarkdown:
+		return "zip"
+	case FormatBookmarks:
+		return "html"
+	default:
+		return string(f)
+	}
+}
+
+// ContentType is the media type of the format
+func (f Format) ContentType() string {
+	switch f {
+	case FormatJSON:
+		return "application/json"
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatMarkdown:
+		return "application/zip"
+	case FormatBookmarks:
+		return "text/html; charset=utf-8"
+	case FormatEPUB:
+		return "application/epub+zip"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// Options describe an export as a whole
+type Options struct {
+	// Title names the export, as the title of an EPUB book or a bookmark file
+	Title      string
+	ExportedAt time.Time
+	// Categories are the user's categories, used to write the full path of
+	// the category of an article
+	Categories []*models.Category
+}
+
+// Writer writes the articles of an export
+type Writer interface {
+	// Write adds an article, which must have its category and tags loaded
+	Write(article *models.Article) error
+	// Close finishes the file; it does not close the underlying writer
+	Close() error
+}
+
+// NewWriter returns a writer of the format that writes to w
+func NewWriter(format Format, w io.Writer, opts Options) (Writer, error) {
+	if opts.Title == "" {
+		opts.Title = "Stockle"
+	}
+	if opts.ExportedAt.IsZero() {
+		opts.ExportedAt = time.Now()
+	}
+	paths := categoryPaths(opts.Categories)
+
+	switch format {
+	case FormatJSON:
+		return newJSONWriter(w, opts, paths), nil
+	case FormatCSV:
+		return newCSVWriter(w, paths)
+	case FormatMarkdown:
+		return newMarkdownWriter(w, paths), nil
+	case FormatBookmarks:
+		return newBookmarksWriter(w, opts), nil
+	case FormatEPUB:
+		return newEPUBWriter(w, opts)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+	}
+}
+
+// categoryPaths maps each category to the names from the top-level
+// category down to it
+func categoryPaths(categories []*models.Category) map[string][]string {
+	byID := make(map[string]*models.Category, len(categories))
+	for _, category := range categories {
+		byID[category.ID] = category
+	}
+
+	paths := make(map[string][]string, len(categories))
+	for _, category := range categories {
+		var path []string
+		// The depth bound stops at a cycle, which the repository prevents
+		for current := category; current != nil && len(path) <= len(categories); {
+			path = append([]string{current.Name}, path...)
+			if current.ParentID == nil {
+				break
+			}
+			current = byID[*current.ParentID]
+		}
+		paths[category.ID] = path
+	}
+	return paths
+}
+
+// categoryPath returns the path of the category of an article, or nil
+func categoryPath(article *models.Article, paths map[string][]string) []string {
+	if article.CategoryID == nil {
+		return nil
+	}
+	if path, ok := paths[*article.CategoryID]; ok {
+		return path
+	}
+	if article.Category != nil {
+		return []string{article.Category.Name}
+	}
+	return nil
+}
+
+func tagNames(article *models.Article) []string {
+	names := make([]string, 0, len(article.Tags))
+	for _, tag := range article.Tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
+
+// summary returns the longest summary of an article
+func summary(article *models.Article) string {
+	for _, s := range []*string{article.SummaryLong, article.Summary, article.SummaryShort} {
+		if s != nil && strings.TrimSpace(*s) != "" {
+			return strings.TrimSpace(*s)
+		}
+	}
+	return ""
+}
+
+func deref(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
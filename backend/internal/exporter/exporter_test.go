@@ -0,0 +1,262 @@
+package exporter
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eikuma/stockle/backend/internal/importer"
+	"github.com/eikuma/stockle/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func strPtr(s string) *string { return &s }
+
+var (
+	tech = &models.Category{ID: "tech", Name: "Tech", DisplayOrder: 1}
+	web  = &models.Category{ID: "web", Name: "Web", ParentID: strPtr("tech")}
+	home = &models.Category{ID: "home", Name: "Home & Garden", DisplayOrder: 2}
+)
+
+func testArticles() []*models.Article {
+	saved := time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)
+	return []*models.Article{
+		{
+			ID:          "a1",
+			URL:         "https://go.dev/blog/pipelines",
+			Title:       "Go Concurrency Patterns: Pipelines",
+			CategoryID:  strPtr("web"),
+			Category:    web,
+			Tags:        []models.Tag{{Name: "go"}, {Name: "concurrency"}},
+			Status:      models.ArticleStatusRead,
+			IsFavorite:  true,
+			SavedAt:     saved,
+			Language:    "en",
+			Author:      strPtr("Sameer Ajmani"),
+			Summary:     strPtr("How to build pipelines with channels."),
+			Content:     strPtr("Go's concurrency primitives make pipelines easy."),
+			ContentHTML: strPtr(`<h2>Pipelines</h2><p>Go's <em>concurrency</em> primitives make <a href="https://go.dev/">pipelines</a> easy.</p><img src="https://go.dev/gopher.png" alt="gopher"><pre><code class="language-go">c := make(chan int)</code></pre><ul><li>one</li><li>two<br>lines</li></ul>`),
+		},
+		{
+			ID:         "a2",
+			URL:        "https://example.com/garden?a=1&b=2",
+			Title:      "庭づくり, \"basics\"",
+			CategoryID: strPtr("home"),
+			Status:     models.ArticleStatusUnread,
+			SavedAt:    saved.Add(time.Hour),
+			Language:   "ja",
+			Content:    strPtr("最初の段落\n\n次の段落"),
+		},
+		{
+			ID:      "a3",
+			URL:     "https://example.com/loose",
+			Title:   "Go Concurrency Patterns: Pipelines",
+			Status:  models.ArticleStatusArchived,
+			SavedAt: saved,
+		},
+	}
+}
+
+func export(t *testing.T, format Format) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer, err := NewWriter(format, &buf, Options{
+		Title:      "My articles",
+		ExportedAt: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+		Categories: []*models.Category{tech, web, home},
+	})
+	require.NoError(t, err)
+	for _, article := range testArticles() {
+		require.NoError(t, writer.Write(article))
+	}
+	require.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+func TestJSON(t *testing.T) {
+	var out struct {
+		ExportedAt time.Time  `json:"exportedAt"`
+		Articles   []document `json:"articles"`
+	}
+	require.NoError(t, json.Unmarshal(export(t, FormatJSON), &out))
+
+	require.Len(t, out.Articles, 3)
+	first := out.Articles[0]
+	assert.Equal(t, "a1", first.ID)
+	assert.Equal(t, []string{"Tech", "Web"}, first.Category)
+	assert.Equal(t, []string{"go", "concurrency"}, first.Tags)
+	assert.True(t, first.IsFavorite)
+	assert.Equal(t, "How to build pipelines with channels.", first.Summary)
+	assert.NotEmpty(t, first.ContentHTML)
+	assert.Empty(t, out.Articles[2].Category)
+
+	// An empty export is still a valid document
+	var buf bytes.Buffer
+	writer, err := NewWriter(FormatJSON, &buf, Options{})
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &out))
+	assert.Empty(t, out.Articles)
+}
+
+func TestCSV(t *testing.T) {
+	records, err := csv.NewReader(bytes.NewReader(export(t, FormatCSV))).ReadAll()
+	require.NoError(t, err)
+
+	require.Len(t, records, 4)
+	assert.Equal(t, csvHeader, records[0])
+	assert.Equal(t, []string{"a1", "https://go.dev/blog/pipelines", "Go Concurrency Patterns: Pipelines", "Tech/Web", "go|concurrency", "read", "true", "0",
+		"2024-05-01T09:30:00Z", "", "Sameer Ajmani", "", "How to build pipelines with channels.", "Go's concurrency primitives make pipelines easy."}, records[1])
+	assert.Equal(t, `庭づくり, "basics"`, records[2][2])
+}
+
+func TestMarkdown(t *testing.T) {
+	data := export(t, FormatMarkdown)
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+
+	var names []string
+	files := map[string]string{}
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+		r, err := file.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(r)
+		require.NoError(t, err)
+		files[file.Name] = string(content)
+	}
+	assert.Equal(t, []string{
+		"2024-05-01-go-concurrency-patterns-pipelines.md",
+		"2024-05-01-庭づくり-basics.md",
+		"2024-05-01-go-concurrency-patterns-pipelines-2.md",
+	}, names)
+
+	content := files[names[0]]
+	require.True(t, strings.HasPrefix(content, "---\n"))
+	parts := strings.SplitN(content, "---\n", 3)
+	require.Len(t, parts, 3)
+	var header frontMatter
+	require.NoError(t, yaml.Unmarshal([]byte(parts[1]), &header))
+	assert.Equal(t, "Go Concurrency Patterns: Pipelines", header.Title)
+	assert.Equal(t, "Tech/Web", header.Category)
+	assert.Equal(t, []string{"go", "concurrency"}, header.Tags)
+	assert.True(t, header.Favorite)
+	assert.Equal(t, "# Go Concurrency Patterns: Pipelines\n\n"+
+		"## Pipelines\n\n"+
+		"Go's *concurrency* primitives make [pipelines](https://go.dev/) easy.\n\n"+
+		"![gopher](https://go.dev/gopher.png)\n\n"+
+		"```go\nc := make(chan int)\n```\n\n"+
+		"- one\n- two  \n  lines\n", strings.TrimLeft(parts[2], "\n"))
+
+	// Articles without HTML keep their text
+	assert.Contains(t, files[names[1]], "最初の段落\n\n次の段落\n")
+}
+
+func TestHTMLToMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{name: "escapes", html: "<p>2 * 3 = [six]</p>", want: `2 \* 3 = \[six\]`},
+		{name: "nested list", html: "<ol start=\"3\"><li>a<ul><li>b</li></ul></li><li>c</li></ol>", want: "3. a\n\n   - b\n4. c"},
+		{name: "quote", html: "<blockquote><p>one</p><p>two</p></blockquote>", want: "> one\n>\n> two"},
+		{name: "table", html: "<table><tr><th>a</th><th>b</th></tr><tr><td>1|2</td></tr></table>", want: "| a | b |\n| --- | --- |\n| 1\\|2 |  |"},
+		{name: "code with backticks", html: "<p><code>a`b</code></p>", want: "``a`b``"},
+		{name: "inline space", html: "<p>very<strong> bold </strong>text</p>", want: "very **bold** text"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, htmlToMarkdown(tt.html))
+		})
+	}
+}
+
+func TestBookmarks_RoundTrip(t *testing.T) {
+	data := export(t, FormatBookmarks)
+
+	// The importer reads the file back with the folders and tags
+	format, err := importer.Detect("bookmarks.html", data)
+	require.NoError(t, err)
+	items, err := importer.Parse(format, bytes.NewReader(data))
+	require.NoError(t, err)
+
+	require.Len(t, items, 3)
+	assert.Equal(t, "https://go.dev/blog/pipelines", items[0].URL)
+	assert.Equal(t, []string{"Tech", "Web"}, items[0].Folders)
+	assert.Equal(t, []string{"go", "concurrency"}, items[0].Tags)
+	assert.Equal(t, time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC), items[0].SavedAt.UTC())
+	assert.Equal(t, "https://example.com/garden?a=1&b=2", items[1].URL)
+	assert.Equal(t, []string{"Home & Garden"}, items[1].Folders)
+	assert.Equal(t, `庭づくり, "basics"`, items[1].Title)
+	assert.Empty(t, items[2].Folders)
+}
+
+func TestEPUB(t *testing.T) {
+	data := export(t, FormatEPUB)
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+
+	require.NotEmpty(t, archive.File)
+	assert.Equal(t, "mimetype", archive.File[0].Name)
+	assert.Equal(t, zip.Store, archive.File[0].Method)
+
+	files := map[string]string{}
+	for _, file := range archive.File {
+		r, err := file.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(r)
+		require.NoError(t, err)
+		files[file.Name] = string(content)
+	}
+	assert.Equal(t, "application/epub+zip", files["mimetype"])
+	assert.Contains(t, files, "META-INF/container.xml")
+
+	// Every XML file of the book must be well-formed
+	for name, content := range files {
+		if strings.HasSuffix(name, ".xhtml") || strings.HasSuffix(name, ".opf") || strings.HasSuffix(name, ".ncx") || strings.HasSuffix(name, ".xml") {
+			decoder := xml.NewDecoder(strings.NewReader(content))
+			decoder.Strict = true
+			decoder.Entity = xml.HTMLEntity
+			for {
+				_, err := decoder.Token()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err, "%s:\n%s", name, content)
+			}
+		}
+	}
+
+	opf := files["OEBPS/content.opf"]
+	assert.Contains(t, opf, "<dc:title>My articles</dc:title>")
+	assert.Contains(t, opf, "<dc:language>en</dc:language>")
+	assert.Contains(t, opf, `<itemref idref="article-0003"/>`)
+	assert.Contains(t, files["OEBPS/nav.xhtml"], `<a href="text/article-0002.xhtml">庭づくり, &#34;basics&#34;</a>`)
+
+	chapter := files["OEBPS/text/article-0001.xhtml"]
+	assert.Contains(t, chapter, `<a href="https://go.dev/">pipelines</a>`)
+	assert.Contains(t, chapter, "<li>two<br/>lines</li>")
+	assert.NotContains(t, chapter, "<img")
+	assert.NotContains(t, chapter, `class="language-go"`)
+	assert.Contains(t, files["OEBPS/text/article-0002.xhtml"], "<p>最初の段落</p>\n<p>次の段落</p>")
+}
+
+func TestParseFormat(t *testing.T) {
+	format, err := ParseFormat("epub")
+	require.NoError(t, err)
+	assert.Equal(t, "epub", format.Extension())
+	assert.Equal(t, "zip", FormatMarkdown.Extension())
+	assert.Equal(t, "application/epub+zip", FormatEPUB.ContentType())
+
+	_, err = ParseFormat("pdf")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
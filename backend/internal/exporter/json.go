@@ -0,0 +1,118 @@
+package exporter
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/eikuma/stockle/backend/internal/models"
+)
+
+// document is an article as written to JSON exports
+type document struct {
+	ID              string     `json:"id"`
+	URL             string     `json:"url"`
+	CanonicalURL    string     `json:"canonicalUrl,omitempty"`
+	Title           string     `json:"title"`
+	Author          string     `json:"author,omitempty"`
+	SiteName        string     `json:"siteName,omitempty"`
+	Language        string     `json:"language,omitempty"`
+	PublishedAt     *time.Time `json:"publishedAt,omitempty"`
+	SavedAt         time.Time  `json:"savedAt"`
+	Status          string     `json:"status"`
+	IsFavorite      bool       `json:"isFavorite"`
+	ReadingProgress float64    `json:"readingProgress"`
+	// Category is the path of the category, from the top level down
+	Category     []string `json:"category,omitempty"`
+	Tags         []string `json:"tags"`
+	Keywords     []string `json:"keywords,omitempty"`
+	SummaryShort string   `json:"summaryShort,omitempty"`
+	Summary      string   `json:"summary,omitempty"`
+	SummaryLong  string   `json:"summaryLong,omitempty"`
+	Content      string   `json:"content,omitempty"`
+	ContentHTML  string   `json:"contentHtml,omitempty"`
+}
+
+func newDocument(article *models.Article, paths map[string][]string) *document {
+	return &document{
+		ID:              article.ID,
+		URL:             article.URL,
+		CanonicalURL:    deref(article.CanonicalURL),
+		Title:           article.Title,
+		Author:          deref(article.Author),
+		SiteName:        deref(article.SiteName),
+		Language:        article.Language,
+		PublishedAt:     article.PublishedAt,
+		SavedAt:         article.SavedAt,
+		Status:          article.Status,
+		IsFavorite:      article.IsFavorite,
+		ReadingProgress: article.ReadingProgress,
+		Category:        categoryPath(article, paths),
+		Tags:            tagNames(article),
+		Keywords:        article.Keywords,
+		SummaryShort:    deref(article.SummaryShort),
+		Summary:         deref(article.Summary),
+		SummaryLong:     deref(article.SummaryLong),
+		Content:         deref(article.Content),
+		ContentHTML:     deref(article.ContentHTML),
+	}
+}
+
+// jsonWriter writes an object with the articles in an array, one per line:
+//
+//	{"exportedAt":"...","articles":[
+//	{"id":"...",...},
+//	{"id":"...",...}
+//	]}
+type jsonWriter struct {
+	w       *bufio.Writer
+	opts    Options
+	paths   map[string][]string
+	started bool
+	count   int
+}
+
+func newJSONWriter(w io.Writer, opts Options, paths map[string][]string) *jsonWriter {
+	return &jsonWriter{w: bufio.NewWriter(w), opts: opts, paths: paths}
+}
+
+func (j *jsonWriter) start() error {
+	if j.started {
+		return nil
+	}
+	j.started = true
+	exportedAt, err := json.Marshal(j.opts.ExportedAt.UTC())
+	if err != nil {
+		return err
+	}
+	_, err = j.w.WriteString(`{"exportedAt":` + string(exportedAt) + `,"articles":[`)
+	return err
+}
+
+func (j *jsonWriter) Write(article *models.Article) error {
+	if err := j.start(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(newDocument(article, j.paths))
+	if err != nil {
+		return err
+	}
+	if j.count > 0 {
+		j.w.WriteByte(',')
+	}
+	j.count++
+	j.w.WriteByte('\n')
+	_, err = j.w.Write(data)
+	return err
+}
+
+func (j *jsonWriter) Close() error {
+	if err := j.start(); err != nil {
+		return err
+	}
+	if _, err := j.w.WriteString("\n]}\n"); err != nil {
+		return err
+	}
+	return j.w.Flush()
+}
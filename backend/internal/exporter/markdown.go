@@ -0,0 +1,140 @@
+package exporter
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/eikuma/stockle/backend/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// maxSlugLength bounds the part of a Markdown file name taken from the title
+const maxSlugLength = 60
+
+// frontMatter is the YAML header of an exported Markdown file. The keys are
+// the ones static site generators and note-taking apps read.
+type frontMatter struct {
+	Title           string     `yaml:"title"`
+	URL             string     `yaml:"url"`
+	Author          string     `yaml:"author,omitempty"`
+	Site            string     `yaml:"site,omitempty"`
+	Published       *time.Time `yaml:"published,omitempty"`
+	Saved           time.Time  `yaml:"saved"`
+	Status          string     `yaml:"status"`
+	Favorite        bool       `yaml:"favorite"`
+	ReadingProgress float64    `yaml:"reading_progress"`
+	Category        string     `yaml:"category,omitempty"`
+	Tags            []string   `yaml:"tags,omitempty"`
+	Summary         string     `yaml:"summary,omitempty"`
+	ID              string     `yaml:"id"`
+}
+
+// markdownWriter writes a zip with one Markdown file per article, named
+// after the date it was saved and its title
+type markdownWriter struct {
+	zip   *zip.Writer
+	paths map[string][]string
+	names map[string]bool
+}
+
+func newMarkdownWriter(w io.Writer, paths map[string][]string) *markdownWriter {
+	return &markdownWriter{
+		zip:   zip.NewWriter(w),
+		paths: paths,
+		names: make(map[string]bool),
+	}
+}
+
+func (m *markdownWriter) Write(article *models.Article) error {
+	header, err := yaml.Marshal(frontMatter{
+		Title:           article.Title,
+		URL:             article.URL,
+		Author:          deref(article.Author),
+		Site:            deref(article.SiteName),
+		Published:       utc(article.PublishedAt),
+		Saved:           article.SavedAt.UTC(),
+		Status:          article.Status,
+		Favorite:        article.IsFavorite,
+		ReadingProgress: article.ReadingProgress,
+		Category:        strings.Join(categoryPath(article, m.paths), "/"),
+		Tags:            tagNames(article),
+		Summary:         summary(article),
+		ID:              article.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	body := deref(article.Content)
+	if article.ContentHTML != nil && strings.TrimSpace(*article.ContentHTML) != "" {
+		body = htmlToMarkdown(*article.ContentHTML)
+	}
+
+	file, err := m.zip.CreateHeader(&zip.FileHeader{
+		Name:     m.fileName(article),
+		Method:   zip.Deflate,
+		Modified: article.SavedAt,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(file, "---\n%s---\n\n# %s\n\n%s\n", header, article.Title, strings.TrimSpace(body))
+	return err
+}
+
+func (m *markdownWriter) Close() error {
+	return m.zip.Close()
+}
+
+// fileName returns a name no other file of the zip has, such as
+// 2024-05-01-go-concurrency-patterns.md
+func (m *markdownWriter) fileName(article *models.Article) string {
+	slug := slugify(article.Title)
+	if slug == "" {
+		slug = article.ID
+	}
+	base := article.SavedAt.UTC().Format("2006-01-02") + "-" + slug
+	name := base + ".md"
+	for n := 2; m.names[name]; n++ {
+		name = fmt.Sprintf("%s-%d.md", base, n)
+	}
+	m.names[name] = true
+	return name
+}
+
+// slugify lowercases a title and joins its words with hyphens. Letters of
+// every script are kept, so Japanese titles stay readable.
+func slugify(title string) string {
+	var b strings.Builder
+	count := 0
+	hyphen := false
+	for _, r := range strings.ToLower(title) {
+		if count >= maxSlugLength {
+			break
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+				count++
+			}
+			hyphen = false
+			b.WriteRune(r)
+			count++
+		} else {
+			hyphen = true
+		}
+	}
+	return b.String()
+}
+
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
@@ -0,0 +1,308 @@
+package exporter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// markdownBlocks are the elements converted as blocks; everything else is
+// inline
+var markdownBlocks = map[string]bool{
+	"p": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"pre": true, "blockquote": true, "ul": true, "ol": true, "li": true, "hr": true,
+	"table": true, "figure": true, "figcaption": true, "dl": true, "dt": true, "dd": true,
+	"div": true, "section": true, "article": true, "caption": true,
+}
+
+// markdownEscaper escapes the characters that would start Markdown syntax
+// inside text
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`,
+)
+
+// htmlToMarkdown converts the sanitized content of an article, which only
+// has the elements the extractor keeps, to Markdown
+func htmlToMarkdown(source string) string {
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(source), body)
+	if err != nil {
+		return ""
+	}
+	for _, node := range nodes {
+		body.AppendChild(node)
+	}
+	return markdownChildren(body)
+}
+
+// markdownChildren converts the children of n, joining blocks with blank
+// lines. Runs of inline nodes between blocks become paragraphs.
+func markdownChildren(n *html.Node) string {
+	var parts []string
+	var inline strings.Builder
+	flush := func() {
+		if text := strings.TrimSpace(inline.String()); text != "" {
+			parts = append(parts, text)
+		}
+		inline.Reset()
+	}
+
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && markdownBlocks[child.Data] {
+			flush()
+			if block := markdownBlock(child); block != "" {
+				parts = append(parts, block)
+			}
+			continue
+		}
+		inline.WriteString(markdownInline(child))
+	}
+	flush()
+	return strings.Join(parts, "\n\n")
+}
+
+func markdownBlock(n *html.Node) string {
+	switch n.Data {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		level := int(n.Data[1] - '0')
+		return strings.Repeat("#", level) + " " + strings.TrimSpace(inlineChildren(n))
+	case "pre":
+		return markdownCode(n)
+	case "blockquote":
+		return prefixLines(markdownChildren(n), "> ", "> ")
+	case "ul", "ol":
+		return markdownList(n)
+	case "hr":
+		return "---"
+	case "table":
+		return markdownTable(n)
+	case "figcaption", "caption":
+		if text := strings.TrimSpace(inlineChildren(n)); text != "" {
+			return "*" + text + "*"
+		}
+		return ""
+	case "dt":
+		if text := strings.TrimSpace(inlineChildren(n)); text != "" {
+			return "**" + text + "**"
+		}
+		return ""
+	default:
+		return markdownChildren(n)
+	}
+}
+
+// markdownCode writes a fenced code block, with the language of a
+// "language-go" class
+func markdownCode(n *html.Node) string {
+	code := strings.TrimRight(textContent(n), "\n")
+	language := ""
+	for node := n; node != nil; node = node.FirstChild {
+		for _, class := range strings.Fields(attr(node, "class")) {
+			if strings.HasPrefix(class, "language-") {
+				language = strings.TrimPrefix(class, "language-")
+			}
+		}
+		if node.Type != html.ElementNode {
+			break
+		}
+	}
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	return fence + language + "\n" + code + "\n" + fence
+}
+
+func markdownList(n *html.Node) string {
+	number := 1
+	if start, err := strconv.Atoi(attr(n, "start")); err == nil {
+		number = start
+	}
+
+	var items []string
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode || child.Data != "li" {
+			continue
+		}
+		marker := "- "
+		if n.Data == "ol" {
+			marker = fmt.Sprintf("%d. ", number)
+			number++
+		}
+		content := markdownChildren(child)
+		items = append(items, prefixLines(content, marker, strings.Repeat(" ", len(marker))))
+	}
+	return strings.Join(items, "\n")
+}
+
+func markdownTable(n *html.Node) string {
+	var rows [][]string
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			switch child.Data {
+			case "tr":
+				var cells []string
+				for cell := child.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type == html.ElementNode && (cell.Data == "td" || cell.Data == "th") {
+						text := strings.TrimSpace(strings.ReplaceAll(inlineChildren(cell), "\n", " "))
+						cells = append(cells, strings.ReplaceAll(text, "|", `\|`))
+					}
+				}
+				rows = append(rows, cells)
+			case "thead", "tbody", "tfoot":
+				walk(child)
+			}
+		}
+	}
+	walk(n)
+	if len(rows) == 0 {
+		return ""
+	}
+
+	columns := 0
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+	var b strings.Builder
+	for i, row := range rows {
+		for len(row) < columns {
+			row = append(row, "")
+		}
+		b.WriteString("| " + strings.Join(row, " | ") + " |\n")
+		if i == 0 {
+			b.WriteString("|" + strings.Repeat(" --- |", columns) + "\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func inlineChildren(n *html.Node) string {
+	var b strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		b.WriteString(markdownInline(child))
+	}
+	return b.String()
+}
+
+func markdownInline(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return markdownEscaper.Replace(collapseSpace(n.Data))
+	case html.ElementNode:
+	default:
+		return ""
+	}
+
+	switch n.Data {
+	case "a":
+		text := strings.TrimSpace(inlineChildren(n))
+		href := attr(n, "href")
+		if href == "" {
+			return text
+		}
+		if text == "" {
+			text = href
+		}
+		return "[" + text + "](" + markdownURL(href) + ")"
+	case "img":
+		return "![" + markdownEscaper.Replace(attr(n, "alt")) + "](" + markdownURL(attr(n, "src")) + ")"
+	case "strong", "b":
+		return wrapInline(inlineChildren(n), "**")
+	case "em", "i", "cite":
+		return wrapInline(inlineChildren(n), "*")
+	case "del", "s":
+		return wrapInline(inlineChildren(n), "~~")
+	case "code", "kbd", "samp":
+		code := textContent(n)
+		fence := "`"
+		for strings.Contains(code, fence) {
+			fence += "`"
+		}
+		return fence + code + fence
+	case "br":
+		return "  \n"
+	default:
+		return inlineChildren(n)
+	}
+}
+
+// wrapInline marks text as emphasized, keeping surrounding spaces outside
+// the markers where Markdown requires them
+func wrapInline(text, marker string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	leading := text[:strings.Index(text, trimmed)]
+	trailing := text[len(leading)+len(trimmed):]
+	return leading + marker + trimmed + marker + trailing
+}
+
+// markdownURL keeps a URL from ending the link it is in
+func markdownURL(url string) string {
+	return strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29").Replace(url)
+}
+
+// prefixLines prefixes the first line with first and the others with rest
+func prefixLines(text, first, rest string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		prefix := rest
+		if i == 0 {
+			prefix = first
+		}
+		if line == "" {
+			lines[i] = strings.TrimRight(prefix, " ")
+		} else {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func collapseSpace(text string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range text {
+		if r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f' {
+			space = true
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+	if space {
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		b.WriteString(textContent(child))
+	}
+	return b.String()
+}
+
+func attr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
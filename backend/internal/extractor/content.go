@@ -0,0 +1,151 @@
+// Package extractor turns fetched HTML pages into article content
+package extractor
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// MaxContentRunes bounds the text kept from one article, both in Text and in
+// the text nodes of HTML
+const MaxContentRunes = 100000
+
+// Content is the main content of a page
+type Content struct {
+	// HTML is sanitized markup that keeps headings, lists, code blocks,
+	// tables and images with absolute URLs
+	HTML string
+	// Text is the plain text of HTML with paragraphs separated by blank lines
+	Text               string
+	WordCount          int
+	ReadingTimeSeconds int
+}
+
+// Page is the article body on one page of an article split across pages
+type Page struct {
+	Selection *goquery.Selection
+	URL       *url.URL
+}
+
+// FromSelection sanitizes a selection already known to be the article body,
+// resolving links and images against pageURL
+func FromSelection(sel *goquery.Selection, pageURL *url.URL) *Content {
+	return FromPages(Page{Selection: sel, URL: pageURL})
+}
+
+// FromPages sanitizes the article body of every page in order and joins them
+// into one content, resolving URLs against the page each part came from
+func FromPages(pages ...Page) *Content {
+	parts := make([]part, 0, len(pages))
+	for _, page := range pages {
+		parts = append(parts, part{nodes: page.Selection.Nodes, base: page.URL})
+	}
+	return newContent(parts...)
+}
+
+// part is a list of nodes sharing a base URL
+type part struct {
+	nodes []*html.Node
+	base  *url.URL
+}
+
+func newContent(parts ...part) *Content {
+	s := &sanitizer{budget: MaxContentRunes}
+	root := s.sanitize(parts)
+
+	text := renderText(root)
+	return &Content{
+		HTML:               renderHTML(root),
+		Text:               text,
+		WordCount:          WordCount(text),
+		ReadingTimeSeconds: int(ReadingTime(text).Seconds()),
+	}
+}
+
+// renderHTML writes the children of root with one top-level block per line
+func renderHTML(root *html.Node) string {
+	var b strings.Builder
+	for child := root.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.TextNode && strings.TrimSpace(child.Data) == "" {
+			continue
+		}
+		if err := html.Render(&b, child); err != nil {
+			continue
+		}
+		b.WriteByte('\n')
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// textBreaks is the number of newlines written around each block element
+var textBreaks = map[string]int{
+	"p": 2, "h2": 2, "h3": 2, "h4": 2, "h5": 2, "h6": 2, "pre": 2, "blockquote": 2,
+	"ul": 2, "ol": 2, "dl": 2, "table": 2, "figure": 2, "hr": 2,
+	"li": 1, "dt": 1, "dd": 1, "tr": 1, "br": 1, "figcaption": 1, "caption": 1,
+}
+
+// textWriter joins text runs, collapsing consecutive block breaks
+type textWriter struct {
+	b       strings.Builder
+	pending int
+}
+
+func (w *textWriter) brk(n int) {
+	if n > w.pending {
+		w.pending = n
+	}
+}
+
+func (w *textWriter) write(text string) {
+	if w.pending > 0 {
+		// Whitespace between blocks is not content
+		text = strings.TrimLeft(text, " ")
+	}
+	if text == "" {
+		return
+	}
+	if w.b.Len() > 0 && w.pending > 0 {
+		// Code blocks may already end with a newline
+		written := w.b.String()
+		trailing := len(written) - len(strings.TrimRight(written, "\n"))
+		if w.pending > trailing {
+			w.b.WriteString(strings.Repeat("\n", w.pending-trailing))
+		}
+	}
+	w.pending = 0
+	w.b.WriteString(text)
+}
+
+func renderText(root *html.Node) string {
+	w := &textWriter{}
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		switch node.Type {
+		case html.TextNode:
+			w.write(node.Data)
+			return
+		case html.ElementNode:
+		default:
+			return
+		}
+
+		breaks := textBreaks[node.Data]
+		w.brk(breaks)
+		if node.Data == "td" || node.Data == "th" {
+			if node.PrevSibling != nil {
+				w.write("\t")
+			}
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+		w.brk(breaks)
+	}
+	for child := root.FirstChild; child != nil; child = child.NextSibling {
+		walk(child)
+	}
+	return strings.TrimSpace(w.b.String())
+}
@@ -0,0 +1,107 @@
+package extractor
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateLayouts are the formats accepted by ParseDate, most specific first
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05.999999999Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 Z0700",
+	"2006-01-02 15:04:05 MST",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/1/2 15:04:05",
+	"2006/1/2 15:04",
+	"2006/1/2",
+	"2006.1.2 15:04",
+	"2006.1.2",
+	"20060102",
+	"2006年1月2日 15時04分",
+	"2006年1月2日 15:04",
+	"2006年1月2日",
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	time.RFC850,
+	time.RFC822Z,
+	time.RFC822,
+	time.ANSIC,
+	"January 2, 2006 3:04 PM",
+	"January 2, 2006 15:04",
+	"January 2, 2006",
+	"Jan 2, 2006 3:04 PM",
+	"Jan 2, 2006",
+	"Monday, January 2, 2006",
+	"2 January 2006 15:04",
+	"2 January 2006",
+	"2 Jan 2006",
+}
+
+var (
+	// japaneseWeekday matches a weekday such as "(土)" in Japanese dates
+	japaneseWeekday = regexp.MustCompile(`\s*[（(][月火水木金土日][)）]\s*`)
+	// ordinalSuffix matches the suffix of days such as "1st" or "22nd"
+	ordinalSuffix = regexp.MustCompile(`\b(\d{1,2})(st|nd|rd|th)\b`)
+	unixSeconds   = regexp.MustCompile(`^\d{9,10}$`)
+	unixMillis    = regexp.MustCompile(`^\d{12,13}$`)
+)
+
+// ParseDate parses the date formats commonly found in page metadata: ISO 8601
+// with or without a zone, RFC 1123 and similar, Japanese dates, written out
+// English dates and Unix timestamps. Values without a zone are taken as UTC.
+// Dates before 1990 or more than a day in the future are rejected as bogus.
+func ParseDate(value string) (time.Time, bool) {
+	value = CleanText(value)
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	if unixSeconds.MatchString(value) || unixMillis.MatchString(value) {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return time.Time{}, false
+		}
+		if len(value) > 10 {
+			return validDate(time.UnixMilli(n).UTC())
+		}
+		return validDate(time.Unix(n, 0).UTC())
+	}
+
+	value = japaneseWeekday.ReplaceAllString(value, " ")
+	value = ordinalSuffix.ReplaceAllString(value, "$1")
+	value = strings.TrimSpace(strings.TrimSuffix(value, "頃"))
+	// "2024-06-01 10:00:00 +0900 JST" as written by Go programs
+	if fields := strings.Fields(value); len(fields) == 4 && len(fields[3]) <= 4 {
+		if t, err := time.Parse("2006-01-02 15:04:05 -0700", strings.Join(fields[:3], " ")); err == nil {
+			return validDate(t)
+		}
+	}
+
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return validDate(t)
+		}
+	}
+	return time.Time{}, false
+}
+
+func validDate(t time.Time) (time.Time, bool) {
+	if t.Year() < 1990 || t.After(time.Now().Add(24*time.Hour)) {
+		return time.Time{}, false
+	}
+	return t, true
+}
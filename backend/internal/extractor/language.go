@@ -0,0 +1,144 @@
+package extractor
+
+import (
+	"strings"
+	"unicode"
+)
+
+// minDetectLetters is the amount of text needed to guess a language
+const minDetectLetters = 20
+
+// scriptLanguages maps scripts used by a single language to that language
+var scriptLanguages = []struct {
+	script   *unicode.RangeTable
+	language string
+}{
+	{unicode.Hangul, "ko"},
+	{unicode.Thai, "th"},
+	{unicode.Greek, "el"},
+	{unicode.Hebrew, "he"},
+	{unicode.Arabic, "ar"},
+	{unicode.Devanagari, "hi"},
+}
+
+// stopwords are frequent words that tell Latin-script languages apart
+var stopwords = map[string][]string{
+	"en": {"the", "and", "of", "to", "is", "in", "that", "it", "for", "with", "was", "on", "are", "this", "be"},
+	"de": {"der", "die", "und", "das", "ist", "nicht", "mit", "ein", "eine", "zu", "den", "von", "sich", "auf", "ich"},
+	"fr": {"le", "la", "les", "et", "est", "des", "une", "dans", "que", "pour", "pas", "qui", "du", "sur", "au"},
+	"es": {"el", "los", "las", "y", "es", "que", "una", "por", "con", "para", "del", "como", "pero", "se", "lo"},
+	"pt": {"o", "os", "as", "e", "é", "que", "uma", "não", "com", "para", "do", "da", "em", "um", "mais"},
+	"it": {"il", "gli", "e", "è", "che", "di", "una", "per", "non", "con", "del", "della", "sono", "ma", "un"},
+	"nl": {"de", "het", "een", "en", "is", "van", "niet", "dat", "op", "te", "zijn", "voor", "met", "ik", "ook"},
+}
+
+// stopwordLanguages maps each stopword to the languages it belongs to
+var stopwordLanguages = func() map[string][]string {
+	index := make(map[string][]string)
+	for language, list := range stopwords {
+		for _, word := range list {
+			index[word] = append(index[word], language)
+		}
+	}
+	return index
+}()
+
+// DetectLanguage guesses the ISO 639-1 language of text from the scripts it
+// uses and, for Latin script, from frequent words. It returns "" when the
+// text is too short or the language is not recognized.
+func DetectLanguage(text string) string {
+	var letters, kana, han, cyrillic, latin int
+	scripts := make(map[string]int)
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r):
+			kana++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		default:
+			for _, s := range scriptLanguages {
+				if unicode.Is(s.script, r) {
+					scripts[s.language]++
+					break
+				}
+			}
+		}
+	}
+	if letters < minDetectLetters {
+		return ""
+	}
+
+	// Japanese mixes kana with kanji and often Latin words, so any
+	// noticeable amount of kana decides it
+	if kana*20 >= letters {
+		return "ja"
+	}
+	if han*2 >= letters {
+		return "zh"
+	}
+	for language, count := range scripts {
+		if count*2 >= letters {
+			return language
+		}
+	}
+	if cyrillic*2 >= letters {
+		return "ru"
+	}
+	if latin*2 >= letters {
+		return detectLatin(text)
+	}
+	return ""
+}
+
+func detectLatin(text string) string {
+	counts := make(map[string]int)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	if len(words) > 2000 {
+		words = words[:2000]
+	}
+	for _, word := range words {
+		for _, language := range stopwordLanguages[word] {
+			counts[language]++
+		}
+	}
+
+	best, bestCount, total := "", 0, 0
+	for _, language := range []string{"en", "de", "fr", "es", "pt", "it", "nl"} {
+		total += counts[language]
+		if counts[language] > bestCount {
+			best, bestCount = language, counts[language]
+		}
+	}
+	// Require a clear signal so that code or lists of names are not guessed
+	if bestCount < 3 || bestCount*10 < len(words) || bestCount*3 < total {
+		return ""
+	}
+	return best
+}
+
+// NormalizeLanguage reduces a language tag such as "en-US" or "ja_JP" to its
+// lowercase primary subtag, or "" when it is not a language tag
+func NormalizeLanguage(tag string) string {
+	tag = strings.TrimSpace(tag)
+	primary, _, _ := strings.Cut(strings.NewReplacer("_", "-").Replace(tag), "-")
+	primary = strings.ToLower(primary)
+	if len(primary) < 2 || len(primary) > 3 {
+		return ""
+	}
+	for _, r := range primary {
+		if r < 'a' || r > 'z' {
+			return ""
+		}
+	}
+	return primary
+}
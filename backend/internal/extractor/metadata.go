@@ -0,0 +1,555 @@
+package extractor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Metadata describes a page as declared by its markup
+type Metadata struct {
+	Title        string
+	Description  string
+	Authors      []string
+	SiteName     string
+	ImageURL     string
+	PublishedAt  *time.Time
+	ModifiedAt   *time.Time
+	Language     string
+	CanonicalURL string
+	Keywords     []string
+}
+
+// Metadata sources in the order of precedence used by ResolveMetadata.
+// Structured data is written for machines and is the most reliable; plain
+// HTML such as the <title> element often carries the site name as well.
+const (
+	sourceJSONLD = iota
+	sourceOpenGraph
+	sourceTwitter
+	sourceDublinCore
+	sourceMicrodata
+	sourceHTML
+	sourceCount
+)
+
+// Some fields use their own precedence. The lang attribute is set for the
+// whole page by its author, while og:locale is often a site-wide default;
+// the canonical link is what search engines use.
+var (
+	defaultOrder   = []int{sourceJSONLD, sourceOpenGraph, sourceTwitter, sourceDublinCore, sourceMicrodata, sourceHTML}
+	languageOrder  = []int{sourceJSONLD, sourceHTML, sourceOpenGraph, sourceDublinCore, sourceMicrodata}
+	canonicalOrder = []int{sourceHTML, sourceOpenGraph, sourceJSONLD, sourceMicrodata}
+)
+
+// articleTypes are the schema.org types read from JSON-LD and microdata
+var articleTypes = map[string]bool{
+	"Article": true, "NewsArticle": true, "BlogPosting": true, "TechArticle": true,
+	"ScholarlyArticle": true, "Report": true, "LiveBlogPosting": true, "SocialMediaPosting": true,
+	"OpinionNewsArticle": true, "AnalysisNewsArticle": true, "ReportageNewsArticle": true,
+	"ReviewNewsArticle": true, "BackgroundNewsArticle": true, "Posting": true,
+}
+
+// timeSelectors find publication dates in the body, most reliable first
+var timeSelectors = []string{"time[pubdate]", "time[itemprop=datePublished]", "article time[datetime]", "time[datetime]"}
+
+// ResolveMetadata merges the metadata declared by JSON-LD, Open Graph,
+// Twitter Cards, Dublin Core, microdata and plain HTML. Every field is taken
+// from the first source that has it; URLs are made absolute against doc.Url.
+// Language is left empty when the page does not declare it, see DetectLanguage.
+func ResolveMetadata(doc *goquery.Document) *Metadata {
+	r := &metadataResolver{doc: doc, base: doc.Url, metas: collectMetas(doc)}
+
+	var sources [sourceCount]*Metadata
+	sources[sourceJSONLD] = r.jsonLD()
+	sources[sourceOpenGraph] = r.openGraph()
+	sources[sourceTwitter] = r.twitter()
+	sources[sourceDublinCore] = r.dublinCore()
+	sources[sourceMicrodata] = r.microdata()
+	sources[sourceHTML] = r.html()
+
+	pick := func(order []int, get func(*Metadata) string) string {
+		for _, source := range order {
+			if value := get(sources[source]); value != "" {
+				return value
+			}
+		}
+		return ""
+	}
+	pickList := func(get func(*Metadata) []string) []string {
+		for _, source := range defaultOrder {
+			if values := get(sources[source]); len(values) > 0 {
+				return values
+			}
+		}
+		return nil
+	}
+	pickTime := func(get func(*Metadata) *time.Time) *time.Time {
+		for _, source := range defaultOrder {
+			if value := get(sources[source]); value != nil {
+				return value
+			}
+		}
+		return nil
+	}
+
+	return &Metadata{
+		Title:        pick(defaultOrder, func(m *Metadata) string { return m.Title }),
+		Description:  pick(defaultOrder, func(m *Metadata) string { return m.Description }),
+		Authors:      pickList(func(m *Metadata) []string { return m.Authors }),
+		SiteName:     pick(defaultOrder, func(m *Metadata) string { return m.SiteName }),
+		ImageURL:     pick(defaultOrder, func(m *Metadata) string { return m.ImageURL }),
+		PublishedAt:  pickTime(func(m *Metadata) *time.Time { return m.PublishedAt }),
+		ModifiedAt:   pickTime(func(m *Metadata) *time.Time { return m.ModifiedAt }),
+		Language:     pick(languageOrder, func(m *Metadata) string { return m.Language }),
+		CanonicalURL: pick(canonicalOrder, func(m *Metadata) string { return m.CanonicalURL }),
+		Keywords:     pickList(func(m *Metadata) []string { return m.Keywords }),
+	}
+}
+
+type metadataResolver struct {
+	doc  *goquery.Document
+	base *url.URL
+	// metas holds the content of every <meta> by lowercase name or property
+	metas map[string][]string
+}
+
+func collectMetas(doc *goquery.Document) map[string][]string {
+	metas := make(map[string][]string)
+	doc.Find("meta").Each(func(_ int, s *goquery.Selection) {
+		content := CleanText(s.AttrOr("content", ""))
+		if content == "" {
+			return
+		}
+		for _, attr := range []string{"property", "name", "itemprop", "http-equiv"} {
+			if key := strings.ToLower(strings.TrimSpace(s.AttrOr(attr, ""))); key != "" {
+				metas[key] = append(metas[key], content)
+			}
+		}
+	})
+	return metas
+}
+
+// meta returns the first value of the first key present
+func (r *metadataResolver) meta(keys ...string) string {
+	for _, key := range keys {
+		if values := r.metas[key]; len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+// metaAll returns every value of every key
+func (r *metadataResolver) metaAll(keys ...string) []string {
+	var values []string
+	for _, key := range keys {
+		values = append(values, r.metas[key]...)
+	}
+	return values
+}
+
+func (r *metadataResolver) date(values ...string) *time.Time {
+	for _, value := range values {
+		if t, ok := ParseDate(value); ok {
+			return &t
+		}
+	}
+	return nil
+}
+
+// absoluteURL resolves raw against the page URL, keeping only http and https URLs
+func (r *metadataResolver) absoluteURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return ""
+	}
+	ref, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	if r.base != nil {
+		ref = r.base.ResolveReference(ref)
+	}
+	if ref.Scheme != "http" && ref.Scheme != "https" {
+		return ""
+	}
+	return ref.String()
+}
+
+func (r *metadataResolver) openGraph() *Metadata {
+	var authors []string
+	for _, author := range r.metaAll("article:author") {
+		// Facebook uses profile URLs here
+		if !strings.Contains(author, "://") {
+			authors = append(authors, author)
+		}
+	}
+	return &Metadata{
+		Title:        r.meta("og:title"),
+		Description:  r.meta("og:description"),
+		Authors:      uniqueNames(authors),
+		SiteName:     r.meta("og:site_name"),
+		ImageURL:     r.absoluteURL(r.meta("og:image:secure_url", "og:image:url", "og:image")),
+		PublishedAt:  r.date(r.meta("article:published_time", "og:published_time")),
+		ModifiedAt:   r.date(r.meta("article:modified_time", "og:updated_time")),
+		Language:     NormalizeLanguage(r.meta("og:locale")),
+		CanonicalURL: r.absoluteURL(r.meta("og:url")),
+		Keywords:     splitKeywords(r.metaAll("article:tag")...),
+	}
+}
+
+func (r *metadataResolver) twitter() *Metadata {
+	m := &Metadata{
+		Title:       r.meta("twitter:title"),
+		Description: r.meta("twitter:description"),
+		ImageURL:    r.absoluteURL(r.meta("twitter:image", "twitter:image:src")),
+	}
+	// Twitter Cards have no author field, but publishing tools add one as
+	// a label and data pair
+	for i := 1; i <= 4; i++ {
+		label := strings.ToLower(r.meta(fmt.Sprintf("twitter:label%d", i)))
+		if label == "written by" || label == "author" || label == "執筆者" || label == "著者" {
+			m.Authors = uniqueNames([]string{r.meta(fmt.Sprintf("twitter:data%d", i))})
+		}
+	}
+	return m
+}
+
+func (r *metadataResolver) dublinCore() *Metadata {
+	return &Metadata{
+		Title:       r.meta("dc.title", "dcterms.title"),
+		Description: r.meta("dc.description", "dcterms.description", "dcterms.abstract"),
+		Authors:     uniqueNames(r.metaAll("dc.creator", "dcterms.creator")),
+		SiteName:    r.meta("dc.publisher", "dcterms.publisher"),
+		PublishedAt: r.date(r.metaAll("dc.date.issued", "dcterms.issued", "dc.date", "dcterms.date", "dcterms.created")...),
+		ModifiedAt:  r.date(r.meta("dcterms.modified", "dc.date.modified")),
+		Language:    NormalizeLanguage(r.meta("dc.language", "dcterms.language")),
+		Keywords:    splitKeywords(r.metaAll("dc.subject", "dcterms.subject")...),
+	}
+}
+
+func (r *metadataResolver) html() *Metadata {
+	m := &Metadata{
+		Title:       CleanText(r.doc.Find("head title").First().Text()),
+		Description: r.meta("description"),
+		Authors:     uniqueNames(r.metaAll("author")),
+		SiteName:    r.meta("application-name", "apple-mobile-web-app-title"),
+		ImageURL:    r.absoluteURL(r.doc.Find(`link[rel="image_src"]`).First().AttrOr("href", "")),
+		PublishedAt: r.date(r.meta("date", "pubdate", "publishdate", "publish_date", "publication_date", "article.published")),
+		Language:    NormalizeLanguage(r.doc.Find("html").First().AttrOr("lang", "")),
+		Keywords:    splitKeywords(r.metaAll("keywords", "news_keywords")...),
+	}
+	if m.Language == "" {
+		m.Language = NormalizeLanguage(r.meta("content-language", "language"))
+	}
+
+	r.doc.Find("link[rel]").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		for _, rel := range strings.Fields(strings.ToLower(s.AttrOr("rel", ""))) {
+			if rel == "canonical" {
+				m.CanonicalURL = r.absoluteURL(s.AttrOr("href", ""))
+				return m.CanonicalURL == ""
+			}
+		}
+		return true
+	})
+
+	// <time> elements are the visible publication date, preferably the
+	// one marked as such or inside the article
+	for _, selector := range timeSelectors {
+		if m.PublishedAt != nil {
+			break
+		}
+		r.doc.Find(selector).EachWithBreak(func(_ int, s *goquery.Selection) bool {
+			m.PublishedAt = r.date(s.AttrOr("datetime", ""), s.Text())
+			return m.PublishedAt == nil
+		})
+	}
+	return m
+}
+
+// microdata reads the itemprops of the first article item on the page
+func (r *metadataResolver) microdata() *Metadata {
+	m := &Metadata{}
+	var item *goquery.Selection
+	r.doc.Find("[itemscope][itemtype]").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		for _, itemType := range strings.Fields(s.AttrOr("itemtype", "")) {
+			if articleTypes[schemaType(itemType)] {
+				item = s
+				return false
+			}
+		}
+		return true
+	})
+	if item == nil {
+		return m
+	}
+
+	// Properties of nested items such as the publisher belong to those items
+	props := item.Find("[itemprop]").FilterFunction(func(_ int, s *goquery.Selection) bool {
+		return s.ParentsFiltered("[itemscope]").First().IsSelection(item)
+	})
+	prop := func(name string) *goquery.Selection {
+		return props.FilterFunction(func(_ int, s *goquery.Selection) bool {
+			for _, field := range strings.Fields(s.AttrOr("itemprop", "")) {
+				if field == name {
+					return true
+				}
+			}
+			return false
+		})
+	}
+	value := func(s *goquery.Selection) string {
+		for _, attr := range []string{"content", "datetime"} {
+			if v, ok := s.Attr(attr); ok {
+				return CleanText(v)
+			}
+		}
+		switch goquery.NodeName(s) {
+		case "img", "audio", "video", "source":
+			return r.absoluteURL(s.AttrOr("src", ""))
+		case "a", "link":
+			return r.absoluteURL(s.AttrOr("href", ""))
+		}
+		return CleanText(s.Text())
+	}
+
+	m.Title = value(prop("headline").First())
+	if m.Title == "" {
+		m.Title = value(prop("name").First())
+	}
+	m.Description = value(prop("description").First())
+	m.ImageURL = r.absoluteURL(value(prop("image").First()))
+	m.PublishedAt = r.date(value(prop("datePublished").First()))
+	m.ModifiedAt = r.date(value(prop("dateModified").First()))
+	m.Language = NormalizeLanguage(value(prop("inLanguage").First()))
+	m.CanonicalURL = r.absoluteURL(value(prop("url").First()))
+	m.Keywords = splitKeywords(value(prop("keywords").First()))
+
+	var authors []string
+	prop("author").Each(func(_ int, s *goquery.Selection) {
+		if _, nested := s.Attr("itemscope"); nested {
+			authors = append(authors, CleanText(s.Find("[itemprop~=name]").First().Text()))
+			return
+		}
+		authors = append(authors, value(s))
+	})
+	m.Authors = uniqueNames(authors)
+	return m
+}
+
+// jsonLD reads the first article node in the JSON-LD scripts of the page
+func (r *metadataResolver) jsonLD() *Metadata {
+	var nodes []map[string]any
+	r.doc.Find(`script[type="application/ld+json"]`).Each(func(_ int, s *goquery.Selection) {
+		nodes = append(nodes, parseJSONLD(s.Text())...)
+	})
+
+	ids := make(map[string]map[string]any)
+	var article, website, webpage map[string]any
+	for _, node := range nodes {
+		if id, ok := node["@id"].(string); ok {
+			ids[id] = node
+		}
+		for _, t := range jsonTypes(node["@type"]) {
+			switch {
+			case articleTypes[t] && article == nil:
+				article = node
+			case t == "WebSite" && website == nil:
+				website = node
+			case t == "WebPage" && webpage == nil:
+				webpage = node
+			}
+		}
+	}
+	// resolve follows {"@id": ...} references into the @graph
+	resolve := func(value any) any {
+		if ref, ok := value.(map[string]any); ok && len(ref) == 1 {
+			if id, ok := ref["@id"].(string); ok && ids[id] != nil {
+				return ids[id]
+			}
+		}
+		return value
+	}
+
+	m := &Metadata{}
+	if website != nil {
+		m.SiteName = jsonString(website["name"])
+		m.Language = NormalizeLanguage(jsonString(website["inLanguage"]))
+	}
+	if webpage != nil && article == nil {
+		m.Language = firstNonEmpty(NormalizeLanguage(jsonString(webpage["inLanguage"])), m.Language)
+	}
+	if article == nil {
+		return m
+	}
+
+	m.Title = firstNonEmpty(jsonString(article["headline"]), jsonString(article["name"]))
+	m.Description = jsonString(article["description"])
+	m.PublishedAt = r.date(jsonString(article["datePublished"]), jsonString(article["dateCreated"]))
+	m.ModifiedAt = r.date(jsonString(article["dateModified"]))
+	m.Language = firstNonEmpty(NormalizeLanguage(jsonString(article["inLanguage"])), m.Language)
+	m.Keywords = splitKeywords(jsonStrings(article["keywords"])...)
+
+	for _, image := range jsonList(resolve(article["image"])) {
+		image = resolve(image)
+		if object, ok := image.(map[string]any); ok {
+			image = firstNonEmpty(jsonString(object["url"]), jsonString(object["contentUrl"]))
+		}
+		if m.ImageURL = r.absoluteURL(jsonString(image)); m.ImageURL != "" {
+			break
+		}
+	}
+
+	var authors []string
+	for _, author := range jsonList(article["author"]) {
+		author = resolve(author)
+		if object, ok := author.(map[string]any); ok {
+			authors = append(authors, jsonString(object["name"]))
+			continue
+		}
+		authors = append(authors, jsonString(author))
+	}
+	m.Authors = uniqueNames(authors)
+
+	if publisher, ok := resolve(article["publisher"]).(map[string]any); ok {
+		m.SiteName = firstNonEmpty(jsonString(publisher["name"]), m.SiteName)
+	}
+
+	for _, key := range []string{"mainEntityOfPage", "url"} {
+		value := resolve(article[key])
+		if object, ok := value.(map[string]any); ok {
+			value = object["@id"]
+		}
+		if m.CanonicalURL = r.absoluteURL(jsonString(value)); m.CanonicalURL != "" {
+			break
+		}
+	}
+	return m
+}
+
+// parseJSONLD returns the objects of a JSON-LD script, flattening arrays and @graph
+func parseJSONLD(script string) []map[string]any {
+	script = strings.TrimSpace(script)
+	script = strings.TrimSuffix(strings.TrimPrefix(script, "<!--"), "-->")
+	script = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(script), "//<![CDATA["), "//]]>")
+
+	var data any
+	if err := json.Unmarshal([]byte(script), &data); err != nil {
+		// Hand-written JSON-LD often has raw line breaks inside strings
+		cleaned := strings.NewReplacer("\n", " ", "\r", " ", "\t", " ").Replace(script)
+		if err := json.Unmarshal([]byte(cleaned), &data); err != nil {
+			return nil
+		}
+	}
+
+	var nodes []map[string]any
+	var walk func(any)
+	walk = func(value any) {
+		switch v := value.(type) {
+		case []any:
+			for _, item := range v {
+				walk(item)
+			}
+		case map[string]any:
+			nodes = append(nodes, v)
+			if graph, ok := v["@graph"]; ok {
+				walk(graph)
+			}
+		}
+	}
+	walk(data)
+	return nodes
+}
+
+// schemaType returns the type name of a schema.org type URL or name
+func schemaType(value string) string {
+	value = strings.TrimRight(value, "/")
+	if i := strings.LastIndexAny(value, "/:"); i >= 0 {
+		value = value[i+1:]
+	}
+	return value
+}
+
+func jsonTypes(value any) []string {
+	var types []string
+	for _, t := range jsonStrings(value) {
+		types = append(types, schemaType(t))
+	}
+	return types
+}
+
+func jsonList(value any) []any {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []any:
+		return v
+	}
+	return []any{value}
+}
+
+func jsonString(value any) string {
+	switch v := value.(type) {
+	case string:
+		return CleanText(v)
+	case []any:
+		if len(v) > 0 {
+			return jsonString(v[0])
+		}
+	case map[string]any:
+		// Language-tagged values: {"@value": "...", "@language": "en"}
+		return jsonString(v["@value"])
+	}
+	return ""
+}
+
+func jsonStrings(value any) []string {
+	var values []string
+	for _, item := range jsonList(value) {
+		if s := jsonString(item); s != "" {
+			values = append(values, s)
+		}
+	}
+	return values
+}
+
+// splitKeywords splits comma separated keyword lists and removes duplicates
+func splitKeywords(values ...string) []string {
+	var keywords []string
+	for _, value := range values {
+		keywords = append(keywords, strings.FieldsFunc(value, func(r rune) bool {
+			return r == ',' || r == '、' || r == '，' || r == ';'
+		})...)
+	}
+	return uniqueNames(keywords)
+}
+
+// uniqueNames trims names and removes empty and duplicate ones, ignoring case
+func uniqueNames(names []string) []string {
+	var unique []string
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = CleanText(name)
+		key := strings.ToLower(name)
+		if name == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, name)
+	}
+	return unique
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,252 @@
+package extractor
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func resolve(t *testing.T, page string) *Metadata {
+	t.Helper()
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	require.NoError(t, err)
+	doc.Url, err = url.Parse("https://blog.example.com/2024/06/post/")
+	require.NoError(t, err)
+	return ResolveMetadata(doc)
+}
+
+func utc(value string) time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestResolveMetadata_JSONLDGraph(t *testing.T) {
+	// The layout written by common SEO plugins: nodes in a @graph that refer
+	// to each other by @id
+	m := resolve(t, `<html lang="fr"><head>
+<title>Graph post - Example Blog</title>
+<meta property="og:title" content="Open Graph title">
+<meta property="og:locale" content="de_DE">
+<link rel="canonical" href="/2024/06/post/">
+<script type="application/ld+json">{"@context":"https://schema.org","@graph":[
+ {"@type":"WebSite","@id":"https://blog.example.com/#website","name":"Example Blog","inLanguage":"en-US"},
+ {"@type":["BlogPosting"],"@id":"https://blog.example.com/2024/06/post/#article",
+  "headline":"Graph post","description":"A post described in JSON-LD",
+  "author":{"@id":"https://blog.example.com/#/schema/person/1"},
+  "publisher":{"@id":"https://blog.example.com/#organization"},
+  "image":{"@id":"https://blog.example.com/#primaryimage"},
+  "datePublished":"2024-06-01T08:00:00+00:00","dateModified":"2024-06-02T08:00:00+00:00",
+  "keywords":["Go","Testing","go"],"inLanguage":"en-US",
+  "mainEntityOfPage":{"@id":"https://blog.example.com/2024/06/post/"}},
+ {"@type":"Person","@id":"https://blog.example.com/#/schema/person/1","name":"Alice Example"},
+ {"@type":"Organization","@id":"https://blog.example.com/#organization","name":"Example Media"},
+ {"@type":"ImageObject","@id":"https://blog.example.com/#primaryimage","url":"/images/cover.png"}
+]}</script>
+</head><body></body></html>`)
+
+	assert.Equal(t, "Graph post", m.Title)
+	assert.Equal(t, "A post described in JSON-LD", m.Description)
+	assert.Equal(t, []string{"Alice Example"}, m.Authors)
+	assert.Equal(t, "Example Media", m.SiteName)
+	assert.Equal(t, "https://blog.example.com/images/cover.png", m.ImageURL)
+	require.NotNil(t, m.PublishedAt)
+	assert.True(t, m.PublishedAt.Equal(utc("2024-06-01T08:00:00Z")))
+	require.NotNil(t, m.ModifiedAt)
+	assert.True(t, m.ModifiedAt.Equal(utc("2024-06-02T08:00:00Z")))
+	assert.Equal(t, "en", m.Language)
+	assert.Equal(t, "https://blog.example.com/2024/06/post/", m.CanonicalURL)
+	assert.Equal(t, []string{"Go", "Testing"}, m.Keywords)
+}
+
+func TestResolveMetadata_NewsArticleWithSeveralAuthors(t *testing.T) {
+	m := resolve(t, `<html><head>
+<script type="application/ld+json">
+[{"@context":"https://schema.org","@type":"NewsArticle","headline":"Council approves
+ cycle lanes","author":[{"@type":"Person","name":"Priya Shah"},{"@type":"Person","name":"Tom Reid"},"priya shah"],
+ "datePublished":"2024-06-01T10:00:00+0900","keywords":"transport, cycling,council",
+ "image":["https://cdn.example.com/a.jpg","https://cdn.example.com/b.jpg"]}]
+</script>
+</head></html>`)
+
+	assert.Equal(t, "Council approves cycle lanes", m.Title)
+	assert.Equal(t, []string{"Priya Shah", "Tom Reid"}, m.Authors)
+	require.NotNil(t, m.PublishedAt)
+	assert.True(t, m.PublishedAt.Equal(utc("2024-06-01T01:00:00Z")))
+	assert.Equal(t, []string{"transport", "cycling", "council"}, m.Keywords)
+	assert.Equal(t, "https://cdn.example.com/a.jpg", m.ImageURL)
+}
+
+func TestResolveMetadata_OpenGraphAndTwitter(t *testing.T) {
+	m := resolve(t, `<html lang="en-GB"><head>
+<title>Ignored | Site</title>
+<meta property="og:title" content="Open Graph title">
+<meta property="og:site_name" content="Example Site">
+<meta property="og:image" content="//cdn.example.com/og.png">
+<meta property="og:locale" content="ja_JP">
+<meta property="og:url" content="https://blog.example.com/post">
+<meta property="article:author" content="https://facebook.com/someone">
+<meta property="article:published_time" content="2024-05-12">
+<meta property="article:tag" content="go">
+<meta property="article:tag" content="interfaces">
+<meta name="twitter:title" content="Twitter title">
+<meta name="twitter:description" content="Twitter description">
+<meta name="twitter:label1" content="Written by">
+<meta name="twitter:data1" content="Ken">
+</head></html>`)
+
+	assert.Equal(t, "Open Graph title", m.Title)
+	// Fields missing from Open Graph come from the next source
+	assert.Equal(t, "Twitter description", m.Description)
+	assert.Equal(t, []string{"Ken"}, m.Authors)
+	assert.Equal(t, "Example Site", m.SiteName)
+	assert.Equal(t, "https://cdn.example.com/og.png", m.ImageURL)
+	require.NotNil(t, m.PublishedAt)
+	assert.True(t, m.PublishedAt.Equal(utc("2024-05-12T00:00:00Z")))
+	// The lang attribute of the page wins over the site-wide og:locale
+	assert.Equal(t, "en", m.Language)
+	assert.Equal(t, "https://blog.example.com/post", m.CanonicalURL)
+	assert.Equal(t, []string{"go", "interfaces"}, m.Keywords)
+}
+
+func TestResolveMetadata_DublinCore(t *testing.T) {
+	m := resolve(t, `<html><head>
+<meta name="DC.title" content="Dublin Core title">
+<meta name="DC.creator" content="Sato, Hanako">
+<meta name="DC.creator" content="Suzuki Taro">
+<meta name="DC.date" content="2023/12/24">
+<meta name="DC.language" content="ja">
+<meta name="DC.subject" content="図書館、メタデータ">
+<meta name="DC.publisher" content="Example University">
+</head></html>`)
+
+	assert.Equal(t, "Dublin Core title", m.Title)
+	assert.Equal(t, []string{"Sato, Hanako", "Suzuki Taro"}, m.Authors)
+	require.NotNil(t, m.PublishedAt)
+	assert.True(t, m.PublishedAt.Equal(utc("2023-12-24T00:00:00Z")))
+	assert.Equal(t, "ja", m.Language)
+	assert.Equal(t, []string{"図書館", "メタデータ"}, m.Keywords)
+	assert.Equal(t, "Example University", m.SiteName)
+}
+
+func TestResolveMetadata_Microdata(t *testing.T) {
+	m := resolve(t, `<html><head><title>Microdata post - Blog</title></head><body>
+<article itemscope itemtype="http://schema.org/BlogPosting">
+  <h1 itemprop="headline">Microdata post</h1>
+  <span itemprop="author" itemscope itemtype="http://schema.org/Person"><span itemprop="name">Hanako</span></span>
+  <time itemprop="datePublished" datetime="2024-01-15T09:00:00+09:00">1月15日</time>
+  <div itemprop="publisher" itemscope itemtype="http://schema.org/Organization"><span itemprop="name">Publisher name</span></div>
+  <img itemprop="image" src="/img/cover.jpg">
+  <meta itemprop="keywords" content="microdata,schema">
+</article></body></html>`)
+
+	assert.Equal(t, "Microdata post", m.Title)
+	assert.Equal(t, []string{"Hanako"}, m.Authors)
+	require.NotNil(t, m.PublishedAt)
+	assert.True(t, m.PublishedAt.Equal(utc("2024-01-15T00:00:00Z")))
+	assert.Equal(t, "https://blog.example.com/img/cover.jpg", m.ImageURL)
+	assert.Equal(t, []string{"microdata", "schema"}, m.Keywords)
+}
+
+func TestResolveMetadata_PlainHTML(t *testing.T) {
+	m := resolve(t, `<html lang="ja-JP"><head>
+<title>素の HTML の記事</title>
+<meta name="description" content="構造化データのないページ">
+<meta name="author" content="山田">
+<meta name="keywords" content="Go、並行処理, go">
+<link rel="alternate canonical" href="../post/?utm_source=x">
+</head><body>
+<time datetime="2020-01-01">サイト開設日</time>
+<article><p>本文</p><time datetime="2024-06-01T10:00:00+09:00">2024年6月1日</time></article>
+</body></html>`)
+
+	assert.Equal(t, "素の HTML の記事", m.Title)
+	assert.Equal(t, "構造化データのないページ", m.Description)
+	assert.Equal(t, []string{"山田"}, m.Authors)
+	assert.Equal(t, []string{"Go", "並行処理"}, m.Keywords)
+	assert.Equal(t, "https://blog.example.com/2024/06/post/?utm_source=x", m.CanonicalURL)
+	assert.Equal(t, "ja", m.Language)
+	// A time inside the article beats an earlier one elsewhere on the page
+	require.NotNil(t, m.PublishedAt)
+	assert.True(t, m.PublishedAt.Equal(utc("2024-06-01T01:00:00Z")))
+}
+
+func TestResolveMetadata_NothingDeclared(t *testing.T) {
+	m := resolve(t, `<html><body><p>No metadata at all</p>
+<script type="application/ld+json">{not json</script></body></html>`)
+
+	assert.Equal(t, &Metadata{}, m)
+}
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "2024-06-01T10:00:00+09:00", want: "2024-06-01T01:00:00Z"},
+		{value: "2024-06-01T10:00:00.123Z", want: "2024-06-01T10:00:00.123Z"},
+		{value: "2024-06-01T10:00:00+0900", want: "2024-06-01T01:00:00Z"},
+		{value: "2024-06-01T10:00:00", want: "2024-06-01T10:00:00Z"},
+		{value: "2024-06-01 10:00", want: "2024-06-01T10:00:00Z"},
+		{value: " 2024-06-01 ", want: "2024-06-01T00:00:00Z"},
+		{value: "2024/6/1", want: "2024-06-01T00:00:00Z"},
+		{value: "2024.06.01", want: "2024-06-01T00:00:00Z"},
+		{value: "2024年6月1日", want: "2024-06-01T00:00:00Z"},
+		{value: "2024年6月1日(土) 10:30", want: "2024-06-01T10:30:00Z"},
+		{value: "Sat, 01 Jun 2024 10:00:00 GMT", want: "2024-06-01T10:00:00Z"},
+		{value: "Sat, 1 Jun 2024 10:00:00 +0200", want: "2024-06-01T08:00:00Z"},
+		{value: "June 1, 2024", want: "2024-06-01T00:00:00Z"},
+		{value: "June 1st, 2024", want: "2024-06-01T00:00:00Z"},
+		{value: "Jun 1, 2024", want: "2024-06-01T00:00:00Z"},
+		{value: "1 June 2024", want: "2024-06-01T00:00:00Z"},
+		{value: "1717236000", want: "2024-06-01T10:00:00Z"},
+		{value: "1717236000000", want: "2024-06-01T10:00:00Z"},
+		{value: "2024-06-01 10:00:00 +0900 JST", want: "2024-06-01T01:00:00Z"},
+	}
+	for _, tt := range tests {
+		got, ok := ParseDate(tt.value)
+		if assert.True(t, ok, tt.value) {
+			assert.True(t, got.Equal(utc(tt.want)), "%s parsed as %s", tt.value, got)
+		}
+	}
+
+	for _, value := range []string{"", "yesterday", "0000-00-00", "1970-01-01", "2999-01-01", "32/13/2024"} {
+		_, ok := ParseDate(value)
+		assert.False(t, ok, value)
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "Goのcontextパッケージは、キャンセルやタイムアウトを伝播するための仕組みです。", want: "ja"},
+		{text: "Kubernetes の Pod と Deployment を YAML で定義する方法を Go のコードと一緒に説明します", want: "ja"},
+		{text: "我们使用上下文来传播取消信号和超时，这是并发编程中非常重要的概念。", want: "zh"},
+		{text: "컨텍스트는 취소 신호와 타임아웃을 전파하는 데 사용되는 중요한 개념입니다.", want: "ko"},
+		{text: "Контекст используется для передачи сигналов отмены и тайм-аутов между горутинами.", want: "ru"},
+		{text: "The context package is used to carry cancellation signals and deadlines across API boundaries, and it is the standard way to do this in Go.", want: "en"},
+		{text: "Das Paket context wird verwendet, um Abbruchsignale und Fristen über API-Grenzen hinweg zu übertragen, und es ist nicht optional.", want: "de"},
+		{text: "Le paquet context est utilisé pour transmettre les signaux d'annulation et les délais dans les appels, et c'est la méthode standard pour le faire.", want: "fr"},
+		{text: "El paquete context se usa para propagar las señales de cancelación y los plazos entre las llamadas, y es la forma estándar de hacerlo.", want: "es"},
+		{text: "Too short", want: ""},
+		{text: "func main() { ctx, cancel := context.WithTimeout(parent, time.Second); defer cancel() }", want: ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, DetectLanguage(tt.text), tt.text)
+	}
+}
+
+func TestNormalizeLanguage(t *testing.T) {
+	for tag, want := range map[string]string{"en-US": "en", "ja_JP": "ja", "ZH-Hant-TW": "zh", " fr ": "fr", "": "", "x": "", "english": "", "12": ""} {
+		assert.Equal(t, want, NormalizeLanguage(tag), tag)
+	}
+}
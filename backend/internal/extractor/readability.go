@@ -0,0 +1,381 @@
+package extractor
+
+import (
+	"math"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// The scoring follows the approach of Mozilla's Readability: paragraphs
+// score their ancestors by length and commas, scores are reduced by link
+// density and class names, and the best candidate is merged with siblings
+// that look like part of the same article.
+
+var (
+	unlikelyPattern = regexp.MustCompile(`(?i)-ad-|ad-slot|adsbygoogle|advert|agegate|banner|breadcrumb|combx|comment|community|cookie|cover-wrap|disqus|extra|footer|gdpr|header|legends|menu|modal|nav|newsletter|pager|pagination|popup|promo|related|remark|replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|supplemental|toolbar|widget`)
+	maybePattern    = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	positivePattern = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|post|text|blog|story`)
+	// adPattern matches ads even inside elements whose names look like content
+	adPattern       = regexp.MustCompile(`(?i)(^|[\s_-])ads?([\s_-]|$)|ad-slot|adsbygoogle|advert`)
+	bylinePattern   = regexp.MustCompile(`(?i)byline|author|dateline|writtenby`)
+	negativePattern = regexp.MustCompile(`(?i)-ad-|hidden|^hid$| hid$| hid |^hid |banner|combx|comment|com-|contact|foot|footer|footnote|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget`)
+	// sentenceEnd matches the end of a sentence in English and Japanese
+	sentenceEnd = regexp.MustCompile(`[.。!?！？]["'”」』)]?\s*$`)
+)
+
+// boilerplateSelector matches elements that are never part of the article
+const boilerplateSelector = `nav, aside, footer, form, [role="navigation"], [role="complementary"], ` +
+	`[role="contentinfo"], [role="banner"], [role="dialog"], [aria-hidden="true"], [hidden]`
+
+// scoredTags are the elements whose text scores their ancestors
+var scoredTags = map[string]bool{"p": true, "pre": true, "td": true, "blockquote": true, "section": true, "div": true}
+
+// minScoredRunes is the minimum text length of a scored paragraph
+const minScoredRunes = 25
+
+type readability struct {
+	scores map[*html.Node]float64
+}
+
+// Extract finds the main content of doc and returns it sanitized. doc is
+// not modified.
+func Extract(doc *goquery.Document, pageURL *url.URL) *Content {
+	body := doc.Find("body").First()
+	if body.Length() == 0 {
+		body = doc.Selection
+	}
+	body = body.Clone()
+
+	prepare(body)
+	removeTitleHeading(body, documentTitle(doc))
+
+	r := &readability{scores: make(map[*html.Node]float64)}
+	top := r.topCandidate(body)
+	if top == nil {
+		return newContent(part{nodes: body.Nodes, base: pageURL})
+	}
+
+	nodes := r.withSiblings(top)
+	for _, node := range nodes {
+		r.cleanConditionally(node)
+	}
+	return newContent(part{nodes: nodes, base: pageURL})
+}
+
+// prepare removes boilerplate and unlikely candidates before scoring
+func prepare(body *goquery.Selection) {
+	body.Find("script, style, noscript, template, iframe, svg").Remove()
+	body.Find(boilerplateSelector).Remove()
+
+	body.Find("*").Each(func(_ int, s *goquery.Selection) {
+		node := s.Get(0)
+		switch node.Data {
+		case "article", "main", "body", "pre", "code", "table", "tbody", "tr", "td", "th", "a":
+			return
+		}
+		// Keep elements inside a code block untouched
+		if s.Closest("pre").Length() > 0 {
+			return
+		}
+		match := getAttr(node, "class") + " " + getAttr(node, "id")
+		if strings.TrimSpace(match) == "" {
+			return
+		}
+		if adPattern.MatchString(match) || (unlikelyPattern.MatchString(match) && !maybePattern.MatchString(match)) {
+			s.Remove()
+			return
+		}
+		// The byline is stored as the author, so drop it from the content
+		if bylinePattern.MatchString(match) && utf8.RuneCountInString(CleanText(s.Text())) < 100 {
+			s.Remove()
+		}
+	})
+
+	// Comments cannot be selected, so walk the tree for them
+	var removeComments func(*html.Node)
+	removeComments = func(node *html.Node) {
+		for child := node.FirstChild; child != nil; {
+			next := child.NextSibling
+			if child.Type == html.CommentNode {
+				node.RemoveChild(child)
+			} else {
+				removeComments(child)
+			}
+			child = next
+		}
+	}
+	for _, node := range body.Nodes {
+		removeComments(node)
+	}
+}
+
+// documentTitle returns the title of the page as declared in its head
+func documentTitle(doc *goquery.Document) string {
+	if title := CleanText(doc.Find("head title").First().Text()); title != "" {
+		return title
+	}
+	return CleanText(doc.Find(`meta[property="og:title"]`).First().AttrOr("content", ""))
+}
+
+// removeTitleHeading drops the first heading that repeats the page title,
+// which is shown separately. Titles often add the site name around the
+// heading, e.g. "Heading | Site".
+func removeTitleHeading(body *goquery.Selection, title string) {
+	if title == "" {
+		return
+	}
+	body.Find("h1, h2").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		heading := CleanText(s.Text())
+		if heading == "" {
+			return true
+		}
+		if heading == title || (utf8.RuneCountInString(heading) >= 10 &&
+			(strings.HasPrefix(title, heading) || strings.HasSuffix(title, heading))) {
+			s.Remove()
+		}
+		return false
+	})
+}
+
+func (r *readability) topCandidate(body *goquery.Selection) *html.Node {
+	var candidates []*html.Node
+
+	body.Find("*").Each(func(_ int, s *goquery.Selection) {
+		node := s.Get(0)
+		if !scoredTags[node.Data] {
+			return
+		}
+		// Containers only score when they hold text directly, like a paragraph
+		if (node.Data == "div" || node.Data == "section") && hasBlockChild(node) {
+			return
+		}
+
+		text := CleanText(textOf(node))
+		length := utf8.RuneCountInString(text)
+		if length < minScoredRunes {
+			return
+		}
+
+		score := 1.0 + float64(countCommas(text)) + math.Min(float64(length/100), 3)
+
+		level := 0
+		for ancestor := node.Parent; ancestor != nil && level < 3; ancestor = ancestor.Parent {
+			if ancestor.Type != html.ElementNode || ancestor.Data == "html" {
+				break
+			}
+			if _, ok := r.scores[ancestor]; !ok {
+				r.scores[ancestor] = initialScore(ancestor)
+				candidates = append(candidates, ancestor)
+			}
+			switch level {
+			case 0:
+				r.scores[ancestor] += score
+			case 1:
+				r.scores[ancestor] += score / 2
+			default:
+				r.scores[ancestor] += score / float64(level*3)
+			}
+			level++
+		}
+	})
+
+	var top *html.Node
+	topScore := 0.0
+	for _, candidate := range candidates {
+		score := r.scores[candidate] * (1 - linkDensity(candidate))
+		r.scores[candidate] = score
+		if top == nil || score > topScore {
+			top, topScore = candidate, score
+		}
+	}
+	if top == nil {
+		return nil
+	}
+
+	// Prefer the parent when the content is split across several candidates
+	// that share it, e.g. sections of a long post
+	for parent := top.Parent; parent != nil && parent.Type == html.ElementNode && parent.Data != "body"; parent = parent.Parent {
+		parentScore, scored := r.scores[parent]
+		if !scored || parentScore < topScore*0.75 {
+			break
+		}
+		top, topScore = parent, parentScore
+	}
+	return top
+}
+
+// withSiblings returns top together with the siblings that belong to the article
+func (r *readability) withSiblings(top *html.Node) []*html.Node {
+	parent := top.Parent
+	if parent == nil {
+		return []*html.Node{top}
+	}
+
+	threshold := math.Max(10, r.scores[top]*0.2)
+	topClass := getAttr(top, "class")
+
+	var nodes []*html.Node
+	for sibling := parent.FirstChild; sibling != nil; sibling = sibling.NextSibling {
+		if sibling.Type != html.ElementNode {
+			continue
+		}
+		if sibling == top {
+			nodes = append(nodes, sibling)
+			continue
+		}
+
+		bonus := 0.0
+		if topClass != "" && getAttr(sibling, "class") == topClass {
+			bonus = r.scores[top] * 0.2
+		}
+		if score, ok := r.scores[sibling]; ok && score+bonus >= threshold {
+			nodes = append(nodes, sibling)
+			continue
+		}
+
+		if sibling.Data == "p" {
+			text := CleanText(textOf(sibling))
+			length := utf8.RuneCountInString(text)
+			density := linkDensity(sibling)
+			if (length > 80 && density < 0.25) || (length > 0 && density == 0 && sentenceEnd.MatchString(text)) {
+				nodes = append(nodes, sibling)
+			}
+		}
+	}
+	return nodes
+}
+
+// cleanConditionally removes lists, tables and containers inside the
+// content that look like navigation or link farms
+func (r *readability) cleanConditionally(root *html.Node) {
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		for child := node.FirstChild; child != nil; {
+			next := child.NextSibling
+			if child.Type == html.ElementNode && r.shouldRemove(child) {
+				node.RemoveChild(child)
+			} else if child.Type == html.ElementNode && child.Data != "pre" {
+				walk(child)
+			}
+			child = next
+		}
+	}
+	walk(root)
+}
+
+func (r *readability) shouldRemove(node *html.Node) bool {
+	switch node.Data {
+	case "div", "section", "ul", "ol", "table", "header":
+	default:
+		return false
+	}
+	if hasDescendant(node, "pre") {
+		return false
+	}
+
+	weight := classWeight(node)
+	if weight < 0 {
+		return true
+	}
+
+	text := CleanText(textOf(node))
+	length := utf8.RuneCountInString(text)
+	density := linkDensity(node)
+
+	// Lists whose items are all links are navigation, unless they are long
+	// reference lists inside a well-scored section
+	if density > 0.5 && weight < 25 {
+		return true
+	}
+	if node.Data == "header" {
+		// A header repeating only the title and byline adds nothing
+		return length < 200 && !hasDescendant(node, "img")
+	}
+	if length < 25 && countDescendants(node, "img") == 0 && node.Data != "table" {
+		return strings.TrimSpace(text) == ""
+	}
+	return false
+}
+
+// initialScore gives the starting score of a candidate by tag and class names
+func initialScore(node *html.Node) float64 {
+	score := classWeight(node)
+	switch node.Data {
+	case "div", "article", "main":
+		score += 5
+	case "pre", "td", "blockquote":
+		score += 3
+	case "address", "ol", "ul", "dl", "dd", "dt", "li", "form":
+		score -= 3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		score -= 5
+	}
+	if node.Data == "article" || node.Data == "main" {
+		score += 20
+	}
+	return score
+}
+
+func classWeight(node *html.Node) float64 {
+	weight := 0.0
+	for _, name := range []string{getAttr(node, "class"), getAttr(node, "id")} {
+		if name == "" {
+			continue
+		}
+		if negativePattern.MatchString(name) {
+			weight -= 25
+		}
+		if positivePattern.MatchString(name) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// linkDensity is the share of the text of node that is inside links
+func linkDensity(node *html.Node) float64 {
+	total := utf8.RuneCountInString(CleanText(textOf(node)))
+	if total == 0 {
+		return 0
+	}
+
+	linked := 0
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			if child.Data == "a" {
+				linked += utf8.RuneCountInString(CleanText(textOf(child)))
+				continue
+			}
+			walk(child)
+		}
+	}
+	walk(node)
+	return float64(linked) / float64(total)
+}
+
+func countCommas(text string) int {
+	return strings.Count(text, ",") + strings.Count(text, "、") + strings.Count(text, "，")
+}
+
+func countDescendants(node *html.Node, tag string) int {
+	count := 0
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode {
+			continue
+		}
+		if child.Data == tag {
+			count++
+		}
+		count += countDescendants(child, tag)
+	}
+	return count
+}
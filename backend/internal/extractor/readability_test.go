@@ -0,0 +1,171 @@
+package extractor
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+// goldenPages are the fixtures in testdata/pages. Besides matching its golden
+// file, every extraction must keep the article and drop the page chrome.
+var goldenPages = []struct {
+	name           string
+	pageURL        string
+	mustContain    []string
+	mustNotContain []string
+}{
+	{
+		name:    "wordpress_blog",
+		pageURL: "https://gopher.example.com/2024/05/12/interfaces/",
+		mustContain: []string{
+			"Interfaces in Go are satisfied implicitly",
+			`<code class="language-go">type Reader interface {` + "\n\tRead(p []byte) (n int, err error)\n}</code>",
+			`<img src="https://gopher.example.com/wp-content/uploads/2024/05/gopher.png" alt="A gopher holding a plug"/>`,
+			`<img src="https://gopher.example.com/2024/05/uploads/2024/05/diagram.svg"`,
+			"<li>Prefer <em>behaviour</em> over <strong>data</strong>.</li>",
+			"reach for generics when the type matters",
+		},
+		mustNotContain: []string{"Skip to the content", "By Ken", "Share this", "Recent Posts", "thoughts on", "Powered by WordPress", "Generics in practice", "dataLayer"},
+	},
+	{
+		name:    "qiita_article",
+		pageURL: "https://qiita.com/gopher_jp/items/0123456789abcdef",
+		mustContain: []string{
+			"Goの<code>context</code>パッケージは",
+			"req, err := http.NewRequestWithContext(ctx, &#34;GET&#34;, url, nil)",
+			"<td>WithTimeout</td>",
+			"contextはリクエストの生存期間を表すものです。",
+		},
+		mustNotContain: []string{"ログイン", "LGTM", "目次", "広告", "Qiitaについて", "ハマったことがあります"},
+	},
+	{
+		name:    "news_article",
+		pageURL: "https://news.example.co.uk/news/local/cycle-lanes-approved",
+		mustContain: []string{
+			"A network of protected cycle lanes",
+			`<img src="https://news.example.co.uk/images/cycle-lane-640.jpg" alt="A cyclist on a protected lane"/>`,
+			"<blockquote><p>We listened to residents",
+			"expected to be completed by 2027.",
+		},
+		mustNotContain: []string{"cookies", "Advertisement", "Related stories", "Bus fares", "Sign up", "Most read", "Privacy", "Share on X", "Priya Shah"},
+	},
+	{
+		name:    "medium_post",
+		pageURL: "https://medium.com/engineering-notes/why-we-moved-back-to-a-monolith-4f2a",
+		mustContain: []string{
+			"Two years ago we split our billing system",
+			"<h2>What went wrong</h2>",
+			"<li>We replaced network calls with interfaces, one service at a time.</li>",
+			"The network between them was not.",
+		},
+		mustNotContain: []string{"Sign in", "Responses (14)", "More from Engineering Notes", "Scaling Postgres", "Why Our Team Moved"},
+	},
+	{
+		name:    "hatena_blog",
+		pageURL: "https://yurufuwa.hatenablog.com/entry/2024/06/01/100000",
+		mustContain: []string{
+			"Raspberry Pi 5に置き換えました",
+			`<pre class="lang-yaml">services:` + "\n  nextcloud:",
+			"問題はなくなりました。<br/>同じ構成",
+			`<img src="https://cdn-ak.f.st-hatena.com/images/fotolife/y/yurufuwa/20240601/rack.jpg" alt="サーバーラックの写真"/>`,
+		},
+		mustNotContain: []string{"関連記事", "プロフィール", "Bookmark", "電源周りは盲点", "ゆるふわ技術日記"},
+	},
+	{
+		name:    "div_soup",
+		pageURL: "http://widgets.example.org/docs/release-3.2.html",
+		mustContain: []string{
+			"<p>Version 3.2 is a maintenance release",
+			"<td>macOS</td>",
+			`<a href="http://widgets.example.org/docs/upgrade.html">upgrade guide</a>`,
+			"Download the release or read the",
+		},
+		mustNotContain: []string{"javascript:", "onclick", "Forum", "<font"},
+	},
+}
+
+func TestExtract_Golden(t *testing.T) {
+	for _, page := range goldenPages {
+		t.Run(page.name, func(t *testing.T) {
+			content := extractFixture(t, page.name, page.pageURL)
+
+			for _, want := range page.mustContain {
+				assert.Contains(t, content.HTML, want)
+			}
+			for _, unwanted := range page.mustNotContain {
+				assert.NotContains(t, content.HTML, unwanted)
+				assert.NotContains(t, content.Text, unwanted)
+			}
+
+			golden := filepath.Join("testdata", "golden", page.name+".golden")
+			got := formatGolden(content)
+			if *update {
+				require.NoError(t, os.MkdirAll(filepath.Dir(golden), 0o755))
+				require.NoError(t, os.WriteFile(golden, []byte(got), 0o644))
+			}
+			want, err := os.ReadFile(golden)
+			require.NoError(t, err, "run go test -update to create the golden file")
+			assert.Equal(t, string(want), got)
+		})
+	}
+}
+
+func TestExtract_DoesNotModifyDocument(t *testing.T) {
+	doc := loadFixture(t, "news_article")
+	before, err := doc.Html()
+	require.NoError(t, err)
+
+	Extract(doc, doc.Url)
+
+	after, err := doc.Html()
+	require.NoError(t, err)
+	assert.Equal(t, before, after)
+}
+
+func TestExtract_TruncatesLongContent(t *testing.T) {
+	paragraph := "<p>" + strings.Repeat("長い本文です。", 1000) + "</p>"
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(
+		"<html><body><article>" + strings.Repeat(paragraph, 20) + "</article></body></html>"))
+	require.NoError(t, err)
+
+	content := Extract(doc, nil)
+	assert.LessOrEqual(t, len([]rune(content.Text)), MaxContentRunes+100)
+	assert.True(t, strings.Contains(content.Text, "…"))
+	assert.True(t, strings.HasSuffix(content.HTML, "…</p>"))
+}
+
+func extractFixture(t *testing.T, name, pageURL string) *Content {
+	t.Helper()
+	doc := loadFixture(t, name)
+	u, err := url.Parse(pageURL)
+	require.NoError(t, err)
+	doc.Url = u
+	return Extract(doc, u)
+}
+
+func loadFixture(t *testing.T, name string) *goquery.Document {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", "pages", name+".html"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	doc, err := goquery.NewDocumentFromReader(f)
+	require.NoError(t, err)
+	doc.Url, _ = url.Parse("https://example.com/")
+	return doc
+}
+
+func formatGolden(content *Content) string {
+	return fmt.Sprintf("word_count: %d\nreading_time_seconds: %d\n\n--- html ---\n%s\n\n--- text ---\n%s\n",
+		content.WordCount, content.ReadingTimeSeconds, content.HTML, content.Text)
+}
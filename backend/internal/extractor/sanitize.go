@@ -0,0 +1,367 @@
+package extractor
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// allowedAttrs lists the elements kept in sanitized content and the
+// attributes each of them may carry
+var allowedAttrs = map[string][]string{
+	"a": {"href", "title"}, "img": {"src", "alt", "title"},
+	"h2": nil, "h3": nil, "h4": nil, "h5": nil, "h6": nil,
+	"p": nil, "br": nil, "hr": nil, "blockquote": nil,
+	"ul": nil, "ol": {"start"}, "li": nil, "dl": nil, "dt": nil, "dd": nil,
+	"pre": {"class"}, "code": {"class"},
+	"em": nil, "i": nil, "strong": nil, "b": nil, "u": nil, "s": nil, "del": nil, "ins": nil,
+	"mark": nil, "small": nil, "sub": nil, "sup": nil, "kbd": nil, "samp": nil, "var": nil,
+	"abbr": {"title"}, "cite": nil, "q": nil,
+	"figure": nil, "figcaption": nil,
+	"table": nil, "caption": nil, "thead": nil, "tbody": nil, "tfoot": nil, "tr": nil,
+	"th": {"colspan", "rowspan"}, "td": {"colspan", "rowspan"},
+}
+
+// droppedTags are removed together with everything inside them
+var droppedTags = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true, "head": true, "title": true,
+	"meta": true, "link": true, "iframe": true, "frame": true, "object": true, "embed": true,
+	"applet": true, "form": true, "input": true, "button": true, "select": true, "textarea": true,
+	"svg": true, "math": true, "canvas": true, "video": true, "audio": true, "source": true,
+	"track": true, "map": true, "dialog": true,
+}
+
+// blockTags are block-level elements; text directly inside an unknown
+// container without any of these children becomes a paragraph
+var blockTags = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "dd": true, "details": true,
+	"div": true, "dl": true, "dt": true, "fieldset": true, "figcaption": true, "figure": true,
+	"footer": true, "form": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true,
+	"h6": true, "header": true, "hr": true, "li": true, "main": true, "nav": true, "ol": true,
+	"p": true, "pre": true, "section": true, "table": true, "ul": true, "tr": true, "td": true,
+	"th": true, "tbody": true, "thead": true, "tfoot": true, "caption": true, "summary": true,
+}
+
+// tableParts are only kept inside a table; elsewhere they are unwrapped
+var tableParts = map[string]bool{
+	"caption": true, "thead": true, "tbody": true, "tfoot": true, "tr": true, "th": true, "td": true,
+}
+
+// keptEmpty elements are kept even when they contain no text
+var keptEmpty = map[string]bool{"img": true, "br": true, "hr": true, "td": true, "th": true}
+
+// flowParents may contain paragraphs created from unknown containers
+var flowParents = map[string]bool{
+	"": true, "blockquote": true, "li": true, "dd": true, "figure": true, "td": true, "th": true,
+}
+
+var codeClassPattern = regexp.MustCompile(`^(language|lang)-[\w+#.-]+$`)
+
+// lazyImageAttrs hold the real image URL on pages that lazy-load images
+var lazyImageAttrs = []string{"data-src", "data-original", "data-lazy-src", "data-actualsrc", "data-url"}
+
+// sanitizer copies the allowed parts of an HTML tree into a new tree
+type sanitizer struct {
+	base *url.URL
+	// budget is the number of text runes still allowed in the output
+	budget int
+	// tables is the number of tables being copied
+	tables int
+}
+
+// sanitize returns a detached root whose children are the sanitized copies
+// of the nodes of every part
+func (s *sanitizer) sanitize(parts []part) *html.Node {
+	root := &html.Node{Type: html.ElementNode, Data: ""}
+	for _, p := range parts {
+		s.base = p.base
+		for _, node := range p.nodes {
+			s.copyNode(node, root, false)
+		}
+	}
+	trimBlocks(root)
+	return root
+}
+
+func (s *sanitizer) copyNode(src, dst *html.Node, inPre bool) {
+	if s.budget <= 0 {
+		return
+	}
+
+	switch src.Type {
+	case html.TextNode:
+		s.copyText(src.Data, dst, inPre)
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	tag := src.Data
+	if droppedTags[tag] {
+		return
+	}
+	if tag == "h1" {
+		// The article title is shown separately
+		tag = "h2"
+	}
+
+	attrNames, allowed := allowedAttrs[tag]
+	if tableParts[tag] && s.tables == 0 {
+		// A layout cell was picked as the content, e.g. on table-based pages
+		allowed = false
+	}
+	if !allowed {
+		if blockTags[tag] && flowParents[dst.Data] && !hasBlockChild(src) && strings.TrimSpace(textOf(src)) != "" {
+			s.appendElement(src, dst, "p", nil, inPre)
+			return
+		}
+		// Unknown elements are unwrapped so that their content is kept
+		s.copyChildren(src, dst, inPre)
+		return
+	}
+
+	var attrs []html.Attribute
+	for _, name := range attrNames {
+		if value, ok := s.attr(src, tag, name); ok {
+			attrs = append(attrs, html.Attribute{Key: name, Val: value})
+		}
+	}
+
+	switch tag {
+	case "img":
+		if !hasAttr(attrs, "src") {
+			return
+		}
+	case "a":
+		if !hasAttr(attrs, "href") {
+			s.copyChildren(src, dst, inPre)
+			return
+		}
+	}
+
+	s.appendElement(src, dst, tag, attrs, inPre || tag == "pre")
+}
+
+func (s *sanitizer) appendElement(src, dst *html.Node, tag string, attrs []html.Attribute, inPre bool) {
+	element := &html.Node{Type: html.ElementNode, Data: tag, DataAtom: atom.Lookup([]byte(tag)), Attr: attrs}
+	if tag == "table" {
+		s.tables++
+		defer func() { s.tables-- }()
+	}
+	s.copyChildren(src, element, inPre)
+
+	if element.FirstChild == nil && !keptEmpty[tag] {
+		return
+	}
+	if !keptEmpty[tag] && strings.TrimSpace(textOf(element)) == "" && !hasDescendant(element, "img") {
+		return
+	}
+	dst.AppendChild(element)
+}
+
+func (s *sanitizer) copyChildren(src, dst *html.Node, inPre bool) {
+	for child := src.FirstChild; child != nil; child = child.NextSibling {
+		s.copyNode(child, dst, inPre)
+	}
+}
+
+func (s *sanitizer) copyText(text string, dst *html.Node, inPre bool) {
+	if !inPre {
+		text = collapseSpace(text)
+		if text == " " && (dst.Data == "" || !phrasingParent(dst)) {
+			return
+		}
+		// Avoid double spaces where inline elements were unwrapped
+		if last := dst.LastChild; strings.HasPrefix(text, " ") && last != nil && last.Type == html.TextNode && strings.HasSuffix(last.Data, " ") {
+			text = text[1:]
+		}
+	}
+	if text == "" {
+		return
+	}
+
+	runes := utf8.RuneCountInString(text)
+	if runes > s.budget {
+		text = TruncateRunes(text, s.budget)
+		runes = s.budget
+	}
+	s.budget -= runes
+
+	if last := dst.LastChild; last != nil && last.Type == html.TextNode {
+		last.Data += text
+		return
+	}
+	dst.AppendChild(&html.Node{Type: html.TextNode, Data: text})
+}
+
+// attr returns the sanitized value of an allowed attribute
+func (s *sanitizer) attr(node *html.Node, tag, name string) (string, bool) {
+	switch {
+	case tag == "img" && name == "src":
+		return s.imageSource(node)
+	case name == "href":
+		return s.resolveURL(getAttr(node, "href"), "http", "https", "mailto")
+	case name == "class":
+		// Only syntax highlighting hints survive
+		var classes []string
+		for _, class := range strings.Fields(getAttr(node, "class")) {
+			if codeClassPattern.MatchString(class) {
+				classes = append(classes, class)
+			}
+		}
+		return strings.Join(classes, " "), len(classes) > 0
+	}
+
+	value := strings.TrimSpace(getAttr(node, name))
+	return value, value != ""
+}
+
+func (s *sanitizer) imageSource(node *html.Node) (string, bool) {
+	candidates := make([]string, 0, len(lazyImageAttrs)+2)
+	for _, name := range lazyImageAttrs {
+		candidates = append(candidates, getAttr(node, name))
+	}
+	candidates = append(candidates, getAttr(node, "src"), firstSrcset(getAttr(node, "srcset")), firstSrcset(getAttr(node, "data-srcset")))
+
+	for _, candidate := range candidates {
+		if src, ok := s.resolveURL(candidate, "http", "https"); ok {
+			return src, true
+		}
+	}
+	return "", false
+}
+
+// resolveURL makes raw absolute against the page URL and accepts only the given schemes
+func (s *sanitizer) resolveURL(raw string, schemes ...string) (string, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" || strings.HasPrefix(raw, "#") {
+		return "", false
+	}
+	ref, err := url.Parse(raw)
+	if err != nil {
+		return "", false
+	}
+	if s.base != nil {
+		ref = s.base.ResolveReference(ref)
+	}
+	for _, scheme := range schemes {
+		if strings.EqualFold(ref.Scheme, scheme) {
+			return ref.String(), true
+		}
+	}
+	return "", false
+}
+
+func firstSrcset(srcset string) string {
+	first, _, _ := strings.Cut(strings.TrimSpace(srcset), ",")
+	fields := strings.Fields(first)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// trimBlocks removes whitespace at the start and end of block elements
+func trimBlocks(node *html.Node) {
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && child.Data != "pre" {
+			trimBlocks(child)
+		}
+	}
+	if node.Data != "" && !blockTags[node.Data] {
+		return
+	}
+	if first := node.FirstChild; first != nil && first.Type == html.TextNode {
+		first.Data = strings.TrimLeftFunc(first.Data, unicode.IsSpace)
+	}
+	if last := node.LastChild; last != nil && last.Type == html.TextNode {
+		last.Data = strings.TrimRightFunc(last.Data, unicode.IsSpace)
+	}
+}
+
+// phrasingParent reports whether whitespace is significant inside the element
+func phrasingParent(node *html.Node) bool {
+	switch node.Data {
+	case "ul", "ol", "dl", "table", "thead", "tbody", "tfoot", "tr", "figure":
+		return false
+	}
+	return true
+}
+
+func hasBlockChild(node *html.Node) bool {
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && (blockTags[child.Data] || hasBlockChild(child)) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasDescendant(node *html.Node, tag string) bool {
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && (child.Data == tag || hasDescendant(child, tag)) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAttr(attrs []html.Attribute, key string) bool {
+	for _, attr := range attrs {
+		if attr.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func getAttr(node *html.Node, key string) string {
+	for _, attr := range node.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// textOf returns the concatenated text below node
+func textOf(node *html.Node) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+			return
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(node)
+	return b.String()
+}
+
+// collapseSpace replaces every run of whitespace with a single space
+func collapseSpace(text string) string {
+	var b strings.Builder
+	b.Grow(len(text))
+	space := false
+	for _, r := range text {
+		if unicode.IsSpace(r) {
+			if !space {
+				b.WriteByte(' ')
+			}
+			space = true
+			continue
+		}
+		space = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
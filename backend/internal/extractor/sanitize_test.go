@@ -0,0 +1,87 @@
+package extractor
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sanitizeFragment(t *testing.T, fragment string) *Content {
+	t.Helper()
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader("<html><body><div id=\"root\">" + fragment + "</div></body></html>"))
+	require.NoError(t, err)
+	base, err := url.Parse("https://example.com/posts/1/")
+	require.NoError(t, err)
+	return FromSelection(doc.Find("#root").Children(), base)
+}
+
+func TestFromSelection_Sanitizes(t *testing.T) {
+	tests := []struct {
+		name     string
+		fragment string
+		want     string
+	}{
+		{
+			name:     "relative links and images become absolute",
+			fragment: `<p><a href="../2/">Next</a> <img src="img/a.png" alt="A"></p>`,
+			want:     `<p><a href="https://example.com/posts/2/">Next</a> <img src="https://example.com/posts/1/img/a.png" alt="A"/></p>`,
+		},
+		{
+			name:     "lazy images use their real source",
+			fragment: `<p><img src="data:image/gif;base64,R0lGOD" data-src="/real.jpg" alt="lazy"></p>`,
+			want:     `<p><img src="https://example.com/real.jpg" alt="lazy"/></p>`,
+		},
+		{
+			name:     "srcset is used when there is no src",
+			fragment: `<figure><img srcset="/small.jpg 320w, /large.jpg 1024w"></figure>`,
+			want:     `<figure><img src="https://example.com/small.jpg"/></figure>`,
+		},
+		{
+			name:     "script links are unwrapped and handlers dropped",
+			fragment: `<p onclick="steal()"><a href="javascript:alert(1)">Click</a> <a href="vbscript:x" style="color:red">here</a></p>`,
+			want:     `<p>Click here</p>`,
+		},
+		{
+			name:     "dangerous elements are removed with their content",
+			fragment: `<p>Safe</p><script>alert(1)</script><iframe src="https://evil.example"></iframe><form><input value="x"></form>`,
+			want:     `<p>Safe</p>`,
+		},
+		{
+			name:     "only language classes survive on code",
+			fragment: `<pre class="highlight lang-go"><code class="hljs language-go">x := 1</code></pre>`,
+			want:     `<pre class="lang-go"><code class="language-go">x := 1</code></pre>`,
+		},
+		{
+			name:     "whitespace inside code blocks is kept",
+			fragment: "<pre>if x {\n\treturn\n}</pre>",
+			want:     "<pre>if x {\n\treturn\n}</pre>",
+		},
+		{
+			name:     "h1 becomes h2 and unknown containers become paragraphs",
+			fragment: `<h1>Title</h1><div class="text">Plain <span>text</span></div>`,
+			want:     "<h2>Title</h2>\n<p>Plain text</p>",
+		},
+		{
+			name:     "empty elements are dropped",
+			fragment: `<p> </p><ul><li></li></ul><p><img alt="no source"></p><p>Kept</p>`,
+			want:     `<p>Kept</p>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, sanitizeFragment(t, tt.fragment).HTML)
+		})
+	}
+}
+
+func TestFromSelection_Text(t *testing.T) {
+	content := sanitizeFragment(t, `<h2>Heading</h2><p>First <b>paragraph</b>.</p><ul><li>One</li><li>Two</li></ul><p>Line<br>break</p>`)
+
+	assert.Equal(t, "Heading\n\nFirst paragraph.\n\nOne\nTwo\n\nLine\nbreak", content.Text)
+	assert.Equal(t, 7, content.WordCount)
+	assert.Equal(t, 2, content.ReadingTimeSeconds)
+}
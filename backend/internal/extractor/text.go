@@ -0,0 +1,83 @@
+package extractor
+
+import (
+	"math"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Reading speeds used for ReadingTime. Scripts written without spaces are
+// read character by character.
+const (
+	wordsPerMinute = 230
+	cjkPerMinute   = 500
+)
+
+// CleanText collapses runs of whitespace into single spaces and trims the result
+func CleanText(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// TruncateRunes shortens text to at most max runes, ending it with an
+// ellipsis when it is cut. It never splits a multi-byte character.
+func TruncateRunes(text string, max int) string {
+	if max <= 0 {
+		return ""
+	}
+	if len(text) <= max || utf8.RuneCountInString(text) <= max {
+		return text
+	}
+
+	count := 0
+	for i := range text {
+		if count == max-1 {
+			return strings.TrimRightFunc(text[:i], unicode.IsSpace) + "…"
+		}
+		count++
+	}
+	return text
+}
+
+// WordCount counts the words in text. Han, Hiragana and Katakana characters
+// count as one word each because those scripts do not separate words.
+func WordCount(text string) int {
+	words, cjk := countText(text)
+	return words + cjk
+}
+
+// ReadingTime estimates how long an average reader needs for text
+func ReadingTime(text string) time.Duration {
+	words, cjk := countText(text)
+	minutes := float64(words)/wordsPerMinute + float64(cjk)/cjkPerMinute
+	return time.Duration(math.Ceil(minutes*60)) * time.Second
+}
+
+func countText(text string) (words, cjk int) {
+	inWord := false
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			cjk++
+			inWord = false
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if !inWord {
+				words++
+				inWord = true
+			}
+		case inWord && (r == '\'' || r == '’'):
+			// Apostrophes keep contractions such as "don't" in one word
+		default:
+			inWord = false
+		}
+	}
+	return words, cjk
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) ||
+		unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) ||
+		r == 'ー'
+}
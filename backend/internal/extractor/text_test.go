@@ -0,0 +1,51 @@
+package extractor
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTruncateRunes(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		max  int
+		want string
+	}{
+		{name: "short text is unchanged", text: "hello", max: 10, want: "hello"},
+		{name: "exact length is unchanged", text: "こんにちは", max: 5, want: "こんにちは"},
+		{name: "japanese is cut on rune boundaries", text: "日本語のタイトルです", max: 5, want: "日本語の…"},
+		{name: "trailing space is trimmed before the ellipsis", text: "Go is fun to write", max: 7, want: "Go is…"},
+		{name: "emoji are not split", text: "🍣🍣🍣🍣", max: 3, want: "🍣🍣…"},
+		{name: "zero max", text: "abc", max: 0, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TruncateRunes(tt.text, tt.max)
+			assert.Equal(t, tt.want, got)
+			assert.True(t, utf8.ValidString(got))
+			assert.LessOrEqual(t, utf8.RuneCountInString(got), max(tt.max, 0))
+		})
+	}
+}
+
+func TestWordCount(t *testing.T) {
+	assert.Equal(t, 0, WordCount(""))
+	assert.Equal(t, 5, WordCount("Don't split contractions, please! 42"))
+	// Every Han, Hiragana and Katakana character is a word
+	assert.Equal(t, 8, WordCount("日本語のテキスト"))
+	assert.Equal(t, 8, WordCount("Goのcontextパッケージ"))
+}
+
+func TestReadingTime(t *testing.T) {
+	assert.Equal(t, time.Duration(0), ReadingTime(""))
+	assert.Equal(t, time.Minute, ReadingTime(strings.Repeat("word ", 230)))
+	assert.Equal(t, time.Minute, ReadingTime(strings.Repeat("語", 500)))
+	// A single word still takes a second
+	assert.Equal(t, time.Second, ReadingTime("Hi"))
+	assert.Equal(t, 90*time.Second, ReadingTime(strings.Repeat("word ", 230)+strings.Repeat("語", 250)))
+}
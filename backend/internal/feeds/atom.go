@@ -0,0 +1,81 @@
+package feeds
+
+import (
+	"strings"
+)
+
+type atomFeed struct {
+	Title   string      `xml:"title"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Links      []atomLink     `xml:"link"`
+	Summary    string         `xml:"summary"`
+	Content    string         `xml:"content"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Authors    []atomAuthor   `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr"`
+}
+
+func (f atomFeed) feed() *Feed {
+	feed := &Feed{
+		Title:   f.Title,
+		SiteURL: alternateLink(f.Links),
+	}
+	for _, e := range f.Entries {
+		entry := Entry{
+			ID:        e.ID,
+			URL:       alternateLink(e.Links),
+			Title:     e.Title,
+			Summary:   firstNonEmpty(e.Summary, e.Content),
+			Published: parseDate(e.Published, e.Updated),
+		}
+		var authors []string
+		for _, author := range e.Authors {
+			if name := strings.TrimSpace(author.Name); name != "" {
+				authors = append(authors, name)
+			}
+		}
+		entry.Author = strings.Join(authors, ", ")
+		for _, category := range e.Categories {
+			if name := strings.TrimSpace(firstNonEmpty(category.Label, category.Term)); name != "" {
+				entry.Categories = append(entry.Categories, name)
+			}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return feed
+}
+
+// alternateLink returns the link to the web page, which Atom marks with
+// rel="alternate" or leaves without rel
+func alternateLink(links []atomLink) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			if link.Type == "" || strings.Contains(link.Type, "html") {
+				return link.Href
+			}
+		}
+	}
+	return ""
+}
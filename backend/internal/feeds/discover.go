@@ -0,0 +1,78 @@
+package feeds
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// feedTypes are the media types of <link rel="alternate"> that point to a
+// feed
+var feedTypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/rdf+xml":   true,
+	"application/feed+json": true,
+}
+
+// Discover returns the feeds an HTML page announces with
+// <link rel="alternate">, resolved against base, in the order of the page
+func Discover(data []byte, base *url.URL) []string {
+	var links []string
+	seen := make(map[string]bool)
+	tokenizer := html.NewTokenizer(bytes.NewReader(data))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return links
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := tokenizer.TagName()
+			if !hasAttr {
+				continue
+			}
+			switch string(name) {
+			case "base":
+				if href := attrs(tokenizer)["href"]; href != "" {
+					if parsed, err := url.Parse(href); err == nil {
+						if base != nil {
+							parsed = base.ResolveReference(parsed)
+						}
+						base = parsed
+					}
+				}
+			case "link":
+				values := attrs(tokenizer)
+				if !hasToken(values["rel"], "alternate") || !feedTypes[strings.ToLower(strings.TrimSpace(values["type"]))] {
+					continue
+				}
+				if link := resolve(base, values["href"]); link != "" && !seen[link] {
+					seen[link] = true
+					links = append(links, link)
+				}
+			}
+		}
+	}
+}
+
+func attrs(tokenizer *html.Tokenizer) map[string]string {
+	values := make(map[string]string)
+	for {
+		key, value, more := tokenizer.TagAttr()
+		values[strings.ToLower(string(key))] = string(value)
+		if !more {
+			return values
+		}
+	}
+}
+
+// hasToken reports whether a space-separated list such as rel has token
+func hasToken(list, token string) bool {
+	for _, field := range strings.Fields(strings.ToLower(list)) {
+		if field == token {
+			return true
+		}
+	}
+	return false
+}
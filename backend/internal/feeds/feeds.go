@@ -0,0 +1,246 @@
+// Package feeds reads the documents of web feeds:
+//
+//   - RSS 2.0 and RSS 1.0 (RDF)
+//   - Atom
+//   - JSON Feed
+//
+// It also finds the feeds a web page links to and reads and writes OPML
+// subscription lists.
+package feeds
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+// ErrNotFeed is returned for documents that are not a feed of a known format
+var ErrNotFeed = errors.New("not a feed")
+
+// Feed is a parsed feed document
+type Feed struct {
+	Title string
+	// SiteURL is the web site the feed belongs to
+	SiteURL string
+	Entries []Entry
+}
+
+// Entry is an item of a feed. Entries without a link are left out.
+type Entry struct {
+	// ID identifies the entry across fetches: its guid or id, or its link
+	// when it has none
+	ID    string
+	URL   string
+	Title string
+	// Summary is the description of the entry as plain text
+	Summary    string
+	Author     string
+	Published  *time.Time
+	Categories []string
+}
+
+// Parse reads a feed document of any supported format. Relative links are
+// resolved against base, the URL the document was fetched from.
+func Parse(data []byte, base *url.URL) (*Feed, error) {
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	var (
+		feed *Feed
+		err  error
+	)
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		feed, err = parseJSONFeed(trimmed)
+	} else {
+		feed, err = parseXMLFeed(trimmed)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	feed.Title = strings.TrimSpace(feed.Title)
+	feed.SiteURL = resolve(base, feed.SiteURL)
+	entries := feed.Entries[:0]
+	for _, entry := range feed.Entries {
+		entry.URL = resolve(base, entry.URL)
+		if entry.URL == "" {
+			continue
+		}
+		entry.ID = strings.TrimSpace(entry.ID)
+		if entry.ID == "" {
+			entry.ID = entry.URL
+		}
+		entry.Title = strings.TrimSpace(htmlText(entry.Title))
+		entry.Summary = htmlText(entry.Summary)
+		entry.Author = strings.TrimSpace(entry.Author)
+		entries = append(entries, entry)
+	}
+	feed.Entries = entries
+	return feed, nil
+}
+
+// parseXMLFeed reads RSS and Atom documents, telling them apart by their
+// root element
+func parseXMLFeed(data []byte) (*Feed, error) {
+	decoder := newDecoder(data)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			// Includes io.EOF for documents without any element
+			return nil, ErrNotFeed
+		}
+		root, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch strings.ToLower(root.Name.Local) {
+		case "rss":
+			var doc rssDocument
+			if err := decoder.DecodeElement(&doc, &root); err != nil {
+				return nil, err
+			}
+			return doc.Channel.feed(doc.Channel.Items), nil
+		case "rdf":
+			var doc rdfDocument
+			if err := decoder.DecodeElement(&doc, &root); err != nil {
+				return nil, err
+			}
+			return doc.Channel.feed(doc.Items), nil
+		case "feed":
+			var doc atomFeed
+			if err := decoder.DecodeElement(&doc, &root); err != nil {
+				return nil, err
+			}
+			return doc.feed(), nil
+		default:
+			return nil, ErrNotFeed
+		}
+	}
+}
+
+// newDecoder returns a lenient decoder that reads documents in the charset
+// they declare
+func newDecoder(data []byte) *xml.Decoder {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+	decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		// HTTP clients convert bodies to UTF-8 when the Content-Type header
+		// names a charset, leaving the declaration of the document behind
+		if utf8.Valid(data) {
+			return input, nil
+		}
+		return charset.NewReaderLabel(label, input)
+	}
+	return decoder
+}
+
+// dateLayouts are the date formats found in feeds, most common first
+var dateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC3339,
+	time.RFC3339Nano,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 02 Jan 2006 15:04 -0700",
+	"Mon, 2 Jan 2006 15:04 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// parseDate reads a feed date in UTC, or returns nil when it is in no
+// known format
+func parseDate(values ...string) *time.Time {
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		for _, layout := range dateLayouts {
+			if t, err := time.Parse(layout, value); err == nil {
+				t = t.UTC()
+				return &t
+			}
+		}
+	}
+	return nil
+}
+
+// resolve makes a link absolute. Links that are not http(s) are dropped.
+func resolve(base *url.URL, link string) string {
+	link = strings.TrimSpace(link)
+	if link == "" {
+		return ""
+	}
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	if base != nil {
+		parsed = base.ResolveReference(parsed)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return ""
+	}
+	return parsed.String()
+}
+
+// htmlText returns the text of an HTML fragment with whitespace collapsed.
+// Feeds often escape markup in titles and descriptions.
+func htmlText(fragment string) string {
+	if !strings.ContainsAny(fragment, "<&") {
+		return strings.Join(strings.Fields(fragment), " ")
+	}
+
+	var b strings.Builder
+	tokenizer := html.NewTokenizer(strings.NewReader(fragment))
+	skip := 0
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return strings.Join(strings.Fields(b.String()), " ")
+		case html.TextToken:
+			if skip == 0 {
+				b.Write(tokenizer.Text())
+			}
+		case html.StartTagToken:
+			name, _ := tokenizer.TagName()
+			switch string(name) {
+			case "script", "style":
+				skip++
+			case "br", "p", "div", "li", "h1", "h2", "h3", "h4", "h5", "h6", "blockquote", "tr":
+				b.WriteByte(' ')
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			switch string(name) {
+			case "script", "style":
+				if skip > 0 {
+					skip--
+				}
+			case "p", "div", "li", "h1", "h2", "h3", "h4", "h5", "h6", "blockquote", "tr":
+				b.WriteByte(' ')
+			}
+		}
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,238 @@
+package feeds
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/japanese"
+)
+
+var base, _ = url.Parse("https://blog.example.com/feed.xml")
+
+func TestParse_RSS(t *testing.T) {
+	feed, err := Parse([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:atom="http://www.w3.org/2005/Atom" xmlns:content="http://purl.org/rss/1.0/modules/content/">
+<channel>
+  <title>Example Blog</title>
+  <atom:link href="https://blog.example.com/feed.xml" rel="self" type="application/rss+xml"/>
+  <link>https://blog.example.com/</link>
+  <item>
+    <title>Tom &amp;amp; Jerry</title>
+    <link>/posts/1</link>
+    <guid isPermaLink="false">post-1</guid>
+    <description>&lt;p&gt;First &lt;b&gt;post&lt;/b&gt;&lt;/p&gt;&lt;p&gt;More&lt;/p&gt;</description>
+    <pubDate>Mon, 06 May 2024 09:30:00 +0900</pubDate>
+    <dc:creator>Alice</dc:creator>
+    <category>Go</category>
+    <category> Web </category>
+  </item>
+  <item>
+    <title>No link</title>
+    <guid>https://blog.example.com/posts/2</guid>
+    <content:encoded><![CDATA[<p>Body</p>]]></content:encoded>
+    <pubDate>Tue, 7 May 2024 10:00:00 GMT</pubDate>
+  </item>
+  <item>
+    <title>Skipped, nothing to link to</title>
+  </item>
+</channel>
+</rss>`), base)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Example Blog", feed.Title)
+	assert.Equal(t, "https://blog.example.com/", feed.SiteURL)
+	require.Len(t, feed.Entries, 2)
+
+	first := feed.Entries[0]
+	assert.Equal(t, "post-1", first.ID)
+	assert.Equal(t, "https://blog.example.com/posts/1", first.URL)
+	assert.Equal(t, "Tom & Jerry", first.Title)
+	assert.Equal(t, "First post More", first.Summary)
+	assert.Equal(t, "Alice", first.Author)
+	assert.Equal(t, []string{"Go", "Web"}, first.Categories)
+	require.NotNil(t, first.Published)
+	assert.Equal(t, time.Date(2024, 5, 6, 0, 30, 0, 0, time.UTC), *first.Published)
+
+	second := feed.Entries[1]
+	assert.Equal(t, "https://blog.example.com/posts/2", second.URL)
+	assert.Equal(t, second.URL, second.ID)
+	assert.Equal(t, "Body", second.Summary)
+	require.NotNil(t, second.Published)
+	assert.Equal(t, time.Date(2024, 5, 7, 10, 0, 0, 0, time.UTC), *second.Published)
+}
+
+func TestParse_RDF(t *testing.T) {
+	feed, err := Parse([]byte(`<?xml version="1.0" encoding="Shift_JIS"?>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+  <channel rdf:about="https://news.example.jp/">
+    <title>News</title>
+    <link>https://news.example.jp/</link>
+  </channel>
+  <item rdf:about="https://news.example.jp/a">
+    <title>Article</title>
+    <link>https://news.example.jp/a</link>
+    <dc:date>2024-05-06T09:30:00+09:00</dc:date>
+  </item>
+</rdf:RDF>`), base)
+	require.NoError(t, err)
+
+	assert.Equal(t, "News", feed.Title)
+	require.Len(t, feed.Entries, 1)
+	assert.Equal(t, "https://news.example.jp/a", feed.Entries[0].ID)
+	require.NotNil(t, feed.Entries[0].Published)
+	assert.Equal(t, time.Date(2024, 5, 6, 0, 30, 0, 0, time.UTC), *feed.Entries[0].Published)
+}
+
+func TestParse_Charset(t *testing.T) {
+	document := `<?xml version="1.0" encoding="Shift_JIS"?>
+<rss version="2.0"><channel><title>日本語のブログ</title>
+<item><title>記事</title><link>https://example.jp/1</link></item>
+</channel></rss>`
+	encoded, err := japanese.ShiftJIS.NewEncoder().String(document)
+	require.NoError(t, err)
+
+	// Read in the declared charset, or as is once converted to UTF-8
+	for _, data := range []string{encoded, document} {
+		feed, err := Parse([]byte(data), base)
+		require.NoError(t, err)
+		assert.Equal(t, "日本語のブログ", feed.Title)
+		require.Len(t, feed.Entries, 1)
+		assert.Equal(t, "記事", feed.Entries[0].Title)
+	}
+}
+
+func TestParse_Atom(t *testing.T) {
+	feed, err := Parse([]byte(`<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title type="text">Atom Blog</title>
+  <link rel="self" href="https://atom.example.com/feed"/>
+  <link href="https://atom.example.com/"/>
+  <entry>
+    <id>tag:atom.example.com,2024:1</id>
+    <title type="html">A &lt;em&gt;fine&lt;/em&gt; post</title>
+    <link rel="replies" href="https://atom.example.com/1#comments"/>
+    <link rel="alternate" type="text/html" href="https://atom.example.com/1"/>
+    <updated>2024-05-08T00:00:00Z</updated>
+    <published>2024-05-06T00:00:00Z</published>
+    <author><name>Bob</name></author>
+    <author><name>Carol</name></author>
+    <category term="go" label="Go"/>
+    <summary>Short</summary>
+    <content type="html">Long</content>
+  </entry>
+</feed>`), base)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Atom Blog", feed.Title)
+	assert.Equal(t, "https://atom.example.com/", feed.SiteURL)
+	require.Len(t, feed.Entries, 1)
+	entry := feed.Entries[0]
+	assert.Equal(t, "tag:atom.example.com,2024:1", entry.ID)
+	assert.Equal(t, "https://atom.example.com/1", entry.URL)
+	assert.Equal(t, "A fine post", entry.Title)
+	assert.Equal(t, "Short", entry.Summary)
+	assert.Equal(t, "Bob, Carol", entry.Author)
+	assert.Equal(t, []string{"Go"}, entry.Categories)
+	assert.Equal(t, time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC), *entry.Published)
+}
+
+func TestParse_JSONFeed(t *testing.T) {
+	feed, err := Parse([]byte(`{
+  "version": "https://jsonfeed.org/version/1.1",
+  "title": "JSON Blog",
+  "home_page_url": "https://json.example.com/",
+  "items": [
+    {"id": 42, "url": "https://json.example.com/42", "title": "Answer", "content_html": "<p>Hi</p>",
+     "date_published": "2024-05-06T00:00:00Z", "authors": [{"name": "Dan"}], "tags": ["life"]},
+    {"id": "x", "external_url": "https://elsewhere.example.com/", "content_text": "Link post"}
+  ]
+}`), base)
+	require.NoError(t, err)
+
+	assert.Equal(t, "JSON Blog", feed.Title)
+	require.Len(t, feed.Entries, 2)
+	assert.Equal(t, "42", feed.Entries[0].ID)
+	assert.Equal(t, "Hi", feed.Entries[0].Summary)
+	assert.Equal(t, "Dan", feed.Entries[0].Author)
+	assert.Equal(t, []string{"life"}, feed.Entries[0].Categories)
+	assert.Equal(t, "https://elsewhere.example.com/", feed.Entries[1].URL)
+}
+
+func TestParse_NotFeed(t *testing.T) {
+	for _, document := range []string{
+		`<!DOCTYPE html><html><head><title>Page</title></head><body></body></html>`,
+		`{"name": "not a feed"}`,
+		``,
+	} {
+		_, err := Parse([]byte(document), base)
+		assert.ErrorIs(t, err, ErrNotFeed, document)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	page, _ := url.Parse("https://site.example.com/blog/post")
+	links := Discover([]byte(`<!DOCTYPE html>
+<html><head>
+<link rel="stylesheet" href="/style.css">
+<link rel="alternate" type="application/rss+xml" title="RSS" href="/feed.xml">
+<link rel="alternate" type="application/atom+xml" href="https://site.example.com/atom.xml">
+<link rel="alternate" hreflang="ja" href="/ja/">
+<link rel="Alternate" type="Application/RSS+XML" href="/feed.xml">
+<link rel="alternate" type="application/feed+json" href="feed.json">
+</head><body></body></html>`), page)
+
+	assert.Equal(t, []string{
+		"https://site.example.com/feed.xml",
+		"https://site.example.com/atom.xml",
+		"https://site.example.com/blog/feed.json",
+	}, links)
+	assert.Empty(t, Discover([]byte(`<html><head></head></html>`), page))
+}
+
+func TestOPML_RoundTrip(t *testing.T) {
+	subscriptions := []Subscription{
+		{Title: "Go Blog", FeedURL: "https://go.dev/blog/feed.atom", SiteURL: "https://go.dev/blog/", Folders: []string{"Tech", "Go"}},
+		{Title: "News & Views", FeedURL: "https://news.example.com/rss?a=1&b=2"},
+		{Title: "Rust Blog", FeedURL: "https://blog.rust-lang.org/feed.xml", Folders: []string{"Tech"}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteOPML(&buf, "Stockle feeds", time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC), subscriptions))
+	assert.Contains(t, buf.String(), "<dateCreated>Mon, 06 May 2024 00:00:00 +0000</dateCreated>")
+
+	parsed, err := ParseOPML(strings.NewReader(buf.String()))
+	require.NoError(t, err)
+	// Feeds of a folder come after its subfolders
+	assert.Equal(t, []Subscription{
+		subscriptions[0],
+		subscriptions[2],
+		subscriptions[1],
+	}, parsed)
+}
+
+func TestParseOPML(t *testing.T) {
+	subscriptions, err := ParseOPML(strings.NewReader(`<?xml version="1.0"?>
+<opml version="1.0">
+  <body>
+    <outline text="News">
+      <outline text="Example" type="rss" xmlUrl="https://example.com/rss" htmlUrl="https://example.com/"/>
+      <outline text="A site, not a feed" htmlUrl="https://example.com/site"/>
+    </outline>
+    <outline title="Top" xmlUrl="https://top.example.com/atom"/>
+    <outline text="Bad" xmlUrl="javascript:alert(1)"/>
+  </body>
+</opml>`))
+	require.NoError(t, err)
+	assert.Equal(t, []Subscription{
+		{Title: "Example", FeedURL: "https://example.com/rss", SiteURL: "https://example.com/", Folders: []string{"News"}},
+		{Title: "Top", FeedURL: "https://top.example.com/atom"},
+	}, subscriptions)
+
+	_, err = ParseOPML(strings.NewReader(`<html><body>not opml</body></html>`))
+	assert.ErrorIs(t, err, ErrNotFeed)
+}
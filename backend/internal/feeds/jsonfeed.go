@@ -0,0 +1,81 @@
+package feeds
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// jsonFeed is a JSON Feed document, version 1 or 1.1
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            json.RawMessage  `json:"id"`
+	URL           string           `json:"url"`
+	ExternalURL   string           `json:"external_url"`
+	Title         string           `json:"title"`
+	Summary       string           `json:"summary"`
+	ContentText   string           `json:"content_text"`
+	ContentHTML   string           `json:"content_html"`
+	DatePublished string           `json:"date_published"`
+	DateModified  string           `json:"date_modified"`
+	Author        *jsonFeedAuthor  `json:"author"`
+	Authors       []jsonFeedAuthor `json:"authors"`
+	Tags          []string         `json:"tags"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+func parseJSONFeed(data []byte) (*Feed, error) {
+	var doc jsonFeed
+	if err := json.Unmarshal(data, &doc); err != nil || !strings.Contains(doc.Version, "jsonfeed.org") {
+		return nil, ErrNotFeed
+	}
+
+	feed := &Feed{
+		Title:   doc.Title,
+		SiteURL: doc.HomePageURL,
+	}
+	for _, item := range doc.Items {
+		entry := Entry{
+			ID:        jsonFeedID(item.ID),
+			URL:       firstNonEmpty(item.URL, item.ExternalURL),
+			Title:     item.Title,
+			Summary:   firstNonEmpty(item.Summary, item.ContentText, item.ContentHTML),
+			Published: parseDate(item.DatePublished, item.DateModified),
+		}
+		authors := item.Authors
+		if item.Author != nil {
+			authors = append(authors, *item.Author)
+		}
+		var names []string
+		for _, author := range authors {
+			if name := strings.TrimSpace(author.Name); name != "" {
+				names = append(names, name)
+			}
+		}
+		entry.Author = strings.Join(names, ", ")
+		for _, tag := range item.Tags {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				entry.Categories = append(entry.Categories, tag)
+			}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return feed, nil
+}
+
+// jsonFeedID reads an item ID, which version 1 allowed to be a number
+func jsonFeedID(raw json.RawMessage) string {
+	var id string
+	if err := json.Unmarshal(raw, &id); err == nil {
+		return id
+	}
+	return strings.TrimSpace(string(raw))
+}
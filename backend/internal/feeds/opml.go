@@ -0,0 +1,109 @@
+package feeds
+
+import (
+	"bufio"
+	"errors"
+	"html"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/eikuma/stockle/backend/internal/opml"
+)
+
+// Subscription is a feed of an OPML subscription list
+type Subscription struct {
+	Title   string
+	FeedURL string
+	SiteURL string
+	// Folders is the path of the outline the feed is in
+	Folders []string
+}
+
+// ParseOPML reads the feeds of an OPML subscription list. Outlines with an
+// xmlUrl attribute are feeds; outlines without one are folders of the
+// outlines inside them.
+func ParseOPML(r io.Reader) ([]Subscription, error) {
+	outlines, err := opml.Parse(r, func(attrs map[string]string) bool {
+		return resolve(nil, attrs["xmlurl"]) != ""
+	})
+	if errors.Is(err, opml.ErrNotOPML) {
+		return nil, ErrNotFeed
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	subscriptions := make([]Subscription, 0, len(outlines))
+	for _, outline := range outlines {
+		subscriptions = append(subscriptions, Subscription{
+			Title:   outline.Title(),
+			FeedURL: resolve(nil, outline.Attrs["xmlurl"]),
+			SiteURL: resolve(nil, outline.Attrs["htmlurl"]),
+			Folders: outline.Folders,
+		})
+	}
+	return subscriptions, nil
+}
+
+// opmlFolder is a folder of the outline being written
+type opmlFolder struct {
+	name    string
+	folders []*opmlFolder
+	feeds   []Subscription
+}
+
+func (f *opmlFolder) folder(name string) *opmlFolder {
+	for _, folder := range f.folders {
+		if folder.name == name {
+			return folder
+		}
+	}
+	folder := &opmlFolder{name: name}
+	f.folders = append(f.folders, folder)
+	return folder
+}
+
+// WriteOPML writes an OPML 2.0 subscription list with a folder outline for
+// every folder path. Folders and feeds keep the order of subscriptions.
+func WriteOPML(w io.Writer, title string, created time.Time, subscriptions []Subscription) error {
+	root := &opmlFolder{}
+	for _, subscription := range subscriptions {
+		folder := root
+		for _, name := range subscription.Folders {
+			folder = folder.folder(name)
+		}
+		folder.feeds = append(folder.feeds, subscription)
+	}
+
+	b := bufio.NewWriter(w)
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<opml version="2.0">` + "\n")
+	b.WriteString("  <head>\n")
+	b.WriteString("    <title>" + html.EscapeString(title) + "</title>\n")
+	b.WriteString("    <dateCreated>" + created.UTC().Format(time.RFC1123Z) + "</dateCreated>\n")
+	b.WriteString("  </head>\n")
+	b.WriteString("  <body>\n")
+	writeOPMLFolder(b, root, 2)
+	b.WriteString("  </body>\n")
+	b.WriteString("</opml>\n")
+	return b.Flush()
+}
+
+func writeOPMLFolder(b *bufio.Writer, folder *opmlFolder, depth int) {
+	indent := strings.Repeat("  ", depth)
+	for _, child := range folder.folders {
+		name := html.EscapeString(child.name)
+		b.WriteString(indent + `<outline text="` + name + `" title="` + name + `">` + "\n")
+		writeOPMLFolder(b, child, depth+1)
+		b.WriteString(indent + "</outline>\n")
+	}
+	for _, feed := range folder.feeds {
+		title := html.EscapeString(firstNonEmpty(feed.Title, feed.FeedURL))
+		b.WriteString(indent + `<outline type="rss" text="` + title + `" title="` + title + `" xmlUrl="` + html.EscapeString(feed.FeedURL) + `"`)
+		if feed.SiteURL != "" {
+			b.WriteString(` htmlUrl="` + html.EscapeString(feed.SiteURL) + `"`)
+		}
+		b.WriteString("/>\n")
+	}
+}
@@ -0,0 +1,74 @@
+package feeds
+
+import (
+	"strings"
+)
+
+// rssDocument is an RSS 2.0 document. Elements are matched by their local
+// name, so dc:creator and content:encoded are read whatever prefix the
+// document gives their namespace.
+type rssDocument struct {
+	Channel rssChannel `xml:"channel"`
+}
+
+// rdfDocument is an RSS 1.0 document, whose items are siblings of the
+// channel rather than inside it
+type rdfDocument struct {
+	Channel rssChannel `xml:"channel"`
+	Items   []rssItem  `xml:"item"`
+}
+
+type rssChannel struct {
+	Title string `xml:"title"`
+	// Links has the plain link of the channel and any atom:link, which has
+	// no text
+	Links []string  `xml:"link"`
+	Items []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Links       []string `xml:"link"`
+	GUID        string   `xml:"guid"`
+	Description string   `xml:"description"`
+	Encoded     string   `xml:"encoded"`
+	PubDate     string   `xml:"pubDate"`
+	Date        string   `xml:"date"`
+	Author      string   `xml:"author"`
+	Creator     string   `xml:"creator"`
+	Categories  []string `xml:"category"`
+	About       string   `xml:"about,attr"`
+}
+
+func (c rssChannel) feed(items []rssItem) *Feed {
+	feed := &Feed{
+		Title:   c.Title,
+		SiteURL: firstNonEmpty(c.Links...),
+	}
+	for _, item := range items {
+		entry := Entry{
+			ID:        firstNonEmpty(item.GUID, item.About),
+			URL:       firstNonEmpty(item.Links...),
+			Title:     item.Title,
+			Summary:   firstNonEmpty(item.Description, item.Encoded),
+			Author:    firstNonEmpty(item.Creator, item.Author),
+			Published: parseDate(item.PubDate, item.Date),
+		}
+		// A guid that is a permalink stands in for a missing link
+		if entry.URL == "" && isWebURL(item.GUID) {
+			entry.URL = item.GUID
+		}
+		for _, category := range item.Categories {
+			if category = strings.TrimSpace(category); category != "" {
+				entry.Categories = append(entry.Categories, category)
+			}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return feed
+}
+
+func isWebURL(value string) bool {
+	value = strings.ToLower(strings.TrimSpace(value))
+	return strings.HasPrefix(value, "http://") || strings.HasPrefix(value, "https://")
+}
@@ -0,0 +1,111 @@
+package importer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Folders of Instapaper that are states rather than folders
+const (
+	instapaperUnread  = "unread"
+	instapaperArchive = "archive"
+	instapaperStarred = "starred"
+)
+
+// parseCSV reads the CSV exports of Pocket and Instapaper, which differ in
+// their columns:
+//
+//	title,url,time_added,tags,status          (Pocket, tags separated by |)
+//	URL,Title,Selection,Folder,Timestamp,Tags (Instapaper, tags as JSON)
+//
+// Columns are looked up by name, so either layout and reordered columns
+// are read.
+func parseCSV(r io.Reader) ([]Item, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, err
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if _, ok := columns[name]; !ok {
+			columns[name] = i
+		}
+	}
+	if _, ok := columns["url"]; !ok {
+		return nil, errors.New("the CSV file has no url column")
+	}
+	get := func(record []string, names ...string) string {
+		for _, name := range names {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+		}
+		return ""
+	}
+
+	var items []Item
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return items, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+
+		item := Item{
+			Position: row,
+			URL:      get(record, "url"),
+			Title:    get(record, "title"),
+			Tags:     parseCSVTags(get(record, "tags")),
+			SavedAt:  parseUnixTime(get(record, "time_added", "timestamp")),
+		}
+
+		switch status := strings.ToLower(get(record, "status")); status {
+		case "archive", "archived", "read":
+			item.Archived = true
+		}
+		if favorite, err := strconv.ParseBool(get(record, "favorite", "is_favorite")); err == nil {
+			item.Favorite = favorite
+		}
+
+		folder := get(record, "folder")
+		switch strings.ToLower(folder) {
+		case "", instapaperUnread:
+		case instapaperArchive:
+			item.Archived = true
+		case instapaperStarred:
+			item.Favorite = true
+		default:
+			item.Folders = splitTags(folder, "/")
+		}
+
+		items = append(items, item)
+	}
+}
+
+// parseCSVTags reads a JSON array of tags, or names separated by | or ,
+func parseCSVTags(value string) []string {
+	if strings.HasPrefix(value, "[") {
+		var tags []string
+		if err := json.Unmarshal([]byte(value), &tags); err == nil {
+			return splitTags(strings.Join(tags, "\n"), "\n")
+		}
+	}
+	return splitTags(value, "|,")
+}
@@ -0,0 +1,137 @@
+package importer
+
+import (
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// parseHTML reads Pocket HTML exports and Netscape bookmark files. Both
+// list links as <a> elements with the date and tags in attributes:
+//
+//	<h1>Read Archive</h1>
+//	<ul><li><a href="..." time_added="1700000000" tags="go,web">Title</a></li></ul>
+//
+//	<DT><H3>Folder</H3>
+//	<DL><p>
+//	    <DT><A HREF="..." ADD_DATE="1700000000" TAGS="go">Title</A>
+//	</DL><p>
+//
+// Bookmark files are not well-formed HTML, so the tokens are followed
+// instead of a parsed tree: an <h3> names the folder of the <dl> that comes
+// next, and Pocket's "Read Archive" heading archives the links below it.
+func parseHTML(r io.Reader) ([]Item, error) {
+	tokenizer := html.NewTokenizer(r)
+
+	var (
+		items    []Item
+		folders  []string
+		pending  *string // folder named by an <h3> whose <dl> has not started
+		current  = -1    // index of the link whose title is being read
+		heading  *strings.Builder
+		skip     bool // the pending folder is one every browser has
+		skipped  []bool
+		archived bool
+		line     = 1
+	)
+
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			if err := tokenizer.Err(); err != io.EOF {
+				return nil, err
+			}
+			return items, nil
+		}
+		raw := tokenizer.Raw()
+		position := line
+		line += strings.Count(string(raw), "\n")
+		token := tokenizer.Token()
+
+		switch tokenType {
+		case html.StartTagToken:
+			switch token.DataAtom.String() {
+			case "a":
+				href := attr(token, "href")
+				if href == "" {
+					continue
+				}
+				item := Item{
+					Position: position,
+					URL:      strings.TrimSpace(href),
+					Folders:  append([]string(nil), folders...),
+					Tags:     splitTags(attr(token, "tags"), ","),
+					SavedAt:  parseUnixTime(firstAttr(token, "time_added", "add_date")),
+					Archived: archived,
+				}
+				items = append(items, item)
+				current = len(items) - 1
+			case "h1", "h3":
+				heading = &strings.Builder{}
+				skip = token.DataAtom.String() == "h3" &&
+					(attr(token, "personal_toolbar_folder") == "true" || attr(token, "unfiled_bookmarks_folder") == "true")
+			case "dl":
+				if pending != nil && !skip {
+					folders = append(folders, *pending)
+					skipped = append(skipped, false)
+				} else {
+					skipped = append(skipped, true)
+				}
+				pending = nil
+				skip = false
+			}
+		case html.TextToken:
+			switch {
+			case current >= 0:
+				items[current].Title += token.Data
+			case heading != nil:
+				heading.WriteString(token.Data)
+			}
+		case html.EndTagToken:
+			switch token.DataAtom.String() {
+			case "a":
+				if current >= 0 {
+					items[current].Title = strings.Join(strings.Fields(items[current].Title), " ")
+					current = -1
+				}
+			case "h1":
+				if heading != nil {
+					archived = strings.EqualFold(strings.TrimSpace(heading.String()), "read archive")
+					heading = nil
+				}
+			case "h3":
+				if heading != nil {
+					name := strings.Join(strings.Fields(heading.String()), " ")
+					pending = &name
+					heading = nil
+				}
+			case "dl":
+				if n := len(skipped); n > 0 {
+					if !skipped[n-1] {
+						folders = folders[:len(folders)-1]
+					}
+					skipped = skipped[:n-1]
+				}
+			}
+		}
+	}
+}
+
+func attr(token html.Token, name string) string {
+	for _, a := range token.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func firstAttr(token html.Token, names ...string) string {
+	for _, name := range names {
+		if value := attr(token, name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
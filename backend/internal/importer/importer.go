@@ -0,0 +1,160 @@
+// Package importer reads the link exports of other read-later tools and
+// browsers into a common list of items:
+//
+//   - Pocket HTML and CSV exports
+//   - Instapaper CSV exports
+//   - Netscape bookmark files, which every browser exports
+//   - OPML outlines with links
+//   - plain lists of URLs, one per line
+//
+// Parsing only reads the file; checking URLs and saving the articles is
+// left to the caller, so a bad row never fails the whole file.
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Format is the kind of file an import reads
+type Format string
+
+const (
+	FormatPocketHTML    Format = "pocket_html"
+	FormatPocketCSV     Format = "pocket_csv"
+	FormatInstapaperCSV Format = "instapaper_csv"
+	FormatBookmarks     Format = "bookmarks_html"
+	FormatOPML          Format = "opml"
+	FormatURLList       Format = "url_list"
+)
+
+// Formats lists every supported format
+var Formats = []Format{
+	FormatPocketHTML,
+	FormatPocketCSV,
+	FormatInstapaperCSV,
+	FormatBookmarks,
+	FormatOPML,
+	FormatURLList,
+}
+
+// ErrUnknownFormat is returned for files whose format cannot be detected
+// and for unsupported format names
+var ErrUnknownFormat = errors.New("unknown import format")
+
+// Item is one link of an export
+type Item struct {
+	// Position is the line, or the row for CSV files, the link was read
+	// from, so that errors can point at it
+	Position int
+	URL      string
+	Title    string
+	// Folders is the path of folders the link was filed in, outermost
+	// first
+	Folders  []string
+	Tags     []string
+	SavedAt  *time.Time
+	Archived bool
+	Favorite bool
+}
+
+// ParseFormat returns the format with the given name
+func ParseFormat(name string) (Format, error) {
+	for _, format := range Formats {
+		if string(format) == name {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnknownFormat, name)
+}
+
+// Detect guesses the format of a file from its name and first bytes
+func Detect(fileName string, data []byte) (Format, error) {
+	head := data
+	if len(head) > 4096 {
+		head = head[:4096]
+	}
+	lower := bytes.ToLower(bytes.TrimSpace(bytes.TrimPrefix(head, []byte("\xef\xbb\xbf"))))
+
+	switch {
+	case bytes.Contains(lower, []byte("netscape-bookmark-file")):
+		return FormatBookmarks, nil
+	case bytes.Contains(lower, []byte("<opml")):
+		return FormatOPML, nil
+	case bytes.HasPrefix(lower, []byte("<")):
+		if bytes.Contains(lower, []byte("pocket")) || bytes.Contains(lower, []byte("time_added")) {
+			return FormatPocketHTML, nil
+		}
+		// Other HTML exports use the bookmark file layout
+		return FormatBookmarks, nil
+	}
+
+	firstLine, _, _ := bytes.Cut(lower, []byte("\n"))
+	header := string(firstLine)
+	switch {
+	case strings.Contains(header, "time_added") && strings.Contains(header, "url"):
+		return FormatPocketCSV, nil
+	case strings.Contains(header, "url") && strings.Contains(header, "folder"):
+		return FormatInstapaperCSV, nil
+	}
+
+	ext := strings.ToLower(filepath.Ext(fileName))
+	if ext == ".txt" || ext == "" || bytes.HasPrefix(lower, []byte("http")) {
+		return FormatURLList, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// Parse reads every item of a file in the given format
+func Parse(format Format, r io.Reader) ([]Item, error) {
+	switch format {
+	case FormatPocketHTML, FormatBookmarks:
+		return parseHTML(r)
+	case FormatPocketCSV, FormatInstapaperCSV:
+		return parseCSV(r)
+	case FormatOPML:
+		return parseOPML(r)
+	case FormatURLList:
+		return parseURLList(r)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+	}
+}
+
+// parseUnixTime reads the seconds since the epoch that exports store dates
+// as; anything else is no date
+func parseUnixTime(value string) *time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	var seconds int64
+	if _, err := fmt.Sscan(value, &seconds); err != nil || seconds <= 0 {
+		return nil
+	}
+	// Some browsers write microseconds
+	if seconds > 1e14 {
+		seconds /= 1e6
+	}
+	t := time.Unix(seconds, 0).UTC()
+	return &t
+}
+
+// splitTags splits a list of tags on any of the separators and drops
+// empty names
+func splitTags(value, separators string) []string {
+	var tags []string
+	for _, tag := range strings.FieldsFunc(value, func(r rune) bool {
+		return strings.ContainsRune(separators, r)
+	}) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,141 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parseFile(t *testing.T, name string) (Format, []Item) {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	format, err := Detect(name, data)
+	require.NoError(t, err)
+	items, err := Parse(format, strings.NewReader(string(data)))
+	require.NoError(t, err)
+	return format, items
+}
+
+func unix(seconds int64) *time.Time {
+	t := time.Unix(seconds, 0).UTC()
+	return &t
+}
+
+func TestParse_Bookmarks(t *testing.T) {
+	format, items := parseFile(t, "bookmarks.html")
+	assert.Equal(t, FormatBookmarks, format)
+
+	// The bookmarks bar is left out of the folders, nested folders are kept
+	require.Len(t, items, 5)
+	assert.Equal(t, Item{Position: 9, URL: "https://go.dev/blog/", Title: "The Go Blog", SavedAt: unix(1700000100)}, items[0])
+	assert.Equal(t, "Go & generics", items[1].Title)
+	assert.Equal(t, []string{"Tech"}, items[1].Folders)
+	assert.Equal(t, []string{"go", "generics"}, items[1].Tags)
+	assert.Equal(t, []string{"Tech", "Web"}, items[2].Folders)
+	assert.Nil(t, items[2].SavedAt)
+	assert.Empty(t, items[3].Folders)
+	assert.Equal(t, "https://example.com/root", items[4].URL)
+	assert.Empty(t, items[4].Folders)
+}
+
+func TestParse_PocketHTML(t *testing.T) {
+	format, items := parseFile(t, "pocket.html")
+	assert.Equal(t, FormatPocketHTML, format)
+
+	require.Len(t, items, 2)
+	assert.Equal(t, "Unread article", items[0].Title)
+	assert.Equal(t, []string{"go", "backend"}, items[0].Tags)
+	assert.Equal(t, unix(1600000000), items[0].SavedAt)
+	assert.False(t, items[0].Archived)
+	assert.True(t, items[1].Archived)
+	assert.Empty(t, items[1].Tags)
+}
+
+func TestParse_PocketCSV(t *testing.T) {
+	format, items := parseFile(t, "pocket.csv")
+	assert.Equal(t, FormatPocketCSV, format)
+
+	require.Len(t, items, 3)
+	assert.Equal(t, Item{
+		Position: 2,
+		URL:      "https://example.com/unread",
+		Title:    "Unread article",
+		Tags:     []string{"go", "backend"},
+		SavedAt:  unix(1600000000),
+	}, items[0])
+	assert.Equal(t, "Read, with comma", items[1].Title)
+	assert.True(t, items[1].Archived)
+	// Rows without a URL are kept so that the import can report them
+	assert.Equal(t, 4, items[2].Position)
+	assert.Empty(t, items[2].URL)
+}
+
+func TestParse_InstapaperCSV(t *testing.T) {
+	format, items := parseFile(t, "instapaper.csv")
+	assert.Equal(t, FormatInstapaperCSV, format)
+
+	require.Len(t, items, 4)
+	assert.Equal(t, []string{"go", "backend"}, items[0].Tags)
+	assert.Empty(t, items[0].Folders)
+	assert.True(t, items[1].Archived)
+	assert.Empty(t, items[1].Tags)
+	assert.True(t, items[2].Favorite)
+	assert.Equal(t, []string{"Reading", "Later"}, items[3].Folders)
+	assert.Equal(t, unix(1300000000), items[3].SavedAt)
+}
+
+func TestParse_OPML(t *testing.T) {
+	format, items := parseFile(t, "links.opml")
+	assert.Equal(t, FormatOPML, format)
+
+	require.Len(t, items, 3)
+	assert.Equal(t, "https://go.dev/blog/", items[0].URL)
+	assert.Equal(t, []string{"Tech"}, items[0].Folders)
+	assert.Equal(t, []string{"go", "generics"}, items[1].Tags)
+	require.NotNil(t, items[1].SavedAt)
+	assert.Equal(t, time.Date(2006, 1, 2, 22, 4, 5, 0, time.UTC), *items[1].SavedAt)
+	assert.Equal(t, "Top", items[2].Title)
+	assert.Empty(t, items[2].Folders)
+}
+
+func TestParse_URLList(t *testing.T) {
+	format, items := parseFile(t, "urls.txt")
+	assert.Equal(t, FormatURLList, format)
+
+	assert.Equal(t, []Item{
+		{Position: 2, URL: "https://example.com/one"},
+		{Position: 4, URL: "https://example.com/two"},
+		{Position: 5, URL: "not"},
+	}, items)
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		data   string
+		format Format
+	}{
+		{name: "url list without extension", file: "links", data: "https://example.com\n", format: FormatURLList},
+		{name: "csv with bom", file: "export.csv", data: "\ufefftitle,url,time_added,tags,status\n", format: FormatPocketCSV},
+		{name: "plain html", file: "export.html", data: "<html><body><a href=\"https://example.com\">x</a></body></html>", format: FormatBookmarks},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, err := Detect(tt.file, []byte(tt.data))
+			require.NoError(t, err)
+			assert.Equal(t, tt.format, format)
+		})
+	}
+
+	_, err := Detect("export.csv", []byte("a,b,c\n1,2,3\n"))
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+	_, err = ParseFormat("evernote")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
@@ -0,0 +1,50 @@
+package importer
+
+import (
+	"io"
+	"time"
+
+	"github.com/eikuma/stockle/backend/internal/opml"
+)
+
+// parseOPML reads the links of an OPML outline. Outlines with a url or
+// htmlUrl attribute are links; outlines without one are folders of the
+// outlines inside them. Feeds are imported as their site, since the feed
+// document itself is not an article.
+func parseOPML(r io.Reader) ([]Item, error) {
+	outlines, err := opml.Parse(r, func(attrs map[string]string) bool {
+		return outlineLink(attrs) != ""
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]Item, 0, len(outlines))
+	for _, outline := range outlines {
+		item := Item{
+			Position: outline.Line,
+			URL:      outlineLink(outline.Attrs),
+			Title:    outline.Title(),
+			Folders:  outline.Folders,
+			Tags:     splitTags(outline.Attrs["category"], ",/"),
+		}
+		created := outline.Attrs["created"]
+		if savedAt, err := time.Parse(time.RFC1123Z, created); err == nil {
+			savedAt = savedAt.UTC()
+			item.SavedAt = &savedAt
+		} else if savedAt, err := time.Parse(time.RFC1123, created); err == nil {
+			savedAt = savedAt.UTC()
+			item.SavedAt = &savedAt
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// outlineLink returns the link of an outline, or "" for a folder
+func outlineLink(attrs map[string]string) string {
+	if link := attrs["url"]; link != "" {
+		return link
+	}
+	return attrs["htmlurl"]
+}
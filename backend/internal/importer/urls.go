@@ -0,0 +1,30 @@
+package importer
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// maxLineLength bounds a line of a URL list; longer lines are an error
+const maxLineLength = 64 * 1024
+
+// parseURLList reads one URL per line. Blank lines and lines starting with
+// # are skipped, and text after the URL, such as a title, is ignored.
+func parseURLList(r io.Reader) ([]Item, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), maxLineLength)
+
+	var items []Item
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		items = append(items, Item{
+			Position: line,
+			URL:      strings.Fields(text)[0],
+		})
+	}
+	return items, scanner.Err()
+}
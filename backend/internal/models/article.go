@@ -9,8 +9,12 @@ type Article struct {
 	UserID                  string     `json:"userId" gorm:"not null;type:varchar(36);index"`
 	CategoryID              *string    `json:"categoryId,omitempty" gorm:"type:varchar(36);index"`
 	URL                     string     `json:"url" gorm:"not null;type:text"`
+	CanonicalURL            *string    `json:"canonicalUrl,omitempty" gorm:"type:text"`
+	URLHash                 *string    `json:"-" gorm:"type:varchar(64)"`
+	CanonicalURLHash        *string    `json:"-" gorm:"type:varchar(64)"`
 	Title                   string     `json:"title" gorm:"not null;type:varchar(500)"`
 	Content                 *string    `json:"content,omitempty" gorm:"type:longtext"`
+	ContentHTML             *string    `json:"contentHtml,omitempty" gorm:"type:longtext"`
 	Summary                 *string    `json:"summary,omitempty" gorm:"type:text"`
 	SummaryShort            *string    `json:"summaryShort,omitempty" gorm:"type:text"`
 	SummaryLong             *string    `json:"summaryLong,omitempty" gorm:"type:longtext"`
@@ -26,9 +30,14 @@ type Article struct {
 	ReadingTimeSeconds      int        `json:"readingTimeSeconds" gorm:"default:0"`
 	WordCount               *int       `json:"wordCount,omitempty"`
 	Language                string     `json:"language" gorm:"type:varchar(10);default:'ja'"`
+	Keywords                StringList `json:"keywords,omitempty" gorm:"type:text"`
 	SummaryGenerationStatus string     `json:"summaryGenerationStatus" gorm:"type:varchar(20);default:'pending'"`
 	SummaryGeneratedAt      *time.Time `json:"summaryGeneratedAt,omitempty"`
 	SummaryModelVersion     *string    `json:"summaryModelVersion,omitempty" gorm:"type:varchar(100)"`
+	SummaryStrategy         *string    `json:"summaryStrategy,omitempty" gorm:"type:varchar(20)"`
+	SummaryConfidence       *float64   `json:"summaryConfidence,omitempty"`
+	ExtractionStatus        string     `json:"extractionStatus" gorm:"type:varchar(20);default:'pending'"`
+	ExtractionError         *string    `json:"extractionError,omitempty" gorm:"type:text"`
 	CreatedAt               time.Time  `json:"createdAt" gorm:"autoCreateTime"`
 	UpdatedAt               time.Time  `json:"updatedAt" gorm:"autoUpdateTime"`
 
@@ -45,10 +54,28 @@ const (
 	ArticleStatusArchived = "archived"
 )
 
+// ExtractionStatus represents possible content extraction statuses
+const (
+	ExtractionStatusPending    = "pending"
+	ExtractionStatusProcessing = "processing"
+	ExtractionStatusCompleted  = "completed"
+	ExtractionStatusFailed     = "failed"
+)
+
 // SummaryGenerationStatus represents possible summary generation statuses
 const (
 	SummaryStatusPending   = "pending"
 	SummaryStatusProcessing = "processing"
 	SummaryStatusCompleted = "completed"
 	SummaryStatusFailed    = "failed"
+	// SummaryStatusSkipped is set when the user turned automatic summaries
+	// off; the article is summarized on request
+	SummaryStatusSkipped = "skipped"
+)
+
+// Summary strategies: a single prompt, or the summaries of the chunks of a
+// long article reduced to one
+const (
+	SummaryStrategySingle    = "single"
+	SummaryStrategyMapReduce = "map_reduce"
 )
\ No newline at end of file
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+)
+
+// Summary types, the lengths a summary is generated in
+const (
+	SummaryTypeShort  = "short"
+	SummaryTypeMedium = "medium"
+	SummaryTypeLong   = "long"
+)
+
+// Summary issues, the problems the validation of a summary finds
+const (
+	SummaryIssueTooShort        = "too_short"
+	SummaryIssueTooLong         = "too_long"
+	SummaryIssueLanguage        = "language_mismatch"
+	SummaryIssueRefusal         = "refusal"
+	SummaryIssueBoilerplate     = "boilerplate"
+	SummaryIssueUnsupportedFact = "unsupported_facts"
+	SummaryIssueLowJudgeScore   = "low_judge_score"
+)
+
+// SummaryTypes lists the summary types from the shortest
+var SummaryTypes = []string{SummaryTypeShort, SummaryTypeMedium, SummaryTypeLong}
+
+// IsSummaryType reports whether t is one of SummaryTypes
+func IsSummaryType(t string) bool {
+	for _, summaryType := range SummaryTypes {
+		if t == summaryType {
+			return true
+		}
+	}
+	return false
+}
+
+// ArticleSummary is a generated summary of an article. Every generation is
+// kept: IsCurrent marks the latest of each type and language. The latest of
+// each type is also on the article as Summary, SummaryShort or SummaryLong.
+type ArticleSummary struct {
+	ID          string `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	ArticleID   string `json:"articleId" gorm:"not null;type:varchar(36);index"`
+	SummaryType string `json:"summaryType" gorm:"not null;type:varchar(10)"`
+	// Language is the language the summary is written in
+	Language     string `json:"language" gorm:"not null;type:varchar(10)"`
+	Content      string `json:"content" gorm:"not null;type:text"`
+	Provider     string `json:"provider" gorm:"type:varchar(50)"`
+	ModelVersion string `json:"modelVersion" gorm:"type:varchar(100)"`
+	Strategy     string `json:"strategy" gorm:"type:varchar(20)"`
+	// Confidence is the quality score of the summary from 0 to 1, lowered
+	// by the Issues its validation found
+	Confidence float64    `json:"confidence" gorm:"not null;default:0"`
+	Issues     StringList `json:"issues,omitempty" gorm:"type:text"`
+	IsCurrent  bool       `json:"isCurrent" gorm:"not null;default:true"`
+	CreatedAt  time.Time  `json:"createdAt" gorm:"autoCreateTime"`
+}
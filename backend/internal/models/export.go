@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+// Export is a file of a user's articles generated by a background job and
+// kept until ExpiresAt for download
+type Export struct {
+	ID     string `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	UserID string `json:"userId" gorm:"not null;type:varchar(36);index"`
+	Format string `json:"format" gorm:"not null;type:varchar(30)"`
+	// ArticleIDs or Query select the exported articles; all of them are
+	// exported when both are empty
+	ArticleIDs   StringList `json:"articleIds,omitempty" gorm:"type:text"`
+	Query        *string    `json:"query,omitempty" gorm:"type:text"`
+	Status       string     `json:"status" gorm:"not null;type:varchar(20);default:'pending'"`
+	ArticleCount int        `json:"articleCount" gorm:"not null;default:0"`
+	FileSize     int64      `json:"fileSize" gorm:"not null;default:0"`
+	FilePath     string     `json:"-" gorm:"type:varchar(500)"`
+	ErrorMessage *string    `json:"errorMessage,omitempty" gorm:"type:text"`
+	CreatedAt    time.Time  `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time  `json:"updatedAt" gorm:"autoUpdateTime"`
+	CompletedAt  *time.Time `json:"completedAt,omitempty"`
+	ExpiresAt    *time.Time `json:"expiresAt,omitempty" gorm:"index"`
+}
+
+// ExportStatus represents possible export statuses
+const (
+	ExportStatusPending    = "pending"
+	ExportStatusProcessing = "processing"
+	ExportStatusCompleted  = "completed"
+	ExportStatusFailed     = "failed"
+	// ExportStatusExpired exports had their file deleted
+	ExportStatusExpired = "expired"
+)
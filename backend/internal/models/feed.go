@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+)
+
+// Feed is a subscription to an RSS, Atom or JSON Feed. New entries of the
+// feed are saved as unread articles in its category, with its tags.
+type Feed struct {
+	ID      string  `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	UserID  string  `json:"userId" gorm:"not null;type:varchar(36);index"`
+	URL     string  `json:"url" gorm:"not null;type:text"`
+	SiteURL *string `json:"siteUrl,omitempty" gorm:"type:text"`
+	Title   string  `json:"title" gorm:"not null;type:varchar(500)"`
+	// CategoryID is where new articles go; the default category when nil
+	CategoryID *string    `json:"categoryId,omitempty" gorm:"type:varchar(36)"`
+	Tags       StringList `json:"tags" gorm:"type:text"`
+	// IncludeKeywords, when set, keep only the entries mentioning one of
+	// them; entries mentioning one of ExcludeKeywords are skipped
+	IncludeKeywords StringList `json:"includeKeywords" gorm:"type:text"`
+	ExcludeKeywords StringList `json:"excludeKeywords" gorm:"type:text"`
+	IsActive        bool       `json:"isActive" gorm:"not null;default:true"`
+	// ETag and LastModified make the next fetch conditional
+	ETag         *string `json:"-" gorm:"column:etag;type:varchar(255)"`
+	LastModified *string `json:"-" gorm:"type:varchar(255)"`
+	// LastFetchedAt is the last successful fetch
+	LastFetchedAt *time.Time `json:"lastFetchedAt,omitempty"`
+	NextFetchAt   time.Time  `json:"nextFetchAt" gorm:"not null;index"`
+	// ErrorCount is the number of failed fetches in a row, which lengthens
+	// the time to the next one
+	ErrorCount int       `json:"errorCount" gorm:"not null;default:0"`
+	LastError  *string   `json:"lastError,omitempty" gorm:"type:text"`
+	CreatedAt  time.Time `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+// FeedEntry records an entry of a feed that was seen, so that it is saved
+// at most once even after its article is deleted
+type FeedEntry struct {
+	FeedID string `gorm:"primaryKey;type:varchar(36)"`
+	// EntryKey is the SHA-256 of the entry ID
+	EntryKey string `gorm:"primaryKey;type:varchar(64)"`
+	// ArticleID is the article saved for the entry, if it was not filtered out
+	ArticleID *string   `gorm:"type:varchar(36)"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+)
+
+// Import is a file of links from another read-later tool or a browser that
+// is being saved as articles by a background job
+type Import struct {
+	ID         string  `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	UserID     string  `json:"userId" gorm:"not null;type:varchar(36);index"`
+	Format     string  `json:"format" gorm:"not null;type:varchar(30)"`
+	FileName   string  `json:"fileName" gorm:"type:varchar(255)"`
+	CategoryID *string `json:"categoryId,omitempty" gorm:"type:varchar(36)"`
+	Status     string  `json:"status" gorm:"not null;type:varchar(20);default:'pending'"`
+	// Counts of the items by outcome; Processed is the sum of the others
+	TotalItems     int        `json:"totalItems" gorm:"not null;default:0"`
+	ProcessedItems int        `json:"processedItems" gorm:"not null;default:0"`
+	ImportedItems  int        `json:"importedItems" gorm:"not null;default:0"`
+	DuplicateItems int        `json:"duplicateItems" gorm:"not null;default:0"`
+	FailedItems    int        `json:"failedItems" gorm:"not null;default:0"`
+	ErrorMessage   *string    `json:"errorMessage,omitempty" gorm:"type:text"`
+	CreatedAt      time.Time  `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt      time.Time  `json:"updatedAt" gorm:"autoUpdateTime"`
+	CompletedAt    *time.Time `json:"completedAt,omitempty"`
+}
+
+// ImportItem is one link of an import, saved while the import runs
+type ImportItem struct {
+	ImportID string `json:"-" gorm:"primaryKey;type:varchar(36)"`
+	// Position is the line or row of the link in the file
+	Position  int        `json:"position" gorm:"primaryKey"`
+	URL       string     `json:"url" gorm:"not null;type:text"`
+	Title     string     `json:"title,omitempty" gorm:"type:varchar(500)"`
+	Folders   StringList `json:"folders,omitempty" gorm:"type:text"`
+	Tags      StringList `json:"tags,omitempty" gorm:"type:text"`
+	SavedAt   *time.Time `json:"savedAt,omitempty"`
+	Archived  bool       `json:"archived" gorm:"not null;default:false"`
+	Favorite  bool       `json:"favorite" gorm:"not null;default:false"`
+	Status    string     `json:"status" gorm:"not null;type:varchar(20);default:'pending'"`
+	ArticleID *string    `json:"articleId,omitempty" gorm:"type:varchar(36)"`
+	Error     *string    `json:"error,omitempty" gorm:"type:text"`
+}
+
+// ImportStatus represents possible import statuses
+const (
+	ImportStatusPending    = "pending"
+	ImportStatusProcessing = "processing"
+	ImportStatusCompleted  = "completed"
+	ImportStatusFailed     = "failed"
+)
+
+// ImportItemStatus represents the outcome of an import item
+const (
+	ImportItemStatusPending   = "pending"
+	ImportItemStatusImported  = "imported"
+	ImportItemStatusDuplicate = "duplicate"
+	ImportItemStatusFailed    = "failed"
+)
@@ -15,6 +15,8 @@ type JobQueue struct {
 	ErrorMessage *string    `json:"error_message,omitempty" gorm:"type:text"`
 	CreatedAt    time.Time  `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt    time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+	// ScheduledAt delays a retry; the job is not picked up before this time
+	ScheduledAt  *time.Time `json:"scheduled_at,omitempty"`
 	StartedAt    *time.Time `json:"started_at,omitempty"`
 	CompletedAt  *time.Time `json:"completed_at,omitempty"`
 }
@@ -29,7 +31,11 @@ const (
 
 // JobType represents possible job types
 const (
-	JobTypeSummarize = "summarize"
+	JobTypeSummarize      = "summarize"
+	JobTypeExtractContent = "extract_content"
+	JobTypeImportArticles = "import_articles"
+	JobTypeExportArticles = "export_articles"
+	JobTypePollFeed       = "poll_feed"
 )
 
 // JobPriority represents job priority levels
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+)
+
+// SearchDocument is an article in the fulltext index with the number of
+// terms in each of its fields, which BM25 needs to normalize for length
+type SearchDocument struct {
+	ArticleID     string `gorm:"primaryKey;type:varchar(36)"`
+	UserID        string `gorm:"not null;type:varchar(36);index"`
+	TitleLength   int    `gorm:"not null;default:0"`
+	SummaryLength int    `gorm:"not null;default:0"`
+	ContentLength int    `gorm:"not null;default:0"`
+	TagsLength    int    `gorm:"not null;default:0"`
+	// Version is the tokenizer version the article was indexed with
+	Version   int       `gorm:"not null;default:0"`
+	IndexedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+// SearchPosting records that an article contains a term, and how often in
+// each field
+type SearchPosting struct {
+	ArticleID        string `gorm:"primaryKey;type:varchar(36)"`
+	Term             string `gorm:"primaryKey;type:varchar(64)"`
+	UserID           string `gorm:"not null;type:varchar(36)"`
+	TitleFrequency   int    `gorm:"not null;default:0"`
+	SummaryFrequency int    `gorm:"not null;default:0"`
+	ContentFrequency int    `gorm:"not null;default:0"`
+	TagsFrequency    int    `gorm:"not null;default:0"`
+}
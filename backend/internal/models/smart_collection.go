@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// SmartCollection is a saved search that lists the articles matching Query,
+// written in the articlequery syntax, like a category that fills itself
+type SmartCollection struct {
+	ID           string    `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	UserID       string    `json:"userId" gorm:"not null;type:varchar(36);index"`
+	Name         string    `json:"name" gorm:"not null;type:varchar(100)"`
+	Query        string    `json:"query" gorm:"not null;type:text"`
+	Color        string    `json:"color" gorm:"type:varchar(7);default:'#6B7280'"`
+	DisplayOrder int       `json:"displayOrder" gorm:"default:0"`
+	ArticleCount int       `json:"articleCount" gorm:"->;-:migration"`
+	QueryError   string    `json:"queryError,omitempty" gorm:"-"` // set when Query no longer parses
+	CreatedAt    time.Time `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+
+	// Associations
+	User *User `json:"-" gorm:"foreignKey:UserID"`
+}
@@ -1,13 +1,17 @@
 package models
 
 import (
+	"strings"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type Tag struct {
 	ID         string    `json:"id" gorm:"primaryKey;type:varchar(36)"`
 	UserID     string    `json:"userId" gorm:"not null;type:varchar(36);index"`
-	Name       string    `json:"name" gorm:"not null;type:varchar(50);uniqueIndex:idx_user_tag_name"`
+	Name       string    `json:"name" gorm:"not null;type:varchar(50)"`
+	NameKey    *string   `json:"-" gorm:"type:varchar(100)"`
 	UsageCount int       `json:"usageCount" gorm:"default:0"`
 	CreatedAt  time.Time `json:"createdAt" gorm:"autoCreateTime"`
 	UpdatedAt  time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
@@ -17,6 +21,19 @@ type Tag struct {
 	User     *User     `json:"-" gorm:"foreignKey:UserID"`
 }
 
+// TagNameKey is the key that makes tag names differing only in case the same
+// tag for a user
+func TagNameKey(name string) string {
+	return strings.ToLower(name)
+}
+
+// BeforeCreate keys the tag by its name
+func (t *Tag) BeforeCreate(tx *gorm.DB) error {
+	key := TagNameKey(t.Name)
+	t.NameKey = &key
+	return nil
+}
+
 // ArticleTag represents the join table for article-tag relationship
 type ArticleTag struct {
 	ArticleID string    `gorm:"primaryKey;type:varchar(36)"`
@@ -8,7 +8,7 @@ type User struct {
 	BaseModel
 	Email         string         `json:"email" gorm:"uniqueIndex;size:255;not null" validate:"required,email"`
 	PasswordHash  *string        `json:"-" gorm:"size:255"`
-	GoogleID      string         `json:"google_id,omitempty" gorm:"uniqueIndex;size:255"`
+	GoogleID      *string        `json:"google_id,omitempty" gorm:"uniqueIndex;size:255"`
 	Name          string         `json:"name" gorm:"size:255;not null" validate:"required,min=1,max=255"`
 	DisplayName   string         `json:"display_name" gorm:"size:255;not null" validate:"required,min=1,max=255"`
 	AuthProvider  string         `json:"auth_provider" gorm:"size:50;default:'email'" validate:"required"`
@@ -24,7 +24,7 @@ type User struct {
 
 type UserSession struct {
 	BaseModel
-	UserID      uint      `json:"user_id" gorm:"not null;index"`
+	UserID      string    `json:"user_id" gorm:"not null;type:varchar(36);index"`
 	TokenHash   string    `json:"-" gorm:"uniqueIndex;size:500;not null"`
 	UserAgent   string    `json:"user_agent,omitempty" gorm:"size:500"`
 	IPAddress   string    `json:"ip_address,omitempty" gorm:"size:45"`
@@ -37,13 +37,15 @@ type UserSession struct {
 }
 
 type UserPreference struct {
-	UserID             uint   `json:"user_id" gorm:"primaryKey"`
+	UserID             string `json:"user_id" gorm:"primaryKey;type:varchar(36)"`
 	Language           string `json:"language" gorm:"size:10;default:'ja'"`
 	Theme              string `json:"theme" gorm:"size:10;default:'light'"`
 	NotificationsEmail bool   `json:"notifications_email" gorm:"default:true"`
 	NotificationsPush  bool   `json:"notifications_push" gorm:"default:false"`
 	AutoSummarize      bool   `json:"auto_summarize" gorm:"default:true"`
 	SummaryLanguage    string `json:"summary_language" gorm:"size:10;default:'ja'"`
+	// SummaryLength is the summary type generated when an article is saved
+	SummaryLength string `json:"summary_length" gorm:"size:10;default:'medium'"`
 	
 	TimestampModel
 	
@@ -51,6 +53,24 @@ type UserPreference struct {
 	User User `json:"user,omitempty" gorm:"constraint:OnDelete:CASCADE"`
 }
 
+// SummaryLanguageOriginal as UserPreference.SummaryLanguage writes each
+// summary in the language of its article
+const SummaryLanguageOriginal = "original"
+
+// DefaultUserPreference returns the preferences of a user who never saved
+// any, the defaults of the user_preferences table
+func DefaultUserPreference(userID string) *UserPreference {
+	return &UserPreference{
+		UserID:             userID,
+		Language:           "ja",
+		Theme:              "light",
+		NotificationsEmail: true,
+		AutoSummarize:      true,
+		SummaryLanguage:    "ja",
+		SummaryLength:      SummaryTypeMedium,
+	}
+}
+
 type UserCreateRequest struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required,min=8,max=128"`
@@ -68,7 +88,7 @@ type UserUpdateRequest struct {
 }
 
 type UserResponse struct {
-	ID            uint                `json:"id"`
+	ID            string              `json:"id"`
 	Email         string              `json:"email"`
 	Name          string              `json:"name"`
 	DisplayName   string              `json:"display_name"`
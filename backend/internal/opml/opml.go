@@ -0,0 +1,99 @@
+// Package opml reads the outlines of OPML documents, the format of feed
+// subscription lists and of some link exports.
+package opml
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+// ErrNotOPML is returned for a document without an opml element
+var ErrNotOPML = errors.New("not an OPML document")
+
+// Outline is an outline holding an entry rather than other outlines
+type Outline struct {
+	// Line is where the outline starts in the document
+	Line int
+	// Attrs are the trimmed attributes of the outline by lowercase name
+	Attrs map[string]string
+	// Folders is the path of the titled folders the outline is in
+	Folders []string
+}
+
+// Title returns the title attribute of the outline, or its text
+func (o Outline) Title() string {
+	if title := o.Attrs["title"]; title != "" {
+		return title
+	}
+	return o.Attrs["text"]
+}
+
+// Parse reads the entries of an OPML document. isEntry tells from its
+// attributes whether an outline is an entry; the other outlines are folders
+// of the outlines inside them.
+func Parse(r io.Reader, isEntry func(attrs map[string]string) bool) ([]Outline, error) {
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+
+	var (
+		outlines []Outline
+		folders  []string
+		// isFolder tells for every open outline whether it was pushed
+		// onto folders
+		isFolder []bool
+		found    bool
+	)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			if !found {
+				return nil, ErrNotOPML
+			}
+			return outlines, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			name := strings.ToLower(element.Name.Local)
+			if name == "opml" {
+				found = true
+			}
+			if name != "outline" {
+				continue
+			}
+			line, _ := decoder.InputPos()
+			outline := Outline{Line: line, Attrs: make(map[string]string, len(element.Attr))}
+			for _, a := range element.Attr {
+				outline.Attrs[strings.ToLower(a.Name.Local)] = strings.TrimSpace(a.Value)
+			}
+
+			if !isEntry(outline.Attrs) {
+				folders = append(folders, outline.Title())
+				isFolder = append(isFolder, true)
+				continue
+			}
+			isFolder = append(isFolder, false)
+
+			for _, folder := range folders {
+				if folder != "" {
+					outline.Folders = append(outline.Folders, folder)
+				}
+			}
+			outlines = append(outlines, outline)
+		case xml.EndElement:
+			if !strings.EqualFold(element.Name.Local, "outline") || len(isFolder) == 0 {
+				continue
+			}
+			if isFolder[len(isFolder)-1] {
+				folders = folders[:len(folders)-1]
+			}
+			isFolder = isFolder[:len(isFolder)-1]
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package opml
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	isEntry := func(attrs map[string]string) bool { return attrs["url"] != "" }
+
+	outlines, err := Parse(strings.NewReader(`<?xml version="1.0"?>
+<opml version="2.0">
+  <body>
+    <outline text="Tech">
+      <outline text="">
+        <outline title="Go &amp; Rust" text="ignored" url=" https://example.com/go "/>
+      </outline>
+      <outline text="Web">
+        <outline text="MDN" url="https://developer.mozilla.org/"/>
+      </outline>
+    </outline>
+    <OUTLINE TEXT="Top" URL="https://example.com/top"/>
+  </body>
+</opml>`), isEntry)
+	require.NoError(t, err)
+	require.Len(t, outlines, 3)
+
+	assert.Equal(t, "Go & Rust", outlines[0].Title())
+	assert.Equal(t, "https://example.com/go", outlines[0].Attrs["url"])
+	assert.Equal(t, []string{"Tech"}, outlines[0].Folders, "untitled folders are left out")
+	assert.Equal(t, 6, outlines[0].Line)
+	assert.Equal(t, []string{"Tech", "Web"}, outlines[1].Folders)
+	assert.Equal(t, "Top", outlines[2].Title())
+	assert.Empty(t, outlines[2].Folders)
+
+	_, err = Parse(strings.NewReader(`<html><body>not opml</body></html>`), isEntry)
+	assert.ErrorIs(t, err, ErrNotOPML)
+}
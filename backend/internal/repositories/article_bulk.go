@@ -0,0 +1,236 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/eikuma/stockle/backend/internal/articlequery"
+	"github.com/eikuma/stockle/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// MaxBulkArticles is the number of articles one bulk operation may change
+const MaxBulkArticles = 1000
+
+// ErrBulkLimitExceeded is returned when a bulk operation selects more than
+// MaxBulkArticles articles
+var ErrBulkLimitExceeded = errors.New("too many articles selected")
+
+// ArticleSelection picks the articles of a bulk operation: the listed IDs,
+// or every article matching Query when there are none
+type ArticleSelection struct {
+	IDs   []string
+	Query *articlequery.Query
+}
+
+// ArticleBulkUpdate are the changes of a bulk operation. Nil and empty
+// fields are left alone; Delete cannot be combined with the other changes.
+type ArticleBulkUpdate struct {
+	Status     *string
+	IsFavorite *bool
+	// CategoryID moves the articles to the category, or out of any category
+	// when it points to an empty string. Callers resolve an empty category
+	// to the default one first, as for a single article.
+	CategoryID   *string
+	AddTagIDs    []string
+	RemoveTagIDs []string
+	Delete       bool
+}
+
+// ArticleBulkResult tells which of the selected articles were changed
+type ArticleBulkResult struct {
+	// Count is the number of the user's articles the operation applies to.
+	// A dry run counts beyond MaxBulkArticles and then lists no IDs.
+	Count int
+	// Matched are the IDs of the user's articles the operation applies to
+	Matched []string
+	// Missing are the requested IDs that are not articles of the user
+	Missing []string
+	// Unextracted are the matched IDs whose content has not been extracted
+	// yet, so that they cannot be summarized
+	Unextracted []string
+}
+
+// BulkUpdate applies update to the user's articles in selection within a
+// single transaction. Requested IDs that belong to someone else are reported
+// as missing and left untouched. With dryRun the articles are only matched,
+// and counted whatever their number.
+func (r *articleRepository) BulkUpdate(
+	userID string,
+	selection ArticleSelection,
+	update ArticleBulkUpdate,
+	dryRun bool,
+) (*ArticleBulkResult, error) {
+	if !dryRun && len(selection.IDs) > MaxBulkArticles {
+		return nil, ErrBulkLimitExceeded
+	}
+
+	var result *ArticleBulkResult
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if dryRun {
+			var count int64
+			if err := r.ownedQuery(tx, userID, selection).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > MaxBulkArticles {
+				result = &ArticleBulkResult{Count: int(count)}
+				return nil
+			}
+		}
+
+		var err error
+		result, err = r.selectOwned(tx, userID, selection)
+		if err != nil {
+			return err
+		}
+		if dryRun || len(result.Matched) == 0 {
+			return nil
+		}
+		return r.applyBulkUpdate(tx, userID, result.Matched, update)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ownedQuery scopes a query to the user's articles in the selection
+func (r *articleRepository) ownedQuery(tx *gorm.DB, userID string, selection ArticleSelection) *gorm.DB {
+	query := tx.Model(&models.Article{}).Where("user_id = ?", userID)
+	if len(selection.IDs) > 0 {
+		return query.Where("id IN ?", selection.IDs)
+	}
+	if selection.Query != nil && selection.Query.Root != nil {
+		return query.Where(compileQuery(r.dialect, userID, selection.Query))
+	}
+	return query
+}
+
+// selectOwned resolves the selection to the IDs of the user's articles
+func (r *articleRepository) selectOwned(tx *gorm.DB, userID string, selection ArticleSelection) (*ArticleBulkResult, error) {
+	query := r.ownedQuery(tx, userID, selection)
+
+	// One more ID than allowed tells that the selection is too large
+	var rows []struct {
+		ID               string
+		ExtractionStatus string
+	}
+	err := query.Select("id", "extraction_status").Order("id").Limit(MaxBulkArticles + 1).Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) > MaxBulkArticles {
+		return nil, ErrBulkLimitExceeded
+	}
+
+	result := &ArticleBulkResult{}
+	ids := make([]string, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.ID)
+		if row.ExtractionStatus != models.ExtractionStatusCompleted {
+			result.Unextracted = append(result.Unextracted, row.ID)
+		}
+	}
+	result.Matched = ids
+	if len(selection.IDs) > 0 {
+		// Keep the order of the request and report what was not found
+		owned := make(map[string]bool, len(ids))
+		for _, id := range ids {
+			owned[id] = true
+		}
+		result.Matched = nil
+		seen := make(map[string]bool, len(selection.IDs))
+		for _, id := range selection.IDs {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			if owned[id] {
+				result.Matched = append(result.Matched, id)
+			} else {
+				result.Missing = append(result.Missing, id)
+			}
+		}
+	}
+	result.Count = len(result.Matched)
+	return result, nil
+}
+
+func (r *articleRepository) applyBulkUpdate(tx *gorm.DB, userID string, ids []string, update ArticleBulkUpdate) error {
+	articles := tx.Model(&models.Article{}).Where("user_id = ? AND id IN ?", userID, ids)
+
+	if update.Delete {
+		// Tags lose their usage along with the articles
+		var tagIDs []string
+		err := tx.Model(&models.ArticleTag{}).Where("article_id IN ?", ids).Distinct().Pluck("tag_id", &tagIDs).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("article_id IN ?", ids).Delete(&models.ArticleTag{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("article_id IN ?", ids).Delete(&models.ArticleSummary{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ? AND id IN ?", userID, ids).Delete(&models.Article{}).Error; err != nil {
+			return err
+		}
+		return recountTagUsage(tx, tagIDs)
+	}
+
+	columns := map[string]interface{}{}
+	if update.Status != nil {
+		columns["status"] = *update.Status
+	}
+	if update.IsFavorite != nil {
+		columns["is_favorite"] = *update.IsFavorite
+	}
+	if update.CategoryID != nil {
+		if *update.CategoryID == "" {
+			columns["category_id"] = nil
+		} else {
+			var count int64
+			err := tx.Model(&models.Category{}).
+				Where("id = ? AND user_id = ?", *update.CategoryID, userID).
+				Count(&count).Error
+			if err != nil {
+				return err
+			}
+			if count == 0 {
+				return gorm.ErrRecordNotFound
+			}
+			columns["category_id"] = *update.CategoryID
+		}
+	}
+	if len(columns) > 0 {
+		if err := articles.Updates(columns).Error; err != nil {
+			return err
+		}
+	}
+
+	return r.changeTags(tx, userID, ids, update.AddTagIDs, update.RemoveTagIDs)
+}
+
+// recountTagUsage sets the usage count of the tags to their number of articles
+func recountTagUsage(tx *gorm.DB, tagIDs []string) error {
+	if len(tagIDs) == 0 {
+		return nil
+	}
+	return tx.Exec(`
+		UPDATE tags SET usage_count = (
+			SELECT COUNT(*) FROM article_tags WHERE article_tags.tag_id = tags.id
+		)
+		WHERE id IN ?
+	`, tagIDs).Error
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var unique []string
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,51 @@
+package repositories
+
+import (
+	"github.com/eikuma/stockle/backend/internal/models"
+)
+
+// Each calls fn with the selected articles of a user, batchSize at a time,
+// in the order they were saved and with their category and tags. Every
+// article is selected when the selection is empty. Only the IDs are read up
+// front, so exporting a large library does not hold all of its content in
+// memory at once.
+func (r *articleRepository) Each(userID string, selection ArticleSelection, batchSize int, fn func([]*models.Article) error) error {
+	query := r.db.Model(&models.Article{}).Where("user_id = ?", userID)
+	if len(selection.IDs) > 0 {
+		query = query.Where("id IN ?", selection.IDs)
+	} else if selection.Query != nil && selection.Query.Root != nil {
+		query = query.Where(compileQuery(r.dialect, userID, selection.Query))
+	}
+
+	var ids []string
+	if err := query.Order("saved_at ASC, id ASC").Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+
+	for start := 0; start < len(ids); start += batchSize {
+		batch := ids[start:min(start+batchSize, len(ids))]
+		articles, err := r.GetByIDsWithAssociations(userID, batch)
+		if err != nil {
+			return err
+		}
+
+		// Keep the order of the IDs; articles deleted meanwhile are skipped
+		byID := make(map[string]*models.Article, len(articles))
+		for _, article := range articles {
+			byID[article.ID] = article
+		}
+		ordered := make([]*models.Article, 0, len(articles))
+		for _, id := range batch {
+			if article, ok := byID[id]; ok {
+				ordered = append(ordered, article)
+			}
+		}
+		if len(ordered) == 0 {
+			continue
+		}
+		if err := fn(ordered); err != nil {
+			return err
+		}
+	}
+	return nil
+}
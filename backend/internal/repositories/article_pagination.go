@@ -0,0 +1,194 @@
+package repositories
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/eikuma/stockle/backend/internal/models"
+	"gorm.io/gorm/clause"
+)
+
+// Fields article listings can be sorted by
+const (
+	SortSavedAt         = "saved_at"
+	SortPublishedAt     = "published_at"
+	SortLastAccessedAt  = "last_accessed_at"
+	SortTitle           = "title"
+	SortReadingProgress = "reading_progress"
+	SortWordCount       = "word_count"
+)
+
+// Page sizes of article listings
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// ErrInvalidCursor is returned for a cursor that was not issued for the
+// requested sort order
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// nullableSortFields may be NULL; those articles come last in both directions
+var nullableSortFields = map[string]bool{
+	SortPublishedAt:    true,
+	SortLastAccessedAt: true,
+	SortWordCount:      true,
+}
+
+// ArticleSort orders article listings. The zero value sorts by saved_at,
+// newest first. Articles with equal values are ordered by ID, so that the
+// order is total and cursors stay valid while articles are added.
+type ArticleSort struct {
+	Field     string
+	Ascending bool
+}
+
+// ParseArticleSort reads a sort field and an order of "asc" or "desc". An
+// empty field sorts by saved_at; an empty order is descending.
+func ParseArticleSort(field, order string) (ArticleSort, error) {
+	sort := ArticleSort{Field: field}
+	switch field {
+	case "":
+		sort.Field = SortSavedAt
+	case SortSavedAt, SortPublishedAt, SortLastAccessedAt, SortTitle, SortReadingProgress, SortWordCount:
+	default:
+		return ArticleSort{}, fmt.Errorf("unknown sort field %q", field)
+	}
+
+	switch order {
+	case "", "desc":
+	case "asc":
+		sort.Ascending = true
+	default:
+		return ArticleSort{}, fmt.Errorf("order must be asc or desc, not %q", order)
+	}
+	return sort, nil
+}
+
+func (s ArticleSort) field() string {
+	if s.Field == "" {
+		return SortSavedAt
+	}
+	return s.Field
+}
+
+func (s ArticleSort) direction() string {
+	if s.Ascending {
+		return "ASC"
+	}
+	return "DESC"
+}
+
+// orderBy is the ORDER BY clause of the sort
+func (s ArticleSort) orderBy() string {
+	column := "articles." + s.field()
+	order := column + " " + s.direction() + ", articles.id " + s.direction()
+	if nullableSortFields[s.field()] {
+		order = "CASE WHEN " + column + " IS NULL THEN 1 ELSE 0 END, " + order
+	}
+	return order
+}
+
+// articleCursor is the position of the last article of a page. Value is the
+// sort column of that article as text, nil when it is NULL.
+type articleCursor struct {
+	Field     string  `json:"f"`
+	Ascending bool    `json:"a,omitempty"`
+	Value     *string `json:"v"`
+	ID        string  `json:"id"`
+}
+
+// cursorAfter returns the cursor continuing the listing after article
+func (s ArticleSort) cursorAfter(article *models.Article) string {
+	c := articleCursor{Field: s.field(), Ascending: s.Ascending, ID: article.ID}
+	formatTime := func(t time.Time) *string {
+		text := t.Format(time.RFC3339Nano)
+		return &text
+	}
+
+	switch c.Field {
+	case SortSavedAt:
+		c.Value = formatTime(article.SavedAt)
+	case SortPublishedAt:
+		if article.PublishedAt != nil {
+			c.Value = formatTime(*article.PublishedAt)
+		}
+	case SortLastAccessedAt:
+		if article.LastAccessedAt != nil {
+			c.Value = formatTime(*article.LastAccessedAt)
+		}
+	case SortTitle:
+		c.Value = &article.Title
+	case SortReadingProgress:
+		text := strconv.FormatFloat(article.ReadingProgress, 'g', -1, 64)
+		c.Value = &text
+	case SortWordCount:
+		if article.WordCount != nil {
+			text := strconv.Itoa(*article.WordCount)
+			c.Value = &text
+		}
+	}
+
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// after returns the condition selecting the articles that follow the
+// cursor in the sort order
+func (s ArticleSort) after(cursor string) (clause.Expr, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return clause.Expr{}, ErrInvalidCursor
+	}
+	var c articleCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == "" {
+		return clause.Expr{}, ErrInvalidCursor
+	}
+	if c.Field != s.field() || c.Ascending != s.Ascending {
+		return clause.Expr{}, ErrInvalidCursor
+	}
+
+	column := "articles." + c.Field
+	compare := "<"
+	if s.Ascending {
+		compare = ">"
+	}
+
+	if c.Value == nil {
+		if !nullableSortFields[c.Field] {
+			return clause.Expr{}, ErrInvalidCursor
+		}
+		// Only NULLs follow a NULL
+		return clause.Expr{
+			SQL:  column + " IS NULL AND articles.id " + compare + " ?",
+			Vars: []interface{}{c.ID},
+		}, nil
+	}
+
+	value, err := cursorValue(c.Field, *c.Value)
+	if err != nil {
+		return clause.Expr{}, ErrInvalidCursor
+	}
+	sql := column + " " + compare + " ? OR (" + column + " = ? AND articles.id " + compare + " ?)"
+	if nullableSortFields[c.Field] {
+		sql += " OR " + column + " IS NULL"
+	}
+	return clause.Expr{SQL: sql, Vars: []interface{}{value, value, c.ID}}, nil
+}
+
+// cursorValue converts the text of a cursor value back to the column type
+func cursorValue(field, text string) (interface{}, error) {
+	switch field {
+	case SortSavedAt, SortPublishedAt, SortLastAccessedAt:
+		return time.Parse(time.RFC3339Nano, text)
+	case SortReadingProgress:
+		return strconv.ParseFloat(text, 64)
+	case SortWordCount:
+		return strconv.Atoi(text)
+	}
+	return text, nil
+}
@@ -0,0 +1,146 @@
+package repositories
+
+import (
+	"strings"
+
+	"github.com/eikuma/stockle/backend/internal/articlequery"
+	"github.com/eikuma/stockle/backend/internal/database"
+	"github.com/eikuma/stockle/backend/internal/search"
+	"gorm.io/gorm/clause"
+)
+
+// compileQuery turns a parsed query into a condition on the articles of
+// userID. Every value is bound as a parameter.
+func compileQuery(dialect database.Dialect, userID string, query *articlequery.Query) clause.Expr {
+	if query == nil || query.Root == nil {
+		return clause.Expr{SQL: "1 = 1"}
+	}
+	return compileNode(dialect, userID, query.Root)
+}
+
+func compileNode(dialect database.Dialect, userID string, node articlequery.Node) clause.Expr {
+	switch n := node.(type) {
+	case *articlequery.And:
+		return joinExprs(dialect, userID, n.Nodes, " AND ")
+	case *articlequery.Or:
+		return joinExprs(dialect, userID, n.Nodes, " OR ")
+	case *articlequery.Not:
+		return clause.Expr{SQL: "NOT (?)", Vars: []interface{}{compileNode(dialect, userID, n.Node)}}
+	case *articlequery.Term:
+		return compileTerm(dialect, userID, n)
+	}
+	return clause.Expr{SQL: "1 = 0"}
+}
+
+func joinExprs(dialect database.Dialect, userID string, nodes []articlequery.Node, separator string) clause.Expr {
+	parts := make([]string, len(nodes))
+	vars := make([]interface{}, len(nodes))
+	for i, node := range nodes {
+		parts[i] = "(?)"
+		vars[i] = compileNode(dialect, userID, node)
+	}
+	return clause.Expr{SQL: strings.Join(parts, separator), Vars: vars}
+}
+
+// compileTerm builds the condition of a single term. Conditions on nullable
+// columns are false rather than NULL for missing values, so that negating
+// them matches those articles.
+func compileTerm(dialect database.Dialect, userID string, term *articlequery.Term) clause.Expr {
+	switch term.Field {
+	case "":
+		return compileText(dialect, userID, term)
+	case articlequery.FieldTag:
+		return clause.Expr{
+			SQL: "EXISTS (SELECT 1 FROM article_tags JOIN tags ON tags.id = article_tags.tag_id " +
+				"WHERE article_tags.article_id = articles.id AND LOWER(tags.name) = LOWER(?))",
+			Vars: []interface{}{term.Value},
+		}
+	case articlequery.FieldCategory:
+		return clause.Expr{
+			SQL: "articles.category_id IS NOT NULL AND articles.category_id IN " +
+				"(SELECT id FROM categories WHERE user_id = ? AND LOWER(name) = LOWER(?))",
+			Vars: []interface{}{userID, term.Value},
+		}
+	case articlequery.FieldSite:
+		return compileSite(dialect, term.Value)
+	case articlequery.FieldStatus:
+		return clause.Expr{SQL: "articles.status = ?", Vars: []interface{}{term.Value}}
+	case articlequery.FieldIs:
+		if term.Value == articlequery.IsFavorite {
+			return clause.Expr{SQL: "articles.is_favorite = ?", Vars: []interface{}{true}}
+		}
+		return clause.Expr{SQL: "articles.status = ?", Vars: []interface{}{term.Value}}
+	case articlequery.FieldLang:
+		// lang:en also matches regional variants such as en-us
+		return clause.Expr{
+			SQL:  "articles.language IS NOT NULL AND (LOWER(articles.language) = ? OR LOWER(articles.language) LIKE ?)",
+			Vars: []interface{}{term.Value, term.Value + "-%"},
+		}
+	case articlequery.FieldSaved:
+		return compileDate("articles.saved_at", term)
+	case articlequery.FieldPublished:
+		return compileDate("articles.published_at", term)
+	}
+	return clause.Expr{SQL: "1 = 0"}
+}
+
+// compileText looks text up in the search index, see search.QueryTerms. The
+// index matches the bigrams of a phrase in any order, so phrases are checked
+// against the text as well.
+func compileText(dialect database.Dialect, userID string, term *articlequery.Term) clause.Expr {
+	terms := search.QueryTerms(term.Value)
+	if len(terms) == 0 {
+		return clause.Expr{SQL: "1 = 0"}
+	}
+	indexed := clause.Expr{
+		SQL: "articles.id IN (SELECT article_id FROM search_postings WHERE user_id = ? AND term IN ? " +
+			"GROUP BY article_id HAVING COUNT(*) = ?)",
+		Vars: []interface{}{userID, terms, len(terms)},
+	}
+	if !term.Phrase {
+		return indexed
+	}
+	return clause.Expr{
+		SQL: "? AND (? OR ? OR ?)",
+		Vars: []interface{}{
+			indexed,
+			dialect.ContainsFold("articles.title", term.Value),
+			dialect.ContainsFold("COALESCE(articles.summary, '')", term.Value),
+			dialect.ContainsFold("COALESCE(articles.content, '')", term.Value),
+		},
+	}
+}
+
+// compileSite matches articles whose URL host is the site or one of its
+// subdomains. LIKE cannot stop the subdomain wildcard at the end of the
+// host, so a path ending in .site also matches.
+func compileSite(dialect database.Dialect, site string) clause.Expr {
+	var conditions []string
+	var vars []interface{}
+	for _, host := range [][]string{{"://" + site}, {"://", "." + site}} {
+		for _, end := range []string{"", "/", ":", "?", "#"} {
+			parts := append([]string{""}, host...)
+			parts[len(parts)-1] += end
+			if end != "" {
+				parts = append(parts, "")
+			}
+			conditions = append(conditions, "?")
+			vars = append(vars, dialect.MatchFold("articles.url", parts...))
+		}
+	}
+	return clause.Expr{SQL: strings.Join(conditions, " OR "), Vars: vars}
+}
+
+func compileDate(column string, term *articlequery.Term) clause.Expr {
+	conditions := []string{column + " IS NOT NULL"}
+	var vars []interface{}
+	if term.From != nil {
+		conditions = append(conditions, column+" >= ?")
+		vars = append(vars, *term.From)
+	}
+	if term.To != nil {
+		conditions = append(conditions, column+" < ?")
+		vars = append(vars, *term.To)
+	}
+	return clause.Expr{SQL: strings.Join(conditions, " AND "), Vars: vars}
+}
@@ -1,20 +1,38 @@
 package repositories
 
 import (
-	"strings"
+	"errors"
 
+	"github.com/eikuma/stockle/backend/internal/articlequery"
+	"github.com/eikuma/stockle/backend/internal/database"
 	"github.com/eikuma/stockle/backend/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // ArticleFilters represents filtering options for articles
 type ArticleFilters struct {
 	Status     string
 	CategoryID string
-	Search     string
-	Favorite   *bool
-	Page       int
-	Limit      int
+	// IncludeDescendants also keeps the articles of the categories below
+	// CategoryID
+	IncludeDescendants bool
+	Search             string
+	Favorite           *bool
+	// Accessed keeps only the articles that were opened at least once
+	Accessed bool
+	// Query narrows the articles down further, see articlequery
+	Query *articlequery.Query
+	// Sort orders the articles, by saved_at descending when zero
+	Sort ArticleSort
+	// Cursor continues a listing after the last article of a previous
+	// page, see ArticleListResult.NextCursor. Page is ignored when set.
+	Cursor string
+	Page   int
+	Limit  int
+	// SkipTotal leaves ArticleListResult.Total at zero instead of counting
+	// every matching article
+	SkipTotal bool
 }
 
 // ArticleListResult represents paginated article results
@@ -23,6 +41,9 @@ type ArticleListResult struct {
 	Total    int64
 	Page     int
 	Limit    int
+	// NextCursor continues the listing after this page; it is empty on
+	// the last page
+	NextCursor string
 }
 
 type ArticleRepository interface {
@@ -31,26 +52,38 @@ type ArticleRepository interface {
 	Update(article *models.Article) error
 	UpdateStatus(id, userID, status string) error
 	UpdateFavorite(id, userID string, isFavorite bool) error
+	UpdateCategory(id, userID string, categoryID *string) error
 	UpdateReadingProgress(id, userID string, progress float64) error
+	UpdateExtraction(article *models.Article) error
+	UpdateSummary(article *models.Article, summaryType string) error
+	UpdateSummaryStatus(article *models.Article) error
 	GetByID(id string) (*models.Article, error)
 	GetByIDWithAssociations(id string) (*models.Article, error)
+	GetByIDsWithAssociations(userID string, ids []string) ([]*models.Article, error)
+	GetIDsByTag(tagID string) ([]string, error)
 	GetByUserID(userID string) ([]*models.Article, error)
 	GetByUserIDWithFilters(userID string, filters ArticleFilters) (*ArticleListResult, error)
-	GetByURL(userID, url string) (*models.Article, error)
+	GetByURLHash(userID, urlHash string) (*models.Article, error)
 	Delete(id, userID string) error
-	SearchByTitle(userID, query string) ([]*models.Article, error)
+	AddTags(id, userID string, tagIDs []string) error
+	RemoveTags(id, userID string, tagIDs []string) error
+	ReplaceTags(id, userID string, tagIDs []string) error
+	BulkUpdate(userID string, selection ArticleSelection, update ArticleBulkUpdate, dryRun bool) (*ArticleBulkResult, error)
+	Each(userID string, selection ArticleSelection, batchSize int, fn func([]*models.Article) error) error
 	GetFavorites(userID string, page, limit int) (*ArticleListResult, error)
 	GetRecentlyRead(userID string, limit int) ([]*models.Article, error)
 	MarkAsAccessed(id string) error
 }
 
 type articleRepository struct {
-	db *gorm.DB
+	db      *gorm.DB
+	dialect database.Dialect
 }
 
 func NewArticleRepository(db *gorm.DB) ArticleRepository {
 	return &articleRepository{
-		db: db,
+		db:      db,
+		dialect: database.DialectOf(db),
 	}
 }
 
@@ -77,7 +110,7 @@ func (r *articleRepository) CreateWithTags(article *models.Article, tagIDs []str
 			}
 		}
 
-		return nil
+		return recountTagUsage(tx, tagIDs)
 	})
 }
 
@@ -97,15 +130,64 @@ func (r *articleRepository) UpdateFavorite(id, userID string, isFavorite bool) e
 		Update("is_favorite", isFavorite).Error
 }
 
+func (r *articleRepository) UpdateCategory(id, userID string, categoryID *string) error {
+	return r.db.Model(&models.Article{}).
+		Where("id = ? AND user_id = ?", id, userID).
+		Update("category_id", categoryID).Error
+}
+
 func (r *articleRepository) UpdateReadingProgress(id, userID string, progress float64) error {
 	return r.db.Model(&models.Article{}).
 		Where("id = ? AND user_id = ?", id, userID).
 		Updates(map[string]interface{}{
 			"reading_progress": progress,
-			"last_accessed_at": gorm.Expr("NOW()"),
+			"last_accessed_at": r.dialect.Now(),
 		}).Error
 }
 
+// UpdateExtraction writes the scraped fields and extraction state only, so
+// that user changes made while the job was running are kept
+func (r *articleRepository) UpdateExtraction(article *models.Article) error {
+	return r.db.Model(article).
+		Select(
+			"title", "content", "content_html", "word_count", "reading_time_seconds", "thumbnail_url",
+			"author", "site_name", "published_at", "language", "canonical_url", "canonical_url_hash", "keywords",
+			"extraction_status", "extraction_error",
+		).
+		Updates(article).Error
+}
+
+// UpdateSummary writes the summary of one type with the summary generation
+// state. The summaries of the other types are left alone, as jobs writing
+// them may run at the same time.
+func (r *articleRepository) UpdateSummary(article *models.Article, summaryType string) error {
+	return r.db.Model(article).
+		Select(
+			summaryColumn(summaryType), "summary_generation_status",
+			"summary_generated_at", "summary_model_version", "summary_strategy", "summary_confidence",
+		).
+		Updates(article).Error
+}
+
+// UpdateSummaryStatus writes the summary generation status only
+func (r *articleRepository) UpdateSummaryStatus(article *models.Article) error {
+	return r.db.Model(article).
+		Select("summary_generation_status").
+		Updates(article).Error
+}
+
+// summaryColumn returns the column holding the summary of a type
+func summaryColumn(summaryType string) string {
+	switch summaryType {
+	case models.SummaryTypeShort:
+		return "summary_short"
+	case models.SummaryTypeLong:
+		return "summary_long"
+	default:
+		return "summary"
+	}
+}
+
 func (r *articleRepository) GetByID(id string) (*models.Article, error) {
 	var article models.Article
 	err := r.db.Where("id = ?", id).First(&article).Error
@@ -127,6 +209,29 @@ func (r *articleRepository) GetByIDWithAssociations(id string) (*models.Article,
 	return &article, nil
 }
 
+// GetByIDsWithAssociations returns the articles of the user among ids, in no
+// particular order
+func (r *articleRepository) GetByIDsWithAssociations(userID string, ids []string) ([]*models.Article, error) {
+	var articles []*models.Article
+	if len(ids) == 0 {
+		return articles, nil
+	}
+	err := r.db.Preload("Category").
+		Preload("Tags").
+		Where("user_id = ? AND id IN ?", userID, ids).
+		Find(&articles).Error
+	return articles, err
+}
+
+// GetIDsByTag returns the IDs of the articles that have the tag
+func (r *articleRepository) GetIDsByTag(tagID string) ([]string, error) {
+	var ids []string
+	err := r.db.Model(&models.ArticleTag{}).
+		Where("tag_id = ?", tagID).
+		Pluck("article_id", &ids).Error
+	return ids, err
+}
+
 func (r *articleRepository) GetByUserID(userID string) ([]*models.Article, error) {
 	var articles []*models.Article
 	err := r.db.Preload("Category").
@@ -149,76 +254,123 @@ func (r *articleRepository) GetByUserIDWithFilters(userID string, filters Articl
 	}
 
 	if filters.CategoryID != "" {
-		query = query.Where("category_id = ?", filters.CategoryID)
+		if filters.IncludeDescendants {
+			tree, err := loadCategoryTree(r.db, userID)
+			if err != nil {
+				return nil, err
+			}
+			query = query.Where("category_id IN ?", tree.descendants(filters.CategoryID))
+		} else {
+			query = query.Where("category_id = ?", filters.CategoryID)
+		}
 	}
 
 	if filters.Favorite != nil {
 		query = query.Where("is_favorite = ?", *filters.Favorite)
 	}
 
+	if filters.Accessed {
+		query = query.Where("last_accessed_at IS NOT NULL")
+	}
+
 	if filters.Search != "" {
-		searchTerm := "%" + strings.ToLower(filters.Search) + "%"
-		query = query.Where(
-			"LOWER(title) LIKE ? OR LOWER(content) LIKE ? OR LOWER(summary) LIKE ?",
-			searchTerm, searchTerm, searchTerm,
-		)
+		query = query.Where(clause.Or(
+			r.dialect.ContainsFold("title", filters.Search),
+			r.dialect.ContainsFold("content", filters.Search),
+			r.dialect.ContainsFold("summary", filters.Search),
+		))
+	}
+
+	if filters.Query != nil && filters.Query.Root != nil {
+		query = query.Where(compileQuery(r.dialect, userID, filters.Query))
 	}
 
-	// Get total count
+	// Count before the cursor narrows the listing down
 	var total int64
-	query.Count(&total)
+	if !filters.SkipTotal {
+		if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			return nil, err
+		}
+	}
 
 	// Apply pagination
 	if filters.Page < 1 {
 		filters.Page = 1
 	}
 	if filters.Limit < 1 {
-		filters.Limit = 20
+		filters.Limit = DefaultPageSize
 	}
+	filters.Limit = min(filters.Limit, MaxPageSize)
 
-	offset := (filters.Page - 1) * filters.Limit
-	query = query.Offset(offset).Limit(filters.Limit)
+	if filters.Cursor != "" {
+		after, err := filters.Sort.after(filters.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where(after)
+	} else {
+		query = query.Offset((filters.Page - 1) * filters.Limit)
+	}
 
-	// Get articles
+	// One more article than asked for tells whether there is a next page
 	var articles []*models.Article
-	err := query.Order("saved_at DESC").Find(&articles).Error
+	err := query.Order(filters.Sort.orderBy()).Limit(filters.Limit + 1).Find(&articles).Error
 	if err != nil {
 		return nil, err
 	}
 
-	return &ArticleListResult{
+	result := &ArticleListResult{
 		Articles: articles,
 		Total:    total,
 		Page:     filters.Page,
 		Limit:    filters.Limit,
-	}, nil
+	}
+	if len(articles) > filters.Limit {
+		result.Articles = articles[:filters.Limit]
+		result.NextCursor = filters.Sort.cursorAfter(result.Articles[filters.Limit-1])
+	}
+	return result, nil
 }
 
-func (r *articleRepository) GetByURL(userID, url string) (*models.Article, error) {
+// GetByURLHash returns the earliest saved article of the user whose saved or
+// canonical URL has the given hash, see urlnorm.Normalizer.Key
+func (r *articleRepository) GetByURLHash(userID, urlHash string) (*models.Article, error) {
 	var article models.Article
-	err := r.db.Where("user_id = ? AND url = ?", userID, url).First(&article).Error
+	err := r.db.Where("user_id = ?", userID).
+		Where(r.db.Where("url_hash = ?", urlHash).Or("canonical_url_hash = ?", urlHash)).
+		Order("saved_at").
+		First(&article).Error
 	if err != nil {
 		return nil, err
 	}
 	return &article, nil
 }
 
+// Delete deletes the user's article and updates the usage counts of its tags
 func (r *articleRepository) Delete(id, userID string) error {
-	return r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Article{}).Error
-}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := ownArticle(tx, id, userID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
 
-func (r *articleRepository) SearchByTitle(userID, query string) ([]*models.Article, error) {
-	var articles []*models.Article
-	searchTerm := "%" + strings.ToLower(query) + "%"
-	
-	err := r.db.Preload("Category").
-		Preload("Tags").
-		Where("user_id = ? AND LOWER(title) LIKE ?", userID, searchTerm).
-		Order("saved_at DESC").
-		Limit(50).
-		Find(&articles).Error
-	
-	return articles, err
+		var tagIDs []string
+		if err := tx.Model(&models.ArticleTag{}).Where("article_id = ?", id).Pluck("tag_id", &tagIDs).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("article_id = ?", id).Delete(&models.ArticleTag{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("article_id = ?", id).Delete(&models.ArticleSummary{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Article{}).Error; err != nil {
+			return err
+		}
+		return recountTagUsage(tx, tagIDs)
+	})
 }
 
 func (r *articleRepository) GetFavorites(userID string, page, limit int) (*ArticleListResult, error) {
@@ -231,21 +383,23 @@ func (r *articleRepository) GetFavorites(userID string, page, limit int) (*Artic
 }
 
 func (r *articleRepository) GetRecentlyRead(userID string, limit int) ([]*models.Article, error) {
-	var articles []*models.Article
-	err := r.db.Preload("Category").
-		Preload("Tags").
-		Where("user_id = ? AND status = ? AND last_accessed_at IS NOT NULL", userID, models.ArticleStatusRead).
-		Order("last_accessed_at DESC").
-		Limit(limit).
-		Find(&articles).Error
-	
-	return articles, err
+	result, err := r.GetByUserIDWithFilters(userID, ArticleFilters{
+		Status:    models.ArticleStatusRead,
+		Accessed:  true,
+		Sort:      ArticleSort{Field: SortLastAccessedAt},
+		Limit:     limit,
+		SkipTotal: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.Articles, nil
 }
 
 func (r *articleRepository) MarkAsAccessed(id string) error {
 	return r.db.Model(&models.Article{}).
 		Where("id = ?", id).
-		Update("last_accessed_at", gorm.Expr("NOW()")).Error
+		Update("last_accessed_at", r.dialect.Now()).Error
 }
 
 // Helper function
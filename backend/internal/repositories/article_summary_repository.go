@@ -0,0 +1,60 @@
+package repositories
+
+import (
+	"github.com/eikuma/stockle/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type ArticleSummaryRepository interface {
+	// Create saves a summary as the current one of its type and language,
+	// keeping the previous ones as history
+	Create(summary *models.ArticleSummary) error
+	// GetByArticleID returns the summaries of an article, the newest first
+	GetByArticleID(articleID string) ([]*models.ArticleSummary, error)
+	// GetCurrent returns the current summary of a type and language of an
+	// article, gorm.ErrRecordNotFound when none was generated
+	GetCurrent(articleID, summaryType, language string) (*models.ArticleSummary, error)
+}
+
+type articleSummaryRepository struct {
+	db *gorm.DB
+}
+
+func NewArticleSummaryRepository(db *gorm.DB) ArticleSummaryRepository {
+	return &articleSummaryRepository{
+		db: db,
+	}
+}
+
+func (r *articleSummaryRepository) Create(summary *models.ArticleSummary) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.ArticleSummary{}).
+			Where("article_id = ? AND summary_type = ? AND language = ? AND is_current = ?",
+				summary.ArticleID, summary.SummaryType, summary.Language, true).
+			Update("is_current", false).Error
+		if err != nil {
+			return err
+		}
+		summary.IsCurrent = true
+		return tx.Create(summary).Error
+	})
+}
+
+func (r *articleSummaryRepository) GetByArticleID(articleID string) ([]*models.ArticleSummary, error) {
+	var summaries []*models.ArticleSummary
+	err := r.db.Where("article_id = ?", articleID).
+		Order("created_at DESC").Order("id").
+		Find(&summaries).Error
+	return summaries, err
+}
+
+func (r *articleSummaryRepository) GetCurrent(articleID, summaryType, language string) (*models.ArticleSummary, error) {
+	var summary models.ArticleSummary
+	err := r.db.Where("article_id = ? AND summary_type = ? AND language = ? AND is_current = ?",
+		articleID, summaryType, language, true).
+		First(&summary).Error
+	if err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
@@ -0,0 +1,104 @@
+package repositories
+
+import (
+	"github.com/eikuma/stockle/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// AddTags attaches the tags to the user's article; tags it already has are
+// kept
+func (r *articleRepository) AddTags(id, userID string, tagIDs []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := ownArticle(tx, id, userID); err != nil {
+			return err
+		}
+		return r.changeTags(tx, userID, []string{id}, tagIDs, nil)
+	})
+}
+
+// RemoveTags detaches the tags from the user's article
+func (r *articleRepository) RemoveTags(id, userID string, tagIDs []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := ownArticle(tx, id, userID); err != nil {
+			return err
+		}
+		return r.changeTags(tx, userID, []string{id}, nil, tagIDs)
+	})
+}
+
+// ReplaceTags makes tagIDs the only tags of the user's article
+func (r *articleRepository) ReplaceTags(id, userID string, tagIDs []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := ownArticle(tx, id, userID); err != nil {
+			return err
+		}
+
+		var current []string
+		if err := tx.Model(&models.ArticleTag{}).Where("article_id = ?", id).Pluck("tag_id", &current).Error; err != nil {
+			return err
+		}
+		keep := make(map[string]bool, len(tagIDs))
+		for _, tagID := range tagIDs {
+			keep[tagID] = true
+		}
+		var remove []string
+		for _, tagID := range current {
+			if !keep[tagID] {
+				remove = append(remove, tagID)
+			}
+		}
+		return r.changeTags(tx, userID, []string{id}, tagIDs, remove)
+	})
+}
+
+// ownArticle fails with gorm.ErrRecordNotFound unless the article is the user's
+func ownArticle(tx *gorm.DB, id, userID string) error {
+	var count int64
+	if err := tx.Model(&models.Article{}).Where("id = ? AND user_id = ?", id, userID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// changeTags adds and removes tags of the articles and updates the usage
+// counts of those tags. Every tag must be the user's; the articles are
+// expected to have been checked by the caller.
+func (r *articleRepository) changeTags(tx *gorm.DB, userID string, articleIDs, add, remove []string) error {
+	add, remove = uniqueStrings(add), uniqueStrings(remove)
+	tagIDs := uniqueStrings(append(append([]string{}, add...), remove...))
+	if len(tagIDs) == 0 {
+		return nil
+	}
+
+	var owned int64
+	if err := tx.Model(&models.Tag{}).Where("id IN ? AND user_id = ?", tagIDs, userID).Count(&owned).Error; err != nil {
+		return err
+	}
+	if int(owned) != len(tagIDs) {
+		return gorm.ErrRecordNotFound
+	}
+
+	if len(remove) > 0 {
+		err := tx.Where("article_id IN ? AND tag_id IN ?", articleIDs, remove).Delete(&models.ArticleTag{}).Error
+		if err != nil {
+			return err
+		}
+	}
+	if len(add) > 0 {
+		links := make([]models.ArticleTag, 0, len(articleIDs)*len(add))
+		for _, articleID := range articleIDs {
+			for _, tagID := range add {
+				links = append(links, models.ArticleTag{ArticleID: articleID, TagID: tagID})
+			}
+		}
+		// Articles that already have a tag keep it
+		err := tx.Clauses(r.dialect.Upsert([]string{"article_id", "tag_id"})).Create(&links).Error
+		if err != nil {
+			return err
+		}
+	}
+	return recountTagUsage(tx, tagIDs)
+}
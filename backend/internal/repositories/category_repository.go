@@ -1,8 +1,23 @@
 package repositories
 
 import (
+	"errors"
+
 	"github.com/eikuma/stockle/backend/internal/models"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReassignTo chooses where the articles of a deleted category go
+type ReassignTo string
+
+const (
+	// ReassignToDefault moves the articles to the default category
+	ReassignToDefault ReassignTo = "default"
+	// ReassignToParent moves the articles to the parent category, or to the
+	// default category when the deleted one is at the top level
+	ReassignToParent ReassignTo = "parent"
 )
 
 type CategoryRepository interface {
@@ -11,9 +26,11 @@ type CategoryRepository interface {
 	GetByID(id string) (*models.Category, error)
 	GetByUserID(userID string) ([]*models.Category, error)
 	GetByUserIDWithCounts(userID string) ([]*models.Category, error)
-	Delete(id, userID string) error
+	Move(id, userID string, parentID *string) error
+	Delete(id, userID string, reassign ReassignTo) error
 	GetDefault(userID string) (*models.Category, error)
 	CreateDefault(userID string) (*models.Category, error)
+	Reorder(userID string, categoryIDs []string) error
 }
 
 type categoryRepository struct {
@@ -65,30 +82,96 @@ func (r *categoryRepository) GetByUserIDWithCounts(userID string) ([]*models.Cat
 		WHERE c.user_id = ?
 		ORDER BY c.display_order ASC, c.created_at ASC
 	`, userID, userID).Scan(&categories).Error
-	
-	return categories, err
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := loadCategoryTree(r.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	tree.rollUp(categories)
+	return categories, nil
+}
+
+// Move places the category below parentID, or at the top level when it is
+// nil. The parent must belong to the same user and must not be the category
+// itself or one of its descendants.
+func (r *categoryRepository) Move(id, userID string, parentID *string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Two moves checked against the same tree could each pass and make
+		// a cycle together, so the categories stay locked until the update.
+		// SQLite has no row locks but allows one writer at a time.
+		tree, err := loadCategoryTree(tx.Clauses(clause.Locking{Strength: "UPDATE"}), userID)
+		if err != nil {
+			return err
+		}
+		if !tree.has(id) {
+			return gorm.ErrRecordNotFound
+		}
+		if parentID != nil {
+			if !tree.has(*parentID) {
+				return gorm.ErrRecordNotFound
+			}
+			if tree.isWithin(*parentID, id) {
+				return ErrCategoryCycle
+			}
+		}
+
+		return tx.Model(&models.Category{}).
+			Where("id = ? AND user_id = ?", id, userID).
+			Update("parent_id", parentID).Error
+	})
 }
 
-func (r *categoryRepository) Delete(id, userID string) error {
+// Delete removes a category other than the default one. Its children move
+// up to its parent and its articles are reassigned as chosen.
+func (r *categoryRepository) Delete(id, userID string, reassign ReassignTo) error {
 	return r.db.Transaction(func(tx *gorm.DB) error {
-		// Get default category
-		var defaultCategory models.Category
-		err := tx.Where("user_id = ? AND is_default = ?", userID, true).First(&defaultCategory).Error
+		var category models.Category
+		err := tx.Where("id = ? AND user_id = ? AND is_default = ?", id, userID, false).First(&category).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
 		if err != nil {
 			return err
 		}
 
-		// Move articles to default category
+		target := category.ParentID
+		if reassign != ReassignToParent || target == nil {
+			var defaultCategory models.Category
+			err := tx.Where("user_id = ? AND is_default = ?", userID, true).First(&defaultCategory).Error
+			if err != nil {
+				return err
+			}
+			target = &defaultCategory.ID
+		}
+
+		// Move articles to the chosen category
 		err = tx.Model(&models.Article{}).
 			Where("category_id = ? AND user_id = ?", id, userID).
-			Update("category_id", defaultCategory.ID).Error
+			Update("category_id", *target).Error
+		if err != nil {
+			return err
+		}
+
+		// Feeds keep saving their articles next to the moved ones
+		err = tx.Model(&models.Feed{}).
+			Where("category_id = ? AND user_id = ?", id, userID).
+			Update("category_id", *target).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&models.Category{}).
+			Where("parent_id = ? AND user_id = ?", id, userID).
+			Update("parent_id", category.ParentID).Error
 		if err != nil {
 			return err
 		}
 
 		// Delete category
-		return tx.Where("id = ? AND user_id = ? AND is_default = ?", id, userID, false).
-			Delete(&models.Category{}).Error
+		return tx.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Category{}).Error
 	})
 }
 
@@ -103,6 +186,7 @@ func (r *categoryRepository) GetDefault(userID string) (*models.Category, error)
 
 func (r *categoryRepository) CreateDefault(userID string) (*models.Category, error) {
 	category := &models.Category{
+		ID:           uuid.New().String(),
 		UserID:       userID,
 		Name:         "未分類",
 		Color:        "#6B7280",
@@ -116,4 +200,8 @@ func (r *categoryRepository) CreateDefault(userID string) (*models.Category, err
 	}
 	
 	return category, nil
-}
\ No newline at end of file
+}
+
+func (r *categoryRepository) Reorder(userID string, categoryIDs []string) error {
+	return reorderByUser(r.db, &models.Category{}, userID, categoryIDs)
+}
@@ -0,0 +1,102 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/eikuma/stockle/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrCategoryCycle is returned when a category would be moved below itself
+// or one of its descendants
+var ErrCategoryCycle = errors.New("category cannot be moved below itself")
+
+// categoryTree links the categories of one user to their parents and
+// children. Users have at most a few hundred categories, so the tree is
+// loaded whole instead of walked with recursive SQL.
+type categoryTree struct {
+	parents  map[string]string
+	children map[string][]string
+}
+
+func loadCategoryTree(tx *gorm.DB, userID string) (*categoryTree, error) {
+	var rows []struct {
+		ID       string
+		ParentID *string
+	}
+	err := tx.Model(&models.Category{}).
+		Select("id, parent_id").
+		Where("user_id = ?", userID).
+		Order("display_order ASC, created_at ASC").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	tree := &categoryTree{
+		parents:  make(map[string]string, len(rows)),
+		children: make(map[string][]string),
+	}
+	for _, row := range rows {
+		tree.parents[row.ID] = ""
+	}
+	for _, row := range rows {
+		// A parent of someone else's is treated as no parent at all
+		if row.ParentID == nil {
+			continue
+		}
+		if _, ok := tree.parents[*row.ParentID]; ok {
+			tree.parents[row.ID] = *row.ParentID
+			tree.children[*row.ParentID] = append(tree.children[*row.ParentID], row.ID)
+		}
+	}
+	return tree, nil
+}
+
+func (t *categoryTree) has(id string) bool {
+	_, ok := t.parents[id]
+	return ok
+}
+
+// descendants returns id followed by every category below it
+func (t *categoryTree) descendants(id string) []string {
+	ids := []string{id}
+	seen := map[string]bool{id: true}
+	for i := 0; i < len(ids); i++ {
+		for _, child := range t.children[ids[i]] {
+			if !seen[child] {
+				seen[child] = true
+				ids = append(ids, child)
+			}
+		}
+	}
+	return ids
+}
+
+// isWithin tells whether id is ancestor or one of its descendants
+func (t *categoryTree) isWithin(id, ancestor string) bool {
+	seen := make(map[string]bool)
+	for id != "" && !seen[id] {
+		if id == ancestor {
+			return true
+		}
+		seen[id] = true
+		id = t.parents[id]
+	}
+	return false
+}
+
+// rollUp sets TotalArticleCount of every category to its own articles plus
+// those of its descendants
+func (t *categoryTree) rollUp(categories []*models.Category) {
+	counts := make(map[string]int, len(categories))
+	for _, category := range categories {
+		counts[category.ID] = category.ArticleCount
+	}
+	for _, category := range categories {
+		category.TotalArticleCount = 0
+		for _, id := range t.descendants(category.ID) {
+			category.TotalArticleCount += counts[id]
+		}
+	}
+}
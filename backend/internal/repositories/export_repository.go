@@ -0,0 +1,59 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/eikuma/stockle/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type ExportRepository interface {
+	Create(export *models.Export) error
+	Update(export *models.Export) error
+	GetByID(id string) (*models.Export, error)
+	GetByUserID(userID string) ([]*models.Export, error)
+	// GetExpired returns the completed exports whose file expired before now
+	GetExpired(now time.Time) ([]*models.Export, error)
+}
+
+type exportRepository struct {
+	db *gorm.DB
+}
+
+func NewExportRepository(db *gorm.DB) ExportRepository {
+	return &exportRepository{
+		db: db,
+	}
+}
+
+func (r *exportRepository) Create(export *models.Export) error {
+	return r.db.Create(export).Error
+}
+
+func (r *exportRepository) Update(export *models.Export) error {
+	return r.db.Save(export).Error
+}
+
+func (r *exportRepository) GetByID(id string) (*models.Export, error) {
+	var export models.Export
+	err := r.db.Where("id = ?", id).First(&export).Error
+	if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+func (r *exportRepository) GetByUserID(userID string) ([]*models.Export, error) {
+	var exports []*models.Export
+	err := r.db.Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&exports).Error
+	return exports, err
+}
+
+func (r *exportRepository) GetExpired(now time.Time) ([]*models.Export, error) {
+	var exports []*models.Export
+	err := r.db.Where("status = ? AND expires_at < ?", models.ExportStatusCompleted, now).
+		Find(&exports).Error
+	return exports, err
+}
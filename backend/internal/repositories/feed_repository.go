@@ -0,0 +1,132 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/eikuma/stockle/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type FeedRepository interface {
+	Create(feed *models.Feed) error
+	Update(feed *models.Feed) error
+	// UpdatePoll writes the fields a poll changes only, so that settings the
+	// user changes while the feed is being fetched are kept
+	UpdatePoll(feed *models.Feed) error
+	Delete(id, userID string) error
+	GetByID(id string) (*models.Feed, error)
+	GetByUserID(userID string) ([]*models.Feed, error)
+	GetByURL(userID, url string) (*models.Feed, error)
+	// GetDue returns up to limit active feeds whose next fetch is at or
+	// before now, the longest overdue first
+	GetDue(now time.Time, limit int) ([]*models.Feed, error)
+	// SeenEntries reports which of the entry keys were already recorded
+	// for the feed
+	SeenEntries(feedID string, keys []string) (map[string]bool, error)
+	// AddEntries records entries, ignoring the ones already recorded
+	AddEntries(entries []*models.FeedEntry) error
+}
+
+type feedRepository struct {
+	db *gorm.DB
+}
+
+func NewFeedRepository(db *gorm.DB) FeedRepository {
+	return &feedRepository{
+		db: db,
+	}
+}
+
+func (r *feedRepository) Create(feed *models.Feed) error {
+	return r.db.Create(feed).Error
+}
+
+func (r *feedRepository) Update(feed *models.Feed) error {
+	return r.db.Save(feed).Error
+}
+
+func (r *feedRepository) UpdatePoll(feed *models.Feed) error {
+	return r.db.Model(feed).
+		Select(
+			"etag", "last_modified", "title", "site_url", "last_fetched_at", "next_fetch_at",
+			"error_count", "last_error",
+		).
+		Updates(feed).Error
+}
+
+func (r *feedRepository) Delete(id, userID string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var feed models.Feed
+		err := tx.Where("id = ? AND user_id = ?", id, userID).First(&feed).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("feed_id = ?", id).Delete(&models.FeedEntry{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&feed).Error
+	})
+}
+
+func (r *feedRepository) GetByID(id string) (*models.Feed, error) {
+	var feed models.Feed
+	err := r.db.Where("id = ?", id).First(&feed).Error
+	if err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
+
+func (r *feedRepository) GetByUserID(userID string) ([]*models.Feed, error) {
+	var feeds []*models.Feed
+	err := r.db.Where("user_id = ?", userID).
+		Order("title ASC").
+		Order("created_at ASC").
+		Find(&feeds).Error
+	return feeds, err
+}
+
+func (r *feedRepository) GetByURL(userID, url string) (*models.Feed, error) {
+	var feed models.Feed
+	err := r.db.Where("user_id = ? AND url = ?", userID, url).First(&feed).Error
+	if err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
+
+func (r *feedRepository) GetDue(now time.Time, limit int) ([]*models.Feed, error) {
+	var feeds []*models.Feed
+	err := r.db.Where("is_active = ? AND next_fetch_at <= ?", true, now).
+		Order("next_fetch_at ASC").
+		Limit(limit).
+		Find(&feeds).Error
+	return feeds, err
+}
+
+func (r *feedRepository) SeenEntries(feedID string, keys []string) (map[string]bool, error) {
+	seen := make(map[string]bool)
+	if len(keys) == 0 {
+		return seen, nil
+	}
+
+	var found []string
+	err := r.db.Model(&models.FeedEntry{}).
+		Where("feed_id = ? AND entry_key IN ?", feedID, keys).
+		Pluck("entry_key", &found).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range found {
+		seen[key] = true
+	}
+	return seen, nil
+}
+
+func (r *feedRepository) AddEntries(entries []*models.FeedEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(entries).Error
+}
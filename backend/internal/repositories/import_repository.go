@@ -0,0 +1,104 @@
+package repositories
+
+import (
+	"github.com/eikuma/stockle/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// importItemBatchSize is the number of items inserted per statement, which
+// keeps large imports below the placeholder limits of the drivers
+const importItemBatchSize = 500
+
+type ImportRepository interface {
+	// Create saves an import together with its items
+	Create(imp *models.Import, items []*models.ImportItem) error
+	Update(imp *models.Import) error
+	GetByID(id string) (*models.Import, error)
+	GetByUserID(userID string) ([]*models.Import, error)
+	// GetItems returns up to limit items of an import in the given status,
+	// in the order of the file
+	GetItems(importID, status string, limit int) ([]*models.ImportItem, error)
+	UpdateItem(item *models.ImportItem) error
+	// CountItems returns the number of items of an import in each status
+	CountItems(importID string) (map[string]int, error)
+}
+
+type importRepository struct {
+	db *gorm.DB
+}
+
+func NewImportRepository(db *gorm.DB) ImportRepository {
+	return &importRepository{
+		db: db,
+	}
+}
+
+func (r *importRepository) Create(imp *models.Import, items []*models.ImportItem) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(imp).Error; err != nil {
+			return err
+		}
+		for _, item := range items {
+			item.ImportID = imp.ID
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(items, importItemBatchSize).Error
+	})
+}
+
+func (r *importRepository) Update(imp *models.Import) error {
+	return r.db.Save(imp).Error
+}
+
+func (r *importRepository) GetByID(id string) (*models.Import, error) {
+	var imp models.Import
+	err := r.db.Where("id = ?", id).First(&imp).Error
+	if err != nil {
+		return nil, err
+	}
+	return &imp, nil
+}
+
+func (r *importRepository) GetByUserID(userID string) ([]*models.Import, error) {
+	var imports []*models.Import
+	err := r.db.Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&imports).Error
+	return imports, err
+}
+
+func (r *importRepository) GetItems(importID, status string, limit int) ([]*models.ImportItem, error) {
+	var items []*models.ImportItem
+	err := r.db.Where("import_id = ? AND status = ?", importID, status).
+		Order("position ASC").
+		Limit(limit).
+		Find(&items).Error
+	return items, err
+}
+
+func (r *importRepository) UpdateItem(item *models.ImportItem) error {
+	return r.db.Save(item).Error
+}
+
+func (r *importRepository) CountItems(importID string) (map[string]int, error) {
+	var rows []struct {
+		Status string
+		Count  int
+	}
+	err := r.db.Model(&models.ImportItem{}).
+		Select("status, COUNT(*) AS count").
+		Where("import_id = ?", importID).
+		Group("status").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
@@ -1,6 +1,8 @@
 package repositories
 
 import (
+	"time"
+
 	"github.com/eikuma/stockle/backend/internal/models"
 	"gorm.io/gorm"
 )
@@ -10,6 +12,8 @@ type JobRepository interface {
 	Update(job *models.JobQueue) error
 	GetByID(id string) (*models.JobQueue, error)
 	GetNextJob() (*models.JobQueue, error)
+	ClaimNextJob(staleAfter time.Duration) (*models.JobQueue, error)
+	Heartbeat(id string) error
 	GetPendingJobs() ([]*models.JobQueue, error)
 }
 
@@ -60,4 +64,65 @@ func (r *jobRepository) GetPendingJobs() ([]*models.JobQueue, error) {
 		Order("priority ASC, created_at ASC").
 		Find(&jobs).Error
 	return jobs, err
-}
\ No newline at end of file
+}
+
+// ClaimNextJob marks the next due pending job as processing and returns it,
+// or nil when there is none. A job is only handed to one caller even when
+// several workers poll at the same time.
+//
+// A job that has been processing for longer than staleAfter without a
+// heartbeat is taken to be abandoned by a worker that stopped, and is
+// claimed again with one more retry counted. A zero staleAfter leaves such
+// jobs alone.
+func (r *jobRepository) ClaimNextJob(staleAfter time.Duration) (*models.JobQueue, error) {
+	for {
+		now := time.Now()
+
+		query := r.db.Where("status = ? AND (scheduled_at IS NULL OR scheduled_at <= ?)", models.JobStatusPending, now)
+		if staleAfter > 0 {
+			query = query.Or("status = ? AND started_at < ?", models.JobStatusProcessing, now.Add(-staleAfter))
+		}
+
+		var job models.JobQueue
+		err := query.Order("priority ASC, created_at ASC").First(&job).Error
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil, nil
+			}
+			return nil, err
+		}
+
+		updates := map[string]interface{}{
+			"status":     models.JobStatusProcessing,
+			"started_at": now,
+		}
+		claim := r.db.Model(&models.JobQueue{}).Where("id = ? AND status = ?", job.ID, job.Status)
+		if job.Status == models.JobStatusProcessing {
+			// A worker that reclaimed it first has moved started_at on
+			claim = claim.Where("started_at < ?", now.Add(-staleAfter))
+			updates["retry_count"] = gorm.Expr("retry_count + 1")
+			job.RetryCount++
+		}
+
+		result := claim.Updates(updates)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 0 {
+			// Another worker claimed it first
+			continue
+		}
+
+		job.Status = models.JobStatusProcessing
+		job.StartedAt = &now
+		return &job, nil
+	}
+}
+
+// Heartbeat moves the start of a processing job to now, so that a job that
+// runs for long is not taken for abandoned
+func (r *jobRepository) Heartbeat(id string) error {
+	return r.db.Model(&models.JobQueue{}).
+		Where("id = ? AND status = ?", id, models.JobStatusProcessing).
+		Update("started_at", time.Now()).Error
+}
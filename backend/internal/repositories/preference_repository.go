@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/eikuma/stockle/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PreferenceRepository interface {
+	// Get returns the preferences of a user, the defaults when the user
+	// never saved any
+	Get(userID string) (*models.UserPreference, error)
+	// Save creates or replaces the preferences of a user
+	Save(preference *models.UserPreference) error
+}
+
+type preferenceRepository struct {
+	db *gorm.DB
+}
+
+func NewPreferenceRepository(db *gorm.DB) PreferenceRepository {
+	return &preferenceRepository{
+		db: db,
+	}
+}
+
+func (r *preferenceRepository) Get(userID string) (*models.UserPreference, error) {
+	var preference models.UserPreference
+	err := r.db.Where("user_id = ?", userID).First(&preference).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.DefaultUserPreference(userID), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &preference, nil
+}
+
+func (r *preferenceRepository) Save(preference *models.UserPreference) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// gorm creates zero values as the column defaults, turning false
+		// into true, so the row is created from a copy and then every
+		// column is written
+		row := *preference
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&row).Error; err != nil {
+			return err
+		}
+		return tx.Model(preference).Select("*").Omit("user_id", "created_at").Updates(preference).Error
+	})
+}
@@ -0,0 +1,30 @@
+package repositories
+
+import "gorm.io/gorm"
+
+// reorderByUser sets display_order of the user's rows of model to their
+// position in ids. Nothing changes unless every ID belongs to the user.
+func reorderByUser(db *gorm.DB, model interface{}, userID string, ids []string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		err := tx.Model(model).
+			Where("id IN ? AND user_id = ?", ids, userID).
+			Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if int(count) != len(ids) {
+			return gorm.ErrRecordNotFound
+		}
+
+		for i, id := range ids {
+			err := tx.Model(model).
+				Where("id = ? AND user_id = ?", id, userID).
+				Update("display_order", i).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
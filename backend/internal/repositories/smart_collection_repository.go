@@ -0,0 +1,131 @@
+package repositories
+
+import (
+	"strings"
+
+	"github.com/eikuma/stockle/backend/internal/articlequery"
+	"github.com/eikuma/stockle/backend/internal/database"
+	"github.com/eikuma/stockle/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type SmartCollectionRepository interface {
+	Create(collection *models.SmartCollection) error
+	Update(collection *models.SmartCollection) error
+	GetByID(id string) (*models.SmartCollection, error)
+	GetByUserID(userID string) ([]*models.SmartCollection, error)
+	GetByUserIDWithCounts(userID string) ([]*models.SmartCollection, error)
+	Delete(id, userID string) error
+	Reorder(userID string, collectionIDs []string) error
+}
+
+type smartCollectionRepository struct {
+	db      *gorm.DB
+	dialect database.Dialect
+}
+
+func NewSmartCollectionRepository(db *gorm.DB) SmartCollectionRepository {
+	return &smartCollectionRepository{
+		db:      db,
+		dialect: database.DialectOf(db),
+	}
+}
+
+func (r *smartCollectionRepository) Create(collection *models.SmartCollection) error {
+	return r.db.Create(collection).Error
+}
+
+func (r *smartCollectionRepository) Update(collection *models.SmartCollection) error {
+	return r.db.Save(collection).Error
+}
+
+func (r *smartCollectionRepository) GetByID(id string) (*models.SmartCollection, error) {
+	var collection models.SmartCollection
+	err := r.db.Where("id = ?", id).First(&collection).Error
+	if err != nil {
+		return nil, err
+	}
+	return &collection, nil
+}
+
+func (r *smartCollectionRepository) GetByUserID(userID string) ([]*models.SmartCollection, error) {
+	var collections []*models.SmartCollection
+	err := r.db.Where("user_id = ?", userID).
+		Order("display_order ASC, created_at ASC").
+		Find(&collections).Error
+	return collections, err
+}
+
+// collectionCountBatch bounds how many collections are counted in one query
+const collectionCountBatch = 20
+
+// GetByUserIDWithCounts returns the collections with the number of articles
+// currently matching each query. A collection whose query no longer parses is
+// listed with QueryError set instead of failing the list.
+func (r *smartCollectionRepository) GetByUserIDWithCounts(userID string) ([]*models.SmartCollection, error) {
+	collections, err := r.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var counted []*models.SmartCollection
+	var conditions []interface{}
+	for _, collection := range collections {
+		query, err := articlequery.Parse(collection.Query)
+		if err != nil {
+			collection.QueryError = err.Error()
+			continue
+		}
+		counted = append(counted, collection)
+		conditions = append(conditions, compileQuery(r.dialect, userID, query))
+	}
+
+	for start := 0; start < len(counted); start += collectionCountBatch {
+		end := min(start+collectionCountBatch, len(counted))
+		counts, err := r.countMatching(userID, conditions[start:end])
+		if err != nil {
+			return nil, err
+		}
+		for i, count := range counts {
+			counted[start+i].ArticleCount = int(count)
+		}
+	}
+	return collections, nil
+}
+
+// countMatching counts the user's articles matching each condition in a
+// single pass over them
+func (r *smartCollectionRepository) countMatching(userID string, conditions []interface{}) ([]int64, error) {
+	columns := make([]string, len(conditions))
+	for i := range columns {
+		columns[i] = "COUNT(CASE WHEN (?) THEN 1 END)"
+	}
+	rows, err := r.db.Model(&models.Article{}).
+		Select(strings.Join(columns, ", "), conditions...).
+		Where("user_id = ?", userID).
+		Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make([]int64, len(conditions))
+	dest := make([]interface{}, len(counts))
+	for i := range counts {
+		dest[i] = &counts[i]
+	}
+	if rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+	}
+	return counts, rows.Err()
+}
+
+func (r *smartCollectionRepository) Delete(id, userID string) error {
+	return r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.SmartCollection{}).Error
+}
+
+func (r *smartCollectionRepository) Reorder(userID string, collectionIDs []string) error {
+	return reorderByUser(r.db, &models.SmartCollection{}, userID, collectionIDs)
+}
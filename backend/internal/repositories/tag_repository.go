@@ -1,12 +1,42 @@
 package repositories
 
 import (
+	"errors"
 	"strings"
 
+	"github.com/eikuma/stockle/backend/internal/database"
 	"github.com/eikuma/stockle/backend/internal/models"
+	"github.com/google/uuid"
+	"golang.org/x/text/unicode/norm"
+	"golang.org/x/text/width"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// MaxTagNameLength is the number of characters a tag name is cut to
+const MaxTagNameLength = 50
+
+// ErrTagNameConflict is returned when a rename would collide with another tag of the same user
+var ErrTagNameConflict = errors.New("tag name already exists")
+
+// NormalizeTagName folds full-width ASCII to half-width and half-width kana
+// to full-width, and collapses whitespace. Names that differ only in case
+// are the same tag; the case of the first one saved is kept.
+func NormalizeTagName(name string) string {
+	name = norm.NFC.String(width.Fold.String(name))
+	name = strings.Join(strings.Fields(name), " ")
+	if runes := []rune(name); len(runes) > MaxTagNameLength {
+		name = strings.TrimSpace(string(runes[:MaxTagNameLength]))
+	}
+	return name
+}
+
+// sameTagName matches the tags named name, ignoring case. Tags left without
+// a name key by BackfillTagNameKeys are matched by their lowercased name.
+func sameTagName(name string) clause.Expr {
+	return clause.Expr{SQL: "(name_key = ? OR LOWER(name) = LOWER(?))", Vars: []interface{}{models.TagNameKey(name), name}}
+}
+
 type TagRepository interface {
 	Create(tag *models.Tag) error
 	GetOrCreate(userID, name string) (*models.Tag, error)
@@ -14,19 +44,25 @@ type TagRepository interface {
 	GetByID(id string) (*models.Tag, error)
 	GetByUserID(userID string) ([]*models.Tag, error)
 	GetPopularTags(userID string, limit int) ([]*models.Tag, error)
-	SearchByName(userID, query string) ([]*models.Tag, error)
+	GetByNames(userID string, names []string) ([]*models.Tag, error)
+	SearchByName(userID, query string, limit int) ([]*models.Tag, error)
 	UpdateUsageCount(tagID string) error
+	Rename(id, userID, name string) error
+	Merge(sourceID, targetID, userID string) error
 	Delete(id, userID string) error
 	GetUnusedTags(userID string) ([]*models.Tag, error)
+	DeleteUnused(userID string) (int64, error)
 }
 
 type tagRepository struct {
-	db *gorm.DB
+	db      *gorm.DB
+	dialect database.Dialect
 }
 
 func NewTagRepository(db *gorm.DB) TagRepository {
 	return &tagRepository{
-		db: db,
+		db:      db,
+		dialect: database.DialectOf(db),
 	}
 }
 
@@ -34,24 +70,33 @@ func (r *tagRepository) Create(tag *models.Tag) error {
 	return r.db.Create(tag).Error
 }
 
+// GetOrCreate returns the user's tag with the normalized name, creating it
+// when there is none, see NormalizeTagName
 func (r *tagRepository) GetOrCreate(userID, name string) (*models.Tag, error) {
-	name = strings.TrimSpace(name)
+	name = NormalizeTagName(name)
 	if name == "" {
 		return nil, gorm.ErrRecordNotFound
 	}
 
 	var tag models.Tag
-	err := r.db.Where("user_id = ? AND name = ?", userID, name).First(&tag).Error
+	err := r.db.Where("user_id = ?", userID).Where(sameTagName(name)).Order("created_at").First(&tag).Error
 	
 	if err == gorm.ErrRecordNotFound {
-		// Create new tag
+		// Create new tag. A concurrent request may create the same name first,
+		// so ignore the conflict and read back whichever row won.
 		tag = models.Tag{
+			ID:         uuid.New().String(),
 			UserID:     userID,
 			Name:       name,
 			UsageCount: 0,
 		}
 		
-		if err := r.db.Create(&tag).Error; err != nil {
+		err := r.db.Clauses(r.dialect.Upsert([]string{"user_id", "name_key"})).Create(&tag).Error
+		if err != nil {
+			return nil, err
+		}
+		
+		if err := r.db.Where("user_id = ?", userID).Where(sameTagName(name)).Order("created_at").First(&tag).Error; err != nil {
 			return nil, err
 		}
 	} else if err != nil {
@@ -61,11 +106,14 @@ func (r *tagRepository) GetOrCreate(userID, name string) (*models.Tag, error) {
 	return &tag, nil
 }
 
+// GetOrCreateMultiple returns the tags with the given names once each, in
+// the order they are first named
 func (r *tagRepository) GetOrCreateMultiple(userID string, names []string) ([]*models.Tag, error) {
 	var tags []*models.Tag
+	seen := make(map[string]bool)
 	
 	for _, name := range names {
-		name = strings.TrimSpace(name)
+		name = NormalizeTagName(name)
 		if name == "" {
 			continue
 		}
@@ -74,6 +122,10 @@ func (r *tagRepository) GetOrCreateMultiple(userID string, names []string) ([]*m
 		if err != nil {
 			return nil, err
 		}
+		if seen[tag.ID] {
+			continue
+		}
+		seen[tag.ID] = true
 		
 		tags = append(tags, tag)
 	}
@@ -107,15 +159,40 @@ func (r *tagRepository) GetPopularTags(userID string, limit int) ([]*models.Tag,
 	return tags, err
 }
 
-func (r *tagRepository) SearchByName(userID, query string) ([]*models.Tag, error) {
+// GetByNames returns the user's tags with any of the names, compared
+// after normalization; names without a tag are skipped
+func (r *tagRepository) GetByNames(userID string, names []string) ([]*models.Tag, error) {
 	var tags []*models.Tag
-	searchTerm := "%" + strings.ToLower(strings.TrimSpace(query)) + "%"
-	
-	err := r.db.Where("user_id = ? AND LOWER(name) LIKE ?", userID, searchTerm).
-		Order("usage_count DESC, name ASC").
-		Limit(20).
+	var keys []string
+	for _, name := range names {
+		if name = NormalizeTagName(name); name != "" {
+			keys = append(keys, models.TagNameKey(name))
+		}
+	}
+	if len(keys) == 0 {
+		return tags, nil
+	}
+
+	err := r.db.Where("user_id = ? AND (name_key IN ? OR LOWER(name) IN ?)", userID, keys, keys).
+		Order("name ASC").
 		Find(&tags).Error
-	
+	return tags, err
+}
+
+// SearchByName suggests tags containing query, those starting with it
+// first and then the most used
+func (r *tagRepository) SearchByName(userID, query string, limit int) ([]*models.Tag, error) {
+	var tags []*models.Tag
+	query = NormalizeTagName(query)
+	err := r.db.Where("user_id = ?", userID).
+		Where(r.dialect.ContainsFold("name", query)).
+		Clauses(clause.OrderBy{Expression: clause.Expr{
+			SQL:  "CASE WHEN ? THEN 0 ELSE 1 END, usage_count DESC, name ASC",
+			Vars: []interface{}{r.dialect.MatchFold("name", query, "")},
+		}}).
+		Limit(limit).
+		Find(&tags).Error
+
 	return tags, err
 }
 
@@ -135,8 +212,94 @@ func (r *tagRepository) UpdateUsageCount(tagID string) error {
 	})
 }
 
+func (r *tagRepository) Rename(id, userID, name string) error {
+	name = NormalizeTagName(name)
+	if name == "" {
+		return gorm.ErrRecordNotFound
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var tag models.Tag
+		if err := tx.Where("id = ? AND user_id = ?", id, userID).First(&tag).Error; err != nil {
+			return err
+		}
+
+		// Refuse to rename onto an existing tag; callers should merge instead
+		var count int64
+		err := tx.Model(&models.Tag{}).
+			Where("user_id = ? AND id <> ?", userID, id).
+			Where(sameTagName(name)).
+			Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrTagNameConflict
+		}
+
+		return tx.Model(&tag).Updates(map[string]interface{}{
+			"name":     name,
+			"name_key": models.TagNameKey(name),
+		}).Error
+	})
+}
+
+func (r *tagRepository) Merge(sourceID, targetID, userID string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Both tags must belong to the user
+		var count int64
+		err := tx.Model(&models.Tag{}).
+			Where("id IN ? AND user_id = ?", []string{sourceID, targetID}, userID).
+			Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count != 2 {
+			return gorm.ErrRecordNotFound
+		}
+
+		// Move associations that the target tag does not already have
+		err = tx.Exec(`
+			INSERT INTO article_tags (article_id, tag_id, created_at)
+			SELECT article_id, ?, created_at
+			FROM article_tags
+			WHERE tag_id = ? AND article_id NOT IN (
+				SELECT article_id FROM (
+					SELECT article_id FROM article_tags WHERE tag_id = ?
+				) existing
+			)
+		`, targetID, sourceID, targetID).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Exec("DELETE FROM article_tags WHERE tag_id = ?", sourceID).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("id = ? AND user_id = ?", sourceID, userID).Delete(&models.Tag{}).Error; err != nil {
+			return err
+		}
+
+		// Recalculate usage count of the merged tag
+		err = tx.Table("article_tags").Where("tag_id = ?", targetID).Count(&count).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Tag{}).
+			Where("id = ?", targetID).
+			Update("usage_count", count).Error
+	})
+}
+
 func (r *tagRepository) Delete(id, userID string) error {
 	return r.db.Transaction(func(tx *gorm.DB) error {
+		var tag models.Tag
+		if err := tx.Where("id = ? AND user_id = ?", id, userID).First(&tag).Error; err != nil {
+			return err
+		}
+
 		// Delete article-tag associations
 		err := tx.Exec("DELETE FROM article_tags WHERE tag_id = ?", id).Error
 		if err != nil {
@@ -154,4 +317,13 @@ func (r *tagRepository) GetUnusedTags(userID string) ([]*models.Tag, error) {
 		Order("created_at DESC").
 		Find(&tags).Error
 	return tags, err
-}
\ No newline at end of file
+}
+
+// DeleteUnused deletes the user's tags that are not on any article and
+// returns how many were deleted
+func (r *tagRepository) DeleteUnused(userID string) (int64, error) {
+	result := r.db.Where("user_id = ?", userID).
+		Where("NOT EXISTS (SELECT 1 FROM article_tags WHERE article_tags.tag_id = tags.id)").
+		Delete(&models.Tag{})
+	return result.RowsAffected, result.Error
+}
@@ -12,20 +12,23 @@ import (
 
 type UserRepository interface {
 	Create(user *models.User) error
+	// CreateWithDefaultCategory creates the user together with their default
+	// category, or neither
+	CreateWithDefaultCategory(user *models.User) error
 	Update(user *models.User) error
-	GetByID(id uint) (*models.User, error)
+	GetByID(id string) (*models.User, error)
 	GetByEmail(email string) (*models.User, error)
 	GetByGoogleID(googleID string) (*models.User, error)
-	Delete(id uint) error
+	Delete(id string) error
 	
 	// セッション管理
 	CreateSession(session *models.UserSession) error
 	GetSessionByToken(token string) (*models.UserSession, error)
-	GetSessionsByUserID(userID uint) ([]models.UserSession, error)
+	GetSessionsByUserID(userID string) ([]models.UserSession, error)
 	UpdateSession(session *models.UserSession) error
-	DeleteSession(sessionID uint) error
+	DeleteSession(sessionID string) error
 	DeleteExpiredSessions() error
-	DeleteUserSessions(userID uint) error
+	DeleteUserSessions(userID string) error
 }
 
 type userRepository struct {
@@ -40,13 +43,23 @@ func (r *userRepository) Create(user *models.User) error {
 	return r.db.Create(user).Error
 }
 
+func (r *userRepository) CreateWithDefaultCategory(user *models.User) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		_, err := NewCategoryRepository(tx).CreateDefault(user.ID)
+		return err
+	})
+}
+
 func (r *userRepository) Update(user *models.User) error {
 	return r.db.Save(user).Error
 }
 
-func (r *userRepository) GetByID(id uint) (*models.User, error) {
+func (r *userRepository) GetByID(id string) (*models.User, error) {
 	var user models.User
-	err := r.db.Preload("Preferences").First(&user, id).Error
+	err := r.db.Preload("Preferences").Where("id = ?", id).First(&user).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("user not found")
@@ -80,8 +93,8 @@ func (r *userRepository) GetByGoogleID(googleID string) (*models.User, error) {
 	return &user, nil
 }
 
-func (r *userRepository) Delete(id uint) error {
-	return r.db.Delete(&models.User{}, id).Error
+func (r *userRepository) Delete(id string) error {
+	return r.db.Where("id = ?", id).Delete(&models.User{}).Error
 }
 
 // セッション管理メソッド
@@ -114,7 +127,7 @@ func (r *userRepository) GetSessionByToken(token string) (*models.UserSession, e
 	return nil, errors.New("session not found")
 }
 
-func (r *userRepository) GetSessionsByUserID(userID uint) ([]models.UserSession, error) {
+func (r *userRepository) GetSessionsByUserID(userID string) ([]models.UserSession, error) {
 	var sessions []models.UserSession
 	err := r.db.Where("user_id = ? AND is_active = ?", userID, true).Find(&sessions).Error
 	return sessions, err
@@ -124,8 +137,8 @@ func (r *userRepository) UpdateSession(session *models.UserSession) error {
 	return r.db.Save(session).Error
 }
 
-func (r *userRepository) DeleteSession(sessionID uint) error {
-	return r.db.Delete(&models.UserSession{}, sessionID).Error
+func (r *userRepository) DeleteSession(sessionID string) error {
+	return r.db.Where("id = ?", sessionID).Delete(&models.UserSession{}).Error
 }
 
 func (r *userRepository) DeleteExpiredSessions() error {
@@ -133,6 +146,6 @@ func (r *userRepository) DeleteExpiredSessions() error {
 	return r.db.Where("expires_at < ?", now).Delete(&models.UserSession{}).Error
 }
 
-func (r *userRepository) DeleteUserSessions(userID uint) error {
+func (r *userRepository) DeleteUserSessions(userID string) error {
 	return r.db.Where("user_id = ?", userID).Delete(&models.UserSession{}).Error
 }
\ No newline at end of file
@@ -0,0 +1,321 @@
+// Package search is the fulltext index of saved articles. Articles are split
+// into terms by Tokenize, stored as postings in the database next to the
+// articles, and ranked with BM25F over their title, summary, content and
+// tags. Japanese text is indexed by character bigrams, so it needs no
+// dictionary.
+package search
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/eikuma/stockle/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Version identifies how documents are tokenized. Articles indexed with an
+// older version are listed by SearchIndex.Stale so they can be indexed again.
+const Version = 1
+
+// Document is the searchable text of an article
+type Document struct {
+	ArticleID string
+	UserID    string
+	Title     string
+	Summary   string
+	Content   string
+	Tags      []string
+}
+
+// Query selects a page of the articles matching Text
+type Query struct {
+	Text   string
+	Offset int
+	Limit  int
+	// After starts the page after this hit instead of at Offset, so that
+	// a listing can be continued from the last hit of the previous page
+	After *Hit
+}
+
+// Hit is an article matching a query, with its relevance
+type Hit struct {
+	ArticleID string
+	Score     float64
+}
+
+// Before reports whether h ranks before other: higher scores first, ties
+// in ID order
+func (h Hit) Before(other Hit) bool {
+	if h.Score != other.Score {
+		return h.Score > other.Score
+	}
+	return h.ArticleID < other.ArticleID
+}
+
+// Results are the hits of a page, best first, and the number of all hits
+type Results struct {
+	Hits  []Hit
+	Total int
+	// Terms are the query terms, for highlighting the hits
+	Terms []string
+}
+
+// SearchIndex is a fulltext index of the articles of all users
+type SearchIndex interface {
+	// Index adds the document or replaces its previous version
+	Index(doc Document) error
+	// Remove drops an article from the index
+	Remove(articleID string) error
+	// Search returns the articles of the user that contain every query term
+	Search(userID string, query Query) (*Results, error)
+	// Stale returns up to limit IDs, greater than after, of the articles that
+	// are missing from the index or were indexed by another Version
+	Stale(after string, limit int) ([]string, error)
+}
+
+type field int
+
+const (
+	fieldTitle field = iota
+	fieldSummary
+	fieldContent
+	fieldTags
+	numFields
+)
+
+// fieldWeights make a match in the title or the tags count more than one in
+// the body
+var fieldWeights = [numFields]float64{
+	fieldTitle:   3,
+	fieldSummary: 1.5,
+	fieldContent: 1,
+	fieldTags:    2,
+}
+
+// BM25 parameters: k1 saturates the term frequency, b normalizes the length
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// candidateBatchSize bounds the number of IDs bound in one IN clause
+const candidateBatchSize = 500
+
+type dbIndex struct {
+	db *gorm.DB
+}
+
+// NewIndex returns a SearchIndex stored in the search_documents and
+// search_postings tables of db
+func NewIndex(db *gorm.DB) SearchIndex {
+	return &dbIndex{db: db}
+}
+
+func (x *dbIndex) Index(doc Document) error {
+	frequencies, lengths := analyze(doc)
+
+	postings := make([]models.SearchPosting, 0, len(frequencies))
+	for term, f := range frequencies {
+		postings = append(postings, models.SearchPosting{
+			ArticleID:        doc.ArticleID,
+			Term:             term,
+			UserID:           doc.UserID,
+			TitleFrequency:   f[fieldTitle],
+			SummaryFrequency: f[fieldSummary],
+			ContentFrequency: f[fieldContent],
+			TagsFrequency:    f[fieldTags],
+		})
+	}
+	// A fixed insert order keeps concurrent reindexing from deadlocking
+	sort.Slice(postings, func(i, j int) bool { return postings[i].Term < postings[j].Term })
+
+	document := models.SearchDocument{
+		ArticleID:     doc.ArticleID,
+		UserID:        doc.UserID,
+		TitleLength:   lengths[fieldTitle],
+		SummaryLength: lengths[fieldSummary],
+		ContentLength: lengths[fieldContent],
+		TagsLength:    lengths[fieldTags],
+		Version:       Version,
+	}
+
+	return x.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("article_id = ?", doc.ArticleID).Delete(&models.SearchPosting{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&document).Error; err != nil {
+			return err
+		}
+		if len(postings) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(postings, candidateBatchSize).Error
+	})
+}
+
+func (x *dbIndex) Remove(articleID string) error {
+	return x.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("article_id = ?", articleID).Delete(&models.SearchPosting{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("article_id = ?", articleID).Delete(&models.SearchDocument{}).Error
+	})
+}
+
+func (x *dbIndex) Search(userID string, query Query) (*Results, error) {
+	terms := QueryTerms(query.Text)
+	results := &Results{Terms: terms}
+	if len(terms) == 0 {
+		return results, nil
+	}
+
+	var postings []models.SearchPosting
+	if err := x.db.Where("user_id = ? AND term IN ?", userID, terms).Find(&postings).Error; err != nil {
+		return nil, fmt.Errorf("failed to read postings: %w", err)
+	}
+
+	byArticle := make(map[string][]*models.SearchPosting)
+	documentFrequency := make(map[string]int, len(terms))
+	for i := range postings {
+		p := &postings[i]
+		byArticle[p.ArticleID] = append(byArticle[p.ArticleID], p)
+		documentFrequency[p.Term]++
+	}
+
+	// Every term must match
+	var candidates []string
+	for articleID, matched := range byArticle {
+		if len(matched) == len(terms) {
+			candidates = append(candidates, articleID)
+		}
+	}
+	if len(candidates) == 0 {
+		return results, nil
+	}
+
+	var stats struct {
+		Documents  float64
+		AvgTitle   float64
+		AvgSummary float64
+		AvgContent float64
+		AvgTags    float64
+	}
+	err := x.db.Model(&models.SearchDocument{}).
+		Select(`COUNT(*) AS documents,
+			COALESCE(AVG(title_length), 0) AS avg_title,
+			COALESCE(AVG(summary_length), 0) AS avg_summary,
+			COALESCE(AVG(content_length), 0) AS avg_content,
+			COALESCE(AVG(tags_length), 0) AS avg_tags`).
+		Where("user_id = ?", userID).
+		Scan(&stats).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index statistics: %w", err)
+	}
+	averages := [numFields]float64{stats.AvgTitle, stats.AvgSummary, stats.AvgContent, stats.AvgTags}
+
+	lengths, err := x.lengths(candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]Hit, 0, len(candidates))
+	for _, articleID := range candidates {
+		score := 0.0
+		for _, p := range byArticle[articleID] {
+			frequencies := [numFields]int{p.TitleFrequency, p.SummaryFrequency, p.ContentFrequency, p.TagsFrequency}
+			idf := inverseDocumentFrequency(stats.Documents, float64(documentFrequency[p.Term]))
+			score += idf * weightedFrequency(frequencies, lengths[articleID], averages)
+		}
+		hits = append(hits, Hit{ArticleID: articleID, Score: score})
+	}
+
+	sort.Slice(hits, func(i, j int) bool { return hits[i].Before(hits[j]) })
+
+	results.Total = len(hits)
+	start := min(max(query.Offset, 0), len(hits))
+	if query.After != nil {
+		start = sort.Search(len(hits), func(i int) bool { return query.After.Before(hits[i]) })
+	}
+	end := len(hits)
+	if query.Limit > 0 {
+		end = min(start+query.Limit, len(hits))
+	}
+	results.Hits = hits[start:end]
+	return results, nil
+}
+
+// lengths returns the field lengths of the indexed articles
+func (x *dbIndex) lengths(articleIDs []string) (map[string][numFields]int, error) {
+	lengths := make(map[string][numFields]int, len(articleIDs))
+	for start := 0; start < len(articleIDs); start += candidateBatchSize {
+		batch := articleIDs[start:min(start+candidateBatchSize, len(articleIDs))]
+
+		var documents []models.SearchDocument
+		if err := x.db.Where("article_id IN ?", batch).Find(&documents).Error; err != nil {
+			return nil, fmt.Errorf("failed to read indexed documents: %w", err)
+		}
+		for _, d := range documents {
+			lengths[d.ArticleID] = [numFields]int{d.TitleLength, d.SummaryLength, d.ContentLength, d.TagsLength}
+		}
+	}
+	return lengths, nil
+}
+
+func (x *dbIndex) Stale(after string, limit int) ([]string, error) {
+	var ids []string
+	err := x.db.Table("articles").
+		Joins("LEFT JOIN search_documents ON search_documents.article_id = articles.id").
+		Where("articles.id > ?", after).
+		Where("search_documents.article_id IS NULL OR search_documents.version <> ?", Version).
+		Order("articles.id").
+		Limit(limit).
+		Pluck("articles.id", &ids).Error
+	return ids, err
+}
+
+// analyze counts the terms of each field of doc and the length of each field
+func analyze(doc Document) (map[string][numFields]int, [numFields]int) {
+	frequencies := make(map[string][numFields]int)
+	var lengths [numFields]int
+
+	add := func(f field, text string) {
+		for _, token := range Tokenize(text) {
+			counts := frequencies[token.Term]
+			counts[f]++
+			frequencies[token.Term] = counts
+			lengths[f]++
+		}
+	}
+
+	add(fieldTitle, doc.Title)
+	add(fieldSummary, doc.Summary)
+	add(fieldContent, doc.Content)
+	for _, tag := range doc.Tags {
+		add(fieldTags, tag)
+	}
+	return frequencies, lengths
+}
+
+// inverseDocumentFrequency is the BM25 IDF of a term found in df of n documents
+func inverseDocumentFrequency(n, df float64) float64 {
+	return math.Log(1 + (n-df+0.5)/(df+0.5))
+}
+
+// weightedFrequency combines the frequencies of a term in each field into
+// one saturated BM25F term weight
+func weightedFrequency(frequencies, lengths [numFields]int, averages [numFields]float64) float64 {
+	tf := 0.0
+	for f := field(0); f < numFields; f++ {
+		if frequencies[f] == 0 {
+			continue
+		}
+		norm := 1.0
+		if averages[f] > 0 {
+			norm = 1 - bm25B + bm25B*float64(lengths[f])/averages[f]
+		}
+		tf += fieldWeights[f] * float64(frequencies[f]) / norm
+	}
+	return tf / (bm25K1 + tf)
+}
@@ -0,0 +1,142 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	highlightStart = "<mark>"
+	highlightEnd   = "</mark>"
+	ellipsis       = "…"
+)
+
+// span is a byte range of a text
+type span struct {
+	start, end int
+}
+
+// Highlight returns text HTML-escaped, with every occurrence of the terms
+// wrapped in <mark> tags
+func Highlight(text string, terms []string) string {
+	return mark(text, matches(text, terms), 0, len(text))
+}
+
+// Snippet returns the part of text of about size characters with the most
+// matches of the terms, HTML-escaped and highlighted like Highlight, and
+// whether anything matched. When nothing matches, the start of text is
+// returned. Cut ends are marked with an ellipsis.
+func Snippet(text string, terms []string, size int) (string, bool) {
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		return "", false
+	}
+	found := matches(text, terms)
+
+	start := 0
+	if len(found) > 0 {
+		start = bestWindow(text, found, size)
+	}
+	end := advance(text, start, size)
+
+	snippet := mark(text, found, start, end)
+	if start > 0 {
+		snippet = ellipsis + snippet
+	}
+	if end < len(text) {
+		snippet += ellipsis
+	}
+	return snippet, len(found) > 0
+}
+
+// matches returns the merged byte ranges of text whose tokens are terms
+func matches(text string, terms []string) []span {
+	if len(terms) == 0 {
+		return nil
+	}
+	wanted := make(map[string]bool, len(terms))
+	for _, term := range terms {
+		wanted[term] = true
+	}
+
+	var found []span
+	for _, token := range Tokenize(text) {
+		if !wanted[token.Term] {
+			continue
+		}
+		// Tokens come in order of their start; bigrams of one phrase overlap
+		if n := len(found); n > 0 && token.Start <= found[n-1].end {
+			if token.End > found[n-1].end {
+				found[n-1].end = token.End
+			}
+			continue
+		}
+		found = append(found, span{token.Start, token.End})
+	}
+	return found
+}
+
+// bestWindow returns the byte offset at which a window of size characters
+// covers the most matches, moved back a little so the first match has some
+// context before it
+func bestWindow(text string, found []span, size int) int {
+	best, bestCount := 0, 0
+	for i, anchor := range found {
+		limit := advance(text, anchor.start, size)
+		count := 0
+		for _, m := range found[i:] {
+			if m.end > limit {
+				break
+			}
+			count++
+		}
+		if count > bestCount {
+			best, bestCount = i, count
+		}
+	}
+
+	start := retreat(text, found[best].start, size/5)
+	// Prefer to start at a word boundary when one is close
+	if space := strings.IndexByte(text[start:found[best].start], ' '); space >= 0 && start > 0 {
+		start += space + 1
+	}
+	return start
+}
+
+// mark escapes text[start:end] and wraps the parts covered by found in tags
+func mark(text string, found []span, start, end int) string {
+	var b strings.Builder
+	pos := start
+	for _, m := range found {
+		if m.end <= start || m.start >= end {
+			continue
+		}
+		from, to := max(m.start, start), min(m.end, end)
+		b.WriteString(html.EscapeString(text[pos:from]))
+		b.WriteString(highlightStart)
+		b.WriteString(html.EscapeString(text[from:to]))
+		b.WriteString(highlightEnd)
+		pos = to
+	}
+	b.WriteString(html.EscapeString(text[pos:end]))
+	return b.String()
+}
+
+// advance returns the byte offset n characters after offset
+func advance(text string, offset, n int) int {
+	for ; n > 0 && offset < len(text); n-- {
+		_, width := utf8.DecodeRuneInString(text[offset:])
+		offset += width
+	}
+	return offset
+}
+
+// retreat returns the byte offset n characters before offset
+func retreat(text string, offset, n int) int {
+	for ; n > 0 && offset > 0; n-- {
+		_, width := utf8.DecodeLastRuneInString(text[:offset])
+		offset -= width
+	}
+	return offset
+}
@@ -0,0 +1,48 @@
+package search
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		query string
+		want  string
+	}{
+		{name: "words are marked", text: "Go Concurrency Patterns", query: "concurrency", want: "Go <mark>Concurrency</mark> Patterns"},
+		{name: "bigrams of a phrase are merged", text: "Goの並行処理入門", query: "並行処理", want: "Goの<mark>並行処理</mark>入門"},
+		{name: "text is escaped", text: "<b>Tips</b> & tricks", query: "tricks", want: "&lt;b&gt;Tips&lt;/b&gt; &amp; <mark>tricks</mark>"},
+		{name: "full-width text matches", text: "ＧＯ入門", query: "go", want: "<mark>ＧＯ</mark>入門"},
+		{name: "no match", text: "Rust", query: "go", want: "Rust"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Highlight(tt.text, QueryTerms(tt.query)))
+		})
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	filler := strings.Repeat("lorem ipsum ", 30)
+	text := filler + "channels and goroutines together, channels everywhere. " + filler
+
+	snippet, matched := Snippet(text, QueryTerms("channels goroutines"), 60)
+	assert.True(t, matched)
+	assert.True(t, strings.HasPrefix(snippet, "…"), snippet)
+	assert.True(t, strings.HasSuffix(snippet, "…"), snippet)
+	assert.Contains(t, snippet, "<mark>channels</mark> and <mark>goroutines</mark>")
+
+	// Without a match the start of the text is shown
+	snippet, matched = Snippet("Short\n\ttext", QueryTerms("rust"), 60)
+	assert.False(t, matched)
+	assert.Equal(t, "Short text", snippet)
+
+	snippet, matched = Snippet("", QueryTerms("rust"), 60)
+	assert.False(t, matched)
+	assert.Empty(t, snippet)
+}
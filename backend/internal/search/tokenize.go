@@ -0,0 +1,145 @@
+package search
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxTermBytes is the longest term stored in the index; longer words are cut
+const maxTermBytes = 64
+
+// Token is a normalized term and the byte range of the text it was read from
+type Token struct {
+	Term  string
+	Start int
+	End   int
+}
+
+// runeClass tells how a character takes part in a term
+type runeClass int
+
+const (
+	classSeparator runeClass = iota
+	// classWord characters form words, e.g. Latin letters and digits
+	classWord
+	// classCJK characters have no word boundaries and are split into bigrams
+	classCJK
+)
+
+// Tokenize splits text into index terms. Words of alphabetic scripts become
+// one lower-cased term each. Runs of Japanese or Chinese characters, which
+// are not separated by spaces, become overlapping bigrams plus one unigram
+// per character, so that both single characters and any longer phrase can
+// be found. Full-width ASCII is folded to its half-width form.
+func Tokenize(text string) []Token {
+	return tokenize(text, true)
+}
+
+// QueryTerms returns the distinct terms a query matches on. Runs of Japanese
+// or Chinese characters are looked up by their bigrams, which must all be
+// present, so a longer phrase is not matched by its single characters.
+func QueryTerms(query string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, token := range tokenize(query, false) {
+		if !seen[token.Term] {
+			seen[token.Term] = true
+			terms = append(terms, token.Term)
+		}
+	}
+	return terms
+}
+
+func tokenize(text string, unigrams bool) []Token {
+	var tokens []Token
+
+	// start is the byte offset of the current word or CJK run, -1 outside one
+	start := -1
+	var class runeClass
+	var word []rune
+	var cjk []int // byte offsets of the characters of the CJK run, and its end
+
+	flush := func(end int) {
+		switch class {
+		case classWord:
+			tokens = append(tokens, Token{Term: truncate(string(word)), Start: start, End: end})
+		case classCJK:
+			cjk = append(cjk, end)
+			chars := len(cjk) - 1
+			for i := 0; i < chars; i++ {
+				if unigrams || chars == 1 {
+					tokens = append(tokens, Token{Term: foldRunes(text[cjk[i]:cjk[i+1]]), Start: cjk[i], End: cjk[i+1]})
+				}
+				if i+2 <= chars {
+					tokens = append(tokens, Token{Term: foldRunes(text[cjk[i]:cjk[i+2]]), Start: cjk[i], End: cjk[i+2]})
+				}
+			}
+		}
+		start = -1
+		word = word[:0]
+		cjk = cjk[:0]
+	}
+
+	for offset, r := range text {
+		r = fold(r)
+		current := classify(r)
+		if start >= 0 && current != class {
+			flush(offset)
+		}
+		if current == classSeparator {
+			continue
+		}
+		if start < 0 {
+			start = offset
+			class = current
+		}
+		if current == classWord {
+			word = append(word, r)
+		} else {
+			cjk = append(cjk, offset)
+		}
+	}
+	if start >= 0 {
+		flush(len(text))
+	}
+	return tokens
+}
+
+// fold maps full-width ASCII to half-width and upper to lower case
+func fold(r rune) rune {
+	if r >= 0xFF01 && r <= 0xFF5E {
+		r -= 0xFEE0
+	}
+	return unicode.ToLower(r)
+}
+
+func foldRunes(s string) string {
+	runes := []rune(s)
+	for i, r := range runes {
+		runes[i] = fold(r)
+	}
+	return string(runes)
+}
+
+func classify(r rune) runeClass {
+	switch {
+	case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana) || r == 'ー' || r == '々':
+		return classCJK
+	case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r):
+		return classWord
+	default:
+		return classSeparator
+	}
+}
+
+// truncate cuts term to maxTermBytes without splitting a character
+func truncate(term string) string {
+	if len(term) <= maxTermBytes {
+		return term
+	}
+	end := maxTermBytes
+	for end > 0 && !utf8.RuneStart(term[end]) {
+		end--
+	}
+	return term[:end]
+}
@@ -0,0 +1,58 @@
+package search
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func terms(tokens []Token) []string {
+	out := make([]string, len(tokens))
+	for i, token := range tokens {
+		out[i] = token.Term
+	}
+	return out
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "words are lower-cased", text: "Go Concurrency, Patterns!", want: []string{"go", "concurrency", "patterns"}},
+		{name: "full-width ASCII is folded", text: "Ｇｏ１２３", want: []string{"go123"}},
+		{name: "japanese runs become unigrams and bigrams", text: "並行処理", want: []string{"並", "並行", "行", "行処", "処", "処理", "理"}},
+		{name: "scripts split words", text: "Goの型", want: []string{"go", "の", "の型", "型"}},
+		{name: "prolonged sound mark stays in katakana", text: "サーバー", want: []string{"サ", "サー", "ー", "ーバ", "バ", "バー", "ー"}},
+		{name: "punctuation separates", text: "API・SDK", want: []string{"api", "sdk"}},
+		{name: "empty text", text: " \n\t", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, terms(Tokenize(tt.text)))
+		})
+	}
+}
+
+func TestTokenize_Offsets(t *testing.T) {
+	text := "Go言語 rocks"
+	for _, token := range Tokenize(text) {
+		assert.Equal(t, token.Term, strings.ToLower(text[token.Start:token.End]))
+	}
+}
+
+func TestTokenize_LongWordsAreCut(t *testing.T) {
+	tokens := Tokenize(strings.Repeat("é", 40))
+	assert.Len(t, tokens, 1)
+	assert.Equal(t, strings.Repeat("é", 32), tokens[0].Term)
+}
+
+func TestQueryTerms(t *testing.T) {
+	// A phrase is looked up by its bigrams only, a single character by itself
+	assert.Equal(t, []string{"並行", "行処", "処理"}, QueryTerms("並行処理"))
+	assert.Equal(t, []string{"型"}, QueryTerms("型"))
+	assert.Equal(t, []string{"go", "型"}, QueryTerms("Go 型 go"))
+	assert.Empty(t, QueryTerms("!?"))
+}
@@ -2,19 +2,56 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/eikuma/stockle/backend/internal/config"
+	"github.com/eikuma/stockle/backend/internal/models"
 	"github.com/eikuma/stockle/backend/pkg/anthropic"
 	"github.com/eikuma/stockle/backend/pkg/groq"
+	"github.com/eikuma/stockle/backend/pkg/llm"
+	"github.com/eikuma/stockle/backend/pkg/openai"
 )
 
+// AIService summarizes articles with a chain of language model providers,
+// falling back to the next provider when one fails
 type AIService struct {
-	groqClient      *groq.Client
-	anthropicClient *anthropic.Client
-	config          *config.AIConfig
+	// chain is tried for the summaries matching no route
+	chain  []*aiProvider
+	routes []aiRoute
+	// byName holds the providers of the chain by their names
+	byName map[string]*aiProvider
+	// judge, when configured, scores every summary
+	judge  *aiProvider
+	config *config.AIConfig
+}
+
+// ErrUnknownProvider is returned for a summary requested of a provider that
+// is not configured
+var ErrUnknownProvider = errors.New("unknown AI provider")
+
+// aiProvider is a provider of the chain with its generation settings
+type aiProvider struct {
+	name        string
+	client      llm.LLMProvider
+	model       string
+	maxTokens   int
+	temperature *float64
+	// contextWindow is the number of tokens of the prompt and the answer
+	contextWindow int
+}
+
+type aiRoute struct {
+	name         string
+	languages    []string
+	summaryTypes []string
+	minLength    int
+	maxLength    int
+	chain        []*aiProvider
 }
 
 type SummaryRequest struct {
@@ -23,107 +60,308 @@ type SummaryRequest struct {
 	URL         string
 	Language    string
 	SummaryType string // "short", "medium", "long"
+	// OutputLanguage is the language of the summary, Language when empty.
+	// An article in another language is translated.
+	OutputLanguage string
+	// Provider, when set, is the only provider asked for the summary,
+	// bypassing the routes and the fallback
+	Provider string
 }
 
 type SummaryResponse struct {
-	Summary      string
+	Summary string
+	// Confidence is the quality score of the summary from 0 to 1, see
+	// SummaryValidation
 	Confidence   float64
+	Issues       []string
 	Provider     string
 	GeneratedAt  time.Time
 	ModelVersion string
 	WordCount    int
+	// Strategy is models.SummaryStrategySingle, or
+	// models.SummaryStrategyMapReduce for an article summarized in Chunks
+	Strategy string
+	Chunks   int
 }
 
-func NewAIService(cfg *config.AIConfig) *AIService {
-	return &AIService{
-		groqClient:      groq.NewClient(cfg.GroqAPIKey),
-		anthropicClient: anthropic.NewClient(cfg.AnthropicAPIKey),
-		config:          cfg,
-	}
+// NewAIService builds the provider chain and the routes of the
+// configuration, see config.AIConfig.ProviderChain
+func NewAIService(cfg *config.AIConfig) (*AIService, error) {
+	return newAIService(cfg, newLLMClient)
 }
 
-func (s *AIService) GenerateSummary(ctx context.Context, req *SummaryRequest) (*SummaryResponse, error) {
-	// Groqを第一選択として使用
-	summary, err := s.generateWithGroq(ctx, req)
-	if err != nil {
-		// Groqが失敗した場合はClaude APIにフォールバック
-		summary, err = s.generateWithClaude(ctx, req)
+// newAIService creates the clients of the providers with newClient, which
+// the tests replace with fakes
+func newAIService(cfg *config.AIConfig, newClient func(config.AIProviderConfig) (llm.LLMProvider, error)) (*AIService, error) {
+	byName := make(map[string]*aiProvider)
+	s := &AIService{byName: byName, config: cfg}
+
+	for _, providerCfg := range cfg.ProviderChain() {
+		if providerCfg.Name == "" {
+			providerCfg.Name = providerCfg.Type
+		}
+		if _, ok := byName[providerCfg.Name]; ok {
+			return nil, fmt.Errorf("duplicate AI provider %q", providerCfg.Name)
+		}
+		client, err := newClient(providerCfg)
 		if err != nil {
-			return nil, fmt.Errorf("all AI providers failed: %w", err)
+			return nil, fmt.Errorf("AI provider %q: %w", providerCfg.Name, err)
+		}
+		rateLimit := providerCfg.RateLimitPerMin
+		if rateLimit == 0 {
+			rateLimit = cfg.RateLimitPerMin
+		}
+		client = llm.NewResilient(client, llm.Policy{
+			MaxRetries:       cfg.MaxRetries,
+			RetryDelay:       cfg.RetryDelay,
+			MaxRetryDelay:    cfg.MaxRetryDelay,
+			RequestTimeout:   cfg.RequestTimeout,
+			RateLimitPerMin:  rateLimit,
+			FailureThreshold: cfg.CircuitBreakerThreshold,
+			Cooldown:         cfg.CircuitBreakerCooldown,
+		})
+		provider := &aiProvider{
+			name:          providerCfg.Name,
+			client:        client,
+			model:         providerCfg.Model,
+			maxTokens:     providerCfg.MaxTokens,
+			temperature:   providerCfg.Temperature,
+			contextWindow: providerCfg.ContextWindow,
+		}
+		byName[provider.name] = provider
+		s.chain = append(s.chain, provider)
+	}
+
+	for i, routeCfg := range cfg.Routes {
+		route := aiRoute{
+			name:         routeCfg.Name,
+			languages:    routeCfg.Languages,
+			summaryTypes: routeCfg.SummaryTypes,
+			minLength:    routeCfg.MinContentLength,
+			maxLength:    routeCfg.MaxContentLength,
+		}
+		if route.name == "" {
+			route.name = fmt.Sprintf("#%d", i+1)
+		}
+		if len(routeCfg.Providers) == 0 {
+			return nil, fmt.Errorf("AI route %s has no providers", route.name)
+		}
+		for _, name := range routeCfg.Providers {
+			provider, ok := byName[name]
+			if !ok {
+				return nil, fmt.Errorf("AI route %s uses unknown provider %q", route.name, name)
+			}
+			route.chain = append(route.chain, provider)
+		}
+		s.routes = append(s.routes, route)
+	}
+
+	if cfg.JudgeProvider != "" {
+		judge, ok := byName[cfg.JudgeProvider]
+		if !ok {
+			return nil, fmt.Errorf("AI judge uses unknown provider %q", cfg.JudgeProvider)
 		}
+		s.judge = judge
 	}
 
-	return summary, nil
+	return s, nil
 }
 
-func (s *AIService) generateWithGroq(ctx context.Context, req *SummaryRequest) (*SummaryResponse, error) {
-	prompt := s.buildPrompt(req)
+func newLLMClient(cfg config.AIProviderConfig) (llm.LLMProvider, error) {
+	switch cfg.Type {
+	case config.AIProviderGroq:
+		client := groq.NewClient(cfg.APIKey)
+		if cfg.BaseURL != "" {
+			client.WithBaseURL(cfg.BaseURL)
+		}
+		return client, nil
+	case config.AIProviderAnthropic:
+		client := anthropic.NewClient(cfg.APIKey)
+		if cfg.BaseURL != "" {
+			client.WithBaseURL(cfg.BaseURL)
+		}
+		return client, nil
+	case config.AIProviderOpenAI:
+		if cfg.BaseURL == "" {
+			return nil, fmt.Errorf("base URL is required")
+		}
+		return openai.NewClient(cfg.BaseURL, cfg.APIKey), nil
+	default:
+		return nil, fmt.Errorf("unknown provider type %q", cfg.Type)
+	}
+}
 
-	groqReq := &groq.ChatCompletionRequest{
-		Model: "llama3-8b-8192",
-		Messages: []groq.Message{
-			{
-				Role:    "system",
-				Content: s.getSystemPrompt(req.SummaryType),
-			},
-			{
-				Role:    "user",
-				Content: prompt,
-			},
-		},
-		MaxTokens:   500,
-		Temperature: 0.3,
+// Providers returns the names of the configured providers in the order of
+// the default chain
+func (s *AIService) Providers() []string {
+	names := make([]string, 0, len(s.chain))
+	for _, provider := range s.chain {
+		names = append(names, provider.name)
 	}
+	return names
+}
 
-	resp, err := s.groqClient.CreateChatCompletion(ctx, groqReq)
-	if err != nil {
-		return nil, fmt.Errorf("groq API error: %w", err)
+func (s *AIService) GenerateSummary(ctx context.Context, req *SummaryRequest) (*SummaryResponse, error) {
+	chain := s.chainFor(req)
+	if req.Provider != "" {
+		provider, ok := s.byName[req.Provider]
+		if !ok {
+			return nil, fmt.Errorf("%w %q", ErrUnknownProvider, req.Provider)
+		}
+		chain = []*aiProvider{provider}
+	}
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("all AI providers failed: no AI provider is configured")
 	}
 
-	summary := strings.TrimSpace(resp.Choices[0].Message.Content)
-	confidence := s.calculateConfidence(summary, req.Content)
+	// 品質の低い要約はチェーンの次のプロバイダーで作り直し、最も良いものを返す
+	var best *SummaryResponse
+	for len(chain) > 0 {
+		result, err := s.generate(ctx, chain, req)
+		if err != nil {
+			if best != nil {
+				break
+			}
+			return nil, err
+		}
 
-	return &SummaryResponse{
-		Summary:      summary,
-		Confidence:   confidence,
-		Provider:     "groq",
-		GeneratedAt:  time.Now(),
-		ModelVersion: resp.Model,
-		WordCount:    len(strings.Fields(summary)),
-	}, nil
+		validation := s.validateSummary(ctx, req, result.Summary)
+		result.Confidence = validation.Score
+		result.Issues = validation.Issues
+		if best == nil || result.Confidence > best.Confidence {
+			best = result
+		}
+		if result.Confidence >= s.config.MinConfidence {
+			break
+		}
+
+		next := len(chain)
+		for i, provider := range chain {
+			if provider.name == result.Provider {
+				next = i + 1
+				break
+			}
+		}
+		chain = chain[next:]
+		if len(chain) > 0 {
+			log.Printf("Summary of %s by %s scored %.2f %v (unsupported: %v), trying %s", req.URL, result.Provider, validation.Score, validation.Issues, validation.Unsupported, chain[0].name)
+		}
+	}
+	return best, nil
 }
 
-func (s *AIService) generateWithClaude(ctx context.Context, req *SummaryRequest) (*SummaryResponse, error) {
+// generate summarizes the request with a chain, in one prompt or in chunks
+func (s *AIService) generate(ctx context.Context, chain []*aiProvider, req *SummaryRequest) (*SummaryResponse, error) {
+	// 長い記事は分割して要約する（summary_pipeline.go）
+	budget := inputBudget(chain)
+	system := s.getSystemPrompt(req.SummaryType)
 	prompt := s.buildPrompt(req)
-
-	claudeReq := &anthropic.MessageRequest{
-		Model:     "claude-3-haiku-20240307",
-		MaxTokens: 500,
-		Messages: []anthropic.Message{
-			{
-				Role:    "user",
-				Content: prompt,
-			},
-		},
-		System: s.getSystemPrompt(req.SummaryType),
+	if llm.EstimateTokens(system)+llm.EstimateTokens(prompt) > budget {
+		return s.generateMapReduce(ctx, chain, req, budget)
 	}
 
-	resp, err := s.anthropicClient.CreateMessage(ctx, claudeReq)
+	completion, provider, err := s.complete(ctx, chain, system, prompt)
+	if errors.Is(err, llm.ErrContextLength) {
+		// the estimate fell short of the tokenizer of the model
+		return s.generateMapReduce(ctx, chain, req, budget)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("claude API error: %w", err)
+		return nil, err
 	}
+	return s.summaryResponse(completion, provider, models.SummaryStrategySingle, 1), nil
+}
 
-	summary := strings.TrimSpace(resp.Content[0].Text)
-	confidence := s.calculateConfidence(summary, req.Content)
+// complete asks the providers of a chain in turn for a completion until one
+// answers
+func (s *AIService) complete(ctx context.Context, chain []*aiProvider, system, prompt string) (*llm.Completion, *aiProvider, error) {
+	var errs []error
+	for _, provider := range chain {
+		completion, err := provider.client.Complete(ctx, &llm.CompletionRequest{
+			Model:  provider.model,
+			System: system,
+			Messages: []llm.Message{
+				{
+					Role:    llm.RoleUser,
+					Content: prompt,
+				},
+			},
+			MaxTokens:   provider.maxTokens,
+			Temperature: provider.temperature,
+		})
+		if err == nil {
+			return completion, provider, nil
+		}
+		errs = append(errs, fmt.Errorf("%s API error: %w", provider.name, err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, nil, fmt.Errorf("all AI providers failed: %w", errors.Join(errs...))
+}
 
+func (s *AIService) summaryResponse(completion *llm.Completion, provider *aiProvider, strategy string, chunks int) *SummaryResponse {
+	summary := strings.TrimSpace(completion.Text)
+	modelVersion := completion.Model
+	if modelVersion == "" {
+		modelVersion = provider.model
+	}
 	return &SummaryResponse{
 		Summary:      summary,
-		Confidence:   confidence,
-		Provider:     "claude",
+		Provider:     provider.name,
 		GeneratedAt:  time.Now(),
-		ModelVersion: resp.Model,
+		ModelVersion: modelVersion,
 		WordCount:    len(strings.Fields(summary)),
-	}, nil
+		Strategy:     strategy,
+		Chunks:       chunks,
+	}
+}
+
+// chainFor returns the chain of the first route matching the request
+func (s *AIService) chainFor(req *SummaryRequest) []*aiProvider {
+	for _, route := range s.routes {
+		if route.matches(req) {
+			return route.chain
+		}
+	}
+	return s.chain
+}
+
+func (r *aiRoute) matches(req *SummaryRequest) bool {
+	if len(r.languages) > 0 && !matchesLanguage(r.languages, req.Language) {
+		return false
+	}
+	if len(r.summaryTypes) > 0 && !containsFold(r.summaryTypes, req.SummaryType) {
+		return false
+	}
+	length := utf8.RuneCountInString(req.Content)
+	if r.minLength > 0 && length < r.minLength {
+		return false
+	}
+	if r.maxLength > 0 && length > r.maxLength {
+		return false
+	}
+	return true
+}
+
+// matchesLanguage reports whether a language tag is one of the languages
+// or a regional variant of one, as en-US is of en
+func matchesLanguage(languages []string, language string) bool {
+	for _, candidate := range languages {
+		if strings.EqualFold(candidate, language) ||
+			len(language) > len(candidate) && language[len(candidate)] == '-' && strings.EqualFold(candidate, language[:len(candidate)]) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(values []string, value string) bool {
+	for _, candidate := range values {
+		if strings.EqualFold(candidate, value) {
+			return true
+		}
+	}
+	return false
 }
 
 func (s *AIService) buildPrompt(req *SummaryRequest) string {
@@ -134,7 +372,42 @@ func (s *AIService) buildPrompt(req *SummaryRequest) string {
 記事内容:
 %s
 
-上記の記事を%sで要約してください。`, req.Title, req.URL, req.Content, s.getSummaryTypeDescription(req.SummaryType))
+上記の記事を%sで要約してください。%s`, req.Title, req.URL, req.Content, s.getSummaryTypeDescription(req.SummaryType), s.languageInstruction(req))
+}
+
+// languageInstruction asks for the summary in the output language of the
+// request, translating an article written in another language
+func (s *AIService) languageInstruction(req *SummaryRequest) string {
+	if req.OutputLanguage == "" || matchesLanguage([]string{req.OutputLanguage}, req.Language) {
+		return ""
+	}
+	return fmt.Sprintf("\n記事は%sで書かれていますが、要約は%sに翻訳して書いてください。", languageName(req.Language), languageName(req.OutputLanguage))
+}
+
+// languageName returns the Japanese name of a language code, or the code
+// for the languages not listed
+func languageName(code string) string {
+	base, _, _ := strings.Cut(strings.ToLower(code), "-")
+	switch base {
+	case "ja":
+		return "日本語"
+	case "en":
+		return "英語"
+	case "zh":
+		return "中国語"
+	case "ko":
+		return "韓国語"
+	case "fr":
+		return "フランス語"
+	case "de":
+		return "ドイツ語"
+	case "es":
+		return "スペイン語"
+	case "":
+		return "元の言語"
+	default:
+		return code
+	}
 }
 
 func (s *AIService) getSystemPrompt(summaryType string) string {
@@ -158,14 +431,3 @@ func (s *AIService) getSummaryTypeDescription(summaryType string) string {
 		return "200-300文字の標準的な要約"
 	}
 }
-
-func (s *AIService) calculateConfidence(summary, originalContent string) float64 {
-	// 簡単な信頼度計算（実際にはより複雑なロジックが必要）
-	if len(summary) < 50 {
-		return 0.6
-	}
-	if len(summary) > len(originalContent) {
-		return 0.4
-	}
-	return 0.8
-}
\ No newline at end of file
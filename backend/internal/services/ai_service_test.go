@@ -2,11 +2,20 @@ package services
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/eikuma/stockle/backend/internal/config"
+	"github.com/eikuma/stockle/backend/internal/models"
+	"github.com/eikuma/stockle/backend/pkg/llm"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -20,7 +29,8 @@ func TestAIService_GenerateSummary(t *testing.T) {
 	cfg, err := config.NewAIConfig()
 	require.NoError(t, err)
 
-	aiService := NewAIService(cfg)
+	aiService, err := NewAIService(cfg)
+	require.NoError(t, err)
 
 	tests := []struct {
 		name        string
@@ -103,7 +113,8 @@ func TestAIService_ProviderFallback(t *testing.T) {
 		RateLimitPerMin: 100,
 	}
 
-	aiService := NewAIService(cfg)
+	aiService, err := NewAIService(cfg)
+	require.NoError(t, err)
 
 	request := &SummaryRequest{
 		Content:     "テスト用の短い記事内容です。AIの要約機能をテストしています。",
@@ -128,4 +139,603 @@ func TestAIService_ProviderFallback(t *testing.T) {
 		assert.Contains(t, err.Error(), "all AI providers failed")
 		t.Logf("Both providers failed as expected: %v", err)
 	}
-}
\ No newline at end of file
+}
+
+// fakeLLMServer serves one provider's wire format and records the requests
+type fakeLLMServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []map[string]any
+	headers  []http.Header
+	// status, when not 200, fails every request
+	status int
+	// errors answer the next requests before status
+	errors []fakeLLMError
+}
+
+type fakeLLMError struct {
+	status  int
+	headers map[string]string
+	body    string
+}
+
+func (f *fakeLLMServer) lastRequest(t *testing.T) (map[string]any, http.Header) {
+	t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	require.NotEmpty(t, f.requests)
+	return f.requests[len(f.requests)-1], f.headers[len(f.headers)-1]
+}
+
+func (f *fakeLLMServer) fail(status int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.status = status
+}
+
+// failNext answers the next request with an error response
+func (f *fakeLLMServer) failNext(status int, headers map[string]string, body string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.errors = append(f.errors, fakeLLMError{status: status, headers: headers, body: body})
+}
+
+func (f *fakeLLMServer) requestCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.requests)
+}
+
+// newFakeLLMServer fakes the messages API for anthropic and the chat
+// completions API for the other types, answering with summary
+func newFakeLLMServer(t *testing.T, providerType, summary string) *fakeLLMServer {
+	f := &fakeLLMServer{status: http.StatusOK}
+	path := "/chat/completions"
+	if providerType == config.AIProviderAnthropic {
+		path = "/messages"
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != path {
+			http.NotFound(w, r)
+			return
+		}
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.mu.Lock()
+		f.requests = append(f.requests, body)
+		f.headers = append(f.headers, r.Header.Clone())
+		status := f.status
+		var next *fakeLLMError
+		if len(f.errors) > 0 {
+			next = &f.errors[0]
+			f.errors = f.errors[1:]
+		}
+		f.mu.Unlock()
+		if next != nil {
+			for name, value := range next.headers {
+				w.Header().Set(name, value)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(next.status)
+			io.WriteString(w, next.body)
+			return
+		}
+		if status != http.StatusOK {
+			http.Error(w, `{"error":{"message":"unavailable"}}`, status)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		model, _ := body["model"].(string)
+		if providerType == config.AIProviderAnthropic {
+			json.NewEncoder(w).Encode(map[string]any{
+				"id":   "msg_1",
+				"type": "message",
+				"role": "assistant",
+				"content": []map[string]any{
+					{"type": "text", "text": summary},
+				},
+				"model": model,
+				"usage": map[string]any{"input_tokens": 120, "output_tokens": 40},
+			})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"id":    "chatcmpl-1",
+			"model": model,
+			"choices": []map[string]any{
+				{"index": 0, "message": map[string]any{"role": "assistant", "content": summary}, "finish_reason": "stop"},
+			},
+			"usage": map[string]any{"prompt_tokens": 120, "completion_tokens": 40, "total_tokens": 160},
+		})
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func newTestSummaryRequest(language, content string) *SummaryRequest {
+	return &SummaryRequest{
+		Content:     content,
+		Title:       "テスト記事",
+		URL:         "https://example.com/test",
+		Language:    language,
+		SummaryType: "short",
+	}
+}
+
+func TestAIService_ProviderWireFormats(t *testing.T) {
+	temperature := 0.0
+	tests := []struct {
+		providerType string
+		apiKey       string
+		checkHeaders func(t *testing.T, headers http.Header)
+		checkBody    func(t *testing.T, body map[string]any)
+	}{
+		{
+			providerType: config.AIProviderGroq,
+			apiKey:       "gsk_test",
+			checkHeaders: func(t *testing.T, headers http.Header) {
+				assert.Equal(t, "Bearer gsk_test", headers.Get("Authorization"))
+			},
+			checkBody: func(t *testing.T, body map[string]any) {
+				messages := body["messages"].([]any)
+				require.Len(t, messages, 2)
+				assert.Equal(t, "system", messages[0].(map[string]any)["role"])
+				assert.Equal(t, "user", messages[1].(map[string]any)["role"])
+			},
+		},
+		{
+			providerType: config.AIProviderAnthropic,
+			apiKey:       "sk-ant-test",
+			checkHeaders: func(t *testing.T, headers http.Header) {
+				assert.Equal(t, "sk-ant-test", headers.Get("x-api-key"))
+				assert.Equal(t, "2023-06-01", headers.Get("anthropic-version"))
+			},
+			checkBody: func(t *testing.T, body map[string]any) {
+				assert.Contains(t, body["system"], "要約")
+				messages := body["messages"].([]any)
+				require.Len(t, messages, 1)
+				assert.Equal(t, "user", messages[0].(map[string]any)["role"])
+			},
+		},
+		{
+			providerType: config.AIProviderOpenAI,
+			checkHeaders: func(t *testing.T, headers http.Header) {
+				assert.Empty(t, headers.Get("Authorization"))
+			},
+			checkBody: func(t *testing.T, body map[string]any) {
+				assert.Equal(t, false, body["stream"])
+				messages := body["messages"].([]any)
+				require.Len(t, messages, 2)
+				assert.Equal(t, "system", messages[0].(map[string]any)["role"])
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.providerType, func(t *testing.T) {
+			server := newFakeLLMServer(t, tt.providerType, "  記事の要約です。  ")
+			aiService, err := NewAIService(&config.AIConfig{
+				Providers: []config.AIProviderConfig{{
+					Name:        "primary",
+					Type:        tt.providerType,
+					BaseURL:     server.URL,
+					APIKey:      tt.apiKey,
+					Model:       "test-model",
+					MaxTokens:   256,
+					Temperature: &temperature,
+				}},
+			})
+			require.NoError(t, err)
+
+			result, err := aiService.GenerateSummary(context.Background(), newTestSummaryRequest("ja", "テスト用の記事内容です。"))
+			require.NoError(t, err)
+			assert.Equal(t, "記事の要約です。", result.Summary)
+			assert.Equal(t, "primary", result.Provider)
+			assert.Equal(t, "test-model", result.ModelVersion)
+
+			body, headers := server.lastRequest(t)
+			assert.Equal(t, "test-model", body["model"])
+			assert.EqualValues(t, 256, body["max_tokens"])
+			assert.EqualValues(t, 0, body["temperature"], "a zero temperature is sent rather than left to the provider")
+			assert.Contains(t, fmt.Sprint(body["messages"]), "テスト用の記事内容です。")
+			tt.checkHeaders(t, headers)
+			tt.checkBody(t, body)
+		})
+	}
+}
+
+func TestAIService_FallbackChain(t *testing.T) {
+	groqServer := newFakeLLMServer(t, config.AIProviderGroq, "groqの要約")
+	groqServer.fail(http.StatusServiceUnavailable)
+	claudeServer := newFakeLLMServer(t, config.AIProviderAnthropic, "claudeの要約")
+
+	aiService, err := NewAIService(&config.AIConfig{
+		Providers: []config.AIProviderConfig{
+			{Name: "groq", Type: config.AIProviderGroq, BaseURL: groqServer.URL, Model: "llama"},
+			{Name: "claude", Type: config.AIProviderAnthropic, BaseURL: claudeServer.URL, Model: "haiku"},
+		},
+	})
+	require.NoError(t, err)
+
+	result, err := aiService.GenerateSummary(context.Background(), newTestSummaryRequest("ja", "記事"))
+	require.NoError(t, err)
+	assert.Equal(t, "claude", result.Provider)
+	assert.Equal(t, "claudeの要約", result.Summary)
+	assert.Equal(t, 1, groqServer.requestCount())
+
+	claudeServer.fail(http.StatusInternalServerError)
+	_, err = aiService.GenerateSummary(context.Background(), newTestSummaryRequest("ja", "記事"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "all AI providers failed")
+	assert.Contains(t, err.Error(), "groq API error")
+	assert.Contains(t, err.Error(), "claude API error")
+}
+
+func TestAIService_Routes(t *testing.T) {
+	groqServer := newFakeLLMServer(t, config.AIProviderGroq, "groqの要約")
+	claudeServer := newFakeLLMServer(t, config.AIProviderAnthropic, "claudeの要約")
+	localServer := newFakeLLMServer(t, config.AIProviderOpenAI, "local summary")
+
+	aiService, err := NewAIService(&config.AIConfig{
+		Providers: []config.AIProviderConfig{
+			{Name: "groq", Type: config.AIProviderGroq, BaseURL: groqServer.URL},
+			{Name: "claude", Type: config.AIProviderAnthropic, BaseURL: claudeServer.URL},
+			{Name: "local", Type: config.AIProviderOpenAI, BaseURL: localServer.URL},
+		},
+		Routes: []config.AIRouteConfig{
+			{Name: "english", Languages: []string{"en"}, Providers: []string{"local", "groq"}},
+			{Name: "long", MinContentLength: 100, Providers: []string{"claude"}},
+		},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		request  *SummaryRequest
+		provider string
+	}{
+		{"language", newTestSummaryRequest("en", "A short article."), "local"},
+		{"regional language variant", newTestSummaryRequest("en-US", "A short article."), "local"},
+		{"content length in characters", newTestSummaryRequest("ja", strings.Repeat("長", 100)), "claude"},
+		{"no matching route", newTestSummaryRequest("ja", strings.Repeat("短", 99)), "groq"},
+		{"language prefix is not a variant", newTestSummaryRequest("eng", "A short article."), "groq"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := aiService.GenerateSummary(context.Background(), tt.request)
+			require.NoError(t, err)
+			assert.Equal(t, tt.provider, result.Provider)
+		})
+	}
+}
+
+func TestAIService_RequestedProvider(t *testing.T) {
+	groqServer := newFakeLLMServer(t, config.AIProviderGroq, "groqの要約")
+	claudeServer := newFakeLLMServer(t, config.AIProviderAnthropic, "claudeの要約")
+
+	aiService, err := NewAIService(&config.AIConfig{
+		Providers: []config.AIProviderConfig{
+			{Name: "groq", Type: config.AIProviderGroq, BaseURL: groqServer.URL},
+			{Name: "claude", Type: config.AIProviderAnthropic, BaseURL: claudeServer.URL},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"groq", "claude"}, aiService.Providers())
+
+	req := newTestSummaryRequest("ja", "短い記事です。")
+	req.Provider = "claude"
+	result, err := aiService.GenerateSummary(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "claude", result.Provider)
+	assert.Zero(t, groqServer.requestCount())
+
+	// the requested provider does not fall back to the others
+	claudeServer.fail(http.StatusInternalServerError)
+	_, err = aiService.GenerateSummary(context.Background(), req)
+	require.Error(t, err)
+	assert.Zero(t, groqServer.requestCount())
+
+	req.Provider = "unknown"
+	_, err = aiService.GenerateSummary(context.Background(), req)
+	assert.ErrorIs(t, err, ErrUnknownProvider)
+}
+
+func TestAIService_OutputLanguage(t *testing.T) {
+	fake := &fakeLLM{}
+	service := newFakeLLMService(t, fake, &config.AIConfig{})
+
+	req := newTestSummaryRequest("en-US", "A short article.")
+	req.OutputLanguage = "ja"
+	_, err := service.GenerateSummary(context.Background(), req)
+	require.NoError(t, err)
+	require.Len(t, fake.calls, 1)
+	assert.Contains(t, fake.calls[0].Messages[0].Content, "記事は英語で書かれていますが、要約は日本語に翻訳して書いてください。")
+
+	// no translation into the language of the article
+	req.OutputLanguage = "en"
+	_, err = service.GenerateSummary(context.Background(), req)
+	require.NoError(t, err)
+	assert.NotContains(t, fake.calls[1].Messages[0].Content, "翻訳")
+
+	// chunks and their reduction are written in the output language too
+	fake = &fakeLLM{}
+	service = newFakeLLMService(t, fake, &config.AIConfig{})
+	long := newTestSummaryRequest("ja", longArticle(12))
+	long.OutputLanguage = "en"
+	result, err := service.GenerateSummary(context.Background(), long)
+	require.NoError(t, err)
+	require.Equal(t, models.SummaryStrategyMapReduce, result.Strategy)
+	for _, prompt := range append(fake.prompts(chunkSystemPrompt), fake.prompts(service.getSystemPrompt("short"))...) {
+		assert.Contains(t, prompt, "要約は英語に翻訳して書いてください。")
+	}
+}
+
+func TestNewAIService_DefaultChain(t *testing.T) {
+	aiService, err := NewAIService(&config.AIConfig{
+		AnthropicAPIKey: "sk-ant-test",
+		OpenAIBaseURL:   "http://localhost:11434/v1",
+		OpenAIModel:     "llama3",
+	})
+	require.NoError(t, err)
+
+	var names []string
+	for _, provider := range aiService.chain {
+		names = append(names, provider.name)
+	}
+	assert.Equal(t, []string{"claude", "local"}, names)
+	assert.Equal(t, "claude-3-haiku-20240307", aiService.chain[0].model)
+	assert.Equal(t, "llama3", aiService.chain[1].model)
+
+	empty, err := NewAIService(&config.AIConfig{})
+	require.NoError(t, err)
+	_, err = empty.GenerateSummary(context.Background(), newTestSummaryRequest("ja", "記事"))
+	assert.ErrorContains(t, err, "no AI provider is configured")
+}
+
+func TestNewAIService_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config config.AIConfig
+		err    string
+	}{
+		{
+			name:   "unknown type",
+			config: config.AIConfig{Providers: []config.AIProviderConfig{{Name: "x", Type: "gemini"}}},
+			err:    `unknown provider type "gemini"`,
+		},
+		{
+			name:   "openai without base URL",
+			config: config.AIConfig{Providers: []config.AIProviderConfig{{Name: "local", Type: config.AIProviderOpenAI}}},
+			err:    "base URL is required",
+		},
+		{
+			name: "duplicate name",
+			config: config.AIConfig{Providers: []config.AIProviderConfig{
+				{Name: "groq", Type: config.AIProviderGroq},
+				{Name: "groq", Type: config.AIProviderGroq},
+			}},
+			err: `duplicate AI provider "groq"`,
+		},
+		{
+			name: "route with unknown provider",
+			config: config.AIConfig{
+				Providers: []config.AIProviderConfig{{Name: "groq", Type: config.AIProviderGroq}},
+				Routes:    []config.AIRouteConfig{{Name: "english", Providers: []string{"claude"}}},
+			},
+			err: `AI route english uses unknown provider "claude"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewAIService(&tt.config)
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestAIService_ProviderErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		providerType string
+		status       int
+		headers      map[string]string
+		body         string
+		kind         error
+		retryAfter   time.Duration
+	}{
+		{
+			name:         "anthropic overloaded",
+			providerType: config.AIProviderAnthropic,
+			status:       529,
+			body:         `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`,
+			kind:         llm.ErrOverloaded,
+		},
+		{
+			name:         "anthropic prompt too long",
+			providerType: config.AIProviderAnthropic,
+			status:       http.StatusBadRequest,
+			body:         `{"type":"error","error":{"type":"invalid_request_error","message":"prompt is too long: 210000 tokens > 200000 maximum"}}`,
+			kind:         llm.ErrContextLength,
+		},
+		{
+			name:         "anthropic authentication",
+			providerType: config.AIProviderAnthropic,
+			status:       http.StatusUnauthorized,
+			body:         `{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`,
+			kind:         llm.ErrAuth,
+		},
+		{
+			name:         "groq rate limit",
+			providerType: config.AIProviderGroq,
+			status:       http.StatusTooManyRequests,
+			headers:      map[string]string{"Retry-After": "7"},
+			body:         `{"error":{"message":"Rate limit reached for model","type":"tokens","code":"rate_limit_exceeded"}}`,
+			kind:         llm.ErrRateLimited,
+			retryAfter:   7 * time.Second,
+		},
+		{
+			name:         "groq invalid request",
+			providerType: config.AIProviderGroq,
+			status:       http.StatusBadRequest,
+			body:         `{"error":{"message":"model is required","type":"invalid_request_error"}}`,
+			kind:         llm.ErrInvalidRequest,
+		},
+		{
+			name:         "openai context length",
+			providerType: config.AIProviderOpenAI,
+			status:       http.StatusBadRequest,
+			body:         `{"error":{"message":"This model's maximum context length is 8192 tokens","type":"invalid_request_error","code":"context_length_exceeded"}}`,
+			kind:         llm.ErrContextLength,
+		},
+		{
+			name:         "openai rate limit in milliseconds",
+			providerType: config.AIProviderOpenAI,
+			status:       http.StatusTooManyRequests,
+			headers:      map[string]string{"retry-after-ms": "250"},
+			body:         `{"error":{"message":"Too many requests"}}`,
+			kind:         llm.ErrRateLimited,
+			retryAfter:   250 * time.Millisecond,
+		},
+		{
+			name:         "server error without a JSON body",
+			providerType: config.AIProviderOpenAI,
+			status:       http.StatusBadGateway,
+			body:         "<html>Bad Gateway</html>",
+			kind:         llm.ErrServer,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeLLMServer(t, tt.providerType, "要約")
+			// context length errors are retried in chunks, see
+			// TestAIService_ContextLengthFallsBackToChunks
+			for i := 0; i < 3; i++ {
+				server.failNext(tt.status, tt.headers, tt.body)
+			}
+			aiService, err := NewAIService(&config.AIConfig{
+				Providers: []config.AIProviderConfig{{Name: "primary", Type: tt.providerType, BaseURL: server.URL}},
+			})
+			require.NoError(t, err)
+
+			_, err = aiService.GenerateSummary(context.Background(), newTestSummaryRequest("ja", "記事"))
+			require.Error(t, err)
+			assert.ErrorIs(t, err, tt.kind)
+			var apiErr *llm.APIError
+			require.ErrorAs(t, err, &apiErr)
+			assert.Equal(t, tt.status, apiErr.StatusCode)
+			assert.Equal(t, tt.retryAfter, apiErr.RetryAfter)
+		})
+	}
+}
+
+func TestAIService_RetriesTransientErrors(t *testing.T) {
+	server := newFakeLLMServer(t, config.AIProviderGroq, "要約")
+	server.failNext(http.StatusServiceUnavailable, nil, `{"error":{"message":"over capacity"}}`)
+	server.failNext(http.StatusTooManyRequests, map[string]string{"retry-after-ms": "20"}, `{"error":{"message":"slow down"}}`)
+	aiService, err := NewAIService(&config.AIConfig{
+		Providers:  []config.AIProviderConfig{{Name: "groq", Type: config.AIProviderGroq, BaseURL: server.URL}},
+		MaxRetries: 2,
+		RetryDelay: time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	start := time.Now()
+	result, err := aiService.GenerateSummary(context.Background(), newTestSummaryRequest("ja", "記事"))
+	require.NoError(t, err)
+	assert.Equal(t, "要約", result.Summary)
+	assert.Equal(t, 3, server.requestCount())
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond, "Retry-After is honored")
+
+	// invalid requests are not retried
+	server.failNext(http.StatusBadRequest, nil, `{"error":{"message":"bad model"}}`)
+	_, err = aiService.GenerateSummary(context.Background(), newTestSummaryRequest("ja", "記事"))
+	assert.ErrorIs(t, err, llm.ErrInvalidRequest)
+	assert.Equal(t, 4, server.requestCount())
+}
+
+func TestAIService_EmptyResponses(t *testing.T) {
+	for _, providerType := range []string{config.AIProviderGroq, config.AIProviderAnthropic, config.AIProviderOpenAI} {
+		t.Run(providerType, func(t *testing.T) {
+			server := newFakeLLMServer(t, providerType, "   ")
+			aiService, err := NewAIService(&config.AIConfig{
+				Providers:  []config.AIProviderConfig{{Name: "primary", Type: providerType, BaseURL: server.URL}},
+				MaxRetries: 1,
+				RetryDelay: time.Millisecond,
+			})
+			require.NoError(t, err)
+
+			_, err = aiService.GenerateSummary(context.Background(), newTestSummaryRequest("ja", "記事"))
+			assert.ErrorIs(t, err, llm.ErrEmptyResponse)
+			assert.Equal(t, 2, server.requestCount(), "empty responses are retried")
+		})
+	}
+
+	t.Run("no choices", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, `{"id":"chatcmpl-1","model":"llama","choices":[]}`)
+		}))
+		defer server.Close()
+		aiService, err := NewAIService(&config.AIConfig{
+			Providers: []config.AIProviderConfig{{Name: "groq", Type: config.AIProviderGroq, BaseURL: server.URL}},
+		})
+		require.NoError(t, err)
+
+		_, err = aiService.GenerateSummary(context.Background(), newTestSummaryRequest("ja", "記事"))
+		assert.ErrorIs(t, err, llm.ErrEmptyResponse)
+	})
+}
+
+func TestAIService_RequestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer slow.Close()
+	defer close(release)
+	fallback := newFakeLLMServer(t, config.AIProviderAnthropic, "claudeの要約")
+
+	aiService, err := NewAIService(&config.AIConfig{
+		Providers: []config.AIProviderConfig{
+			{Name: "slow", Type: config.AIProviderOpenAI, BaseURL: slow.URL},
+			{Name: "claude", Type: config.AIProviderAnthropic, BaseURL: fallback.URL},
+		},
+		RequestTimeout: 50 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	result, err := aiService.GenerateSummary(context.Background(), newTestSummaryRequest("ja", "記事"))
+	require.NoError(t, err)
+	assert.Equal(t, "claude", result.Provider)
+}
+
+func TestAIService_CircuitBreaker(t *testing.T) {
+	failing := newFakeLLMServer(t, config.AIProviderGroq, "groqの要約")
+	failing.fail(http.StatusInternalServerError)
+	fallback := newFakeLLMServer(t, config.AIProviderAnthropic, "claudeの要約")
+
+	aiService, err := NewAIService(&config.AIConfig{
+		Providers: []config.AIProviderConfig{
+			{Name: "groq", Type: config.AIProviderGroq, BaseURL: failing.URL},
+			{Name: "claude", Type: config.AIProviderAnthropic, BaseURL: fallback.URL},
+		},
+		CircuitBreakerThreshold: 2,
+		CircuitBreakerCooldown:  time.Hour,
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 4; i++ {
+		result, err := aiService.GenerateSummary(context.Background(), newTestSummaryRequest("ja", "記事"))
+		require.NoError(t, err)
+		assert.Equal(t, "claude", result.Provider)
+	}
+	assert.Equal(t, 2, failing.requestCount(), "the open circuit skips the failing provider")
+	assert.Equal(t, 4, fallback.requestCount())
+}
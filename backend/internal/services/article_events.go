@@ -0,0 +1,101 @@
+package services
+
+import (
+	"sync"
+
+	"github.com/eikuma/stockle/backend/internal/models"
+)
+
+// ArticleEvent is the processing state of an article after a background job changed it
+type ArticleEvent struct {
+	ArticleID               string  `json:"articleId"`
+	ExtractionStatus        string  `json:"extractionStatus"`
+	ExtractionError         *string `json:"extractionError,omitempty"`
+	SummaryGenerationStatus string  `json:"summaryGenerationStatus"`
+	// DuplicateOfID is set when scraping found that the article had already
+	// been saved, and the article was removed in favor of this one
+	DuplicateOfID string `json:"duplicateOfId,omitempty"`
+}
+
+// NewArticleEvent captures the current processing state of article
+func NewArticleEvent(article *models.Article) ArticleEvent {
+	return ArticleEvent{
+		ArticleID:               article.ID,
+		ExtractionStatus:        article.ExtractionStatus,
+		ExtractionError:         article.ExtractionError,
+		SummaryGenerationStatus: article.SummaryGenerationStatus,
+	}
+}
+
+// Done reports whether both the extraction and the summary have finished,
+// or the article was removed as a duplicate
+func (e ArticleEvent) Done() bool {
+	if e.DuplicateOfID != "" {
+		return true
+	}
+	return isFinished(e.ExtractionStatus) && isFinished(e.SummaryGenerationStatus)
+}
+
+func isFinished(status string) bool {
+	return status == models.ExtractionStatusCompleted || status == models.ExtractionStatusFailed ||
+		status == models.SummaryStatusSkipped
+}
+
+// ArticleEventBroker fans out article state changes to subscribers in this process
+type ArticleEventBroker struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan ArticleEvent]struct{}
+}
+
+func NewArticleEventBroker() *ArticleEventBroker {
+	return &ArticleEventBroker{
+		subscribers: make(map[string]map[chan ArticleEvent]struct{}),
+	}
+}
+
+// Subscribe returns a channel receiving the events of one article and a
+// function that must be called to unsubscribe
+func (b *ArticleEventBroker) Subscribe(articleID string) (<-chan ArticleEvent, func()) {
+	ch := make(chan ArticleEvent, 1)
+
+	b.mu.Lock()
+	if b.subscribers[articleID] == nil {
+		b.subscribers[articleID] = make(map[chan ArticleEvent]struct{})
+	}
+	b.subscribers[articleID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers[articleID], ch)
+		if len(b.subscribers[articleID]) == 0 {
+			delete(b.subscribers, articleID)
+		}
+	}
+	return ch, unsubscribe
+}
+
+// Publish delivers event without blocking. Events carry the full state, so a
+// slow subscriber only ever needs the latest one.
+func (b *ArticleEventBroker) Publish(event ArticleEvent) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers[event.ArticleID] {
+		select {
+		case ch <- event:
+		default:
+			// Replace the stale event still waiting in the buffer
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- event
+		}
+	}
+}
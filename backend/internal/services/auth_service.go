@@ -60,7 +60,8 @@ func (s *AuthService) Register(req RegisterRequest) (*models.User, error) {
 		AuthProvider: "email",
 	}
 
-	if err := s.userRepo.Create(user); err != nil {
+	// 未分類カテゴリと同時に作成し、片方だけ残らないようにする
+	if err := s.userRepo.CreateWithDefaultCategory(user); err != nil {
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
@@ -125,7 +126,7 @@ func (s *AuthService) RefreshToken(refreshToken string) (*TokenPair, error) {
 func (s *AuthService) generateTokenPair(user *models.User) (*TokenPair, error) {
 	// アクセストークンの生成
 	accessClaims := &Claims{
-		UserID: fmt.Sprintf("%d", user.ID),
+		UserID: user.ID,
 		Email:  user.Email,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.jwtConfig.AccessExpiry)),
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/eikuma/stockle/backend/internal/models"
+	"github.com/eikuma/stockle/backend/internal/repositories"
+	"github.com/google/uuid"
+)
+
+// categoryPaths maps the folder paths of imported files to the categories
+// of a user. Folders are matched by their path against the existing
+// categories, so importing the same file again files the links in the same
+// places; the missing categories are created along the way.
+type categoryPaths struct {
+	categoryRepo repositories.CategoryRepository
+	userID       string
+	// byKey maps a folder path, see folderKey, to its category
+	byKey map[string]string
+	// byID maps a category to its path
+	byID         map[string][]string
+	displayOrder int
+}
+
+func newCategoryPaths(categoryRepo repositories.CategoryRepository, userID string) (*categoryPaths, error) {
+	categories, err := categoryRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &categoryPaths{
+		categoryRepo: categoryRepo,
+		userID:       userID,
+		byKey:        make(map[string]string),
+		byID:         make(map[string][]string, len(categories)),
+	}
+	byID := make(map[string]*models.Category, len(categories))
+	for _, category := range categories {
+		byID[category.ID] = category
+		if category.DisplayOrder >= p.displayOrder {
+			p.displayOrder = category.DisplayOrder + 1
+		}
+	}
+	for _, category := range categories {
+		var path []string
+		for current := category; current != nil && len(path) <= len(categories); {
+			path = append([]string{current.Name}, path...)
+			if current.ParentID == nil {
+				break
+			}
+			current = byID[*current.ParentID]
+		}
+		p.byID[category.ID] = path
+		if _, ok := p.byKey[folderKey(path)]; !ok {
+			p.byKey[folderKey(path)] = category.ID
+		}
+	}
+	return p, nil
+}
+
+// path returns the folder path of a category
+func (p *categoryPaths) path(categoryID string) []string {
+	return p.byID[categoryID]
+}
+
+// categoryFor returns the category of a folder path, creating the missing
+// categories along it, or nil when the path has no folder
+func (p *categoryPaths) categoryFor(folders []string) (*string, error) {
+	var path []string
+	var parentID *string
+	for _, folder := range folders {
+		name := truncate(strings.TrimSpace(folder), maxCategoryNameLength)
+		if name == "" {
+			continue
+		}
+		path = append(path, name)
+		key := folderKey(path)
+		if id, ok := p.byKey[key]; ok {
+			parentID = &id
+			continue
+		}
+
+		category := &models.Category{
+			ID:           uuid.New().String(),
+			UserID:       p.userID,
+			ParentID:     parentID,
+			Name:         name,
+			Color:        defaultCategoryColor,
+			DisplayOrder: p.displayOrder,
+		}
+		if err := p.categoryRepo.Create(category); err != nil {
+			return nil, err
+		}
+		p.displayOrder++
+		p.byKey[key] = category.ID
+		p.byID[category.ID] = append([]string(nil), path...)
+		parentID = &category.ID
+	}
+	return parentID, nil
+}
+
+// folderKey identifies a folder path; folder names are matched without
+// regard to case
+func folderKey(path []string) string {
+	return strings.ToLower(strings.Join(path, "\x00"))
+}
@@ -0,0 +1,311 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/eikuma/stockle/backend/internal/articlequery"
+	"github.com/eikuma/stockle/backend/internal/config"
+	"github.com/eikuma/stockle/backend/internal/exporter"
+	"github.com/eikuma/stockle/backend/internal/models"
+	"github.com/eikuma/stockle/backend/internal/repositories"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// exportBatchSize is the number of articles read from the database at a time
+const exportBatchSize = 100
+
+var (
+	// ErrInvalidDownloadLink is returned for download links that were not
+	// signed by the service or were changed
+	ErrInvalidDownloadLink = errors.New("invalid download link")
+	// ErrDownloadLinkExpired is returned for links past their expiry and for
+	// exports whose file was deleted
+	ErrDownloadLinkExpired = errors.New("download link expired")
+	// ErrExportNotReady is returned for exports that have no file yet
+	ErrExportNotReady = errors.New("export is not ready")
+)
+
+// ExportService writes a user's articles to a file in a background job and
+// hands out signed links to download it. Files are kept for the retention
+// period of the configuration and deleted afterwards.
+type ExportService struct {
+	exportRepo   repositories.ExportRepository
+	articleRepo  repositories.ArticleRepository
+	categoryRepo repositories.CategoryRepository
+	jobService   *JobService
+	dir          string
+	retention    time.Duration
+	linkExpiry   time.Duration
+	signingKey   []byte
+}
+
+func NewExportService(
+	exportRepo repositories.ExportRepository,
+	articleRepo repositories.ArticleRepository,
+	categoryRepo repositories.CategoryRepository,
+	jobService *JobService,
+	cfg *config.ExportsConfig,
+) *ExportService {
+	dir := cfg.Dir
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "stockle-exports")
+	}
+	return &ExportService{
+		exportRepo:   exportRepo,
+		articleRepo:  articleRepo,
+		categoryRepo: categoryRepo,
+		jobService:   jobService,
+		dir:          dir,
+		retention:    cfg.Retention,
+		linkExpiry:   cfg.LinkExpiry,
+		signingKey:   []byte(cfg.SigningKey),
+	}
+}
+
+// Start schedules an export of the articles with the given IDs, or of the
+// articles matching query, or of every article when both are empty. The
+// query must have been validated by the caller.
+func (s *ExportService) Start(userID string, format exporter.Format, articleIDs []string, query string) (*models.Export, error) {
+	export := &models.Export{
+		ID:         uuid.New().String(),
+		UserID:     userID,
+		Format:     string(format),
+		ArticleIDs: models.StringList(articleIDs),
+		Status:     models.ExportStatusPending,
+	}
+	if query != "" {
+		export.Query = &query
+	}
+
+	if err := s.exportRepo.Create(export); err != nil {
+		return nil, err
+	}
+	if err := s.jobService.EnqueueExportJob(export.ID); err != nil {
+		export.Status = models.ExportStatusFailed
+		export.ErrorMessage = stringPtr("Failed to schedule the export: " + err.Error())
+		s.exportRepo.Update(export)
+		return nil, err
+	}
+	return export, nil
+}
+
+// ProcessJob writes the file of an export, implementing JobHandler. The
+// file is written under a temporary name and renamed once complete, so a
+// download never sees a partial file.
+func (s *ExportService) ProcessJob(ctx context.Context, job *models.JobQueue, payload *JobPayload) error {
+	s.PurgeExpired(time.Now())
+
+	export, err := s.exportRepo.GetByID(payload.ExportID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if export.Status == models.ExportStatusCompleted || export.Status == models.ExportStatusExpired {
+		return nil
+	}
+
+	format, err := exporter.ParseFormat(export.Format)
+	if err != nil {
+		return err
+	}
+	selection := repositories.ArticleSelection{IDs: export.ArticleIDs}
+	if export.Query != nil && len(export.ArticleIDs) == 0 {
+		selection.Query, err = articlequery.Parse(*export.Query)
+		if err != nil {
+			return err
+		}
+	}
+
+	export.Status = models.ExportStatusProcessing
+	export.ErrorMessage = nil
+	if err := s.exportRepo.Update(export); err != nil {
+		return err
+	}
+
+	categories, err := s.categoryRepo.GetByUserID(export.UserID)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(s.dir, export.ID+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		file.Close()
+		os.Remove(file.Name())
+	}()
+
+	buffered := bufio.NewWriter(file)
+	writer, err := exporter.NewWriter(format, buffered, exporter.Options{
+		ExportedAt: time.Now(),
+		Categories: categories,
+	})
+	if err != nil {
+		return err
+	}
+	count := 0
+	err = s.articleRepo.Each(export.UserID, selection, exportBatchSize, func(articles []*models.Article) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		for _, article := range articles {
+			if err := writer.Write(article); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	if err := buffered.Flush(); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	path := filepath.Join(s.dir, export.ID+"."+format.Extension())
+	if err := os.Rename(file.Name(), path); err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	export.Status = models.ExportStatusCompleted
+	export.ArticleCount = count
+	export.FileSize = info.Size()
+	export.FilePath = path
+	export.CompletedAt = &now
+	export.ExpiresAt = timePtr(now.Add(s.retention))
+	return s.exportRepo.Update(export)
+}
+
+// JobFailed marks the export as failed once its job gave up
+func (s *ExportService) JobFailed(job *models.JobQueue, payload *JobPayload, jobErr error) {
+	export, err := s.exportRepo.GetByID(payload.ExportID)
+	if err != nil {
+		return
+	}
+	export.Status = models.ExportStatusFailed
+	export.ErrorMessage = stringPtr(jobErr.Error())
+	export.CompletedAt = timePtr(time.Now())
+	if err := s.exportRepo.Update(export); err != nil {
+		log.Printf("Failed to mark export %s as failed: %v", export.ID, err)
+	}
+}
+
+// PurgeExpired deletes the files of the exports that expired before now
+func (s *ExportService) PurgeExpired(now time.Time) {
+	exports, err := s.exportRepo.GetExpired(now)
+	if err != nil {
+		log.Printf("Failed to list expired exports: %v", err)
+		return
+	}
+	for _, export := range exports {
+		if export.FilePath != "" {
+			if err := os.Remove(export.FilePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+				log.Printf("Failed to delete export file %s: %v", export.FilePath, err)
+				continue
+			}
+		}
+		export.Status = models.ExportStatusExpired
+		export.FilePath = ""
+		if err := s.exportRepo.Update(export); err != nil {
+			log.Printf("Failed to mark export %s as expired: %v", export.ID, err)
+		}
+	}
+}
+
+// RunCleanup purges expired exports every interval until ctx is done
+func (s *ExportService) RunCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.PurgeExpired(now)
+		}
+	}
+}
+
+// DownloadURL returns a signed link to the file of a completed export. The
+// link expires after the configured link expiry, or with the file when
+// that comes first.
+func (s *ExportService) DownloadURL(export *models.Export) string {
+	expires := time.Now().Add(s.linkExpiry)
+	if export.ExpiresAt != nil && export.ExpiresAt.Before(expires) {
+		expires = *export.ExpiresAt
+	}
+	unix := strconv.FormatInt(expires.Unix(), 10)
+
+	query := url.Values{}
+	query.Set("expires", unix)
+	query.Set("signature", s.sign(export.ID, unix))
+	return "/api/v1/exports/" + export.ID + "/download?" + query.Encode()
+}
+
+// Download checks a signed link and returns the export it points to
+func (s *ExportService) Download(id, expires, signature string) (*models.Export, error) {
+	expected, err := hex.DecodeString(s.sign(id, expires))
+	if err != nil {
+		return nil, err
+	}
+	given, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(expected, given) {
+		return nil, ErrInvalidDownloadLink
+	}
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidDownloadLink
+	}
+	if time.Now().Unix() > unix {
+		return nil, ErrDownloadLinkExpired
+	}
+
+	export, err := s.exportRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case export.Status == models.ExportStatusExpired,
+		export.Status == models.ExportStatusCompleted && export.ExpiresAt != nil && time.Now().After(*export.ExpiresAt):
+		return nil, ErrDownloadLinkExpired
+	case export.Status != models.ExportStatusCompleted:
+		return nil, ErrExportNotReady
+	}
+	return export, nil
+}
+
+// sign returns the hex HMAC-SHA256 of an export ID and a link expiry
+func (s *ExportService) sign(id, expires string) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(id + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
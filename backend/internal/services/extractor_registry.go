@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/eikuma/stockle/backend/internal/urlnorm"
+)
+
+// GenericExtractorName is reported when no site extractor matches a page
+const GenericExtractorName = "generic"
+
+// FetchFunc downloads and parses another page of the same article, e.g. the
+// next page of a paginated post
+type FetchFunc func(ctx context.Context, url string) (*goquery.Document, error)
+
+// SiteExtractor extracts articles from the sites it declares. Extractors are
+// either declarative rules loaded from YAML or Go code for sites that need logic.
+type SiteExtractor interface {
+	// Name identifies the extractor in logs and in ArticleMetadata.Extractor
+	Name() string
+	// Hosts are the host patterns the extractor handles. A pattern matches the
+	// host itself and its subdomains; a leading "*." matches subdomains only.
+	Hosts() []string
+	// Extract reads the article from doc, whose URL is the final page URL.
+	// fetch is nil when further pages must not be downloaded.
+	Extract(ctx context.Context, doc *goquery.Document, fetch FetchFunc) (*ArticleMetadata, error)
+}
+
+// ExtractorRegistry selects the site extractor for a URL. It is safe for
+// concurrent use.
+type ExtractorRegistry struct {
+	mu      sync.RWMutex
+	entries []registryEntry
+}
+
+type registryEntry struct {
+	extractor SiteExtractor
+	// source is where the extractor was defined, e.g. the rules file
+	source string
+}
+
+// NewExtractorRegistry creates a registry holding the built-in extractors
+func NewExtractorRegistry() *ExtractorRegistry {
+	r := &ExtractorRegistry{}
+	rules, err := parseSiteRules(builtinSiteRules, "built-in rules")
+	if err != nil {
+		// The embedded rules are covered by tests
+		panic(err)
+	}
+	for _, rule := range rules {
+		r.Register(rule, "built-in")
+	}
+	r.Register(newZennExtractor(), "built-in")
+	return r
+}
+
+// LoadExtractorRegistry creates a registry with the built-in extractors and
+// the site rules at rulesPath, if any
+func LoadExtractorRegistry(rulesPath string) (*ExtractorRegistry, error) {
+	registry := NewExtractorRegistry()
+	if rulesPath == "" {
+		return registry, nil
+	}
+	if err := registry.LoadRules(rulesPath); err != nil {
+		return nil, err
+	}
+	return registry, nil
+}
+
+// Register adds an extractor. When several extractors match a host the most
+// specific pattern wins, and among equally specific ones the latest registered,
+// so rules loaded from files override the built-in ones.
+func (r *ExtractorRegistry) Register(extractor SiteExtractor, source string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, registryEntry{extractor: extractor, source: source})
+}
+
+// LoadRules registers the site rules in a YAML file, or in every .yaml and
+// .yml file of a directory. Nothing is registered when any rule is invalid.
+func (r *ExtractorRegistry) LoadRules(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to load site rules: %w", err)
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		files = nil
+		for _, pattern := range []string{"*.yaml", "*.yml"} {
+			matches, err := filepath.Glob(filepath.Join(path, pattern))
+			if err != nil {
+				return fmt.Errorf("failed to load site rules: %w", err)
+			}
+			files = append(files, matches...)
+		}
+		sort.Strings(files)
+	}
+
+	type loaded struct {
+		rules []*siteRule
+		file  string
+	}
+	var all []loaded
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to load site rules: %w", err)
+		}
+		rules, err := parseSiteRules(data, file)
+		if err != nil {
+			return err
+		}
+		all = append(all, loaded{rules: rules, file: file})
+	}
+
+	for _, l := range all {
+		for _, rule := range l.rules {
+			r.Register(rule, l.file)
+		}
+	}
+	return nil
+}
+
+// Lookup returns the extractor for u and where it was defined, or nil when
+// the generic extraction applies
+func (r *ExtractorRegistry) Lookup(u *url.URL) (SiteExtractor, string) {
+	if r == nil || u == nil {
+		return nil, ""
+	}
+	host := strings.ToLower(u.Hostname())
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var best *registryEntry
+	bestLength := 0
+	for i := range r.entries {
+		entry := &r.entries[i]
+		for _, pattern := range entry.extractor.Hosts() {
+			if length := urlnorm.MatchHost(pattern, host); length > 0 && length >= bestLength {
+				best, bestLength = entry, length
+			}
+		}
+	}
+	if best == nil {
+		return nil, ""
+	}
+	return best.extractor, best.source
+}
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/eikuma/stockle/backend/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeRules(t *testing.T, dir, name, rules string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(rules), 0o644))
+	return path
+}
+
+func lookupName(t *testing.T, registry *ExtractorRegistry, rawURL string) string {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	require.NoError(t, err)
+	site, _ := registry.Lookup(u)
+	if site == nil {
+		return GenericExtractorName
+	}
+	return site.Name()
+}
+
+func TestExtractorRegistry_Lookup(t *testing.T) {
+	registry := NewExtractorRegistry()
+	dir := t.TempDir()
+	writeRules(t, dir, "a.yaml", `
+sites:
+  - name: blog-wildcard
+    hosts: ["*.blog.example.com"]
+    content: article
+  - name: blog-docs
+    hosts: [docs.blog.example.com]
+    content: main
+  - name: custom-qiita
+    hosts: [qiita.com]
+    content: .it-MdContent
+`)
+	writeRules(t, dir, "ignored.txt", "not yaml")
+	require.NoError(t, registry.LoadRules(dir))
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url: "https://zenn.dev/gopher/articles/context", want: "zenn"},
+		{url: "https://yurufuwa.hatenablog.com/entry/1", want: "hatenablog"},
+		{url: "https://note.com/user/n/n123", want: "note"},
+		{url: "https://www.note.com/user/n/n123", want: "note"},
+		{url: "https://notnote.com/a", want: GenericExtractorName},
+		{url: "https://a.blog.example.com/post", want: "blog-wildcard"},
+		{url: "https://blog.example.com/post", want: GenericExtractorName},
+		// An exact host is more specific than a wildcard for the same domain
+		{url: "https://docs.blog.example.com/post", want: "blog-docs"},
+		// Rules from files override built-in rules for the same hosts
+		{url: "https://QIITA.com/items/1", want: "custom-qiita"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, lookupName(t, registry, tt.url), tt.url)
+	}
+
+	u, _ := url.Parse("https://qiita.com/items/1")
+	_, source := registry.Lookup(u)
+	assert.Equal(t, filepath.Join(dir, "a.yaml"), source)
+}
+
+func TestExtractorRegistry_LoadRulesRejectsInvalidRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   string
+		message string
+	}{
+		{name: "unknown field", rules: "sites:\n  - name: a\n    hosts: [a.com]\n    content: main\n    contnet: x\n", message: "contnet"},
+		{name: "missing name", rules: "sites:\n  - hosts: [a.com]\n    content: main\n", message: "#1"},
+		{name: "missing hosts", rules: "sites:\n  - name: a\n    content: main\n", message: "host"},
+		{name: "host with a scheme", rules: "sites:\n  - name: a\n    hosts: [\"https://a.com\"]\n    content: main\n", message: "invalid host"},
+		{name: "missing content", rules: "sites:\n  - name: a\n    hosts: [a.com]\n", message: "content selector is required"},
+		{name: "invalid selector", rules: "sites:\n  - name: a\n    hosts: [a.com]\n    content: main\n    author: \"a[\"\n", message: "invalid author selector"},
+		{name: "too many pages", rules: "sites:\n  - name: a\n    hosts: [a.com]\n    content: main\n    max_pages: 100\n", message: "max_pages"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeRules(t, dir, "1-valid.yaml", "sites:\n  - name: valid\n    hosts: [valid.example.com]\n    content: main\n")
+			writeRules(t, dir, "2-invalid.yaml", tt.rules)
+
+			registry := NewExtractorRegistry()
+			err := registry.LoadRules(dir)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "2-invalid.yaml")
+			assert.Contains(t, err.Error(), tt.message)
+			// A partly invalid directory registers nothing
+			assert.Equal(t, GenericExtractorName, lookupName(t, registry, "https://valid.example.com/"))
+		})
+	}
+
+	_, err := LoadExtractorRegistry(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
+
+// newPaginatedServer serves a three page article whose last page links back to the first
+func newPaginatedServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		var page, next string
+		switch r.URL.Path {
+		case "/story":
+			page, next = "1", "/story/page/2"
+		case "/story/page/2":
+			page, next = "2", "../page/3"
+		case "/story/page/3":
+			page, next = "3", "/story"
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `<html><head><title>Paged story | Example</title></head><body>
+<h1 class="headline">Paged story</h1>
+<div class="story"><p>Text of page %[1]s of the story.</p><img src="img/%[1]s.png" alt="figure %[1]s"><div class="share">Share page %[1]s</div></div>
+<a class="next" href="%[2]s">Next</a>
+<p class="date">2024/03/0%[1]s</p>
+</body></html>`, page, next)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSiteRule_FollowsPages(t *testing.T) {
+	server := newPaginatedServer(t)
+	registry := NewExtractorRegistry()
+	path := writeRules(t, t.TempDir(), "story.yaml", `
+sites:
+  - name: story
+    hosts: [127.0.0.1]
+    title: .headline
+    content: .story
+    published: p.date
+    date_format: "2006/01/02"
+    strip: [.share]
+    next_page: a.next
+`)
+	require.NoError(t, registry.LoadRules(path))
+	scraper := NewScraperService(&config.ScraperConfig{}, registry)
+
+	metadata, err := scraper.ExtractMetadata(context.Background(), server.URL+"/story")
+	require.NoError(t, err)
+
+	assert.Equal(t, "story", metadata.Extractor)
+	assert.Equal(t, "Paged story", metadata.Title)
+	require.NotNil(t, metadata.PublishedAt)
+	assert.Equal(t, "2024-03-01", metadata.PublishedAt.Format("2006-01-02"))
+	assert.Equal(t, 3, metadata.Pages)
+	assert.Empty(t, metadata.Warnings)
+	assert.Equal(t, "Text of page 1 of the story.\n\nText of page 2 of the story.\n\nText of page 3 of the story.", metadata.Content)
+	assert.NotContains(t, metadata.ContentHTML, "Share")
+	// Images resolve against the page they appear on
+	assert.Contains(t, metadata.ContentHTML, `src="`+server.URL+`/img/1.png"`)
+	assert.Contains(t, metadata.ContentHTML, `src="`+server.URL+`/story/page/img/2.png"`)
+
+	// max_pages bounds the pages read
+	limited := &siteRule{RuleName: "limited", HostPatterns: []string{"127.0.0.1"}, Content: ".story", NextPage: "a.next", MaxPages: 2}
+	registry.Register(limited, "test")
+	metadata, err = scraper.ExtractMetadata(context.Background(), server.URL+"/story")
+	require.NoError(t, err)
+	assert.Equal(t, "limited", metadata.Extractor)
+	assert.Equal(t, 2, metadata.Pages)
+}
+
+func TestSiteRule_FallsBackToGenericExtraction(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<html><head><title>Moved</title></head><body>
+<div class="new-layout"><p>The site changed its layout, but the article is still readable here.</p></div></body></html>`))
+	require.NoError(t, err)
+	doc.Url, _ = url.Parse("https://qiita.com/items/1")
+
+	scraper := NewScraperService(&config.ScraperConfig{}, NewExtractorRegistry())
+	metadata, err := scraper.ExtractDocument(context.Background(), doc, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "qiita", metadata.Extractor)
+	assert.Equal(t, "ja", metadata.Language)
+	assert.Equal(t, "The site changed its layout, but the article is still readable here.", metadata.Content)
+	require.Len(t, metadata.Warnings, 1)
+	assert.Contains(t, metadata.Warnings[0], "matched nothing")
+}
+
+func TestZennExtractor(t *testing.T) {
+	page := `<html><head><title>Zenn article</title></head><body>
+<h1>Heading on the page</h1>
+<div class="znc"><p>Body from the page markup, used when there is no page data.</p></div>
+%s
+</body></html>`
+	nextData := `<script id="__NEXT_DATA__" type="application/json">{"props":{"pageProps":{"article":{
+"title":"Go の context 入門","bodyHtml":"<h2 id=\"intro\">はじめに</h2><p>context はキャンセルを伝える仕組みです。</p>",
+"publishedAt":"2024-02-03T09:00:00.000+09:00","user":{"name":"gopher"}}}}}</script>`
+
+	scraper := NewScraperService(&config.ScraperConfig{}, NewExtractorRegistry())
+	extract := func(html string) *ArticleMetadata {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+		require.NoError(t, err)
+		doc.Url, _ = url.Parse("https://zenn.dev/gopher/articles/context")
+		metadata, err := scraper.ExtractDocument(context.Background(), doc, nil)
+		require.NoError(t, err)
+		return metadata
+	}
+
+	metadata := extract(fmt.Sprintf(page, nextData))
+	assert.Equal(t, "zenn", metadata.Extractor)
+	assert.Equal(t, "Go の context 入門", metadata.Title)
+	assert.Equal(t, "gopher", metadata.Author)
+	require.NotNil(t, metadata.PublishedAt)
+	assert.True(t, metadata.PublishedAt.Equal(time.Date(2024, 2, 3, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, "<h2>はじめに</h2>\n<p>context はキャンセルを伝える仕組みです。</p>", metadata.ContentHTML)
+
+	metadata = extract(fmt.Sprintf(page, ""))
+	assert.Equal(t, "zenn", metadata.Extractor)
+	assert.Equal(t, "Heading on the page", metadata.Title)
+	assert.Equal(t, "Body from the page markup, used when there is no page data.", metadata.Content)
+}
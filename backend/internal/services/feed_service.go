@@ -0,0 +1,547 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/eikuma/stockle/backend/internal/config"
+	"github.com/eikuma/stockle/backend/internal/feeds"
+	"github.com/eikuma/stockle/backend/internal/models"
+	"github.com/eikuma/stockle/backend/internal/repositories"
+	"github.com/eikuma/stockle/backend/internal/urlnorm"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// initialFeedEntries is the number of entries saved when a feed is
+	// fetched for the first time; the older ones are only marked as seen
+	initialFeedEntries = 10
+	// dueFeedsBatchSize is the number of due feeds scheduled at a time
+	dueFeedsBatchSize = 100
+	// maxAuthorLength is the column size, in characters, of article authors
+	maxAuthorLength = 255
+)
+
+var (
+	// ErrFeedNotFound is returned when a URL is neither a feed nor a page
+	// that announces one
+	ErrFeedNotFound = errors.New("no feed found at the URL")
+	// ErrAlreadySubscribed is returned when the user already follows the feed
+	ErrAlreadySubscribed = errors.New("already subscribed to the feed")
+	// ErrFeedUnreachable wraps the error of a URL that could not be fetched
+	ErrFeedUnreachable = errors.New("failed to fetch the URL")
+)
+
+// FeedOptions are the settings of a feed that the user chooses
+type FeedOptions struct {
+	// CategoryID is where new articles go; the default category when nil
+	CategoryID      *string
+	Tags            []string
+	IncludeKeywords []string
+	ExcludeKeywords []string
+}
+
+// FeedService follows RSS, Atom and JSON Feed subscriptions. A scheduler
+// queues a poll job for every feed that is due; the job fetches the feed
+// conditionally and saves its new entries as unread articles. Feeds that
+// keep failing are fetched less and less often.
+type FeedService struct {
+	feedRepo     repositories.FeedRepository
+	articleRepo  repositories.ArticleRepository
+	categoryRepo repositories.CategoryRepository
+	tagRepo      repositories.TagRepository
+	jobService   *JobService
+	scraper      *ScraperService
+	urls         *urlnorm.Normalizer
+	search       *SearchService
+	pollInterval time.Duration
+	maxBackoff   time.Duration
+}
+
+func NewFeedService(
+	feedRepo repositories.FeedRepository,
+	articleRepo repositories.ArticleRepository,
+	categoryRepo repositories.CategoryRepository,
+	tagRepo repositories.TagRepository,
+	jobService *JobService,
+	scraper *ScraperService,
+	urls *urlnorm.Normalizer,
+	search *SearchService,
+	cfg *config.FeedsConfig,
+) *FeedService {
+	pollInterval := cfg.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = 30 * time.Minute
+	}
+	maxBackoff := cfg.MaxBackoff
+	if maxBackoff < pollInterval {
+		maxBackoff = pollInterval
+	}
+	return &FeedService{
+		feedRepo:     feedRepo,
+		articleRepo:  articleRepo,
+		categoryRepo: categoryRepo,
+		tagRepo:      tagRepo,
+		jobService:   jobService,
+		scraper:      scraper,
+		urls:         urls,
+		search:       search,
+		pollInterval: pollInterval,
+		maxBackoff:   maxBackoff,
+	}
+}
+
+// Subscribe follows the feed at rawURL, or the first feed announced by the
+// page at rawURL, and schedules its first poll
+func (s *FeedService) Subscribe(ctx context.Context, userID, rawURL string, options FeedOptions) (*models.Feed, error) {
+	doc, parsed, err := s.resolve(ctx, strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil, err
+	}
+
+	feedURL := doc.URL.String()
+	if _, err := s.feedRepo.GetByURL(userID, feedURL); err == nil {
+		return nil, ErrAlreadySubscribed
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	feed := &models.Feed{
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		URL:         feedURL,
+		Title:       feedTitle(parsed.Title, feedURL),
+		IsActive:    true,
+		NextFetchAt: time.Now(),
+	}
+	if parsed.SiteURL != "" {
+		feed.SiteURL = &parsed.SiteURL
+	}
+	s.ApplyOptions(feed, options)
+
+	if err := s.feedRepo.Create(feed); err != nil {
+		return nil, err
+	}
+	s.schedule(feed)
+	return feed, nil
+}
+
+// resolve fetches rawURL and returns it when it is a feed, or else the
+// first feed that the page announces
+func (s *FeedService) resolve(ctx context.Context, rawURL string) (*FetchedDocument, *feeds.Feed, error) {
+	doc, err := s.scraper.FetchDocument(ctx, rawURL, "", "")
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrFeedUnreachable, err)
+	}
+	parsed, err := feeds.Parse(doc.Body, doc.URL)
+	if err == nil {
+		return doc, parsed, nil
+	}
+
+	for _, link := range feeds.Discover(doc.Body, doc.URL) {
+		linked, err := s.scraper.FetchDocument(ctx, link, "", "")
+		if err != nil {
+			continue
+		}
+		if parsed, err := feeds.Parse(linked.Body, linked.URL); err == nil {
+			return linked, parsed, nil
+		}
+	}
+	return nil, nil, ErrFeedNotFound
+}
+
+// ApplyOptions sets the category, tags and filters of a feed, dropping
+// blank and repeated values
+func (s *FeedService) ApplyOptions(feed *models.Feed, options FeedOptions) {
+	feed.CategoryID = options.CategoryID
+
+	feed.Tags = models.StringList{}
+	seen := make(map[string]bool)
+	for _, tag := range options.Tags {
+		name := repositories.NormalizeTagName(tag)
+		if key := strings.ToLower(name); name != "" && !seen[key] {
+			seen[key] = true
+			feed.Tags = append(feed.Tags, name)
+		}
+	}
+	feed.IncludeKeywords = keywordList(options.IncludeKeywords)
+	feed.ExcludeKeywords = keywordList(options.ExcludeKeywords)
+}
+
+// Refresh schedules a poll of a feed now, without waiting for its turn
+func (s *FeedService) Refresh(feed *models.Feed) error {
+	return s.jobService.EnqueueFeedJob(feed.ID)
+}
+
+// ProcessJob fetches a feed and saves its new entries as articles,
+// implementing JobHandler
+func (s *FeedService) ProcessJob(ctx context.Context, job *models.JobQueue, payload *JobPayload) error {
+	feed, err := s.feedRepo.GetByID(payload.FeedID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if !feed.IsActive {
+		return nil
+	}
+
+	var etag, lastModified string
+	if feed.ETag != nil {
+		etag = *feed.ETag
+	}
+	if feed.LastModified != nil {
+		lastModified = *feed.LastModified
+	}
+	doc, err := s.scraper.FetchDocument(ctx, feed.URL, etag, lastModified)
+	var parsed *feeds.Feed
+	if err == nil && !doc.NotModified {
+		parsed, err = feeds.Parse(doc.Body, doc.URL)
+		if errors.Is(err, feeds.ErrNotFeed) {
+			err = fmt.Errorf("%s is no longer a feed", feed.URL)
+		}
+	}
+	if err != nil {
+		// The job is retried; JobFailed backs off once it gives up
+		feed.LastError = stringPtr(err.Error())
+		if updateErr := s.feedRepo.UpdatePoll(feed); updateErr != nil {
+			log.Printf("Failed to record the error of feed %s: %v", feed.ID, updateErr)
+		}
+		return err
+	}
+
+	now := time.Now()
+	if parsed != nil {
+		if err := s.saveEntries(feed, parsed.Entries); err != nil {
+			return err
+		}
+		feed.ETag = nonEmpty(doc.ETag)
+		feed.LastModified = nonEmpty(doc.LastModified)
+		if parsed.Title != "" {
+			feed.Title = feedTitle(parsed.Title, feed.URL)
+		}
+		if parsed.SiteURL != "" {
+			feed.SiteURL = &parsed.SiteURL
+		}
+	}
+	feed.LastFetchedAt = &now
+	feed.NextFetchAt = now.Add(s.pollInterval)
+	feed.ErrorCount = 0
+	feed.LastError = nil
+	return s.feedRepo.UpdatePoll(feed)
+}
+
+// JobFailed records that a feed could not be fetched after the last retry
+// and doubles the time to its next fetch, up to the maximum backoff
+func (s *FeedService) JobFailed(job *models.JobQueue, payload *JobPayload, jobErr error) {
+	feed, err := s.feedRepo.GetByID(payload.FeedID)
+	if err != nil {
+		return
+	}
+	feed.ErrorCount++
+	feed.LastError = stringPtr(jobErr.Error())
+	feed.NextFetchAt = time.Now().Add(s.backoff(feed.ErrorCount))
+	if err := s.feedRepo.UpdatePoll(feed); err != nil {
+		log.Printf("Failed to mark feed %s as failed: %v", feed.ID, err)
+	}
+}
+
+// backoff is the time to the next fetch of a feed that failed errorCount
+// times in a row
+func (s *FeedService) backoff(errorCount int) time.Duration {
+	wait := s.pollInterval
+	for i := 0; i < errorCount && wait < s.maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > s.maxBackoff {
+		wait = s.maxBackoff
+	}
+	return wait
+}
+
+// saveEntries saves the entries of a feed that were not seen before and
+// records them as seen
+func (s *FeedService) saveEntries(feed *models.Feed, entries []feeds.Entry) error {
+	keys := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		keys = append(keys, entryKey(entry))
+	}
+	seen, err := s.feedRepo.SeenEntries(feed.ID, keys)
+	if err != nil {
+		return err
+	}
+
+	categoryID := feed.CategoryID
+	if categoryID == nil {
+		if defaultCategory, err := s.categoryRepo.GetDefault(feed.UserID); err == nil {
+			categoryID = &defaultCategory.ID
+		}
+	}
+	var tagIDs []string
+	if len(feed.Tags) > 0 {
+		tags, err := s.tagRepo.GetOrCreateMultiple(feed.UserID, feed.Tags)
+		if err != nil {
+			return err
+		}
+		for _, tag := range tags {
+			tagIDs = append(tagIDs, tag.ID)
+		}
+	}
+
+	saved := 0
+	for i, entry := range entries {
+		if seen[keys[i]] {
+			continue
+		}
+		seen[keys[i]] = true
+
+		record := &models.FeedEntry{FeedID: feed.ID, EntryKey: keys[i]}
+		// A new subscription starts with the latest entries only
+		backlog := feed.LastFetchedAt == nil && saved >= initialFeedEntries
+		if !backlog && matchesFilters(feed, entry) {
+			articleID, err := s.saveArticle(feed, entry, categoryID, tagIDs)
+			if err != nil {
+				log.Printf("Failed to save entry %s of feed %s: %v", entry.URL, feed.ID, err)
+				continue
+			}
+			record.ArticleID = articleID
+			saved++
+		}
+		if err := s.feedRepo.AddEntries([]*models.FeedEntry{record}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveArticle saves an entry as an unread article and schedules the
+// extraction of its page. The article the user already has with the same
+// URL is returned instead of a new one.
+func (s *FeedService) saveArticle(feed *models.Feed, entry feeds.Entry, categoryID *string, tagIDs []string) (*string, error) {
+	urlHash, err := s.urls.Key(entry.URL)
+	if err != nil {
+		return nil, err
+	}
+	if existing, err := s.articleRepo.GetByURLHash(feed.UserID, urlHash); err == nil {
+		return &existing.ID, nil
+	}
+
+	title := strings.TrimSpace(entry.Title)
+	if title == "" {
+		title = entry.URL
+	}
+	article := &models.Article{
+		ID:                      uuid.New().String(),
+		UserID:                  feed.UserID,
+		CategoryID:              categoryID,
+		URL:                     entry.URL,
+		URLHash:                 &urlHash,
+		Title:                   truncate(title, maxTitleLength),
+		Author:                  nonEmpty(truncate(entry.Author, maxAuthorLength)),
+		PublishedAt:             entry.Published,
+		Status:                  models.ArticleStatusUnread,
+		ExtractionStatus:        models.ExtractionStatusPending,
+		SummaryGenerationStatus: models.SummaryStatusPending,
+	}
+	if err := s.articleRepo.CreateWithTags(article, tagIDs); err != nil {
+		return nil, err
+	}
+	s.search.Refresh(article.ID)
+
+	// Feed articles wait behind the ones the user saves by hand
+	if err := s.jobService.EnqueueExtractionJob(article.ID, models.JobPriorityLow); err != nil {
+		message := "Failed to schedule content extraction: " + err.Error()
+		article.ExtractionStatus = models.ExtractionStatusFailed
+		article.ExtractionError = &message
+		article.SummaryGenerationStatus = models.SummaryStatusFailed
+		if err := s.articleRepo.UpdateExtraction(article); err == nil {
+			s.articleRepo.UpdateSummaryStatus(article)
+		}
+	}
+	return &article.ID, nil
+}
+
+// EnqueueDue schedules a poll of every active feed that is due at now.
+// Each feed is moved to its next turn first so that it is not scheduled
+// again while its job waits in the queue.
+func (s *FeedService) EnqueueDue(now time.Time) (int, error) {
+	scheduled := 0
+	for {
+		due, err := s.feedRepo.GetDue(now, dueFeedsBatchSize)
+		if err != nil {
+			return scheduled, err
+		}
+		for _, feed := range due {
+			feed.NextFetchAt = now.Add(s.pollInterval)
+			if err := s.feedRepo.UpdatePoll(feed); err != nil {
+				return scheduled, err
+			}
+			if err := s.jobService.EnqueueFeedJob(feed.ID); err != nil {
+				return scheduled, err
+			}
+			scheduled++
+		}
+		if len(due) < dueFeedsBatchSize {
+			return scheduled, nil
+		}
+	}
+}
+
+// RunScheduler schedules the due feeds every interval until ctx is done
+func (s *FeedService) RunScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := s.EnqueueDue(now); err != nil {
+				log.Printf("Failed to schedule feeds: %v", err)
+			}
+		}
+	}
+}
+
+// ImportOPML subscribes to the feeds of an OPML list that the user does
+// not follow yet, filing each in the category of its folder. The feeds are
+// not fetched here; their first poll fills in the title and site.
+func (s *FeedService) ImportOPML(userID string, subscriptions []feeds.Subscription) ([]*models.Feed, error) {
+	categories, err := newCategoryPaths(s.categoryRepo, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	imported := []*models.Feed{}
+	for _, subscription := range subscriptions {
+		if _, err := s.feedRepo.GetByURL(userID, subscription.FeedURL); err == nil {
+			continue
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return imported, err
+		}
+
+		categoryID, err := categories.categoryFor(subscription.Folders)
+		if err != nil {
+			return imported, err
+		}
+		feed := &models.Feed{
+			ID:              uuid.New().String(),
+			UserID:          userID,
+			URL:             subscription.FeedURL,
+			Title:           feedTitle(subscription.Title, subscription.FeedURL),
+			CategoryID:      categoryID,
+			Tags:            models.StringList{},
+			IncludeKeywords: models.StringList{},
+			ExcludeKeywords: models.StringList{},
+			IsActive:        true,
+			NextFetchAt:     time.Now(),
+		}
+		if subscription.SiteURL != "" {
+			feed.SiteURL = stringPtr(subscription.SiteURL)
+		}
+		if err := s.feedRepo.Create(feed); err != nil {
+			return imported, err
+		}
+		s.schedule(feed)
+		imported = append(imported, feed)
+	}
+	return imported, nil
+}
+
+// ExportOPML writes the user's feeds as an OPML list, with the category
+// path of each feed as its folders
+func (s *FeedService) ExportOPML(w io.Writer, userID string) error {
+	list, err := s.feedRepo.GetByUserID(userID)
+	if err != nil {
+		return err
+	}
+	categories, err := newCategoryPaths(s.categoryRepo, userID)
+	if err != nil {
+		return err
+	}
+
+	subscriptions := make([]feeds.Subscription, 0, len(list))
+	for _, feed := range list {
+		subscription := feeds.Subscription{
+			Title:   feed.Title,
+			FeedURL: feed.URL,
+		}
+		if feed.SiteURL != nil {
+			subscription.SiteURL = *feed.SiteURL
+		}
+		if feed.CategoryID != nil {
+			subscription.Folders = categories.path(*feed.CategoryID)
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	return feeds.WriteOPML(w, "Stockle feeds", time.Now(), subscriptions)
+}
+
+// schedule queues the first poll of a new feed. When that fails the
+// scheduler picks the feed up, as it is already due.
+func (s *FeedService) schedule(feed *models.Feed) {
+	if err := s.jobService.EnqueueFeedJob(feed.ID); err != nil {
+		log.Printf("Failed to schedule feed %s: %v", feed.ID, err)
+	}
+}
+
+// matchesFilters reports whether an entry passes the keyword filters of a
+// feed. Keywords are matched without regard to case against the title,
+// summary and categories of the entry.
+func matchesFilters(feed *models.Feed, entry feeds.Entry) bool {
+	if len(feed.IncludeKeywords) == 0 && len(feed.ExcludeKeywords) == 0 {
+		return true
+	}
+	text := strings.ToLower(strings.Join(append([]string{entry.Title, entry.Summary}, entry.Categories...), "\n"))
+	for _, keyword := range feed.ExcludeKeywords {
+		if strings.Contains(text, strings.ToLower(keyword)) {
+			return false
+		}
+	}
+	if len(feed.IncludeKeywords) == 0 {
+		return true
+	}
+	for _, keyword := range feed.IncludeKeywords {
+		if strings.Contains(text, strings.ToLower(keyword)) {
+			return true
+		}
+	}
+	return false
+}
+
+// entryKey identifies an entry within its feed
+func entryKey(entry feeds.Entry) string {
+	sum := sha256.Sum256([]byte(entry.ID))
+	return hex.EncodeToString(sum[:])
+}
+
+// keywordList trims keywords and drops blank and repeated ones
+func keywordList(keywords []string) models.StringList {
+	list := models.StringList{}
+	seen := make(map[string]bool)
+	for _, keyword := range keywords {
+		keyword = strings.TrimSpace(keyword)
+		if key := strings.ToLower(keyword); keyword != "" && !seen[key] {
+			seen[key] = true
+			list = append(list, keyword)
+		}
+	}
+	return list
+}
+
+// feedTitle cuts a feed title to the column size, falling back to the URL
+func feedTitle(title, feedURL string) string {
+	if title = strings.TrimSpace(title); title == "" {
+		title = feedURL
+	}
+	return truncate(title, maxTitleLength)
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// hostLimiter bounds concurrent fetches globally and per host, and spaces
+// requests to the same host by a minimum delay
+type hostLimiter struct {
+	global      chan struct{}
+	parallelism int
+	delay       time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*hostSlot
+}
+
+type hostSlot struct {
+	sem chan struct{}
+	// next is the earliest start time of the next request to the host
+	next time.Time
+	// users counts callers holding or waiting for the slot
+	users int
+}
+
+// idle host slots are swept once the map grows beyond this size
+const hostSlotSweepThreshold = 1024
+
+func newHostLimiter(maxConcurrency, perHostParallelism int, delay time.Duration) *hostLimiter {
+	return &hostLimiter{
+		global:      make(chan struct{}, maxConcurrency),
+		parallelism: perHostParallelism,
+		delay:       delay,
+		hosts:       make(map[string]*hostSlot),
+	}
+}
+
+// acquire waits until a request to host may start. The returned function
+// must be called once the request has finished.
+func (l *hostLimiter) acquire(ctx context.Context, host string) (func(), error) {
+	slot := l.slot(host)
+
+	// Wait for the host first so that a slow host does not hold global slots
+	select {
+	case slot.sem <- struct{}{}:
+	case <-ctx.Done():
+		l.leave(slot)
+		return nil, ctx.Err()
+	}
+
+	l.mu.Lock()
+	start := time.Now()
+	if slot.next.After(start) {
+		start = slot.next
+	}
+	slot.next = start.Add(l.delay)
+	l.mu.Unlock()
+
+	if wait := time.Until(start); wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			<-slot.sem
+			l.leave(slot)
+			return nil, ctx.Err()
+		}
+	}
+
+	select {
+	case l.global <- struct{}{}:
+	case <-ctx.Done():
+		<-slot.sem
+		l.leave(slot)
+		return nil, ctx.Err()
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			<-l.global
+			<-slot.sem
+			l.leave(slot)
+		})
+	}, nil
+}
+
+func (l *hostLimiter) slot(host string) *hostSlot {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.hosts) >= hostSlotSweepThreshold {
+		now := time.Now()
+		for key, slot := range l.hosts {
+			if slot.users == 0 && !slot.next.After(now) {
+				delete(l.hosts, key)
+			}
+		}
+	}
+
+	slot, ok := l.hosts[host]
+	if !ok {
+		slot = &hostSlot{sem: make(chan struct{}, l.parallelism)}
+		l.hosts[host] = slot
+	}
+	slot.users++
+	return slot
+}
+
+func (l *hostLimiter) leave(slot *hostSlot) {
+	l.mu.Lock()
+	slot.users--
+	l.mu.Unlock()
+}
@@ -0,0 +1,364 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/eikuma/stockle/backend/internal/importer"
+	"github.com/eikuma/stockle/backend/internal/models"
+	"github.com/eikuma/stockle/backend/internal/repositories"
+	"github.com/eikuma/stockle/backend/internal/urlnorm"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MaxImportItems is the number of links one import may have
+const MaxImportItems = 20000
+
+const (
+	// importBatchSize is the number of items read and counted at a time
+	importBatchSize = 100
+	// maxTitleLength and maxCategoryNameLength are the column sizes, in
+	// characters, that imported titles and folder names are cut to
+	maxTitleLength        = 500
+	maxCategoryNameLength = 100
+	defaultCategoryColor  = "#6B7280"
+)
+
+// ImportService saves the links of an import file as articles. The items
+// are stored when the file is uploaded and a job works through them, so a
+// large file does not hold up the request. An import whose worker stopped
+// is taken over by another worker once the job is stale, and carries on
+// with the items that are still pending.
+type ImportService struct {
+	importRepo   repositories.ImportRepository
+	articleRepo  repositories.ArticleRepository
+	categoryRepo repositories.CategoryRepository
+	tagRepo      repositories.TagRepository
+	jobService   *JobService
+	urls         *urlnorm.Normalizer
+	search       *SearchService
+}
+
+func NewImportService(
+	importRepo repositories.ImportRepository,
+	articleRepo repositories.ArticleRepository,
+	categoryRepo repositories.CategoryRepository,
+	tagRepo repositories.TagRepository,
+	jobService *JobService,
+	urls *urlnorm.Normalizer,
+	search *SearchService,
+) *ImportService {
+	return &ImportService{
+		importRepo:   importRepo,
+		articleRepo:  articleRepo,
+		categoryRepo: categoryRepo,
+		tagRepo:      tagRepo,
+		jobService:   jobService,
+		urls:         urls,
+		search:       search,
+	}
+}
+
+// Start saves the items of a file and schedules their import. Links
+// without a folder go to categoryID, or to the default category when it is
+// nil.
+func (s *ImportService) Start(userID string, format importer.Format, fileName string, categoryID *string, items []importer.Item) (*models.Import, error) {
+	imp := &models.Import{
+		ID:         uuid.New().String(),
+		UserID:     userID,
+		Format:     string(format),
+		FileName:   fileName,
+		CategoryID: categoryID,
+		Status:     models.ImportStatusPending,
+		TotalItems: len(items),
+	}
+
+	importItems := make([]*models.ImportItem, 0, len(items))
+	for _, item := range items {
+		importItems = append(importItems, &models.ImportItem{
+			Position: item.Position,
+			URL:      item.URL,
+			Title:    truncate(item.Title, maxTitleLength),
+			Folders:  models.StringList(item.Folders),
+			Tags:     models.StringList(item.Tags),
+			SavedAt:  item.SavedAt,
+			Archived: item.Archived,
+			Favorite: item.Favorite,
+			Status:   models.ImportItemStatusPending,
+		})
+	}
+
+	if err := s.importRepo.Create(imp, importItems); err != nil {
+		return nil, err
+	}
+	if err := s.jobService.EnqueueImportJob(imp.ID); err != nil {
+		message := "Failed to schedule the import: " + err.Error()
+		imp.Status = models.ImportStatusFailed
+		imp.ErrorMessage = &message
+		s.importRepo.Update(imp)
+		return nil, err
+	}
+	return imp, nil
+}
+
+// ProcessJob imports the pending items of an import, implementing JobHandler
+func (s *ImportService) ProcessJob(ctx context.Context, job *models.JobQueue, payload *JobPayload) error {
+	imp, err := s.importRepo.GetByID(payload.ImportID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if imp.Status == models.ImportStatusCompleted {
+		return nil
+	}
+
+	imp.Status = models.ImportStatusProcessing
+	imp.ErrorMessage = nil
+	if err := s.importRepo.Update(imp); err != nil {
+		return err
+	}
+
+	run, err := s.newImportRun(imp)
+	if err != nil {
+		return err
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		items, err := s.importRepo.GetItems(imp.ID, models.ImportItemStatusPending, importBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			break
+		}
+		for _, item := range items {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			run.importItem(item)
+			if err := s.importRepo.UpdateItem(item); err != nil {
+				return err
+			}
+		}
+		if err := s.saveProgress(imp); err != nil {
+			return err
+		}
+	}
+
+	imp.Status = models.ImportStatusCompleted
+	imp.CompletedAt = timePtr(time.Now())
+	return s.saveProgress(imp)
+}
+
+// JobFailed marks the import as failed once its job gave up. The items
+// imported so far are kept.
+func (s *ImportService) JobFailed(job *models.JobQueue, payload *JobPayload, jobErr error) {
+	imp, err := s.importRepo.GetByID(payload.ImportID)
+	if err != nil {
+		return
+	}
+	imp.Status = models.ImportStatusFailed
+	imp.ErrorMessage = stringPtr(jobErr.Error())
+	imp.CompletedAt = timePtr(time.Now())
+	if err := s.saveProgress(imp); err != nil {
+		log.Printf("Failed to mark import %s as failed: %v", imp.ID, err)
+	}
+}
+
+// saveProgress counts the items of an import by outcome and saves it
+func (s *ImportService) saveProgress(imp *models.Import) error {
+	counts, err := s.importRepo.CountItems(imp.ID)
+	if err != nil {
+		return err
+	}
+	imp.ImportedItems = counts[models.ImportItemStatusImported]
+	imp.DuplicateItems = counts[models.ImportItemStatusDuplicate]
+	imp.FailedItems = counts[models.ImportItemStatusFailed]
+	imp.ProcessedItems = imp.ImportedItems + imp.DuplicateItems + imp.FailedItems
+	return s.importRepo.Update(imp)
+}
+
+// importRun holds what the items of one import share: the category that
+// takes links without a folder and the categories and tags found or
+// created so far
+type importRun struct {
+	*ImportService
+	imp        *models.Import
+	categoryID *string
+	categories *categoryPaths
+	// tags maps a lowercased tag name to its tag
+	tags map[string]string
+}
+
+func (s *ImportService) newImportRun(imp *models.Import) (*importRun, error) {
+	categories, err := newCategoryPaths(s.categoryRepo, imp.UserID)
+	if err != nil {
+		return nil, err
+	}
+	run := &importRun{
+		ImportService: s,
+		imp:           imp,
+		categoryID:    imp.CategoryID,
+		categories:    categories,
+		tags:          make(map[string]string),
+	}
+	if run.categoryID == nil {
+		if defaultCategory, err := s.categoryRepo.GetDefault(imp.UserID); err == nil {
+			run.categoryID = &defaultCategory.ID
+		}
+	}
+	return run, nil
+}
+
+// importItem saves an item as an article and records the outcome on it
+func (r *importRun) importItem(item *models.ImportItem) {
+	fail := func(message string) {
+		item.Status = models.ImportItemStatusFailed
+		item.Error = &message
+	}
+
+	if strings.TrimSpace(item.URL) == "" {
+		fail("The link has no URL")
+		return
+	}
+	urlHash, err := r.urls.Key(item.URL)
+	if err != nil {
+		fail(err.Error())
+		return
+	}
+	if r.markIfDuplicate(item, urlHash) {
+		return
+	}
+
+	categoryID, err := r.categoryFor(item.Folders)
+	if err != nil {
+		fail("Failed to create category: " + err.Error())
+		return
+	}
+	tagIDs, err := r.tagIDs(item.Tags)
+	if err != nil {
+		fail("Failed to create tags: " + err.Error())
+		return
+	}
+
+	status := models.ArticleStatusUnread
+	if item.Archived {
+		status = models.ArticleStatusArchived
+	}
+	title := strings.TrimSpace(item.Title)
+	if title == "" {
+		title = truncate(item.URL, maxTitleLength)
+	}
+	article := &models.Article{
+		ID:                      uuid.New().String(),
+		UserID:                  r.imp.UserID,
+		CategoryID:              categoryID,
+		URL:                     strings.TrimSpace(item.URL),
+		URLHash:                 &urlHash,
+		Title:                   title,
+		Status:                  status,
+		IsFavorite:              item.Favorite,
+		ExtractionStatus:        models.ExtractionStatusPending,
+		SummaryGenerationStatus: models.SummaryStatusPending,
+	}
+	if item.Archived {
+		article.ReadingProgress = 1
+	}
+	if item.SavedAt != nil {
+		article.SavedAt = *item.SavedAt
+	}
+
+	if err := r.articleRepo.CreateWithTags(article, tagIDs); err != nil {
+		// The same URL may have been saved in the meantime
+		if r.markIfDuplicate(item, urlHash) {
+			return
+		}
+		fail("Failed to save article: " + err.Error())
+		return
+	}
+	r.search.Refresh(article.ID)
+	item.Status = models.ImportItemStatusImported
+	item.ArticleID = &article.ID
+	item.Error = nil
+
+	// Imported articles wait behind the ones the user saves by hand
+	if err := r.jobService.EnqueueExtractionJob(article.ID, models.JobPriorityLow); err != nil {
+		message := "Failed to schedule content extraction: " + err.Error()
+		article.ExtractionStatus = models.ExtractionStatusFailed
+		article.ExtractionError = &message
+		article.SummaryGenerationStatus = models.SummaryStatusFailed
+		if err := r.articleRepo.UpdateExtraction(article); err == nil {
+			r.articleRepo.UpdateSummaryStatus(article)
+		}
+	}
+}
+
+// markIfDuplicate marks the item as a duplicate when the user already has
+// an article with the URL
+func (r *importRun) markIfDuplicate(item *models.ImportItem, urlHash string) bool {
+	existing, err := r.articleRepo.GetByURLHash(r.imp.UserID, urlHash)
+	if err != nil {
+		return false
+	}
+	item.Status = models.ImportItemStatusDuplicate
+	item.ArticleID = &existing.ID
+	item.Error = nil
+	return true
+}
+
+// categoryFor returns the category of a folder path, creating the missing
+// categories along it
+func (r *importRun) categoryFor(folders []string) (*string, error) {
+	categoryID, err := r.categories.categoryFor(folders)
+	if err != nil || categoryID != nil {
+		return categoryID, err
+	}
+	return r.categoryID, nil
+}
+
+// tagIDs returns the tags with the given names, creating the missing ones
+func (r *importRun) tagIDs(names []string) ([]string, error) {
+	var ids, missing []string
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		key := strings.ToLower(repositories.NormalizeTagName(name))
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		if id, ok := r.tags[key]; ok {
+			ids = append(ids, id)
+		} else {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) == 0 {
+		return ids, nil
+	}
+
+	tags, err := r.tagRepo.GetOrCreateMultiple(r.imp.UserID, missing)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range tags {
+		r.tags[strings.ToLower(tag.Name)] = tag.ID
+		ids = append(ids, tag.ID)
+	}
+	return ids, nil
+}
+
+// truncate cuts value to at most max characters
+func truncate(value string, max int) string {
+	if runes := []rune(value); len(runes) > max {
+		return string(runes[:max])
+	}
+	return value
+}
@@ -3,44 +3,212 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
 	"github.com/eikuma/stockle/backend/internal/models"
 	"github.com/eikuma/stockle/backend/internal/repositories"
+	"github.com/eikuma/stockle/backend/internal/urlnorm"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
+// Summarizer generates article summaries. It is implemented by AIService.
+type Summarizer interface {
+	GenerateSummary(ctx context.Context, req *SummaryRequest) (*SummaryResponse, error)
+	// Providers returns the names of the providers a summary can be
+	// requested of
+	Providers() []string
+}
+
+// DefaultJobStaleAfter is how long a processing job may go without a
+// heartbeat before it is taken to be abandoned
+const DefaultJobStaleAfter = 10 * time.Minute
+
+// errJobAbandoned is recorded on a job given up after its worker stopped
+// while running it too many times
+var errJobAbandoned = errors.New("job was abandoned by its worker too many times")
+
+// errNoContent fails a summary job without retrying it, as retrying does not
+// give the article content
+var errNoContent = errors.New("article has no content to summarize")
+
 type JobService struct {
 	jobRepo     repositories.JobRepository
 	articleRepo repositories.ArticleRepository
-	aiService   *AIService
+	summaryRepo repositories.ArticleSummaryRepository
+	prefRepo    repositories.PreferenceRepository
+	aiService   Summarizer
+	scraperSvc  *ScraperService
+	urls        *urlnorm.Normalizer
+	search      *SearchService
+	events      *ArticleEventBroker
+	handlers    map[string]JobHandler
+
+	// staleAfter is how long a processing job may go without a heartbeat
+	// before another worker takes it over
+	staleAfter time.Duration
+
+	// wake lets an idle worker pick up a newly enqueued job without waiting for the next poll
+	wake chan struct{}
 }
 
 type JobPayload struct {
 	ArticleID string                 `json:"article_id"`
+	ImportID  string                 `json:"import_id,omitempty"`
+	ExportID  string                 `json:"export_id,omitempty"`
+	FeedID    string                 `json:"feed_id,omitempty"`
 	JobType   string                 `json:"job_type"`
 	Options   map[string]interface{} `json:"options"`
 }
 
-func NewJobService(jobRepo repositories.JobRepository, articleRepo repositories.ArticleRepository, aiService *AIService) *JobService {
+// SummaryOptions are the options of a summary job. The empty ones follow the
+// preferences of the owner of the article.
+type SummaryOptions struct {
+	// Type is one of models.SummaryTypes
+	Type string
+	// Language is the language of the summary, or
+	// models.SummaryLanguageOriginal for the language of the article
+	Language string
+	// Provider is the only AI provider asked for the summary
+	Provider string
+	// Regenerate replaces a summary that already exists
+	Regenerate bool
+}
+
+// JobHandler processes the jobs of a type that JobService does not know
+// itself. JobFailed is called once the job gave up after the last retry.
+type JobHandler interface {
+	ProcessJob(ctx context.Context, job *models.JobQueue, payload *JobPayload) error
+	JobFailed(job *models.JobQueue, payload *JobPayload, err error)
+}
+
+func NewJobService(
+	jobRepo repositories.JobRepository,
+	articleRepo repositories.ArticleRepository,
+	summaryRepo repositories.ArticleSummaryRepository,
+	prefRepo repositories.PreferenceRepository,
+	aiService Summarizer,
+	scraperSvc *ScraperService,
+	urls *urlnorm.Normalizer,
+	search *SearchService,
+	events *ArticleEventBroker,
+) *JobService {
 	return &JobService{
 		jobRepo:     jobRepo,
 		articleRepo: articleRepo,
+		summaryRepo: summaryRepo,
+		prefRepo:    prefRepo,
 		aiService:   aiService,
+		scraperSvc:  scraperSvc,
+		urls:        urls,
+		search:      search,
+		events:      events,
+		handlers:    make(map[string]JobHandler),
+		staleAfter:  DefaultJobStaleAfter,
+		wake:        make(chan struct{}, 1),
 	}
 }
 
+// EnqueueExtractionJob schedules scraping of a saved article. A summary job
+// is enqueued once the content has been extracted.
+func (s *JobService) EnqueueExtractionJob(articleID string, priority int) error {
+	return s.enqueue(models.JobTypeExtractContent, priority, JobPayload{
+		ArticleID: articleID,
+		JobType:   models.JobTypeExtractContent,
+	})
+}
+
+// EnqueueSummaryJob schedules the summary of an article in the length and
+// language its owner prefers
 func (s *JobService) EnqueueSummaryJob(articleID string, priority int) error {
-	payload := JobPayload{
+	return s.EnqueueSummary(articleID, priority, SummaryOptions{})
+}
+
+// EnqueueRegenerateSummaryJob schedules a new summary of an article,
+// replacing the one it has
+func (s *JobService) EnqueueRegenerateSummaryJob(articleID string, priority int) error {
+	return s.EnqueueSummary(articleID, priority, SummaryOptions{Regenerate: true})
+}
+
+// EnqueueSummary schedules a summary of an article with options. It returns
+// ErrUnknownProvider for a provider that is not configured.
+func (s *JobService) EnqueueSummary(articleID string, priority int, opts SummaryOptions) error {
+	if opts.Provider != "" && !s.HasSummaryProvider(opts.Provider) {
+		return fmt.Errorf("%w %q", ErrUnknownProvider, opts.Provider)
+	}
+
+	options := map[string]interface{}{}
+	if opts.Type != "" {
+		options["summary_type"] = opts.Type
+	}
+	if opts.Language != "" {
+		options["language"] = opts.Language
+	}
+	if opts.Provider != "" {
+		options["provider"] = opts.Provider
+	}
+	if opts.Regenerate {
+		options["regenerate"] = true
+	}
+	return s.enqueue(models.JobTypeSummarize, priority, JobPayload{
 		ArticleID: articleID,
-		JobType:   "summarize",
-		Options: map[string]interface{}{
-			"summary_type": "medium",
-		},
+		JobType:   models.JobTypeSummarize,
+		Options:   options,
+	})
+}
+
+// HasSummaryProvider reports whether summaries can be requested of a
+// provider
+func (s *JobService) HasSummaryProvider(name string) bool {
+	for _, provider := range s.aiService.Providers() {
+		if provider == name {
+			return true
+		}
 	}
+	return false
+}
+
+// EnqueueImportJob schedules the import of the links saved with an import
+func (s *JobService) EnqueueImportJob(importID string) error {
+	return s.enqueue(models.JobTypeImportArticles, models.JobPriorityMedium, JobPayload{
+		ImportID: importID,
+		JobType:  models.JobTypeImportArticles,
+	})
+}
+
+// EnqueueExportJob schedules the generation of the file of an export
+func (s *JobService) EnqueueExportJob(exportID string) error {
+	return s.enqueue(models.JobTypeExportArticles, models.JobPriorityMedium, JobPayload{
+		ExportID: exportID,
+		JobType:  models.JobTypeExportArticles,
+	})
+}
+
+// EnqueueFeedJob schedules a fetch of a feed for new entries
+func (s *JobService) EnqueueFeedJob(feedID string) error {
+	return s.enqueue(models.JobTypePollFeed, models.JobPriorityLow, JobPayload{
+		FeedID:  feedID,
+		JobType: models.JobTypePollFeed,
+	})
+}
+
+// Handle registers the handler of a job type. It must be called before the
+// workers start.
+func (s *JobService) Handle(jobType string, handler JobHandler) {
+	s.handlers[jobType] = handler
+}
 
+// SetStaleAfter sets how long a processing job may go without a heartbeat
+// before another worker takes it over; zero never takes jobs over. It must
+// be called before the workers start.
+func (s *JobService) SetStaleAfter(staleAfter time.Duration) {
+	s.staleAfter = staleAfter
+}
+
+func (s *JobService) enqueue(jobType string, priority int, payload JobPayload) error {
 	payloadJSON, err := json.Marshal(payload)
 	if err != nil {
 		return fmt.Errorf("failed to marshal job payload: %w", err)
@@ -48,14 +216,22 @@ func (s *JobService) EnqueueSummaryJob(articleID string, priority int) error {
 
 	job := &models.JobQueue{
 		ID:         uuid.New().String(),
-		JobType:    "summarize",
+		JobType:    jobType,
 		Priority:   priority,
-		Status:     "pending",
+		Status:     models.JobStatusPending,
 		Payload:    string(payloadJSON),
 		MaxRetries: 3,
 	}
 
-	return s.jobRepo.Create(job)
+	if err := s.jobRepo.Create(job); err != nil {
+		return err
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return nil
 }
 
 func (s *JobService) ProcessJob(ctx context.Context, job *models.JobQueue) error {
@@ -65,37 +241,186 @@ func (s *JobService) ProcessJob(ctx context.Context, job *models.JobQueue) error
 	}
 
 	switch job.JobType {
-	case "summarize":
+	case models.JobTypeExtractContent:
+		return s.processExtractionJob(ctx, job, &payload)
+	case models.JobTypeSummarize:
 		return s.processSummaryJob(ctx, job, &payload)
 	default:
+		if handler, ok := s.handlers[job.JobType]; ok {
+			return handler.ProcessJob(ctx, job, &payload)
+		}
 		return fmt.Errorf("unknown job type: %s", job.JobType)
 	}
 }
 
+func (s *JobService) processExtractionJob(ctx context.Context, job *models.JobQueue, payload *JobPayload) error {
+	article, err := s.articleRepo.GetByID(payload.ArticleID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// 処理待ちの間に記事が削除された
+			return nil
+		}
+		return fmt.Errorf("failed to get article: %w", err)
+	}
+
+	if article.ExtractionStatus == models.ExtractionStatusCompleted {
+		return nil
+	}
+
+	article.ExtractionStatus = models.ExtractionStatusProcessing
+	if err := s.articleRepo.UpdateExtraction(article); err != nil {
+		return fmt.Errorf("failed to update article: %w", err)
+	}
+	s.publish(article)
+
+	metadata, err := s.scraperSvc.ExtractMetadata(ctx, article.URL)
+	if err != nil {
+		return fmt.Errorf("failed to extract content: %w", err)
+	}
+
+	// 取得できた項目のみ上書きする
+	if metadata.Title != "" {
+		article.Title = metadata.Title
+	}
+	article.Content = nonEmpty(metadata.Content)
+	article.ContentHTML = nonEmpty(metadata.ContentHTML)
+	article.WordCount = &metadata.WordCount
+	article.ReadingTimeSeconds = metadata.ReadingTimeSeconds
+	article.ThumbnailURL = nonEmpty(metadata.ThumbnailURL)
+	article.Author = nonEmpty(metadata.Author)
+	article.SiteName = nonEmpty(metadata.SiteName)
+	article.PublishedAt = metadata.PublishedAt
+	if metadata.Language != "" {
+		article.Language = metadata.Language
+	}
+	article.Keywords = metadata.Keywords
+
+	// リダイレクト先または canonical URL で既に保存済みの記事と照合する
+	resolvedURL := metadata.CanonicalURL
+	if resolvedURL == "" {
+		resolvedURL = metadata.URL
+	}
+	article.CanonicalURL = nonEmpty(resolvedURL)
+	article.CanonicalURLHash = nil
+	if key, err := s.urls.Key(resolvedURL); err == nil {
+		article.CanonicalURLHash = &key
+		existing, err := s.articleRepo.GetByURLHash(article.UserID, key)
+		if err == nil && existing.ID != article.ID {
+			return s.removeDuplicate(article, existing)
+		}
+	}
+	article.ExtractionStatus = models.ExtractionStatusCompleted
+	article.ExtractionError = nil
+
+	if err := s.articleRepo.UpdateExtraction(article); err != nil {
+		return fmt.Errorf("failed to update article: %w", err)
+	}
+	s.search.Refresh(article.ID)
+
+	// 本文が取れなければ要約できない
+	if article.Content == nil {
+		article.SummaryGenerationStatus = models.SummaryStatusFailed
+		if err := s.articleRepo.UpdateSummaryStatus(article); err != nil {
+			return fmt.Errorf("failed to update article: %w", err)
+		}
+		s.publish(article)
+		return nil
+	}
+
+	// 自動要約を無効にしているユーザーの記事は、要求されるまで要約しない
+	preference, err := s.prefRepo.Get(article.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to get preferences: %w", err)
+	}
+	if !preference.AutoSummarize {
+		article.SummaryGenerationStatus = models.SummaryStatusSkipped
+		if err := s.articleRepo.UpdateSummaryStatus(article); err != nil {
+			return fmt.Errorf("failed to update article: %w", err)
+		}
+		s.publish(article)
+		return nil
+	}
+
+	s.publish(article)
+	return s.EnqueueSummaryJob(article.ID, models.JobPriorityMedium)
+}
+
+// removeDuplicate deletes article, which scraping showed to be the same page
+// as the earlier saved existing, and tells subscribers which article to show
+func (s *JobService) removeDuplicate(article, existing *models.Article) error {
+	if err := s.articleRepo.Delete(article.ID, article.UserID); err != nil {
+		return fmt.Errorf("failed to delete duplicate article: %w", err)
+	}
+	log.Printf("Article %s is a duplicate of %s and was removed", article.ID, existing.ID)
+	s.search.RemoveArticle(article.ID)
+
+	event := NewArticleEvent(article)
+	event.DuplicateOfID = existing.ID
+	s.events.Publish(event)
+	return nil
+}
+
 func (s *JobService) processSummaryJob(ctx context.Context, job *models.JobQueue, payload *JobPayload) error {
 	// 記事の取得
 	article, err := s.articleRepo.GetByID(payload.ArticleID)
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
 		return fmt.Errorf("failed to get article: %w", err)
 	}
 
-	// 既に要約が存在する場合はスキップ
-	if article.Summary != nil && *article.Summary != "" {
-		return nil
+	preference, err := s.prefRepo.Get(article.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to get preferences: %w", err)
+	}
+	summaryType, _ := payload.Options["summary_type"].(string)
+	if summaryType == "" {
+		summaryType = preference.SummaryLength
+	}
+	if !models.IsSummaryType(summaryType) {
+		summaryType = models.SummaryTypeMedium
+	}
+	language, _ := payload.Options["language"].(string)
+	if language == "" {
+		language = preference.SummaryLanguage
+	}
+	if language == "" || language == models.SummaryLanguageOriginal {
+		language = article.Language
 	}
+	provider, _ := payload.Options["provider"].(string)
 
-	// 要約生成リクエストの作成
-	summaryType := "medium"
-	if st, ok := payload.Options["summary_type"].(string); ok {
-		summaryType = st
+	// 既に要約が存在する場合はスキップ（再生成を指定された場合を除く）
+	regenerate, _ := payload.Options["regenerate"].(bool)
+	if !regenerate {
+		_, err := s.summaryRepo.GetCurrent(article.ID, summaryType, language)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to get summary: %w", err)
+		}
+	}
+
+	if article.Content == nil || *article.Content == "" {
+		return fmt.Errorf("article %s: %w", article.ID, errNoContent)
 	}
 
+	article.SummaryGenerationStatus = models.SummaryStatusProcessing
+	if err := s.articleRepo.UpdateSummaryStatus(article); err != nil {
+		return fmt.Errorf("failed to update article: %w", err)
+	}
+	s.publish(article)
+
+	// 要約生成リクエストの作成
 	req := &SummaryRequest{
-		Content:     *article.Content,
-		Title:       article.Title,
-		URL:         article.URL,
-		Language:    article.Language,
-		SummaryType: summaryType,
+		Content:        *article.Content,
+		Title:          article.Title,
+		URL:            article.URL,
+		Language:       article.Language,
+		SummaryType:    summaryType,
+		OutputLanguage: language,
+		Provider:       provider,
 	}
 
 	// 要約生成
@@ -104,50 +429,132 @@ func (s *JobService) processSummaryJob(ctx context.Context, job *models.JobQueue
 		return fmt.Errorf("failed to generate summary: %w", err)
 	}
 
-	// 記事の更新
-	article.Summary = &summary.Summary
-	article.SummaryGenerationStatus = "completed"
+	// 履歴として保存し、記事には長さごとに最新の要約を持たせる
+	err = s.summaryRepo.Create(&models.ArticleSummary{
+		ID:           uuid.New().String(),
+		ArticleID:    article.ID,
+		SummaryType:  summaryType,
+		Language:     language,
+		Content:      summary.Summary,
+		Provider:     summary.Provider,
+		ModelVersion: summary.ModelVersion,
+		Strategy:     summary.Strategy,
+		Confidence:   summary.Confidence,
+		Issues:       summary.Issues,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save summary: %w", err)
+	}
+
+	switch summaryType {
+	case models.SummaryTypeShort:
+		article.SummaryShort = &summary.Summary
+	case models.SummaryTypeLong:
+		article.SummaryLong = &summary.Summary
+	default:
+		article.Summary = &summary.Summary
+	}
+	article.SummaryGenerationStatus = models.SummaryStatusCompleted
 	article.SummaryGeneratedAt = &summary.GeneratedAt
 	article.SummaryModelVersion = &summary.ModelVersion
+	article.SummaryStrategy = &summary.Strategy
+	article.SummaryConfidence = &summary.Confidence
 
-	if err := s.articleRepo.Update(article); err != nil {
+	if err := s.articleRepo.UpdateSummary(article, summaryType); err != nil {
 		return fmt.Errorf("failed to update article: %w", err)
 	}
+	s.search.Refresh(article.ID)
+	s.publish(article)
 
-	log.Printf("Summary generated for article %s using %s", article.ID, summary.Provider)
+	log.Printf("Summary (%s, %s) generated for article %s using %s (%s, %d chunks, confidence %.2f)", summaryType, language, article.ID, summary.Provider, summary.Strategy, summary.Chunks, summary.Confidence)
 	return nil
 }
 
+// ProcessNext claims and processes the next due job. It reports whether a
+// job was found.
+func (s *JobService) ProcessNext(ctx context.Context) (bool, error) {
+	job, err := s.jobRepo.ClaimNextJob(s.staleAfter)
+	if err != nil {
+		return false, err
+	}
+	if job == nil {
+		return false, nil
+	}
+
+	// 中断された回数が上限を超えたジョブは再実行しない
+	if job.RetryCount >= job.MaxRetries {
+		log.Printf("Job %s failed: %v", job.ID, errJobAbandoned)
+		job.Status = models.JobStatusFailed
+		job.ErrorMessage = stringPtr(errJobAbandoned.Error())
+		s.markFailed(job, errJobAbandoned)
+		if err := s.jobRepo.Update(job); err != nil {
+			log.Printf("Failed to update job %s: %v", job.ID, err)
+		}
+		return true, nil
+	}
+
+	stopHeartbeat := s.startHeartbeat(job.ID)
+	s.processJobWithRetry(ctx, job)
+	stopHeartbeat()
+	return true, nil
+}
+
+// startHeartbeat keeps a running job from being taken for abandoned until
+// the returned function is called
+func (s *JobService) startHeartbeat(jobID string) func() {
+	if s.staleAfter <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(s.staleAfter / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := s.jobRepo.Heartbeat(jobID); err != nil {
+					log.Printf("Failed to record heartbeat of job %s: %v", jobID, err)
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
 func (s *JobService) StartWorker(ctx context.Context, workerID int) {
 	log.Printf("Starting worker %d", workerID)
 
 	for {
+		processed, err := s.ProcessNext(ctx)
+		if processed {
+			continue
+		}
+
+		wait := 5 * time.Second
+		if err != nil {
+			log.Printf("Worker %d failed to fetch job: %v", workerID, err)
+			wait = 1 * time.Second
+		}
+
 		select {
 		case <-ctx.Done():
 			log.Printf("Worker %d shutting down", workerID)
 			return
-		default:
-			job, err := s.jobRepo.GetNextJob()
-			if err != nil {
-				time.Sleep(1 * time.Second)
-				continue
-			}
-
-			if job == nil {
-				time.Sleep(5 * time.Second)
-				continue
-			}
-
-			s.processJobWithRetry(ctx, job)
+		case <-s.wake:
+		case <-time.After(wait):
 		}
 	}
 }
 
 func (s *JobService) processJobWithRetry(ctx context.Context, job *models.JobQueue) {
-	job.Status = "processing"
-	job.StartedAt = timePtr(time.Now())
-	s.jobRepo.Update(job)
-
 	err := s.ProcessJob(ctx, job)
 
 	if err != nil {
@@ -156,19 +563,94 @@ func (s *JobService) processJobWithRetry(ctx context.Context, job *models.JobQue
 		job.RetryCount++
 		job.ErrorMessage = stringPtr(err.Error())
 
-		if job.RetryCount >= job.MaxRetries {
-			job.Status = "failed"
+		// 本文がない記事は再試行しても要約できない
+		if job.RetryCount >= job.MaxRetries || errors.Is(err, errNoContent) {
+			job.Status = models.JobStatusFailed
+			s.markFailed(job, err)
 		} else {
-			job.Status = "pending"
+			// 指数バックオフ（2^n秒）で再試行
+			job.Status = models.JobStatusPending
+			job.ScheduledAt = timePtr(time.Now().Add(time.Duration(1<<job.RetryCount) * time.Second))
 		}
 	} else {
-		job.Status = "completed"
+		job.Status = models.JobStatusCompleted
 		job.CompletedAt = timePtr(time.Now())
 	}
 
-	s.jobRepo.Update(job)
+	if err := s.jobRepo.Update(job); err != nil {
+		log.Printf("Failed to update job %s: %v", job.ID, err)
+	}
+}
+
+// markFailed records that a job gave up after the last retry, on its
+// article or through the handler of its type
+func (s *JobService) markFailed(job *models.JobQueue, jobErr error) {
+	var payload JobPayload
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		return
+	}
+	if handler, ok := s.handlers[job.JobType]; ok {
+		handler.JobFailed(job, &payload, jobErr)
+		return
+	}
+	s.markArticleFailed(job, &payload, jobErr)
+}
+
+// markArticleFailed records on the article that its job gave up after the last retry
+func (s *JobService) markArticleFailed(job *models.JobQueue, payload *JobPayload, jobErr error) {
+	if payload.ArticleID == "" {
+		return
+	}
+
+	article, err := s.articleRepo.GetByID(payload.ArticleID)
+	if err != nil {
+		return
+	}
+
+	switch job.JobType {
+	case models.JobTypeExtractContent:
+		article.ExtractionStatus = models.ExtractionStatusFailed
+		article.ExtractionError = stringPtr(jobErr.Error())
+		// 本文がないため要約も行わない
+		article.SummaryGenerationStatus = failedSummaryStatus(article)
+		err = s.articleRepo.UpdateExtraction(article)
+		if err == nil {
+			err = s.articleRepo.UpdateSummaryStatus(article)
+		}
+	case models.JobTypeSummarize:
+		article.SummaryGenerationStatus = failedSummaryStatus(article)
+		err = s.articleRepo.UpdateSummaryStatus(article)
+	default:
+		return
+	}
+
+	if err != nil {
+		log.Printf("Failed to mark article %s as failed: %v", article.ID, err)
+		return
+	}
+	s.publish(article)
+}
+
+// failedSummaryStatus is the summary status of an article after a summary
+// of it failed; the summaries of other lengths it has still stand
+func failedSummaryStatus(article *models.Article) string {
+	if article.Summary != nil || article.SummaryShort != nil || article.SummaryLong != nil {
+		return models.SummaryStatusCompleted
+	}
+	return models.SummaryStatusFailed
+}
+
+func (s *JobService) publish(article *models.Article) {
+	s.events.Publish(NewArticleEvent(article))
+}
+
+func nonEmpty(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
 }
 
 func timePtr(t time.Time) *time.Time {
 	return &t
-}
\ No newline at end of file
+}
@@ -1,270 +1,330 @@
 package services
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"net/http"
 	"net/url"
 	"strings"
 	"time"
 
+	"github.com/PuerkitoBio/goquery"
+	"github.com/eikuma/stockle/backend/internal/config"
+	"github.com/eikuma/stockle/backend/internal/extractor"
 	"github.com/gocolly/colly/v2"
 	"github.com/gocolly/colly/v2/extensions"
 )
 
+// Column limits of the articles table, in characters
+const (
+	maxTitleRunes       = 500
+	maxAuthorRunes      = 255
+	maxSiteNameRunes    = 255
+	maxDescriptionRunes = 1000
+)
+
 // ArticleMetadata represents the extracted metadata from a webpage
 type ArticleMetadata struct {
-	Title        string
-	Content      string
+	Title string
+	// Content is the plain text of the main content
+	Content string
+	// ContentHTML is the sanitized markup of the main content
+	ContentHTML        string
+	WordCount          int
+	ReadingTimeSeconds int
+	// Author lists the names in Authors, separated by commas
 	Author       string
+	Authors      []string
 	SiteName     string
 	ThumbnailURL string
 	PublishedAt  *time.Time
 	Description  string
 	Language     string
+	// URL is the address of the page after redirects
+	URL string
+	// CanonicalURL is the preferred address the page declares, if it is plausible
+	CanonicalURL string
+	Keywords     []string
+	// Extractor is the name of the site extractor used, or GenericExtractorName
+	Extractor string
+	// Pages is the number of pages the content was read from
+	Pages int
+	// Warnings describe fallbacks taken during extraction
+	Warnings []string
 }
 
-// ScraperService handles web scraping operations
+// ScraperService handles web scraping operations. It is safe for concurrent
+// use: every extraction visits the page with its own collector, so callbacks
+// and extracted metadata are never shared between calls.
 type ScraperService struct {
+	// collector is the template cloned for every extraction and never visits pages itself
 	collector *colly.Collector
+	limiter   *hostLimiter
+	timeout   time.Duration
+	registry  *ExtractorRegistry
 }
 
-// NewScraperService creates a new scraper service
-func NewScraperService() *ScraperService {
+// NewScraperService creates a new scraper service. Pages of sites in registry
+// are read with their site extractor; a nil registry uses the generic
+// extraction for every page.
+func NewScraperService(cfg *config.ScraperConfig, registry *ExtractorRegistry) *ScraperService {
+	maxConcurrency := cfg.MaxConcurrency
+	if maxConcurrency <= 0 {
+		maxConcurrency = 8
+	}
+	perHostParallelism := cfg.PerHostParallelism
+	if perHostParallelism <= 0 {
+		perHostParallelism = 2
+	}
+	timeout := cfg.RequestTimeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
 	c := colly.NewCollector(
-		colly.Async(true),
 		colly.AllowURLRevisit(),
 	)
 
-	// Set random user agent
-	extensions.RandomUserAgent(c)
-
-	// Set limits
-	c.Limit(&colly.LimitRule{
-		DomainGlob:  "*",
-		Parallelism: 2,
-		Delay:       1 * time.Second,
-	})
-
 	// Set timeouts
-	c.SetRequestTimeout(30 * time.Second)
+	c.SetRequestTimeout(timeout)
 
 	return &ScraperService{
 		collector: c,
+		limiter:   newHostLimiter(maxConcurrency, perHostParallelism, cfg.PerHostDelay),
+		timeout:   timeout,
+		registry:  registry,
 	}
 }
 
-// ExtractMetadata extracts metadata from the given URL
-func (s *ScraperService) ExtractMetadata(targetURL string) (*ArticleMetadata, error) {
-	// Validate URL
-	_, err := url.Parse(targetURL)
+// FetchedDocument is a response read as is by FetchDocument
+type FetchedDocument struct {
+	// URL is the address of the document after redirects
+	URL          *url.URL
+	ContentType  string
+	Body         []byte
+	ETag         string
+	LastModified string
+	// NotModified is set when the server answered a conditional request
+	// with 304 and there is no body
+	NotModified bool
+}
+
+// collectorFor waits until the concurrency limits allow a request to the
+// host of targetURL and returns a collector of its own bound to ctx. done
+// frees the slot and must be called once the request finished.
+func (s *ScraperService) collectorFor(ctx context.Context, targetURL string) (c *colly.Collector, done func(), err error) {
+	parsedURL, err := url.Parse(targetURL)
 	if err != nil {
-		return nil, fmt.Errorf("invalid URL: %w", err)
+		return nil, nil, fmt.Errorf("invalid URL: %w", err)
 	}
-
-	metadata := &ArticleMetadata{
-		Language: "ja", // Default to Japanese
+	if parsedURL.Host == "" {
+		return nil, nil, fmt.Errorf("invalid URL: %s has no host", targetURL)
 	}
 
-	// Set up callbacks for metadata extraction
-	s.collector.OnHTML("head", func(e *colly.HTMLElement) {
-		// Extract title
-		if metadata.Title == "" {
-			metadata.Title = e.ChildText("title")
-			if metadata.Title == "" {
-				metadata.Title = e.ChildAttr("meta[property='og:title']", "content")
-			}
-		}
-
-		// Extract description
-		metadata.Description = e.ChildAttr("meta[name='description']", "content")
-		if metadata.Description == "" {
-			metadata.Description = e.ChildAttr("meta[property='og:description']", "content")
-		}
-
-		// Extract thumbnail
-		metadata.ThumbnailURL = e.ChildAttr("meta[property='og:image']", "content")
-		if metadata.ThumbnailURL == "" {
-			metadata.ThumbnailURL = e.ChildAttr("meta[name='twitter:image']", "content")
-		}
-
-		// Extract site name
-		metadata.SiteName = e.ChildAttr("meta[property='og:site_name']", "content")
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	release, err := s.limiter.acquire(ctx, parsedURL.Host)
+	if err != nil {
+		cancel()
+		return nil, nil, fmt.Errorf("scraping failed for %s: %w", targetURL, err)
+	}
 
-		// Extract author
-		metadata.Author = e.ChildAttr("meta[name='author']", "content")
-		if metadata.Author == "" {
-			metadata.Author = e.ChildAttr("meta[property='article:author']", "content")
-		}
+	c = s.collector.Clone()
+	c.Context = ctx
 
-		// Extract published date
-		publishedTime := e.ChildAttr("meta[property='article:published_time']", "content")
-		if publishedTime != "" {
-			if t, err := time.Parse(time.RFC3339, publishedTime); err == nil {
-				metadata.PublishedAt = &t
-			}
-		}
+	// Set random user agent
+	extensions.RandomUserAgent(c)
 
-		// Extract language
-		lang := e.ChildAttr("meta[property='og:locale']", "content")
-		if lang != "" {
-			metadata.Language = strings.Split(lang, "_")[0]
-		}
-	})
+	return c, func() {
+		release()
+		cancel()
+	}, nil
+}
 
-	// Extract main content
-	s.collector.OnHTML("article", func(e *colly.HTMLElement) {
-		content := e.Text
-		if len(content) > len(metadata.Content) {
-			metadata.Content = content
-		}
-	})
+// fetch downloads targetURL with a collector of its own once the concurrency
+// limits allow it and parses it as HTML. The document URL is the final URL
+// after redirects.
+func (s *ScraperService) fetch(ctx context.Context, targetURL string) (*goquery.Document, error) {
+	c, done, err := s.collectorFor(ctx, targetURL)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
 
-	// Fallback content extraction
-	s.collector.OnHTML("main", func(e *colly.HTMLElement) {
-		if metadata.Content == "" {
-			metadata.Content = e.Text
+	var doc *goquery.Document
+	var scraperErr error
+	c.OnResponse(func(r *colly.Response) {
+		contentType := strings.ToLower(r.Headers.Get("Content-Type"))
+		if contentType != "" && !strings.Contains(contentType, "html") {
+			scraperErr = fmt.Errorf("scraping failed for %s: unsupported content type %q", targetURL, contentType)
+			return
 		}
-	})
 
-	s.collector.OnHTML("body", func(e *colly.HTMLElement) {
-		if metadata.Content == "" {
-			// Try to find content in common content containers
-			selectors := []string{
-				".content",
-				"#content",
-				".post-content",
-				".entry-content",
-				".article-content",
-				".main-content",
-			}
-			
-			for _, selector := range selectors {
-				content := e.ChildText(selector)
-				if content != "" && len(content) > len(metadata.Content) {
-					metadata.Content = content
-					break
-				}
-			}
+		parsed, err := goquery.NewDocumentFromReader(bytes.NewReader(r.Body))
+		if err != nil {
+			scraperErr = fmt.Errorf("scraping failed for %s: %w", targetURL, err)
+			return
 		}
+		parsed.Url = r.Request.URL
+		doc = parsed
 	})
 
 	// Error handling
-	var scraperErr error
-	s.collector.OnError(func(r *colly.Response, err error) {
+	c.OnError(func(r *colly.Response, err error) {
 		scraperErr = fmt.Errorf("scraping failed for %s: %w", targetURL, err)
 	})
 
 	// Visit the URL
-	err = s.collector.Visit(targetURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to visit URL: %w", err)
+	if err := c.Visit(targetURL); err != nil && scraperErr == nil {
+		scraperErr = fmt.Errorf("failed to visit URL: %w", err)
 	}
-
-	// Wait for async operations
-	s.collector.Wait()
-
 	if scraperErr != nil {
 		return nil, scraperErr
 	}
-
-	// Clean up extracted content
-	metadata.Content = cleanText(metadata.Content)
-	metadata.Title = cleanText(metadata.Title)
-	metadata.Description = cleanText(metadata.Description)
-
-	return metadata, nil
-}
-
-// cleanText removes excessive whitespace and cleans up text
-func cleanText(text string) string {
-	// Remove extra whitespace
-	text = strings.TrimSpace(text)
-	
-	// Replace multiple spaces with single space
-	text = strings.Join(strings.Fields(text), " ")
-	
-	// Limit length for database storage
-	if len(text) > 10000 {
-		text = text[:10000] + "..."
+	if doc == nil {
+		return nil, fmt.Errorf("scraping failed for %s: empty response", targetURL)
 	}
-	
-	return text
+	return doc, nil
 }
 
-// ExtractContentForSite extracts content with site-specific rules
-func (s *ScraperService) ExtractContentForSite(targetURL string) (*ArticleMetadata, error) {
-	parsedURL, err := url.Parse(targetURL)
+// FetchDocument downloads targetURL without parsing it, within the same
+// concurrency limits as the extraction of pages. A non-empty etag or
+// lastModified makes the request conditional, see NotModified.
+func (s *ScraperService) FetchDocument(ctx context.Context, targetURL, etag, lastModified string) (*FetchedDocument, error) {
+	c, done, err := s.collectorFor(ctx, targetURL)
 	if err != nil {
 		return nil, err
 	}
+	defer done()
 
-	// Site-specific extraction rules
-	host := parsedURL.Host
-
-	switch {
-	case strings.Contains(host, "qiita.com"):
-		return s.extractQiitaContent(targetURL)
-	case strings.Contains(host, "zenn.dev"):
-		return s.extractZennContent(targetURL)
-	case strings.Contains(host, "note.com"):
-		return s.extractNoteContent(targetURL)
-	default:
-		// Use generic extraction
-		return s.ExtractMetadata(targetURL)
-	}
-}
-
-// extractQiitaContent extracts content specifically from Qiita
-func (s *ScraperService) extractQiitaContent(targetURL string) (*ArticleMetadata, error) {
-	metadata := &ArticleMetadata{Language: "ja"}
+	c.OnRequest(func(r *colly.Request) {
+		if etag != "" {
+			r.Headers.Set("If-None-Match", etag)
+		}
+		if lastModified != "" {
+			r.Headers.Set("If-Modified-Since", lastModified)
+		}
+	})
 
-	s.collector.OnHTML("article", func(e *colly.HTMLElement) {
-		metadata.Title = e.ChildText("h1")
-		metadata.Content = e.ChildText(".it-MdContent")
-		metadata.Author = e.ChildText(".it-Header_authorName")
+	var doc *FetchedDocument
+	var fetchErr error
+	c.OnResponse(func(r *colly.Response) {
+		doc = &FetchedDocument{
+			URL:          r.Request.URL,
+			ContentType:  r.Headers.Get("Content-Type"),
+			Body:         r.Body,
+			ETag:         r.Headers.Get("ETag"),
+			LastModified: r.Headers.Get("Last-Modified"),
+		}
+	})
+	c.OnError(func(r *colly.Response, err error) {
+		if r != nil && r.StatusCode == http.StatusNotModified {
+			doc = &FetchedDocument{URL: r.Request.URL, NotModified: true}
+			return
+		}
+		fetchErr = fmt.Errorf("fetching %s failed: %w", targetURL, err)
 	})
 
-	err := s.collector.Visit(targetURL)
+	if err := c.Visit(targetURL); err != nil && fetchErr == nil && doc == nil {
+		fetchErr = fmt.Errorf("failed to visit URL: %w", err)
+	}
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+	if doc == nil {
+		return nil, fmt.Errorf("fetching %s failed: empty response", targetURL)
+	}
+	return doc, nil
+}
+
+// ExtractMetadata extracts metadata from the given URL with the site
+// extractor matching the final URL after redirects
+func (s *ScraperService) ExtractMetadata(ctx context.Context, targetURL string) (*ArticleMetadata, error) {
+	doc, err := s.fetch(ctx, targetURL)
 	if err != nil {
 		return nil, err
 	}
-
-	s.collector.Wait()
-	return metadata, nil
+	return s.ExtractDocument(ctx, doc, s.fetch)
 }
 
-// extractZennContent extracts content specifically from Zenn
-func (s *ScraperService) extractZennContent(targetURL string) (*ArticleMetadata, error) {
-	metadata := &ArticleMetadata{Language: "ja"}
+// ExtractDocument extracts metadata from an already downloaded page. fetch
+// downloads further pages of the article; when it is nil only doc is read.
+// The language is detected from the content when the page does not declare it.
+func (s *ScraperService) ExtractDocument(ctx context.Context, doc *goquery.Document, fetch FetchFunc) (*ArticleMetadata, error) {
+	var metadata *ArticleMetadata
+	if site, _ := s.registry.Lookup(doc.Url); site != nil {
+		var err error
+		metadata, err = site.Extract(ctx, doc, fetch)
+		if err != nil {
+			return nil, fmt.Errorf("%s extractor failed: %w", site.Name(), err)
+		}
+	} else {
+		metadata = metadataFromDocument(doc)
+		metadata.Extractor = GenericExtractorName
+		metadata.Pages = 1
+		setContent(metadata, extractor.Extract(doc, doc.Url))
+	}
 
-	s.collector.OnHTML("article", func(e *colly.HTMLElement) {
-		metadata.Title = e.ChildText("h1")
-		metadata.Content = e.ChildText(".znc")
-	})
+	if metadata.Language == "" {
+		metadata.Language = extractor.DetectLanguage(metadata.Content)
+	}
+	if doc.Url != nil {
+		metadata.URL = doc.Url.String()
+		if !plausibleCanonical(doc.Url, metadata.CanonicalURL) {
+			metadata.CanonicalURL = ""
+		}
+	}
+	return metadata, nil
+}
 
-	err := s.collector.Visit(targetURL)
+// plausibleCanonical reports whether canonical can be the preferred address
+// of the page at pageURL. Some sites declare their home page as the canonical
+// URL of every page, which would make all their articles duplicates.
+func plausibleCanonical(pageURL *url.URL, canonical string) bool {
+	if canonical == "" {
+		return false
+	}
+	u, err := url.Parse(canonical)
 	if err != nil {
-		return nil, err
+		return false
 	}
-
-	s.collector.Wait()
-	return metadata, nil
+	isRoot := func(u *url.URL) bool { return strings.Trim(u.Path, "/") == "" && u.RawQuery == "" }
+	return !isRoot(u) || isRoot(pageURL)
 }
 
-// extractNoteContent extracts content specifically from Note
-func (s *ScraperService) extractNoteContent(targetURL string) (*ArticleMetadata, error) {
-	metadata := &ArticleMetadata{Language: "ja"}
+// Limits of the keywords kept for an article
+const (
+	maxKeywords     = 30
+	maxKeywordRunes = 100
+)
 
-	s.collector.OnHTML(".note-common-container", func(e *colly.HTMLElement) {
-		metadata.Title = e.ChildText("h1")
-		metadata.Content = e.ChildText(".note-body")
-		metadata.Author = e.ChildText(".o-noteContentHeader__authorName")
-	})
+// metadataFromDocument reads the metadata the page declares, see extractor.ResolveMetadata
+func metadataFromDocument(doc *goquery.Document) *ArticleMetadata {
+	resolved := extractor.ResolveMetadata(doc)
 
-	err := s.collector.Visit(targetURL)
-	if err != nil {
-		return nil, err
+	metadata := &ArticleMetadata{
+		Title:        extractor.TruncateRunes(resolved.Title, maxTitleRunes),
+		Description:  extractor.TruncateRunes(resolved.Description, maxDescriptionRunes),
+		Authors:      resolved.Authors,
+		Author:       extractor.TruncateRunes(strings.Join(resolved.Authors, ", "), maxAuthorRunes),
+		SiteName:     extractor.TruncateRunes(resolved.SiteName, maxSiteNameRunes),
+		ThumbnailURL: resolved.ImageURL,
+		PublishedAt:  resolved.PublishedAt,
+		Language:     resolved.Language,
+		CanonicalURL: resolved.CanonicalURL,
 	}
+	for _, keyword := range resolved.Keywords {
+		if len(metadata.Keywords) == maxKeywords {
+			break
+		}
+		metadata.Keywords = append(metadata.Keywords, extractor.TruncateRunes(keyword, maxKeywordRunes))
+	}
+	return metadata
+}
 
-	s.collector.Wait()
-	return metadata, nil
-}
\ No newline at end of file
+func setContent(metadata *ArticleMetadata, content *extractor.Content) {
+	metadata.Content = content.Text
+	metadata.ContentHTML = content.HTML
+	metadata.WordCount = content.WordCount
+	metadata.ReadingTimeSeconds = content.ReadingTimeSeconds
+}
@@ -0,0 +1,272 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eikuma/stockle/backend/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fixtureServer serves the pages in testdata/scraper and records how many
+// requests it is handling at once
+type fixtureServer struct {
+	*httptest.Server
+	host fixtureCounter
+	// global, when set, is shared between servers to record cross-host concurrency
+	global *fixtureCounter
+}
+
+type fixtureCounter struct {
+	inFlight    atomic.Int32
+	maxInFlight atomic.Int32
+}
+
+func (c *fixtureCounter) enter() func() {
+	current := c.inFlight.Add(1)
+	for {
+		max := c.maxInFlight.Load()
+		if current <= max || c.maxInFlight.CompareAndSwap(max, current) {
+			break
+		}
+	}
+	return func() { c.inFlight.Add(-1) }
+}
+
+func newFixtureServer(t *testing.T, global *fixtureCounter) *fixtureServer {
+	t.Helper()
+
+	article, err := os.ReadFile(filepath.Join("testdata", "scraper", "article.html"))
+	require.NoError(t, err)
+	mainFallback, err := os.ReadFile(filepath.Join("testdata", "scraper", "main_fallback.html"))
+	require.NoError(t, err)
+
+	server := &fixtureServer{global: global}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer server.host.enter()()
+		if server.global != nil {
+			defer server.global.enter()()
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/articles/"):
+			id := strings.TrimPrefix(r.URL.Path, "/articles/")
+			// Keep requests in flight long enough to overlap
+			time.Sleep(2 * time.Millisecond)
+			page := strings.NewReplacer("{{ID}}", id, "{{TITLE}}", "Article "+id).Replace(string(article))
+			_, _ = w.Write([]byte(page))
+		case r.URL.Path == "/main":
+			_, _ = w.Write(mainFallback)
+		case r.URL.Path == "/redirect":
+			http.Redirect(w, r, "/articles/7", http.StatusMovedPermanently)
+		case r.URL.Path == "/home-canonical":
+			_, _ = w.Write([]byte(`<html><head><link rel="canonical" href="/"></head><body><p>Every page claims to be the home page.</p></body></html>`))
+		case r.URL.Path == "/slow":
+			<-r.Context().Done()
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestScraper(cfg config.ScraperConfig) *ScraperService {
+	return NewScraperService(&cfg, nil)
+}
+
+func TestScraperService_ExtractMetadata(t *testing.T) {
+	server := newFixtureServer(t, nil)
+	scraper := newTestScraper(config.ScraperConfig{})
+
+	metadata, err := scraper.ExtractMetadata(context.Background(), server.URL+"/articles/42")
+	require.NoError(t, err)
+	assert.Equal(t, "Article 42", metadata.Title)
+	assert.Equal(t, "Fixture page number 42", metadata.Description)
+	assert.Equal(t, "Fixture Blog", metadata.SiteName)
+	assert.Equal(t, "Author 42", metadata.Author)
+	assert.Equal(t, "https://example.com/images/42.png", metadata.ThumbnailURL)
+	assert.Equal(t, "en", metadata.Language)
+	require.NotNil(t, metadata.PublishedAt)
+	assert.Equal(t, time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC), metadata.PublishedAt.UTC())
+	assert.Equal(t, "Body of fixture article 42.\n\nEvery extraction must only ever see its own page.", metadata.Content)
+	assert.Equal(t, "<p>Body of fixture article 42.</p>\n<p>Every extraction must only ever see its own page.</p>", metadata.ContentHTML)
+	assert.Equal(t, 14, metadata.WordCount)
+	assert.Equal(t, 4, metadata.ReadingTimeSeconds)
+
+	t.Run("falls back to og:title and main", func(t *testing.T) {
+		metadata, err := scraper.ExtractMetadata(context.Background(), server.URL+"/main")
+		require.NoError(t, err)
+		assert.Equal(t, "Open Graph Title", metadata.Title)
+		assert.Equal(t, "Content found in the main element.", metadata.Content)
+		// Too little text to detect a language; the article keeps its default
+		assert.Empty(t, metadata.Language)
+	})
+
+	t.Run("redirects and canonical links are resolved", func(t *testing.T) {
+		metadata, err := scraper.ExtractMetadata(context.Background(), server.URL+"/redirect")
+		require.NoError(t, err)
+		assert.Equal(t, server.URL+"/articles/7", metadata.URL)
+		assert.Equal(t, "Article 7", metadata.Title)
+
+		// A home page canonical on an article page is a site misconfiguration
+		metadata, err = scraper.ExtractMetadata(context.Background(), server.URL+"/home-canonical")
+		require.NoError(t, err)
+		assert.Equal(t, server.URL+"/home-canonical", metadata.URL)
+		assert.Empty(t, metadata.CanonicalURL)
+	})
+
+	t.Run("HTTP errors fail the extraction", func(t *testing.T) {
+		_, err := scraper.ExtractMetadata(context.Background(), server.URL+"/missing")
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid URL", func(t *testing.T) {
+		_, err := scraper.ExtractMetadata(context.Background(), "not a url")
+		assert.Error(t, err)
+	})
+}
+
+// TestScraperService_ConcurrentExtractions runs hundreds of extractions at
+// once against several hosts. Run with -race to check that no state is shared
+// between calls.
+func TestScraperService_ConcurrentExtractions(t *testing.T) {
+	const (
+		extractions        = 300
+		maxConcurrency     = 6
+		perHostParallelism = 3
+	)
+
+	global := &fixtureCounter{}
+	servers := []*fixtureServer{
+		newFixtureServer(t, global),
+		newFixtureServer(t, global),
+		newFixtureServer(t, global),
+	}
+	scraper := newTestScraper(config.ScraperConfig{
+		MaxConcurrency:     maxConcurrency,
+		PerHostParallelism: perHostParallelism,
+	})
+
+	var wg sync.WaitGroup
+	errs := make([]error, extractions)
+	results := make([]*ArticleMetadata, extractions)
+	for i := 0; i < extractions; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			server := servers[i%len(servers)]
+			results[i], errs[i] = scraper.ExtractMetadata(context.Background(), fmt.Sprintf("%s/articles/%d", server.URL, i))
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < extractions; i++ {
+		require.NoError(t, errs[i], "extraction %d", i)
+		assert.Equal(t, fmt.Sprintf("Article %d", i), results[i].Title)
+		assert.Equal(t, fmt.Sprintf("Author %d", i), results[i].Author)
+		assert.Contains(t, results[i].Content, fmt.Sprintf("Body of fixture article %d.", i))
+	}
+
+	assert.LessOrEqual(t, global.maxInFlight.Load(), int32(maxConcurrency))
+	for _, server := range servers {
+		assert.LessOrEqual(t, server.host.maxInFlight.Load(), int32(perHostParallelism))
+	}
+}
+
+func TestScraperService_Deadlines(t *testing.T) {
+	server := newFixtureServer(t, nil)
+
+	t.Run("context cancellation stops the request", func(t *testing.T) {
+		scraper := newTestScraper(config.ScraperConfig{})
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		started := time.Now()
+		_, err := scraper.ExtractMetadata(ctx, server.URL+"/slow")
+		require.Error(t, err)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(started), 5*time.Second)
+	})
+
+	t.Run("per-call timeout", func(t *testing.T) {
+		scraper := newTestScraper(config.ScraperConfig{RequestTimeout: 100 * time.Millisecond})
+
+		started := time.Now()
+		_, err := scraper.ExtractMetadata(context.Background(), server.URL+"/slow")
+		require.Error(t, err)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(started), 5*time.Second)
+	})
+
+	t.Run("waiting for a busy host respects the deadline", func(t *testing.T) {
+		scraper := newTestScraper(config.ScraperConfig{PerHostParallelism: 1})
+
+		busy, cancelBusy := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_, _ = scraper.ExtractMetadata(busy, server.URL+"/slow")
+		}()
+		// Wait until the slow request holds the only slot
+		require.Eventually(t, func() bool { return server.host.inFlight.Load() == 1 }, 5*time.Second, 5*time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err := scraper.ExtractMetadata(ctx, server.URL+"/articles/1")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		cancelBusy()
+		<-done
+
+		// The slot is released afterwards
+		metadata, err := scraper.ExtractMetadata(context.Background(), server.URL+"/articles/1")
+		require.NoError(t, err)
+		assert.Equal(t, "Article 1", metadata.Title)
+	})
+}
+
+func TestScraperService_PerHostDelay(t *testing.T) {
+	server := newFixtureServer(t, nil)
+	const delay = 50 * time.Millisecond
+	scraper := newTestScraper(config.ScraperConfig{PerHostParallelism: 4, PerHostDelay: delay})
+
+	var mu sync.Mutex
+	var finished []time.Time
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := scraper.ExtractMetadata(context.Background(), fmt.Sprintf("%s/articles/%d", server.URL, i))
+			assert.NoError(t, err)
+			mu.Lock()
+			finished = append(finished, time.Now())
+			mu.Unlock()
+		}(i)
+	}
+	wg.Wait()
+
+	// Four requests to one host need at least three delays between them
+	first, last := finished[0], finished[0]
+	for _, at := range finished {
+		if at.Before(first) {
+			first = at
+		}
+		if at.After(last) {
+			last = at
+		}
+	}
+	assert.GreaterOrEqual(t, last.Sub(first), 3*delay-10*time.Millisecond)
+}
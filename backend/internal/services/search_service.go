@@ -0,0 +1,236 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/eikuma/stockle/backend/internal/models"
+	"github.com/eikuma/stockle/backend/internal/repositories"
+	"github.com/eikuma/stockle/backend/internal/search"
+	"gorm.io/gorm"
+)
+
+const (
+	// snippetLength is the approximate number of characters of a snippet
+	snippetLength = 160
+	// reindexBatchSize is the number of stale articles indexed per round
+	reindexBatchSize = 200
+)
+
+// SearchService keeps the fulltext index in step with the articles and
+// answers searches with the matching articles and highlighted snippets
+type SearchService struct {
+	index       search.SearchIndex
+	articleRepo repositories.ArticleRepository
+}
+
+// SearchHit is an article matching a search. Highlights are HTML-escaped,
+// with the matched words wrapped in <mark> tags.
+type SearchHit struct {
+	Article    *models.Article  `json:"article"`
+	Score      float64          `json:"score"`
+	Highlights SearchHighlights `json:"highlights"`
+}
+
+type SearchHighlights struct {
+	Title   string `json:"title"`
+	Snippet string `json:"snippet"`
+}
+
+// SearchResult is a page of search hits, best first
+type SearchResult struct {
+	Hits  []SearchHit
+	Total int
+	// NextCursor continues the search after this page; it is empty on the
+	// last page
+	NextCursor string
+}
+
+// searchCursor is the last hit of a page
+type searchCursor struct {
+	Score     float64 `json:"s"`
+	ArticleID string  `json:"id"`
+}
+
+func NewSearchService(index search.SearchIndex, articleRepo repositories.ArticleRepository) *SearchService {
+	return &SearchService{
+		index:       index,
+		articleRepo: articleRepo,
+	}
+}
+
+// IndexArticle indexes the current title, summary, content and tags of an
+// article. An article that no longer exists is removed from the index.
+func (s *SearchService) IndexArticle(articleID string) error {
+	article, err := s.articleRepo.GetByIDWithAssociations(articleID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return s.index.Remove(articleID)
+	}
+	if err != nil {
+		return err
+	}
+
+	doc := search.Document{
+		ArticleID: article.ID,
+		UserID:    article.UserID,
+		Title:     article.Title,
+	}
+	if article.Summary != nil {
+		doc.Summary = *article.Summary
+	}
+	if article.Content != nil {
+		doc.Content = *article.Content
+	}
+	for _, tag := range article.Tags {
+		doc.Tags = append(doc.Tags, tag.Name)
+	}
+	return s.index.Index(doc)
+}
+
+// RemoveArticle drops a deleted article from the index
+func (s *SearchService) RemoveArticle(articleID string) {
+	if err := s.index.Remove(articleID); err != nil {
+		log.Printf("Failed to remove article %s from the search index: %v", articleID, err)
+	}
+}
+
+// Refresh indexes articles again after they changed. A failure only makes
+// search results stale, so it is logged rather than failing the change.
+func (s *SearchService) Refresh(articleIDs ...string) {
+	for _, id := range articleIDs {
+		if err := s.IndexArticle(id); err != nil {
+			log.Printf("Failed to index article %s: %v", id, err)
+		}
+	}
+}
+
+// IndexStale indexes the articles that are missing from the index, such as
+// articles saved before it existed, or that were indexed by an older
+// tokenizer
+func (s *SearchService) IndexStale() error {
+	indexed, failed := 0, 0
+	after := ""
+	for {
+		ids, err := s.index.Stale(after, reindexBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to find articles to index: %w", err)
+		}
+		if len(ids) == 0 {
+			break
+		}
+		after = ids[len(ids)-1]
+
+		for _, id := range ids {
+			if err := s.IndexArticle(id); err != nil {
+				log.Printf("Failed to index article %s: %v", id, err)
+				failed++
+				continue
+			}
+			indexed++
+		}
+	}
+
+	if indexed > 0 || failed > 0 {
+		log.Printf("Indexed %d articles for search, %d failed", indexed, failed)
+	}
+	return nil
+}
+
+// Search returns a page of the user's articles matching query, ranked by
+// relevance. The page starts after cursor when it is set, see
+// SearchResult.NextCursor, and at page otherwise. Scores change as articles
+// are indexed, so a cursor does not guarantee that no hit is repeated or
+// skipped, only that the listing moves on.
+func (s *SearchService) Search(userID, query, cursor string, page, limit int) (*SearchResult, error) {
+	q := search.Query{
+		Text:   query,
+		Offset: (page - 1) * limit,
+		// One more hit than asked for tells whether there is a next page
+		Limit: limit + 1,
+	}
+	if cursor != "" {
+		after, err := decodeSearchCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		q.After = after
+	}
+
+	results, err := s.index.Search(userID, q)
+	if err != nil {
+		return nil, err
+	}
+	var nextCursor string
+	if len(results.Hits) > limit {
+		results.Hits = results.Hits[:limit]
+		last := results.Hits[limit-1]
+		data, _ := json.Marshal(searchCursor{Score: last.Score, ArticleID: last.ArticleID})
+		nextCursor = base64.RawURLEncoding.EncodeToString(data)
+	}
+
+	ids := make([]string, len(results.Hits))
+	for i, hit := range results.Hits {
+		ids[i] = hit.ArticleID
+	}
+	articles, err := s.articleRepo.GetByIDsWithAssociations(userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*models.Article, len(articles))
+	for _, article := range articles {
+		byID[article.ID] = article
+	}
+
+	result := &SearchResult{Hits: []SearchHit{}, Total: results.Total, NextCursor: nextCursor}
+	for _, hit := range results.Hits {
+		// Articles deleted since the search ran are left out
+		article, ok := byID[hit.ArticleID]
+		if !ok {
+			continue
+		}
+		result.Hits = append(result.Hits, SearchHit{
+			Article: article,
+			Score:   hit.Score,
+			Highlights: SearchHighlights{
+				Title:   search.Highlight(article.Title, results.Terms),
+				Snippet: snippet(article, results.Terms),
+			},
+		})
+	}
+	return result, nil
+}
+
+// decodeSearchCursor reads a cursor made by Search
+func decodeSearchCursor(cursor string) (*search.Hit, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, repositories.ErrInvalidCursor
+	}
+	var c searchCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ArticleID == "" {
+		return nil, repositories.ErrInvalidCursor
+	}
+	return &search.Hit{ArticleID: c.ArticleID, Score: c.Score}, nil
+}
+
+// snippet shows where the article matched: the content, else the summary,
+// else the beginning of whichever of them the article has
+func snippet(article *models.Article, terms []string) string {
+	var fallback string
+	for _, text := range []*string{article.Content, article.Summary} {
+		if text == nil {
+			continue
+		}
+		snippet, matched := search.Snippet(*text, terms, snippetLength)
+		if matched {
+			return snippet
+		}
+		if fallback == "" {
+			fallback = snippet
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,275 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"github.com/eikuma/stockle/backend/internal/extractor"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed site_rules.yaml
+var builtinSiteRules []byte
+
+// Pagination limits of site rules
+const (
+	defaultMaxPages = 5
+	maxMaxPages     = 20
+)
+
+// siteRulesFile is the layout of a site rules YAML file, documented in site_rules.yaml
+type siteRulesFile struct {
+	Sites []*siteRule `yaml:"sites"`
+}
+
+// siteRule is a declarative SiteExtractor
+type siteRule struct {
+	RuleName     string   `yaml:"name"`
+	HostPatterns []string `yaml:"hosts"`
+	Title        string   `yaml:"title"`
+	Content      string   `yaml:"content"`
+	Author       string   `yaml:"author"`
+	Published    string   `yaml:"published"`
+	DateFormat   string   `yaml:"date_format"`
+	Strip        []string `yaml:"strip"`
+	NextPage     string   `yaml:"next_page"`
+	MaxPages     int      `yaml:"max_pages"`
+	Language     string   `yaml:"language"`
+}
+
+// attrSuffix matches the "@attr" suffix of a value selector
+var attrSuffix = regexp.MustCompile(`@([A-Za-z_:][-A-Za-z0-9_:.]*)$`)
+
+// parseSiteRules decodes and validates the rules in data. source names the
+// file in error messages.
+func parseSiteRules(data []byte, source string) ([]*siteRule, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	var file siteRulesFile
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid site rules in %s: %w", source, err)
+	}
+	for i, rule := range file.Sites {
+		if err := rule.validate(); err != nil {
+			name := rule.RuleName
+			if name == "" {
+				name = fmt.Sprintf("#%d", i+1)
+			}
+			return nil, fmt.Errorf("invalid site rule %s in %s: %w", name, source, err)
+		}
+	}
+	return file.Sites, nil
+}
+
+func (r *siteRule) validate() error {
+	if strings.TrimSpace(r.RuleName) == "" {
+		return errors.New("name is required")
+	}
+	if len(r.HostPatterns) == 0 {
+		return errors.New("at least one host is required")
+	}
+	for _, host := range r.HostPatterns {
+		if strings.Trim(strings.TrimPrefix(strings.TrimSpace(host), "*."), ".") == "" || strings.ContainsAny(host, "/:") {
+			return fmt.Errorf("invalid host %q", host)
+		}
+	}
+	if strings.TrimSpace(r.Content) == "" {
+		return errors.New("content selector is required")
+	}
+	if r.MaxPages < 0 || r.MaxPages > maxMaxPages {
+		return fmt.Errorf("max_pages must be between 1 and %d", maxMaxPages)
+	}
+
+	selectors := map[string]string{"content": r.Content, "next_page": r.NextPage}
+	for field, value := range map[string]string{"title": r.Title, "author": r.Author, "published": r.Published} {
+		selectors[field], _ = splitValueSelector(value)
+	}
+	for i, strip := range r.Strip {
+		selectors[fmt.Sprintf("strip[%d]", i)] = strip
+	}
+	for field, selector := range selectors {
+		if selector == "" {
+			continue
+		}
+		if _, err := cascadia.ParseGroup(selector); err != nil {
+			return fmt.Errorf("invalid %s selector %q: %w", field, selector, err)
+		}
+	}
+	return nil
+}
+
+// Name implements SiteExtractor
+func (r *siteRule) Name() string {
+	return r.RuleName
+}
+
+// Hosts implements SiteExtractor
+func (r *siteRule) Hosts() []string {
+	return r.HostPatterns
+}
+
+// Extract implements SiteExtractor. The page metadata is used for everything
+// the rule does not select, and the generic extraction when the content
+// selector matches nothing, e.g. after the site changed its layout.
+func (r *siteRule) Extract(ctx context.Context, doc *goquery.Document, fetch FetchFunc) (*ArticleMetadata, error) {
+	metadata := metadataFromDocument(doc)
+	metadata.Extractor = r.RuleName
+	if r.Language != "" {
+		metadata.Language = r.Language
+	}
+
+	if title := selectValue(doc.Selection, r.Title); title != "" {
+		metadata.Title = extractor.TruncateRunes(title, maxTitleRunes)
+	}
+	if author := selectValue(doc.Selection, r.Author); author != "" {
+		metadata.Authors = []string{author}
+		metadata.Author = extractor.TruncateRunes(author, maxAuthorRunes)
+	}
+	if published := selectValue(doc.Selection, r.Published); published != "" {
+		if t, ok := parsePublishedAt(published, r.DateFormat); ok {
+			metadata.PublishedAt = &t
+		} else {
+			metadata.Warnings = append(metadata.Warnings, fmt.Sprintf("could not parse published date %q", published))
+		}
+	}
+
+	content := doc.Find(r.Content).First()
+	if content.Length() == 0 {
+		metadata.Warnings = append(metadata.Warnings, fmt.Sprintf("content selector %q matched nothing; used generic extraction", r.Content))
+		setContent(metadata, extractor.Extract(doc, doc.Url))
+		metadata.Pages = 1
+		return metadata, nil
+	}
+
+	pages := []extractor.Page{{Selection: r.strip(content), URL: doc.Url}}
+	if r.NextPage != "" && fetch != nil {
+		more, warnings, err := r.followPages(ctx, doc, fetch)
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, more...)
+		metadata.Warnings = append(metadata.Warnings, warnings...)
+	}
+
+	setContent(metadata, extractor.FromPages(pages...))
+	metadata.Pages = len(pages)
+	return metadata, nil
+}
+
+// followPages fetches the next pages of the article on the same host. A page
+// that cannot be fetched ends the article with a warning instead of failing
+// the extraction, unless ctx is done.
+func (r *siteRule) followPages(ctx context.Context, doc *goquery.Document, fetch FetchFunc) ([]extractor.Page, []string, error) {
+	maxPages := r.MaxPages
+	if maxPages == 0 {
+		maxPages = defaultMaxPages
+	}
+
+	var pages []extractor.Page
+	var warnings []string
+	visited := map[string]bool{doc.Url.String(): true}
+	current := doc
+	for len(pages)+1 < maxPages {
+		next := nextPageURL(current, r.NextPage)
+		if next == nil || visited[next.String()] {
+			break
+		}
+		if !strings.EqualFold(next.Hostname(), doc.Url.Hostname()) {
+			warnings = append(warnings, fmt.Sprintf("not following next page on another host: %s", next))
+			break
+		}
+		visited[next.String()] = true
+
+		nextDoc, err := fetch(ctx, next.String())
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, nil, err
+			}
+			warnings = append(warnings, fmt.Sprintf("stopped at page %d: %v", len(pages)+2, err))
+			break
+		}
+		content := nextDoc.Find(r.Content).First()
+		if content.Length() == 0 {
+			warnings = append(warnings, fmt.Sprintf("stopped at page %d: content selector matched nothing", len(pages)+2))
+			break
+		}
+		pages = append(pages, extractor.Page{Selection: r.strip(content), URL: nextDoc.Url})
+		current = nextDoc
+	}
+	return pages, warnings, nil
+}
+
+// strip returns a copy of content without the stripped elements
+func (r *siteRule) strip(content *goquery.Selection) *goquery.Selection {
+	if len(r.Strip) == 0 {
+		return content
+	}
+	content = content.Clone()
+	for _, selector := range r.Strip {
+		content.Find(selector).Remove()
+	}
+	return content
+}
+
+// nextPageURL resolves the href of the next page link against the document URL
+func nextPageURL(doc *goquery.Document, selector string) *url.URL {
+	href, ok := doc.Find(selector).First().Attr("href")
+	href = strings.TrimSpace(href)
+	if !ok || href == "" || strings.HasPrefix(href, "#") {
+		return nil
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return nil
+	}
+	next := doc.Url.ResolveReference(ref)
+	next.Fragment = ""
+	if next.Scheme != "http" && next.Scheme != "https" {
+		return nil
+	}
+	return next
+}
+
+// splitValueSelector splits "selector@attr" into the selector and the attribute
+func splitValueSelector(value string) (string, string) {
+	value = strings.TrimSpace(value)
+	if loc := attrSuffix.FindStringSubmatchIndex(value); loc != nil {
+		return strings.TrimSpace(value[:loc[0]]), value[loc[2]:loc[3]]
+	}
+	return value, ""
+}
+
+// selectValue returns the cleaned text or attribute selected by value
+func selectValue(root *goquery.Selection, value string) string {
+	selector, attr := splitValueSelector(value)
+	if selector == "" {
+		return ""
+	}
+	selection := root.Find(selector).First()
+	if attr != "" {
+		return extractor.CleanText(selection.AttrOr(attr, ""))
+	}
+	return extractor.CleanText(selection.Text())
+}
+
+// parsePublishedAt parses value with layout when the rule has one, and
+// leniently otherwise
+func parsePublishedAt(value, layout string) (time.Time, bool) {
+	if layout != "" {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return extractor.ParseDate(value)
+}
@@ -0,0 +1,335 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/eikuma/stockle/backend/internal/models"
+	"github.com/eikuma/stockle/backend/pkg/llm"
+)
+
+const (
+	// defaultContextWindow is assumed for the providers configured without
+	// one, the window of the smaller open models
+	defaultContextWindow = 8192
+	// defaultMaxTokens is assumed for the answers of the providers
+	// configured without a token limit
+	defaultMaxTokens = 1024
+	// maxReduceLevels bounds the rounds of summarizing summaries that do
+	// not fit in one prompt together
+	maxReduceLevels = 3
+	// maxHeadingRunes is the length of the longest paragraph taken for a
+	// heading
+	maxHeadingRunes = 60
+)
+
+const chunkSystemPrompt = "あなたは長い記事の一部を要約する専門家です。与えられた部分の重要な事実、主張、数値を漏らさず、200-400文字で要約してください。他の部分の内容を推測しないでください。"
+
+// inputBudget returns the number of tokens of a prompt that every provider
+// of the chain can read while leaving room for its answer
+func inputBudget(chain []*aiProvider) int {
+	budget := 0
+	for i, provider := range chain {
+		window := provider.contextWindow
+		if window <= 0 {
+			window = defaultContextWindow
+		}
+		maxTokens := provider.maxTokens
+		if maxTokens <= 0 {
+			maxTokens = defaultMaxTokens
+		}
+		// the estimate of the prompt is rough; keep a tenth of the window
+		// spare
+		available := window - maxTokens - window/10
+		if i == 0 || available < budget {
+			budget = available
+		}
+	}
+	if budget < 256 {
+		budget = 256
+	}
+	return budget
+}
+
+// generateMapReduce summarizes an article too long for one prompt: the
+// content is split into chunks along paragraphs, the chunks are summarized
+// in parallel and their summaries are reduced to a summary of the requested
+// type
+func (s *AIService) generateMapReduce(ctx context.Context, chain []*aiProvider, req *SummaryRequest, budget int) (*SummaryResponse, error) {
+	overhead := llm.EstimateTokens(chunkSystemPrompt) + llm.EstimateTokens(s.buildChunkPrompt(req, 0, 0, ""))
+	size := budget - overhead
+	if s.config.ChunkTokens > 0 && s.config.ChunkTokens < size {
+		size = s.config.ChunkTokens
+	}
+
+	chunks := splitContent(req.Content, size)
+	partials, err := s.summarizeChunks(ctx, chain, req, chunks)
+	if err != nil {
+		return nil, err
+	}
+
+	// 部分要約がまとめて収まらない場合は、さらに要約を重ねる
+	system := s.getSystemPrompt(req.SummaryType)
+	for level := 0; level < maxReduceLevels && len(partials) > 1; level++ {
+		reduceOverhead := llm.EstimateTokens(system) + llm.EstimateTokens(s.buildReducePrompt(req, nil))
+		if reduceOverhead+llm.EstimateTokens(strings.Join(partials, "\n\n")) <= budget {
+			break
+		}
+		groups := splitContent(strings.Join(partials, "\n\n"), size)
+		if len(groups) >= len(partials) {
+			break
+		}
+		if partials, err = s.summarizeChunks(ctx, chain, req, groups); err != nil {
+			return nil, err
+		}
+	}
+
+	completion, provider, err := s.complete(ctx, chain, system, s.buildReducePrompt(req, partials))
+	if err != nil {
+		return nil, err
+	}
+	return s.summaryResponse(completion, provider, models.SummaryStrategyMapReduce, len(chunks)), nil
+}
+
+// summarizeChunks summarizes the chunks with at most ChunkConcurrency
+// requests at once, returning the summaries in the order of the chunks. The
+// first failure cancels the chunks not summarized yet.
+func (s *AIService) summarizeChunks(ctx context.Context, chain []*aiProvider, req *SummaryRequest, chunks []string) ([]string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	concurrency := s.config.ChunkConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	summaries := make([]string, len(chunks))
+	errs := make([]error, len(chunks))
+	var wg sync.WaitGroup
+
+	for i, chunk := range chunks {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+		}
+		if errs[i] != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(i int, chunk string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			completion, _, err := s.complete(ctx, chain, chunkSystemPrompt, s.buildChunkPrompt(req, i, len(chunks), chunk))
+			if err != nil {
+				errs[i] = err
+				cancel()
+				return
+			}
+			summaries[i] = strings.TrimSpace(completion.Text)
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	// report the failure that cancelled the others rather than a
+	// cancellation
+	var canceled error
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, context.Canceled) {
+			return nil, fmt.Errorf("failed to summarize chunk %d of %d: %w", i+1, len(chunks), err)
+		}
+		if canceled == nil {
+			canceled = err
+		}
+	}
+	if canceled != nil {
+		return nil, canceled
+	}
+	return summaries, nil
+}
+
+func (s *AIService) buildChunkPrompt(req *SummaryRequest, index, count int, chunk string) string {
+	return fmt.Sprintf(`記事タイトル: %s
+
+以下は記事を%d個に分けた第%d部分です。
+
+%s
+
+上記の部分を要約してください。%s`, req.Title, count, index+1, chunk, s.languageInstruction(req))
+}
+
+func (s *AIService) buildReducePrompt(req *SummaryRequest, partials []string) string {
+	var parts strings.Builder
+	for i, partial := range partials {
+		fmt.Fprintf(&parts, "[第%d部分の要約]\n%s\n\n", i+1, partial)
+	}
+	return fmt.Sprintf(`記事タイトル: %s
+
+記事URL: %s
+
+以下は記事を分割して要約した各部分の要約です。
+
+%s上記をまとめて、記事全体を%sで要約してください。%s`, req.Title, req.URL, parts.String(), s.getSummaryTypeDescription(req.SummaryType), s.languageInstruction(req))
+}
+
+// splitContent splits text into chunks of at most maxTokens estimated
+// tokens. Chunks end at paragraph boundaries, preferably before a heading;
+// paragraphs too long for a chunk are split between sentences.
+func splitContent(text string, maxTokens int) []string {
+	if maxTokens < 1 {
+		maxTokens = 1
+	}
+
+	var blocks []string
+	for _, paragraph := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" {
+			continue
+		}
+		if llm.EstimateTokens(paragraph) > maxTokens {
+			blocks = append(blocks, splitParagraph(paragraph, maxTokens)...)
+		} else {
+			blocks = append(blocks, paragraph)
+		}
+	}
+
+	var chunks []string
+	var current []string
+	currentTokens := 0
+	flush := func() {
+		if len(current) > 0 {
+			chunks = append(chunks, strings.Join(current, "\n\n"))
+		}
+		current = nil
+		currentTokens = 0
+	}
+	for _, block := range blocks {
+		tokens := llm.EstimateTokens(block)
+		separator := 0
+		if len(current) > 0 {
+			separator = 1
+		}
+
+		switch {
+		case currentTokens+separator+tokens > maxTokens:
+			// a heading closing the chunk moves to the next one with its
+			// section
+			var heading string
+			if last := len(current) - 1; last > 0 && isHeading(current[last]) {
+				heading = current[last]
+				current = current[:last]
+			}
+			flush()
+			if heading != "" && llm.EstimateTokens(heading)+1+tokens <= maxTokens {
+				current = []string{heading}
+				currentTokens = llm.EstimateTokens(heading) + 1
+			} else if heading != "" {
+				chunks = append(chunks, heading)
+			}
+		case isHeading(block) && currentTokens >= maxTokens/2:
+			flush()
+		}
+
+		current = append(current, block)
+		currentTokens += tokens
+		if len(current) > 1 {
+			currentTokens++
+		}
+	}
+	flush()
+	return chunks
+}
+
+// splitParagraph splits a paragraph longer than maxTokens between
+// sentences, and sentences still too long between characters
+func splitParagraph(paragraph string, maxTokens int) []string {
+	var parts []string
+	var current strings.Builder
+	currentTokens := 0
+	add := func(piece string) {
+		tokens := llm.EstimateTokens(piece)
+		if currentTokens+tokens > maxTokens && current.Len() > 0 {
+			parts = append(parts, strings.TrimSpace(current.String()))
+			current.Reset()
+			currentTokens = 0
+		}
+		current.WriteString(piece)
+		currentTokens += tokens
+	}
+
+	for _, sentence := range splitSentences(paragraph) {
+		if llm.EstimateTokens(sentence) <= maxTokens {
+			add(sentence)
+			continue
+		}
+		for len(sentence) > 0 {
+			piece := sentence
+			for llm.EstimateTokens(piece) > maxTokens {
+				piece = piece[:len(piece)/2]
+				for !utf8.ValidString(piece) {
+					piece = piece[:len(piece)-1]
+				}
+			}
+			if piece == "" {
+				_, size := utf8.DecodeRuneInString(sentence)
+				piece = sentence[:size]
+			}
+			add(piece)
+			sentence = sentence[len(piece):]
+		}
+	}
+	if current.Len() > 0 {
+		parts = append(parts, strings.TrimSpace(current.String()))
+	}
+	return parts
+}
+
+// splitSentences splits text after the sentence ending punctuation, keeping
+// the punctuation and the spaces following it with the sentence
+func splitSentences(text string) []string {
+	var sentences []string
+	start := 0
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		switch runes[i] {
+		case '。', '！', '？', '.', '!', '?', '\n':
+			end := i + 1
+			for end < len(runes) && (runes[end] == ' ' || runes[end] == '\n') {
+				end++
+			}
+			if runes[i] == '.' && end == i+1 && end < len(runes) {
+				// a dot inside a word or a number
+				continue
+			}
+			sentences = append(sentences, string(runes[start:end]))
+			start = end
+			i = end - 1
+		}
+	}
+	if start < len(runes) {
+		sentences = append(sentences, string(runes[start:]))
+	}
+	return sentences
+}
+
+// isHeading reports whether a paragraph looks like a heading of the plain
+// text of an article: a short single line without closing punctuation
+func isHeading(paragraph string) bool {
+	if strings.HasPrefix(paragraph, "#") {
+		return true
+	}
+	if strings.Contains(paragraph, "\n") || utf8.RuneCountInString(paragraph) > maxHeadingRunes {
+		return false
+	}
+	last, _ := utf8.DecodeLastRuneInString(paragraph)
+	return !strings.ContainsRune("。．.!?！？:：、,", last)
+}
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eikuma/stockle/backend/internal/config"
+	"github.com/eikuma/stockle/backend/internal/models"
+	"github.com/eikuma/stockle/backend/pkg/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var chunkNumber = regexp.MustCompile(`第(\d+)部分です`)
+
+// fakeLLM answers deterministically from the prompt: chunks get
+// "部分要約N" (or partial, when set), reductions "最終要約" and single
+// prompts "単一要約"
+type fakeLLM struct {
+	mu      sync.Mutex
+	calls   []llm.CompletionRequest
+	current int
+	maxSeen int
+
+	// partial, when set, is the summary of every chunk
+	partial string
+	// contextLimit rejects the prompts estimated longer with
+	// ErrContextLength, as a model with a smaller window than configured
+	contextLimit int
+	// failChunk fails the chunk with this number
+	failChunk int
+}
+
+func (f *fakeLLM) Name() string {
+	return "fake"
+}
+
+func (f *fakeLLM) Complete(ctx context.Context, req *llm.CompletionRequest) (*llm.Completion, error) {
+	f.mu.Lock()
+	f.calls = append(f.calls, *req)
+	f.current++
+	if f.current > f.maxSeen {
+		f.maxSeen = f.current
+	}
+	f.mu.Unlock()
+	defer func() {
+		f.mu.Lock()
+		f.current--
+		f.mu.Unlock()
+	}()
+	// let the concurrent requests overlap
+	time.Sleep(2 * time.Millisecond)
+
+	prompt := req.Messages[0].Content
+	if f.contextLimit > 0 && llm.EstimateTokens(req.System)+llm.EstimateTokens(prompt) > f.contextLimit {
+		return nil, &llm.APIError{Kind: llm.ErrContextLength, StatusCode: 400}
+	}
+	switch {
+	case req.System == chunkSystemPrompt:
+		n := chunkNumber.FindStringSubmatch(prompt)[1]
+		if fmt.Sprint(f.failChunk) == n {
+			return nil, &llm.APIError{Kind: llm.ErrInvalidRequest, StatusCode: 400}
+		}
+		if f.partial != "" {
+			return &llm.Completion{Text: f.partial, Model: req.Model}, nil
+		}
+		return &llm.Completion{Text: "部分要約" + n, Model: req.Model}, nil
+	case strings.Contains(prompt, "各部分の要約"):
+		return &llm.Completion{Text: "最終要約", Model: req.Model}, nil
+	default:
+		return &llm.Completion{Text: "単一要約", Model: req.Model}, nil
+	}
+}
+
+// prompts returns the prompts sent with a system prompt
+func (f *fakeLLM) prompts(system string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var prompts []string
+	for _, call := range f.calls {
+		if call.System == system {
+			prompts = append(prompts, call.Messages[0].Content)
+		}
+	}
+	return prompts
+}
+
+func newFakeLLMService(t *testing.T, fake *fakeLLM, cfg *config.AIConfig) *AIService {
+	t.Helper()
+	if cfg.Providers == nil {
+		cfg.Providers = []config.AIProviderConfig{
+			{Name: "fake", Type: config.AIProviderOpenAI, Model: "fake-model", MaxTokens: 256, ContextWindow: 2048},
+		}
+	}
+	service, err := newAIService(cfg, func(config.AIProviderConfig) (llm.LLMProvider, error) {
+		return fake, nil
+	})
+	require.NoError(t, err)
+	return service
+}
+
+// longArticle returns sections of a heading and paragraphs of Japanese
+// sentences, about 500 estimated tokens a section
+func longArticle(sections int) string {
+	var b strings.Builder
+	for i := 1; i <= sections; i++ {
+		fmt.Fprintf(&b, "見出し%d\n\n", i)
+		for p := 1; p <= 3; p++ {
+			fmt.Fprintf(&b, "段落%d-%d。%s\n\n", i, p, strings.Repeat("これは長い記事の本文です。", 12))
+		}
+	}
+	return b.String()
+}
+
+func TestAIService_ShortArticleSingle(t *testing.T) {
+	fake := &fakeLLM{}
+	service := newFakeLLMService(t, fake, &config.AIConfig{})
+
+	result, err := service.GenerateSummary(context.Background(), newTestSummaryRequest("ja", "短い記事です。"))
+	require.NoError(t, err)
+	assert.Equal(t, "単一要約", result.Summary)
+	assert.Equal(t, models.SummaryStrategySingle, result.Strategy)
+	assert.Equal(t, 1, result.Chunks)
+	assert.Len(t, fake.calls, 1)
+}
+
+func TestAIService_MapReduce(t *testing.T) {
+	fake := &fakeLLM{}
+	service := newFakeLLMService(t, fake, &config.AIConfig{ChunkConcurrency: 2})
+	req := newTestSummaryRequest("ja", longArticle(12))
+	req.SummaryType = "long"
+
+	result, err := service.GenerateSummary(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "最終要約", result.Summary)
+	assert.Equal(t, models.SummaryStrategyMapReduce, result.Strategy)
+	assert.Equal(t, "fake-model", result.ModelVersion)
+
+	chunkPrompts := fake.prompts(chunkSystemPrompt)
+	require.Greater(t, len(chunkPrompts), 1)
+	assert.Equal(t, len(chunkPrompts), result.Chunks)
+	budget := inputBudget(service.chain)
+	for _, prompt := range chunkPrompts {
+		assert.LessOrEqual(t, llm.EstimateTokens(chunkSystemPrompt)+llm.EstimateTokens(prompt), budget)
+	}
+	assert.LessOrEqual(t, fake.maxSeen, 2, "chunks are summarized with bounded concurrency")
+	assert.Equal(t, 2, fake.maxSeen, "chunks are summarized in parallel")
+
+	// the reduction asks for the requested summary type with the partial
+	// summaries in the order of the chunks
+	reducePrompts := fake.prompts(service.getSystemPrompt("long"))
+	require.Len(t, reducePrompts, 1)
+	last := -1
+	for i := 1; i <= len(chunkPrompts); i++ {
+		at := strings.Index(reducePrompts[0], fmt.Sprintf("部分要約%d\n", i))
+		require.Greater(t, at, last, "partial summary %d", i)
+		last = at
+	}
+	assert.Contains(t, reducePrompts[0], service.getSummaryTypeDescription("long"))
+}
+
+func TestAIService_MapReduceUsesSmallestWindowOfChain(t *testing.T) {
+	fake := &fakeLLM{}
+	article := longArticle(12)
+	service := newFakeLLMService(t, fake, &config.AIConfig{
+		Providers: []config.AIProviderConfig{
+			{Name: "large", Type: config.AIProviderAnthropic, MaxTokens: 500, ContextWindow: 200000},
+			{Name: "small", Type: config.AIProviderOpenAI, MaxTokens: 500, ContextWindow: 4096},
+		},
+	})
+
+	result, err := service.GenerateSummary(context.Background(), newTestSummaryRequest("ja", article))
+	require.NoError(t, err)
+	assert.Equal(t, models.SummaryStrategyMapReduce, result.Strategy, "the fallback provider could not read the article")
+}
+
+func TestAIService_ContextLengthFallsBackToChunks(t *testing.T) {
+	fake := &fakeLLM{contextLimit: 1500}
+	service := newFakeLLMService(t, fake, &config.AIConfig{
+		Providers: []config.AIProviderConfig{
+			{Name: "fake", Type: config.AIProviderOpenAI, ContextWindow: 32768},
+		},
+		ChunkTokens: 1000,
+	})
+
+	result, err := service.GenerateSummary(context.Background(), newTestSummaryRequest("ja", longArticle(8)))
+	require.NoError(t, err)
+	assert.Equal(t, "最終要約", result.Summary)
+	assert.Equal(t, models.SummaryStrategyMapReduce, result.Strategy)
+	assert.Greater(t, result.Chunks, 1)
+}
+
+func TestAIService_ChunkFailure(t *testing.T) {
+	fake := &fakeLLM{failChunk: 3}
+	service := newFakeLLMService(t, fake, &config.AIConfig{ChunkConcurrency: 2})
+
+	_, err := service.GenerateSummary(context.Background(), newTestSummaryRequest("ja", longArticle(12)))
+	require.Error(t, err)
+	assert.ErrorIs(t, err, llm.ErrInvalidRequest)
+	assert.Contains(t, err.Error(), "failed to summarize chunk 3 of")
+	assert.Empty(t, fake.prompts(service.getSystemPrompt("short")), "no reduction after a failed chunk")
+}
+
+func TestAIService_HierarchicalReduce(t *testing.T) {
+	// partial summaries too long to reduce in one prompt
+	fake := &fakeLLM{partial: strings.Repeat("部分要約の内容です。", 30)}
+	service := newFakeLLMService(t, fake, &config.AIConfig{})
+	article := longArticle(24)
+
+	result, err := service.GenerateSummary(context.Background(), newTestSummaryRequest("ja", article))
+	require.NoError(t, err)
+	assert.Equal(t, "最終要約", result.Summary)
+
+	chunkPrompts := fake.prompts(chunkSystemPrompt)
+	assert.Greater(t, len(chunkPrompts), result.Chunks, "the partial summaries were summarized again")
+	reducePrompts := fake.prompts(service.getSystemPrompt("short"))
+	require.Len(t, reducePrompts, 1)
+	assert.LessOrEqual(t, llm.EstimateTokens(service.getSystemPrompt("short"))+llm.EstimateTokens(reducePrompts[0]), inputBudget(service.chain))
+}
+
+func TestSplitContent(t *testing.T) {
+	t.Run("keeps every paragraph in order", func(t *testing.T) {
+		article := longArticle(6)
+		chunks := splitContent(article, 700)
+		require.Greater(t, len(chunks), 1)
+		for _, chunk := range chunks {
+			assert.LessOrEqual(t, llm.EstimateTokens(chunk), 700)
+		}
+		assert.Equal(t, strings.Join(strings.Fields(article), ""), strings.Join(strings.Fields(strings.Join(chunks, "")), ""))
+	})
+
+	t.Run("starts chunks at headings", func(t *testing.T) {
+		chunks := splitContent(longArticle(6), 1200)
+		for i, chunk := range chunks {
+			assert.True(t, strings.HasPrefix(chunk, "見出し"), "chunk %d starts with %q", i, chunk[:20])
+		}
+	})
+
+	t.Run("splits long paragraphs between sentences", func(t *testing.T) {
+		paragraph := strings.Repeat("一文目の文章です。", 100)
+		chunks := splitContent(paragraph, 100)
+		require.Greater(t, len(chunks), 1)
+		for _, chunk := range chunks {
+			assert.LessOrEqual(t, llm.EstimateTokens(chunk), 100)
+			assert.True(t, strings.HasSuffix(chunk, "。"), chunk)
+		}
+	})
+
+	t.Run("splits sentences without punctuation", func(t *testing.T) {
+		text := strings.Repeat("あ", 250)
+		chunks := splitContent(text, 100)
+		assert.Equal(t, text, strings.Join(chunks, ""))
+		for _, chunk := range chunks {
+			assert.LessOrEqual(t, llm.EstimateTokens(chunk), 100)
+		}
+	})
+
+	t.Run("keeps decimal points inside sentences", func(t *testing.T) {
+		assert.Equal(t, []string{"Version 1.5 is out. ", "It is fast."}, splitSentences("Version 1.5 is out. It is fast."))
+	})
+}
@@ -0,0 +1,338 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/eikuma/stockle/backend/internal/models"
+	"github.com/eikuma/stockle/backend/pkg/llm"
+)
+
+// Penalties subtracted from the score of a summary for each issue. A refusal
+// scores 0.
+const (
+	lengthPenalty      = 0.2
+	boilerplatePenalty = 0.1
+	languagePenalty    = 0.6
+	// unsupportedPenalty is scaled by the share of the facts checked that
+	// the article does not contain
+	unsupportedPenalty = 0.5
+)
+
+// maxRefusalLength is the length, in characters, up to which a reply opening
+// with a refusal is a refusal whatever its structure
+const maxRefusalLength = 200
+
+const judgeSystemPrompt = "あなたは記事の要約を評価する専門家です。要約が記事の内容に忠実か（記事にない事実を含まないか）、主要なポイントを押さえているかを評価し、1（悪い）から5（優れている）の整数だけを答えてください。"
+
+// SummaryValidation is the quality of a summary checked against its article
+type SummaryValidation struct {
+	// Score is from 0 to 1
+	Score  float64
+	Issues []string
+	// Unsupported are the numbers and names of the summary that the
+	// article does not contain
+	Unsupported []string
+}
+
+var (
+	boilerplatePrefixes = []string{
+		"here is a summary", "here's a summary", "here is the summary", "here's the summary", "summary:",
+		"以下は要約です", "以下が要約です", "以下は記事の要約です", "要約：", "要約:",
+	}
+
+	// refusalOpening and refusalOpeningJA match a reply opening by
+	// declining the task, after an optional apology: "I'm sorry, but I
+	// cannot summarize", "申し訳ありませんが、この記事は要約できません"
+	refusalOpening = regexp.MustCompile(`^(?:(?:i'm sorry|i am sorry|sorry|unfortunately|as an ai[^,.]*)[,.]?\s*(?:but\s+)?)*` +
+		`(?:i\s+(?:cannot|can't|can\s+not|am\s+unable\s+to|'m\s+unable\s+to|am\s+not\s+able\s+to|'m\s+not\s+able\s+to|won't|will\s+not)|unable\s+to)` +
+		`\s+(?:\S+\s+){0,2}?(?:summar|help|assist|access|provide|comply|fulfil|complete)`)
+	refusalOpeningJA = regexp.MustCompile(`^(?:申し訳(?:ありません|ございません)|aiとして)?[^。！？\n]{0,40}?` +
+		`(?:要約|お手伝い|対応|お応え|アクセス)(?:すること|いたすこと)?[はがを]?(?:でき(?:ません|ない)|いたしかねます|しかねます)`)
+
+	summaryNumber = regexp.MustCompile(`\d+(?:[.,]\d+)*`)
+	latinName     = regexp.MustCompile(`[A-Z][A-Za-z0-9]+`)
+	katakanaRun   = regexp.MustCompile(`[\p{Katakana}ー]{3,}`)
+	// judgeRating is the answer of the judge: a single rating, optionally
+	// after a label as "評価: 4" and with a period
+	judgeRating = regexp.MustCompile(`^(?:[^\d\n]{0,20}[:：]\s*)?([1-5])\s*[.。]?$`)
+)
+
+// validateSummary scores a summary of the request: its length for the
+// summary type, its language, refusals and boilerplate, the numbers and
+// names it does not take from the article and, with a judge provider
+// configured, the score the judge gives it
+func (s *AIService) validateSummary(ctx context.Context, req *SummaryRequest, summary string) SummaryValidation {
+	var v SummaryValidation
+	if isRefusal(summary) {
+		v.Issues = append(v.Issues, models.SummaryIssueRefusal)
+		return v
+	}
+
+	language := req.OutputLanguage
+	if language == "" {
+		language = req.Language
+	}
+
+	score := 1.0
+	if issue := checkSummaryLength(req.SummaryType, language, summary); issue != "" {
+		v.Issues = append(v.Issues, issue)
+		score -= lengthPenalty
+	}
+	if !matchesScript(language, summary) {
+		v.Issues = append(v.Issues, models.SummaryIssueLanguage)
+		score -= languagePenalty
+	}
+	if hasBoilerplate(summary) {
+		v.Issues = append(v.Issues, models.SummaryIssueBoilerplate)
+		score -= boilerplatePenalty
+	}
+	// names are written differently in a translation, so only numbers are
+	// checked then
+	sameLanguage := req.OutputLanguage == "" || matchesLanguage([]string{req.OutputLanguage}, req.Language)
+	checked, unsupported := unsupportedFacts(summary, req.Content, sameLanguage)
+	if len(unsupported) > 0 {
+		v.Issues = append(v.Issues, models.SummaryIssueUnsupportedFact)
+		v.Unsupported = unsupported
+		score -= unsupportedPenalty * float64(len(unsupported)) / float64(checked)
+	}
+
+	if s.judge != nil {
+		judged, err := s.judgeSummary(ctx, req, summary)
+		if err != nil {
+			// the heuristics alone score the summary
+			log.Printf("Failed to judge summary of %s: %v", req.URL, err)
+		} else {
+			if judged < 0.5 {
+				v.Issues = append(v.Issues, models.SummaryIssueLowJudgeScore)
+			}
+			score = (score + judged) / 2
+		}
+	}
+
+	v.Score = clampScore(score)
+	return v
+}
+
+// judgeSummary asks the judge provider to rate the summary, returning the
+// rating scaled from 0 to 1
+func (s *AIService) judgeSummary(ctx context.Context, req *SummaryRequest, summary string) (float64, error) {
+	content := req.Content
+	overhead := llm.EstimateTokens(judgeSystemPrompt) + llm.EstimateTokens(s.buildJudgePrompt(req, "", summary))
+	if budget := inputBudget([]*aiProvider{s.judge}) - overhead; llm.EstimateTokens(content) > budget {
+		// the beginning of a long article is enough to catch a summary of
+		// another article or invented facts in its lead
+		content = splitContent(content, budget)[0]
+	}
+
+	completion, _, err := s.complete(ctx, []*aiProvider{s.judge}, judgeSystemPrompt, s.buildJudgePrompt(req, content, summary))
+	if err != nil {
+		return 0, err
+	}
+	match := judgeRating.FindStringSubmatch(strings.TrimSpace(completion.Text))
+	if match == nil {
+		return 0, fmt.Errorf("judge %s answered without a rating: %q", s.judge.name, completion.Text)
+	}
+	return float64(match[1][0]-'1') / 4, nil
+}
+
+func (s *AIService) buildJudgePrompt(req *SummaryRequest, content, summary string) string {
+	return fmt.Sprintf(`記事タイトル: %s
+
+記事内容:
+%s
+
+要約:
+%s
+
+上記の要約を1から5で評価してください。`, req.Title, content, summary)
+}
+
+// checkSummaryLength returns the issue of a summary far outside the length
+// its type asks for, in characters. Models writing in alphabetic scripts
+// read the number of characters loosely, so their upper bound is tripled.
+func checkSummaryLength(summaryType, language, summary string) string {
+	minLength, maxLength := 200, 300
+	switch summaryType {
+	case models.SummaryTypeShort:
+		minLength, maxLength = 50, 100
+	case models.SummaryTypeLong:
+		minLength, maxLength = 500, 800
+	}
+	if !isCJKLanguage(language) {
+		maxLength *= 3
+	}
+
+	length := utf8.RuneCountInString(summary)
+	switch {
+	case length < minLength/2:
+		return models.SummaryIssueTooShort
+	case length > maxLength*3/2:
+		return models.SummaryIssueTooLong
+	default:
+		return ""
+	}
+}
+
+// matchesScript reports whether most letters of a summary are in the script
+// of its language. Languages other than Japanese, Chinese, Korean and those
+// written in Latin letters are not checked.
+func matchesScript(language, summary string) bool {
+	var letters, kana, han, hangul, latin int
+	for _, r := range summary {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		}
+	}
+	if letters == 0 {
+		return true
+	}
+
+	share := func(n int) float64 { return float64(n) / float64(letters) }
+	base, _, _ := strings.Cut(strings.ToLower(language), "-")
+	switch base {
+	case "ja":
+		// product names and terms are often left in Latin letters
+		return share(kana+han) >= 0.3 && kana > 0
+	case "zh":
+		return share(han) >= 0.5 && share(kana) < 0.05
+	case "ko":
+		return share(hangul) >= 0.3
+	case "en", "fr", "de", "es", "it", "pt", "nl", "sv", "da", "no", "fi", "pl", "id":
+		return share(latin) >= 0.7
+	default:
+		return true
+	}
+}
+
+func isCJKLanguage(language string) bool {
+	base, _, _ := strings.Cut(strings.ToLower(language), "-")
+	return base == "ja" || base == "zh" || base == "ko"
+}
+
+// isRefusal reports whether the model declined to summarize instead of
+// summarizing. The whole reply must be the refusal: it opens by declining
+// the task and is short or lacks the sentences of a summary, so a summary
+// quoting an apology or saying "I cannot stress enough" is kept.
+func isRefusal(summary string) bool {
+	lower := strings.ToLower(strings.TrimLeft(summary, " \t\r\n\"'「『*"))
+	lower = strings.ReplaceAll(lower, "’", "'")
+	if !refusalOpening.MatchString(lower) && !refusalOpeningJA.MatchString(lower) {
+		return false
+	}
+	return utf8.RuneCountInString(lower) <= maxRefusalLength || countSentences(lower) < 3
+}
+
+// countSentences returns the number of sentences and lines of text
+func countSentences(text string) int {
+	count := 0
+	for _, sentence := range strings.FieldsFunc(text, func(r rune) bool {
+		return strings.ContainsRune(".!?。！？\n", r)
+	}) {
+		if strings.TrimSpace(sentence) != "" {
+			count++
+		}
+	}
+	return count
+}
+
+// hasBoilerplate reports whether a summary starts with a preamble instead of
+// the summary itself
+func hasBoilerplate(summary string) bool {
+	lower := strings.ToLower(strings.TrimSpace(summary))
+	for _, prefix := range boilerplatePrefixes {
+		if strings.HasPrefix(lower, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// unsupportedFacts returns the number of facts of a summary checked against
+// its source and those the source does not contain: numbers of two digits
+// or more and, unless the summary is a translation, capitalized words not
+// starting a sentence and runs of katakana
+func unsupportedFacts(summary, source string, checkNames bool) (int, []string) {
+	normalizedSource := strings.ReplaceAll(source, ",", "")
+	lowerSource := strings.ToLower(source)
+
+	checked := 0
+	seen := make(map[string]bool)
+	var unsupported []string
+	check := func(fact string, found bool) {
+		if seen[fact] {
+			return
+		}
+		seen[fact] = true
+		checked++
+		if !found {
+			unsupported = append(unsupported, fact)
+		}
+	}
+
+	for _, number := range summaryNumber.FindAllString(summary, -1) {
+		number = strings.ReplaceAll(number, ",", "")
+		if len(strings.TrimLeft(number, "0")) < 2 {
+			continue
+		}
+		check(number, strings.Contains(normalizedSource, number))
+	}
+
+	if checkNames {
+		for _, loc := range latinName.FindAllStringIndex(summary, -1) {
+			if skipName(summary, loc[0]) {
+				continue
+			}
+			name := summary[loc[0]:loc[1]]
+			check(name, strings.Contains(lowerSource, strings.ToLower(name)))
+		}
+		for _, name := range katakanaRun.FindAllString(summary, -1) {
+			check(name, strings.Contains(source, name))
+		}
+	}
+	return checked, unsupported
+}
+
+// skipName reports whether the capitalized word at offset i of text is not
+// taken for a name: it starts a sentence, where any word is capitalized, or
+// continues a word, as Phone in iPhone
+func skipName(text string, i int) bool {
+	if i > 0 {
+		previous, _ := utf8.DecodeLastRuneInString(text[:i])
+		if unicode.Is(unicode.Latin, previous) || unicode.IsDigit(previous) {
+			return true
+		}
+	}
+	before := strings.TrimRight(text[:i], " \t\n\"'(")
+	if before == "" {
+		return true
+	}
+	last, _ := utf8.DecodeLastRuneInString(before)
+	return strings.ContainsRune(".!?:", last)
+}
+
+func clampScore(score float64) float64 {
+	if score < 0 {
+		return 0
+	}
+	if score > 1 {
+		return 1
+	}
+	return score
+}
@@ -0,0 +1,278 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/eikuma/stockle/backend/internal/config"
+	"github.com/eikuma/stockle/backend/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	validationArticle = "Stockleは2024年に公開された記事保存サービスです。保存した記事はGroqやClaudeのAPIで要約され、1,500人のユーザーが利用しています。"
+	validationSummary = "Stockleは2024年に公開された記事保存サービスで、保存した記事をGroqやClaudeで要約し、1500人が利用している。"
+)
+
+func TestAIService_ValidateSummary(t *testing.T) {
+	service := newFakeLLMService(t, &fakeLLM{}, &config.AIConfig{})
+	request := func(language, outputLanguage string) *SummaryRequest {
+		req := newTestSummaryRequest(language, validationArticle)
+		req.OutputLanguage = outputLanguage
+		return req
+	}
+
+	tests := []struct {
+		name        string
+		request     *SummaryRequest
+		summary     string
+		issues      []string
+		unsupported []string
+		minScore    float64
+		maxScore    float64
+	}{
+		{
+			name:     "faithful summary",
+			request:  request("ja", ""),
+			summary:  validationSummary,
+			minScore: 1,
+			maxScore: 1,
+		},
+		{
+			name:     "too short",
+			request:  request("ja", ""),
+			summary:  "記事保存サービスです。",
+			issues:   []string{models.SummaryIssueTooShort},
+			minScore: 0.8,
+			maxScore: 0.8,
+		},
+		{
+			name:     "too long",
+			request:  request("ja", ""),
+			summary:  strings.Repeat("記事保存サービスで要約を作る。", 20),
+			issues:   []string{models.SummaryIssueTooLong},
+			minScore: 0.8,
+			maxScore: 0.8,
+		},
+		{
+			name:     "English is allowed more characters",
+			request:  request("en", ""),
+			summary:  "Stockle is a read-it-later service launched in 2024 that summarizes saved articles with Groq and Claude for its 1500 users.",
+			minScore: 1,
+			maxScore: 1,
+		},
+		{
+			name:     "wrong language",
+			request:  request("ja", ""),
+			summary:  "Stockle is a read-it-later service launched in 2024 that summarizes saved articles with Groq and Claude for its 1500 users.",
+			issues:   []string{models.SummaryIssueLanguage},
+			minScore: 0.4,
+			maxScore: 0.4,
+		},
+		{
+			name:     "refusal",
+			request:  request("en", ""),
+			summary:  "I'm sorry, but I cannot summarize this article because the content is incomplete.",
+			issues:   []string{models.SummaryIssueRefusal},
+			maxScore: 0,
+		},
+		{
+			name:     "Japanese refusal",
+			request:  request("ja", ""),
+			summary:  "申し訳ありませんが、この記事は要約できません。内容が不足しています。",
+			issues:   []string{models.SummaryIssueRefusal},
+			maxScore: 0,
+		},
+		{
+			name:     "summary opening with a refusal phrase",
+			request:  request("en", ""),
+			summary:  "I cannot stress enough how much Stockle, launched in 2024, helps its 1500 users: it summarizes saved articles with Groq and Claude.",
+			minScore: 1,
+			maxScore: 1,
+		},
+		{
+			name:     "summary quoting an apology",
+			request:  request("ja", ""),
+			summary:  "「申し訳ありません」と謝る必要もなく、Stockleは2024年に公開された記事保存サービスで、保存した記事をGroqやClaudeで要約し、1500人が利用している。",
+			minScore: 1,
+			maxScore: 1,
+		},
+		{
+			name:     "boilerplate",
+			request:  request("ja", ""),
+			summary:  "以下は要約です。" + validationSummary,
+			issues:   []string{models.SummaryIssueBoilerplate},
+			minScore: 0.9,
+			maxScore: 0.9,
+		},
+		{
+			name:        "invented numbers and names",
+			request:     request("ja", ""),
+			summary:     "Stockleは2019年に公開された記事保存サービスで、OpenAIのAPIで要約し、1500人が利用している。",
+			issues:      []string{models.SummaryIssueUnsupportedFact},
+			unsupported: []string{"2019", "OpenAI"},
+			minScore:    0.5,
+			maxScore:    0.8,
+		},
+		{
+			name:        "invented katakana names",
+			request:     request("ja", ""),
+			summary:     "Stockleは2024年に公開された記事保存サービスで、保存した記事をジェミニで要約し、1500人が利用している。",
+			issues:      []string{models.SummaryIssueUnsupportedFact},
+			unsupported: []string{"ジェミニ"},
+			minScore:    0.5,
+			maxScore:    0.9,
+		},
+		{
+			name:     "names of translations are not checked",
+			request:  request("en", "ja"),
+			summary:  "ストックルは2024年に公開された記事保存サービスで、保存した記事をグロックで要約し、1500人が利用している。",
+			minScore: 1,
+			maxScore: 1,
+		},
+		{
+			name:        "numbers of translations are checked",
+			request:     request("en", "ja"),
+			summary:     "ストックルは2019年に公開された記事保存サービスで、保存した記事をグロックで要約し、1500人が利用している。",
+			issues:      []string{models.SummaryIssueUnsupportedFact},
+			unsupported: []string{"2019"},
+			minScore:    0.5,
+			maxScore:    0.9,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validation := service.validateSummary(context.Background(), tt.request, tt.summary)
+			assert.Equal(t, tt.issues, validation.Issues)
+			assert.Equal(t, tt.unsupported, validation.Unsupported)
+			assert.GreaterOrEqual(t, validation.Score, tt.minScore-0.001)
+			assert.LessOrEqual(t, validation.Score, tt.maxScore+0.001)
+		})
+	}
+}
+
+func TestAIService_LowConfidenceRetriesNextProvider(t *testing.T) {
+	groqServer := newFakeLLMServer(t, config.AIProviderGroq, "I cannot summarize this article.")
+	claudeServer := newFakeLLMServer(t, config.AIProviderAnthropic, validationSummary)
+	localServer := newFakeLLMServer(t, config.AIProviderOpenAI, "Stockleは記事保存サービスで、2019年に公開され、要約機能を持つ。")
+
+	providers := []config.AIProviderConfig{
+		{Name: "groq", Type: config.AIProviderGroq, BaseURL: groqServer.URL},
+		{Name: "claude", Type: config.AIProviderAnthropic, BaseURL: claudeServer.URL},
+		{Name: "local", Type: config.AIProviderOpenAI, BaseURL: localServer.URL},
+	}
+	aiService, err := NewAIService(&config.AIConfig{Providers: providers, MinConfidence: 0.5})
+	require.NoError(t, err)
+
+	result, err := aiService.GenerateSummary(context.Background(), newTestSummaryRequest("ja", validationArticle))
+	require.NoError(t, err)
+	assert.Equal(t, "claude", result.Provider)
+	assert.Equal(t, validationSummary, result.Summary)
+	assert.InDelta(t, 1, result.Confidence, 0.001)
+	assert.Empty(t, result.Issues)
+	assert.Equal(t, 1, groqServer.requestCount())
+	assert.Zero(t, localServer.requestCount(), "a good summary is not generated again")
+
+	t.Run("the best summary when none is good enough", func(t *testing.T) {
+		aiService, err := NewAIService(&config.AIConfig{Providers: providers, MinConfidence: 1.1})
+		require.NoError(t, err)
+
+		result, err := aiService.GenerateSummary(context.Background(), newTestSummaryRequest("ja", validationArticle))
+		require.NoError(t, err)
+		assert.Equal(t, "claude", result.Provider)
+		assert.Equal(t, 1, localServer.requestCount())
+	})
+
+	t.Run("the requested provider is not retried", func(t *testing.T) {
+		req := newTestSummaryRequest("ja", validationArticle)
+		req.Provider = "groq"
+		result, err := aiService.GenerateSummary(context.Background(), req)
+		require.NoError(t, err)
+		assert.Equal(t, "groq", result.Provider)
+		assert.Zero(t, result.Confidence)
+		assert.Equal(t, []string{models.SummaryIssueRefusal}, result.Issues)
+	})
+
+	t.Run("the summary of a failing provider is kept", func(t *testing.T) {
+		claudeServer.fail(400)
+		localServer.fail(400)
+		result, err := aiService.GenerateSummary(context.Background(), newTestSummaryRequest("ja", validationArticle))
+		require.NoError(t, err)
+		assert.Equal(t, "groq", result.Provider)
+	})
+}
+
+func TestAIService_JudgeScore(t *testing.T) {
+	groqServer := newFakeLLMServer(t, config.AIProviderGroq, validationSummary)
+	judgeServer := newFakeLLMServer(t, config.AIProviderOpenAI, "2")
+
+	aiService, err := NewAIService(&config.AIConfig{
+		Providers: []config.AIProviderConfig{
+			{Name: "groq", Type: config.AIProviderGroq, BaseURL: groqServer.URL},
+			{Name: "judge", Type: config.AIProviderOpenAI, BaseURL: judgeServer.URL},
+		},
+		JudgeProvider: "judge",
+	})
+	require.NoError(t, err)
+
+	result, err := aiService.GenerateSummary(context.Background(), newTestSummaryRequest("ja", validationArticle))
+	require.NoError(t, err)
+	assert.Equal(t, "groq", result.Provider)
+	// the heuristics score 1 and the judge 2 of 5
+	assert.InDelta(t, (1+0.25)/2, result.Confidence, 0.001)
+	assert.Equal(t, []string{models.SummaryIssueLowJudgeScore}, result.Issues)
+
+	body, _ := judgeServer.lastRequest(t)
+	messages := body["messages"].([]any)
+	prompt := messages[len(messages)-1].(map[string]any)["content"].(string)
+	assert.Contains(t, prompt, validationArticle)
+	assert.Contains(t, prompt, validationSummary)
+
+	t.Run("a failing judge leaves the heuristics", func(t *testing.T) {
+		judgeServer.fail(400)
+		result, err := aiService.GenerateSummary(context.Background(), newTestSummaryRequest("ja", validationArticle))
+		require.NoError(t, err)
+		assert.InDelta(t, 1, result.Confidence, 0.001)
+	})
+
+	t.Run("only a single rating is read", func(t *testing.T) {
+		tests := []struct {
+			reply      string
+			confidence float64
+		}{
+			{"4", (1 + 0.75) / 2},
+			{" 5。\n", 1},
+			{"評価: 3", (1 + 0.5) / 2},
+			// the heuristics alone score the summary
+			{"10点満点中8", 1},
+			{"Score: 4/5 (1 issue)", 1},
+			{"1から5のうち2です", 1},
+			{"6", 1},
+		}
+		for _, tt := range tests {
+			judgeServer := newFakeLLMServer(t, config.AIProviderOpenAI, tt.reply)
+			aiService, err := NewAIService(&config.AIConfig{
+				Providers: []config.AIProviderConfig{
+					{Name: "groq", Type: config.AIProviderGroq, BaseURL: groqServer.URL},
+					{Name: "judge", Type: config.AIProviderOpenAI, BaseURL: judgeServer.URL},
+				},
+				JudgeProvider: "judge",
+			})
+			require.NoError(t, err)
+
+			result, err := aiService.GenerateSummary(context.Background(), newTestSummaryRequest("ja", validationArticle))
+			require.NoError(t, err)
+			assert.InDelta(t, tt.confidence, result.Confidence, 0.001, tt.reply)
+		}
+	})
+
+	t.Run("the judge must be configured", func(t *testing.T) {
+		_, err := NewAIService(&config.AIConfig{
+			Providers:     []config.AIProviderConfig{{Name: "groq", Type: config.AIProviderGroq}},
+			JudgeProvider: "judge",
+		})
+		assert.ErrorContains(t, err, `AI judge uses unknown provider "judge"`)
+	})
+}
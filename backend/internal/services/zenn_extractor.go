@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/eikuma/stockle/backend/internal/extractor"
+)
+
+// zennExtractor reads Zenn articles from the Next.js page data, which holds
+// the rendered body without the surrounding widgets. Pages without it, such
+// as books and scraps, use the fallback rule.
+type zennExtractor struct {
+	fallback *siteRule
+}
+
+// zennPageData is the part of the __NEXT_DATA__ script used for articles
+type zennPageData struct {
+	Props struct {
+		PageProps struct {
+			Article *struct {
+				Title       string `json:"title"`
+				BodyHTML    string `json:"bodyHtml"`
+				PublishedAt string `json:"publishedAt"`
+				User        struct {
+					Name string `json:"name"`
+				} `json:"user"`
+			} `json:"article"`
+		} `json:"pageProps"`
+	} `json:"props"`
+}
+
+func newZennExtractor() *zennExtractor {
+	return &zennExtractor{
+		fallback: &siteRule{
+			RuleName:     "zenn",
+			HostPatterns: []string{"zenn.dev"},
+			Title:        "h1",
+			Content:      ".znc",
+			Language:     "ja",
+		},
+	}
+}
+
+// Name implements SiteExtractor
+func (z *zennExtractor) Name() string {
+	return "zenn"
+}
+
+// Hosts implements SiteExtractor
+func (z *zennExtractor) Hosts() []string {
+	return []string{"zenn.dev"}
+}
+
+// Extract implements SiteExtractor
+func (z *zennExtractor) Extract(ctx context.Context, doc *goquery.Document, fetch FetchFunc) (*ArticleMetadata, error) {
+	var data zennPageData
+	script := doc.Find("script#__NEXT_DATA__").First().Text()
+	if script == "" || json.Unmarshal([]byte(script), &data) != nil {
+		return z.fallback.Extract(ctx, doc, fetch)
+	}
+	article := data.Props.PageProps.Article
+	if article == nil || strings.TrimSpace(article.BodyHTML) == "" {
+		return z.fallback.Extract(ctx, doc, fetch)
+	}
+
+	body, err := goquery.NewDocumentFromReader(strings.NewReader(article.BodyHTML))
+	if err != nil {
+		return z.fallback.Extract(ctx, doc, fetch)
+	}
+
+	metadata := metadataFromDocument(doc)
+	metadata.Extractor = z.Name()
+	metadata.Language = "ja"
+	if title := extractor.CleanText(article.Title); title != "" {
+		metadata.Title = extractor.TruncateRunes(title, maxTitleRunes)
+	}
+	if author := extractor.CleanText(article.User.Name); author != "" {
+		metadata.Authors = []string{author}
+		metadata.Author = extractor.TruncateRunes(author, maxAuthorRunes)
+	}
+	if t, ok := extractor.ParseDate(article.PublishedAt); ok {
+		metadata.PublishedAt = &t
+	}
+
+	setContent(metadata, extractor.FromSelection(body.Find("body"), doc.Url))
+	metadata.Pages = 1
+	return metadata, nil
+}
@@ -0,0 +1,293 @@
+// Package urlnorm reduces the URLs of articles to a normal form, so that
+// variants of the same page such as tracking parameters, http and https,
+// trailing slashes or AMP versions are recognized as one article.
+package urlnorm
+
+import (
+	"bytes"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed rules.yaml
+var builtinRules []byte
+
+// Rule adjusts the normalization for the hosts it matches, see rules.yaml
+type Rule struct {
+	Hosts             []string `yaml:"hosts"`
+	KeepParams        []string `yaml:"keep_params"`
+	StripParams       []string `yaml:"strip_params"`
+	StripQuery        bool     `yaml:"strip_query"`
+	KeepTrailingSlash bool     `yaml:"keep_trailing_slash"`
+}
+
+type rulesFile struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// trackingParams are removed from every URL. Names ending in "_" are prefixes.
+var trackingParams = []string{
+	"utm_", "fbclid", "gclid", "dclid", "gbraid", "wbraid", "msclkid", "yclid", "twclid", "ttclid",
+	"mc_cid", "mc_eid", "_ga", "_gl", "_hsenc", "_hsmi", "__hssc", "__hstc", "hsctatracking",
+	"igshid", "mkt_tok", "oly_anon_id", "oly_enc_id", "vero_id", "wickedid", "rb_clickid",
+	"s_cid", "spm", "scm", "ref_src", "ref_url", "__twitter_impression", "cmpid", "ncid", "sr_share",
+	"amp", "usqp", "outputtype",
+}
+
+// defaultPorts are removed from URLs of their scheme
+var defaultPorts = map[string]string{"http": "80", "https": "443"}
+
+// indexFiles are directory index pages that name the same page as the directory
+var indexFiles = map[string]bool{"index.html": true, "index.htm": true, "index.php": true}
+
+// Normalizer normalizes URLs with the built-in rules and optionally more
+// rules loaded from a file. It is safe for concurrent use.
+type Normalizer struct {
+	rules []Rule
+}
+
+// New creates a normalizer with the built-in rules followed by rules. For a
+// host the most specific pattern wins, and among equally specific ones the
+// latest rule.
+func New(rules ...Rule) *Normalizer {
+	builtin, err := parseRules(builtinRules, "built-in rules")
+	if err != nil {
+		panic(err)
+	}
+	return &Normalizer{rules: append(builtin, rules...)}
+}
+
+// Load creates a normalizer with the built-in rules and the rules in the YAML
+// file at rulesPath, if any
+func Load(rulesPath string) (*Normalizer, error) {
+	if rulesPath == "" {
+		return New(), nil
+	}
+	data, err := os.ReadFile(rulesPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load URL rules: %w", err)
+	}
+	rules, err := parseRules(data, rulesPath)
+	if err != nil {
+		return nil, err
+	}
+	return New(rules...), nil
+}
+
+func parseRules(data []byte, source string) ([]Rule, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	var file rulesFile
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid URL rules in %s: %w", source, err)
+	}
+	for i, rule := range file.Rules {
+		if len(rule.Hosts) == 0 {
+			return nil, fmt.Errorf("invalid URL rule #%d in %s: at least one host is required", i+1, source)
+		}
+		for _, host := range rule.Hosts {
+			if strings.Trim(strings.TrimPrefix(strings.TrimSpace(host), "*."), ".") == "" || strings.ContainsAny(host, "/:") {
+				return nil, fmt.Errorf("invalid URL rule #%d in %s: invalid host %q", i+1, source, host)
+			}
+		}
+	}
+	return file.Rules, nil
+}
+
+// Normalize returns the normal form of an absolute http or https URL. It is
+// meant for comparing URLs and is not necessarily a working address: the
+// scheme is always https, "www." is dropped from the host and so is the
+// fragment, the default port, tracking parameters, a trailing slash, index
+// files and AMP markers. The remaining query parameters are sorted.
+func (n *Normalizer) Normalize(rawURL string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("invalid URL %q: only http and https URLs are supported", rawURL)
+	}
+	if u.Hostname() == "" {
+		return "", fmt.Errorf("invalid URL %q: missing host", rawURL)
+	}
+	if ampURL, ok := unwrapAMPCache(u); ok {
+		u = ampURL
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	port := u.Port()
+	if port == defaultPorts[u.Scheme] {
+		port = ""
+	}
+	host = strings.TrimPrefix(host, "www.")
+	host = strings.TrimPrefix(host, "amp.")
+	rule := n.rule(host)
+
+	normalized := &url.URL{
+		Scheme:   "https",
+		Host:     host,
+		Path:     normalizePath(u.Path, rule.KeepTrailingSlash),
+		RawQuery: normalizeQuery(u.Query(), rule),
+	}
+	if port != "" {
+		normalized.Host += ":" + port
+	}
+	// Single page applications route with "#!" fragments
+	if strings.HasPrefix(u.Fragment, "!") {
+		normalized.Fragment = u.Fragment
+	}
+	return normalized.String(), nil
+}
+
+// Key returns the hash of the normal form of rawURL, see Normalize and Hash
+func (n *Normalizer) Key(rawURL string) (string, error) {
+	normalized, err := n.Normalize(rawURL)
+	if err != nil {
+		return "", err
+	}
+	return Hash(normalized), nil
+}
+
+// Hash returns the hex encoded SHA-256 of a normalized URL, which is what
+// articles store to find duplicates
+func Hash(normalized string) string {
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// rule returns the rule with the most specific pattern matching host
+func (n *Normalizer) rule(host string) Rule {
+	var best Rule
+	bestLength := 0
+	for _, rule := range n.rules {
+		for _, pattern := range rule.Hosts {
+			pattern = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(pattern)), "www.")
+			if length := MatchHost(pattern, host); length > 0 && length >= bestLength {
+				best, bestLength = rule, length
+			}
+		}
+	}
+	return best
+}
+
+// MatchHost returns how specifically pattern matches the lowercase host, or
+// 0 when it does not. A pattern matches its domain and subdomains, and
+// "*.example.com" only the subdomains. The longer match is the more specific
+// one.
+func MatchHost(pattern, host string) int {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	if subdomains, ok := strings.CutPrefix(pattern, "*."); ok {
+		if strings.HasSuffix(host, "."+subdomains) {
+			return len(subdomains)
+		}
+		return 0
+	}
+	if host == pattern || strings.HasSuffix(host, "."+pattern) {
+		// Exact patterns beat wildcards for the same domain
+		return len(pattern) + 1
+	}
+	return 0
+}
+
+// unwrapAMPCache returns the publisher URL of a page served from the Google
+// AMP cache, e.g. https://example-com.cdn.ampproject.org/c/s/example.com/post
+func unwrapAMPCache(u *url.URL) (*url.URL, bool) {
+	if !strings.HasSuffix(strings.ToLower(u.Hostname()), ".cdn.ampproject.org") {
+		return nil, false
+	}
+	scheme := "http"
+	rest, ok := strings.CutPrefix(u.Path, "/c/")
+	if !ok {
+		rest, ok = strings.CutPrefix(u.Path, "/v/")
+	}
+	if !ok {
+		return nil, false
+	}
+	if secure, ok := strings.CutPrefix(rest, "s/"); ok {
+		scheme, rest = "https", secure
+	}
+	publisher, err := url.Parse(scheme + "://" + rest)
+	if err != nil || publisher.Hostname() == "" {
+		return nil, false
+	}
+	publisher.RawQuery = u.RawQuery
+	return publisher, true
+}
+
+func normalizePath(p string, keepTrailingSlash bool) string {
+	if p == "" || p == "/" {
+		return "/"
+	}
+	trailingSlash := strings.HasSuffix(p, "/")
+	p = path.Clean("/" + p)
+
+	// AMP versions at /post/amp or /post.amp.html
+	if strings.HasSuffix(p, "/amp") && p != "/amp" {
+		p = path.Dir(p)
+	}
+	if base := path.Base(p); strings.Contains(base, ".amp.") {
+		p = path.Join(path.Dir(p), strings.Replace(base, ".amp.", ".", 1))
+	}
+	if indexFiles[strings.ToLower(path.Base(p))] {
+		p, trailingSlash = path.Dir(p), true
+	}
+
+	if p != "/" && trailingSlash && keepTrailingSlash {
+		p += "/"
+	}
+	return p
+}
+
+func normalizeQuery(query url.Values, rule Rule) string {
+	if rule.StripQuery || len(query) == 0 {
+		return ""
+	}
+
+	keep := make(map[string]bool, len(rule.KeepParams))
+	for _, name := range rule.KeepParams {
+		keep[strings.ToLower(name)] = true
+	}
+	strip := make(map[string]bool, len(rule.StripParams))
+	for _, name := range rule.StripParams {
+		strip[strings.ToLower(name)] = true
+	}
+
+	for name := range query {
+		lower := strings.ToLower(name)
+		switch {
+		case len(keep) > 0 && !keep[lower], strip[lower], isTrackingParam(lower):
+			query.Del(name)
+		}
+	}
+	for name, values := range query {
+		sort.Strings(values)
+		query[name] = values
+	}
+	// Encode sorts by name
+	return query.Encode()
+}
+
+func isTrackingParam(name string) bool {
+	for _, param := range trackingParams {
+		if prefix, ok := strings.CutSuffix(param, "_"); ok && strings.HasPrefix(name, prefix+"_") {
+			return true
+		}
+		if name == param {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+package urlnorm
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	n := New()
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{name: "already normal", url: "https://example.com/posts/1", want: "https://example.com/posts/1"},
+		{name: "http and https are one page", url: "http://example.com/posts/1", want: "https://example.com/posts/1"},
+		{name: "host case and www", url: "https://WWW.Example.COM/posts/1", want: "https://example.com/posts/1"},
+		{name: "default port", url: "https://example.com:443/posts/1", want: "https://example.com/posts/1"},
+		{name: "http default port", url: "http://example.com:80/posts/1", want: "https://example.com/posts/1"},
+		{name: "other ports are kept", url: "https://example.com:8443/posts/1", want: "https://example.com:8443/posts/1"},
+		{name: "fragment", url: "https://example.com/posts/1#comments", want: "https://example.com/posts/1"},
+		{name: "hashbang route", url: "https://example.com/#!/posts/1", want: "https://example.com/#!/posts/1"},
+		{name: "trailing slash", url: "https://example.com/posts/1/", want: "https://example.com/posts/1"},
+		{name: "root", url: "https://example.com", want: "https://example.com/"},
+		{name: "duplicate slashes", url: "https://example.com//posts///1", want: "https://example.com/posts/1"},
+		{name: "index file", url: "https://example.com/posts/index.html", want: "https://example.com/posts"},
+		{
+			name: "tracking parameters",
+			url:  "https://example.com/posts/1?utm_source=twitter&utm_medium=social&fbclid=abc&gclid=def",
+			want: "https://example.com/posts/1",
+		},
+		{name: "other parameters are sorted", url: "https://example.com/search?q=go&page=2&utm_campaign=x", want: "https://example.com/search?page=2&q=go"},
+		{name: "AMP path", url: "https://example.com/posts/1/amp/", want: "https://example.com/posts/1"},
+		{name: "AMP file", url: "https://example.com/posts/1.amp.html", want: "https://example.com/posts/1.html"},
+		{name: "AMP parameter", url: "https://example.com/posts/1?amp=1", want: "https://example.com/posts/1"},
+		{name: "AMP host", url: "https://amp.example.com/posts/1", want: "https://example.com/posts/1"},
+		{
+			name: "AMP cache",
+			url:  "https://example-com.cdn.ampproject.org/c/s/example.com/posts/1/amp?usqp=mq331AQ",
+			want: "https://example.com/posts/1",
+		},
+		{name: "keep_params rule", url: "https://www.youtube.com/watch?v=abc&t=42s&feature=share", want: "https://youtube.com/watch?v=abc"},
+		{name: "strip_query rule", url: "https://x.com/user/status/1?s=20&t=abc", want: "https://x.com/user/status/1"},
+		{name: "strip_params rule", url: "https://medium.com/@user/post-123?source=rss&sk=1", want: "https://medium.com/@user/post-123"},
+		{name: "rules match subdomains", url: "https://user.medium.com/post-123?source=rss", want: "https://user.medium.com/post-123"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := n.Normalize(tt.url)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	for _, invalid := range []string{"", "example.com/posts/1", "ftp://example.com/file", "https://", "javascript:alert(1)"} {
+		_, err := n.Normalize(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestNormalizer_Key(t *testing.T) {
+	n := New()
+	a, err := n.Key("http://www.example.com/posts/1/?utm_source=feed")
+	require.NoError(t, err)
+	b, err := n.Key("https://example.com/posts/1")
+	require.NoError(t, err)
+	c, err := n.Key("https://example.com/posts/2")
+	require.NoError(t, err)
+
+	assert.Equal(t, a, b)
+	assert.NotEqual(t, a, c)
+	assert.Len(t, a, 64)
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "urls.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+rules:
+  - hosts: [example.com]
+    keep_params: [id]
+    keep_trailing_slash: true
+  - hosts: [youtube.com]
+    keep_params: [v, t]
+`), 0o644))
+
+	n, err := Load(path)
+	require.NoError(t, err)
+
+	got, err := n.Normalize("https://example.com/item/?id=7&session=abc")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/item/?id=7", got)
+
+	// Rules from the file override the built-in rules for the same host
+	got, err = n.Normalize("https://youtube.com/watch?v=abc&t=42s&list=x")
+	require.NoError(t, err)
+	assert.Equal(t, "https://youtube.com/watch?t=42s&v=abc", got)
+
+	for name, content := range map[string]string{
+		"unknown field": "rules:\n  - hosts: [example.com]\n    keep: [id]\n",
+		"no hosts":      "rules:\n  - strip_query: true\n",
+		"invalid host":  "rules:\n  - hosts: [https://example.com]\n",
+	} {
+		path := filepath.Join(dir, "invalid.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		_, err := Load(path)
+		assert.Error(t, err, name)
+	}
+
+	_, err = Load(filepath.Join(dir, "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestMatchHost(t *testing.T) {
+	tests := []struct {
+		pattern, host string
+		want          int
+	}{
+		{pattern: "example.com", host: "example.com", want: 12},
+		{pattern: "Example.com ", host: "blog.example.com", want: 12},
+		{pattern: "*.example.com", host: "blog.example.com", want: 11},
+		{pattern: "*.example.com", host: "example.com", want: 0},
+		{pattern: "example.com", host: "notexample.com", want: 0},
+		{pattern: "blog.example.com", host: "example.com", want: 0},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, MatchHost(tt.pattern, tt.host), "%s ~ %s", tt.pattern, tt.host)
+	}
+}
@@ -0,0 +1,22 @@
+// Package migrations embeds the versioned SQL migrations applied by
+// database.Migrator. Each supported driver has its own directory of
+// NNNNNN_name.up.sql / NNNNNN_name.down.sql files, and every migration must
+// exist for all drivers under the same version.
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+)
+
+//go:embed mysql/*.sql sqlite/*.sql postgres/*.sql
+var files embed.FS
+
+// ForDriver returns the migrations for a database driver (mysql, sqlite or postgres)
+func ForDriver(driver string) (fs.FS, error) {
+	if _, err := fs.Stat(files, driver); err != nil {
+		return nil, fmt.Errorf("no migrations for database driver %q", driver)
+	}
+	return fs.Sub(files, driver)
+}
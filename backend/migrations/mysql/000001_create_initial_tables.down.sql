@@ -4,5 +4,6 @@ DROP TABLE IF EXISTS tags;
 DROP TABLE IF EXISTS articles;
 DROP TABLE IF EXISTS categories;
 DROP TABLE IF EXISTS job_queues;
-DROP TABLE IF EXISTS refresh_tokens;
-DROP TABLE IF EXISTS users;
\ No newline at end of file
+DROP TABLE IF EXISTS user_preferences;
+DROP TABLE IF EXISTS user_sessions;
+DROP TABLE IF EXISTS users;
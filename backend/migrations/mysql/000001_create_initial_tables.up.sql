@@ -1,29 +1,55 @@
 -- Create users table
 CREATE TABLE IF NOT EXISTS users (
     id VARCHAR(36) PRIMARY KEY,
-    email VARCHAR(255) NOT NULL UNIQUE,
+    email VARCHAR(255) NOT NULL,
+    password_hash VARCHAR(255),
+    google_id VARCHAR(255),
     name VARCHAR(255) NOT NULL,
-    picture VARCHAR(500),
-    provider VARCHAR(50) NOT NULL,
-    provider_id VARCHAR(255) NOT NULL,
+    display_name VARCHAR(255) NOT NULL,
+    auth_provider VARCHAR(50) DEFAULT 'email',
+    avatar_url VARCHAR(500),
     is_active BOOLEAN DEFAULT true,
+    email_verified BOOLEAN DEFAULT false,
+    last_login_at TIMESTAMP NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-    INDEX idx_users_email (email),
-    INDEX idx_users_provider_id (provider_id)
+    deleted_at TIMESTAMP NULL,
+    UNIQUE INDEX idx_users_email (email),
+    UNIQUE INDEX idx_users_google_id (google_id),
+    INDEX idx_users_deleted_at (deleted_at)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
 
--- Create refresh_tokens table
-CREATE TABLE IF NOT EXISTS refresh_tokens (
+-- Create user_sessions table
+CREATE TABLE IF NOT EXISTS user_sessions (
     id VARCHAR(36) PRIMARY KEY,
     user_id VARCHAR(36) NOT NULL,
-    token VARCHAR(500) NOT NULL UNIQUE,
+    token_hash VARCHAR(500) NOT NULL,
+    user_agent VARCHAR(500),
+    ip_address VARCHAR(45),
     expires_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP NOT NULL,
+    is_active BOOLEAN DEFAULT true,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-    INDEX idx_refresh_tokens_user_id (user_id),
-    INDEX idx_refresh_tokens_token (token),
-    CONSTRAINT fk_refresh_tokens_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+    deleted_at TIMESTAMP NULL,
+    UNIQUE INDEX idx_user_sessions_token_hash (token_hash),
+    INDEX idx_user_sessions_user_id (user_id),
+    INDEX idx_user_sessions_deleted_at (deleted_at),
+    CONSTRAINT fk_user_sessions_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Create user_preferences table
+CREATE TABLE IF NOT EXISTS user_preferences (
+    user_id VARCHAR(36) PRIMARY KEY,
+    language VARCHAR(10) DEFAULT 'ja',
+    theme VARCHAR(10) DEFAULT 'light',
+    notifications_email BOOLEAN DEFAULT true,
+    notifications_push BOOLEAN DEFAULT false,
+    auto_summarize BOOLEAN DEFAULT true,
+    summary_language VARCHAR(10) DEFAULT 'ja',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    CONSTRAINT fk_user_preferences_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
 
 -- Create categories table
@@ -104,19 +130,18 @@ CREATE TABLE IF NOT EXISTS article_tags (
 -- Create job_queues table
 CREATE TABLE IF NOT EXISTS job_queues (
     id VARCHAR(36) PRIMARY KEY,
-    type VARCHAR(50) NOT NULL,
-    status VARCHAR(20) DEFAULT 'pending',
-    priority INT DEFAULT 0,
-    payload JSON,
-    max_attempts INT DEFAULT 3,
-    attempt_count INT DEFAULT 0,
-    error TEXT,
-    scheduled_at TIMESTAMP NULL,
+    job_type VARCHAR(50) NOT NULL,
+    priority INT NOT NULL DEFAULT 5,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    payload TEXT,
+    max_retries INT NOT NULL DEFAULT 3,
+    retry_count INT NOT NULL DEFAULT 0,
+    error_message TEXT,
     started_at TIMESTAMP NULL,
     completed_at TIMESTAMP NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     INDEX idx_job_queues_status (status),
-    INDEX idx_job_queues_type (type),
-    INDEX idx_job_queues_scheduled_at (scheduled_at)
-) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
\ No newline at end of file
+    INDEX idx_job_queues_job_type (job_type),
+    INDEX idx_job_queues_priority_created_at (priority, created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,3 @@
+ALTER TABLE job_queues DROP COLUMN scheduled_at;
+ALTER TABLE articles DROP COLUMN extraction_error;
+ALTER TABLE articles DROP COLUMN extraction_status;
@@ -0,0 +1,4 @@
+DROP INDEX idx_articles_user_canonical_url_hash ON articles;
+DROP INDEX idx_articles_user_url_hash ON articles;
+ALTER TABLE articles DROP COLUMN canonical_url_hash;
+ALTER TABLE articles DROP COLUMN url_hash;
@@ -0,0 +1,29 @@
+-- Fulltext index of articles: one row per indexed article with the number of
+-- terms in each field, and one posting per article and term with the term
+-- frequency in each field. Terms are compared byte-wise so that kana which
+-- the default collation treats as equal stay distinct.
+CREATE TABLE IF NOT EXISTS search_documents (
+    article_id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL,
+    title_length INT NOT NULL DEFAULT 0,
+    summary_length INT NOT NULL DEFAULT 0,
+    content_length INT NOT NULL DEFAULT 0,
+    tags_length INT NOT NULL DEFAULT 0,
+    version INT NOT NULL DEFAULT 0,
+    indexed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_search_documents_user_id (user_id),
+    CONSTRAINT fk_search_documents_article_id FOREIGN KEY (article_id) REFERENCES articles(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS search_postings (
+    article_id VARCHAR(36) NOT NULL,
+    term VARCHAR(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL,
+    user_id VARCHAR(36) NOT NULL,
+    title_frequency INT NOT NULL DEFAULT 0,
+    summary_frequency INT NOT NULL DEFAULT 0,
+    content_frequency INT NOT NULL DEFAULT 0,
+    tags_frequency INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (article_id, term),
+    INDEX idx_search_postings_user_term (user_id, term),
+    CONSTRAINT fk_search_postings_article_id FOREIGN KEY (article_id) REFERENCES articles(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
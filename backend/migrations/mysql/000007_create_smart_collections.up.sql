@@ -0,0 +1,14 @@
+-- Saved searches shown next to the categories; query is written in the
+-- article query language
+CREATE TABLE IF NOT EXISTS smart_collections (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    query TEXT NOT NULL,
+    color VARCHAR(7) DEFAULT '#6B7280',
+    display_order INT DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_smart_collections_user_id (user_id),
+    CONSTRAINT fk_smart_collections_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
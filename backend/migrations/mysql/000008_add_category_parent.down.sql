@@ -0,0 +1,2 @@
+DROP INDEX idx_categories_parent_id ON categories;
+ALTER TABLE categories DROP COLUMN parent_id;
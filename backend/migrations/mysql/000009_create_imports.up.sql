@@ -0,0 +1,40 @@
+-- Imports of links from other read-later tools and browsers. The links of
+-- the file are saved as import items first and turned into articles by a
+-- background job, which records the outcome of every item.
+CREATE TABLE IF NOT EXISTS imports (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL,
+    format VARCHAR(30) NOT NULL,
+    file_name VARCHAR(255),
+    category_id VARCHAR(36),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    total_items INT NOT NULL DEFAULT 0,
+    processed_items INT NOT NULL DEFAULT 0,
+    imported_items INT NOT NULL DEFAULT 0,
+    duplicate_items INT NOT NULL DEFAULT 0,
+    failed_items INT NOT NULL DEFAULT 0,
+    error_message TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP NULL,
+    INDEX idx_imports_user_id (user_id),
+    CONSTRAINT fk_imports_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS import_items (
+    import_id VARCHAR(36) NOT NULL,
+    position INT NOT NULL,
+    url TEXT NOT NULL,
+    title VARCHAR(500),
+    folders TEXT,
+    tags TEXT,
+    saved_at TIMESTAMP NULL,
+    archived BOOLEAN NOT NULL DEFAULT false,
+    favorite BOOLEAN NOT NULL DEFAULT false,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    article_id VARCHAR(36),
+    error TEXT,
+    PRIMARY KEY (import_id, position),
+    INDEX idx_import_items_status (import_id, status),
+    CONSTRAINT fk_import_items_import_id FOREIGN KEY (import_id) REFERENCES imports(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,21 @@
+-- Exports of a user's articles. A background job writes the file, which
+-- is downloaded through a signed link and deleted once it expires.
+CREATE TABLE IF NOT EXISTS exports (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL,
+    format VARCHAR(30) NOT NULL,
+    article_ids TEXT,
+    query TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    article_count INT NOT NULL DEFAULT 0,
+    file_size BIGINT NOT NULL DEFAULT 0,
+    file_path VARCHAR(500),
+    error_message TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP NULL,
+    expires_at TIMESTAMP NULL,
+    INDEX idx_exports_user_id (user_id),
+    INDEX idx_exports_expires_at (expires_at),
+    CONSTRAINT fk_exports_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,36 @@
+-- Feed subscriptions. The scheduler queues a poll job for every feed whose
+-- next_fetch_at has passed; feed_entries remembers the entries already seen
+-- so that each is saved as an article at most once.
+CREATE TABLE IF NOT EXISTS feeds (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL,
+    url TEXT NOT NULL,
+    site_url TEXT,
+    title VARCHAR(500) NOT NULL,
+    category_id VARCHAR(36),
+    tags TEXT,
+    include_keywords TEXT,
+    exclude_keywords TEXT,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    etag VARCHAR(255),
+    last_modified VARCHAR(255),
+    last_fetched_at TIMESTAMP NULL,
+    next_fetch_at TIMESTAMP NOT NULL,
+    error_count INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_feeds_user_id (user_id),
+    INDEX idx_feeds_next_fetch_at (next_fetch_at),
+    CONSTRAINT fk_feeds_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_feeds_category_id FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS feed_entries (
+    feed_id VARCHAR(36) NOT NULL,
+    entry_key VARCHAR(64) NOT NULL,
+    article_id VARCHAR(36),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (feed_id, entry_key),
+    CONSTRAINT fk_feed_entries_feed_id FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
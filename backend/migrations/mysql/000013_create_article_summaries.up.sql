@@ -0,0 +1,21 @@
+-- Every generated summary of an article. is_current marks the latest of each
+-- length and language, the one also copied to the summary columns of
+-- articles; the older ones are kept as history.
+CREATE TABLE IF NOT EXISTS article_summaries (
+    id VARCHAR(36) PRIMARY KEY,
+    article_id VARCHAR(36) NOT NULL,
+    summary_type VARCHAR(10) NOT NULL,
+    language VARCHAR(10) NOT NULL,
+    content LONGTEXT NOT NULL,
+    provider VARCHAR(50),
+    model_version VARCHAR(100),
+    strategy VARCHAR(20),
+    confidence DOUBLE NOT NULL DEFAULT 0,
+    is_current BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_article_summaries_article_id (article_id),
+    CONSTRAINT fk_article_summaries_article_id FOREIGN KEY (article_id) REFERENCES articles(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Length of the summary generated when an article is saved: short, medium or long
+ALTER TABLE user_preferences ADD COLUMN summary_length VARCHAR(10) DEFAULT 'medium';
@@ -0,0 +1,3 @@
+-- Quality score of the summary from 0 to 1, and the problems its validation found
+ALTER TABLE articles ADD COLUMN summary_confidence DOUBLE;
+ALTER TABLE article_summaries ADD COLUMN issues TEXT;
@@ -0,0 +1,3 @@
+DROP INDEX idx_tags_user_name_key ON tags;
+CREATE UNIQUE INDEX idx_user_tag_name ON tags (user_id, name);
+ALTER TABLE tags DROP COLUMN name_key;
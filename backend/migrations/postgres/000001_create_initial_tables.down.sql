@@ -0,0 +1,9 @@
+-- Drop tables in reverse order of creation
+DROP TABLE IF EXISTS article_tags;
+DROP TABLE IF EXISTS tags;
+DROP TABLE IF EXISTS articles;
+DROP TABLE IF EXISTS categories;
+DROP TABLE IF EXISTS job_queues;
+DROP TABLE IF EXISTS user_preferences;
+DROP TABLE IF EXISTS user_sessions;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,139 @@
+-- Create users table
+CREATE TABLE IF NOT EXISTS users (
+    id VARCHAR(36) PRIMARY KEY,
+    email VARCHAR(255) NOT NULL,
+    password_hash VARCHAR(255),
+    google_id VARCHAR(255),
+    name VARCHAR(255) NOT NULL,
+    display_name VARCHAR(255) NOT NULL,
+    auth_provider VARCHAR(50) DEFAULT 'email',
+    avatar_url VARCHAR(500),
+    is_active BOOLEAN DEFAULT true,
+    email_verified BOOLEAN DEFAULT false,
+    last_login_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_google_id ON users (google_id);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+-- Create user_sessions table
+CREATE TABLE IF NOT EXISTS user_sessions (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(500) NOT NULL,
+    user_agent VARCHAR(500),
+    ip_address VARCHAR(45),
+    expires_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP NOT NULL,
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_sessions_token_hash ON user_sessions (token_hash);
+CREATE INDEX IF NOT EXISTS idx_user_sessions_user_id ON user_sessions (user_id);
+CREATE INDEX IF NOT EXISTS idx_user_sessions_deleted_at ON user_sessions (deleted_at);
+
+-- Create user_preferences table
+CREATE TABLE IF NOT EXISTS user_preferences (
+    user_id VARCHAR(36) PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    language VARCHAR(10) DEFAULT 'ja',
+    theme VARCHAR(10) DEFAULT 'light',
+    notifications_email BOOLEAN DEFAULT true,
+    notifications_push BOOLEAN DEFAULT false,
+    auto_summarize BOOLEAN DEFAULT true,
+    summary_language VARCHAR(10) DEFAULT 'ja',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create categories table
+CREATE TABLE IF NOT EXISTS categories (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    color VARCHAR(7) DEFAULT '#6B7280',
+    display_order INTEGER DEFAULT 0,
+    is_default BOOLEAN DEFAULT false,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_categories_user_id ON categories (user_id);
+
+-- Create articles table
+CREATE TABLE IF NOT EXISTS articles (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    category_id VARCHAR(36) REFERENCES categories(id) ON DELETE SET NULL,
+    url TEXT NOT NULL,
+    title VARCHAR(500) NOT NULL,
+    content TEXT,
+    summary TEXT,
+    summary_short TEXT,
+    summary_long TEXT,
+    thumbnail_url TEXT,
+    author VARCHAR(255),
+    site_name VARCHAR(255),
+    published_at TIMESTAMP NULL,
+    saved_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_accessed_at TIMESTAMP NULL,
+    status VARCHAR(20) DEFAULT 'unread',
+    is_favorite BOOLEAN DEFAULT false,
+    reading_progress DOUBLE PRECISION DEFAULT 0,
+    reading_time_seconds INTEGER DEFAULT 0,
+    word_count INTEGER,
+    language VARCHAR(10) DEFAULT 'ja',
+    summary_generation_status VARCHAR(20) DEFAULT 'pending',
+    summary_generated_at TIMESTAMP NULL,
+    summary_model_version VARCHAR(100),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_articles_user_id ON articles (user_id);
+CREATE INDEX IF NOT EXISTS idx_articles_category_id ON articles (category_id);
+CREATE INDEX IF NOT EXISTS idx_articles_status ON articles (status);
+CREATE INDEX IF NOT EXISTS idx_articles_saved_at ON articles (saved_at);
+CREATE INDEX IF NOT EXISTS idx_articles_is_favorite ON articles (is_favorite);
+
+-- Create tags table
+CREATE TABLE IF NOT EXISTS tags (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    usage_count INTEGER DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_tags_user_id ON tags (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_tag_name ON tags (user_id, name);
+
+-- Create article_tags join table
+CREATE TABLE IF NOT EXISTS article_tags (
+    article_id VARCHAR(36) NOT NULL REFERENCES articles(id) ON DELETE CASCADE,
+    tag_id VARCHAR(36) NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (article_id, tag_id)
+);
+CREATE INDEX IF NOT EXISTS idx_article_tags_tag_id ON article_tags (tag_id);
+
+-- Create job_queues table
+CREATE TABLE IF NOT EXISTS job_queues (
+    id VARCHAR(36) PRIMARY KEY,
+    job_type VARCHAR(50) NOT NULL,
+    priority INTEGER NOT NULL DEFAULT 5,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    payload TEXT,
+    max_retries INTEGER NOT NULL DEFAULT 3,
+    retry_count INTEGER NOT NULL DEFAULT 0,
+    error_message TEXT,
+    started_at TIMESTAMP NULL,
+    completed_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_job_queues_status ON job_queues (status);
+CREATE INDEX IF NOT EXISTS idx_job_queues_job_type ON job_queues (job_type);
+CREATE INDEX IF NOT EXISTS idx_job_queues_priority_created_at ON job_queues (priority, created_at);
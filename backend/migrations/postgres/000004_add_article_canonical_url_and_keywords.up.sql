@@ -0,0 +1,3 @@
+-- Canonical URL declared by the page and its keywords as a JSON array
+ALTER TABLE articles ADD COLUMN canonical_url TEXT;
+ALTER TABLE articles ADD COLUMN keywords TEXT;
@@ -0,0 +1,7 @@
+-- SHA-256 of the normalized URL the article was saved with and of the
+-- canonical URL found while scraping, used to detect duplicates. Existing
+-- articles are hashed by the server after the migration.
+ALTER TABLE articles ADD COLUMN url_hash VARCHAR(64);
+ALTER TABLE articles ADD COLUMN canonical_url_hash VARCHAR(64);
+CREATE UNIQUE INDEX idx_articles_user_url_hash ON articles (user_id, url_hash);
+CREATE INDEX idx_articles_user_canonical_url_hash ON articles (user_id, canonical_url_hash);
@@ -0,0 +1,38 @@
+-- Imports of links from other read-later tools and browsers. The links of
+-- the file are saved as import items first and turned into articles by a
+-- background job, which records the outcome of every item.
+CREATE TABLE IF NOT EXISTS imports (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    format VARCHAR(30) NOT NULL,
+    file_name VARCHAR(255),
+    category_id VARCHAR(36),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    total_items INTEGER NOT NULL DEFAULT 0,
+    processed_items INTEGER NOT NULL DEFAULT 0,
+    imported_items INTEGER NOT NULL DEFAULT 0,
+    duplicate_items INTEGER NOT NULL DEFAULT 0,
+    failed_items INTEGER NOT NULL DEFAULT 0,
+    error_message TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_imports_user_id ON imports (user_id);
+
+CREATE TABLE IF NOT EXISTS import_items (
+    import_id VARCHAR(36) NOT NULL REFERENCES imports(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    url TEXT NOT NULL,
+    title VARCHAR(500),
+    folders TEXT,
+    tags TEXT,
+    saved_at TIMESTAMP,
+    archived BOOLEAN NOT NULL DEFAULT false,
+    favorite BOOLEAN NOT NULL DEFAULT false,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    article_id VARCHAR(36),
+    error TEXT,
+    PRIMARY KEY (import_id, position)
+);
+CREATE INDEX IF NOT EXISTS idx_import_items_status ON import_items (import_id, status);
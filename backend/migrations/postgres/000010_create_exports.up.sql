@@ -0,0 +1,20 @@
+-- Exports of a user's articles. A background job writes the file, which
+-- is downloaded through a signed link and deleted once it expires.
+CREATE TABLE IF NOT EXISTS exports (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    format VARCHAR(30) NOT NULL,
+    article_ids TEXT,
+    query TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    article_count INTEGER NOT NULL DEFAULT 0,
+    file_size BIGINT NOT NULL DEFAULT 0,
+    file_path VARCHAR(500),
+    error_message TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP,
+    expires_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_exports_user_id ON exports (user_id);
+CREATE INDEX IF NOT EXISTS idx_exports_expires_at ON exports (expires_at);
@@ -0,0 +1,33 @@
+-- Feed subscriptions. The scheduler queues a poll job for every feed whose
+-- next_fetch_at has passed; feed_entries remembers the entries already seen
+-- so that each is saved as an article at most once.
+CREATE TABLE IF NOT EXISTS feeds (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    site_url TEXT,
+    title VARCHAR(500) NOT NULL,
+    category_id VARCHAR(36) REFERENCES categories(id) ON DELETE SET NULL,
+    tags TEXT,
+    include_keywords TEXT,
+    exclude_keywords TEXT,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    etag VARCHAR(255),
+    last_modified VARCHAR(255),
+    last_fetched_at TIMESTAMP,
+    next_fetch_at TIMESTAMP NOT NULL,
+    error_count INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_feeds_user_id ON feeds (user_id);
+CREATE INDEX IF NOT EXISTS idx_feeds_next_fetch_at ON feeds (next_fetch_at);
+
+CREATE TABLE IF NOT EXISTS feed_entries (
+    feed_id VARCHAR(36) NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    entry_key VARCHAR(64) NOT NULL,
+    article_id VARCHAR(36),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (feed_id, entry_key)
+);
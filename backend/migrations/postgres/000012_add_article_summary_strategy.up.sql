@@ -0,0 +1,2 @@
+-- How the summary was generated: single for one prompt, map_reduce for a long article summarized in chunks
+ALTER TABLE articles ADD COLUMN summary_strategy VARCHAR(20);
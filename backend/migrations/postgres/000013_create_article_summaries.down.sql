@@ -0,0 +1,2 @@
+ALTER TABLE user_preferences DROP COLUMN summary_length;
+DROP TABLE IF EXISTS article_summaries;
@@ -0,0 +1,20 @@
+-- Every generated summary of an article. is_current marks the latest of each
+-- length and language, the one also copied to the summary columns of
+-- articles; the older ones are kept as history.
+CREATE TABLE IF NOT EXISTS article_summaries (
+    id VARCHAR(36) PRIMARY KEY,
+    article_id VARCHAR(36) NOT NULL REFERENCES articles(id) ON DELETE CASCADE,
+    summary_type VARCHAR(10) NOT NULL,
+    language VARCHAR(10) NOT NULL,
+    content TEXT NOT NULL,
+    provider VARCHAR(50),
+    model_version VARCHAR(100),
+    strategy VARCHAR(20),
+    confidence DOUBLE PRECISION NOT NULL DEFAULT 0,
+    is_current BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_article_summaries_article_id ON article_summaries (article_id);
+
+-- Length of the summary generated when an article is saved: short, medium or long
+ALTER TABLE user_preferences ADD COLUMN summary_length VARCHAR(10) DEFAULT 'medium';
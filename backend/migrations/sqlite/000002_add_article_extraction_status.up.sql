@@ -0,0 +1,9 @@
+-- Content extraction runs in a background job; track its state per article
+ALTER TABLE articles ADD COLUMN extraction_status VARCHAR(20) DEFAULT 'pending';
+ALTER TABLE articles ADD COLUMN extraction_error TEXT;
+
+-- Articles saved before extraction became asynchronous were scraped on save
+UPDATE articles SET extraction_status = 'completed';
+
+-- Failed jobs are retried with a delay
+ALTER TABLE job_queues ADD COLUMN scheduled_at DATETIME NULL;
@@ -0,0 +1,2 @@
+-- Sanitized HTML of the extracted main content, kept next to the plain text in content
+ALTER TABLE articles ADD COLUMN content_html TEXT;
@@ -0,0 +1,2 @@
+ALTER TABLE articles DROP COLUMN keywords;
+ALTER TABLE articles DROP COLUMN canonical_url;
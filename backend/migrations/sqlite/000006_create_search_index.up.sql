@@ -0,0 +1,26 @@
+-- Fulltext index of articles: one row per indexed article with the number of
+-- terms in each field, and one posting per article and term with the term
+-- frequency in each field
+CREATE TABLE IF NOT EXISTS search_documents (
+    article_id VARCHAR(36) PRIMARY KEY REFERENCES articles(id) ON DELETE CASCADE,
+    user_id VARCHAR(36) NOT NULL,
+    title_length INTEGER NOT NULL DEFAULT 0,
+    summary_length INTEGER NOT NULL DEFAULT 0,
+    content_length INTEGER NOT NULL DEFAULT 0,
+    tags_length INTEGER NOT NULL DEFAULT 0,
+    version INTEGER NOT NULL DEFAULT 0,
+    indexed_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_search_documents_user_id ON search_documents (user_id);
+
+CREATE TABLE IF NOT EXISTS search_postings (
+    article_id VARCHAR(36) NOT NULL REFERENCES articles(id) ON DELETE CASCADE,
+    term VARCHAR(64) NOT NULL,
+    user_id VARCHAR(36) NOT NULL,
+    title_frequency INTEGER NOT NULL DEFAULT 0,
+    summary_frequency INTEGER NOT NULL DEFAULT 0,
+    content_frequency INTEGER NOT NULL DEFAULT 0,
+    tags_frequency INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (article_id, term)
+);
+CREATE INDEX IF NOT EXISTS idx_search_postings_user_term ON search_postings (user_id, term);
@@ -0,0 +1,13 @@
+-- Saved searches shown next to the categories; query is written in the
+-- article query language
+CREATE TABLE IF NOT EXISTS smart_collections (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    query TEXT NOT NULL,
+    color VARCHAR(7) DEFAULT '#6B7280',
+    display_order INTEGER DEFAULT 0,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_smart_collections_user_id ON smart_collections (user_id);
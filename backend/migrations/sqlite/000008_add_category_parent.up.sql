@@ -0,0 +1,5 @@
+-- Parent of a nested category, NULL for top-level categories. The
+-- repository keeps the tree free of cycles and reparents the children of a
+-- deleted category.
+ALTER TABLE categories ADD COLUMN parent_id VARCHAR(36);
+CREATE INDEX IF NOT EXISTS idx_categories_parent_id ON categories (parent_id);
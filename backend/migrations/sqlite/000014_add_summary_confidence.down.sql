@@ -0,0 +1,2 @@
+ALTER TABLE article_summaries DROP COLUMN issues;
+ALTER TABLE articles DROP COLUMN summary_confidence;
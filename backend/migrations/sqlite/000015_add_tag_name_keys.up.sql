@@ -0,0 +1,6 @@
+-- Lowercased tag name, unique per user, so that names differing only in case
+-- are one tag even when saved concurrently. It replaces the unique index on
+-- the name itself. Existing tags are keyed by the server after the migration.
+ALTER TABLE tags ADD COLUMN name_key VARCHAR(100);
+DROP INDEX idx_user_tag_name;
+CREATE UNIQUE INDEX idx_tags_user_name_key ON tags (user_id, name_key);
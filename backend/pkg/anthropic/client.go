@@ -6,7 +6,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
+
+	"github.com/eikuma/stockle/backend/pkg/llm"
 )
 
 const (
@@ -20,10 +23,11 @@ type Client struct {
 }
 
 type MessageRequest struct {
-	Model     string    `json:"model"`
-	MaxTokens int       `json:"max_tokens"`
-	Messages  []Message `json:"messages"`
-	System    string    `json:"system,omitempty"`
+	Model       string    `json:"model"`
+	MaxTokens   int       `json:"max_tokens"`
+	Messages    []Message `json:"messages"`
+	System      string    `json:"system,omitempty"`
+	Temperature *float64  `json:"temperature,omitempty"`
 }
 
 type Message struct {
@@ -60,6 +64,58 @@ func NewClient(apiKey string) *Client {
 	}
 }
 
+// defaultMaxTokens is sent when a completion request leaves the limit to
+// the provider, as the messages API requires one
+const defaultMaxTokens = 1024
+
+// WithBaseURL points the client at another endpoint of the API
+func (c *Client) WithBaseURL(baseURL string) *Client {
+	c.baseURL = strings.TrimSuffix(baseURL, "/")
+	return c
+}
+
+func (c *Client) Name() string {
+	return "anthropic"
+}
+
+// Complete implements llm.LLMProvider on the messages API
+func (c *Client) Complete(ctx context.Context, req *llm.CompletionRequest) (*llm.Completion, error) {
+	msgReq := &MessageRequest{
+		Model:       req.Model,
+		MaxTokens:   req.MaxTokens,
+		System:      req.System,
+		Temperature: req.Temperature,
+	}
+	if msgReq.MaxTokens <= 0 {
+		msgReq.MaxTokens = defaultMaxTokens
+	}
+	for _, message := range req.Messages {
+		msgReq.Messages = append(msgReq.Messages, Message{Role: message.Role, Content: message.Content})
+	}
+
+	resp, err := c.CreateMessage(ctx, msgReq)
+	if err != nil {
+		return nil, err
+	}
+
+	var text strings.Builder
+	for _, content := range resp.Content {
+		if content.Type == "text" {
+			text.WriteString(content.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return nil, &llm.APIError{Kind: llm.ErrEmptyResponse, Message: "response has no text content"}
+	}
+
+	return &llm.Completion{
+		Text:         text.String(),
+		Model:        resp.Model,
+		InputTokens:  resp.Usage.InputTokens,
+		OutputTokens: resp.Usage.OutputTokens,
+	}, nil
+}
+
 func (c *Client) CreateMessage(ctx context.Context, req *MessageRequest) (*MessageResponse, error) {
 	jsonData, err := json.Marshal(req)
 	if err != nil {
@@ -77,12 +133,12 @@ func (c *Client) CreateMessage(ctx context.Context, req *MessageRequest) (*Messa
 
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, llm.TransportError(ctx, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API returned status %d", resp.StatusCode)
+		return nil, llm.ReadAPIError(resp)
 	}
 
 	var msgResp MessageResponse
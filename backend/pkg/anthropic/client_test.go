@@ -0,0 +1,154 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/eikuma/stockle/backend/pkg/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordedRequest is what a test server received
+type recordedRequest struct {
+	path   string
+	header http.Header
+	body   map[string]any
+}
+
+// newTestServer answers every request with status and body, and records the
+// last request it received
+func newTestServer(t *testing.T, status int, body string, header http.Header) (*Client, *recordedRequest) {
+	t.Helper()
+	received := &recordedRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.path = r.URL.Path
+		received.header = r.Header.Clone()
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received.body))
+		for key, values := range header {
+			w.Header()[key] = values
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return NewClient("test-key").WithBaseURL(server.URL + "/"), received
+}
+
+func TestClient_Complete(t *testing.T) {
+	client, received := newTestServer(t, http.StatusOK, `{
+		"id": "msg_1",
+		"type": "message",
+		"role": "assistant",
+		"model": "claude-3-haiku-20240307",
+		"content": [{"type": "text", "text": "A "}, {"type": "tool_use"}, {"type": "text", "text": "summary"}],
+		"usage": {"input_tokens": 42, "output_tokens": 7}
+	}`, nil)
+
+	temperature := 0.2
+	completion, err := client.Complete(context.Background(), &llm.CompletionRequest{
+		Model:       "claude-3-haiku-20240307",
+		System:      "Summarize",
+		Messages:    []llm.Message{{Role: llm.RoleUser, Content: "Article"}},
+		MaxTokens:   300,
+		Temperature: &temperature,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &llm.Completion{Text: "A summary", Model: "claude-3-haiku-20240307", InputTokens: 42, OutputTokens: 7}, completion)
+
+	assert.Equal(t, "/messages", received.path)
+	assert.Equal(t, "test-key", received.header.Get("x-api-key"))
+	assert.Equal(t, "2023-06-01", received.header.Get("anthropic-version"))
+	assert.Equal(t, map[string]any{
+		"model":       "claude-3-haiku-20240307",
+		"system":      "Summarize",
+		"messages":    []any{map[string]any{"role": "user", "content": "Article"}},
+		"max_tokens":  float64(300),
+		"temperature": 0.2,
+	}, received.body)
+}
+
+func TestClient_CompleteDefaultsMaxTokens(t *testing.T) {
+	client, received := newTestServer(t, http.StatusOK, `{"content": [{"type": "text", "text": "ok"}]}`, nil)
+
+	_, err := client.Complete(context.Background(), &llm.CompletionRequest{
+		Model:    "claude-3-haiku-20240307",
+		Messages: []llm.Message{{Role: llm.RoleUser, Content: "Article"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, float64(defaultMaxTokens), received.body["max_tokens"])
+	assert.NotContains(t, received.body, "system")
+	assert.NotContains(t, received.body, "temperature")
+}
+
+func TestClient_CompleteErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		header     http.Header
+		kind       error
+		retryAfter time.Duration
+	}{
+		{
+			name:       "rate limited",
+			status:     http.StatusTooManyRequests,
+			body:       `{"type": "error", "error": {"type": "rate_limit_error", "message": "Number of request tokens has exceeded your rate limit"}}`,
+			header:     http.Header{"Retry-After": {"7"}},
+			kind:       llm.ErrRateLimited,
+			retryAfter: 7 * time.Second,
+		},
+		{
+			name:   "overloaded",
+			status: 529,
+			body:   `{"type": "error", "error": {"type": "overloaded_error", "message": "Overloaded"}}`,
+			kind:   llm.ErrOverloaded,
+		},
+		{
+			name:   "context length",
+			status: http.StatusBadRequest,
+			body:   `{"type": "error", "error": {"type": "invalid_request_error", "message": "prompt is too long: 210000 tokens > 200000 maximum"}}`,
+			kind:   llm.ErrContextLength,
+		},
+		{
+			name:   "invalid request",
+			status: http.StatusBadRequest,
+			body:   `{"type": "error", "error": {"type": "invalid_request_error", "message": "max_tokens: must be greater than 0"}}`,
+			kind:   llm.ErrInvalidRequest,
+		},
+		{
+			name:   "invalid api key",
+			status: http.StatusUnauthorized,
+			body:   `{"type": "error", "error": {"type": "authentication_error", "message": "invalid x-api-key"}}`,
+			kind:   llm.ErrAuth,
+		},
+		{
+			name:   "no text content",
+			status: http.StatusOK,
+			body:   `{"id": "msg_1", "content": []}`,
+			kind:   llm.ErrEmptyResponse,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := newTestServer(t, tt.status, tt.body, tt.header)
+			_, err := client.Complete(context.Background(), &llm.CompletionRequest{
+				Model:    "claude-3-haiku-20240307",
+				Messages: []llm.Message{{Role: llm.RoleUser, Content: "Article"}},
+			})
+			require.Error(t, err)
+			assert.ErrorIs(t, err, tt.kind)
+
+			var apiErr *llm.APIError
+			require.True(t, errors.As(err, &apiErr))
+			assert.Equal(t, tt.retryAfter, apiErr.RetryAfter)
+		})
+	}
+}
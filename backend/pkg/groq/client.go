@@ -6,7 +6,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
+
+	"github.com/eikuma/stockle/backend/pkg/llm"
 )
 
 const (
@@ -23,7 +26,7 @@ type ChatCompletionRequest struct {
 	Model       string    `json:"model"`
 	Messages    []Message `json:"messages"`
 	MaxTokens   int       `json:"max_tokens,omitempty"`
-	Temperature float64   `json:"temperature,omitempty"`
+	Temperature *float64  `json:"temperature,omitempty"`
 	TopP        float64   `json:"top_p,omitempty"`
 	Stream      bool      `json:"stream,omitempty"`
 }
@@ -64,6 +67,46 @@ func NewClient(apiKey string) *Client {
 	}
 }
 
+// WithBaseURL points the client at another endpoint of the API
+func (c *Client) WithBaseURL(baseURL string) *Client {
+	c.baseURL = strings.TrimSuffix(baseURL, "/")
+	return c
+}
+
+func (c *Client) Name() string {
+	return "groq"
+}
+
+// Complete implements llm.LLMProvider on the chat completions API
+func (c *Client) Complete(ctx context.Context, req *llm.CompletionRequest) (*llm.Completion, error) {
+	chatReq := &ChatCompletionRequest{
+		Model:       req.Model,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+	}
+	if req.System != "" {
+		chatReq.Messages = append(chatReq.Messages, Message{Role: "system", Content: req.System})
+	}
+	for _, message := range req.Messages {
+		chatReq.Messages = append(chatReq.Messages, Message{Role: message.Role, Content: message.Content})
+	}
+
+	resp, err := c.CreateChatCompletion(ctx, chatReq)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return nil, &llm.APIError{Kind: llm.ErrEmptyResponse, Message: "response has no choices"}
+	}
+
+	return &llm.Completion{
+		Text:         resp.Choices[0].Message.Content,
+		Model:        resp.Model,
+		InputTokens:  resp.Usage.PromptTokens,
+		OutputTokens: resp.Usage.CompletionTokens,
+	}, nil
+}
+
 func (c *Client) CreateChatCompletion(ctx context.Context, req *ChatCompletionRequest) (*ChatCompletionResponse, error) {
 	jsonData, err := json.Marshal(req)
 	if err != nil {
@@ -80,12 +123,12 @@ func (c *Client) CreateChatCompletion(ctx context.Context, req *ChatCompletionRe
 
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, llm.TransportError(ctx, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API returned status %d", resp.StatusCode)
+		return nil, llm.ReadAPIError(resp)
 	}
 
 	var chatResp ChatCompletionResponse
@@ -0,0 +1,189 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kinds of provider errors, matched with errors.Is against an *APIError
+var (
+	ErrRateLimited    = errors.New("rate limited")
+	ErrAuth           = errors.New("authentication failed")
+	ErrInvalidRequest = errors.New("invalid request")
+	ErrOverloaded     = errors.New("provider overloaded")
+	ErrTimeout        = errors.New("request timed out")
+	// ErrContextLength is returned when the prompt does not fit the context
+	// window of the model; the request has to be shortened
+	ErrContextLength = errors.New("context length exceeded")
+	ErrServer        = errors.New("server error")
+	ErrEmptyResponse = errors.New("empty response")
+	// ErrCircuitOpen is returned without calling a provider that keeps
+	// failing, see Resilient
+	ErrCircuitOpen = errors.New("circuit open")
+)
+
+// APIError is an error returned by a provider
+type APIError struct {
+	// Kind is one of the Err values of this package
+	Kind       error
+	StatusCode int
+	// Type is the error type or code of the provider, such as
+	// overloaded_error
+	Type    string
+	Message string
+	// RetryAfter is the wait asked for by the provider, 0 when none
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Kind.Error())
+	if e.StatusCode != 0 {
+		fmt.Fprintf(&b, " (status %d)", e.StatusCode)
+	}
+	if e.Message != "" {
+		b.WriteString(": ")
+		b.WriteString(e.Message)
+	}
+	return b.String()
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Kind
+}
+
+// Retryable reports whether the same request may succeed later
+func Retryable(err error) bool {
+	return errors.Is(err, ErrRateLimited) ||
+		errors.Is(err, ErrOverloaded) ||
+		errors.Is(err, ErrTimeout) ||
+		errors.Is(err, ErrServer) ||
+		errors.Is(err, ErrEmptyResponse)
+}
+
+// retryAfter returns the wait asked for by the provider with an error
+func retryAfter(err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.RetryAfter
+	}
+	return 0
+}
+
+// maxErrorBody limits how much of an error response is read
+const maxErrorBody = 64 << 10
+
+// errorBody is the error format of the OpenAI compatible APIs,
+// {"error": {"message", "type", "code"}}, and of the Anthropic API,
+// {"type": "error", "error": {"type", "message"}}
+type errorBody struct {
+	Error struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+		Code    any    `json:"code"`
+	} `json:"error"`
+}
+
+// NewAPIError classifies an unsuccessful response of a provider from its
+// status, its Retry-After headers and its error body
+func NewAPIError(resp *http.Response, body []byte) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header, time.Now()),
+	}
+
+	var parsed errorBody
+	if json.Unmarshal(body, &parsed) == nil && (parsed.Error.Message != "" || parsed.Error.Type != "") {
+		apiErr.Message = parsed.Error.Message
+		apiErr.Type = parsed.Error.Type
+		if code, ok := parsed.Error.Code.(string); ok && code != "" {
+			apiErr.Type = code
+		}
+	} else {
+		apiErr.Message = strings.TrimSpace(string(body))
+		if len(apiErr.Message) > 200 {
+			apiErr.Message = apiErr.Message[:200]
+		}
+	}
+
+	apiErr.Kind = classify(resp.StatusCode, apiErr.Type, apiErr.Message)
+	return apiErr
+}
+
+// ReadAPIError reads the body of an unsuccessful response and classifies it
+func ReadAPIError(resp *http.Response) *APIError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	return NewAPIError(resp, body)
+}
+
+func classify(status int, errType, message string) error {
+	lowerType := strings.ToLower(errType)
+	lowerMessage := strings.ToLower(message)
+	switch {
+	case lowerType == "context_length_exceeded" ||
+		strings.Contains(lowerMessage, "context length") ||
+		strings.Contains(lowerMessage, "context_length_exceeded") ||
+		strings.Contains(lowerMessage, "prompt is too long") ||
+		strings.Contains(lowerMessage, "maximum context"):
+		return ErrContextLength
+	case lowerType == "overloaded_error" || status == 529 || status == http.StatusServiceUnavailable:
+		return ErrOverloaded
+	case lowerType == "rate_limit_error" || lowerType == "rate_limit_exceeded" || status == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case lowerType == "authentication_error" || lowerType == "permission_error" ||
+		lowerType == "invalid_api_key" || status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return ErrAuth
+	case status == http.StatusRequestTimeout || status == http.StatusGatewayTimeout:
+		return ErrTimeout
+	case status == http.StatusRequestEntityTooLarge:
+		return ErrContextLength
+	case status >= 500:
+		return ErrServer
+	default:
+		return ErrInvalidRequest
+	}
+}
+
+// parseRetryAfter reads the retry-after-ms header some OpenAI compatible
+// APIs send, then Retry-After in seconds or as an HTTP date
+func parseRetryAfter(header http.Header, now time.Time) time.Duration {
+	if ms, err := strconv.ParseFloat(header.Get("retry-after-ms"), 64); err == nil && ms > 0 {
+		return time.Duration(ms * float64(time.Millisecond))
+	}
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+// TransportError classifies an error of sending a request: timeouts,
+// including the deadline of ctx, become ErrTimeout while the cancellation
+// of ctx is returned as is
+func TransportError(ctx context.Context, err error) error {
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return ctx.Err()
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout() {
+		return &APIError{Kind: ErrTimeout, Message: err.Error()}
+	}
+	return fmt.Errorf("failed to send request: %w", err)
+}
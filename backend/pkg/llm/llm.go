@@ -0,0 +1,45 @@
+// Package llm defines the interface shared by the language model clients, so
+// the summary service can chain and route between providers without knowing
+// their wire formats.
+package llm
+
+import "context"
+
+// Roles of the messages of a completion request
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+type Message struct {
+	Role    string
+	Content string
+}
+
+// CompletionRequest is a provider independent chat completion request
+type CompletionRequest struct {
+	Model string
+	// System is the system prompt, sent the way the provider expects it
+	System   string
+	Messages []Message
+	// MaxTokens limits the length of the completion; 0 leaves it to the
+	// provider
+	MaxTokens int
+	// Temperature is left to the provider when nil
+	Temperature *float64
+}
+
+// Completion is the answer of a provider to a completion request
+type Completion struct {
+	Text         string
+	Model        string
+	InputTokens  int
+	OutputTokens int
+}
+
+// LLMProvider is a language model API
+type LLMProvider interface {
+	// Name identifies the kind of provider, such as groq or anthropic
+	Name() string
+	Complete(ctx context.Context, req *CompletionRequest) (*Completion, error)
+}
@@ -0,0 +1,296 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Policy is how Resilient calls a provider; the zero value calls it once,
+// without a rate limit or a circuit breaker
+type Policy struct {
+	// MaxRetries is the number of retries of a request failing with a
+	// retryable error, see Retryable
+	MaxRetries int
+	// RetryDelay is the base of the exponential backoff between retries
+	RetryDelay time.Duration
+	// MaxRetryDelay caps the backoff; a provider asking to wait longer with
+	// Retry-After is not retried
+	MaxRetryDelay time.Duration
+	// RequestTimeout limits each attempt
+	RequestTimeout time.Duration
+	// RateLimitPerMin is the size of the token bucket, refilled over a minute
+	RateLimitPerMin int
+	// FailureThreshold is the number of failed requests in a row opening the
+	// circuit; while open, requests fail with ErrCircuitOpen until Cooldown
+	// has passed and a trial request succeeds
+	FailureThreshold int
+	Cooldown         time.Duration
+}
+
+// Resilient wraps a provider with retries, a client-side rate limit and a
+// circuit breaker
+type Resilient struct {
+	provider LLMProvider
+	policy   Policy
+	limiter  *tokenBucket
+	breaker  *circuitBreaker
+
+	// sleep and jitter are replaced by the tests
+	sleep  func(ctx context.Context, d time.Duration) error
+	jitter func(d time.Duration) time.Duration
+}
+
+func NewResilient(provider LLMProvider, policy Policy) *Resilient {
+	r := &Resilient{
+		provider: provider,
+		policy:   policy,
+		sleep:    sleepContext,
+		jitter:   fullJitter,
+	}
+	if policy.RateLimitPerMin > 0 {
+		r.limiter = newTokenBucket(policy.RateLimitPerMin, time.Minute, time.Now)
+	}
+	if policy.FailureThreshold > 0 {
+		r.breaker = newCircuitBreaker(policy.FailureThreshold, policy.Cooldown, time.Now)
+	}
+	return r
+}
+
+func (r *Resilient) Name() string {
+	return r.provider.Name()
+}
+
+func (r *Resilient) Complete(ctx context.Context, req *CompletionRequest) (*Completion, error) {
+	var trial bool
+	if r.breaker != nil {
+		var err error
+		if trial, err = r.breaker.allow(); err != nil {
+			return nil, err
+		}
+	}
+
+	completion, err := r.complete(ctx, req)
+	if r.breaker != nil {
+		r.breaker.record(trial, err)
+	}
+	return completion, err
+}
+
+func (r *Resilient) complete(ctx context.Context, req *CompletionRequest) (*Completion, error) {
+	for attempt := 0; ; attempt++ {
+		if r.limiter != nil {
+			if err := r.limiter.wait(ctx, r.sleep); err != nil {
+				return nil, err
+			}
+		}
+
+		completion, err := r.attempt(ctx, req)
+		if err == nil {
+			return completion, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if !Retryable(err) || attempt >= r.policy.MaxRetries {
+			return nil, err
+		}
+
+		delay, ok := r.backoff(attempt, retryAfter(err))
+		if !ok {
+			return nil, err
+		}
+		if err := r.sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (r *Resilient) attempt(ctx context.Context, req *CompletionRequest) (*Completion, error) {
+	if r.policy.RequestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.policy.RequestTimeout)
+		defer cancel()
+	}
+
+	completion, err := r.provider.Complete(ctx, req)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, &APIError{Kind: ErrTimeout, Message: err.Error()}
+		}
+		return nil, err
+	}
+	if strings.TrimSpace(completion.Text) == "" {
+		return nil, &APIError{Kind: ErrEmptyResponse}
+	}
+	return completion, nil
+}
+
+// backoff returns the wait before a retry, doubling from RetryDelay with
+// jitter, or the Retry-After of the provider when longer; ok is false when
+// the provider asks to wait past MaxRetryDelay
+func (r *Resilient) backoff(attempt int, retryAfter time.Duration) (delay time.Duration, ok bool) {
+	if r.policy.MaxRetryDelay > 0 && retryAfter > r.policy.MaxRetryDelay {
+		return 0, false
+	}
+
+	delay = r.policy.RetryDelay << attempt
+	if delay < r.policy.RetryDelay || r.policy.MaxRetryDelay > 0 && delay > r.policy.MaxRetryDelay {
+		delay = r.policy.MaxRetryDelay
+	}
+	delay = r.jitter(delay)
+	if retryAfter > delay {
+		delay = retryAfter
+	}
+	return delay, true
+}
+
+// fullJitter spreads retries over [d/2, d] so that clients failing together
+// do not retry together
+func fullJitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// tokenBucket holds up to capacity tokens, refilled evenly over period
+type tokenBucket struct {
+	mu       sync.Mutex
+	capacity float64
+	tokens   float64
+	// perToken is the time to refill one token
+	perToken time.Duration
+	last     time.Time
+	now      func() time.Time
+}
+
+func newTokenBucket(capacity int, period time.Duration, now func() time.Time) *tokenBucket {
+	return &tokenBucket{
+		capacity: float64(capacity),
+		tokens:   float64(capacity),
+		perToken: period / time.Duration(capacity),
+		last:     now(),
+		now:      now,
+	}
+}
+
+// reserve takes a token, returning how long to wait until it is available
+func (b *tokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += float64(elapsed) / float64(b.perToken)
+		if b.tokens > b.capacity {
+			b.tokens = b.capacity
+		}
+		b.last = now
+	}
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens * float64(b.perToken))
+}
+
+// cancel returns a token taken by reserve that went unused
+func (b *tokenBucket) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens++
+}
+
+func (b *tokenBucket) wait(ctx context.Context, sleep func(context.Context, time.Duration) error) error {
+	delay := b.reserve()
+	if delay == 0 {
+		return nil
+	}
+	if err := sleep(ctx, delay); err != nil {
+		b.cancel()
+		return err
+	}
+	return nil
+}
+
+// circuitBreaker opens after threshold failed requests in a row. Once open,
+// it rejects requests for cooldown, then lets a single trial request
+// through: its success closes the circuit and its failure opens it again.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	trial     bool
+	now       func() time.Time
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration, now func() time.Time) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown, now: now}
+}
+
+// allow reports whether a request may be sent, and whether it is the trial
+// of an open circuit
+func (b *circuitBreaker) allow() (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return false, nil
+	}
+	if b.trial || b.now().Before(b.openUntil) {
+		return false, ErrCircuitOpen
+	}
+	b.trial = true
+	return true, nil
+}
+
+// record counts the outcome of an allowed request. Errors of the request
+// itself, such as an invalid request or a cancelled context, say nothing of
+// the health of the provider and are not counted. Once the circuit is open,
+// only the outcome of the trial counts: requests sent before it opened
+// finish without closing it or letting another trial through.
+func (b *circuitBreaker) record(trial bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !trial && b.failures >= b.threshold {
+		return
+	}
+	b.trial = false
+	switch {
+	case err == nil:
+		b.failures = 0
+	case errors.Is(err, ErrInvalidRequest), errors.Is(err, ErrContextLength),
+		errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		if trial {
+			// the trial proved nothing; let the next request try
+			b.openUntil = time.Time{}
+		}
+	default:
+		b.failures++
+		if b.failures >= b.threshold {
+			b.openUntil = b.now().Add(b.cooldown)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedProvider fails with errs, one per call, then succeeds
+type scriptedProvider struct {
+	errs  []error
+	calls int
+}
+
+func (p *scriptedProvider) Name() string {
+	return "scripted"
+}
+
+func (p *scriptedProvider) Complete(ctx context.Context, req *CompletionRequest) (*Completion, error) {
+	p.calls++
+	if len(p.errs) > 0 {
+		err := p.errs[0]
+		p.errs = p.errs[1:]
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &Completion{Text: "ok"}, nil
+}
+
+// fakeClock records the sleeps of a Resilient instead of sleeping
+type fakeClock struct {
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d)
+	return ctx.Err()
+}
+
+func newTestResilient(provider LLMProvider, policy Policy) (*Resilient, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	r := NewResilient(provider, policy)
+	r.sleep = clock.Sleep
+	r.jitter = func(d time.Duration) time.Duration { return d }
+	if r.limiter != nil {
+		r.limiter = newTokenBucket(policy.RateLimitPerMin, time.Minute, clock.Now)
+	}
+	if r.breaker != nil {
+		r.breaker = newCircuitBreaker(policy.FailureThreshold, policy.Cooldown, clock.Now)
+	}
+	return r, clock
+}
+
+func TestResilient_ExponentialBackoff(t *testing.T) {
+	provider := &scriptedProvider{errs: []error{
+		&APIError{Kind: ErrServer},
+		&APIError{Kind: ErrOverloaded},
+		&APIError{Kind: ErrTimeout},
+		&APIError{Kind: ErrServer},
+	}}
+	r, clock := newTestResilient(provider, Policy{MaxRetries: 4, RetryDelay: time.Second, MaxRetryDelay: 5 * time.Second})
+
+	completion, err := r.Complete(context.Background(), &CompletionRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "ok", completion.Text)
+	assert.Equal(t, 5, provider.calls)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}, clock.sleeps)
+}
+
+func TestResilient_GivesUpAfterMaxRetries(t *testing.T) {
+	provider := &scriptedProvider{errs: []error{
+		&APIError{Kind: ErrServer}, &APIError{Kind: ErrServer}, &APIError{Kind: ErrServer},
+	}}
+	r, _ := newTestResilient(provider, Policy{MaxRetries: 2, RetryDelay: time.Second})
+
+	_, err := r.Complete(context.Background(), &CompletionRequest{})
+	assert.ErrorIs(t, err, ErrServer)
+	assert.Equal(t, 3, provider.calls)
+}
+
+func TestResilient_DoesNotRetryPermanentErrors(t *testing.T) {
+	for _, kind := range []error{ErrAuth, ErrInvalidRequest, ErrContextLength} {
+		provider := &scriptedProvider{errs: []error{&APIError{Kind: kind}}}
+		r, clock := newTestResilient(provider, Policy{MaxRetries: 3, RetryDelay: time.Second})
+
+		_, err := r.Complete(context.Background(), &CompletionRequest{})
+		assert.ErrorIs(t, err, kind)
+		assert.Equal(t, 1, provider.calls, kind.Error())
+		assert.Empty(t, clock.sleeps)
+	}
+}
+
+func TestResilient_RetryAfter(t *testing.T) {
+	provider := &scriptedProvider{errs: []error{&APIError{Kind: ErrRateLimited, RetryAfter: 3 * time.Second}}}
+	r, clock := newTestResilient(provider, Policy{MaxRetries: 1, RetryDelay: 100 * time.Millisecond, MaxRetryDelay: 10 * time.Second})
+
+	_, err := r.Complete(context.Background(), &CompletionRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, []time.Duration{3 * time.Second}, clock.sleeps)
+
+	// a wait longer than MaxRetryDelay is left to the next provider
+	provider = &scriptedProvider{errs: []error{&APIError{Kind: ErrRateLimited, RetryAfter: time.Minute}}}
+	r, clock = newTestResilient(provider, Policy{MaxRetries: 1, RetryDelay: 100 * time.Millisecond, MaxRetryDelay: 10 * time.Second})
+
+	_, err = r.Complete(context.Background(), &CompletionRequest{})
+	assert.ErrorIs(t, err, ErrRateLimited)
+	assert.Equal(t, 1, provider.calls)
+	assert.Empty(t, clock.sleeps)
+}
+
+func TestResilient_EmptyCompletion(t *testing.T) {
+	r, _ := newTestResilient(emptyProvider{}, Policy{})
+
+	_, err := r.Complete(context.Background(), &CompletionRequest{})
+	assert.ErrorIs(t, err, ErrEmptyResponse)
+}
+
+type emptyProvider struct{}
+
+func (emptyProvider) Name() string {
+	return "empty"
+}
+
+func (emptyProvider) Complete(ctx context.Context, req *CompletionRequest) (*Completion, error) {
+	return &Completion{Text: " \n"}, nil
+}
+
+func TestResilient_RateLimit(t *testing.T) {
+	provider := &scriptedProvider{}
+	r, clock := newTestResilient(provider, Policy{RateLimitPerMin: 2})
+
+	for i := 0; i < 4; i++ {
+		_, err := r.Complete(context.Background(), &CompletionRequest{})
+		require.NoError(t, err)
+	}
+	// the bucket starts full, then refills a token every 30 seconds
+	assert.Equal(t, []time.Duration{30 * time.Second, 30 * time.Second}, clock.sleeps)
+}
+
+func TestResilient_CircuitBreaker(t *testing.T) {
+	failure := &APIError{Kind: ErrServer}
+	provider := &scriptedProvider{errs: []error{failure, failure, failure}}
+	r, clock := newTestResilient(provider, Policy{FailureThreshold: 2, Cooldown: time.Minute})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, err := r.Complete(ctx, &CompletionRequest{})
+		assert.ErrorIs(t, err, ErrServer)
+	}
+	_, err := r.Complete(ctx, &CompletionRequest{})
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, 2, provider.calls)
+
+	// after the cooldown a failing trial opens the circuit again
+	clock.now = clock.now.Add(time.Minute)
+	_, err = r.Complete(ctx, &CompletionRequest{})
+	assert.ErrorIs(t, err, ErrServer)
+	_, err = r.Complete(ctx, &CompletionRequest{})
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+
+	// and a successful one closes it
+	clock.now = clock.now.Add(time.Minute)
+	_, err = r.Complete(ctx, &CompletionRequest{})
+	require.NoError(t, err)
+	_, err = r.Complete(ctx, &CompletionRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, 5, provider.calls)
+}
+
+func TestCircuitBreaker_OnlyTrialDecides(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	b := newCircuitBreaker(2, time.Minute, clock.Now)
+	failure := &APIError{Kind: ErrServer}
+
+	// a request sent while the circuit is closed finishes after it opened
+	stale, err := b.allow()
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		trial, err := b.allow()
+		require.NoError(t, err)
+		b.record(trial, failure)
+	}
+	clock.now = clock.now.Add(time.Minute)
+	trial, err := b.allow()
+	require.NoError(t, err)
+	require.True(t, trial)
+
+	b.record(stale, nil)
+	_, err = b.allow()
+	assert.ErrorIs(t, err, ErrCircuitOpen, "the stale success neither closes the circuit nor lets a second trial through")
+
+	b.record(trial, failure)
+	_, err = b.allow()
+	assert.ErrorIs(t, err, ErrCircuitOpen, "the failed trial opens the circuit again")
+	clock.now = clock.now.Add(time.Minute)
+	trial, err = b.allow()
+	require.NoError(t, err)
+	b.record(trial, nil)
+	trial, err = b.allow()
+	require.NoError(t, err)
+	assert.False(t, trial, "the successful trial closes the circuit")
+}
+
+func TestResilient_CircuitIgnoresRequestErrors(t *testing.T) {
+	invalid := &APIError{Kind: ErrInvalidRequest}
+	provider := &scriptedProvider{errs: []error{invalid, invalid, invalid}}
+	r, _ := newTestResilient(provider, Policy{FailureThreshold: 2, Cooldown: time.Minute})
+
+	for i := 0; i < 3; i++ {
+		_, err := r.Complete(context.Background(), &CompletionRequest{})
+		assert.ErrorIs(t, err, ErrInvalidRequest)
+	}
+	assert.Equal(t, 3, provider.calls)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+	}{
+		{"none", http.Header{}, 0},
+		{"seconds", http.Header{"Retry-After": {"12"}}, 12 * time.Second},
+		{"fractional seconds", http.Header{"Retry-After": {"1.5"}}, 1500 * time.Millisecond},
+		{"date", http.Header{"Retry-After": {now.Add(time.Minute).Format(http.TimeFormat)}}, time.Minute},
+		{"past date", http.Header{"Retry-After": {now.Add(-time.Minute).Format(http.TimeFormat)}}, 0},
+		{"milliseconds first", http.Header{"Retry-After": {"2"}, "Retry-After-Ms": {"150"}}, 150 * time.Millisecond},
+		{"invalid", http.Header{"Retry-After": {"soon"}}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseRetryAfter(tt.header, now))
+		})
+	}
+}
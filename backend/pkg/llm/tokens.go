@@ -0,0 +1,20 @@
+package llm
+
+import "unicode"
+
+// EstimateTokens approximates the number of tokens of a text without the
+// tokenizer of a model: about four characters a token for ASCII text, and a
+// token a character for the other scripts, such as Japanese, which the
+// common tokenizers split much finer. It errs on the high side so that
+// estimated prompts fit.
+func EstimateTokens(text string) int {
+	var ascii, other int
+	for _, r := range text {
+		if r <= unicode.MaxASCII {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+3)/4 + other
+}
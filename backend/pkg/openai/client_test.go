@@ -0,0 +1,159 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/eikuma/stockle/backend/pkg/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordedRequest is what a test server received
+type recordedRequest struct {
+	path   string
+	header http.Header
+	body   map[string]any
+}
+
+// newTestServer answers every request with status and body, and records the
+// last request it received
+func newTestServer(t *testing.T, status int, body string, header http.Header) (*Client, *recordedRequest) {
+	t.Helper()
+	received := &recordedRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.path = r.URL.Path
+		received.header = r.Header.Clone()
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received.body))
+		for key, values := range header {
+			w.Header()[key] = values
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return NewClient(server.URL+"/v1/", "test-key"), received
+}
+
+func TestClient_Complete(t *testing.T) {
+	client, received := newTestServer(t, http.StatusOK, `{
+		"id": "chatcmpl-1",
+		"model": "gpt-4o-mini",
+		"choices": [{"index": 0, "message": {"role": "assistant", "content": "A summary"}, "finish_reason": "stop"}],
+		"usage": {"prompt_tokens": 42, "completion_tokens": 7, "total_tokens": 49}
+	}`, nil)
+
+	temperature := 0.2
+	completion, err := client.Complete(context.Background(), &llm.CompletionRequest{
+		Model:       "gpt-4o-mini",
+		System:      "Summarize",
+		Messages:    []llm.Message{{Role: llm.RoleUser, Content: "Article"}},
+		MaxTokens:   300,
+		Temperature: &temperature,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &llm.Completion{Text: "A summary", Model: "gpt-4o-mini", InputTokens: 42, OutputTokens: 7}, completion)
+
+	assert.Equal(t, "/v1/chat/completions", received.path)
+	assert.Equal(t, "Bearer test-key", received.header.Get("Authorization"))
+	assert.Equal(t, map[string]any{
+		"model": "gpt-4o-mini",
+		"messages": []any{
+			map[string]any{"role": "system", "content": "Summarize"},
+			map[string]any{"role": "user", "content": "Article"},
+		},
+		"max_tokens":  float64(300),
+		"temperature": 0.2,
+		"stream":      false,
+	}, received.body)
+}
+
+func TestClient_CompleteWithoutKey(t *testing.T) {
+	client, received := newTestServer(t, http.StatusOK, `{
+		"choices": [{"index": 0, "message": {"role": "assistant", "content": "A summary"}}]
+	}`, nil)
+	client.apiKey = ""
+
+	completion, err := client.Complete(context.Background(), &llm.CompletionRequest{
+		Model:    "llama3",
+		Messages: []llm.Message{{Role: llm.RoleUser, Content: "Article"}},
+	})
+	require.NoError(t, err)
+	// Local servers often leave the model out of the response
+	assert.Equal(t, "llama3", completion.Model)
+	assert.Empty(t, received.header.Get("Authorization"))
+	assert.NotContains(t, received.body, "max_tokens")
+	assert.NotContains(t, received.body, "temperature")
+}
+
+func TestClient_CompleteErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		header     http.Header
+		kind       error
+		retryAfter time.Duration
+	}{
+		{
+			name:       "rate limited",
+			status:     http.StatusTooManyRequests,
+			body:       `{"error": {"message": "Rate limit reached", "type": "tokens", "code": "rate_limit_exceeded"}}`,
+			header:     http.Header{"Retry-After": {"7"}},
+			kind:       llm.ErrRateLimited,
+			retryAfter: 7 * time.Second,
+		},
+		{
+			name:   "context length",
+			status: http.StatusBadRequest,
+			body:   `{"error": {"message": "Please reduce the length of the messages", "type": "invalid_request_error", "code": "context_length_exceeded"}}`,
+			kind:   llm.ErrContextLength,
+		},
+		{
+			name:   "invalid request",
+			status: http.StatusBadRequest,
+			body:   `{"error": {"message": "model does not exist", "type": "invalid_request_error", "code": "model_not_found"}}`,
+			kind:   llm.ErrInvalidRequest,
+		},
+		{
+			name:   "invalid api key",
+			status: http.StatusUnauthorized,
+			body:   `{"error": {"message": "Invalid API Key", "type": "invalid_request_error", "code": "invalid_api_key"}}`,
+			kind:   llm.ErrAuth,
+		},
+		{
+			name:   "server error",
+			status: http.StatusBadGateway,
+			body:   `bad gateway`,
+			kind:   llm.ErrServer,
+		},
+		{
+			name:   "no choices",
+			status: http.StatusOK,
+			body:   `{"id": "chatcmpl-1", "choices": []}`,
+			kind:   llm.ErrEmptyResponse,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := newTestServer(t, tt.status, tt.body, tt.header)
+			_, err := client.Complete(context.Background(), &llm.CompletionRequest{
+				Model:    "gpt-4o-mini",
+				Messages: []llm.Message{{Role: llm.RoleUser, Content: "Article"}},
+			})
+			require.Error(t, err)
+			assert.ErrorIs(t, err, tt.kind)
+
+			var apiErr *llm.APIError
+			require.True(t, errors.As(err, &apiErr))
+			assert.Equal(t, tt.retryAfter, apiErr.RetryAfter)
+		})
+	}
+}
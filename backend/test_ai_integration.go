@@ -42,7 +42,10 @@ func main() {
 	}
 
 	// AIサービスの初期化
-	aiService := services.NewAIService(cfg)
+	aiService, err := services.NewAIService(cfg)
+	if err != nil {
+		log.Fatalf("❌ AIサービスの初期化に失敗しました: %v", err)
+	}
 
 	// テスト用の記事内容
 	testCases := []struct {